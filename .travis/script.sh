@@ -13,6 +13,8 @@ fi
 
 if [ ${TESTMODE} == "unit" ]; then
   ginkgo -r -v -cover -randomizeAllSpecs -randomizeSuites -trace -skipPackage integrationtests,benchmark
+  # run the tests for the hooks only available with the quictesthooks build tag
+  ginkgo -v -randomizeAllSpecs -trace -tags quictesthooks -focus "Test Hooks"
 fi
 
 if [ ${TESTMODE} == "integration" ]; then
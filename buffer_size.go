@@ -0,0 +1,59 @@
+package quic
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/qlog"
+)
+
+// bufferSizeConn is implemented by the *net.UDPConn
+type bufferSizeConn interface {
+	SetReadBuffer(bytes int) error
+	SetWriteBuffer(bytes int) error
+	SyscallConn() (syscall.RawConn, error)
+}
+
+// A bufferSizeLimit records that the operating system used a smaller buffer size than requested.
+type bufferSizeLimit struct {
+	buffer    qlog.SocketBuffer
+	requested int
+	actual    int
+}
+
+// setBufferSizes sets the receive and send buffer sizes configured in the quic.Config.
+// The operating system might silently use a smaller size than requested.
+// If we can detect this, the sizes are returned, so they can be recorded by the tracer of every session.
+func setBufferSizes(c bufferSizeConn, config *Config, logger utils.Logger) ([]bufferSizeLimit, error) {
+	if config == nil {
+		return nil, nil
+	}
+	var limits []bufferSizeLimit
+	if size := config.ReceiveBufferSize; size > 0 {
+		if err := c.SetReadBuffer(size); err != nil {
+			return nil, err
+		}
+		if actual, ok := getBufferSize(c, syscall.SO_RCVBUF); ok && actual < size {
+			logger.Debugf("Failed to increase the receive buffer size. Requested: %d bytes, got: %d bytes.", size, actual)
+			limits = append(limits, bufferSizeLimit{buffer: qlog.SocketBufferReceive, requested: size, actual: actual})
+		}
+	}
+	if size := config.SendBufferSize; size > 0 {
+		if err := c.SetWriteBuffer(size); err != nil {
+			return nil, err
+		}
+		if actual, ok := getBufferSize(c, syscall.SO_SNDBUF); ok && actual < size {
+			logger.Debugf("Failed to increase the send buffer size. Requested: %d bytes, got: %d bytes.", size, actual)
+			limits = append(limits, bufferSizeLimit{buffer: qlog.SocketBufferSend, requested: size, actual: actual})
+		}
+	}
+	return limits, nil
+}
+
+func traceBufferSizeLimits(qlogger qlog.Tracer, limits []bufferSizeLimit) {
+	now := time.Now()
+	for _, l := range limits {
+		qlogger.LimitedSocketBuffer(now, l.buffer, l.requested, l.actual)
+	}
+}
@@ -0,0 +1,23 @@
+// +build linux
+
+package quic
+
+import "syscall"
+
+// getBufferSize reads the size of the socket buffer.
+// The Linux kernel doubles the value passed to setsockopt (to allow space for bookkeeping overhead),
+// and reports the doubled value, see socket(7).
+func getBufferSize(c bufferSizeConn, opt int) (int, bool) {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	var size int
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		size, serr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, opt)
+	}); err != nil || serr != nil {
+		return 0, false
+	}
+	return size / 2, true
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package quic
+
+// getBufferSize reads the size of the socket buffer.
+// It is only implemented on Linux.
+func getBufferSize(bufferSizeConn, int) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,130 @@
+package quic
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/qlog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// socketBufferRecorder is a qlog.Tracer that records the limited socket buffers.
+// It panics if any other method is called.
+type socketBufferRecorder struct {
+	qlog.Tracer
+	limits []bufferSizeLimit
+}
+
+func (r *socketBufferRecorder) LimitedSocketBuffer(_ time.Time, buffer qlog.SocketBuffer, requested, actual int) {
+	r.limits = append(r.limits, bufferSizeLimit{buffer: buffer, requested: requested, actual: actual})
+}
+
+type mockBufferSizeConn struct {
+	readBufferSize, writeBufferSize int
+	readBufferErr, writeBufferErr   error
+}
+
+var _ bufferSizeConn = &mockBufferSizeConn{}
+
+func (c *mockBufferSizeConn) SetReadBuffer(bytes int) error {
+	c.readBufferSize = bytes
+	return c.readBufferErr
+}
+
+func (c *mockBufferSizeConn) SetWriteBuffer(bytes int) error {
+	c.writeBufferSize = bytes
+	return c.writeBufferErr
+}
+
+func (c *mockBufferSizeConn) SyscallConn() (syscall.RawConn, error) {
+	return nil, errors.New("not implemented")
+}
+
+var _ = Describe("Buffer Sizes", func() {
+	It("sets the configured buffer sizes", func() {
+		conn := &mockBufferSizeConn{}
+		limits, err := setBufferSizes(conn, &Config{ReceiveBufferSize: 1 << 20, SendBufferSize: 1 << 21}, utils.DefaultLogger)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limits).To(BeEmpty())
+		Expect(conn.readBufferSize).To(Equal(1 << 20))
+		Expect(conn.writeBufferSize).To(Equal(1 << 21))
+	})
+
+	It("doesn't change the buffer sizes if not configured", func() {
+		conn := &mockBufferSizeConn{}
+		_, err := setBufferSizes(conn, &Config{}, utils.DefaultLogger)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = setBufferSizes(conn, nil, utils.DefaultLogger)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.readBufferSize).To(BeZero())
+		Expect(conn.writeBufferSize).To(BeZero())
+	})
+
+	It("returns errors when setting the receive buffer size fails", func() {
+		testErr := errors.New("test error")
+		conn := &mockBufferSizeConn{readBufferErr: testErr}
+		_, err := setBufferSizes(conn, &Config{ReceiveBufferSize: 1 << 20}, utils.DefaultLogger)
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("returns errors when setting the send buffer size fails", func() {
+		testErr := errors.New("test error")
+		conn := &mockBufferSizeConn{writeBufferErr: testErr}
+		_, err := setBufferSizes(conn, &Config{SendBufferSize: 1 << 20}, utils.DefaultLogger)
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("sets the buffer sizes on a UDP socket", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		// use a small size, so the operating system doesn't clamp it
+		const size = 1 << 12
+		limits, err := setBufferSizes(conn, &Config{ReceiveBufferSize: size, SendBufferSize: size}, utils.DefaultLogger)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limits).To(BeEmpty())
+		if actual, ok := getBufferSize(conn, syscall.SO_RCVBUF); ok {
+			Expect(actual).To(BeNumerically(">=", size))
+		}
+		if actual, ok := getBufferSize(conn, syscall.SO_SNDBUF); ok {
+			Expect(actual).To(BeNumerically(">=", size))
+		}
+	})
+
+	It("reports when the operating system uses smaller buffer sizes", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		if _, ok := getBufferSize(conn, syscall.SO_RCVBUF); !ok {
+			Skip("reading the buffer size is not supported on this platform")
+		}
+		// use a large size, so the operating system clamps it
+		const size = 1 << 30
+		limits, err := setBufferSizes(conn, &Config{ReceiveBufferSize: size, SendBufferSize: size}, utils.DefaultLogger)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(limits).To(HaveLen(2))
+		Expect(limits[0].buffer).To(Equal(qlog.SocketBufferReceive))
+		Expect(limits[1].buffer).To(Equal(qlog.SocketBufferSend))
+		for _, l := range limits {
+			Expect(l.requested).To(Equal(size))
+			Expect(l.actual).To(BeNumerically("<", size))
+		}
+	})
+
+	It("traces the buffer size limits", func() {
+		tracer := &socketBufferRecorder{}
+		traceBufferSizeLimits(tracer, []bufferSizeLimit{
+			{buffer: qlog.SocketBufferReceive, requested: 1 << 24, actual: 1 << 18},
+			{buffer: qlog.SocketBufferSend, requested: 1 << 23, actual: 1 << 17},
+		})
+		Expect(tracer.limits).To(Equal([]bufferSizeLimit{
+			{buffer: qlog.SocketBufferReceive, requested: 1 << 24, actual: 1 << 18},
+			{buffer: qlog.SocketBufferSend, requested: 1 << 23, actual: 1 << 17},
+		}))
+	})
+})
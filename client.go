@@ -8,7 +8,6 @@ import (
 	"net"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -23,6 +22,8 @@ type client struct {
 	// If the client is created with DialAddr, we create a packet conn.
 	// If it is started with Dial, we take a packet conn as a parameter.
 	createdPacketConn bool
+	// set if the operating system used smaller buffer sizes than configured for the packet conn we created
+	bufferSizeLimits []bufferSizeLimit
 
 	use0RTT bool
 
@@ -168,6 +169,15 @@ func dialContext(
 	if tlsConf == nil {
 		return nil, errors.New("quic: tls.Config not set")
 	}
+	var bufferSizeLimits []bufferSizeLimit
+	if bconn, ok := pconn.(bufferSizeConn); ok && createdPacketConn {
+		var err error
+		bufferSizeLimits, err = setBufferSizes(bconn, config, utils.DefaultLogger)
+		if err != nil {
+			pconn.Close()
+			return nil, err
+		}
+	}
 	config = populateClientConfig(config, createdPacketConn)
 	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.StatelessResetKey)
 	if err != nil {
@@ -178,6 +188,7 @@ func dialContext(
 		return nil, err
 	}
 	c.packetHandlers = packetHandlers
+	c.bufferSizeLimits = bufferSizeLimits
 
 	var qlogger qlog.Tracer
 	if c.config.GetLogWriter != nil {
@@ -223,9 +234,12 @@ func newClient(
 				return nil, fmt.Errorf("%s is not a valid QUIC version", v)
 			}
 		}
+		if config.MinIdleTimeout > config.MaxIdleTimeout {
+			return nil, fmt.Errorf("quic: MinIdleTimeout (%s) is larger than MaxIdleTimeout (%s)", config.MinIdleTimeout, config.MaxIdleTimeout)
+		}
 	}
 
-	srcConnID, err := generateConnectionID(config.ConnectionIDLength)
+	srcConnID, err := getConnectionIDGenerator(config)(config.ConnectionIDLength)
 	if err != nil {
 		return nil, err
 	}
@@ -251,7 +265,8 @@ func newClient(
 func (c *client) dial(ctx context.Context, qlogger qlog.Tracer) error {
 	c.logger.Infof("Starting new connection to %s (%s -> %s), source connection ID %s, destination connection ID %s, version %s", c.tlsConf.ServerName, c.conn.LocalAddr(), c.conn.RemoteAddr(), c.srcConnID, c.destConnID, c.version)
 	if qlogger != nil {
-		qlogger.StartedConnection(time.Now(), c.conn.LocalAddr(), c.conn.LocalAddr(), c.version, c.srcConnID, c.destConnID)
+		qlogger.StartedConnection(getClock().Now(), c.conn.LocalAddr(), c.conn.RemoteAddr(), c.version, c.srcConnID, c.destConnID)
+		traceBufferSizeLimits(qlogger, c.bufferSizeLimits)
 	}
 
 	c.mutex.Lock()
@@ -492,6 +492,14 @@ var _ = Describe("Client", func() {
 				Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
 			})
 
+			It("errors when the MinIdleTimeout is larger than the MaxIdleTimeout", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", tlsConf, &Config{MinIdleTimeout: 10 * time.Second, MaxIdleTimeout: 5 * time.Second})
+				Expect(err).To(MatchError("quic: MinIdleTimeout (10s) is larger than MaxIdleTimeout (5s)"))
+			})
+
 			It("disables bidirectional streams", func() {
 				config := &Config{
 					MaxIncomingStreams:    -1,
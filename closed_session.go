@@ -10,6 +10,8 @@ import (
 // A closedLocalSession is a session that we closed locally.
 // When receiving packets for such a session, we need to retransmit the packet containing the CONNECTION_CLOSE frame,
 // with an exponential backoff.
+// After retransmitting it MaxConnectionCloseRetransmissions times, the session is draining:
+// it drops all packets without responding.
 type closedLocalSession struct {
 	conn            connection
 	connClosePacket []byte
@@ -19,6 +21,7 @@ type closedLocalSession struct {
 
 	receivedPackets chan *receivedPacket
 	counter         uint64 // number of packets received
+	retransmissions int    // number of times the CONNECTION_CLOSE was retransmitted
 
 	perspective protocol.Perspective
 
@@ -65,6 +68,9 @@ func (s *closedLocalSession) handlePacket(p *receivedPacket) {
 }
 
 func (s *closedLocalSession) handlePacketImpl(_ *receivedPacket) {
+	if s.retransmissions >= protocol.MaxConnectionCloseRetransmissions {
+		return
+	}
 	s.counter++
 	// exponential backoff
 	// only send a CONNECTION_CLOSE for the 1st, 2nd, 4th, 8th, 16th, ... packet arriving
@@ -73,6 +79,7 @@ func (s *closedLocalSession) handlePacketImpl(_ *receivedPacket) {
 			return
 		}
 	}
+	s.retransmissions++
 	s.logger.Debugf("Received %d packets after sending CONNECTION_CLOSE. Retransmitting.", s.counter)
 	if err := s.conn.Write(s.connClosePacket); err != nil {
 		s.logger.Debugf("Error retransmitting CONNECTION_CLOSE: %s", err)
@@ -48,6 +48,18 @@ var _ = Describe("Closed local session", func() {
 		sess.shutdown()
 	})
 
+	It("stops retransmitting the CONNECTION_CLOSE after a while", func() {
+		var numWritten int
+		mconn.EXPECT().Write([]byte("close")).Do(func([]byte) { numWritten++ }).Times(protocol.MaxConnectionCloseRetransmissions)
+		s := sess.(*closedLocalSession)
+		for i := 0; i < 1<<(protocol.MaxConnectionCloseRetransmissions+2); i++ {
+			s.handlePacketImpl(&receivedPacket{})
+		}
+		Expect(numWritten).To(Equal(protocol.MaxConnectionCloseRetransmissions))
+		// stop the session
+		sess.shutdown()
+	})
+
 	It("destroys sessions", func() {
 		Expect(areClosedSessionsRunning()).To(BeTrue())
 		sess.destroy(errors.New("destroy"))
@@ -1,6 +1,13 @@
 package quic
 
-import "github.com/lucas-clemente/quic-go/internal/protocol"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
 
 // Clone clones a Config
 func (c *Config) Clone() *Config {
@@ -15,6 +22,9 @@ func populateServerConfig(config *Config) *Config {
 	if config.ConnectionIDLength == 0 {
 		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
 	}
+	if config.RetryConnectionIDLength == 0 {
+		config.RetryConnectionIDLength = config.ConnectionIDLength
+	}
 	if config.AcceptToken == nil {
 		config.AcceptToken = defaultAcceptToken
 	}
@@ -55,6 +65,14 @@ func populateConfig(config *Config) *Config {
 	if maxReceiveConnectionFlowControlWindow == 0 {
 		maxReceiveConnectionFlowControlWindow = protocol.DefaultMaxReceiveConnectionFlowControlWindow
 	}
+	receiveWindowGrowthFactor := config.ReceiveWindowGrowthFactor
+	if receiveWindowGrowthFactor <= 0 {
+		receiveWindowGrowthFactor = protocol.DefaultReceiveWindowGrowthFactor
+	}
+	streamWindowUpdateThreshold := config.StreamWindowUpdateThreshold
+	if streamWindowUpdateThreshold <= 0 || streamWindowUpdateThreshold >= 1 {
+		streamWindowUpdateThreshold = protocol.WindowUpdateThreshold
+	}
 	maxIncomingStreams := config.MaxIncomingStreams
 	if maxIncomingStreams == 0 {
 		maxIncomingStreams = protocol.DefaultMaxIncomingStreams
@@ -67,21 +85,313 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxUndecryptablePackets := config.MaxUndecryptablePackets
+	if maxUndecryptablePackets <= 0 {
+		maxUndecryptablePackets = protocol.MaxUndecryptablePackets
+	}
+	maxAckRanges := config.MaxAckRanges
+	if maxAckRanges <= 0 {
+		maxAckRanges = protocol.MaxNumAckRanges
+	}
+	maxIssuedConnectionIDs := config.MaxIssuedConnectionIDs
+	if maxIssuedConnectionIDs <= 0 {
+		maxIssuedConnectionIDs = protocol.DefaultMaxIssuedConnectionIDs
+	}
+	packetReorderingThreshold := config.PacketReorderingThreshold
+	if packetReorderingThreshold <= 0 {
+		packetReorderingThreshold = protocol.DefaultPacketReorderingThreshold
+	}
+	maxAutoIncomingStreams := config.MaxAutoIncomingStreams
+	if maxAutoIncomingStreams < 0 {
+		maxAutoIncomingStreams = 0
+	}
+	maxAutoIncomingUniStreams := config.MaxAutoIncomingUniStreams
+	if maxAutoIncomingUniStreams < 0 {
+		maxAutoIncomingUniStreams = 0
+	}
 
 	return &Config{
 		Versions:                              versions,
 		HandshakeTimeout:                      handshakeTimeout,
+		HandshakeIdleTimeout:                  config.HandshakeIdleTimeout,
 		MaxIdleTimeout:                        idleTimeout,
+		MinIdleTimeout:                        config.MinIdleTimeout,
+		SendCloseOnIdleTimeout:                config.SendCloseOnIdleTimeout,
+		MaxProbeTimeout:                       config.MaxProbeTimeout,
+		MaxConnectionLifetime:                 config.MaxConnectionLifetime,
 		AcceptToken:                           config.AcceptToken,
+		RequireAddressValidation:              config.RequireAddressValidation,
+		GenerateToken:                         config.GenerateToken,
+		ValidateToken:                         config.ValidateToken,
+		Allow0RTT:                             config.Allow0RTT,
+		Accept0RTTTransportParameters:         config.Accept0RTTTransportParameters,
+		TokenReplayCache:                      config.TokenReplayCache,
 		KeepAlive:                             config.KeepAlive,
+		NATKeepAlivePeriod:                    config.NATKeepAlivePeriod,
+		HealthCheckInterval:                   config.HealthCheckInterval,
+		EnableAckFrequency:                    config.EnableAckFrequency,
+		AckFrequencyPacketTolerance:           config.AckFrequencyPacketTolerance,
+		ImmediateAckAfterIdle:                 config.ImmediateAckAfterIdle,
+		MaxUndecryptablePackets:               maxUndecryptablePackets,
+		MaxAckRanges:                          maxAckRanges,
+		PacketReorderingThreshold:             packetReorderingThreshold,
+		EarlyRetransmit:                       config.EarlyRetransmit,
+		DisableSpinBit:                        config.DisableSpinBit,
+		DisableMaxPacketSizeParameter:         config.DisableMaxPacketSizeParameter,
+		TransportParameterOrder:               config.TransportParameterOrder,
+		ReceiveBufferSize:                     config.ReceiveBufferSize,
+		SendBufferSize:                        config.SendBufferSize,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
+		ReceiveWindowGrowthFactor:             receiveWindowGrowthFactor,
+		EnableBDPWindowAutoTuning:             config.EnableBDPWindowAutoTuning,
+		StreamWindowUpdateThreshold:           streamWindowUpdateThreshold,
 		MaxIncomingStreams:                    maxIncomingStreams,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
+		MaxAutoIncomingStreams:                maxAutoIncomingStreams,
+		MaxAutoIncomingUniStreams:             maxAutoIncomingUniStreams,
+		AllowStreamLimitIncrease:              config.AllowStreamLimitIncrease,
 		ConnectionIDLength:                    config.ConnectionIDLength,
+		GenerateConnectionID:                  config.GenerateConnectionID,
+		RetryConnectionIDLength:               config.RetryConnectionIDLength,
+		MaxIssuedConnectionIDs:                maxIssuedConnectionIDs,
 		StatelessResetKey:                     config.StatelessResetKey,
 		TokenStore:                            config.TokenStore,
 		QuicTracer:                            config.QuicTracer,
+		PacketTimestampTracer:                 config.PacketTimestampTracer,
+		OnConnectionClose:                     config.OnConnectionClose,
 		GetLogWriter:                          config.GetLogWriter,
+		NewTLSConn:                            config.NewTLSConn,
+	}
+}
+
+// getConnectionIDGenerator returns the function used to generate connection IDs.
+// If the application configured a GenerateConnectionID callback, the connection IDs
+// it generates are checked to have the requested length.
+func getConnectionIDGenerator(config *Config) func(int) (protocol.ConnectionID, error) {
+	if config.GenerateConnectionID == nil {
+		return generateConnectionID
+	}
+	return func(l int) (protocol.ConnectionID, error) {
+		if l == 0 {
+			return protocol.ConnectionID{}, nil
+		}
+		b, err := config.GenerateConnectionID(l)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != l {
+			return nil, fmt.Errorf("generated connection ID has invalid length (expected %d, got %d)", l, len(b))
+		}
+		return protocol.ConnectionID(b), nil
+	}
+}
+
+// configJSON is the JSON representation of a Config.
+// Durations are encoded as strings, e.g. "30s".
+type configJSON struct {
+	Versions                              []VersionNumber `json:"versions,omitempty"`
+	ConnectionIDLength                    int             `json:"connection_id_length,omitempty"`
+	RetryConnectionIDLength               int             `json:"retry_connection_id_length,omitempty"`
+	MaxIssuedConnectionIDs                int             `json:"max_issued_connection_ids,omitempty"`
+	HandshakeTimeout                      string          `json:"handshake_timeout,omitempty"`
+	HandshakeIdleTimeout                  string          `json:"handshake_idle_timeout,omitempty"`
+	MaxIdleTimeout                        string          `json:"max_idle_timeout,omitempty"`
+	MinIdleTimeout                        string          `json:"min_idle_timeout,omitempty"`
+	SendCloseOnIdleTimeout                bool            `json:"send_close_on_idle_timeout,omitempty"`
+	MaxProbeTimeout                       string          `json:"max_probe_timeout,omitempty"`
+	MaxConnectionLifetime                 string          `json:"max_connection_lifetime,omitempty"`
+	MaxReceiveStreamFlowControlWindow     uint64          `json:"max_receive_stream_flow_control_window,omitempty"`
+	MaxReceiveConnectionFlowControlWindow uint64          `json:"max_receive_connection_flow_control_window,omitempty"`
+	ReceiveWindowGrowthFactor             int             `json:"receive_window_growth_factor,omitempty"`
+	EnableBDPWindowAutoTuning             bool            `json:"enable_bdp_window_auto_tuning,omitempty"`
+	StreamWindowUpdateThreshold           float64         `json:"stream_window_update_threshold,omitempty"`
+	MaxIncomingStreams                    int             `json:"max_incoming_streams,omitempty"`
+	MaxIncomingUniStreams                 int             `json:"max_incoming_uni_streams,omitempty"`
+	MaxAutoIncomingStreams                int             `json:"max_auto_incoming_streams,omitempty"`
+	MaxAutoIncomingUniStreams             int             `json:"max_auto_incoming_uni_streams,omitempty"`
+	StatelessResetKey                     []byte          `json:"stateless_reset_key,omitempty"`
+	KeepAlive                             bool            `json:"keep_alive,omitempty"`
+	NATKeepAlivePeriod                    string          `json:"nat_keep_alive_period,omitempty"`
+	HealthCheckInterval                   string          `json:"health_check_interval,omitempty"`
+	EnableAckFrequency                    bool            `json:"enable_ack_frequency,omitempty"`
+	AckFrequencyPacketTolerance           int             `json:"ack_frequency_packet_tolerance,omitempty"`
+	ImmediateAckAfterIdle                 bool            `json:"immediate_ack_after_idle,omitempty"`
+	MaxUndecryptablePackets               int             `json:"max_undecryptable_packets,omitempty"`
+	MaxAckRanges                          int             `json:"max_ack_ranges,omitempty"`
+	PacketReorderingThreshold             int             `json:"packet_reordering_threshold,omitempty"`
+	EarlyRetransmit                       bool            `json:"early_retransmit,omitempty"`
+	DisableSpinBit                        bool            `json:"disable_spin_bit,omitempty"`
+	DisableMaxPacketSizeParameter         bool            `json:"disable_max_packet_size_parameter,omitempty"`
+	TransportParameterOrder               []uint64        `json:"transport_parameter_order,omitempty"`
+	ReceiveBufferSize                     int             `json:"receive_buffer_size,omitempty"`
+	SendBufferSize                        int             `json:"send_buffer_size,omitempty"`
+}
+
+// MarshalJSON encodes the Config as JSON.
+// Function fields (AcceptToken, RequireAddressValidation, GenerateToken, ValidateToken, Allow0RTT, Accept0RTTTransportParameters, AllowStreamLimitIncrease, GenerateConnectionID, OnConnectionClose, GetLogWriter, NewTLSConn), the TokenStore, the TokenReplayCache, the QuicTracer and the PacketTimestampTracer are not encoded.
+// To serialize the effective configuration, marshal a Config that has all default values set.
+func (c Config) MarshalJSON() ([]byte, error) {
+	j := &configJSON{
+		Versions:                              c.Versions,
+		ConnectionIDLength:                    c.ConnectionIDLength,
+		RetryConnectionIDLength:               c.RetryConnectionIDLength,
+		MaxIssuedConnectionIDs:                c.MaxIssuedConnectionIDs,
+		MaxReceiveStreamFlowControlWindow:     c.MaxReceiveStreamFlowControlWindow,
+		MaxReceiveConnectionFlowControlWindow: c.MaxReceiveConnectionFlowControlWindow,
+		ReceiveWindowGrowthFactor:             c.ReceiveWindowGrowthFactor,
+		EnableBDPWindowAutoTuning:             c.EnableBDPWindowAutoTuning,
+		StreamWindowUpdateThreshold:           c.StreamWindowUpdateThreshold,
+		MaxIncomingStreams:                    c.MaxIncomingStreams,
+		MaxIncomingUniStreams:                 c.MaxIncomingUniStreams,
+		MaxAutoIncomingStreams:                c.MaxAutoIncomingStreams,
+		MaxAutoIncomingUniStreams:             c.MaxAutoIncomingUniStreams,
+		StatelessResetKey:                     c.StatelessResetKey,
+		SendCloseOnIdleTimeout:                c.SendCloseOnIdleTimeout,
+		KeepAlive:                             c.KeepAlive,
+		EnableAckFrequency:                    c.EnableAckFrequency,
+		AckFrequencyPacketTolerance:           c.AckFrequencyPacketTolerance,
+		ImmediateAckAfterIdle:                 c.ImmediateAckAfterIdle,
+		MaxUndecryptablePackets:               c.MaxUndecryptablePackets,
+		MaxAckRanges:                          c.MaxAckRanges,
+		PacketReorderingThreshold:             c.PacketReorderingThreshold,
+		EarlyRetransmit:                       c.EarlyRetransmit,
+		DisableSpinBit:                        c.DisableSpinBit,
+		DisableMaxPacketSizeParameter:         c.DisableMaxPacketSizeParameter,
+		TransportParameterOrder:               c.TransportParameterOrder,
+		ReceiveBufferSize:                     c.ReceiveBufferSize,
+		SendBufferSize:                        c.SendBufferSize,
+	}
+	if c.HandshakeTimeout != 0 {
+		j.HandshakeTimeout = c.HandshakeTimeout.String()
+	}
+	if c.HandshakeIdleTimeout != 0 {
+		j.HandshakeIdleTimeout = c.HandshakeIdleTimeout.String()
+	}
+	if c.MaxIdleTimeout != 0 {
+		j.MaxIdleTimeout = c.MaxIdleTimeout.String()
+	}
+	if c.MinIdleTimeout != 0 {
+		j.MinIdleTimeout = c.MinIdleTimeout.String()
+	}
+	if c.MaxProbeTimeout != 0 {
+		j.MaxProbeTimeout = c.MaxProbeTimeout.String()
+	}
+	if c.MaxConnectionLifetime != 0 {
+		j.MaxConnectionLifetime = c.MaxConnectionLifetime.String()
+	}
+	if c.NATKeepAlivePeriod != 0 {
+		j.NATKeepAlivePeriod = c.NATKeepAlivePeriod.String()
+	}
+	if c.HealthCheckInterval != 0 {
+		j.HealthCheckInterval = c.HealthCheckInterval.String()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a Config that was encoded by MarshalJSON.
+// Fields that are not encoded (see MarshalJSON) are left unchanged.
+// It returns an error if the decoded values are invalid.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var j configJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	handshakeTimeout, err := parseConfigDuration(j.HandshakeTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid handshake_timeout: %s", err)
+	}
+	handshakeIdleTimeout, err := parseConfigDuration(j.HandshakeIdleTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid handshake_idle_timeout: %s", err)
+	}
+	idleTimeout, err := parseConfigDuration(j.MaxIdleTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid max_idle_timeout: %s", err)
+	}
+	minIdleTimeout, err := parseConfigDuration(j.MinIdleTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid min_idle_timeout: %s", err)
+	}
+	probeTimeout, err := parseConfigDuration(j.MaxProbeTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid max_probe_timeout: %s", err)
+	}
+	maxConnectionLifetime, err := parseConfigDuration(j.MaxConnectionLifetime)
+	if err != nil {
+		return fmt.Errorf("invalid max_connection_lifetime: %s", err)
+	}
+	natKeepAlivePeriod, err := parseConfigDuration(j.NATKeepAlivePeriod)
+	if err != nil {
+		return fmt.Errorf("invalid nat_keep_alive_period: %s", err)
+	}
+	healthCheckInterval, err := parseConfigDuration(j.HealthCheckInterval)
+	if err != nil {
+		return fmt.Errorf("invalid health_check_interval: %s", err)
+	}
+	for _, v := range j.Versions {
+		if !protocol.IsValidVersion(v) {
+			return fmt.Errorf("invalid QUIC version: %s", v)
+		}
+	}
+	if j.ConnectionIDLength != 0 && (j.ConnectionIDLength < 4 || j.ConnectionIDLength > 18) {
+		return fmt.Errorf("invalid connection ID length: %d", j.ConnectionIDLength)
+	}
+	if j.RetryConnectionIDLength < 0 || j.RetryConnectionIDLength > protocol.MaxConnIDLen {
+		return fmt.Errorf("invalid Retry connection ID length: %d", j.RetryConnectionIDLength)
+	}
+	if j.ReceiveBufferSize < 0 || j.SendBufferSize < 0 {
+		return errors.New("buffer sizes must not be negative")
+	}
+	c.Versions = j.Versions
+	c.ConnectionIDLength = j.ConnectionIDLength
+	c.RetryConnectionIDLength = j.RetryConnectionIDLength
+	c.MaxIssuedConnectionIDs = j.MaxIssuedConnectionIDs
+	c.HandshakeTimeout = handshakeTimeout
+	c.HandshakeIdleTimeout = handshakeIdleTimeout
+	c.MaxIdleTimeout = idleTimeout
+	c.MinIdleTimeout = minIdleTimeout
+	c.SendCloseOnIdleTimeout = j.SendCloseOnIdleTimeout
+	c.MaxProbeTimeout = probeTimeout
+	c.MaxConnectionLifetime = maxConnectionLifetime
+	c.MaxReceiveStreamFlowControlWindow = j.MaxReceiveStreamFlowControlWindow
+	c.MaxReceiveConnectionFlowControlWindow = j.MaxReceiveConnectionFlowControlWindow
+	c.ReceiveWindowGrowthFactor = j.ReceiveWindowGrowthFactor
+	c.EnableBDPWindowAutoTuning = j.EnableBDPWindowAutoTuning
+	c.StreamWindowUpdateThreshold = j.StreamWindowUpdateThreshold
+	c.MaxIncomingStreams = j.MaxIncomingStreams
+	c.MaxIncomingUniStreams = j.MaxIncomingUniStreams
+	c.MaxAutoIncomingStreams = j.MaxAutoIncomingStreams
+	c.MaxAutoIncomingUniStreams = j.MaxAutoIncomingUniStreams
+	c.StatelessResetKey = j.StatelessResetKey
+	c.KeepAlive = j.KeepAlive
+	c.NATKeepAlivePeriod = natKeepAlivePeriod
+	c.HealthCheckInterval = healthCheckInterval
+	c.EnableAckFrequency = j.EnableAckFrequency
+	c.AckFrequencyPacketTolerance = j.AckFrequencyPacketTolerance
+	c.ImmediateAckAfterIdle = j.ImmediateAckAfterIdle
+	c.MaxUndecryptablePackets = j.MaxUndecryptablePackets
+	c.MaxAckRanges = j.MaxAckRanges
+	c.PacketReorderingThreshold = j.PacketReorderingThreshold
+	c.EarlyRetransmit = j.EarlyRetransmit
+	c.DisableSpinBit = j.DisableSpinBit
+	c.DisableMaxPacketSizeParameter = j.DisableMaxPacketSizeParameter
+	c.TransportParameterOrder = j.TransportParameterOrder
+	c.ReceiveBufferSize = j.ReceiveBufferSize
+	c.SendBufferSize = j.SendBufferSize
+	return nil
+}
+
+func parseConfigDuration(s string) (time.Duration, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration: %s", s)
 	}
+	return d, nil
 }
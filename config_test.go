@@ -1,6 +1,9 @@
 package quic
 
 import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -28,32 +31,88 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "AcceptToken", "GetLogWriter":
+			case "AcceptToken", "RequireAddressValidation", "GenerateToken", "ValidateToken", "Allow0RTT", "Accept0RTTTransportParameters", "AllowStreamLimitIncrease", "GenerateConnectionID", "OnConnectionClose", "GetLogWriter", "NewTLSConn":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
 			case "ConnectionIDLength":
 				f.Set(reflect.ValueOf(8))
+			case "RetryConnectionIDLength":
+				f.Set(reflect.ValueOf(15))
+			case "MaxIssuedConnectionIDs":
+				f.Set(reflect.ValueOf(3))
 			case "HandshakeTimeout":
 				f.Set(reflect.ValueOf(time.Second))
+			case "HandshakeIdleTimeout":
+				f.Set(reflect.ValueOf(3 * time.Second))
 			case "MaxIdleTimeout":
 				f.Set(reflect.ValueOf(time.Hour))
+			case "MinIdleTimeout":
+				f.Set(reflect.ValueOf(5 * time.Minute))
+			case "SendCloseOnIdleTimeout":
+				f.Set(reflect.ValueOf(true))
+			case "MaxProbeTimeout":
+				f.Set(reflect.ValueOf(2 * time.Second))
+			case "MaxConnectionLifetime":
+				f.Set(reflect.ValueOf(12 * time.Hour))
 			case "TokenStore":
 				f.Set(reflect.ValueOf(NewLRUTokenStore(2, 3)))
+			case "TokenReplayCache":
+				f.Set(reflect.ValueOf(NewTokenReplayCache(4)))
 			case "MaxReceiveStreamFlowControlWindow":
 				f.Set(reflect.ValueOf(uint64(9)))
 			case "MaxReceiveConnectionFlowControlWindow":
 				f.Set(reflect.ValueOf(uint64(10)))
+			case "ReceiveWindowGrowthFactor":
+				f.Set(reflect.ValueOf(4))
+			case "EnableBDPWindowAutoTuning":
+				f.Set(reflect.ValueOf(true))
+			case "StreamWindowUpdateThreshold":
+				f.Set(reflect.ValueOf(0.6))
 			case "MaxIncomingStreams":
 				f.Set(reflect.ValueOf(11))
 			case "MaxIncomingUniStreams":
 				f.Set(reflect.ValueOf(12))
+			case "MaxAutoIncomingStreams":
+				f.Set(reflect.ValueOf(13))
+			case "MaxAutoIncomingUniStreams":
+				f.Set(reflect.ValueOf(14))
 			case "StatelessResetKey":
 				f.Set(reflect.ValueOf([]byte{1, 2, 3, 4}))
 			case "KeepAlive":
 				f.Set(reflect.ValueOf(true))
+			case "NATKeepAlivePeriod":
+				f.Set(reflect.ValueOf(25 * time.Second))
+			case "HealthCheckInterval":
+				f.Set(reflect.ValueOf(26 * time.Second))
+			case "EnableAckFrequency":
+				f.Set(reflect.ValueOf(true))
+			case "AckFrequencyPacketTolerance":
+				f.Set(reflect.ValueOf(17))
+			case "ImmediateAckAfterIdle":
+				f.Set(reflect.ValueOf(true))
+			case "MaxUndecryptablePackets":
+				f.Set(reflect.ValueOf(12))
+			case "MaxAckRanges":
+				f.Set(reflect.ValueOf(16))
+			case "PacketReorderingThreshold":
+				f.Set(reflect.ValueOf(6))
+			case "EarlyRetransmit":
+				f.Set(reflect.ValueOf(true))
+			case "DisableSpinBit":
+				f.Set(reflect.ValueOf(true))
+			case "DisableMaxPacketSizeParameter":
+				f.Set(reflect.ValueOf(true))
+			case "TransportParameterOrder":
+				f.Set(reflect.ValueOf([]uint64{0x4, 0x1}))
+			case "ReceiveBufferSize":
+				f.Set(reflect.ValueOf(1 << 20))
+			case "SendBufferSize":
+				f.Set(reflect.ValueOf(1 << 21))
 			case "QuicTracer":
 				f.Set(reflect.ValueOf(quictrace.NewTracer()))
+			case "PacketTimestampTracer":
+				f.Set(reflect.ValueOf(&packetTimestampRecorder{}))
 			default:
 				Fail(fmt.Sprintf("all fields must be accounted for, but saw unknown field %q", fn))
 			}
@@ -62,16 +121,43 @@ var _ = Describe("Config", func() {
 	}
 	Context("cloning", func() {
 		It("clones function fields", func() {
-			var calledAcceptToken, calledGetLogWriter bool
+			var calledAcceptToken, calledRequireAddressValidation, calledGenerateToken, calledValidateToken, calledAllow0RTT, calledAccept0RTTTransportParameters, calledAllowStreamLimitIncrease, calledGenerateConnectionID, calledOnConnectionClose, calledGetLogWriter, calledNewTLSConn bool
 			c1 := &Config{
-				AcceptToken:  func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
-				GetLogWriter: func(connectionID []byte) io.WriteCloser { calledGetLogWriter = true; return nil },
+				AcceptToken:                   func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
+				RequireAddressValidation:      func(net.Addr) bool { calledRequireAddressValidation = true; return true },
+				GenerateToken:                 func(net.Addr, bool, []byte) ([]byte, error) { calledGenerateToken = true; return nil, nil },
+				ValidateToken:                 func(net.Addr, []byte) (*Token, []byte, error) { calledValidateToken = true; return nil, nil, nil },
+				Allow0RTT:                     func(*ClientInfo) bool { calledAllow0RTT = true; return true },
+				Accept0RTTTransportParameters: func(_, _ *ZeroRTTTransportParameters) bool { calledAccept0RTTTransportParameters = true; return true },
+				AllowStreamLimitIncrease:      func(bool, int) bool { calledAllowStreamLimitIncrease = true; return true },
+				GenerateConnectionID:          func(int) ([]byte, error) { calledGenerateConnectionID = true; return nil, nil },
+				OnConnectionClose:             func(ConnectionCloseInfo) { calledOnConnectionClose = true },
+				GetLogWriter:                  func(connectionID []byte) io.WriteCloser { calledGetLogWriter = true; return nil },
+				NewTLSConn:                    func(*tls.Config, TLSRecordLayer, []byte, bool) TLSConn { calledNewTLSConn = true; return nil },
 			}
 			c2 := c1.Clone()
 			c2.AcceptToken(&net.UDPAddr{}, &Token{})
+			c2.RequireAddressValidation(&net.UDPAddr{})
+			c2.GenerateToken(&net.UDPAddr{}, true, nil)
+			c2.ValidateToken(&net.UDPAddr{}, nil)
+			c2.Allow0RTT(&ClientInfo{})
+			c2.Accept0RTTTransportParameters(&ZeroRTTTransportParameters{}, &ZeroRTTTransportParameters{})
+			c2.AllowStreamLimitIncrease(true, 10)
+			c2.GenerateConnectionID(4)
+			c2.OnConnectionClose(ConnectionCloseInfo{})
 			c2.GetLogWriter([]byte{1, 2, 3})
+			c2.NewTLSConn(nil, nil, nil, true)
 			Expect(calledAcceptToken).To(BeTrue())
+			Expect(calledRequireAddressValidation).To(BeTrue())
+			Expect(calledGenerateToken).To(BeTrue())
+			Expect(calledValidateToken).To(BeTrue())
+			Expect(calledAllow0RTT).To(BeTrue())
+			Expect(calledAccept0RTTTransportParameters).To(BeTrue())
+			Expect(calledAllowStreamLimitIncrease).To(BeTrue())
+			Expect(calledGenerateConnectionID).To(BeTrue())
+			Expect(calledOnConnectionClose).To(BeTrue())
 			Expect(calledGetLogWriter).To(BeTrue())
+			Expect(calledNewTLSConn).To(BeTrue())
 		})
 
 		It("clones non-function fields", func() {
@@ -95,16 +181,43 @@ var _ = Describe("Config", func() {
 
 	Context("populating", func() {
 		It("populates function fields", func() {
-			var calledAcceptToken, calledGetLogWriter bool
+			var calledAcceptToken, calledRequireAddressValidation, calledGenerateToken, calledValidateToken, calledAllow0RTT, calledAccept0RTTTransportParameters, calledAllowStreamLimitIncrease, calledGenerateConnectionID, calledOnConnectionClose, calledGetLogWriter, calledNewTLSConn bool
 			c1 := &Config{
-				AcceptToken:  func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
-				GetLogWriter: func(connectionID []byte) io.WriteCloser { calledGetLogWriter = true; return nil },
+				AcceptToken:                   func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
+				RequireAddressValidation:      func(net.Addr) bool { calledRequireAddressValidation = true; return true },
+				GenerateToken:                 func(net.Addr, bool, []byte) ([]byte, error) { calledGenerateToken = true; return nil, nil },
+				ValidateToken:                 func(net.Addr, []byte) (*Token, []byte, error) { calledValidateToken = true; return nil, nil, nil },
+				Allow0RTT:                     func(*ClientInfo) bool { calledAllow0RTT = true; return true },
+				Accept0RTTTransportParameters: func(_, _ *ZeroRTTTransportParameters) bool { calledAccept0RTTTransportParameters = true; return true },
+				AllowStreamLimitIncrease:      func(bool, int) bool { calledAllowStreamLimitIncrease = true; return true },
+				GenerateConnectionID:          func(int) ([]byte, error) { calledGenerateConnectionID = true; return nil, nil },
+				OnConnectionClose:             func(ConnectionCloseInfo) { calledOnConnectionClose = true },
+				GetLogWriter:                  func(connectionID []byte) io.WriteCloser { calledGetLogWriter = true; return nil },
+				NewTLSConn:                    func(*tls.Config, TLSRecordLayer, []byte, bool) TLSConn { calledNewTLSConn = true; return nil },
 			}
 			c2 := populateConfig(c1)
 			c2.AcceptToken(&net.UDPAddr{}, &Token{})
+			c2.RequireAddressValidation(&net.UDPAddr{})
+			c2.GenerateToken(&net.UDPAddr{}, true, nil)
+			c2.ValidateToken(&net.UDPAddr{}, nil)
+			c2.Allow0RTT(&ClientInfo{})
+			c2.Accept0RTTTransportParameters(&ZeroRTTTransportParameters{}, &ZeroRTTTransportParameters{})
+			c2.AllowStreamLimitIncrease(true, 10)
+			c2.GenerateConnectionID(4)
+			c2.OnConnectionClose(ConnectionCloseInfo{})
 			c2.GetLogWriter([]byte{1, 2, 3})
+			c2.NewTLSConn(nil, nil, nil, true)
 			Expect(calledAcceptToken).To(BeTrue())
+			Expect(calledRequireAddressValidation).To(BeTrue())
+			Expect(calledGenerateToken).To(BeTrue())
+			Expect(calledValidateToken).To(BeTrue())
+			Expect(calledAllow0RTT).To(BeTrue())
+			Expect(calledAccept0RTTTransportParameters).To(BeTrue())
+			Expect(calledAllowStreamLimitIncrease).To(BeTrue())
+			Expect(calledGenerateConnectionID).To(BeTrue())
+			Expect(calledOnConnectionClose).To(BeTrue())
 			Expect(calledGetLogWriter).To(BeTrue())
+			Expect(calledNewTLSConn).To(BeTrue())
 		})
 
 		It("copies non-function fields", func() {
@@ -118,13 +231,20 @@ var _ = Describe("Config", func() {
 			Expect(c.HandshakeTimeout).To(Equal(protocol.DefaultHandshakeTimeout))
 			Expect(c.MaxReceiveStreamFlowControlWindow).To(BeEquivalentTo(protocol.DefaultMaxReceiveStreamFlowControlWindow))
 			Expect(c.MaxReceiveConnectionFlowControlWindow).To(BeEquivalentTo(protocol.DefaultMaxReceiveConnectionFlowControlWindow))
+			Expect(c.ReceiveWindowGrowthFactor).To(Equal(protocol.DefaultReceiveWindowGrowthFactor))
+			Expect(c.StreamWindowUpdateThreshold).To(Equal(protocol.WindowUpdateThreshold))
 			Expect(c.MaxIncomingStreams).To(Equal(protocol.DefaultMaxIncomingStreams))
 			Expect(c.MaxIncomingUniStreams).To(Equal(protocol.DefaultMaxIncomingUniStreams))
+			Expect(c.MaxUndecryptablePackets).To(Equal(protocol.MaxUndecryptablePackets))
+			Expect(c.MaxAckRanges).To(Equal(protocol.MaxNumAckRanges))
+			Expect(c.PacketReorderingThreshold).To(Equal(protocol.DefaultPacketReorderingThreshold))
+			Expect(c.MaxIssuedConnectionIDs).To(Equal(protocol.DefaultMaxIssuedConnectionIDs))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
 			c := populateServerConfig(&Config{})
 			Expect(c.ConnectionIDLength).To(Equal(protocol.DefaultConnectionIDLength))
+			Expect(c.RetryConnectionIDLength).To(Equal(protocol.DefaultConnectionIDLength))
 			Expect(c.AcceptToken).ToNot(BeNil())
 		})
 
@@ -138,4 +258,109 @@ var _ = Describe("Config", func() {
 			Expect(c.ConnectionIDLength).To(BeZero())
 		})
 	})
+
+	Context("generating connection IDs", func() {
+		It("generates random connection IDs if no generator is configured", func() {
+			c1, err := getConnectionIDGenerator(&Config{})(8)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c1.Len()).To(Equal(8))
+			c2, err := getConnectionIDGenerator(&Config{})(8)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c2).ToNot(Equal(c1))
+		})
+
+		It("uses the configured generator", func() {
+			generate := getConnectionIDGenerator(&Config{
+				GenerateConnectionID: func(l int) ([]byte, error) {
+					Expect(l).To(Equal(5))
+					return []byte{1, 2, 3, 4, 5}, nil
+				},
+			})
+			connID, err := generate(5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID).To(Equal(protocol.ConnectionID{1, 2, 3, 4, 5}))
+		})
+
+		It("doesn't call the configured generator for zero-length connection IDs", func() {
+			generate := getConnectionIDGenerator(&Config{
+				GenerateConnectionID: func(int) ([]byte, error) {
+					Fail("generator called")
+					return nil, nil
+				},
+			})
+			connID, err := generate(0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID.Len()).To(BeZero())
+		})
+
+		It("returns errors from the configured generator", func() {
+			testErr := errors.New("test error")
+			generate := getConnectionIDGenerator(&Config{
+				GenerateConnectionID: func(int) ([]byte, error) { return nil, testErr },
+			})
+			_, err := generate(5)
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("rejects connection IDs with the wrong length", func() {
+			generate := getConnectionIDGenerator(&Config{
+				GenerateConnectionID: func(int) ([]byte, error) { return []byte{1, 2, 3}, nil },
+			})
+			_, err := generate(5)
+			Expect(err).To(MatchError("generated connection ID has invalid length (expected 5, got 3)"))
+		})
+	})
+
+	Context("JSON", func() {
+		It("round-trips all serializable fields", func() {
+			c1 := configWithNonZeroNonFunctionFields()
+			c1.Versions = protocol.SupportedVersions
+			// the TokenStore, the TokenReplayCache, the QuicTracer and the PacketTimestampTracer are not serialized
+			c1.TokenStore = nil
+			c1.TokenReplayCache = nil
+			c1.QuicTracer = nil
+			c1.PacketTimestampTracer = nil
+			data, err := json.Marshal(c1)
+			Expect(err).ToNot(HaveOccurred())
+			c2 := &Config{}
+			Expect(json.Unmarshal(data, c2)).To(Succeed())
+			Expect(c2).To(Equal(c1))
+		})
+
+		It("encodes durations as strings", func() {
+			data, err := json.Marshal(&Config{HandshakeTimeout: 5 * time.Second, MaxIdleTimeout: 90 * time.Minute})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"handshake_timeout":"5s","max_idle_timeout":"1h30m0s"}`))
+		})
+
+		It("doesn't overwrite function fields", func() {
+			c := &Config{AcceptToken: func(net.Addr, *Token) bool { return true }}
+			Expect(json.Unmarshal([]byte(`{"keep_alive":true}`), c)).To(Succeed())
+			Expect(c.KeepAlive).To(BeTrue())
+			Expect(c.AcceptToken).ToNot(BeNil())
+		})
+
+		It("rejects invalid durations", func() {
+			Expect(json.Unmarshal([]byte(`{"max_idle_timeout":"foobar"}`), &Config{})).To(MatchError(ContainSubstring("invalid max_idle_timeout")))
+			Expect(json.Unmarshal([]byte(`{"handshake_timeout":"-1s"}`), &Config{})).To(MatchError("invalid handshake_timeout: negative duration: -1s"))
+			Expect(json.Unmarshal([]byte(`{"handshake_idle_timeout":"1x"}`), &Config{})).To(MatchError(ContainSubstring("invalid handshake_idle_timeout")))
+		})
+
+		It("rejects invalid versions", func() {
+			Expect(json.Unmarshal([]byte(`{"versions":[42]}`), &Config{})).To(MatchError("invalid QUIC version: 0x2a"))
+		})
+
+		It("rejects invalid connection ID lengths", func() {
+			Expect(json.Unmarshal([]byte(`{"connection_id_length":3}`), &Config{})).To(MatchError("invalid connection ID length: 3"))
+			Expect(json.Unmarshal([]byte(`{"connection_id_length":19}`), &Config{})).To(MatchError("invalid connection ID length: 19"))
+		})
+
+		It("rejects invalid Retry connection ID lengths", func() {
+			Expect(json.Unmarshal([]byte(`{"retry_connection_id_length":21}`), &Config{})).To(MatchError("invalid Retry connection ID length: 21"))
+		})
+
+		It("rejects negative buffer sizes", func() {
+			Expect(json.Unmarshal([]byte(`{"send_buffer_size":-1}`), &Config{})).To(MatchError("buffer sizes must not be negative"))
+		})
+	})
 })
@@ -2,6 +2,8 @@ package quic
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -10,8 +12,15 @@ import (
 )
 
 type connIDGenerator struct {
+	// mutex protects the connection IDs, which are also read by ConnectionIDs
+	mutex sync.Mutex
+
 	connIDLen  int
 	highestSeq uint64
+	// the maximum number of connection IDs issued at the same time
+	maxIssued uint64
+
+	generateConnectionID func(int) (protocol.ConnectionID, error)
 
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	initialClientDestConnID protocol.ConnectionID
@@ -27,6 +36,8 @@ type connIDGenerator struct {
 func newConnIDGenerator(
 	initialConnectionID protocol.ConnectionID,
 	initialClientDestConnID protocol.ConnectionID, // nil for the client
+	generateConnectionID func(int) (protocol.ConnectionID, error),
+	maxIssued int,
 	addConnectionID func(protocol.ConnectionID),
 	getStatelessResetToken func(protocol.ConnectionID) [16]byte,
 	removeConnectionID func(protocol.ConnectionID),
@@ -36,6 +47,8 @@ func newConnIDGenerator(
 ) *connIDGenerator {
 	m := &connIDGenerator{
 		connIDLen:              initialConnectionID.Len(),
+		generateConnectionID:   generateConnectionID,
+		maxIssued:              uint64(maxIssued),
 		activeSrcConnIDs:       make(map[uint64]protocol.ConnectionID),
 		addConnectionID:        addConnectionID,
 		getStatelessResetToken: getStatelessResetToken,
@@ -53,13 +66,18 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	if m.connIDLen == 0 {
 		return nil
 	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	// The active_connection_id_limit transport parameter is the number of
 	// connection IDs the peer will store. This limit includes the connection ID
 	// used during the handshake, and the one sent in the preferred_address
 	// transport parameter.
 	// We currently don't send the preferred_address transport parameter,
 	// so we can issue (limit - 1) connection IDs.
-	for i := uint64(1); i < utils.MinUint64(limit, protocol.MaxIssuedConnectionIDs); i++ {
+	// This function is called twice when 0-RTT is used (first with the remembered limit,
+	// then with the limit from the handshake), so only issue the missing connection IDs.
+	for i := uint64(len(m.activeSrcConnIDs)); i < utils.MinUint64(limit, m.maxIssued); i++ {
 		if err := m.issueNewConnID(); err != nil {
 			return err
 		}
@@ -67,7 +85,15 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	return nil
 }
 
-func (m *connIDGenerator) Retire(seq uint64) error {
+// Retire retires the connection ID with the given sequence number.
+// sentWithDestConnID is the Destination Connection ID of the packet that contained the RETIRE_CONNECTION_ID frame.
+func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.ConnectionID) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.connIDLen == 0 {
+		return qerr.Error(qerr.ProtocolViolation, "received RETIRE_CONNECTION_ID frame, but we're using zero-length connection IDs")
+	}
 	if seq > m.highestSeq {
 		return qerr.Error(qerr.ProtocolViolation, fmt.Sprintf("tried to retire connection ID %d. Highest issued: %d", seq, m.highestSeq))
 	}
@@ -76,17 +102,29 @@ func (m *connIDGenerator) Retire(seq uint64) error {
 	if !ok {
 		return nil
 	}
+	if connID.Equal(sentWithDestConnID) {
+		return qerr.Error(qerr.ProtocolViolation, fmt.Sprintf("tried to retire connection ID %d (%s), which was used as the Destination Connection ID on this packet", seq, connID))
+	}
 	m.retireConnectionID(connID)
 	delete(m.activeSrcConnIDs, seq)
-	// Don't issue a replacement for the initial connection ID.
-	if seq == 0 {
+	// Don't issue a replacement for the initial connection ID,
+	// unless the peer retired all connection IDs we issued.
+	// Without a replacement, the peer wouldn't be able to send us any more packets.
+	exhausted := len(m.activeSrcConnIDs) == 0
+	if seq == 0 && !exhausted {
 		return nil
 	}
-	return m.issueNewConnID()
+	if err := m.issueNewConnID(); err != nil {
+		if exhausted {
+			return qerr.Error(qerr.InternalError, fmt.Sprintf("peer retired all connection IDs, and issuing a new one failed: %s", err))
+		}
+		return err
+	}
+	return nil
 }
 
 func (m *connIDGenerator) issueNewConnID() error {
-	connID, err := protocol.GenerateConnectionID(m.connIDLen)
+	connID, err := m.generateConnectionID(m.connIDLen)
 	if err != nil {
 		return err
 	}
@@ -101,6 +139,20 @@ func (m *connIDGenerator) issueNewConnID() error {
 	return nil
 }
 
+// ConnectionIDs returns the connection IDs that were not retired yet, ordered by sequence number,
+// as well as the highest sequence number issued.
+func (m *connIDGenerator) ConnectionIDs() ([]ConnectionIDInfo, uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	connIDs := make([]ConnectionIDInfo, 0, len(m.activeSrcConnIDs))
+	for seq, connID := range m.activeSrcConnIDs {
+		connIDs = append(connIDs, ConnectionIDInfo{SequenceNumber: seq, ConnectionID: connID})
+	}
+	sort.Slice(connIDs, func(i, j int) bool { return connIDs[i].SequenceNumber < connIDs[j].SequenceNumber })
+	return connIDs, m.highestSeq
+}
+
 func (m *connIDGenerator) SetHandshakeComplete() {
 	if m.initialClientDestConnID != nil {
 		m.retireConnectionID(m.initialClientDestConnID)
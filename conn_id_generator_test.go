@@ -1,6 +1,8 @@
 package quic
 
 import (
+	"errors"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
@@ -15,6 +17,8 @@ var _ = Describe("Connection ID Generator", func() {
 		removedConnIDs     []protocol.ConnectionID
 		replacedWithClosed map[string]packetHandler
 		queuedFrames       []wire.Frame
+		generateConnID     func(int) (protocol.ConnectionID, error)
+		maxIssued          int
 		g                  *connIDGenerator
 	)
 	initialConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7}
@@ -30,9 +34,16 @@ var _ = Describe("Connection ID Generator", func() {
 		removedConnIDs = nil
 		queuedFrames = nil
 		replacedWithClosed = make(map[string]packetHandler)
+		generateConnID = protocol.GenerateConnectionID
+		maxIssued = protocol.DefaultMaxIssuedConnectionIDs
+	})
+
+	JustBeforeEach(func() {
 		g = newConnIDGenerator(
 			initialConnID,
 			initialClientDestConnID,
+			generateConnID,
+			maxIssued,
 			func(c protocol.ConnectionID) { addedConnIDs = append(addedConnIDs, c) },
 			connIDToToken,
 			func(c protocol.ConnectionID) { removedConnIDs = append(removedConnIDs, c) },
@@ -60,22 +71,79 @@ var _ = Describe("Connection ID Generator", func() {
 		}
 	})
 
+	Context("using a custom connection ID generator", func() {
+		BeforeEach(func() {
+			var counter byte
+			generateConnID = getConnectionIDGenerator(&Config{
+				GenerateConnectionID: func(l int) ([]byte, error) {
+					counter++
+					b := make([]byte, l)
+					b[0] = 0x42
+					b[l-1] = counter
+					return b, nil
+				},
+			})
+		})
+
+		It("issues connection IDs generated by the custom generator", func() {
+			Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+			Expect(addedConnIDs).To(Equal([]protocol.ConnectionID{
+				{0x42, 0, 0, 0, 0, 0, 1},
+				{0x42, 0, 0, 0, 0, 0, 2},
+				{0x42, 0, 0, 0, 0, 0, 3},
+			}))
+			Expect(queuedFrames).To(HaveLen(3))
+			for i, f := range queuedFrames {
+				Expect(f.(*wire.NewConnectionIDFrame).ConnectionID).To(Equal(addedConnIDs[i]))
+			}
+		})
+	})
+
 	It("limits the number of connection IDs that it issues", func() {
 		Expect(g.SetMaxActiveConnIDs(9999999)).To(Succeed())
 		Expect(retiredConnIDs).To(BeEmpty())
-		Expect(addedConnIDs).To(HaveLen(protocol.MaxIssuedConnectionIDs - 1))
-		Expect(queuedFrames).To(HaveLen(protocol.MaxIssuedConnectionIDs - 1))
+		Expect(addedConnIDs).To(HaveLen(protocol.DefaultMaxIssuedConnectionIDs - 1))
+		Expect(queuedFrames).To(HaveLen(protocol.DefaultMaxIssuedConnectionIDs - 1))
+	})
+
+	Context("with a configured limit", func() {
+		BeforeEach(func() { maxIssued = 3 })
+
+		It("doesn't issue more connection IDs than configured", func() {
+			Expect(g.SetMaxActiveConnIDs(100)).To(Succeed())
+			Expect(addedConnIDs).To(HaveLen(2))
+			Expect(queuedFrames).To(HaveLen(2))
+			// retiring a connection ID doesn't increase the number of issued connection IDs
+			Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+			connIDs, _ := g.ConnectionIDs()
+			Expect(connIDs).To(HaveLen(3))
+		})
+
+		It("doesn't issue more connection IDs than the peer's limit", func() {
+			Expect(g.SetMaxActiveConnIDs(2)).To(Succeed())
+			Expect(addedConnIDs).To(HaveLen(1))
+			Expect(queuedFrames).To(HaveLen(1))
+		})
+	})
+
+	It("doesn't issue connection IDs twice when the limit is set again", func() {
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(3))
+		queuedFrames = nil
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(queuedFrames).To(BeEmpty())
+		Expect(addedConnIDs).To(HaveLen(3))
 	})
 
 	It("errors if the peers tries to retire a connection ID that wasn't yet issued", func() {
-		Expect(g.Retire(1)).To(MatchError("PROTOCOL_VIOLATION: tried to retire connection ID 1. Highest issued: 0"))
+		Expect(g.Retire(1, protocol.ConnectionID{})).To(MatchError("PROTOCOL_VIOLATION: tried to retire connection ID 1. Highest issued: 0"))
 	})
 
 	It("issues new connection IDs, when old ones are retired", func() {
 		Expect(g.SetMaxActiveConnIDs(5)).To(Succeed())
 		queuedFrames = nil
 		Expect(retiredConnIDs).To(BeEmpty())
-		Expect(g.Retire(3)).To(Succeed())
+		Expect(g.Retire(3, protocol.ConnectionID{})).To(Succeed())
 		Expect(queuedFrames).To(HaveLen(1))
 		Expect(queuedFrames[0]).To(BeAssignableToTypeOf(&wire.NewConnectionIDFrame{}))
 		nf := queuedFrames[0].(*wire.NewConnectionIDFrame)
@@ -83,26 +151,102 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(nf.ConnectionID.Len()).To(Equal(7))
 	})
 
+	It("reports the connection IDs that were not retired", func() {
+		connIDs, highestSeq := g.ConnectionIDs()
+		Expect(connIDs).To(Equal([]ConnectionIDInfo{{SequenceNumber: 0, ConnectionID: initialConnID}}))
+		Expect(highestSeq).To(BeZero())
+		Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+		Expect(addedConnIDs).To(HaveLen(2))
+		connIDs, highestSeq = g.ConnectionIDs()
+		Expect(connIDs).To(Equal([]ConnectionIDInfo{
+			{SequenceNumber: 0, ConnectionID: initialConnID},
+			{SequenceNumber: 1, ConnectionID: addedConnIDs[0]},
+			{SequenceNumber: 2, ConnectionID: addedConnIDs[1]},
+		}))
+		Expect(highestSeq).To(BeEquivalentTo(2))
+		Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+		Expect(addedConnIDs).To(HaveLen(3))
+		connIDs, highestSeq = g.ConnectionIDs()
+		Expect(connIDs).To(Equal([]ConnectionIDInfo{
+			{SequenceNumber: 0, ConnectionID: initialConnID},
+			{SequenceNumber: 2, ConnectionID: addedConnIDs[1]},
+			{SequenceNumber: 3, ConnectionID: addedConnIDs[2]},
+		}))
+		Expect(highestSeq).To(BeEquivalentTo(3))
+	})
+
 	It("retires the initial connection ID", func() {
-		Expect(g.Retire(0)).To(Succeed())
+		Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+		Expect(addedConnIDs).To(HaveLen(2))
+		Expect(g.Retire(0, protocol.ConnectionID{})).To(Succeed())
 		Expect(removedConnIDs).To(BeEmpty())
 		Expect(retiredConnIDs).To(HaveLen(1))
 		Expect(retiredConnIDs[0]).To(Equal(initialConnID))
-		Expect(addedConnIDs).To(BeEmpty())
+		Expect(addedConnIDs).To(HaveLen(2))
 	})
 
 	It("handles duplicate retirements", func() {
 		Expect(g.SetMaxActiveConnIDs(11)).To(Succeed())
 		queuedFrames = nil
 		Expect(retiredConnIDs).To(BeEmpty())
-		Expect(g.Retire(5)).To(Succeed())
+		Expect(g.Retire(5, protocol.ConnectionID{})).To(Succeed())
 		Expect(retiredConnIDs).To(HaveLen(1))
 		Expect(queuedFrames).To(HaveLen(1))
-		Expect(g.Retire(5)).To(Succeed())
+		Expect(g.Retire(5, protocol.ConnectionID{})).To(Succeed())
 		Expect(retiredConnIDs).To(HaveLen(1))
 		Expect(queuedFrames).To(HaveLen(1))
 	})
 
+	It("errors if the peer tries to retire the connection ID that the packet was sent to", func() {
+		Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+		Expect(addedConnIDs).To(HaveLen(2))
+		err := g.Retire(1, addedConnIDs[0])
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("PROTOCOL_VIOLATION: tried to retire connection ID 1"))
+		Expect(retiredConnIDs).To(BeEmpty())
+	})
+
+	It("errors if the peer retires a connection ID when using zero-length connection IDs", func() {
+		g = newConnIDGenerator(
+			protocol.ConnectionID{},
+			nil,
+			generateConnID,
+			maxIssued,
+			func(c protocol.ConnectionID) { addedConnIDs = append(addedConnIDs, c) },
+			connIDToToken,
+			func(c protocol.ConnectionID) { removedConnIDs = append(removedConnIDs, c) },
+			func(c protocol.ConnectionID) { retiredConnIDs = append(retiredConnIDs, c) },
+			func(c protocol.ConnectionID, h packetHandler) { replacedWithClosed[string(c)] = h },
+			func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
+		)
+		Expect(g.Retire(0, protocol.ConnectionID{})).To(MatchError("PROTOCOL_VIOLATION: received RETIRE_CONNECTION_ID frame, but we're using zero-length connection IDs"))
+		Expect(retiredConnIDs).To(BeEmpty())
+	})
+
+	Context("when the peer retires all connection IDs", func() {
+		BeforeEach(func() { maxIssued = 1 })
+
+		It("issues a replacement for the initial connection ID", func() {
+			Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+			Expect(addedConnIDs).To(BeEmpty())
+			Expect(g.Retire(0, protocol.ConnectionID{})).To(Succeed())
+			Expect(retiredConnIDs).To(Equal([]protocol.ConnectionID{initialConnID}))
+			Expect(addedConnIDs).To(HaveLen(1))
+			Expect(queuedFrames).To(HaveLen(1))
+			Expect(queuedFrames[0]).To(BeAssignableToTypeOf(&wire.NewConnectionIDFrame{}))
+			Expect(queuedFrames[0].(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(1))
+			connIDs, highestSeq := g.ConnectionIDs()
+			Expect(connIDs).To(Equal([]ConnectionIDInfo{{SequenceNumber: 1, ConnectionID: addedConnIDs[0]}}))
+			Expect(highestSeq).To(BeEquivalentTo(1))
+		})
+
+		It("errors if no new connection ID can be issued", func() {
+			testErr := errors.New("connection ID generation failed")
+			g.generateConnectionID = func(int) (protocol.ConnectionID, error) { return nil, testErr }
+			Expect(g.Retire(0, protocol.ConnectionID{})).To(MatchError("INTERNAL_ERROR: peer retired all connection IDs, and issuing a new one failed: connection ID generation failed"))
+		})
+	})
+
 	It("retires the client's initial destination connection ID when the handshake completes", func() {
 		g.SetHandshakeComplete()
 		Expect(retiredConnIDs).To(HaveLen(1))
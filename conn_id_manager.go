@@ -5,6 +5,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	mrand "math/rand"
+	"sort"
+	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -13,12 +15,18 @@ import (
 )
 
 type connIDManager struct {
+	// mutex protects the queue and the active connection ID, which are also read by ConnectionIDs
+	mutex sync.Mutex
+
 	queue utils.NewConnectionIDList
 
 	activeSequenceNumber      uint64
 	highestRetired            uint64
 	activeConnectionID        protocol.ConnectionID
 	activeStatelessResetToken *[16]byte
+	// retired contains the sequence numbers at or above highestRetired that were retired individually,
+	// so that duplicate NEW_CONNECTION_ID frames for them are not queued again
+	retired map[uint64]struct{}
 
 	// We change the connection ID after sending on average
 	// protocol.PacketsPerConnectionID packets. The actual value is randomized
@@ -54,10 +62,16 @@ func newConnIDManager(
 }
 
 func (h *connIDManager) AddFromPreferredAddress(connID protocol.ConnectionID, resetToken *[16]byte) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	return h.addConnectionID(1, connID, resetToken)
 }
 
 func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	if err := h.add(f); err != nil {
 		return err
 	}
@@ -70,7 +84,7 @@ func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
 func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 	// If the NEW_CONNECTION_ID frame is reordered, such that its sequence number
 	// was already retired, send the RETIRE_CONNECTION_ID frame immediately.
-	if f.SequenceNumber < h.highestRetired {
+	if _, ok := h.retired[f.SequenceNumber]; ok || f.SequenceNumber < h.highestRetired {
 		h.queueControlFrame(&wire.RetireConnectionIDFrame{
 			SequenceNumber: f.SequenceNumber,
 		})
@@ -91,7 +105,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 			})
 			h.queue.Remove(el)
 		}
-		h.highestRetired = f.RetirePriorTo
+		h.setHighestRetired(f.RetirePriorTo)
 	}
 
 	if f.SequenceNumber == h.activeSequenceNumber {
@@ -147,7 +161,8 @@ func (h *connIDManager) updateConnectionID() {
 	h.queueControlFrame(&wire.RetireConnectionIDFrame{
 		SequenceNumber: h.activeSequenceNumber,
 	})
-	h.highestRetired = utils.MaxUint64(h.highestRetired, h.activeSequenceNumber)
+	h.setHighestRetired(utils.MaxUint64(h.highestRetired, h.activeSequenceNumber))
+	h.markRetired(h.activeSequenceNumber)
 	if h.activeStatelessResetToken != nil {
 		h.retireStatelessResetToken(*h.activeStatelessResetToken)
 	}
@@ -161,6 +176,50 @@ func (h *connIDManager) updateConnectionID() {
 	h.addStatelessResetToken(*h.activeStatelessResetToken)
 }
 
+// markRetired records that the connection ID with the given sequence number was retired.
+func (h *connIDManager) markRetired(seq uint64) {
+	if seq < h.highestRetired {
+		return
+	}
+	if h.retired == nil {
+		h.retired = make(map[uint64]struct{})
+	}
+	h.retired[seq] = struct{}{}
+}
+
+func (h *connIDManager) setHighestRetired(seq uint64) {
+	h.highestRetired = seq
+	for s := range h.retired {
+		if s < seq {
+			delete(h.retired, s)
+		}
+	}
+}
+
+// Retire retires the connection ID with the given sequence number, which was issued by the peer.
+// If it is the connection ID that is currently in use, we switch to the next connection ID.
+func (h *connIDManager) Retire(seq uint64) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if seq == h.activeSequenceNumber {
+		if h.queue.Len() == 0 {
+			return fmt.Errorf("can't retire connection ID with sequence number %d: no other connection ID available", seq)
+		}
+		h.updateConnectionID()
+		return nil
+	}
+	for el := h.queue.Front(); el != nil; el = el.Next() {
+		if el.Value.SequenceNumber == seq {
+			h.queueControlFrame(&wire.RetireConnectionIDFrame{SequenceNumber: seq})
+			h.queue.Remove(el)
+			h.markRetired(seq)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown connection ID sequence number %d", seq)
+}
+
 func (h *connIDManager) Close() {
 	if h.activeStatelessResetToken != nil {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
@@ -170,6 +229,9 @@ func (h *connIDManager) Close() {
 // is called when the server performs a Retry
 // and when the server changes the connection ID in the first Initial sent
 func (h *connIDManager) ChangeInitialConnID(newConnID protocol.ConnectionID) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	if h.activeSequenceNumber != 0 {
 		panic("expected first connection ID to have sequence number 0")
 	}
@@ -202,8 +264,34 @@ func (h *connIDManager) shouldUpdateConnID() bool {
 }
 
 func (h *connIDManager) Get() protocol.ConnectionID {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	if h.shouldUpdateConnID() {
 		h.updateConnectionID()
 	}
 	return h.activeConnectionID
 }
+
+// ConnectionIDs returns the active connection ID and the connection IDs that were not retired yet,
+// ordered by sequence number, as well as the sequence number below which all connection IDs were retired.
+func (h *connIDManager) ConnectionIDs() ([]ConnectionIDInfo, uint64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	connIDs := make([]ConnectionIDInfo, 0, h.queue.Len()+1)
+	connIDs = append(connIDs, ConnectionIDInfo{
+		SequenceNumber: h.activeSequenceNumber,
+		ConnectionID:   h.activeConnectionID,
+		InUse:          true,
+	})
+	for el := h.queue.Front(); el != nil; el = el.Next() {
+		connIDs = append(connIDs, ConnectionIDInfo{
+			SequenceNumber: el.Value.SequenceNumber,
+			ConnectionID:   el.Value.ConnectionID,
+		})
+	}
+	// Reordered NEW_CONNECTION_ID frames can add connection IDs with a lower sequence number than the active one.
+	sort.Slice(connIDs, func(i, j int) bool { return connIDs[i].SequenceNumber < connIDs[j].SequenceNumber })
+	return connIDs, h.highestRetired
+}
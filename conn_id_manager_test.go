@@ -292,6 +292,137 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(retiredTokens[0]).To(Equal([16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
 	})
 
+	It("never uses a connection ID after retiring it", func() {
+		retired := make(map[uint64]bool)
+		checkNotRetired := func() {
+			for _, f := range frameQueue {
+				retired[f.(*wire.RetireConnectionIDFrame).SequenceNumber] = true
+			}
+			frameQueue = nil
+			connID := m.Get()
+			// Get might have retired the connection ID it used before
+			for _, f := range frameQueue {
+				retired[f.(*wire.RetireConnectionIDFrame).SequenceNumber] = true
+			}
+			frameQueue = nil
+			// the connection ID with sequence number s is {s, s, s, s}
+			Expect(retired).ToNot(HaveKey(uint64(connID[0])))
+		}
+
+		for s := uint8(1); s < 100; s++ {
+			var retirePriorTo uint64
+			if s > 3 {
+				retirePriorTo = uint64(s - 3)
+			}
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(s),
+				RetirePriorTo:       retirePriorTo,
+				ConnectionID:        protocol.ConnectionID{s, s, s, s},
+				StatelessResetToken: [16]byte{s, s, s, s, s, s, s, s, s, s, s, s, s, s, s, s},
+			})).To(Succeed())
+			checkNotRetired()
+			for i := 0; i < protocol.PacketsPerConnectionID; i++ {
+				m.SentPacket()
+			}
+			checkNotRetired()
+		}
+		Expect(retired).ToNot(BeEmpty())
+	})
+
+	It("retires the active connection ID on request", func() {
+		m.SetStatelessResetToken([16]byte{1})
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      1,
+			ConnectionID:        protocol.ConnectionID{1, 2, 3, 4},
+			StatelessResetToken: [16]byte{2},
+		})).To(Succeed())
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      2,
+			ConnectionID:        protocol.ConnectionID{2, 3, 4, 5},
+			StatelessResetToken: [16]byte{3},
+		})).To(Succeed())
+		frameQueue = nil
+		retiredTokens = nil
+		Expect(m.Retire(1)).To(Succeed())
+		Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		Expect(retiredTokens).To(Equal([][16]byte{{2}}))
+		Expect(*tokenAdded).To(Equal([16]byte{3}))
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 3, 4, 5}))
+	})
+
+	It("retires a connection ID that is not in use on request", func() {
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
+		})).To(Succeed())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 2,
+			ConnectionID:   protocol.ConnectionID{2, 3, 4, 5},
+		})).To(Succeed())
+		Expect(m.Retire(1)).To(Succeed())
+		Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		// The first connection ID update skips the retired connection ID.
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 3, 4, 5}))
+	})
+
+	It("doesn't use a connection ID retired on request when the NEW_CONNECTION_ID frame is received again", func() {
+		f := &wire.NewConnectionIDFrame{
+			SequenceNumber:      1,
+			ConnectionID:        protocol.ConnectionID{1, 2, 3, 4},
+			StatelessResetToken: [16]byte{1},
+		}
+		Expect(m.Add(f)).To(Succeed())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      2,
+			ConnectionID:        protocol.ConnectionID{2, 3, 4, 5},
+			StatelessResetToken: [16]byte{2},
+		})).To(Succeed())
+		Expect(m.Retire(1)).To(Succeed())
+		frameQueue = nil
+		Expect(m.Add(f)).To(Succeed())
+		Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		connIDs, _ := m.ConnectionIDs()
+		Expect(connIDs).To(HaveLen(2))
+		Expect(connIDs[1].SequenceNumber).To(BeEquivalentTo(2))
+		// The first connection ID update switches to the connection ID with sequence number 2.
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 3, 4, 5}))
+		Expect(m.queue.Len()).To(BeZero())
+	})
+
+	It("doesn't use the connection ID that was active when the NEW_CONNECTION_ID frame is received again", func() {
+		m.SetStatelessResetToken([16]byte{0})
+		f := &wire.NewConnectionIDFrame{
+			SequenceNumber:      1,
+			ConnectionID:        protocol.ConnectionID{1, 2, 3, 4},
+			StatelessResetToken: [16]byte{1},
+		}
+		Expect(m.Add(f)).To(Succeed())
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      2,
+			ConnectionID:        protocol.ConnectionID{2, 3, 4, 5},
+			StatelessResetToken: [16]byte{2},
+		})).To(Succeed())
+		Expect(m.Retire(1)).To(Succeed())
+		frameQueue = nil
+		Expect(m.Add(f)).To(Succeed())
+		Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		Expect(m.queue.Len()).To(BeZero())
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 3, 4, 5}))
+	})
+
+	It("refuses to retire the active connection ID if there's no other connection ID", func() {
+		Expect(m.Retire(0)).To(MatchError("can't retire connection ID with sequence number 0: no other connection ID available"))
+		Expect(frameQueue).To(BeEmpty())
+		Expect(m.Get()).To(Equal(initialConnID))
+	})
+
+	It("refuses to retire unknown connection IDs", func() {
+		Expect(m.Retire(42)).To(MatchError("unknown connection ID sequence number 42"))
+		Expect(frameQueue).To(BeEmpty())
+	})
+
 	It("removes the currently active stateless reset token when it is closed", func() {
 		m.Close()
 		Expect(retiredTokens).To(BeEmpty())
@@ -307,4 +438,37 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(removedTokens).To(HaveLen(1))
 		Expect(removedTokens[0]).To(Equal([16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
 	})
+
+	It("reports the connection IDs", func() {
+		connIDs, retiredBelow := m.ConnectionIDs()
+		Expect(connIDs).To(Equal([]ConnectionIDInfo{{SequenceNumber: 0, ConnectionID: initialConnID, InUse: true}}))
+		Expect(retiredBelow).To(BeZero())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
+		})).To(Succeed())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 2,
+			ConnectionID:   protocol.ConnectionID{2, 3, 4, 5},
+		})).To(Succeed())
+		connIDs, retiredBelow = m.ConnectionIDs()
+		Expect(connIDs).To(Equal([]ConnectionIDInfo{
+			{SequenceNumber: 0, ConnectionID: initialConnID, InUse: true},
+			{SequenceNumber: 1, ConnectionID: protocol.ConnectionID{1, 2, 3, 4}},
+			{SequenceNumber: 2, ConnectionID: protocol.ConnectionID{2, 3, 4, 5}},
+		}))
+		Expect(retiredBelow).To(BeZero())
+		// the peer asks us to retire connection IDs 0 and 1
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 3,
+			RetirePriorTo:  2,
+			ConnectionID:   protocol.ConnectionID{3, 4, 5, 6},
+		})).To(Succeed())
+		connIDs, retiredBelow = m.ConnectionIDs()
+		Expect(connIDs).To(Equal([]ConnectionIDInfo{
+			{SequenceNumber: 2, ConnectionID: protocol.ConnectionID{2, 3, 4, 5}, InUse: true},
+			{SequenceNumber: 3, ConnectionID: protocol.ConnectionID{3, 4, 5, 6}},
+		}))
+		Expect(retiredBelow).To(BeEquivalentTo(2))
+	})
 })
@@ -83,6 +83,16 @@ var _ = Describe("Framer", func() {
 			Expect(framer.AppendStreamFrames(nil, 1000)).To(BeEmpty())
 		})
 
+		It("says if it has active streams", func() {
+			Expect(framer.HasActiveStreams()).To(BeFalse())
+			framer.AddActiveStream(id1)
+			Expect(framer.HasActiveStreams()).To(BeTrue())
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}}, false)
+			framer.AppendStreamFrames(nil, 1000)
+			Expect(framer.HasActiveStreams()).To(BeFalse())
+		})
+
 		It("returns STREAM frames", func() {
 			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
 			f := &wire.StreamFrame{
@@ -18,6 +18,7 @@ func Fuzz(data []byte) int {
 	}
 	parser := wire.NewFrameParser(version)
 	parser.SetAckDelayExponent(protocol.DefaultAckDelayExponent)
+	parser.EnableResetStreamAt()
 
 	var encLevel protocol.EncryptionLevel
 	switch data[0] % 3 {
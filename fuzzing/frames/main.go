@@ -128,6 +128,12 @@ func getFrames() []wire.Frame {
 			ErrorCode:  quic.ErrorCode(getRandomNumber()),
 			ByteOffset: protocol.MaxByteCount,
 		},
+		&wire.ResetStreamFrame{ // RESET_STREAM_AT
+			StreamID:     protocol.StreamID(getRandomNumber()),
+			ErrorCode:    quic.ErrorCode(getRandomNumber()),
+			ByteOffset:   protocol.MaxByteCount,
+			ReliableSize: protocol.ByteCount(getRandomNumber()),
+		},
 		&wire.StopSendingFrame{
 			StreamID:  protocol.StreamID(getRandomNumber()),
 			ErrorCode: quic.ErrorCode(getRandomNumber()),
@@ -102,3 +102,16 @@ func (r *body) Close() error {
 	r.str.CancelRead(quic.ErrorCode(errorRequestCanceled))
 	return nil
 }
+
+// The body of the http.Response to an extended CONNECT request.
+// The application takes over the stream by calling DataStream.
+type connectResponseBody struct {
+	*body
+}
+
+var _ DataStreamer = &connectResponseBody{}
+
+func (r *connectResponseBody) DataStream() quic.Stream {
+	r.requestDone()
+	return r.str
+}
@@ -2,6 +2,7 @@ package http3
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -29,9 +31,16 @@ var defaultQuicConfig = &quic.Config{
 
 var dialAddr = quic.DialAddrEarly
 
+var (
+	errGoAway                      = errors.New("http3: server sent GOAWAY, not sending any new requests")
+	errExtendedConnectNotSupported = errors.New("http3: server didn't enable extended CONNECT")
+)
+
 type roundTripperOpts struct {
 	DisableCompression bool
 	MaxHeaderBytes     int64
+	AdditionalSettings func([]Setting) []Setting
+	ReceivedSettings   func([]Setting)
 }
 
 // client is a HTTP3 client doing requests
@@ -51,6 +60,19 @@ type client struct {
 	hostname string
 	session  quic.EarlySession
 
+	rcvdControlStream int32 // accessed atomically
+	// closed when the server's SETTINGS frame is received
+	settingsReceived     chan struct{}
+	settingsReceivedOnce sync.Once
+	// only read after settingsReceived was closed
+	enableConnectProtocol bool
+
+	goAwayMutex    sync.Mutex
+	receivedGoAway bool
+	goAwayStreamID uint64
+	// onGoAway, if set, is called when the first GOAWAY frame is received
+	onGoAway func()
+
 	logger utils.Logger
 }
 
@@ -75,14 +97,15 @@ func newClient(
 	logger := utils.DefaultLogger.WithPrefix("h3 client")
 
 	return &client{
-		hostname:      authorityAddr("https", hostname),
-		tlsConf:       tlsConf,
-		requestWriter: newRequestWriter(logger),
-		decoder:       qpack.NewDecoder(func(hf qpack.HeaderField) {}),
-		config:        quicConfig,
-		opts:          opts,
-		dialer:        dialer,
-		logger:        logger,
+		hostname:         authorityAddr("https", hostname),
+		tlsConf:          tlsConf,
+		requestWriter:    newRequestWriter(logger),
+		decoder:          qpack.NewDecoder(func(hf qpack.HeaderField) {}),
+		config:           quicConfig,
+		opts:             opts,
+		dialer:           dialer,
+		settingsReceived: make(chan struct{}),
+		logger:           logger,
 	}
 }
 
@@ -104,6 +127,7 @@ func (c *client) dial() error {
 			c.session.CloseWithError(quic.ErrorCode(errorInternalError), "")
 		}
 	}()
+	go c.handleUnidirectionalStreams()
 
 	return nil
 }
@@ -114,11 +138,17 @@ func (c *client) setupSession() error {
 	if err != nil {
 		return err
 	}
+	settings := &settingsFrame{}
+	if c.opts.AdditionalSettings != nil {
+		if err := settings.setAdditional(c.opts.AdditionalSettings(settings.list())); err != nil {
+			return err
+		}
+	}
 	buf := &bytes.Buffer{}
 	// write the type byte
 	buf.Write([]byte{0x0})
 	// send the SETTINGS frame
-	(&settingsFrame{}).Write(buf)
+	settings.Write(buf)
 	if _, err := str.Write(buf.Bytes()); err != nil {
 		return err
 	}
@@ -126,6 +156,104 @@ func (c *client) setupSession() error {
 	return nil
 }
 
+func (c *client) handleUnidirectionalStreams() {
+	for {
+		str, err := c.session.AcceptUniStream(context.Background())
+		if err != nil {
+			c.logger.Debugf("Accepting unidirectional stream failed: %s", err)
+			return
+		}
+		go func() {
+			streamType, err := utils.ReadVarInt(&byteReaderImpl{str})
+			if err != nil {
+				c.logger.Debugf("Reading stream type on unidirectional stream %d failed: %s", str.StreamID(), err)
+				return
+			}
+			switch streamType {
+			case streamTypeControlStream:
+				if !atomic.CompareAndSwapInt32(&c.rcvdControlStream, 0, 1) {
+					c.session.CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream")
+					return
+				}
+				c.handleControlStream(str)
+			case streamTypePushStream:
+				// We never send a MAX_PUSH_ID frame, so the server must not push.
+				c.session.CloseWithError(quic.ErrorCode(errorIDError), "server opened a push stream")
+			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
+				// We don't use the QPACK dynamic table, and the peer can't insert any entries.
+			default:
+				str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+			}
+		}()
+	}
+}
+
+func (c *client) handleControlStream(str quic.ReceiveStream) {
+	frame, err := parseNextFrame(str)
+	if err != nil {
+		c.logger.Debugf("Reading the SETTINGS frame failed: %s", err)
+		return
+	}
+	settings, ok := frame.(*settingsFrame)
+	if !ok {
+		c.session.CloseWithError(quic.ErrorCode(errorMissingSettings), "expected first frame on control stream to be a SETTINGS frame")
+		return
+	}
+	val, _ := settings.get(settingEnableConnectProtocol)
+	c.enableConnectProtocol = val == 1
+	if c.opts.ReceivedSettings != nil {
+		c.opts.ReceivedSettings(settings.list())
+	}
+	c.settingsReceivedOnce.Do(func() { close(c.settingsReceived) })
+	for {
+		frame, err := parseNextFrame(str)
+		if err != nil {
+			if err == io.EOF {
+				c.session.CloseWithError(quic.ErrorCode(errorClosedCriticalStream), "control stream closed")
+				return
+			}
+			c.logger.Debugf("Reading from the control stream failed: %s", err)
+			return
+		}
+		switch f := frame.(type) {
+		case *goAwayFrame:
+			if err := c.handleGoAway(f.StreamID); err != nil {
+				c.session.CloseWithError(quic.ErrorCode(errorIDError), err.Error())
+				return
+			}
+		case *cancelPushFrame:
+			// We never allow the server to push, so there's nothing to cancel.
+		default:
+			c.session.CloseWithError(quic.ErrorCode(errorFrameUnexpected), fmt.Sprintf("unexpected frame on control stream: %T", frame))
+			return
+		}
+	}
+}
+
+func (c *client) handleGoAway(streamID uint64) error {
+	c.goAwayMutex.Lock()
+	if c.receivedGoAway && streamID > c.goAwayStreamID {
+		c.goAwayMutex.Unlock()
+		return fmt.Errorf("GOAWAY increased the stream ID from %d to %d", c.goAwayStreamID, streamID)
+	}
+	c.logger.Debugf("Received GOAWAY for stream %d", streamID)
+	first := !c.receivedGoAway
+	c.receivedGoAway = true
+	c.goAwayStreamID = streamID
+	c.goAwayMutex.Unlock()
+
+	if first && c.onGoAway != nil {
+		c.onGoAway()
+	}
+	return nil
+}
+
+func (c *client) goingAway() bool {
+	c.goAwayMutex.Lock()
+	defer c.goAwayMutex.Unlock()
+	return c.receivedGoAway
+}
+
 func (c *client) Close() error {
 	if c.session == nil {
 		return nil
@@ -169,6 +297,25 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 		}
 	}
 
+	// Requests sent after the GOAWAY frame would be rejected by the server.
+	if c.goingAway() {
+		return nil, errGoAway
+	}
+
+	// Extended CONNECT requests can only be sent if the server enabled them in its SETTINGS.
+	if isExtendedConnect(req) {
+		select {
+		case <-c.settingsReceived:
+		case <-c.session.Context().Done():
+			return nil, errExtendedConnectNotSupported
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		if !c.enableConnectProtocol {
+			return nil, errExtendedConnectNotSupported
+		}
+	}
+
 	str, err := c.session.OpenStreamSync(req.Context())
 	if err != nil {
 		return nil, err
@@ -210,7 +357,8 @@ func (c *client) doRequest(
 	reqDone chan struct{},
 ) (*http.Response, requestError) {
 	var requestGzip bool
-	if !c.opts.DisableCompression && req.Method != "HEAD" && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
+	extendedConnect := isExtendedConnect(req)
+	if !c.opts.DisableCompression && req.Method != "HEAD" && !extendedConnect && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
 		requestGzip = true
 	}
 	if err := c.requestWriter.WriteRequest(str, req, requestGzip); err != nil {
@@ -259,7 +407,9 @@ func (c *client) doRequest(
 	respBody := newResponseBody(str, reqDone, func() {
 		c.session.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
 	})
-	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
+	if extendedConnect {
+		res.Body = &connectResponseBody{respBody}
+	} else if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
 		res.Header.Del("Content-Encoding")
 		res.Header.Del("Content-Length")
 		res.ContentLength = -1
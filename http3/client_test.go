@@ -135,6 +135,7 @@ var _ = Describe("Client", func() {
 		client = newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
 		session := mockquic.NewMockEarlySession(mockCtrl)
 		session.EXPECT().OpenUniStream().Return(nil, testErr).MaxTimes(1)
+		session.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, testErr).AnyTimes()
 		session.EXPECT().HandshakeComplete().Return(handshakeCtx).MaxTimes(1)
 		session.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr).MaxTimes(1)
 		session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).MaxTimes(1)
@@ -185,6 +186,7 @@ var _ = Describe("Client", func() {
 			str = mockquic.NewMockStream(mockCtrl)
 			sess = mockquic.NewMockEarlySession(mockCtrl)
 			sess.EXPECT().OpenUniStream().Return(controlStr, nil).MaxTimes(1)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
 			dialAddr = func(hostname string, _ *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
 				return sess, nil
 			}
@@ -233,6 +235,70 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		It("refuses new requests after receiving a GOAWAY frame", func() {
+			Expect(client.handleGoAway(4)).To(Succeed())
+			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			// don't EXPECT any calls to OpenStreamSync()
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError(errGoAway))
+		})
+
+		Context("extended CONNECT", func() {
+			BeforeEach(func() {
+				request.Method = http.MethodConnect
+				request.Proto = "webtransport"
+			})
+
+			It("sends extended CONNECT requests and hands out the stream", func() {
+				client.enableConnectProtocol = true
+				close(client.settingsReceived)
+				sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+				rspBuf := &bytes.Buffer{}
+				rw := newResponseWriter(rspBuf, utils.DefaultLogger)
+				rw.WriteHeader(200)
+
+				gomock.InOrder(
+					sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+					sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				)
+				buf := &bytes.Buffer{}
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return buf.Write(p)
+				}).AnyTimes()
+				// don't EXPECT any calls to Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Expect(rsp.Body).To(BeAssignableToTypeOf(&connectResponseBody{}))
+				Expect(rsp.Body.(DataStreamer).DataStream()).To(Equal(str))
+				hfs := decodeHeader(buf)
+				Expect(hfs).To(HaveKeyWithValue(":method", "CONNECT"))
+				Expect(hfs).To(HaveKeyWithValue(":protocol", "webtransport"))
+				Expect(hfs).ToNot(HaveKey("accept-encoding"))
+			})
+
+			It("refuses extended CONNECT requests if the server didn't enable them", func() {
+				close(client.settingsReceived)
+				sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				// don't EXPECT any calls to OpenStreamSync()
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError(errExtendedConnectNotSupported))
+			})
+
+			It("refuses extended CONNECT requests if the session is closed before receiving the SETTINGS", func() {
+				sessCtx, cancel := context.WithCancel(context.Background())
+				cancel()
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				sess.EXPECT().Context().Return(sessCtx)
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError(errExtendedConnectNotSupported))
+			})
+		})
+
 		Context("validating the address", func() {
 			It("refuses to do requests for the wrong host", func() {
 				req, err := http.NewRequest("https", "https://quic.clemente.io:1336/foobar.html", nil)
@@ -492,4 +558,127 @@ var _ = Describe("Client", func() {
 			})
 		})
 	})
+
+	Context("control stream", func() {
+		var sess *mockquic.MockEarlySession
+
+		BeforeEach(func() {
+			sess = mockquic.NewMockEarlySession(mockCtrl)
+			client.session = sess
+		})
+
+		controlStream := func(frames ...interface{ Write(*bytes.Buffer) }) *mockquic.MockStream {
+			buf := &bytes.Buffer{}
+			for _, f := range frames {
+				f.Write(buf)
+			}
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				if buf.Len() == 0 {
+					return 0, io.EOF
+				}
+				return buf.Read(p)
+			}).AnyTimes()
+			return str
+		}
+
+		It("handles GOAWAY frames", func() {
+			var goAwayCalls int
+			client.onGoAway = func() { goAwayCalls++ }
+			Expect(client.goingAway()).To(BeFalse())
+			str := controlStream(&settingsFrame{}, &goAwayFrame{StreamID: 8}, &goAwayFrame{StreamID: 4})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any())
+			client.handleControlStream(str)
+			Expect(client.goingAway()).To(BeTrue())
+			Expect(client.goAwayStreamID).To(BeEquivalentTo(4))
+			Expect(goAwayCalls).To(Equal(1))
+		})
+
+		It("reads the SETTINGS frame", func() {
+			str := controlStream(&settingsFrame{settings: []Setting{{ID: settingEnableConnectProtocol, Value: 1}}})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any())
+			client.handleControlStream(str)
+			Expect(client.settingsReceived).To(BeClosed())
+			Expect(client.enableConnectProtocol).To(BeTrue())
+		})
+
+		It("surfaces the server's settings", func() {
+			var received []Setting
+			client.opts.ReceivedSettings = func(s []Setting) { received = s }
+			str := controlStream(&settingsFrame{settings: []Setting{{ID: 0x1337, Value: 42}, {ID: settingEnableConnectProtocol, Value: 1}}})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any())
+			client.handleControlStream(str)
+			Expect(received).To(Equal([]Setting{
+				{ID: settingEnableConnectProtocol, Value: 1},
+				{ID: 0x1337, Value: 42},
+			}))
+		})
+
+		It("sends additional settings", func() {
+			client.opts.AdditionalSettings = func(defaults []Setting) []Setting {
+				Expect(defaults).To(BeEmpty())
+				return []Setting{{ID: 0x1337, Value: 42}}
+			}
+			buf := &bytes.Buffer{}
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write)
+			sess.EXPECT().OpenUniStream().Return(str, nil)
+			Expect(client.setupSession()).To(Succeed())
+			streamType, err := buf.ReadByte()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&settingsFrame{}))
+			Expect(frame.(*settingsFrame).settings).To(Equal([]Setting{{ID: 0x1337, Value: 42}}))
+		})
+
+		It("errors when additional settings use the identifier of a standard setting", func() {
+			client.opts.AdditionalSettings = func([]Setting) []Setting {
+				return []Setting{{ID: settingEnableConnectProtocol, Value: 1}}
+			}
+			sess.EXPECT().OpenUniStream().Return(mockquic.NewMockStream(mockCtrl), nil)
+			Expect(client.setupSession()).To(MatchError("http3: setting 0x8 is a standard setting"))
+		})
+
+		It("closes the connection when a GOAWAY frame increases the stream ID", func() {
+			str := controlStream(&settingsFrame{}, &goAwayFrame{StreamID: 4}, &goAwayFrame{StreamID: 8})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), "GOAWAY increased the stream ID from 4 to 8")
+			client.handleControlStream(str)
+		})
+
+		It("closes the connection when the first frame is not a SETTINGS frame", func() {
+			str := controlStream(&goAwayFrame{StreamID: 4})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorMissingSettings), gomock.Any())
+			client.handleControlStream(str)
+			Expect(client.goingAway()).To(BeFalse())
+		})
+
+		It("closes the connection when the server opens a second control stream", func() {
+			newControlStream := func() *mockquic.MockStream {
+				buf := &bytes.Buffer{}
+				utils.WriteVarInt(buf, streamTypeControlStream)
+				(&settingsFrame{}).Write(buf)
+				str := mockquic.NewMockStream(mockCtrl)
+				// block after the SETTINGS frame, so that the control stream stays open
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					if buf.Len() == 0 {
+						<-sess.Context().Done()
+						return 0, errors.New("session closed")
+					}
+					return buf.Read(p)
+				}).AnyTimes()
+				return str
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			sess.EXPECT().Context().Return(ctx).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(newControlStream(), nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(newControlStream(), nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done"))
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream").Do(func(quic.ErrorCode, string) { cancel() })
+			client.handleUnidirectionalStreams()
+			Eventually(ctx.Done()).Should(BeClosed())
+			Eventually(client.settingsReceived).Should(BeClosed())
+		})
+	})
 })
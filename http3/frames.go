@@ -46,16 +46,28 @@ func parseNextFrame(b io.Reader) (frame, error) {
 		return &dataFrame{Length: l}, nil
 	case 0x1:
 		return &headersFrame{Length: l}, nil
+	case 0x3:
+		pushID, err := parseVarIntPayload(br, l)
+		if err != nil {
+			return nil, err
+		}
+		return &cancelPushFrame{PushID: pushID}, nil
 	case 0x4:
 		return parseSettingsFrame(br, l)
-	case 0x3: // CANCEL_PUSH
-		fallthrough
+	case 0x7:
+		streamID, err := parseVarIntPayload(br, l)
+		if err != nil {
+			return nil, err
+		}
+		return &goAwayFrame{StreamID: streamID}, nil
+	case 0xd:
+		pushID, err := parseVarIntPayload(br, l)
+		if err != nil {
+			return nil, err
+		}
+		return &maxPushIDFrame{PushID: pushID}, nil
 	case 0x5: // PUSH_PROMISE
 		fallthrough
-	case 0x7: // GOAWAY
-		fallthrough
-	case 0xd: // MAX_PUSH_ID
-		fallthrough
 	case 0xe: // DUPLICATE_PUSH
 		fallthrough
 	default:
@@ -85,8 +97,11 @@ func (f *headersFrame) Write(b *bytes.Buffer) {
 	utils.WriteVarInt(b, f.Length)
 }
 
+// settingEnableConnectProtocol enables extended CONNECT requests, see RFC 8441
+const settingEnableConnectProtocol = 0x8
+
 type settingsFrame struct {
-	settings map[uint64]uint64
+	settings []Setting // in the order they are written
 }
 
 func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
@@ -100,7 +115,8 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 		}
 		return nil, err
 	}
-	frame := &settingsFrame{settings: make(map[uint64]uint64)}
+	frame := &settingsFrame{}
+	seen := make(map[uint64]struct{})
 	b := bytes.NewReader(buf)
 	for b.Len() > 0 {
 		id, err := utils.ReadVarInt(b)
@@ -111,10 +127,11 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 		if err != nil { // should not happen. We allocated the whole frame already.
 			return nil, err
 		}
-		if _, ok := frame.settings[id]; ok {
+		if _, ok := seen[id]; ok {
 			return nil, fmt.Errorf("duplicate setting: %d", id)
 		}
-		frame.settings[id] = val
+		seen[id] = struct{}{}
+		frame.settings = append(frame.settings, Setting{ID: id, Value: val})
 	}
 	return frame, nil
 }
@@ -122,12 +139,79 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 func (f *settingsFrame) Write(b *bytes.Buffer) {
 	utils.WriteVarInt(b, 0x4)
 	var l protocol.ByteCount
-	for id, val := range f.settings {
-		l += utils.VarIntLen(id) + utils.VarIntLen(val)
+	for _, s := range f.settings {
+		l += utils.VarIntLen(s.ID) + utils.VarIntLen(s.Value)
 	}
 	utils.WriteVarInt(b, uint64(l))
-	for id, val := range f.settings {
-		utils.WriteVarInt(b, id)
-		utils.WriteVarInt(b, val)
+	for _, s := range f.settings {
+		utils.WriteVarInt(b, s.ID)
+		utils.WriteVarInt(b, s.Value)
 	}
 }
+
+// parseVarIntPayload parses the payload of a frame that only consists of a single varint,
+// i.e. of the CANCEL_PUSH, the GOAWAY and the MAX_PUSH_ID frame.
+func parseVarIntPayload(r io.Reader, l uint64) (uint64, error) {
+	if l > 8 {
+		return 0, fmt.Errorf("unexpected size for a frame containing a single varint: %d", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	b := bytes.NewReader(buf)
+	val, err := utils.ReadVarInt(b)
+	if err != nil {
+		return 0, err
+	}
+	if b.Len() > 0 {
+		return 0, fmt.Errorf("unexpected size for a frame containing a single varint: %d", l)
+	}
+	return val, nil
+}
+
+type cancelPushFrame struct {
+	PushID uint64
+}
+
+func (f *cancelPushFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0x3)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.PushID)))
+	utils.WriteVarInt(b, f.PushID)
+}
+
+type goAwayFrame struct {
+	StreamID uint64
+}
+
+func (f *goAwayFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0x7)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.StreamID)))
+	utils.WriteVarInt(b, f.StreamID)
+}
+
+type maxPushIDFrame struct {
+	PushID uint64
+}
+
+func (f *maxPushIDFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0xd)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.PushID)))
+	utils.WriteVarInt(b, f.PushID)
+}
+
+// A pushPromiseFrame is followed by the header block of the promised request.
+// Length is the length of that header block.
+type pushPromiseFrame struct {
+	PushID uint64
+	Length uint64
+}
+
+func (f *pushPromiseFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0x5)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.PushID))+f.Length)
+	utils.WriteVarInt(b, f.PushID)
+}
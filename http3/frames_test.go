@@ -84,8 +84,7 @@ var _ = Describe("Frames", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame).To(BeAssignableToTypeOf(&settingsFrame{}))
 			sf := frame.(*settingsFrame)
-			Expect(sf.settings).To(HaveKeyWithValue(uint64(13), uint64(37)))
-			Expect(sf.settings).To(HaveKeyWithValue(uint64(0xdead), uint64(0xbeef)))
+			Expect(sf.settings).To(Equal([]Setting{{ID: 13, Value: 37}, {ID: 0xdead, Value: 0xbeef}}))
 		})
 
 		It("rejects duplicate settings", func() {
@@ -101,10 +100,10 @@ var _ = Describe("Frames", func() {
 		})
 
 		It("writes", func() {
-			sf := &settingsFrame{settings: map[uint64]uint64{
-				1:  2,
-				99: 999,
-				13: 37,
+			sf := &settingsFrame{settings: []Setting{
+				{ID: 1, Value: 2},
+				{ID: 99, Value: 999},
+				{ID: 13, Value: 37},
 			}}
 			buf := &bytes.Buffer{}
 			sf.Write(buf)
@@ -113,10 +112,27 @@ var _ = Describe("Frames", func() {
 			Expect(frame).To(Equal(sf))
 		})
 
+		It("writes the settings in order", func() {
+			sf := &settingsFrame{settings: []Setting{
+				{ID: 0x1337, Value: 1},
+				{ID: 0x8, Value: 2},
+				{ID: 0x42, Value: 3},
+			}}
+			buf := &bytes.Buffer{}
+			sf.Write(buf)
+			Expect(buf.Bytes()).To(Equal([]byte{
+				0x4, // type byte
+				8,   // length
+				0x53, 0x37, 0x1,
+				0x8, 0x2,
+				0x40, 0x42, 0x3,
+			}))
+		})
+
 		It("errors on EOF", func() {
-			sf := &settingsFrame{settings: map[uint64]uint64{
-				13:         37,
-				0xdeadbeef: 0xdecafbad,
+			sf := &settingsFrame{settings: []Setting{
+				{ID: 13, Value: 37},
+				{ID: 0xdeadbeef, Value: 0xdecafbad},
 			}}
 			buf := &bytes.Buffer{}
 			sf.Write(buf)
@@ -133,4 +149,116 @@ var _ = Describe("Frames", func() {
 			}
 		})
 	})
+
+	Context("CANCEL_PUSH frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 3) // type byte
+			data = appendVarInt(data, 2)
+			data = appendVarInt(data, 0x1337)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&cancelPushFrame{PushID: 0x1337}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&cancelPushFrame{PushID: 0xdeadbeef}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&cancelPushFrame{PushID: 0xdeadbeef}))
+		})
+
+		It("errors if the frame contains more than the Push ID", func() {
+			data := appendVarInt(nil, 3) // type byte
+			data = appendVarInt(data, 3)
+			data = appendVarInt(data, 0x1337)
+			data = append(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected size for a frame containing a single varint: 3"))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&cancelPushFrame{PushID: 0xdeadbeef}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				_, err := parseNextFrame(bytes.NewReader(data[:i]))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("GOAWAY frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, 2)
+			data = appendVarInt(data, 0x1337)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 0x1337}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0xdeadbeef}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 0xdeadbeef}))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0xdeadbeef}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				_, err := parseNextFrame(bytes.NewReader(data[:i]))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("MAX_PUSH_ID frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 0xd) // type byte
+			data = appendVarInt(data, 1)
+			data = appendVarInt(data, 42)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&maxPushIDFrame{PushID: 42}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&maxPushIDFrame{PushID: 0xdecafbad}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&maxPushIDFrame{PushID: 0xdecafbad}))
+		})
+
+		It("errors if the frame is too long", func() {
+			data := appendVarInt(nil, 0xd) // type byte
+			data = appendVarInt(data, 9)
+			data = append(data, make([]byte, 9)...)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected size for a frame containing a single varint: 9"))
+		})
+	})
+
+	Context("PUSH_PROMISE frames", func() {
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&pushPromiseFrame{PushID: 0x1337, Length: 100}).Write(buf)
+			r := bytes.NewReader(buf.Bytes())
+			t, err := utils.ReadVarInt(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t).To(Equal(uint64(5)))
+			l, err := utils.ReadVarInt(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(l).To(Equal(uint64(2 + 100)))
+			pushID, err := utils.ReadVarInt(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pushID).To(Equal(uint64(0x1337)))
+			Expect(r.Len()).To(BeZero())
+		})
+	})
 })
@@ -0,0 +1,130 @@
+package http3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+var errPushIDLimit = errors.New("http3: push ID limit reached")
+
+// The pushController keeps track of the server pushes on a connection.
+// The client controls the number of pushes by sending MAX_PUSH_ID frames.
+// It can cancel individual pushes using CANCEL_PUSH frames.
+type pushController struct {
+	mutex sync.Mutex
+
+	sess quic.EarlySession
+
+	maxPushIDReceived bool
+	maxPushID         uint64
+	nextPushID        uint64
+
+	// pushes that were promised, but for which the push stream wasn't opened yet
+	promised map[uint64]struct{}
+	// push streams that are currently being sent
+	streams map[uint64]quic.SendStream
+	// pushes that were promised, but canceled by the client before the push stream was opened
+	canceled map[uint64]struct{}
+}
+
+func newPushController(sess quic.EarlySession) *pushController {
+	return &pushController{
+		sess:     sess,
+		promised: make(map[uint64]struct{}),
+		streams:  make(map[uint64]quic.SendStream),
+		canceled: make(map[uint64]struct{}),
+	}
+}
+
+func (c *pushController) HandleMaxPushID(pushID uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.maxPushIDReceived && pushID < c.maxPushID {
+		return fmt.Errorf("MAX_PUSH_ID reduced the maximum Push ID from %d to %d", c.maxPushID, pushID)
+	}
+	c.maxPushIDReceived = true
+	c.maxPushID = pushID
+	return nil
+}
+
+func (c *pushController) HandleCancelPush(pushID uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.maxPushIDReceived || pushID > c.maxPushID {
+		return fmt.Errorf("CANCEL_PUSH for Push ID %d exceeds the maximum Push ID", pushID)
+	}
+	// Only pushes that were promised can be canceled.
+	// This limits the number of canceled pushes we need to keep track of.
+	if pushID >= c.nextPushID {
+		return fmt.Errorf("CANCEL_PUSH for Push ID %d, which was not promised yet", pushID)
+	}
+	if str, ok := c.streams[pushID]; ok {
+		str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+		delete(c.streams, pushID)
+		return nil
+	}
+	// The push stream wasn't opened yet.
+	// Otherwise, the push already completed, and there's nothing left to cancel.
+	if _, ok := c.promised[pushID]; ok {
+		c.canceled[pushID] = struct{}{}
+	}
+	return nil
+}
+
+// GetPushID returns the Push ID for a new push.
+// It returns http.ErrNotSupported if the client didn't allow any pushes.
+func (c *pushController) GetPushID() (uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.maxPushIDReceived {
+		return 0, http.ErrNotSupported
+	}
+	if c.nextPushID > c.maxPushID {
+		return 0, errPushIDLimit
+	}
+	pushID := c.nextPushID
+	c.nextPushID++
+	c.promised[pushID] = struct{}{}
+	return pushID, nil
+}
+
+// OpenPushStream opens the push stream for a promised push.
+// It returns nil if the client canceled the push.
+func (c *pushController) OpenPushStream(pushID uint64) (quic.SendStream, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.promised, pushID)
+	if _, ok := c.canceled[pushID]; ok {
+		delete(c.canceled, pushID)
+		return nil, nil
+	}
+	str, err := c.sess.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	utils.WriteVarInt(buf, streamTypePushStream)
+	utils.WriteVarInt(buf, pushID)
+	if _, err := str.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	c.streams[pushID] = str
+	return str, nil
+}
+
+// PushCompleted is called when the push stream was closed.
+func (c *pushController) PushCompleted(pushID uint64) {
+	c.mutex.Lock()
+	delete(c.streams, pushID)
+	c.mutex.Unlock()
+}
@@ -0,0 +1,113 @@
+package http3
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Push Controller", func() {
+	var (
+		sess   *mockquic.MockEarlySession
+		pushes *pushController
+	)
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		pushes = newPushController(sess)
+	})
+
+	expectPushStream := func(pushID uint64) *mockquic.MockStream {
+		str := mockquic.NewMockStream(mockCtrl)
+		buf := &bytes.Buffer{}
+		utils.WriteVarInt(buf, streamTypePushStream)
+		utils.WriteVarInt(buf, pushID)
+		str.EXPECT().Write(buf.Bytes()).Return(buf.Len(), nil)
+		sess.EXPECT().OpenUniStream().Return(str, nil)
+		return str
+	}
+
+	It("doesn't allow pushes before receiving a MAX_PUSH_ID frame", func() {
+		_, err := pushes.GetPushID()
+		Expect(err).To(MatchError(http.ErrNotSupported))
+	})
+
+	It("hands out Push IDs up to the maximum Push ID", func() {
+		Expect(pushes.HandleMaxPushID(1)).To(Succeed())
+		Expect(pushes.GetPushID()).To(Equal(uint64(0)))
+		Expect(pushes.GetPushID()).To(Equal(uint64(1)))
+		_, err := pushes.GetPushID()
+		Expect(err).To(MatchError(errPushIDLimit))
+		Expect(pushes.HandleMaxPushID(2)).To(Succeed())
+		Expect(pushes.GetPushID()).To(Equal(uint64(2)))
+	})
+
+	It("errors when the maximum Push ID is reduced", func() {
+		Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+		Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+		Expect(pushes.HandleMaxPushID(9)).To(MatchError("MAX_PUSH_ID reduced the maximum Push ID from 10 to 9"))
+	})
+
+	It("opens push streams", func() {
+		Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+		pushID, err := pushes.GetPushID()
+		Expect(err).ToNot(HaveOccurred())
+		str := expectPushStream(pushID)
+		Expect(pushes.OpenPushStream(pushID)).To(Equal(str))
+	})
+
+	It("errors when a CANCEL_PUSH frame exceeds the maximum Push ID", func() {
+		Expect(pushes.HandleCancelPush(0)).To(MatchError("CANCEL_PUSH for Push ID 0 exceeds the maximum Push ID"))
+		Expect(pushes.HandleMaxPushID(3)).To(Succeed())
+		Expect(pushes.HandleCancelPush(4)).To(MatchError("CANCEL_PUSH for Push ID 4 exceeds the maximum Push ID"))
+	})
+
+	It("cancels push streams that are being sent", func() {
+		Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+		pushID, err := pushes.GetPushID()
+		Expect(err).ToNot(HaveOccurred())
+		str := expectPushStream(pushID)
+		_, err = pushes.OpenPushStream(pushID)
+		Expect(err).ToNot(HaveOccurred())
+		str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+		Expect(pushes.HandleCancelPush(pushID)).To(Succeed())
+		// canceling again is a no-op
+		Expect(pushes.HandleCancelPush(pushID)).To(Succeed())
+	})
+
+	It("doesn't open the push stream if the push was canceled after it was promised", func() {
+		Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+		pushID, err := pushes.GetPushID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pushes.HandleCancelPush(pushID)).To(Succeed())
+		str, err := pushes.OpenPushStream(pushID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str).To(BeNil())
+	})
+
+	It("errors when a CANCEL_PUSH frame is received for a push that wasn't promised yet", func() {
+		Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+		Expect(pushes.HandleCancelPush(0)).To(MatchError("CANCEL_PUSH for Push ID 0, which was not promised yet"))
+		Expect(pushes.GetPushID()).To(BeZero())
+		Expect(pushes.HandleCancelPush(1)).To(MatchError("CANCEL_PUSH for Push ID 1, which was not promised yet"))
+		Expect(pushes.canceled).To(BeEmpty())
+	})
+
+	It("ignores CANCEL_PUSH frames for completed pushes", func() {
+		Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+		pushID, err := pushes.GetPushID()
+		Expect(err).ToNot(HaveOccurred())
+		expectPushStream(pushID)
+		_, err = pushes.OpenPushStream(pushID)
+		Expect(err).ToNot(HaveOccurred())
+		pushes.PushCompleted(pushID)
+		Expect(pushes.HandleCancelPush(pushID)).To(Succeed())
+		Expect(pushes.canceled).To(BeEmpty())
+	})
+})
@@ -12,7 +12,7 @@ import (
 )
 
 func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
-	var path, authority, method, contentLengthStr string
+	var path, authority, method, protocol, scheme, contentLengthStr string
 	httpHeaders := http.Header{}
 
 	for _, h := range headers {
@@ -23,6 +23,10 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 			method = h.Value
 		case ":authority":
 			authority = h.Value
+		case ":protocol":
+			protocol = h.Value
+		case ":scheme":
+			scheme = h.Value
 		case "content-length":
 			contentLengthStr = h.Value
 		default:
@@ -41,10 +45,25 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		return nil, errors.New(":path, :authority and :method must not be empty")
 	}
 
+	proto := "HTTP/3"
+	if len(protocol) > 0 {
+		if method != http.MethodConnect {
+			return nil, errors.New(":protocol must only be used with the CONNECT method")
+		}
+		if len(scheme) == 0 {
+			return nil, errors.New(":scheme must not be empty for extended CONNECT requests")
+		}
+		proto = protocol
+	}
+
 	u, err := url.ParseRequestURI(path)
 	if err != nil {
 		return nil, err
 	}
+	if len(protocol) > 0 {
+		u.Scheme = scheme
+		u.Host = authority
+	}
 
 	var contentLength int64
 	if len(contentLengthStr) > 0 {
@@ -57,7 +76,7 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	return &http.Request{
 		Method:        method,
 		URL:           u,
-		Proto:         "HTTP/3",
+		Proto:         proto,
 		ProtoMajor:    3,
 		ProtoMinor:    0,
 		Header:        httpHeaders,
@@ -69,6 +88,12 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	}, nil
 }
 
+// isExtendedConnect says if the request is an extended CONNECT request (see RFC 8441).
+// The protocol requested by an extended CONNECT request (the :protocol pseudo-header) is carried in Request.Proto.
+func isExtendedConnect(req *http.Request) bool {
+	return req.Method == http.MethodConnect && req.Proto != "" && req.Proto != "HTTP/1.1" && req.Proto != "HTTP/3"
+}
+
 func hostnameFromRequest(req *http.Request) string {
 	if req.URL != nil {
 		return req.URL.Host
@@ -108,6 +108,59 @@ var _ = Describe("Request", func() {
 		Expect(err).To(MatchError(":path, :authority and :method must not be empty"))
 	})
 
+	Context("extended CONNECT", func() {
+		It("populates extended CONNECT requests", func() {
+			headers := []qpack.HeaderField{
+				{Name: ":path", Value: "/foo?bar"},
+				{Name: ":authority", Value: "quic.clemente.io"},
+				{Name: ":method", Value: "CONNECT"},
+				{Name: ":protocol", Value: "webtransport"},
+				{Name: ":scheme", Value: "https"},
+			}
+			req, err := requestFromHeaders(headers)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(req.Method).To(Equal(http.MethodConnect))
+			Expect(req.Proto).To(Equal("webtransport"))
+			Expect(req.URL.String()).To(Equal("https://quic.clemente.io/foo?bar"))
+			Expect(req.RequestURI).To(Equal("/foo?bar"))
+			Expect(isExtendedConnect(req)).To(BeTrue())
+		})
+
+		It("doesn't treat other requests as extended CONNECT requests", func() {
+			headers := []qpack.HeaderField{
+				{Name: ":path", Value: "/foo"},
+				{Name: ":authority", Value: "quic.clemente.io"},
+				{Name: ":method", Value: "CONNECT"},
+			}
+			req, err := requestFromHeaders(headers)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isExtendedConnect(req)).To(BeFalse())
+		})
+
+		It("errors if the :protocol pseudo-header is used with a method other than CONNECT", func() {
+			headers := []qpack.HeaderField{
+				{Name: ":path", Value: "/foo"},
+				{Name: ":authority", Value: "quic.clemente.io"},
+				{Name: ":method", Value: "GET"},
+				{Name: ":protocol", Value: "webtransport"},
+				{Name: ":scheme", Value: "https"},
+			}
+			_, err := requestFromHeaders(headers)
+			Expect(err).To(MatchError(":protocol must only be used with the CONNECT method"))
+		})
+
+		It("errors with missing scheme", func() {
+			headers := []qpack.HeaderField{
+				{Name: ":path", Value: "/foo"},
+				{Name: ":authority", Value: "quic.clemente.io"},
+				{Name: ":method", Value: "CONNECT"},
+				{Name: ":protocol", Value: "webtransport"},
+			}
+			_, err := requestFromHeaders(headers)
+			Expect(err).To(MatchError(":scheme must not be empty for extended CONNECT requests"))
+		})
+	})
+
 	Context("extracting the hostname from a request", func() {
 		var url *url.URL
 
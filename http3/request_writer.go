@@ -44,6 +44,10 @@ func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip bo
 	if _, err := str.Write(headers); err != nil {
 		return err
 	}
+	// For extended CONNECT requests, the stream is used by the application after the request was sent.
+	if isExtendedConnect(req) {
+		return nil
+	}
 	// TODO: add support for trailers
 	if req.Body == nil {
 		str.Close()
@@ -117,8 +121,9 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 		return err
 	}
 
+	extendedConnect := isExtendedConnect(req)
 	var path string
-	if req.Method != "CONNECT" {
+	if req.Method != "CONNECT" || extendedConnect {
 		path = req.URL.RequestURI()
 		if !validPseudoPath(path) {
 			orig := path
@@ -155,10 +160,13 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 		// [RFC3986]).
 		f(":authority", host)
 		f(":method", req.Method)
-		if req.Method != "CONNECT" {
+		if req.Method != "CONNECT" || extendedConnect {
 			f(":path", path)
 			f(":scheme", req.URL.Scheme)
 		}
+		if extendedConnect {
+			f(":protocol", req.Proto)
+		}
 		if trailers != "" {
 			f("trailer", trailers)
 		}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
 )
@@ -18,11 +19,27 @@ type responseWriter struct {
 	status        int // status code passed to WriteHeader
 	headerWritten bool
 
+	// pusher is nil if the server doesn't support pushing on this connection
+	pusher func(target string, opts *http.PushOptions) error
+
+	// dataStream is only set for extended CONNECT requests
+	dataStream     quic.Stream
+	dataStreamUsed bool
+
 	logger utils.Logger
 }
 
 var _ http.ResponseWriter = &responseWriter{}
 
+// DataStreamer lets the application take over the stream of an extended CONNECT request.
+// On the server side, it is implemented by the http.ResponseWriter passed to the handler.
+// On the client side, it is implemented by the body of the http.Response.
+// After a call to DataStream, the HTTP/3 library doesn't use the stream any more,
+// and it becomes the application's responsibility to close it.
+type DataStreamer interface {
+	DataStream() quic.Stream
+}
+
 func newResponseWriter(stream io.Writer, logger utils.Logger) *responseWriter {
 	return &responseWriter{
 		header: http.Header{},
@@ -81,9 +98,40 @@ func (w *responseWriter) Write(p []byte) (int, error) {
 
 func (w *responseWriter) Flush() {}
 
+// Push initiates a server push.
+// Pushes are only possible if the client allowed them by sending a MAX_PUSH_ID frame,
+// otherwise http.ErrNotSupported is returned.
+// The pushed request is served by the server's handler, on a separate push stream.
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if w.pusher == nil {
+		return http.ErrNotSupported
+	}
+	return w.pusher(target, opts)
+}
+
+// DataStream takes over the stream of an extended CONNECT request.
+// If no response header was written yet, a 200 response is sent.
+// It returns nil for all other requests.
+func (w *responseWriter) DataStream() quic.Stream {
+	if w.dataStream == nil {
+		return nil
+	}
+	if !w.headerWritten {
+		w.WriteHeader(200)
+	}
+	w.dataStreamUsed = true
+	return w.dataStream
+}
+
 // test that we implement http.Flusher
 var _ http.Flusher = &responseWriter{}
 
+// test that we implement http.Pusher
+var _ http.Pusher = &responseWriter{}
+
+// test that we implement DataStreamer
+var _ DataStreamer = &responseWriter{}
+
 // copied from http2/http2.go
 // bodyAllowedForStatus reports whether a given response status code
 // permits a body. See RFC 2616, section 4.4.
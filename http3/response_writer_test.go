@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
 
@@ -117,4 +118,30 @@ var _ = Describe("Response Writer", func() {
 		Expect(n).To(BeZero())
 		Expect(err).To(MatchError(http.ErrBodyNotAllowed))
 	})
+
+	Context("taking over the stream", func() {
+		It("doesn't return the stream for requests other than extended CONNECT", func() {
+			Expect(rw.DataStream()).To(BeNil())
+			Expect(strBuf.Len()).To(BeZero())
+		})
+
+		It("returns the stream for extended CONNECT requests", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			rw.dataStream = str
+			Expect(rw.DataStream()).To(Equal(str))
+			Expect(rw.dataStreamUsed).To(BeTrue())
+			fields := decodeHeader(strBuf)
+			Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		})
+
+		It("doesn't write the header again if it was already written", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			rw.dataStream = str
+			rw.WriteHeader(http.StatusTeapot)
+			Expect(rw.DataStream()).To(Equal(str))
+			fields := decodeHeader(strBuf)
+			Expect(fields).To(HaveKeyWithValue(":status", []string{"418"}))
+			Expect(strBuf.Len()).To(BeZero())
+		})
+	})
 })
@@ -51,6 +51,17 @@ type RoundTripper struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
+	// AdditionalSettings, if set, is called when sending the SETTINGS frame on a new connection.
+	// It is passed the settings that are sent by default, and returns all settings that are sent,
+	// in the order they are written to the frame, e.g. to test experimental extensions.
+	// The default settings must be included with their values.
+	// The identifiers of the other settings must neither be used by a standard setting nor be repeated.
+	// If they are, the connection is closed.
+	AdditionalSettings func(defaults []Setting) []Setting
+
+	// ReceivedSettings, if set, is called with the settings sent by the server, ordered by identifier.
+	ReceivedSettings func([]Setting)
+
 	clients map[string]roundTripCloser
 }
 
@@ -130,21 +141,37 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (http.RoundTr
 		if onlyCached {
 			return nil, ErrNoCachedConn
 		}
-		client = newClient(
+		c := newClient(
 			hostname,
 			r.TLSClientConfig,
 			&roundTripperOpts{
 				DisableCompression: r.DisableCompression,
 				MaxHeaderBytes:     r.MaxResponseHeaderBytes,
+				AdditionalSettings: r.AdditionalSettings,
+				ReceivedSettings:   r.ReceivedSettings,
 			},
 			r.QuicConfig,
 			r.Dial,
 		)
+		// The server won't accept any new requests on this connection.
+		// Requests that are already running are completed, new requests use a new connection.
+		c.onGoAway = func() { r.removeClient(hostname, c) }
+		client = c
 		r.clients[hostname] = client
 	}
 	return client, nil
 }
 
+// removeClient removes a client from the cache, if it wasn't replaced by a new client yet.
+func (r *RoundTripper) removeClient(hostname string, client roundTripCloser) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.clients[hostname] == client {
+		delete(r.clients, hostname)
+	}
+}
+
 // Close closes the QUIC connections that this RoundTripper has used
 func (r *RoundTripper) Close() error {
 	r.mutex.Lock()
@@ -80,6 +80,7 @@ var _ = Describe("RoundTripper", func() {
 
 		BeforeEach(func() {
 			session = mockquic.NewMockEarlySession(mockCtrl)
+			session.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
 			origDialAddr = dialAddr
 			dialAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.EarlySession, error) {
 				// return an error when trying to open a stream
@@ -222,6 +223,29 @@ var _ = Describe("RoundTripper", func() {
 		})
 	})
 
+	Context("GOAWAY", func() {
+		It("removes a client from the cache when it receives a GOAWAY frame", func() {
+			cl, err := rt.getClient("quic.clemente.io:443", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rt.clients).To(HaveKeyWithValue("quic.clemente.io:443", cl))
+			Expect(cl.(*client).handleGoAway(0)).To(Succeed())
+			Expect(rt.clients).To(BeEmpty())
+			// the next request uses a new client
+			cl2, err := rt.getClient("quic.clemente.io:443", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cl2).ToNot(BeIdenticalTo(cl))
+		})
+
+		It("doesn't remove a client that replaced the client that received the GOAWAY frame", func() {
+			cl, err := rt.getClient("quic.clemente.io:443", false)
+			Expect(err).ToNot(HaveOccurred())
+			newCl := &mockClient{}
+			rt.clients["quic.clemente.io:443"] = newCl
+			Expect(cl.(*client).handleGoAway(0)).To(Succeed())
+			Expect(rt.clients).To(HaveKeyWithValue("quic.clemente.io:443", newCl))
+		})
+	})
+
 	Context("closing", func() {
 		It("closes", func() {
 			rt.clients = make(map[string]roundTripCloser)
@@ -9,7 +9,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,6 +29,14 @@ var (
 
 const nextProtoH3 = "h3-27"
 
+// unidirectional stream types
+const (
+	streamTypeControlStream      = 0x0
+	streamTypePushStream         = 0x1
+	streamTypeQPACKEncoderStream = 0x2
+	streamTypeQPACKDecoderStream = 0x3
+)
+
 // contextKey is a value for use with context.WithValue. It's used as
 // a pointer so it fits in an interface{} without allocation.
 type contextKey struct {
@@ -43,6 +53,9 @@ var (
 	ServerContextKey = &contextKey{"http3-server"}
 )
 
+// errDataStreamUsed is returned by handleRequest when the handler took over the request stream
+var errDataStreamUsed = errors.New("http3: the handler took over the request stream")
+
 type requestError struct {
 	err       error
 	streamErr errorCode
@@ -65,12 +78,38 @@ type Server struct {
 	// If nil, it uses reasonable default values.
 	QuicConfig *quic.Config
 
+	// EnableConnectProtocol enables extended CONNECT requests (see RFC 8441),
+	// as used by WebTransport, by sending the SETTINGS_ENABLE_CONNECT_PROTOCOL setting.
+	// The protocol requested by the client is available in Request.Proto.
+	// The handler can take over the request stream using the DataStreamer interface.
+	EnableConnectProtocol bool
+
+	// AdditionalSettings, if set, is called when sending the SETTINGS frame on a new connection.
+	// It is passed the settings that are sent by default, and returns all settings that are sent,
+	// in the order they are written to the frame, e.g. to test experimental extensions.
+	// The default settings must be included with their values.
+	// The identifiers of the other settings must neither be used by a standard setting nor be repeated.
+	// If they are, the connection is closed.
+	AdditionalSettings func(defaults []Setting) []Setting
+
+	// ReceivedSettings, if set, is called with the settings sent by the client, ordered by identifier.
+	ReceivedSettings func([]Setting)
+
 	port uint32 // used atomically
 
 	mutex     sync.Mutex
 	listeners map[*quic.EarlyListener]struct{}
+	conns     map[*serverConn]struct{}
+	goingAway bool
 	closed    utils.AtomicBool
 
+	// the number of requests that were accepted, and that CloseGracefully waits for
+	numRequests int
+	// closed when numRequests drops to 0, if CloseGracefully is waiting for it
+	requestsDone chan struct{}
+	// set when the server is shut down, no new requests are accepted after that
+	shutdown bool
+
 	loggerOnce sync.Once
 	logger     utils.Logger
 }
@@ -177,9 +216,109 @@ func (s *Server) removeListener(l *quic.EarlyListener) {
 	s.mutex.Unlock()
 }
 
+// A serverConn is a connection handled by the server.
+type serverConn struct {
+	controlStream quic.SendStream
+
+	mutex     sync.Mutex
+	goingAway bool
+	goAwayID  quic.StreamID
+	// the lowest stream ID that the client might use for the next request
+	nextStreamID quic.StreamID
+}
+
+// acceptRequest is called for every request stream accepted on the connection.
+// It returns false if the request must be rejected, since it was sent after the GOAWAY frame.
+func (c *serverConn) acceptRequest(id quic.StreamID) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.goingAway && id >= c.goAwayID {
+		return false
+	}
+	if id >= c.nextStreamID {
+		c.nextStreamID = id + 4
+	}
+	return true
+}
+
+// goAway sends a GOAWAY frame.
+// Requests that were not accepted yet will be rejected.
+func (c *serverConn) goAway() {
+	c.mutex.Lock()
+	if c.goingAway {
+		c.mutex.Unlock()
+		return
+	}
+	c.goingAway = true
+	c.goAwayID = c.nextStreamID
+	goAwayID := c.goAwayID
+	c.mutex.Unlock()
+
+	buf := &bytes.Buffer{}
+	(&goAwayFrame{StreamID: uint64(goAwayID)}).Write(buf)
+	c.controlStream.Write(buf.Bytes())
+}
+
+func (s *Server) addConn(c *serverConn) {
+	s.mutex.Lock()
+	if s.conns == nil {
+		s.conns = make(map[*serverConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	goingAway := s.goingAway
+	s.mutex.Unlock()
+
+	if goingAway {
+		c.goAway()
+	}
+}
+
+func (s *Server) removeConn(c *serverConn) {
+	s.mutex.Lock()
+	delete(s.conns, c)
+	s.mutex.Unlock()
+}
+
+// addRequest is called for every request that is handled.
+// It returns false if the server was already shut down, and the request must be rejected.
+func (s *Server) addRequest() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.shutdown {
+		return false
+	}
+	s.numRequests++
+	return true
+}
+
+func (s *Server) removeRequest() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.numRequests--
+	if s.numRequests == 0 && s.requestsDone != nil {
+		close(s.requestsDone)
+		s.requestsDone = nil
+	}
+}
+
 func (s *Server) handleConn(sess quic.EarlySession) {
-	// TODO: accept control streams
 	decoder := qpack.NewDecoder(nil)
+	pushes := newPushController(sess)
+
+	settings := &settingsFrame{}
+	if s.EnableConnectProtocol {
+		settings.settings = []Setting{{ID: settingEnableConnectProtocol, Value: 1}}
+	}
+	if s.AdditionalSettings != nil {
+		if err := settings.setAdditional(s.AdditionalSettings(settings.list())); err != nil {
+			s.logger.Errorf("Adding settings failed: %s", err)
+			sess.CloseWithError(quic.ErrorCode(errorInternalError), "")
+			return
+		}
+	}
 
 	// send a SETTINGS frame
 	str, err := sess.OpenUniStream()
@@ -188,9 +327,15 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 		return
 	}
 	buf := bytes.NewBuffer([]byte{0})
-	(&settingsFrame{}).Write(buf)
+	settings.Write(buf)
 	str.Write(buf.Bytes())
 
+	conn := &serverConn{controlStream: str}
+	s.addConn(conn)
+	defer s.removeConn(conn)
+
+	go s.handleUnidirectionalStreams(sess, pushes)
+
 	// Process all requests immediately.
 	// It's the client's responsibility to decide which requests are eligible for 0-RTT.
 	for {
@@ -199,10 +344,26 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 			s.logger.Debugf("Accepting stream failed: %s", err)
 			return
 		}
+		if !conn.acceptRequest(str.StreamID()) {
+			s.logger.Debugf("Rejecting request on stream %d, since it was sent after GOAWAY", str.StreamID())
+			str.CancelRead(quic.ErrorCode(errorRequestRejected))
+			str.CancelWrite(quic.ErrorCode(errorRequestRejected))
+			continue
+		}
+		if !s.addRequest() {
+			s.logger.Debugf("Rejecting request on stream %d, since the server is shutting down", str.StreamID())
+			str.CancelRead(quic.ErrorCode(errorRequestRejected))
+			str.CancelWrite(quic.ErrorCode(errorRequestRejected))
+			continue
+		}
 		go func() {
-			rerr := s.handleRequest(sess, str, decoder, func() {
+			defer s.removeRequest()
+			rerr := s.handleRequest(sess, str, decoder, pushes, func() {
 				sess.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
 			})
+			if rerr.err == errDataStreamUsed {
+				return
+			}
 			if rerr.err != nil || rerr.streamErr != 0 || rerr.connErr != 0 {
 				s.logger.Debugf("Handling request failed: %s", err)
 				if rerr.streamErr != 0 {
@@ -222,6 +383,80 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 	}
 }
 
+func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession, pushes *pushController) {
+	var rcvdControlStream int32 // accessed atomically
+	for {
+		str, err := sess.AcceptUniStream(context.Background())
+		if err != nil {
+			s.logger.Debugf("Accepting unidirectional stream failed: %s", err)
+			return
+		}
+		go func() {
+			streamType, err := utils.ReadVarInt(&byteReaderImpl{str})
+			if err != nil {
+				s.logger.Debugf("Reading stream type on unidirectional stream %d failed: %s", str.StreamID(), err)
+				return
+			}
+			switch streamType {
+			case streamTypeControlStream:
+				if !atomic.CompareAndSwapInt32(&rcvdControlStream, 0, 1) {
+					sess.CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream")
+					return
+				}
+				s.handleControlStream(sess, str, pushes)
+			case streamTypePushStream:
+				sess.CloseWithError(quic.ErrorCode(errorStreamCreationError), "client opened a push stream")
+			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
+				// We don't use the QPACK dynamic table, and the peer can't insert any entries.
+			default:
+				str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+			}
+		}()
+	}
+}
+
+func (s *Server) handleControlStream(sess quic.EarlySession, str quic.ReceiveStream, pushes *pushController) {
+	frame, err := parseNextFrame(str)
+	if err != nil {
+		s.logger.Debugf("Reading the SETTINGS frame failed: %s", err)
+		return
+	}
+	settings, ok := frame.(*settingsFrame)
+	if !ok {
+		sess.CloseWithError(quic.ErrorCode(errorMissingSettings), "expected first frame on control stream to be a SETTINGS frame")
+		return
+	}
+	if s.ReceivedSettings != nil {
+		s.ReceivedSettings(settings.list())
+	}
+	for {
+		frame, err := parseNextFrame(str)
+		if err != nil {
+			if err == io.EOF {
+				sess.CloseWithError(quic.ErrorCode(errorClosedCriticalStream), "control stream closed")
+				return
+			}
+			s.logger.Debugf("Reading from the control stream failed: %s", err)
+			return
+		}
+		switch f := frame.(type) {
+		case *maxPushIDFrame:
+			if err := pushes.HandleMaxPushID(f.PushID); err != nil {
+				sess.CloseWithError(quic.ErrorCode(errorIDError), err.Error())
+				return
+			}
+		case *cancelPushFrame:
+			if err := pushes.HandleCancelPush(f.PushID); err != nil {
+				sess.CloseWithError(quic.ErrorCode(errorIDError), err.Error())
+				return
+			}
+		default:
+			sess.CloseWithError(quic.ErrorCode(errorFrameUnexpected), fmt.Sprintf("unexpected frame on control stream: %T", frame))
+			return
+		}
+	}
+}
+
 func (s *Server) maxHeaderBytes() uint64 {
 	if s.Server.MaxHeaderBytes <= 0 {
 		return http.DefaultMaxHeaderBytes
@@ -229,7 +464,7 @@ func (s *Server) maxHeaderBytes() uint64 {
 	return uint64(s.Server.MaxHeaderBytes)
 }
 
-func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpack.Decoder, onFrameError func()) requestError {
+func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpack.Decoder, pushes *pushController, onFrameError func()) requestError {
 	frame, err := parseNextFrame(str)
 	if err != nil {
 		return newStreamError(errorRequestIncomplete, err)
@@ -255,6 +490,10 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 		// TODO: use the right error code
 		return newStreamError(errorGeneralProtocolError, err)
 	}
+	extendedConnect := isExtendedConnect(req)
+	if extendedConnect && !s.EnableConnectProtocol {
+		return newStreamError(errorGeneralProtocolError, errors.New("received an extended CONNECT request, but extended CONNECT is not enabled"))
+	}
 
 	req.RemoteAddr = sess.RemoteAddr().String()
 	req.Body = newRequestBody(str, onFrameError)
@@ -270,29 +509,27 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 	ctx = context.WithValue(ctx, http.LocalAddrContextKey, sess.LocalAddr())
 	req = req.WithContext(ctx)
 	responseWriter := newResponseWriter(str, s.logger)
-	handler := s.Handler
-	if handler == nil {
-		handler = http.DefaultServeMux
+	if pushes != nil {
+		responseWriter.pusher = func(target string, opts *http.PushOptions) error {
+			return s.push(sess, pushes, str, req, target, opts)
+		}
+	}
+	if extendedConnect {
+		responseWriter.dataStream = str
 	}
 
-	var panicked, readEOF bool
-	func() {
-		defer func() {
-			if p := recover(); p != nil {
-				// Copied from net/http/server.go
-				const size = 64 << 10
-				buf := make([]byte, size)
-				buf = buf[:runtime.Stack(buf, false)]
-				s.logger.Errorf("http: panic serving: %v\n%s", p, buf)
-				panicked = true
-			}
-		}()
-		handler.ServeHTTP(responseWriter, req)
+	var readEOF bool
+	panicked := s.runHandler(responseWriter, req)
+	if !panicked && responseWriter.dataStreamUsed {
+		// The handler is now responsible for the stream.
+		return requestError{err: errDataStreamUsed}
+	}
+	if !panicked {
 		// read the eof
 		if _, err = str.Read([]byte{0}); err == io.EOF {
 			readEOF = true
 		}
-	}()
+	}
 
 	if panicked {
 		responseWriter.WriteHeader(500)
@@ -306,6 +543,118 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 	return requestError{}
 }
 
+// runHandler runs the handler, and reports whether it panicked.
+func (s *Server) runHandler(w http.ResponseWriter, req *http.Request) (panicked bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			// Copied from net/http/server.go
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			s.logger.Errorf("http: panic serving: %v\n%s", p, buf)
+			panicked = true
+		}
+	}()
+	handler := s.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	handler.ServeHTTP(w, req)
+	return false
+}
+
+// push sends a PUSH_PROMISE frame on the request stream,
+// and serves the promised request on a new push stream.
+func (s *Server) push(sess quic.Session, pushes *pushController, reqStr io.Writer, req *http.Request, target string, opts *http.PushOptions) error {
+	if opts == nil {
+		opts = &http.PushOptions{}
+	}
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return fmt.Errorf("http3: cannot push with method %s", method)
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "" || u.Host != "" {
+		if u.Scheme != "https" || u.Host != req.Host {
+			return fmt.Errorf("http3: cannot push %s, it is not on the same origin as the request", target)
+		}
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		return fmt.Errorf("http3: push target must be an absolute path or URL: %s", target)
+	}
+
+	pushID, err := pushes.GetPushID()
+	if err != nil {
+		return err
+	}
+	hfs := []qpack.HeaderField{
+		{Name: ":method", Value: method},
+		{Name: ":scheme", Value: "https"},
+		{Name: ":authority", Value: req.Host},
+		{Name: ":path", Value: u.RequestURI()},
+	}
+	for k, vv := range opts.Header {
+		for _, v := range vv {
+			hfs = append(hfs, qpack.HeaderField{Name: strings.ToLower(k), Value: v})
+		}
+	}
+	headers := &bytes.Buffer{}
+	enc := qpack.NewEncoder(headers)
+	for _, hf := range hfs {
+		if err := enc.WriteField(hf); err != nil {
+			return err
+		}
+	}
+	buf := &bytes.Buffer{}
+	(&pushPromiseFrame{PushID: pushID, Length: uint64(headers.Len())}).Write(buf)
+	buf.Write(headers.Bytes())
+	if _, err := reqStr.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	pushedReq, err := requestFromHeaders(hfs)
+	if err != nil {
+		return err
+	}
+	go s.handlePush(sess, pushes, pushID, pushedReq)
+	return nil
+}
+
+func (s *Server) handlePush(sess quic.Session, pushes *pushController, pushID uint64, req *http.Request) {
+	str, err := pushes.OpenPushStream(pushID)
+	if err != nil {
+		s.logger.Debugf("Opening push stream for push %d failed: %s", pushID, err)
+		return
+	}
+	if str == nil {
+		s.logger.Debugf("Not sending push %d, since it was canceled by the client", pushID)
+		return
+	}
+	defer pushes.PushCompleted(pushID)
+
+	req.RemoteAddr = sess.RemoteAddr().String()
+	req.Body = http.NoBody
+	ctx := str.Context()
+	ctx = context.WithValue(ctx, ServerContextKey, s)
+	ctx = context.WithValue(ctx, http.LocalAddrContextKey, sess.LocalAddr())
+	req = req.WithContext(ctx)
+	s.logger.Infof("Pushing %s %s%s", req.Method, req.Host, req.RequestURI)
+
+	responseWriter := newResponseWriter(str, s.logger)
+	if s.runHandler(responseWriter, req) {
+		responseWriter.WriteHeader(500)
+	} else {
+		responseWriter.WriteHeader(200)
+	}
+	str.Close()
+}
+
 // Close the server immediately, aborting requests and sending CONNECTION_CLOSE frames to connected clients.
 // Close in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) Close() error {
@@ -314,6 +663,7 @@ func (s *Server) Close() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.shutdown = true
 	var err error
 	for ln := range s.listeners {
 		if cerr := (*ln).Close(); cerr != nil && err == nil {
@@ -324,10 +674,38 @@ func (s *Server) Close() error {
 }
 
 // CloseGracefully shuts down the server gracefully. The server sends a GOAWAY frame first, then waits for either timeout to trigger, or for all running requests to complete.
+// Requests that clients send after receiving the GOAWAY frame are rejected.
 // CloseGracefully in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) CloseGracefully(timeout time.Duration) error {
-	// TODO: implement
-	return nil
+	s.mutex.Lock()
+	s.goingAway = true
+	conns := make([]*serverConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mutex.Unlock()
+
+	for _, c := range conns {
+		c.goAway()
+	}
+
+	s.mutex.Lock()
+	var done <-chan struct{}
+	if s.numRequests > 0 {
+		if s.requestsDone == nil {
+			s.requestsDone = make(chan struct{})
+		}
+		done = s.requestsDone
+	}
+	s.mutex.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-time.After(timeout):
+		}
+	}
+	return s.Close()
 }
 
 // SetQuicHeaders can be used to set the proper headers that announce that this server supports QUIC.
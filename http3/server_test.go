@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"time"
@@ -123,7 +124,7 @@ var _ = Describe("Server", func() {
 				return len(p), nil
 			}).AnyTimes()
 
-			Expect(s.handleRequest(sess, str, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(s.handleRequest(sess, str, qpackDecoder, nil, nil)).To(Equal(requestError{}))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
@@ -141,7 +142,7 @@ var _ = Describe("Server", func() {
 				return responseBuf.Write(p)
 			}).AnyTimes()
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, qpackDecoder, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -160,12 +161,115 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, qpackDecoder, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
 		})
 
+		Context("extended CONNECT", func() {
+			encodeExtendedConnectRequest := func() []byte {
+				req, err := http.NewRequest(http.MethodConnect, "https://www.example.com/session", nil)
+				Expect(err).ToNot(HaveOccurred())
+				req.Proto = "webtransport"
+				buf := &bytes.Buffer{}
+				str := mockquic.NewMockStream(mockCtrl)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return buf.Write(p)
+				}).AnyTimes()
+				Expect(newRequestWriter(utils.DefaultLogger).WriteRequest(str, req, false)).To(Succeed())
+				return buf.Bytes()
+			}
+
+			It("sends the SETTINGS_ENABLE_CONNECT_PROTOCOL setting", func() {
+				s.EnableConnectProtocol = true
+				controlStrBuf := &bytes.Buffer{}
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return controlStrBuf.Write(p)
+				})
+				sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
+				s.handleConn(sess)
+				streamType, err := controlStrBuf.ReadByte()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+				frame, err := parseNextFrame(controlStrBuf)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(BeAssignableToTypeOf(&settingsFrame{}))
+				Expect(frame.(*settingsFrame).settings).To(Equal([]Setting{{ID: settingEnableConnectProtocol, Value: 1}}))
+			})
+
+			It("sends additional settings", func() {
+				s.EnableConnectProtocol = true
+				s.AdditionalSettings = func(defaults []Setting) []Setting {
+					Expect(defaults).To(Equal([]Setting{{ID: settingEnableConnectProtocol, Value: 1}}))
+					return []Setting{{ID: 0x1337, Value: 42}, {ID: settingEnableConnectProtocol, Value: 1}}
+				}
+				controlStrBuf := &bytes.Buffer{}
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(controlStrBuf.Write)
+				sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
+				s.handleConn(sess)
+				_, err := controlStrBuf.ReadByte()
+				Expect(err).ToNot(HaveOccurred())
+				frame, err := parseNextFrame(controlStrBuf)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(BeAssignableToTypeOf(&settingsFrame{}))
+				Expect(frame.(*settingsFrame).settings).To(Equal([]Setting{
+					{ID: 0x1337, Value: 42},
+					{ID: settingEnableConnectProtocol, Value: 1},
+				}))
+			})
+
+			It("closes the connection when additional settings are duplicated", func() {
+				s.AdditionalSettings = func([]Setting) []Setting {
+					return []Setting{{ID: 0x1337, Value: 1}, {ID: 0x1337, Value: 2}}
+				}
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorInternalError), gomock.Any())
+				s.handleConn(sess)
+			})
+
+			It("lets the handler take over the stream", func() {
+				s.EnableConnectProtocol = true
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					Expect(r.Method).To(Equal(http.MethodConnect))
+					Expect(r.Proto).To(Equal("webtransport"))
+					Expect(r.URL.Path).To(Equal("/session"))
+					Expect(w.(DataStreamer).DataStream()).To(Equal(str))
+				})
+
+				responseBuf := &bytes.Buffer{}
+				setRequest(encodeExtendedConnectRequest())
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return responseBuf.Write(p)
+				}).AnyTimes()
+				// don't EXPECT any calls to CancelRead() or Close()
+
+				serr := s.handleRequest(sess, str, qpackDecoder, nil, nil)
+				Expect(serr.err).To(Equal(errDataStreamUsed))
+				hfs := decodeHeader(responseBuf)
+				Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
+				Expect(responseBuf.Len()).To(BeZero())
+			})
+
+			It("rejects extended CONNECT requests if extended CONNECT is not enabled", func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					Fail("handler should not be called")
+				})
+				setRequest(encodeExtendedConnectRequest())
+
+				serr := s.handleRequest(sess, str, qpackDecoder, nil, nil)
+				Expect(serr.err).To(MatchError("received an extended CONNECT request, but extended CONNECT is not enabled"))
+				Expect(serr.streamErr).To(Equal(errorGeneralProtocolError))
+			})
+		})
+
 		Context("stream- and connection-level errors", func() {
 			var sess *mockquic.MockEarlySession
 
@@ -175,8 +279,10 @@ var _ = Describe("Server", func() {
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				controlStr.EXPECT().Write(gomock.Any())
 				sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+				str.EXPECT().StreamID().AnyTimes()
 				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
 				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
 				sess.EXPECT().RemoteAddr().Return(addr).AnyTimes()
 				sess.EXPECT().LocalAddr().AnyTimes()
 			})
@@ -305,7 +411,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, qpackDecoder, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -328,10 +434,349 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, qpackDecoder, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
+
+		Context("server push", func() {
+			var pushes *pushController
+
+			// parsePushPromise parses a PUSH_PROMISE frame, and returns the Push ID and the promised request's headers
+			parsePushPromise := func(r *bytes.Reader) (uint64, map[string]string) {
+				t, err := utils.ReadVarInt(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(t).To(BeEquivalentTo(0x5))
+				l, err := utils.ReadVarInt(r)
+				Expect(err).ToNot(HaveOccurred())
+				pushID, err := utils.ReadVarInt(r)
+				Expect(err).ToNot(HaveOccurred())
+				headerBlock := make([]byte, l-uint64(utils.VarIntLen(pushID)))
+				_, err = io.ReadFull(r, headerBlock)
+				Expect(err).ToNot(HaveOccurred())
+				hfs, err := qpack.NewDecoder(nil).DecodeFull(headerBlock)
+				Expect(err).ToNot(HaveOccurred())
+				fields := make(map[string]string)
+				for _, hf := range hfs {
+					fields[hf.Name] = hf.Value
+				}
+				return pushID, fields
+			}
+
+			BeforeEach(func() {
+				pushes = newPushController(sess)
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+			})
+
+			It("pushes a resource", func() {
+				pushErrChan := make(chan error, 1)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					switch r.URL.Path {
+					case "/":
+						pushErrChan <- w.(http.Pusher).Push("/style.css", &http.PushOptions{
+							Header: http.Header{"Accept-Encoding": {"gzip"}},
+						})
+						w.Write([]byte("index"))
+					case "/style.css":
+						Expect(r.Method).To(Equal(http.MethodGet))
+						Expect(r.Host).To(Equal("www.example.com"))
+						Expect(r.Header.Get("Accept-Encoding")).To(Equal("gzip"))
+						w.Write([]byte("pushed"))
+					}
+				})
+				Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+
+				responseBuf := &bytes.Buffer{}
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return responseBuf.Write(p)
+				}).AnyTimes()
+				pushStr := mockquic.NewMockStream(mockCtrl)
+				pushBuf := &bytes.Buffer{}
+				pushStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return pushBuf.Write(p)
+				}).AnyTimes()
+				pushStr.EXPECT().Context().Return(context.Background())
+				pushDone := make(chan struct{})
+				pushStr.EXPECT().Close().Do(func() { close(pushDone) })
+				sess.EXPECT().OpenUniStream().Return(pushStr, nil)
+
+				serr := s.handleRequest(sess, str, qpackDecoder, pushes, nil)
+				Expect(serr.err).ToNot(HaveOccurred())
+				Expect(pushErrChan).To(Receive(BeNil()))
+
+				// the PUSH_PROMISE is sent on the request stream, before the response
+				r := bytes.NewReader(responseBuf.Bytes())
+				pushID, fields := parsePushPromise(r)
+				Expect(pushID).To(BeZero())
+				Expect(fields).To(Equal(map[string]string{
+					":method":         "GET",
+					":scheme":         "https",
+					":authority":      "www.example.com",
+					":path":           "/style.css",
+					"accept-encoding": "gzip",
+				}))
+				Expect(decodeHeader(r)).To(HaveKeyWithValue(":status", []string{"200"}))
+
+				// the pushed response is sent on the push stream
+				Eventually(pushDone).Should(BeClosed())
+				pr := bytes.NewReader(pushBuf.Bytes())
+				streamType, err := utils.ReadVarInt(pr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(streamType).To(BeEquivalentTo(streamTypePushStream))
+				id, err := utils.ReadVarInt(pr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(id).To(BeZero())
+				Expect(decodeHeader(pr)).To(HaveKeyWithValue(":status", []string{"200"}))
+				frame, err := parseNextFrame(pr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&dataFrame{Length: 6}))
+				data, err := ioutil.ReadAll(pr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(data)).To(Equal("pushed"))
+			})
+
+			It("aborts the pushed response when the client cancels the push", func() {
+				handlerStarted := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					switch r.URL.Path {
+					case "/":
+						Expect(w.(http.Pusher).Push("/large", nil)).To(Succeed())
+					case "/large":
+						close(handlerStarted)
+						<-r.Context().Done()
+						_, err := w.Write([]byte("foobar"))
+						Expect(err).To(HaveOccurred())
+					}
+				})
+				Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				pushStr := mockquic.NewMockStream(mockCtrl)
+				pushCtx, cancelPush := context.WithCancel(context.Background())
+				canceled := make(chan struct{})
+				pushStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					select {
+					case <-canceled:
+						return 0, errors.New("canceled")
+					default:
+						return len(p), nil
+					}
+				}).AnyTimes()
+				pushStr.EXPECT().Context().Return(pushCtx)
+				pushStr.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled)).Do(func(quic.ErrorCode) {
+					close(canceled)
+					cancelPush()
+				})
+				pushDone := make(chan struct{})
+				pushStr.EXPECT().Close().Do(func() { close(pushDone) })
+				sess.EXPECT().OpenUniStream().Return(pushStr, nil)
+
+				serr := s.handleRequest(sess, str, qpackDecoder, pushes, nil)
+				Expect(serr.err).ToNot(HaveOccurred())
+				Eventually(handlerStarted).Should(BeClosed())
+				Expect(pushes.HandleCancelPush(0)).To(Succeed())
+				Eventually(pushDone).Should(BeClosed())
+			})
+
+			It("doesn't push if the client didn't send a MAX_PUSH_ID frame", func() {
+				pushErrChan := make(chan error, 1)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					pushErrChan <- w.(http.Pusher).Push("/style.css", nil)
+				})
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+
+				serr := s.handleRequest(sess, str, qpackDecoder, pushes, nil)
+				Expect(serr.err).ToNot(HaveOccurred())
+				Expect(pushErrChan).To(Receive(MatchError(http.ErrNotSupported)))
+			})
+
+			It("rejects pushes to a different origin", func() {
+				pushErrChan := make(chan error, 1)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					pushErrChan <- w.(http.Pusher).Push("https://quic.clemente.io/style.css", nil)
+				})
+				Expect(pushes.HandleMaxPushID(10)).To(Succeed())
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+
+				serr := s.handleRequest(sess, str, qpackDecoder, pushes, nil)
+				Expect(serr.err).ToNot(HaveOccurred())
+				Expect(pushErrChan).To(Receive(MatchError("http3: cannot push https://quic.clemente.io/style.css, it is not on the same origin as the request")))
+			})
+		})
+	})
+
+	Context("GOAWAY", func() {
+		It("rejects requests sent after the GOAWAY frame", func() {
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			conn := &serverConn{controlStream: controlStr}
+			Expect(conn.acceptRequest(0)).To(BeTrue())
+			Expect(conn.acceptRequest(4)).To(BeTrue())
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 8}).Write(buf)
+			controlStr.EXPECT().Write(buf.Bytes())
+			conn.goAway()
+			conn.goAway() // only sends a single GOAWAY frame
+			Expect(conn.acceptRequest(8)).To(BeFalse())
+			Expect(conn.acceptRequest(12)).To(BeFalse())
+		})
+
+		It("sends GOAWAY on new connections when closing gracefully", func() {
+			s.CloseGracefully(0)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0}).Write(buf)
+			controlStr.EXPECT().Write(buf.Bytes())
+			conn := &serverConn{controlStream: controlStr}
+			s.addConn(conn)
+			Expect(conn.acceptRequest(0)).To(BeFalse())
+		})
+
+		It("waits for running requests when closing gracefully", func() {
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Write(gomock.Any())
+			conn := &serverConn{controlStream: controlStr}
+			s.addConn(conn)
+			Expect(conn.acceptRequest(0)).To(BeTrue())
+			Expect(s.addRequest()).To(BeTrue())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(s.CloseGracefully(time.Hour)).To(Succeed())
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			// requests that are accepted while waiting are waited for as well
+			Expect(s.addRequest()).To(BeTrue())
+			s.removeRequest()
+			Consistently(done).ShouldNot(BeClosed())
+			s.removeRequest()
+			Eventually(done).Should(BeClosed())
+			// no new requests are accepted after the server was shut down
+			Expect(s.addRequest()).To(BeFalse())
+		})
+	})
+
+	Context("control stream", func() {
+		var (
+			sess   *mockquic.MockEarlySession
+			pushes *pushController
+		)
+
+		BeforeEach(func() {
+			sess = mockquic.NewMockEarlySession(mockCtrl)
+			pushes = newPushController(sess)
+		})
+
+		controlStream := func(frames ...interface{ Write(*bytes.Buffer) }) *mockquic.MockStream {
+			buf := &bytes.Buffer{}
+			for _, f := range frames {
+				f.Write(buf)
+			}
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				if buf.Len() == 0 {
+					return 0, io.EOF
+				}
+				return buf.Read(p)
+			}).AnyTimes()
+			return str
+		}
+
+		It("handles MAX_PUSH_ID and CANCEL_PUSH frames", func() {
+			str := controlStream(&settingsFrame{}, &maxPushIDFrame{PushID: 5}, &cancelPushFrame{PushID: 3})
+			// pretend that pushes 0 to 3 were promised
+			pushes.nextPushID = 4
+			pushes.promised[3] = struct{}{}
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			s.handleControlStream(sess, str, pushes)
+			Eventually(done).Should(BeClosed())
+			Expect(pushes.maxPushID).To(BeEquivalentTo(5))
+			Expect(pushes.canceled).To(HaveKey(uint64(3)))
+		})
+
+		It("surfaces the client's settings", func() {
+			var received []Setting
+			s.ReceivedSettings = func(settings []Setting) { received = settings }
+			str := controlStream(&settingsFrame{settings: []Setting{{ID: 0x1337, Value: 42}}})
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			s.handleControlStream(sess, str, pushes)
+			Eventually(done).Should(BeClosed())
+			Expect(received).To(Equal([]Setting{{ID: 0x1337, Value: 42}}))
+		})
+
+		It("closes the connection when the first frame is not a SETTINGS frame", func() {
+			str := controlStream(&maxPushIDFrame{PushID: 5})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorMissingSettings), gomock.Any())
+			s.handleControlStream(sess, str, pushes)
+		})
+
+		It("closes the connection when the client reduces the maximum Push ID", func() {
+			str := controlStream(&settingsFrame{}, &maxPushIDFrame{PushID: 5}, &maxPushIDFrame{PushID: 4})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any())
+			s.handleControlStream(sess, str, pushes)
+		})
+
+		It("closes the connection when a CANCEL_PUSH frame exceeds the maximum Push ID", func() {
+			str := controlStream(&settingsFrame{}, &cancelPushFrame{PushID: 0})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any())
+			s.handleControlStream(sess, str, pushes)
+		})
+
+		It("closes the connection when a CANCEL_PUSH frame is received for a push that wasn't promised", func() {
+			str := controlStream(&settingsFrame{}, &maxPushIDFrame{PushID: 5}, &cancelPushFrame{PushID: 0})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any())
+			s.handleControlStream(sess, str, pushes)
+		})
+
+		It("closes the connection when the client opens a push stream", func() {
+			buf := &bytes.Buffer{}
+			utils.WriteVarInt(buf, streamTypePushStream)
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			done := make(chan struct{})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(str, nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done"))
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorStreamCreationError), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+			s.handleUnidirectionalStreams(sess, pushes)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection when the client opens a second control stream", func() {
+			newControlStream := func() *mockquic.MockStream {
+				buf := &bytes.Buffer{}
+				utils.WriteVarInt(buf, streamTypeControlStream)
+				(&settingsFrame{}).Write(buf)
+				str := mockquic.NewMockStream(mockCtrl)
+				// block after the SETTINGS frame, so that the control stream stays open
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					if buf.Len() == 0 {
+						<-sess.Context().Done()
+						return 0, errors.New("session closed")
+					}
+					return buf.Read(p)
+				}).AnyTimes()
+				return str
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			sess.EXPECT().Context().Return(ctx).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(newControlStream(), nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(newControlStream(), nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done"))
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream").Do(func(quic.ErrorCode, string) { cancel() })
+			s.handleUnidirectionalStreams(sess, pushes)
+			Eventually(ctx.Done()).Should(BeClosed())
+		})
 	})
 
 	Context("setting http headers", func() {
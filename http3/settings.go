@@ -0,0 +1,79 @@
+package http3
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A Setting is an HTTP/3 setting, as sent in the SETTINGS frame.
+type Setting struct {
+	ID    uint64
+	Value uint64
+}
+
+// standardSettingIDs are the identifiers of the settings defined by the HTTP/3 and QPACK specifications
+// and by the extensions supported by this package.
+// The identifiers 0x0 and 0x2 to 0x5 are reserved, since they were used by HTTP/2.
+var standardSettingIDs = map[uint64]struct{}{
+	0x0:                          {},
+	0x1:                          {}, // SETTINGS_QPACK_MAX_TABLE_CAPACITY
+	0x2:                          {},
+	0x3:                          {},
+	0x4:                          {},
+	0x5:                          {},
+	0x6:                          {}, // SETTINGS_MAX_FIELD_SECTION_SIZE
+	0x7:                          {}, // SETTINGS_QPACK_BLOCKED_STREAMS
+	settingEnableConnectProtocol: {},
+}
+
+// get returns the value of a setting.
+func (f *settingsFrame) get(id uint64) (uint64, bool) {
+	for _, s := range f.settings {
+		if s.ID == id {
+			return s.Value, true
+		}
+	}
+	return 0, false
+}
+
+// list returns the settings, ordered by identifier.
+func (f *settingsFrame) list() []Setting {
+	settings := make([]Setting, len(f.settings))
+	copy(settings, f.settings)
+	sort.Slice(settings, func(i, j int) bool { return settings[i].ID < settings[j].ID })
+	return settings
+}
+
+// setAdditional replaces the settings with the list returned by the application,
+// which is written in the given order.
+// The list must contain the default settings with their values.
+// It rejects other settings that use the identifier of a standard setting, and duplicate settings.
+func (f *settingsFrame) setAdditional(settings []Setting) error {
+	defaults := make(map[uint64]uint64, len(f.settings))
+	for _, s := range f.settings {
+		defaults[s.ID] = s.Value
+	}
+	seen := make(map[uint64]struct{}, len(settings))
+	for _, s := range settings {
+		if _, ok := seen[s.ID]; ok {
+			return fmt.Errorf("http3: duplicate setting %#x", s.ID)
+		}
+		seen[s.ID] = struct{}{}
+		if val, ok := defaults[s.ID]; ok {
+			if s.Value != val {
+				return fmt.Errorf("http3: changed the value of default setting %#x", s.ID)
+			}
+			continue
+		}
+		if _, ok := standardSettingIDs[s.ID]; ok {
+			return fmt.Errorf("http3: setting %#x is a standard setting", s.ID)
+		}
+	}
+	for id := range defaults {
+		if _, ok := seen[id]; !ok {
+			return fmt.Errorf("http3: missing default setting %#x", id)
+		}
+	}
+	f.settings = settings
+	return nil
+}
@@ -0,0 +1,58 @@
+package http3
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Settings", func() {
+	It("lists the settings, ordered by identifier", func() {
+		f := &settingsFrame{settings: []Setting{{ID: 0x1337, Value: 1}, {ID: settingEnableConnectProtocol, Value: 1}, {ID: 0x42, Value: 2}}}
+		Expect(f.list()).To(Equal([]Setting{
+			{ID: settingEnableConnectProtocol, Value: 1},
+			{ID: 0x42, Value: 2},
+			{ID: 0x1337, Value: 1},
+		}))
+		// the frame itself keeps its order
+		Expect(f.settings[0].ID).To(BeEquivalentTo(0x1337))
+		Expect((&settingsFrame{}).list()).To(BeEmpty())
+	})
+
+	It("gets settings", func() {
+		f := &settingsFrame{settings: []Setting{{ID: 0x1337, Value: 1}, {ID: 0x42, Value: 2}}}
+		val, ok := f.get(0x42)
+		Expect(ok).To(BeTrue())
+		Expect(val).To(BeEquivalentTo(2))
+		_, ok = f.get(0x43)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("sets additional settings, keeping their order", func() {
+		f := &settingsFrame{settings: []Setting{{ID: settingEnableConnectProtocol, Value: 1}}}
+		settings := []Setting{{ID: 0xdead, Value: 0}, {ID: settingEnableConnectProtocol, Value: 1}, {ID: 0x1337, Value: 42}}
+		Expect(f.setAdditional(settings)).To(Succeed())
+		Expect(f.settings).To(Equal(settings))
+	})
+
+	It("rejects additional settings that use the identifier of a standard setting", func() {
+		for _, id := range []uint64{0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, settingEnableConnectProtocol} {
+			f := &settingsFrame{}
+			Expect(f.setAdditional([]Setting{{ID: id, Value: 1}})).To(MatchError(ContainSubstring("is a standard setting")))
+		}
+	})
+
+	It("rejects duplicate additional settings", func() {
+		f := &settingsFrame{}
+		Expect(f.setAdditional([]Setting{{ID: 0x1337, Value: 1}, {ID: 0x1337, Value: 2}})).To(MatchError("http3: duplicate setting 0x1337"))
+	})
+
+	It("rejects settings that don't contain the default settings", func() {
+		f := &settingsFrame{settings: []Setting{{ID: settingEnableConnectProtocol, Value: 1}}}
+		Expect(f.setAdditional([]Setting{{ID: 0x1337, Value: 1}})).To(MatchError("http3: missing default setting 0x8"))
+	})
+
+	It("rejects settings that change the value of a default setting", func() {
+		f := &settingsFrame{settings: []Setting{{ID: settingEnableConnectProtocol, Value: 1}}}
+		Expect(f.setAdditional([]Setting{{ID: settingEnableConnectProtocol, Value: 0}})).To(MatchError("http3: changed the value of default setting 0x8"))
+	})
+})
@@ -0,0 +1,80 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bandwidth estimation", func() {
+	It("estimates the bandwidth of a rate-limited link", func() {
+		const rate = 512 * 1024 // bytes per second
+		const delay = 5 * time.Millisecond
+		data := GeneratePRData(3 * rate)
+
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverSess := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			serverSess <- sess
+		}()
+
+		// Packets from the server to the client are sent over a link that transmits rate bytes per second.
+		var mutex sync.Mutex
+		var linkFree time.Time
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(dir quicproxy.Direction, packet []byte) time.Duration {
+				if dir == quicproxy.DirectionIncoming {
+					return delay
+				}
+				mutex.Lock()
+				defer mutex.Unlock()
+				now := time.Now()
+				if linkFree.Before(now) {
+					linkFree = now
+				}
+				linkFree = linkFree.Add(time.Duration(len(packet)) * time.Second / rate)
+				return linkFree.Sub(now) + delay
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		received, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received).To(Equal(data))
+
+		var sSess quic.Session
+		Eventually(serverSess).Should(Receive(&sSess))
+		Expect(sSess.EstimatedBandwidth()).To(BeNumerically("~", rate, rate/4))
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+	})
+})
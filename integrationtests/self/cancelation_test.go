@@ -60,6 +60,57 @@ var _ = Describe("Stream Cancelations", func() {
 			Expect(server.Close()).To(Succeed())
 		})
 
+		It("makes the peer's writes fail with the error code on a bidirectional stream", func() {
+			var err error
+			server, err = quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			serverErrChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				sess, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := sess.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				// read the first chunk, then tell the peer to stop sending
+				_, err = io.ReadFull(str, make([]byte, 100))
+				Expect(err).ToNot(HaveOccurred())
+				str.CancelRead(1234)
+				// the write side is unaffected by canceling the read side
+				_, err = str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				serverErrChan <- str.Close()
+			}()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				nil,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData[:100])
+			Expect(err).ToNot(HaveOccurred())
+			// keep writing until the STOP_SENDING frame arrives
+			Eventually(func() error {
+				_, err := str.Write(PRData[:100])
+				return err
+			}).Should(HaveOccurred())
+			_, err = str.Write([]byte("foo"))
+			serr, ok := err.(quic.StreamError)
+			Expect(ok).To(BeTrue())
+			Expect(serr.Canceled()).To(BeTrue())
+			Expect(serr.ErrorCode()).To(Equal(quic.ErrorCode(1234)))
+			Expect(err).To(MatchError(fmt.Sprintf("stream %d was reset with error code 1234", str.StreamID())))
+			// the read side of the stream is still usable
+			Eventually(serverErrChan).Should(Receive(BeNil()))
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(sess.CloseWithError(0, "")).To(Succeed())
+		})
+
 		It("downloads when the client immediately cancels most streams", func() {
 			serverCanceledCounterChan := runServer()
 			sess, err := quic.DialAddr(
@@ -254,6 +305,62 @@ var _ = Describe("Stream Cancelations", func() {
 			clientCanceledStreams := runClient(server)
 			Expect(clientCanceledStreams).To(Equal(atomic.LoadInt32(&canceledCounter)))
 		})
+		It("delivers data up to the reliable size when the server cancels streams", func() {
+			server, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			var mutex sync.Mutex
+			reliableSizes := make(map[quic.StreamID]int)
+			go func() {
+				defer GinkgoRecover()
+				sess, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				for i := 0; i < numStreams; i++ {
+					go func() {
+						defer GinkgoRecover()
+						str, err := sess.OpenUniStreamSync(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						length := 1 + int(rand.Int31n(int32(len(PRData)-1)))
+						_, err = str.Write(PRData[:length])
+						Expect(err).ToNot(HaveOccurred())
+						reliableSize := rand.Intn(length)
+						mutex.Lock()
+						reliableSizes[str.StreamID()] = reliableSize
+						mutex.Unlock()
+						Expect(str.CancelWriteAt(quic.ErrorCode(str.StreamID()), uint64(reliableSize))).To(Succeed())
+					}()
+				}
+			}()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				&quic.Config{MaxIncomingUniStreams: numStreams / 2},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+
+			var wg sync.WaitGroup
+			wg.Add(numStreams)
+			for i := 0; i < numStreams; i++ {
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					str, err := sess.AcceptUniStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					data, err := ioutil.ReadAll(str)
+					Expect(err).To(MatchError(fmt.Sprintf("stream %d was reset with error code %d", str.StreamID(), str.StreamID())))
+					// Data beyond the reliable size might have been received before the RESET_STREAM_AT frame.
+					Expect(data).To(Equal(PRData[:len(data)]))
+					mutex.Lock()
+					reliableSize := reliableSizes[str.StreamID()]
+					mutex.Unlock()
+					Expect(len(data)).To(BeNumerically(">=", reliableSize))
+				}()
+			}
+			wg.Wait()
+		})
 	})
 
 	Context("canceling both read and write side", func() {
@@ -513,7 +620,7 @@ var _ = Describe("Stream Cancelations", func() {
 					str, err := sess.OpenUniStreamSync(ctx)
 					if err != nil {
 						atomic.AddInt32(&numCanceled, 1)
-						Expect(err).To(MatchError("context canceled"))
+						Expect(err).To(MatchError(context.Canceled))
 						continue
 					}
 					numOpened++
@@ -0,0 +1,78 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Congestion control", func() {
+	for _, a := range []struct {
+		name      string
+		algorithm quic.CongestionControlAlgorithm
+	}{
+		{name: "Cubic", algorithm: quic.CongestionControlCubic},
+		{name: "Reno", algorithm: quic.CongestionControlReno},
+	} {
+		algorithm := a.algorithm
+
+		It(fmt.Sprintf("switches to %s in the middle of a transfer", a.name), func() {
+			data := GeneratePRData(2 * 1024 * 1024)
+
+			server, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			serverSess := make(chan quic.Session, 1)
+			go func() {
+				defer GinkgoRecover()
+				sess, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := sess.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(data[:len(data)/2])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sess.SetCongestionControl(algorithm)).To(Succeed())
+				_, err = str.Write(data[len(data)/2:])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				serverSess <- sess
+			}()
+
+			// Drop some packets, so that the switch may happen while recovering from a loss.
+			proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+				RemoteAddr:  fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 5 * time.Millisecond },
+				DropPacket: func(dir quicproxy.Direction, _ []byte) bool {
+					return dir == quicproxy.DirectionOutgoing && rand.Intn(100) == 0
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer proxy.Close()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+				getTLSClientConfig(),
+				nil,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			received, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(received).To(Equal(data))
+
+			Eventually(serverSess).Should(Receive())
+			Expect(sess.CloseWithError(0, "")).To(Succeed())
+		})
+	}
+})
@@ -88,3 +88,64 @@ var _ = Describe("Connection ID lengths tests", func() {
 		runClient(ln.Addr(), clientConf)
 	})
 })
+
+var _ = Describe("Connection ID state", func() {
+	It("reports connection IDs after they were issued and retired", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), &quic.Config{ConnectionIDLength: 8})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		serverSess := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			serverSess <- sess
+		}()
+
+		cl, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer cl.CloseWithError(0, "")
+		str, err := cl.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+
+		var sess quic.Session
+		Eventually(serverSess).Should(Receive(&sess))
+		// The server issued new connection IDs using NEW_CONNECTION_ID frames,
+		// and the client switched to a new connection ID, retiring the initial one.
+		clientConnIDs := cl.ConnectionIDs()
+		Expect(clientConnIDs.Peer).ToNot(BeEmpty())
+		Expect(clientConnIDs.Peer[0].SequenceNumber).ToNot(BeZero())
+		var inUse int
+		for _, c := range clientConnIDs.Peer {
+			if c.InUse {
+				inUse++
+			}
+		}
+		Expect(inUse).To(Equal(1))
+		// The client uses a zero-length connection ID, and therefore doesn't issue any connection IDs.
+		Expect(clientConnIDs.Local).To(HaveLen(1))
+		Expect(clientConnIDs.HighestLocalSequenceNumber).To(BeZero())
+
+		Eventually(func() uint64 { return sess.ConnectionIDs().Local[0].SequenceNumber }).ShouldNot(BeZero())
+		serverConnIDs := sess.ConnectionIDs()
+		Expect(serverConnIDs.HighestLocalSequenceNumber).To(BeNumerically(">", 0))
+		Expect(serverConnIDs.Local[len(serverConnIDs.Local)-1].SequenceNumber).To(Equal(serverConnIDs.HighestLocalSequenceNumber))
+		for i, c := range serverConnIDs.Local {
+			Expect(c.ConnectionID).To(HaveLen(8))
+			Expect(c.ConnectionID).To(Equal(clientConnIDs.Peer[i].ConnectionID))
+		}
+	})
+})
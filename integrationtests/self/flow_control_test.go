@@ -0,0 +1,89 @@
+package self_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Flow control", func() {
+	// sentFrames returns the frames of the given type contained in sent packets
+	sentFrames := func(qlog []byte, frameType string) []map[string]interface{} {
+		var q map[string]interface{}
+		ExpectWithOffset(1, json.Unmarshal(qlog, &q)).To(Succeed())
+		var frames []map[string]interface{}
+		for _, e := range q["traces"].([]interface{})[0].(map[string]interface{})["events"].([]interface{}) {
+			ev := e.([]interface{})
+			if ev[2].(string) != "packet_sent" {
+				continue
+			}
+			fs, ok := ev[3].(map[string]interface{})["frames"]
+			if !ok {
+				continue
+			}
+			for _, f := range fs.([]interface{}) {
+				frame := f.(map[string]interface{})
+				if frame["frame_type"] == frameType {
+					frames = append(frames, frame)
+				}
+			}
+		}
+		return frames
+	}
+
+	It("sends BLOCKED frames when it is blocked by flow control", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		// The server accepts the streams, but never reads from them.
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			for {
+				if _, err := sess.AcceptStream(context.Background()); err != nil {
+					return
+				}
+			}
+		}()
+
+		clientQlog := newQlogBuffer()
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			&quic.Config{GetLogWriter: func([]byte) io.WriteCloser { return clientQlog }},
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		// The first stream uses up its stream-level flow control window,
+		// the second one the rest of the connection-level flow control window.
+		for i := 0; i < 2; i++ {
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.SetWriteDeadline(time.Now().Add(500 * time.Millisecond))).To(Succeed())
+			_, err = str.Write(GeneratePRData(2 * protocol.InitialMaxStreamData))
+			Expect(err).To(HaveOccurred())
+			Expect(err.(net.Error).Timeout()).To(BeTrue())
+		}
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+		Eventually(clientQlog.closed).Should(BeClosed())
+
+		streamDataBlocked := sentFrames(clientQlog.Bytes(), "stream_data_blocked")
+		Expect(streamDataBlocked).To(HaveLen(1))
+		Expect(streamDataBlocked[0]["limit"]).To(Equal(strconv.Itoa(protocol.InitialMaxStreamData)))
+		dataBlocked := sentFrames(clientQlog.Bytes(), "data_blocked")
+		Expect(dataBlocked).To(HaveLen(1))
+		Expect(dataBlocked[0]["limit"]).To(Equal(strconv.Itoa(protocol.InitialMaxData)))
+	})
+})
@@ -134,13 +134,14 @@ var _ = Describe("Handshake RTT tests", func() {
 		}
 		serverTLSConfig.CurvePreferences = []tls.CurveID{tls.CurveP384}
 		runServerAndProxy()
-		_, err := quic.DialAddr(
+		sess, err := quic.DialAddr(
 			fmt.Sprintf("localhost:%d", proxy.LocalAddr().(*net.UDPAddr).Port),
 			getTLSClientConfig(),
 			clientConfig,
 		)
 		Expect(err).ToNot(HaveOccurred())
 		expectDurationInRTTs(2)
+		Expect(sess.UsedHelloRetryRequest()).To(BeTrue())
 	})
 
 	It("doesn't complete the handshake when the server never accepts the token", func() {
@@ -1,25 +1,30 @@
 package self_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
+	"sync"
 	"time"
 
 	quic "github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/integrationtests/tools/israce"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/marten-seemann/qtls"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
-type versioner interface {
-	GetVersion() protocol.VersionNumber
-}
-
 type tokenStore struct {
 	store quic.TokenStore
 	gets  chan<- string
@@ -111,7 +116,7 @@ var _ = Describe("Handshake tests", func() {
 					nil,
 				)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.SupportedVersions[0]))
+				Expect(sess.GetVersion()).To(Equal(protocol.SupportedVersions[0]))
 				Expect(sess.CloseWithError(0, "")).To(Succeed())
 			})
 
@@ -130,7 +135,112 @@ var _ = Describe("Handshake tests", func() {
 					conf,
 				)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.SupportedVersions[0]))
+				Expect(sess.GetVersion()).To(Equal(protocol.SupportedVersions[0]))
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+			})
+
+			It("reports the negotiated version on both sides", func() {
+				serverConfig.Versions = []protocol.VersionNumber{7, 8, protocol.SupportedVersions[0], 9}
+				ln, err := quic.ListenAddr("localhost:0", tlsServerConf, serverConfig)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				serverSessChan := make(chan quic.Session, 1)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					serverSessChan <- sess
+				}()
+				conf := &quic.Config{
+					Versions: []protocol.VersionNumber{10, protocol.SupportedVersions[0], 7},
+				}
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					conf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				Expect(sess.GetVersion()).To(Equal(protocol.SupportedVersions[0]))
+				var serverSess quic.Session
+				Eventually(serverSessChan).Should(Receive(&serverSess))
+				Expect(serverSess.GetVersion()).To(Equal(protocol.SupportedVersions[0]))
+			})
+
+			It("ignores an injected Version Negotiation packet that lists the offered version", func() {
+				// An attacker could inject a Version Negotiation packet to make the client use a different version.
+				// The packet lists the version that the client offered, so it can't have been sent by the server.
+				server := runServer()
+				defer server.Close()
+
+				clientConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				defer clientConn.Close()
+				attackerConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				defer attackerConn.Close()
+
+				injected := make(chan struct{})
+				var mutex sync.Mutex
+				// If the client accepted the Version Negotiation packet, it would start a new connection,
+				// and send a second ClientHello to the Destination Connection ID that it chose itself.
+				serverConnIDs := make(map[string]struct{})
+				var numClientHellos int
+				proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+					RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+					DropPacket: func(dir quicproxy.Direction, data []byte) bool {
+						hdr, _, _, err := wire.ParsePacket(data, 0)
+						Expect(err).ToNot(HaveOccurred())
+						if !hdr.IsLongHeader {
+							return false
+						}
+						mutex.Lock()
+						defer mutex.Unlock()
+						if dir == quicproxy.DirectionOutgoing {
+							serverConnIDs[string(hdr.SrcConnectionID)] = struct{}{}
+							return false
+						}
+						if hdr.Type != protocol.PacketTypeInitial {
+							return false
+						}
+						if _, ok := serverConnIDs[string(hdr.DestConnectionID)]; !ok {
+							numClientHellos++
+						}
+						select {
+						case <-injected:
+							return false
+						default:
+						}
+						defer close(injected)
+						vn, err := wire.ComposeVersionNegotiation(
+							hdr.SrcConnectionID,
+							hdr.DestConnectionID,
+							[]protocol.VersionNumber{7, hdr.Version},
+						)
+						Expect(err).ToNot(HaveOccurred())
+						_, err = attackerConn.WriteTo(vn, clientConn.LocalAddr())
+						Expect(err).ToNot(HaveOccurred())
+						return false
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				defer proxy.Close()
+
+				sess, err := quic.Dial(
+					clientConn,
+					proxy.LocalAddr(),
+					"localhost",
+					getTLSClientConfig(),
+					&quic.Config{Versions: []protocol.VersionNumber{protocol.SupportedVersions[0], 7}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(injected).To(BeClosed())
+				Expect(sess.GetVersion()).To(Equal(protocol.SupportedVersions[0]))
+				// Give the client some time to process the Version Negotiation packet.
+				time.Sleep(50 * time.Millisecond)
+				mutex.Lock()
+				Expect(numClientHellos).To(Equal(1))
+				mutex.Unlock()
 				Expect(sess.CloseWithError(0, "")).To(Succeed())
 			})
 		})
@@ -236,6 +346,38 @@ var _ = Describe("Handshake tests", func() {
 					Expect(err).To(MatchError("CRYPTO_ERROR: tls: bad certificate"))
 				})
 
+				It("exposes the client certificate chain if the client provides a valid client cert", func() {
+					tlsServerConf.ClientAuth = tls.RequireAndVerifyClientCert
+					tlsServerConf.ClientCAs = testdata.GetRootCA()
+					ln, err := quic.ListenAddr("localhost:0", tlsServerConf, serverConfig)
+					Expect(err).ToNot(HaveOccurred())
+					defer ln.Close()
+
+					sessChan := make(chan quic.Session, 1)
+					go func() {
+						defer GinkgoRecover()
+						sess, err := ln.Accept(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						sessChan <- sess
+					}()
+
+					tlsConf := getTLSClientConfig()
+					tlsConf.Certificates = testdata.GetTLSConfig().Certificates
+					sess, err := quic.DialAddr(
+						fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+						tlsConf,
+						clientConfig,
+					)
+					Expect(err).ToNot(HaveOccurred())
+					defer sess.CloseWithError(0, "")
+					var serverSess quic.Session
+					Eventually(sessChan).Should(Receive(&serverSess))
+					state := serverSess.ConnectionState()
+					Expect(state.PeerCertificates).ToNot(BeEmpty())
+					Expect(state.PeerCertificates[0].Raw).To(Equal(tlsConf.Certificates[0].Certificate[0]))
+					Expect(state.VerifiedChains).ToNot(BeEmpty())
+				})
+
 				It("uses the ServerName in the tls.Config", func() {
 					tlsConf := getTLSClientConfig()
 					tlsConf.ServerName = "localhost"
@@ -398,6 +540,113 @@ var _ = Describe("Handshake tests", func() {
 		})
 	})
 
+	Context("using an external TLS stack", func() {
+		// newTLSConn uses qtls as an external TLS stack, and records the QUIC transport parameters sent by the peer.
+		newTLSConn := func(peerParams chan<- []byte) func(*tls.Config, quic.TLSRecordLayer, []byte, bool) quic.TLSConn {
+			return func(conf *tls.Config, rl quic.TLSRecordLayer, params []byte, isClient bool) quic.TLSConn {
+				sendParamsIn, receiveParamsIn := uint8(8), uint8(1) // EncryptedExtensions, ClientHello
+				if isClient {
+					sendParamsIn, receiveParamsIn = receiveParamsIn, sendParamsIn
+				}
+				qconf := &qtls.Config{
+					ServerName:             conf.ServerName,
+					RootCAs:                conf.RootCAs,
+					InsecureSkipVerify:     conf.InsecureSkipVerify,
+					NextProtos:             conf.NextProtos,
+					MinVersion:             qtls.VersionTLS13,
+					AlternativeRecordLayer: &qtlsRecordLayer{rl: rl, writeLevel: quic.TLSEncryptionInitial},
+					GetExtensions: func(msgType uint8) []qtls.Extension {
+						if msgType != sendParamsIn {
+							return nil
+						}
+						return []qtls.Extension{{Type: 0xffa5, Data: params}}
+					},
+					ReceivedExtensions: func(msgType uint8, exts []qtls.Extension) {
+						if msgType != receiveParamsIn {
+							return
+						}
+						for _, ext := range exts {
+							if ext.Type == 0xffa5 {
+								peerParams <- ext.Data
+								rl.ReceivedTransportParameters(ext.Data)
+							}
+						}
+					},
+				}
+				for _, c := range conf.Certificates {
+					qconf.Certificates = append(qconf.Certificates, qtls.Certificate{Certificate: c.Certificate, PrivateKey: c.PrivateKey})
+				}
+				if isClient {
+					return qtls.Client(nil, qconf)
+				}
+				return qtls.Server(nil, qconf)
+			}
+		}
+
+		It("exchanges the transport parameters", func() {
+			serverParamsChan := make(chan []byte, 1)
+			clientParamsChan := make(chan []byte, 1)
+			serverConfig.MaxIncomingStreams = 1234
+			serverConfig.NewTLSConn = newTLSConn(clientParamsChan)
+			server := runServer()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				&quic.Config{
+					MaxIncomingStreams: 4321,
+					NewTLSConn:         newTLSConn(serverParamsChan),
+				},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+
+			Expect(sess.ConnectionState().NegotiatedProtocol).To(Equal(alpn))
+			var data []byte
+			Expect(serverParamsChan).To(Receive(&data))
+			serverParams := &handshake.TransportParameters{}
+			Expect(serverParams.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(serverParams.MaxBidiStreamNum).To(BeEquivalentTo(1234))
+			Eventually(clientParamsChan).Should(Receive(&data))
+			clientParams := &handshake.TransportParameters{}
+			Expect(clientParams.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(clientParams.MaxBidiStreamNum).To(BeEquivalentTo(4321))
+		})
+
+		It("sends the transport parameters in the configured order", func() {
+			clientParamsChan := make(chan []byte, 1)
+			serverConfig.NewTLSConn = newTLSConn(clientParamsChan)
+			server := runServer()
+
+			// initial_max_data (0x4) and max_idle_timeout (0x1) are sent in the reverse of the default order
+			order := []uint64{0x4, 0x1}
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				&quic.Config{TransportParameterOrder: order},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+
+			var data []byte
+			Eventually(clientParamsChan).Should(Receive(&data))
+			r := bytes.NewReader(data)
+			var ids []uint64
+			for r.Len() > 0 {
+				id, err := utils.ReadVarInt(r)
+				Expect(err).ToNot(HaveOccurred())
+				l, err := utils.ReadVarInt(r)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = r.Seek(int64(l), io.SeekCurrent)
+				Expect(err).ToNot(HaveOccurred())
+				ids = append(ids, id)
+			}
+			// the greased parameter is sent first
+			Expect(ids[0] % 31).To(BeEquivalentTo(27))
+			Expect(ids[1:3]).To(Equal(order))
+		})
+	})
+
 	Context("using tokens", func() {
 		It("uses tokens provided in NEW_TOKEN frames", func() {
 			tokenChan := make(chan *quic.Token, 100)
@@ -456,6 +705,35 @@ var _ = Describe("Handshake tests", func() {
 		})
 	})
 
+	It("performs a Retry if address validation is required", func() {
+		tokenChan := make(chan *quic.Token, 10)
+		serverConfig.AcceptToken = func(addr net.Addr, token *quic.Token) bool {
+			tokenChan <- token
+			return true
+		}
+		serverConfig.RequireAddressValidation = func(addr net.Addr) bool {
+			return addr.(*net.UDPAddr).IP.IsLoopback()
+		}
+
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		Expect(tokenChan).To(HaveLen(2))
+		token := <-tokenChan
+		Expect(token).To(BeNil())
+		token = <-tokenChan
+		Expect(token).ToNot(BeNil())
+		Expect(token.IsRetryToken).To(BeTrue())
+	})
+
 	It("rejects invalid Retry token with the INVALID_TOKEN error", func() {
 		tokenChan := make(chan *quic.Token, 10)
 		serverConfig.AcceptToken = func(addr net.Addr, token *quic.Token) bool {
@@ -481,4 +759,159 @@ var _ = Describe("Handshake tests", func() {
 		Expect(token).ToNot(BeNil())
 		Expect(token.IsRetryToken).To(BeTrue())
 	})
+
+	It("reports the packet numbers used in the packet number spaces", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		// The client drops the Initial packet number space when it sends its first Handshake packet,
+		// and the Handshake packet number space when the handshake is confirmed.
+		Expect(sess.PacketNumberSpaces().Initial.Dropped).To(BeTrue())
+		Eventually(func() bool { return sess.PacketNumberSpaces().Handshake.Dropped }).Should(BeTrue())
+
+		before := sess.PacketNumberSpaces().AppData
+		Expect(before.Dropped).To(BeFalse())
+		Expect(before.NextPacketNumber).To(BeNumerically(">", before.LargestSent))
+		str, err := sess.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+
+		Eventually(func() int64 { return sess.PacketNumberSpaces().AppData.LargestAcked }).Should(BeNumerically(">", before.LargestSent))
+		after := sess.PacketNumberSpaces().AppData
+		Expect(after.LargestSent).To(BeNumerically(">", before.LargestSent))
+		Expect(after.NextPacketNumber).To(BeNumerically(">", after.LargestSent))
+		Expect(after.LargestAcked).To(BeNumerically("<=", after.LargestSent))
+	})
+
+	It("counts coalesced packets sent during the handshake", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		statsChan := make(chan quic.CoalescingStats, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			statsChan <- sess.CoalescingStats()
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+
+		// The server coalesces its Initial and Handshake packets.
+		var stats quic.CoalescingStats
+		Eventually(statsChan).Should(Receive(&stats))
+		Expect(stats.CoalescedDatagramsSent).ToNot(BeZero())
+		Expect(stats.PacketsSent).To(BeNumerically(">", stats.DatagramsSent))
+		Expect(stats.PacketsPerDatagram()).To(BeNumerically(">", 1))
+		Expect(stats.AverageCoalescedDatagramSize()).To(BeNumerically("<=", protocol.MaxPacketSizeIPv4))
+		clientStats := sess.CoalescingStats()
+		Expect(clientStats.DatagramsSent).ToNot(BeZero())
+		Expect(clientStats.PacketsSent).To(BeNumerically(">=", clientStats.DatagramsSent))
+	})
+
+	It("calls OnConnectionClose with the peer's close information", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.CloseWithError(0x1337, "closing for a test")).To(Succeed())
+		}()
+
+		closeInfoChan := make(chan quic.ConnectionCloseInfo, 1)
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			&quic.Config{OnConnectionClose: func(info quic.ConnectionCloseInfo) { closeInfoChan <- info }},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		var info quic.ConnectionCloseInfo
+		Eventually(closeInfoChan).Should(Receive(&info))
+		Expect(info.RemoteAddr.String()).To(Equal(server.Addr().String()))
+		Expect(info).To(Equal(quic.ConnectionCloseInfo{
+			Session:            sess,
+			RemoteAddr:         info.RemoteAddr,
+			IsApplicationError: true,
+			ErrorCode:          0x1337,
+			ReasonPhrase:       "closing for a test",
+		}))
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
 })
+
+// qtlsRecordLayer makes qtls use a quic.TLSRecordLayer.
+type qtlsRecordLayer struct {
+	rl         quic.TLSRecordLayer
+	writeLevel quic.TLSEncryptionLevel
+}
+
+var _ qtls.RecordLayer = &qtlsRecordLayer{}
+
+func toTLSEncryptionLevel(encLevel qtls.EncryptionLevel) quic.TLSEncryptionLevel {
+	switch encLevel {
+	case qtls.EncryptionHandshake:
+		return quic.TLSEncryptionHandshake
+	case qtls.EncryptionApplication:
+		return quic.TLSEncryptionApplication
+	default:
+		panic("unexpected encryption level")
+	}
+}
+
+func (r *qtlsRecordLayer) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
+	if err := r.rl.SetReadKey(toTLSEncryptionLevel(encLevel), suite.ID, trafficSecret); err != nil {
+		panic(err)
+	}
+}
+
+func (r *qtlsRecordLayer) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
+	r.writeLevel = toTLSEncryptionLevel(encLevel)
+	if err := r.rl.SetWriteKey(r.writeLevel, suite.ID, trafficSecret); err != nil {
+		panic(err)
+	}
+}
+
+func (r *qtlsRecordLayer) ReadHandshakeMessage() ([]byte, error) { return r.rl.ReadHandshakeMessage() }
+
+func (r *qtlsRecordLayer) WriteRecord(p []byte) (int, error) {
+	if err := r.rl.WriteHandshakeMessage(r.writeLevel, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (r *qtlsRecordLayer) SendAlert(alert uint8) { r.rl.SendAlert(alert) }
@@ -76,7 +76,11 @@ var _ = Describe("HTTP tests", func() {
 				Handler:   mux,
 				TLSConfig: testdata.GetTLSConfig(),
 			},
-			QuicConfig: &quic.Config{Versions: versions},
+			QuicConfig:            &quic.Config{Versions: versions},
+			EnableConnectProtocol: true,
+			AdditionalSettings: func(defaults []http3.Setting) []http3.Setting {
+				return append(defaults, http3.Setting{ID: 0x1337, Value: 42})
+			},
 		}
 
 		addr, err := net.ResolveUDPAddr("udp", "0.0.0.0:0")
@@ -280,6 +284,95 @@ var _ = Describe("HTTP tests", func() {
 				_, err = resp.Body.Read([]byte{0})
 				Expect(err).To(HaveOccurred())
 			})
+
+			It("performs an extended CONNECT handshake", func() {
+				mux.HandleFunc("/session", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					Expect(r.Method).To(Equal(http.MethodConnect))
+					Expect(r.Proto).To(Equal("webtransport"))
+					w.WriteHeader(200)
+					str := w.(http3.DataStreamer).DataStream()
+					Expect(str).ToNot(BeNil())
+					// echo everything the client sends on the stream
+					go func() {
+						defer GinkgoRecover()
+						_, err := io.Copy(str, str)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(str.Close()).To(Succeed())
+					}()
+				})
+
+				req, err := http.NewRequest(http.MethodConnect, "https://localhost:"+port+"/session", nil)
+				Expect(err).ToNot(HaveOccurred())
+				req.Proto = "webtransport"
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				str := resp.Body.(http3.DataStreamer).DataStream()
+				data := GeneratePRData(10 * 1024)
+				_, err = str.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				echoed, err := ioutil.ReadAll(gbytes.TimeoutReader(str, 5*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(echoed).To(Equal(data))
+			})
+
+			It("exchanges custom settings", func() {
+				receivedSettings := make(chan []http3.Setting, 1)
+				client.Transport.(*http3.RoundTripper).ReceivedSettings = func(s []http3.Setting) { receivedSettings <- s }
+				resp, err := client.Get("https://localhost:" + port + "/hello")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				var settings []http3.Setting
+				Eventually(receivedSettings).Should(Receive(&settings))
+				Expect(settings).To(Equal([]http3.Setting{
+					{ID: 0x8, Value: 1}, // SETTINGS_ENABLE_CONNECT_PROTOCOL
+					{ID: 0x1337, Value: 42},
+				}))
+			})
+
+			It("completes running requests and refuses new ones after closing gracefully", func() {
+				handlerCalled := make(chan struct{})
+				release := make(chan struct{})
+				finish := make(chan struct{})
+				mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+					close(handlerCalled)
+					<-release
+					w.Write([]byte("done"))
+					<-finish
+				})
+
+				// establish the connection and start a slow request
+				respChan := make(chan *http.Response, 1)
+				go func() {
+					defer GinkgoRecover()
+					resp, err := client.Get("https://localhost:" + port + "/slow")
+					Expect(err).ToNot(HaveOccurred())
+					respChan <- resp
+				}()
+				Eventually(handlerCalled).Should(BeClosed())
+
+				closed := make(chan error, 1)
+				go func() { closed <- server.CloseGracefully(10 * time.Second) }()
+				Eventually(func() error {
+					_, err := client.Get("https://localhost:" + port + "/hello")
+					return err
+				}).Should(MatchError(ContainSubstring("GOAWAY")))
+
+				close(release)
+				var resp *http.Response
+				Eventually(respChan).Should(Receive(&resp))
+				Expect(resp.StatusCode).To(Equal(200))
+				body := make([]byte, 4)
+				_, err := io.ReadFull(gbytes.TimeoutReader(resp.Body, 3*time.Second), body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("done"))
+				// the server waits for the handler to return
+				Consistently(closed).ShouldNot(Receive())
+				close(finish)
+				Eventually(closed).Should(Receive(BeNil()))
+			})
 		})
 	}
 })
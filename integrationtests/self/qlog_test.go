@@ -0,0 +1,169 @@
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	quic "github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// qlogEventSchema lists the events of the qlog event definitions (draft-02) that we export,
+// together with the fields that are required for each event.
+var qlogEventSchema = map[string]map[string][]string{
+	"connectivity": {
+		"connection_started":   {"ip_version", "src_ip", "src_port", "dst_ip", "dst_port", "quic_version", "src_cid", "dst_cid"},
+		"handshake_progressed": {"event"},
+	},
+	"transport": {
+		"parameters_set":  {"owner"},
+		"packet_sent":     {"packet_type", "header"},
+		"packet_received": {"packet_type", "header"},
+		"packet_buffered": {"packet_type"},
+	},
+	"security": {
+		"key_updated": {"trigger", "key_type"},
+	},
+	"recovery": {
+		"metrics_updated":          {},
+		"congestion_state_updated": {"new"},
+		"packet_lost":              {"packet_type", "packet_number"},
+	},
+}
+
+type qlogBuffer struct {
+	bytes.Buffer
+	closed chan struct{}
+}
+
+var _ io.WriteCloser = &qlogBuffer{}
+
+func newQlogBuffer() *qlogBuffer {
+	return &qlogBuffer{closed: make(chan struct{})}
+}
+
+func (b *qlogBuffer) Close() error {
+	close(b.closed)
+	return nil
+}
+
+// validateQlog checks that a qlog conforms to the qlog schema, and returns the names of all events.
+func validateQlog(data []byte, vantagePoint string) []string {
+	var qlog map[string]interface{}
+	ExpectWithOffset(1, json.Unmarshal(data, &qlog)).To(Succeed())
+	ExpectWithOffset(1, qlog).To(HaveKeyWithValue("qlog_version", "draft-02-wip"))
+	ExpectWithOffset(1, qlog).To(HaveKey("traces"))
+	traces := qlog["traces"].([]interface{})
+	ExpectWithOffset(1, traces).To(HaveLen(1))
+	trace := traces[0].(map[string]interface{})
+	ExpectWithOffset(1, trace["vantage_point"]).To(HaveKeyWithValue("type", vantagePoint))
+	ExpectWithOffset(1, trace["common_fields"]).To(HaveKey("ODCID"))
+	ExpectWithOffset(1, trace["event_fields"]).To(Equal([]interface{}{"time", "category", "event", "data"}))
+
+	var names []string
+	var lastTime float64
+	for _, e := range trace["events"].([]interface{}) {
+		ev := e.([]interface{})
+		ExpectWithOffset(1, ev).To(HaveLen(4))
+		t, ok := ev[0].(float64)
+		ExpectWithOffset(1, ok).To(BeTrue())
+		ExpectWithOffset(1, t).To(BeNumerically(">=", lastTime))
+		lastTime = t
+		category := ev[1].(string)
+		name := ev[2].(string)
+		ExpectWithOffset(1, qlogEventSchema).To(HaveKey(category))
+		ExpectWithOffset(1, qlogEventSchema[category]).To(HaveKey(name), fmt.Sprintf("unknown event %s:%s", category, name))
+		data := ev[3].(map[string]interface{})
+		for _, field := range qlogEventSchema[category][name] {
+			ExpectWithOffset(1, data).To(HaveKey(field), fmt.Sprintf("event %s:%s is missing the %s field", category, name, field))
+		}
+		if name == "packet_sent" || name == "packet_received" {
+			ExpectWithOffset(1, data["header"]).To(HaveKey("packet_number"))
+			if frames, ok := data["frames"]; ok {
+				for _, f := range frames.([]interface{}) {
+					ExpectWithOffset(1, f).To(HaveKey("frame_type"))
+				}
+			}
+		}
+		names = append(names, category+":"+name)
+	}
+	return names
+}
+
+var _ = Describe("qlog", func() {
+	It("exports qlogs that conform to the qlog schema", func() {
+		var mutex sync.Mutex
+		var serverQlog, clientQlog *qlogBuffer
+
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			&quic.Config{
+				GetLogWriter: func([]byte) io.WriteCloser {
+					mutex.Lock()
+					defer mutex.Unlock()
+					serverQlog = newQlogBuffer()
+					return serverQlog
+				},
+			},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			&quic.Config{
+				GetLogWriter: func([]byte) io.WriteCloser {
+					clientQlog = newQlogBuffer()
+					return clientQlog
+				},
+			},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+
+		Eventually(clientQlog.closed).Should(BeClosed())
+		clientEvents := validateQlog(clientQlog.Bytes(), "client")
+		Expect(clientEvents).To(ContainElement("connectivity:connection_started"))
+		Expect(clientEvents).To(ContainElement("transport:packet_sent"))
+		Expect(clientEvents).To(ContainElement("transport:packet_received"))
+		Expect(clientEvents).To(ContainElement("transport:parameters_set"))
+		Expect(clientEvents).To(ContainElement("recovery:metrics_updated"))
+		Expect(clientEvents).To(ContainElement("security:key_updated"))
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(serverQlog).ToNot(BeNil())
+		Eventually(serverQlog.closed).Should(BeClosed())
+		serverEvents := validateQlog(serverQlog.Bytes(), "server")
+		Expect(serverEvents).To(ContainElement("connectivity:connection_started"))
+		Expect(serverEvents).To(ContainElement("transport:packet_sent"))
+		Expect(serverEvents).To(ContainElement("transport:packet_received"))
+		Expect(serverEvents).To(ContainElement("recovery:metrics_updated"))
+	})
+})
@@ -117,4 +117,39 @@ var _ = Describe("non-zero RTT", func() {
 			}
 		})
 	}
+
+	It("surfaces the ACK delay when the peer delays ACKs", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		dataChan := make(chan []byte, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			dataChan <- data
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		// A single ack-eliciting packet doesn't trigger an immediate ACK.
+		// The server sends the ACK when its ACK timer fires.
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(sess.LatestAckDelay).Should(BeNumerically(">", 0))
+		Expect(sess.LatestAckDelay()).To(BeNumerically("<=", protocol.MaxAckDelay+10*time.Millisecond))
+		Expect(str.Close()).To(Succeed())
+		Eventually(dataChan).Should(Receive(Equal([]byte("foobar"))))
+	})
 })
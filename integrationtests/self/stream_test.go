@@ -158,6 +158,66 @@ var _ = Describe("Bidirectional streams", func() {
 				<-done1
 				<-done2
 			})
+
+			It("reports the flow control offsets as data flows", func() {
+				data := GeneratePRData(2 * 1024 * 1024) // larger than the initial flow control windows
+				offsetsChan := make(chan [2]quic.FlowControlOffsets, 1)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					dataRead, err := ioutil.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(dataRead).To(Equal(data))
+					offsetsChan <- [2]quic.FlowControlOffsets{str.FlowControlOffsets(), sess.FlowControlOffsets()}
+				}()
+
+				client, err := quic.DialAddr(
+					serverAddr,
+					getTLSClientConfig(),
+					qconf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				initialSessOffsets := client.FlowControlOffsets()
+				Expect(initialSessOffsets.BytesSent).To(BeZero())
+				Expect(initialSessOffsets.SendWindow).ToNot(BeZero())
+				str, err := client.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				initialOffsets := str.FlowControlOffsets()
+				Expect(initialOffsets.BytesSent).To(BeZero())
+				Expect(initialOffsets.SendWindow).ToNot(BeZero())
+				Expect(initialOffsets.SendWindow).To(BeNumerically("<", len(data)))
+				Expect(initialOffsets.ReceiveWindow).ToNot(BeZero())
+				_, err = str.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+
+				var serverOffsets [2]quic.FlowControlOffsets
+				Eventually(offsetsChan).Should(Receive(&serverOffsets))
+				// The peer must have granted additional flow control credit,
+				// otherwise the data couldn't have been sent.
+				offsets := str.FlowControlOffsets()
+				Expect(offsets.BytesSent).To(BeEquivalentTo(len(data)))
+				Expect(offsets.SendWindow).To(BeNumerically(">=", len(data)))
+				Expect(offsets.SendWindow).To(BeNumerically(">", initialOffsets.SendWindow))
+				Expect(offsets.BytesRead).To(BeZero())
+				sessOffsets := client.FlowControlOffsets()
+				Expect(sessOffsets.BytesSent).To(BeEquivalentTo(len(data)))
+				Expect(sessOffsets.SendWindow).To(BeNumerically(">", initialSessOffsets.SendWindow))
+
+				serverStrOffsets := serverOffsets[0]
+				Expect(serverStrOffsets.BytesRead).To(BeEquivalentTo(len(data)))
+				Expect(serverStrOffsets.HighestReceived).To(BeEquivalentTo(len(data)))
+				Expect(serverStrOffsets.ReceiveWindow).To(BeNumerically(">", initialOffsets.SendWindow))
+				Expect(serverStrOffsets.BytesSent).To(BeZero())
+				serverSessOffsets := serverOffsets[1]
+				Expect(serverSessOffsets.BytesRead).To(BeEquivalentTo(len(data)))
+				Expect(serverSessOffsets.HighestReceived).To(BeEquivalentTo(len(data)))
+				Expect(serverSessOffsets.ReceiveWindow).To(BeNumerically(">", initialSessOffsets.SendWindow))
+				Expect(client.CloseWithError(0, "")).To(Succeed())
+			})
 		})
 	}
 })
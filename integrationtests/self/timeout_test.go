@@ -38,6 +38,31 @@ var _ = Describe("Timeout tests", func() {
 		checkTimeoutError(err)
 	})
 
+	It("times out quickly when the server doesn't respond, if a handshake idle timeout is set", func() {
+		// this UDP socket never responds
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		errChan := make(chan error)
+		start := time.Now()
+		go func() {
+			_, err := quic.DialAddr(
+				conn.LocalAddr().String(),
+				getTLSClientConfig(),
+				&quic.Config{
+					HandshakeTimeout:     time.Minute,
+					HandshakeIdleTimeout: 50 * time.Millisecond,
+				},
+			)
+			errChan <- err
+		}()
+		Eventually(errChan).Should(Receive(&err))
+		checkTimeoutError(err)
+		Expect(err.Error()).To(ContainSubstring("No recent network activity during the handshake"))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
 	It("returns the context error when the context expires", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
 		defer cancel()
@@ -122,6 +147,109 @@ var _ = Describe("Timeout tests", func() {
 		checkTimeoutError(err)
 	})
 
+	It("closes the connection when the maximum connection lifetime is exceeded", func() {
+		const lifetime = 200 * time.Millisecond
+
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverSessChan := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverSessChan <- sess
+		}()
+
+		start := time.Now()
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			&quic.Config{MaxConnectionLifetime: lifetime, KeepAlive: true},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		var serverSess quic.Session
+		Eventually(serverSessChan).Should(Receive(&serverSess))
+		Consistently(sess.Context().Done(), lifetime/2).ShouldNot(BeClosed())
+		Eventually(sess.Context().Done()).Should(BeClosed())
+		Expect(time.Since(start)).To(BeNumerically(">=", lifetime))
+		_, err = sess.OpenStream()
+		Expect(err).To(MatchError("NO_ERROR: maximum connection lifetime exceeded"))
+		nerr, ok := err.(net.Error)
+		Expect(ok).To(BeTrue())
+		Expect(nerr.Timeout()).To(BeFalse())
+		// the peer is notified
+		Eventually(serverSess.Context().Done()).Should(BeClosed())
+		_, err = serverSess.OpenStream()
+		Expect(err).To(MatchError("NO_ERROR: maximum connection lifetime exceeded"))
+	})
+
+	It("sends a CONNECTION_CLOSE when the idle timeout expires, if configured", func() {
+		const idleTimeout = 200 * time.Millisecond
+
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			&quic.Config{MaxIdleTimeout: idleTimeout, SendCloseOnIdleTimeout: true},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverSessChan := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverSessChan <- sess
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			// keep sending data, such that the client doesn't run into its idle timeout
+			for {
+				if _, err := str.Write([]byte("foobar")); err != nil {
+					return
+				}
+				time.Sleep(idleTimeout / 10)
+			}
+		}()
+
+		// drop all packets sent by the client, such that the server runs into its idle timeout
+		drop := utils.AtomicBool{}
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(dir quicproxy.Direction, _ []byte) bool {
+				return dir == quicproxy.DirectionIncoming && drop.Get()
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Read(make([]byte, 6))
+		Expect(err).ToNot(HaveOccurred())
+		var serverSess quic.Session
+		Eventually(serverSessChan).Should(Receive(&serverSess))
+
+		drop.Set(true)
+		Eventually(serverSess.Context().Done()).Should(BeClosed())
+		_, err = serverSess.OpenStream()
+		checkTimeoutError(err)
+		// the client is notified
+		Eventually(sess.Context().Done()).Should(BeClosed())
+		_, err = sess.OpenStream()
+		Expect(err).To(MatchError("NO_ERROR: No recent network activity"))
+		nerr, ok := err.(net.Error)
+		Expect(ok).To(BeTrue())
+		Expect(nerr.Timeout()).To(BeFalse())
+	})
+
 	Context("timing out at the right time", func() {
 		var idleTimeout time.Duration
 
@@ -289,4 +417,56 @@ var _ = Describe("Timeout tests", func() {
 		Eventually(serverSessionClosed).Should(BeClosed())
 	})
 
+	It("detects a broken path before the idle timeout, if health checks are enabled", func() {
+		const idleTimeout = 10 * time.Second
+
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			sess.AcceptStream(context.Background()) // blocks until the session is closed
+		}()
+
+		drop := utils.AtomicBool{}
+
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(quicproxy.Direction, []byte) bool {
+				return drop.Get()
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			&quic.Config{
+				MaxIdleTimeout:      idleTimeout,
+				HealthCheckInterval: 50 * time.Millisecond,
+			},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		Consistently(sess.PathUnhealthy, 200*time.Millisecond).Should(BeFalse())
+
+		// black-hole the path
+		start := time.Now()
+		drop.Set(true)
+		Eventually(sess.PathUnhealthy, 2*time.Second).Should(BeTrue())
+		Expect(time.Since(start)).To(BeNumerically("<", idleTimeout/2))
+		Expect(sess.Context().Done()).ToNot(BeClosed())
+
+		// the path is healthy again once the peer's packets get through
+		drop.Set(false)
+		Eventually(sess.PathUnhealthy, 5*time.Second).Should(BeFalse())
+	})
 })
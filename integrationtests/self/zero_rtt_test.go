@@ -128,6 +128,71 @@ var _ = Describe("0-RTT", func() {
 				Expect(num0RTT).ToNot(BeZero())
 			})
 
+			It("transfers 0-RTT data using an exported session state", func() {
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					getTLSConfig(),
+					&quic.Config{
+						Versions:    []protocol.VersionNumber{version},
+						AcceptToken: func(_ net.Addr, _ *quic.Token) bool { return true },
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				clientConf := dialAndReceiveSessionTicket(ln, proxy.LocalPort())
+				state, ok := clientConf.ClientSessionCache.Get("localhost")
+				Expect(ok).To(BeTrue())
+				sessionState, err := quic.ExportSessionState(state)
+				Expect(err).ToNot(HaveOccurred())
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.AcceptUniStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					data, err := ioutil.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal(PRData))
+					Expect(sess.ConnectionState().Used0RTT).To(BeTrue())
+					close(done)
+				}()
+
+				udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				defer udpConn.Close()
+				remoteAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("localhost:%d", proxy.LocalPort()))
+				Expect(err).ToNot(HaveOccurred())
+				// Use a tls.Config without a session cache.
+				// The session is resumed from the exported session state.
+				sess, err := quic.DialEarlyWithSessionState(
+					udpConn,
+					remoteAddr,
+					"localhost",
+					sessionState,
+					getTLSClientConfig(),
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				str, err := sess.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				Expect(sess.ConnectionState().Used0RTT).To(BeTrue())
+				Eventually(done).Should(BeClosed())
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+
+				num0RTT := atomic.LoadUint32(num0RTTPackets)
+				fmt.Fprintf(GinkgoWriter, "Sent %d 0-RTT packets.", num0RTT)
+				Expect(num0RTT).ToNot(BeZero())
+			})
+
 			// Test that data intended to be sent with 1-RTT protection is not sent in 0-RTT packets.
 			It("waits until a session until the handshake is done", func() {
 				ln, err := quic.ListenAddrEarly(
@@ -362,6 +427,83 @@ var _ = Describe("0-RTT", func() {
 				Expect(num0RTT).ToNot(BeZero())
 			})
 
+			It("accepts 0-RTT when the server's transport parameters decreased, if allowed by the application", func() {
+				const maxStreams = 3
+				tlsConf := getTLSConfig()
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					&quic.Config{
+						Versions:              []protocol.VersionNumber{version},
+						AcceptToken:           func(_ net.Addr, _ *quic.Token) bool { return true },
+						MaxIncomingUniStreams: maxStreams,
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+
+				clientConf := dialAndReceiveSessionTicket(ln, ln.Addr().(*net.UDPAddr).Port)
+
+				// now close the listener and restart it with a lower stream limit
+				Expect(ln.Close()).To(Succeed())
+				cachedChan := make(chan *quic.ZeroRTTTransportParameters, 1)
+				ln, err = quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					&quic.Config{
+						Versions:              []protocol.VersionNumber{version},
+						AcceptToken:           func(_ net.Addr, _ *quic.Token) bool { return true },
+						MaxIncomingUniStreams: 1,
+						Accept0RTTTransportParameters: func(cached, current *quic.ZeroRTTTransportParameters) bool {
+							cachedChan <- cached
+							return current.MaxUniStreams == 1
+						},
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					for i := 0; i < maxStreams; i++ {
+						str, err := sess.AcceptUniStream(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						data, err := ioutil.ReadAll(str)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(data).To(Equal(PRData))
+					}
+					Expect(sess.ConnectionState().Used0RTT).To(BeTrue())
+				}()
+
+				sess, err := quic.DialAddrEarly(
+					fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+					clientConf,
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				// open more streams than allowed by the server's current stream limit
+				for i := 0; i < maxStreams; i++ {
+					str, err := sess.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write(PRData)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}
+				Expect(sess.ConnectionState().Used0RTT).To(BeTrue())
+				Eventually(done).Should(BeClosed())
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+
+				Expect(atomic.LoadUint32(num0RTTPackets)).ToNot(BeZero())
+				var cached *quic.ZeroRTTTransportParameters
+				Expect(cachedChan).To(Receive(&cached))
+				Expect(cached.MaxUniStreams).To(BeEquivalentTo(maxStreams))
+			})
+
 			It("rejects 0-RTT when the ALPN changed", func() {
 				tlsConf := getTLSConfig()
 				ln, err := quic.ListenAddrEarly(
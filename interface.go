@@ -2,6 +2,7 @@ package quic
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
 	"net"
 	"time"
@@ -14,6 +15,9 @@ import (
 // The StreamID is the ID of a QUIC stream.
 type StreamID = protocol.StreamID
 
+// A PacketNumber is a QUIC packet number.
+type PacketNumber = protocol.PacketNumber
+
 // A VersionNumber is a QUIC version number.
 type VersionNumber = protocol.VersionNumber
 
@@ -27,6 +31,27 @@ type Token struct {
 	SentTime     time.Time
 }
 
+// ClientInfo contains information about a client attempting to establish a connection.
+type ClientInfo struct {
+	// RemoteAddr is the address of the client.
+	RemoteAddr net.Addr
+	// ServerName is the server name indicated by the client (SNI).
+	// It is empty if the client didn't send a server name.
+	ServerName string
+}
+
+// ZeroRTTTransportParameters are the transport parameters that limit what a client may send in 0-RTT packets.
+// When resuming a session, the client uses the values it remembered from the session it received the session ticket on.
+type ZeroRTTTransportParameters struct {
+	InitialMaxStreamDataBidiLocal  uint64
+	InitialMaxStreamDataBidiRemote uint64
+	InitialMaxStreamDataUni        uint64
+	InitialMaxData                 uint64
+	MaxBidiStreams                 int64
+	MaxUniStreams                  int64
+	MaxPacketSize                  uint64
+}
+
 // A ClientToken is a token received by the client.
 // It can be used to skip address validation on future connection attempts.
 type ClientToken struct {
@@ -44,6 +69,25 @@ type TokenStore interface {
 	Put(key string, token *ClientToken)
 }
 
+// A TokenReplayCache is used by the server to detect Retry tokens that are used more than once.
+type TokenReplayCache interface {
+	// Add adds a token that was used to establish a connection.
+	// It returns true if the token was already added before.
+	Add(token []byte) (replayed bool)
+}
+
+// A PacketTimestampTracer records when packets are sent and when they are acknowledged,
+// e.g. to estimate the variation of the one-way delay.
+// It is only called for packets in the application data packet number space (0-RTT and 1-RTT packets).
+// Warning: Experimental. This API should not be considered stable and might change.
+type PacketTimestampTracer interface {
+	// SentPacket is called for every packet sent.
+	SentPacket(pn PacketNumber, sendTime time.Time)
+	// ReceivedAck is called for every ACK frame received.
+	// The ackDelay is the delay reported by the peer.
+	ReceivedAck(largestAcked PacketNumber, ackDelay time.Duration, rcvTime time.Time)
+}
+
 // An ErrorCode is an application-defined error code.
 // Valid values range between 0 and MAX_UINT62.
 type ErrorCode = protocol.ApplicationErrorCode
@@ -70,11 +114,29 @@ type ReceiveStream interface {
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	io.Reader
+	// ReadBuffer returns the next chunk of data received on the stream, without copying it.
+	// It can be used instead of Read to avoid copying data out of the stream's receive buffers.
+	// The caller takes ownership of the returned slice, and must call release when it is done with it.
+	// The slice must not be accessed after release was called, since the buffer is reused.
+	// release is never nil. It must be called exactly once, even if data is empty or an error is returned.
+	// ReadBuffer returns the same errors as Read. It returns io.EOF together with the last chunk of data.
+	// Calls to ReadBuffer and Read can be interleaved.
+	ReadBuffer() (data []byte, release func(), err error)
 	// CancelRead aborts receiving on this stream.
 	// It will ask the peer to stop transmitting stream data.
 	// Read will unblock immediately, and future Read calls will fail.
 	// When called multiple times or after reading the io.EOF it is a no-op.
 	CancelRead(ErrorCode)
+	// SetReceiveWindow sets the size of the flow control window for receiving data on this stream, in bytes.
+	// It overrides the window size used by default, and disables auto-tuning of the window for this stream.
+	// The window can't grow larger than the MaxReceiveConnectionFlowControlWindow.
+	// If the window is enlarged, the peer is granted the additional flow control credit right away.
+	SetReceiveWindow(uint64)
+	// FlowControlOffsets returns the current flow control offsets of the receive side of this stream.
+	// Only the receive fields (BytesRead, HighestReceived and ReceiveWindow) are set,
+	// unless this is a bidirectional stream.
+	// Warning: This API should not be considered stable and might change soon.
+	FlowControlOffsets() FlowControlOffsets
 	// SetReadDeadline sets the deadline for future Read calls and
 	// any currently-blocked Read call.
 	// A zero value for t means Read will not time out.
@@ -104,6 +166,15 @@ type SendStream interface {
 	// Write will unblock immediately, and future calls to Write will fail.
 	// When called multiple times or after closing the stream it is a no-op.
 	CancelWrite(ErrorCode)
+	// CancelWriteAt aborts sending on this stream, like CancelWrite.
+	// However, the first reliableSize bytes written to the stream are still delivered reliably,
+	// using a RESET_STREAM_AT frame.
+	// It returns an error if the peer doesn't support the RESET_STREAM_AT extension,
+	// or if less than reliableSize bytes were sent.
+	// Bytes count as sent once they have been packed into STREAM frames.
+	// This includes all data of completed Write calls, but possibly only a part of the data of a Write call that is still blocked.
+	// When called after the stream was canceled it is a no-op.
+	CancelWriteAt(code ErrorCode, reliableSize uint64) error
 	// The context is canceled as soon as the write-side of the stream is closed.
 	// This happens when Close() or CancelWrite() is called, or when the peer
 	// cancels the read-side of their stream.
@@ -115,8 +186,158 @@ type SendStream interface {
 	// some of the data was successfully written.
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// SendQueueDepth returns the amount of data written to this stream
+	// that was not yet sent, or not yet acknowledged by the peer.
+	// Warning: This API should not be considered stable and might change soon.
+	SendQueueDepth() SendQueueDepth
+	// FlowControlOffsets returns the current flow control offsets of the send side of this stream.
+	// Only the send fields (BytesSent and SendWindow) are set,
+	// unless this is a bidirectional stream.
+	// Warning: This API should not be considered stable and might change soon.
+	FlowControlOffsets() FlowControlOffsets
+}
+
+// SendQueueDepth is the amount of data buffered on the send side.
+type SendQueueDepth struct {
+	// Queued is the number of bytes that were written, but not yet sent.
+	// This includes data that was declared lost and is waiting to be retransmitted.
+	Queued uint64
+	// Unacknowledged is the number of bytes that were sent, but not yet acknowledged by the peer.
+	Unacknowledged uint64
+}
+
+// StreamCounts are the numbers of streams that are currently open on a session.
+// Bidirectional streams are counted both as send and as receive streams.
+type StreamCounts struct {
+	// SendStreams is the number of open bidirectional and unidirectional send streams.
+	SendStreams int
+	// ReceiveStreams is the number of open bidirectional and unidirectional receive streams.
+	ReceiveStreams int
+}
+
+// CoalescingStats count how many QUIC packets were coalesced into the UDP datagrams sent on a session.
+// During the handshake, packets of different encryption levels are coalesced into a single datagram.
+type CoalescingStats struct {
+	// DatagramsSent is the number of UDP datagrams sent.
+	DatagramsSent uint64
+	// PacketsSent is the number of QUIC packets sent.
+	PacketsSent uint64
+	// CoalescedDatagramsSent is the number of datagrams that contained more than one QUIC packet.
+	CoalescedDatagramsSent uint64
+	// CoalescedBytesSent is the total size of the datagrams that contained more than one QUIC packet.
+	CoalescedBytesSent uint64
+}
+
+// PacketsPerDatagram is the average number of QUIC packets per datagram.
+// It is zero if no datagram was sent yet.
+func (s CoalescingStats) PacketsPerDatagram() float64 {
+	if s.DatagramsSent == 0 {
+		return 0
+	}
+	return float64(s.PacketsSent) / float64(s.DatagramsSent)
+}
+
+// AverageCoalescedDatagramSize is the average size of the datagrams that contained more than one QUIC packet.
+// It is zero if no such datagram was sent yet.
+func (s CoalescingStats) AverageCoalescedDatagramSize() float64 {
+	if s.CoalescedDatagramsSent == 0 {
+		return 0
+	}
+	return float64(s.CoalescedBytesSent) / float64(s.CoalescedDatagramsSent)
+}
+
+// PacketNumberSpaceStats are the packet numbers used for sending packets in a packet number space.
+// They are meant for debugging.
+type PacketNumberSpaceStats struct {
+	// NextPacketNumber is the packet number that will be used for the next packet.
+	NextPacketNumber int64
+	// LargestSent is the largest packet number sent. It is -1 if no packet was sent yet.
+	LargestSent int64
+	// LargestAcked is the largest packet number acknowledged by the peer.
+	// It is -1 if no packet was acknowledged yet.
+	LargestAcked int64
+	// Dropped is set once the packet number space was dropped, after the handshake progressed beyond it.
+	// The packet numbers are zero in that case.
+	Dropped bool
+}
+
+// PacketNumberSpaces are the three packet number spaces of a connection.
+type PacketNumberSpaces struct {
+	Initial   PacketNumberSpaceStats
+	Handshake PacketNumberSpaceStats
+	// AppData is the packet number space shared by 0-RTT and 1-RTT packets.
+	AppData PacketNumberSpaceStats
+}
+
+// FlowControlOffsets are the flow control offsets of a stream or of the connection.
+// They are meant for debugging, and can't be used to modify the flow control state.
+type FlowControlOffsets struct {
+	// BytesSent is the number of bytes sent.
+	BytesSent uint64
+	// SendWindow is the offset up to which we are allowed to send,
+	// as last advertised by the peer in its transport parameters or in a MAX_DATA / MAX_STREAM_DATA frame.
+	SendWindow uint64
+	// BytesRead is the number of bytes read by the application.
+	BytesRead uint64
+	// HighestReceived is the highest offset received from the peer.
+	HighestReceived uint64
+	// ReceiveWindow is the offset up to which the peer is allowed to send,
+	// as last advertised by us in our transport parameters or in a MAX_DATA / MAX_STREAM_DATA frame.
+	ReceiveWindow uint64
+}
+
+// ConnectionIDs is the state of the connection IDs of a session.
+// It is meant for debugging.
+type ConnectionIDs struct {
+	// Local are the connection IDs issued by us that the peer didn't retire yet, ordered by sequence number.
+	Local []ConnectionIDInfo
+	// HighestLocalSequenceNumber is the highest sequence number of a connection ID issued by us.
+	// Connection IDs with a lower sequence number that are not contained in Local were retired by the peer.
+	HighestLocalSequenceNumber uint64
+	// Peer are the connection IDs issued by the peer that we didn't retire yet, ordered by sequence number.
+	Peer []ConnectionIDInfo
+	// PeerRetiredBelow is the sequence number below which all connection IDs issued by the peer were retired.
+	// This includes connection IDs retired because the peer requested it (using the Retire Prior To field),
+	// as well as connection IDs we stopped using.
+	PeerRetiredBelow uint64
+}
+
+// ConnectionIDInfo describes a connection ID.
+type ConnectionIDInfo struct {
+	SequenceNumber uint64
+	ConnectionID   []byte
+	// InUse is set for the connection ID that is currently used to send packets.
+	// For connection IDs issued by us, it is never set, since we don't know which connection ID the peer uses.
+	InUse bool
+}
+
+// ConnectionCloseInfo describes a CONNECTION_CLOSE frame received from the peer.
+type ConnectionCloseInfo struct {
+	// Session is the session that is being closed.
+	// It can be compared to the sessions returned by Dial and Accept, but its methods must not be called from OnConnectionClose.
+	Session Session
+	// RemoteAddr is the address of the peer.
+	RemoteAddr net.Addr
+	// IsApplicationError is set if the peer closed the connection with an application error code.
+	// Otherwise, ErrorCode is a QUIC transport error code.
+	IsApplicationError bool
+	ErrorCode          uint64
+	// FrameType is the type of the frame that triggered the error.
+	// It is only set for transport errors, and is zero if the error wasn't caused by a specific frame.
+	FrameType    uint64
+	ReasonPhrase string
 }
 
+// CongestionControlAlgorithm is a congestion control algorithm (see Session.SetCongestionControl).
+type CongestionControlAlgorithm uint8
+
+const (
+	// CongestionControlReno is Reno. This is the algorithm used by default.
+	CongestionControlReno CongestionControlAlgorithm = iota
+	// CongestionControlCubic is Cubic.
+	CongestionControlCubic
+)
+
 // StreamError is returned by Read and Write when the peer cancels the stream.
 type StreamError interface {
 	error
@@ -126,6 +347,56 @@ type StreamError interface {
 
 type ConnectionState = handshake.ConnectionState
 
+// A TLSConn is a TLS 1.3 stack that can be used to perform the handshake, instead of the built-in one.
+// The TLS stack doesn't send or receive any records itself, it uses the TLSRecordLayer passed to it when it is created:
+// * Handshake messages are read using ReadHandshakeMessage, and written using WriteHandshakeMessage.
+// * The traffic secrets are installed using SetReadKey and SetWriteKey, as soon as they are available.
+// * The TLS stack must send the transport parameters it was created with in the quic_transport_parameters extension,
+//   in the ClientHello (client) or in the EncryptedExtensions (server).
+// * It must pass the content of the peer's quic_transport_parameters extension to ReceivedTransportParameters,
+//   before writing the next handshake message.
+// * Fatal errors are reported using SendAlert, before Handshake returns an error.
+// 0-RTT is not supported when using a TLSConn, so no 0-RTT keys are installed.
+// Warning: This API should not be considered stable and might change soon.
+type TLSConn = handshake.TLSConn
+
+// A TLSRecordLayer is used by a TLSConn to exchange handshake messages and keys with QUIC.
+type TLSRecordLayer = handshake.TLSRecordLayer
+
+// TLSEncryptionLevel is the encryption level at which a TLSConn exchanges handshake messages and keys.
+type TLSEncryptionLevel = handshake.TLSEncryptionLevel
+
+const (
+	// TLSEncryptionInitial is the Initial encryption level.
+	TLSEncryptionInitial = handshake.TLSEncryptionInitial
+	// TLSEncryptionHandshake is the Handshake encryption level.
+	TLSEncryptionHandshake = handshake.TLSEncryptionHandshake
+	// TLSEncryptionApplication is the 1-RTT encryption level.
+	TLSEncryptionApplication = handshake.TLSEncryptionApplication
+)
+
+// ZeroRTTRejection describes why 0-RTT was rejected.
+type ZeroRTTRejection = handshake.ZeroRTTRejection
+
+// ZeroRTTRejectionReason is the reason why 0-RTT was rejected.
+type ZeroRTTRejectionReason = handshake.ZeroRTTRejectionReason
+
+const (
+	// ZeroRTTNotRejected is used if 0-RTT was not rejected, or not attempted at all.
+	ZeroRTTNotRejected = handshake.ZeroRTTNotRejected
+	// ZeroRTTRejectedByTLS is used if early data was rejected in the TLS handshake,
+	// for example because the ALPN changed, or because the session ticket couldn't be decrypted.
+	ZeroRTTRejectedByTLS = handshake.ZeroRTTRejectedByTLS
+	// ZeroRTTRejectedByPolicy is used if the server didn't allow 0-RTT for this client (see Config.Allow0RTT).
+	ZeroRTTRejectedByPolicy = handshake.ZeroRTTRejectedByPolicy
+	// ZeroRTTRejectedTransportParameters is used if the transport parameters
+	// changed since the session ticket was issued (see Config.Accept0RTTTransportParameters).
+	ZeroRTTRejectedTransportParameters = handshake.ZeroRTTRejectedTransportParameters
+	// ZeroRTTRejectedInvalidSessionTicket is used if the data stored in the session ticket couldn't be parsed,
+	// for example because it was issued by a different version of quic-go.
+	ZeroRTTRejectedInvalidSessionTicket = handshake.ZeroRTTRejectedInvalidSessionTicket
+)
+
 // A Session is a QUIC connection between two peers.
 type Session interface {
 	// AcceptStream returns the next stream opened by the peer, blocking until one is available.
@@ -146,8 +417,17 @@ type Session interface {
 	// OpenStreamSync opens a new bidirectional QUIC stream.
 	// It blocks until a new stream can be opened.
 	// If the error is non-nil, it satisfies the net.Error interface.
+	// If the context is canceled while waiting for the peer to raise the stream limit,
+	// Temporary() will be true, and the error wraps the context's error.
 	// If the session was closed due to a timeout, Timeout() will be true.
 	OpenStreamSync(context.Context) (Stream, error)
+	// OpenStreamWithWindow opens a new bidirectional QUIC stream, like OpenStream.
+	// Additionally, it tries to reserve n bytes of the connection-level send window for this stream,
+	// such that other streams can't use up the connection-level flow control credit.
+	// If the reservation fails, the stream is returned nonetheless,
+	// and writing to it might block until the peer grants more flow control credit.
+	// The reservation is released when the stream is closed or canceled.
+	OpenStreamWithWindow(n uint64) (Stream, error)
 	// OpenUniStream opens a new outgoing unidirectional QUIC stream.
 	// If the error is non-nil, it satisfies the net.Error interface.
 	// When reaching the peer's stream limit, Temporary() will be true.
@@ -156,12 +436,20 @@ type Session interface {
 	// OpenUniStreamSync opens a new outgoing unidirectional QUIC stream.
 	// It blocks until a new stream can be opened.
 	// If the error is non-nil, it satisfies the net.Error interface.
+	// If the context is canceled while waiting for the peer to raise the stream limit,
+	// Temporary() will be true, and the error wraps the context's error.
 	// If the session was closed due to a timeout, Timeout() will be true.
 	OpenUniStreamSync(context.Context) (SendStream, error)
 	// LocalAddr returns the local address.
 	LocalAddr() net.Addr
 	// RemoteAddr returns the address of the peer.
 	RemoteAddr() net.Addr
+	// ResetAllStreams resets all open streams with the same error code,
+	// as if CancelWrite and CancelRead were called on every stream.
+	// This sends a RESET_STREAM frame and / or a STOP_SENDING frame for every stream.
+	// It can be used before closing the connection on application-level fatal errors.
+	// Streams that were already reset are not reset again, so it is safe to call it multiple times.
+	ResetAllStreams(ErrorCode)
 	// Close the connection with an error.
 	// The error string will be sent to the peer.
 	CloseWithError(ErrorCode, string) error
@@ -170,8 +458,90 @@ type Session interface {
 	Context() context.Context
 	// ConnectionState returns basic details about the QUIC connection.
 	// It blocks until the handshake completes.
+	// If the server requested a client certificate (using the ClientAuth field of the tls.Config),
+	// the certificates presented by the client are available in the PeerCertificates field.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// NumKeyUpdates returns the number of 1-RTT key updates that occurred on this connection,
+	// initiated by either endpoint.
+	NumKeyUpdates() uint64
+	// UsedHelloRetryRequest says if the server sent a HelloRetryRequest during the handshake,
+	// for example because the client didn't send a key share for a group supported by the server.
+	// A HelloRetryRequest adds a round trip to the handshake.
+	UsedHelloRetryRequest() bool
+	// ZeroRTTRejection says if and why 0-RTT was rejected.
+	// The server doesn't tell the client why it rejected 0-RTT, so on the client, the reason is always ZeroRTTRejectedByTLS.
+	// On the server, if the transport parameters prevented 0-RTT, the mismatching parameters are listed.
+	// It is only final once the handshake has completed.
+	ZeroRTTRejection() ZeroRTTRejection
+	// LatestAckDelay returns the ACK delay reported by the peer in the most recent ACK frame for 1-RTT packets,
+	// decoded using the peer's ack_delay_exponent.
+	// It is the time the peer held back the ACK, and helps to distinguish the network RTT from the peer's processing delay.
+	// It is zero if no such ACK frame was received yet.
+	LatestAckDelay() time.Duration
+	// AckFrequencyPacketTolerance returns the number of ack-eliciting packets that may be received before an ACK is sent,
+	// as requested by the peer in the most recent ACK_FREQUENCY frame.
+	// It is zero if no ACK_FREQUENCY frame was received yet, in which case the default ACK behavior is used.
+	AckFrequencyPacketTolerance() uint64
+	// AckFrequencyMaxAckDelay returns the maximum time an ACK is delayed,
+	// as requested by the peer in the most recent ACK_FREQUENCY frame.
+	// If no ACK_FREQUENCY frame was received yet, it is the default max_ack_delay.
+	AckFrequencyMaxAckDelay() time.Duration
+	// EstimatedBandwidth returns an estimate of the rate (in bytes per second) at which data sent on this session
+	// is delivered to the peer. It is derived from samples of the delivery rate taken when packets are acknowledged.
+	// It is zero if no sample was taken yet.
+	EstimatedBandwidth() uint64
+	// NumSpuriousRetransmissions returns the number of packets that were declared lost and retransmitted,
+	// but were acknowledged by the peer later, for example because they were reordered on the path.
+	NumSpuriousRetransmissions() uint64
+	// NumDroppedUndecryptablePackets returns the number of packets that were dropped because
+	// the buffer for packets that can't be decrypted yet was full (see Config.MaxUndecryptablePackets).
+	NumDroppedUndecryptablePackets() uint64
+	// PathUnhealthy says if the path to the peer is considered broken.
+	// This requires health checks to be enabled (see Config.HealthCheckInterval).
+	// The path is marked as unhealthy when a health check PING is not acknowledged within 3 PTOs,
+	// and as healthy again as soon as a packet is received from the peer.
+	PathUnhealthy() bool
+	// FlushACKs sends ACKs for all packets received so far right away,
+	// instead of waiting for the ACK delay.
+	// This can reduce latency for RPC-style protocols, where the ACK delay would otherwise delay the peer.
+	// This is an advanced tuning knob. Calling it frequently increases the number of packets sent.
+	FlushACKs()
+	// CoalescingStats returns how many QUIC packets were coalesced into the UDP datagrams sent on this session.
+	// Warning: This API should not be considered stable and might change soon.
+	CoalescingStats() CoalescingStats
+	// GetVersion returns the QUIC version used by this session.
+	// If version negotiation was performed, this is the negotiated version.
+	GetVersion() VersionNumber
+	// SendQueueDepth returns the amount of stream data that was not yet sent,
+	// or not yet acknowledged by the peer, summed over all open streams.
+	// Warning: This API should not be considered stable and might change soon.
+	SendQueueDepth() SendQueueDepth
+	// StreamCounts returns the number of send and receive streams that are currently open,
+	// including streams opened by the peer that were not yet accepted.
+	// A stream is counted until it is completed, i.e. until both its send and receive side are closed.
+	StreamCounts() StreamCounts
+	// FlowControlOffsets returns the current connection-level flow control offsets.
+	// Warning: This API should not be considered stable and might change soon.
+	FlowControlOffsets() FlowControlOffsets
+	// PacketNumberSpaces returns the packet numbers used for sending in the Initial, Handshake and application data packet number spaces.
+	// Warning: This API should not be considered stable and might change soon.
+	PacketNumberSpaces() PacketNumberSpaces
+	// ConnectionIDs returns the connection IDs issued by both endpoints, together with their sequence numbers.
+	// Warning: This API should not be considered stable and might change soon.
+	ConnectionIDs() ConnectionIDs
+	// RetirePeerConnectionID retires a connection ID issued by the peer, identified by its sequence number
+	// (see ConnectionIDs), by sending a RETIRE_CONNECTION_ID frame.
+	// If this connection ID is currently in use, we switch to another connection ID issued by the peer.
+	// It returns an error if the sequence number is unknown, or if there's no other connection ID to switch to.
+	// Warning: This API should not be considered stable and might change soon.
+	RetirePeerConnectionID(seq uint64) error
+	// SetCongestionControl switches the congestion control algorithm used for this session.
+	// The congestion window, the slow start threshold and the RTT statistics are kept.
+	// If the session is currently recovering from a loss event, the switch is applied once recovery ends.
+	// It returns an error if the algorithm is unknown.
+	// Warning: This API should not be considered stable and might change soon.
+	SetCongestionControl(CongestionControlAlgorithm) error
 }
 
 // An EarlySession is a session that is handshaking.
@@ -200,16 +570,66 @@ type Config struct {
 	// If used for a server, or dialing on a packet conn, a 4 byte connection ID will be used.
 	// When dialing on a packet conn, the ConnectionIDLength value must be the same for every Dial call.
 	ConnectionIDLength int
+	// GenerateConnectionID is called to generate the connection IDs used by this endpoint.
+	// This allows encoding information into the connection ID, e.g. for routing by a load balancer.
+	// The generated connection ID must be exactly length bytes long, where length is the ConnectionIDLength.
+	// It is not called if the ConnectionIDLength is 0.
+	// If not set, connection IDs are chosen randomly.
+	GenerateConnectionID func(length int) ([]byte, error)
+	// RetryConnectionIDLength is the length of the connection ID that the server chooses when sending a Retry packet.
+	// The client uses this connection ID as the destination connection ID until it receives the server's first Initial.
+	// If GenerateConnectionID is set, it is used to generate this connection ID.
+	// It must be between 1 and 20 bytes. It is only valid for the server.
+	// If not set, the ConnectionIDLength is used.
+	RetryConnectionIDLength int
+	// MaxIssuedConnectionIDs is the maximum number of connection IDs issued to the peer at the same time,
+	// including the connection ID used during the handshake.
+	// Every connection ID issued requires some state to be kept, so this value should be kept small.
+	// Independent of this value, no more connection IDs than allowed by the peer's active_connection_id_limit are issued.
+	// If not set, it will default to 6.
+	MaxIssuedConnectionIDs int
 	// HandshakeTimeout is the maximum duration that the cryptographic handshake may take.
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 10 seconds.
 	HandshakeTimeout time.Duration
+	// HandshakeIdleTimeout is the maximum duration that may pass without any incoming network activity
+	// before the handshake completes.
+	// It allows failing fast when the peer is unreachable, without lowering the HandshakeTimeout.
+	// Once the handshake has completed, the negotiated idle timeout (see MaxIdleTimeout) applies.
+	// If this value is zero, only the HandshakeTimeout applies during the handshake.
+	HandshakeIdleTimeout time.Duration
 	// MaxIdleTimeout is the maximum duration that may pass without any incoming network activity.
 	// The actual value for the idle timeout is the minimum of this value and the peer's.
 	// This value only applies after the handshake has completed.
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 30 seconds.
 	MaxIdleTimeout time.Duration
+	// MinIdleTimeout is the minimum idle timeout that is acceptable for a connection.
+	// If the negotiated idle timeout (the minimum of MaxIdleTimeout and the peer's value) is smaller,
+	// the connection is closed with SERVER_BUSY (CONNECTION_REFUSED in later drafts) during the handshake,
+	// instead of using the smaller value.
+	// If this value is zero, any idle timeout offered by the peer is accepted.
+	// It must not be larger than MaxIdleTimeout.
+	MinIdleTimeout time.Duration
+	// SendCloseOnIdleTimeout makes this peer send a CONNECTION_CLOSE when the idle timeout expires,
+	// so that the peer learns immediately that the connection is gone.
+	// By default, the connection is closed silently.
+	SendCloseOnIdleTimeout bool
+	// MaxProbeTimeout is the maximum duration of the probe timeout (PTO).
+	// The PTO doubles with every consecutive PTO that expires without an acknowledgement being received.
+	// Only this exponential backoff is capped: if the PTO derived from the RTT is larger than MaxProbeTimeout, it is used as is.
+	// Setting this value bounds the interval between probe packets, such that a connection that lost
+	// connectivity keeps probing, and notices quickly when connectivity is restored.
+	// Sending probe packets doesn't reset the idle timeout, so the connection is still closed after
+	// MaxIdleTimeout if no packets are received.
+	// If this value is zero, the PTO is not capped.
+	MaxProbeTimeout time.Duration
+	// MaxConnectionLifetime is the maximum duration that a connection may be used for, regardless of its activity.
+	// Once it is exceeded, the connection is closed gracefully: Streams get a brief window (3 PTOs) to
+	// get all their data acknowledged before a CONNECTION_CLOSE with NO_ERROR is sent.
+	// The error returned from the session then reports that the maximum connection lifetime was exceeded.
+	// If this value is zero, the lifetime of a connection is not limited.
+	MaxConnectionLifetime time.Duration
 	// AcceptToken determines if a Token is accepted.
 	// It is called with token = nil if the client didn't send a token.
 	// If not set, a default verification function is used:
@@ -218,6 +638,54 @@ type Config struct {
 	//   * else, that it was issued within the last 24 hours.
 	// This option is only valid for the server.
 	AcceptToken func(clientAddr net.Addr, token *Token) bool
+	// RequireAddressValidation determines if the client's address has to be validated using a Retry,
+	// before the handshake is allowed to proceed.
+	// If it returns true, a Retry is sent unless the client sent a Retry token (that was accepted by AcceptToken).
+	// Tokens received in NEW_TOKEN frames are not sufficient in that case.
+	// If not set, the decision is left to AcceptToken.
+	// This option is only valid for the server.
+	RequireAddressValidation func(clientAddr net.Addr) bool
+	// GenerateToken generates the tokens sent to the client, in Retry packets and in NEW_TOKEN frames.
+	// This allows using an external address validation service that issues tokens in its own format.
+	// The token is opaque to quic-go.
+	// For Retry tokens, origDestConnID is the destination connection ID of the client's first Initial packet.
+	// It must be returned by ValidateToken when the client uses the token.
+	// GenerateToken and ValidateToken must either both be set, or both be nil.
+	// If not set, tokens are generated and encrypted by quic-go.
+	// This option is only valid for the server.
+	GenerateToken func(clientAddr net.Addr, isRetry bool, origDestConnID []byte) ([]byte, error)
+	// ValidateToken decodes a token sent by the client, which was generated by GenerateToken.
+	// For Retry tokens, it returns the origDestConnID that was passed to GenerateToken.
+	// If it returns an error, the token is treated as if the client didn't send any token.
+	// The Token is then passed to AcceptToken.
+	// This option is only valid for the server.
+	ValidateToken func(clientAddr net.Addr, token []byte) (t *Token, origDestConnID []byte, err error)
+	// Allow0RTT decides if 0-RTT is offered to and accepted from a client.
+	// It is consulted before issuing a session ticket that can be used for 0-RTT,
+	// and before accepting early data sent by a client resuming a session.
+	// If it returns false, the client has to use a 1-RTT handshake.
+	// If not set, 0-RTT is allowed for all clients.
+	// This option is only valid for the server, and only takes effect when using ListenEarly.
+	Allow0RTT func(*ClientInfo) bool
+	// Accept0RTTTransportParameters decides if the transport parameters saved in a session ticket are valid for 0-RTT.
+	// It is called with the saved (cached) parameters and the parameters the server is currently using.
+	// The client may use up the limits saved in the session ticket in 0-RTT packets.
+	// When the saved parameters are accepted, the flow control windows and stream limits of this connection
+	// are therefore raised to the saved values, if those are larger than the current ones.
+	// If not set, 0-RTT is only accepted if the flow control windows and the stream limits are unchanged,
+	// and the max packet size was not decreased.
+	// This option is only valid for the server, and only takes effect when using ListenEarly.
+	Accept0RTTTransportParameters func(cached, current *ZeroRTTTransportParameters) bool
+	// The TokenReplayCache is used to make sure that every Retry token is only used for a single connection.
+	// A Retry token is valid for a few seconds, during which an on-path attacker could use a captured token
+	// to establish connections from the client's address.
+	// The cache needs to be large enough to hold all Retry tokens accepted during their validity period,
+	// otherwise tokens are evicted before they expire and can be replayed.
+	// A cache only detects replays on a single server. If multiple servers share the same token key,
+	// they need to share the cache as well.
+	// If not set, Retry tokens can be used multiple times within their validity period.
+	// This option is only valid for the server.
+	TokenReplayCache TokenReplayCache
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
@@ -229,6 +697,27 @@ type Config struct {
 	// MaxReceiveConnectionFlowControlWindow is the connection-level flow control window for receiving data.
 	// If this value is zero, it will default to 1.5 MB for the server and 15 MB for the client.
 	MaxReceiveConnectionFlowControlWindow uint64
+	// ReceiveWindowGrowthFactor is the factor by which flow control auto-tuning increases
+	// the stream- and connection-level receive windows when the application consumes data quickly.
+	// Larger values let the windows reach the MaxReceiveStreamFlowControlWindow and MaxReceiveConnectionFlowControlWindow
+	// faster, which benefits latency-insensitive bulk transfers, at the cost of committing more memory early.
+	// A value of 1 disables auto-tuning.
+	// If not set, it will default to 2.
+	ReceiveWindowGrowthFactor int
+	// EnableBDPWindowAutoTuning enables sizing of the stream- and connection-level receive windows
+	// based on the bandwidth-delay product (BDP) of the connection.
+	// The bandwidth is estimated from the rate at which data is consumed, and multiplied by the smoothed RTT.
+	// The windows are then increased to twice the BDP, which keeps the pipe full on long-fat networks,
+	// where growing the windows by the ReceiveWindowGrowthFactor would take many round trips.
+	// The windows never grow beyond MaxReceiveStreamFlowControlWindow and MaxReceiveConnectionFlowControlWindow.
+	EnableBDPWindowAutoTuning bool
+	// StreamWindowUpdateThreshold is the fraction of the stream-level receive window that has to be consumed
+	// before a MAX_STREAM_DATA frame is sent to increase the window.
+	// Larger values reduce the number of MAX_STREAM_DATA frames sent, but leave the peer less room to keep sending.
+	// Independent of this value, if the peer used up the whole stream-level window, and is therefore blocked,
+	// a MAX_STREAM_DATA frame is sent as soon as the window can be increased by at least one full packet.
+	// It must be larger than 0 and smaller than 1. If not set, it will default to 0.25.
+	StreamWindowUpdateThreshold float64
 	// MaxIncomingStreams is the maximum number of concurrent bidirectional streams that a peer is allowed to open.
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any bidirectional streams.
@@ -237,18 +726,116 @@ type Config struct {
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any unidirectional streams.
 	MaxIncomingUniStreams int
+	// MaxAutoIncomingStreams is the limit up to which MaxIncomingStreams is raised
+	// when the peer is blocked from opening new bidirectional streams (i.e. when it sends a STREAMS_BLOCKED frame).
+	// Every time the peer is blocked, the limit is doubled.
+	// If not set, or not larger than MaxIncomingStreams, the limit is never raised.
+	MaxAutoIncomingStreams int
+	// MaxAutoIncomingUniStreams is the limit up to which MaxIncomingUniStreams is raised
+	// when the peer is blocked from opening new unidirectional streams.
+	// It works like MaxAutoIncomingStreams.
+	MaxAutoIncomingUniStreams int
+	// AllowStreamLimitIncrease is called before the stream limit is raised in response to a STREAMS_BLOCKED frame.
+	// It is passed the type of the streams and the new limit.
+	// If it returns false, the limit is not raised.
+	// If not set, all increases up to MaxAutoIncomingStreams and MaxAutoIncomingUniStreams are allowed.
+	AllowStreamLimitIncrease func(unidirectional bool, newLimit int) bool
 	// The StatelessResetKey is used to generate stateless reset tokens.
 	// If no key is configured, sending of stateless resets is disabled.
 	StatelessResetKey []byte
 	// KeepAlive defines whether this peer will periodically send a packet to keep the connection alive.
 	KeepAlive bool
+	// NATKeepAlivePeriod is the maximum duration that may pass without sending a packet.
+	// Some NATs expire UDP bindings faster than the idle timeout.
+	// To keep the NAT binding alive, a PING frame is sent when no packet was sent for this duration.
+	// Unlike KeepAlive, this period is independent of the idle timeout.
+	// If zero, no PING frames are sent to keep the NAT binding alive.
+	NATKeepAlivePeriod time.Duration
+	// HealthCheckInterval enables health checks of the path.
+	// When no packet was received from the peer for this duration, a PING frame is sent.
+	// If it is not acknowledged within 3 PTOs, the path is considered unhealthy (see Session.PathUnhealthy).
+	// This detects broken paths long before the idle timeout expires.
+	// If zero, no health checks are performed.
+	HealthCheckInterval time.Duration
+	// EnableAckFrequency enables the ACK Frequency extension for receiving.
+	// Support is advertised using the min_ack_delay transport parameter,
+	// and the peer can then use ACK_FREQUENCY frames to tune how often ACKs are sent.
+	EnableAckFrequency bool
+	// AckFrequencyPacketTolerance is the number of ack-eliciting packets the peer may receive before sending an ACK.
+	// If the peer supports the ACK Frequency extension, it is sent in an ACK_FREQUENCY frame once the handshake completes.
+	// If zero, no ACK_FREQUENCY frame is sent.
+	AckFrequencyPacketTolerance int
+	// ImmediateAckAfterIdle makes this peer acknowledge the first ack-eliciting packet received after an idle period immediately.
+	// The connection is considered idle if no packet was received for longer than both the RTT and the max ack delay.
+	// Otherwise, acknowledgements are delayed by up to the max ack delay.
+	ImmediateAckAfterIdle bool
+	// MaxUndecryptablePackets is the maximum number of packets that are buffered because they can't be decrypted yet,
+	// e.g. 1-RTT packets that arrive before the handshake completes.
+	// When the buffer is full, the oldest buffered packet is dropped.
+	// If not set, it will default to 33.
+	MaxUndecryptablePackets int
+	// MaxAckRanges is the maximum number of ACK ranges that are tracked for received packets.
+	// Every gap in the received packet numbers creates a new range.
+	// When more ranges are tracked, the oldest ranges are dropped, and the packets they contain are not acknowledged (again).
+	// If not set, it will default to 500.
+	MaxAckRanges int
+	// PacketReorderingThreshold is the maximum reordering in packets before a packet is declared lost.
+	// A packet is declared lost once a packet sent at least this many packets after it is acknowledged.
+	// On paths with heavy reordering, increasing this value avoids spurious retransmissions,
+	// at the cost of detecting actual losses later.
+	// If not set, it will default to 3, as recommended by RFC 9002.
+	PacketReorderingThreshold int
+	// EarlyRetransmit enables early retransmit (see RFC 5827).
+	// When all packets sent after a packet have been acknowledged, no further acknowledgements
+	// can push that packet over the PacketReorderingThreshold, and loss recovery has to wait for the time threshold.
+	// If enabled, such packets are declared lost as soon as the acknowledgement arrives.
+	// To avoid spurious retransmissions, early retransmit is disabled once reordering is observed on the connection.
+	EarlyRetransmit bool
+	// DisableSpinBit disables the latency spin bit.
+	// The spin bit allows on-path observers to measure the RTT of a connection.
+	// If disabled, the spin bit is set to a random value for the lifetime of the connection.
+	// Note that the spin bit is disabled for a random subset of connections, even if this option is not set.
+	DisableSpinBit bool
+	// DisableMaxPacketSizeParameter disables sending of the max_packet_size transport parameter.
+	// Some middleboxes fail to handle this parameter.
+	// If disabled, the peer uses the default maximum packet size of 65527 bytes.
+	// Warning: quic-go only reads datagrams up to 1452 bytes (protocol.MaxReceivePacketSize).
+	// Larger datagrams sent by the peer are truncated, and then dropped, since they can't be decrypted.
+	// The max_packet_size sent by the peer is respected in any case.
+	DisableMaxPacketSizeParameter bool
+	// TransportParameterOrder is the order (a list of transport parameter IDs) in which the transport parameters are sent.
+	// Parameters that are not contained in the list are sent afterwards, in the default order.
+	// It is only needed to interoperate with peers that (incorrectly) require a specific ordering.
+	// If not set, the default order is used.
+	TransportParameterOrder []uint64
+	// ReceiveBufferSize is the size of the receive buffer of the UDP socket, in bytes.
+	// SendBufferSize is the size of the send buffer of the UDP socket, in bytes.
+	// They are only applied to UDP sockets created by quic-go, i.e. when using DialAddr or ListenAddr.
+	// If not set, the operating system's default is used.
+	// The operating system might use a smaller buffer than requested.
+	// This is recorded in the qlog of every session (see GetLogWriter).
+	ReceiveBufferSize int
+	SendBufferSize    int
 	// QUIC Event Tracer.
 	// Warning: Experimental. This API should not be considered stable and will change soon.
 	QuicTracer quictrace.Tracer
+	// PacketTimestampTracer is used to record the send times of packets and the receive times of ACKs.
+	// If nil, no timestamps are recorded.
+	PacketTimestampTracer PacketTimestampTracer
+	// OnConnectionClose is called when a CONNECTION_CLOSE frame is received from the peer.
+	// It is called before the session is closed, i.e. before the session's methods start returning the close error.
+	// It must not block, and must not call any methods on the session.
+	OnConnectionClose func(ConnectionCloseInfo)
 	// GetLogWriter is used to pass in a writer for the qlog.
 	// If it is nil, no qlog will be collected and exported.
 	// If it returns nil, no qlog will be collected and exported for the respective connection.
 	GetLogWriter func(connectionID []byte) io.WriteCloser
+	// NewTLSConn creates the TLS stack used for the handshake, see TLSConn for the contract it has to fulfill.
+	// The tls.Config is a copy of the tls.Config passed to Dial or Listen.
+	// The transport parameters are marshaled, and must be sent to the peer as they are.
+	// If nil, the built-in TLS stack is used.
+	// Warning: This API should not be considered stable and might change soon.
+	NewTLSConn func(conf *tls.Config, recordLayer TLSRecordLayer, transportParameters []byte, isClient bool) TLSConn
 }
 
 // A Listener for incoming QUIC connections
@@ -258,6 +845,8 @@ type Listener interface {
 	// Addr returns the local network addr that the server is listening on.
 	Addr() net.Addr
 	// Accept returns new sessions. It should be called in a loop.
+	// It is safe to call Accept from multiple goroutines concurrently.
+	// Every session is returned to exactly one caller.
 	Accept(context.Context) (Session, error)
 }
 
@@ -269,5 +858,7 @@ type EarlyListener interface {
 	// Addr returns the local network addr that the server is listening on.
 	Addr() net.Addr
 	// Accept returns new early sessions. It should be called in a loop.
+	// It is safe to call Accept from multiple goroutines concurrently.
+	// Every session is returned to exactly one caller.
 	Accept(context.Context) (EarlySession, error)
 }
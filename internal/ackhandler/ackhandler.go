@@ -1,6 +1,8 @@
 package ackhandler
 
 import (
+	"time"
+
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -12,11 +14,17 @@ func NewAckHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *congestion.RTTStats,
 	pers protocol.Perspective,
+	maxPTO time.Duration,
+	packetThreshold int,
+	earlyRetransmit bool,
+	ackImmediatelyAfterIdle bool,
+	maxAckRanges int,
 	traceCallback func(quictrace.Event),
 	qlogger qlog.Tracer,
+	clock utils.Clock,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, rttStats, pers, traceCallback, qlogger, logger)
-	return sph, newReceivedPacketHandler(sph, rttStats, logger, version)
+	sph := newSentPacketHandler(initialPacketNumber, rttStats, pers, maxPTO, packetThreshold, earlyRetransmit, traceCallback, qlogger, clock, logger)
+	return sph, newReceivedPacketHandler(sph, rttStats, ackImmediatelyAfterIdle, maxAckRanges, clock, logger, version)
 }
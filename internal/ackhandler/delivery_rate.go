@@ -0,0 +1,85 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The deliveryRateEstimator estimates the rate at which data is delivered to the peer.
+// It implements the delivery rate sampling described in draft-cheng-iccrg-delivery-rate-estimation:
+// When a packet is acknowledged, the amount of data delivered since that packet was sent
+// is divided by the length of the interval it was delivered in.
+// The estimate is an exponentially weighted moving average of these samples.
+type deliveryRateEstimator struct {
+	// the total number of bytes acknowledged
+	delivered protocol.ByteCount
+	// the time when delivered was last updated
+	deliveredTime time.Time
+	// the send time of the packet that was most recently used to generate a sample
+	firstSentTime time.Time
+
+	// the sample that is being generated while an ACK frame is processed
+	hasSample      bool
+	priorDelivered protocol.ByteCount
+	sendElapsed    time.Duration
+	ackElapsed     time.Duration
+
+	rate congestion.Bandwidth
+}
+
+// OnPacketSent must be called for every ack-eliciting packet, before it is added to the bytes in flight.
+func (e *deliveryRateEstimator) OnPacketSent(p *Packet, bytesInFlight protocol.ByteCount) {
+	// If there's no data in flight, we're starting a new flight of packets.
+	if bytesInFlight == 0 {
+		e.firstSentTime = p.SendTime
+		e.deliveredTime = p.SendTime
+	}
+	p.delivered = e.delivered
+	p.deliveredTime = e.deliveredTime
+	p.firstSentTime = e.firstSentTime
+}
+
+// OnPacketAcked must be called for every newly acknowledged ack-eliciting packet.
+func (e *deliveryRateEstimator) OnPacketAcked(p *Packet, rcvTime time.Time) {
+	e.delivered += p.Length
+	e.deliveredTime = rcvTime
+	// use the packet that was sent most recently to generate the sample
+	if e.hasSample && (p.delivered < e.priorDelivered || (p.delivered == e.priorDelivered && !p.SendTime.After(e.firstSentTime))) {
+		return
+	}
+	e.hasSample = true
+	e.priorDelivered = p.delivered
+	e.sendElapsed = p.SendTime.Sub(p.firstSentTime)
+	e.ackElapsed = e.deliveredTime.Sub(p.deliveredTime)
+	e.firstSentTime = p.SendTime
+}
+
+// OnAckProcessed must be called after all packets acknowledged by an ACK frame were passed to OnPacketAcked.
+// Samples taken over an interval shorter than the minimum RTT are discarded,
+// since they are likely to overestimate the delivery rate.
+func (e *deliveryRateEstimator) OnAckProcessed(minRTT time.Duration) {
+	if !e.hasSample {
+		return
+	}
+	e.hasSample = false
+	// The send rate might be smaller than the ACK rate, e.g. when the ACKs were compressed.
+	interval := utils.MaxDuration(e.sendElapsed, e.ackElapsed)
+	if interval <= 0 || interval < minRTT {
+		return
+	}
+	sample := congestion.BandwidthFromDelta(e.delivered-e.priorDelivered, interval)
+	if e.rate == 0 {
+		e.rate = sample
+		return
+	}
+	e.rate = (7*e.rate + sample) / 8
+}
+
+// DeliveryRate returns the current estimate of the delivery rate.
+// It returns 0 if no valid sample has been taken yet.
+func (e *deliveryRateEstimator) DeliveryRate() congestion.Bandwidth {
+	return e.rate
+}
@@ -0,0 +1,112 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Delivery Rate Estimator", func() {
+	var e *deliveryRateEstimator
+
+	BeforeEach(func() {
+		e = &deliveryRateEstimator{}
+	})
+
+	It("doesn't have an estimate before any packet is acknowledged", func() {
+		Expect(e.DeliveryRate()).To(BeZero())
+		e.OnPacketSent(&Packet{Length: 1000, SendTime: time.Now()}, 0)
+		e.OnAckProcessed(0)
+		Expect(e.DeliveryRate()).To(BeZero())
+	})
+
+	It("takes a sample when a packet is acknowledged", func() {
+		now := time.Now()
+		p1 := &Packet{Length: 1000, SendTime: now}
+		p2 := &Packet{Length: 1000, SendTime: now.Add(10 * time.Millisecond)}
+		e.OnPacketSent(p1, 0)
+		e.OnPacketSent(p2, 1000)
+		e.OnPacketAcked(p1, now.Add(100*time.Millisecond))
+		e.OnAckProcessed(0)
+		// 1000 bytes were delivered in 100ms
+		Expect(e.DeliveryRate()).To(Equal(10000 * congestion.BytesPerSecond))
+		e.OnPacketAcked(p2, now.Add(200*time.Millisecond))
+		e.OnAckProcessed(0)
+		// 2000 bytes were delivered in 200ms, resulting in the same delivery rate
+		Expect(e.DeliveryRate()).To(Equal(10000 * congestion.BytesPerSecond))
+	})
+
+	It("uses the most recently sent packet of an ACK frame to take the sample", func() {
+		now := time.Now()
+		p1 := &Packet{Length: 1000, SendTime: now}
+		p2 := &Packet{Length: 1000, SendTime: now.Add(50 * time.Millisecond)}
+		e.OnPacketSent(p1, 0)
+		e.OnPacketSent(p2, 1000)
+		e.OnPacketAcked(p2, now.Add(100*time.Millisecond))
+		e.OnPacketAcked(p1, now.Add(100*time.Millisecond))
+		e.OnAckProcessed(0)
+		Expect(e.DeliveryRate()).To(Equal(20000 * congestion.BytesPerSecond))
+	})
+
+	It("discards samples that were taken over an interval shorter than the minimum RTT", func() {
+		now := time.Now()
+		p := &Packet{Length: 1000, SendTime: now}
+		e.OnPacketSent(p, 0)
+		e.OnPacketAcked(p, now.Add(10*time.Millisecond))
+		e.OnAckProcessed(20 * time.Millisecond)
+		Expect(e.DeliveryRate()).To(BeZero())
+	})
+
+	It("estimates the rate of a rate-limited link", func() {
+		const (
+			packetSize = 1000
+			linkRate   = 1e6 // bytes per second
+			numPackets = 1000
+			delay      = 10 * time.Millisecond // the one-way delay
+		)
+		// The sender sends faster than the link can transmit the packets.
+		sendInterval := 500 * time.Microsecond
+		transmissionTime := time.Duration(packetSize * float64(time.Second) / linkRate)
+
+		now := time.Now()
+		packets := make([]*Packet, numPackets)
+		ackTimes := make([]time.Time, numPackets)
+		var linkFree time.Time
+		for i := range packets {
+			sendTime := now.Add(time.Duration(i) * sendInterval)
+			packets[i] = &Packet{
+				PacketNumber: protocol.PacketNumber(i),
+				Length:       packetSize,
+				SendTime:     sendTime,
+			}
+			arrival := sendTime.Add(delay)
+			if arrival.Before(linkFree) {
+				arrival = linkFree
+			}
+			linkFree = arrival.Add(transmissionTime)
+			ackTimes[i] = linkFree.Add(delay)
+		}
+
+		var bytesInFlight protocol.ByteCount
+		var acked int
+		ack := func(until time.Time) {
+			for acked < numPackets && !ackTimes[acked].After(until) {
+				e.OnPacketAcked(packets[acked], ackTimes[acked])
+				e.OnAckProcessed(2 * delay)
+				bytesInFlight -= packetSize
+				acked++
+			}
+		}
+		for _, p := range packets {
+			ack(p.SendTime)
+			e.OnPacketSent(p, bytesInFlight)
+			bytesInFlight += packetSize
+		}
+		ack(ackTimes[numPackets-1])
+		Expect(e.DeliveryRate()).To(BeNumerically("~", linkRate*congestion.BytesPerSecond, linkRate*congestion.BytesPerSecond/20))
+	})
+})
@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	"github.com/lucas-clemente/quic-go/quictrace"
@@ -18,6 +19,20 @@ type Packet struct {
 	SendTime        time.Time
 
 	includedInBytesInFlight bool
+
+	// state of the delivery rate estimator when this packet was sent
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+	firstSentTime time.Time
+}
+
+// PacketNumberSpaceStats are the packet numbers used in a packet number space.
+type PacketNumberSpaceStats struct {
+	NextPacketNumber protocol.PacketNumber
+	LargestSent      protocol.PacketNumber // InvalidPacketNumber if no packet was sent yet
+	LargestAcked     protocol.PacketNumber // InvalidPacketNumber if no packet was acknowledged yet
+	// Dropped is set once the packet number space was dropped. The packet numbers are not set in that case.
+	Dropped bool
 }
 
 // SentPacketHandler handles ACKs received for outgoing packets
@@ -50,6 +65,20 @@ type SentPacketHandler interface {
 	GetLossDetectionTimeout() time.Time
 	OnLossDetectionTimeout() error
 
+	// DeliveryRate returns the estimated rate at which data is delivered to the peer.
+	DeliveryRate() congestion.Bandwidth
+	// NumSpuriousRetransmissions returns the number of packets that were declared lost,
+	// but were acknowledged by the peer later.
+	NumSpuriousRetransmissions() uint64
+	// PacketNumberSpaceStats returns the packet numbers used in a packet number space.
+	// It can be called concurrently with the other methods.
+	PacketNumberSpaceStats(protocol.EncryptionLevel) PacketNumberSpaceStats
+	// SetCongestionControl switches the congestion controller to Reno (or Cubic, if reno is false).
+	// The congestion window, the slow start threshold and the RTT statistics are kept.
+	// If the congestion controller is in recovery, the switch is applied once recovery ends.
+	// It can be called concurrently with the other methods.
+	SetCongestionControl(reno bool)
+
 	// report some congestion statistics. For tracing only.
 	GetStats() *quictrace.TransportState
 }
@@ -65,4 +94,10 @@ type ReceivedPacketHandler interface {
 
 	GetAlarmTimeout() time.Time
 	GetAckFrame(protocol.EncryptionLevel) *wire.AckFrame
+	// FlushAcks queues an ACK in every packet number space that has packets waiting to be acknowledged,
+	// such that the next call to GetAckFrame returns the ACK without waiting for the ACK timer.
+	FlushAcks()
+
+	// SetAckFrequency applies the values of an ACK_FREQUENCY frame to the acknowledgement of 1-RTT packets.
+	SetAckFrequency(packetTolerance uint64, maxAckDelay time.Duration, ignoreOrder bool)
 }
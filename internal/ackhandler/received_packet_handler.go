@@ -46,14 +46,17 @@ var _ ReceivedPacketHandler = &receivedPacketHandler{}
 func newReceivedPacketHandler(
 	sentPackets sentPacketTracker,
 	rttStats *congestion.RTTStats,
+	ackImmediatelyAfterIdle bool,
+	maxAckRanges int,
+	clock utils.Clock,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) ReceivedPacketHandler {
 	return &receivedPacketHandler{
 		sentPackets:      sentPackets,
-		initialPackets:   newReceivedPacketTracker(rttStats, logger, version),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger, version),
-		appDataPackets:   newReceivedPacketTracker(rttStats, logger, version),
+		initialPackets:   newReceivedPacketTracker(rttStats, false, maxAckRanges, clock, logger, version),
+		handshakePackets: newReceivedPacketTracker(rttStats, false, maxAckRanges, clock, logger, version),
+		appDataPackets:   newReceivedPacketTracker(rttStats, ackImmediatelyAfterIdle, maxAckRanges, clock, logger, version),
 		lowest1RTTPacket: protocol.InvalidPacketNumber,
 	}
 }
@@ -100,6 +103,20 @@ func (h *receivedPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 	}
 }
 
+func (h *receivedPacketHandler) SetAckFrequency(packetTolerance uint64, maxAckDelay time.Duration, ignoreOrder bool) {
+	h.appDataPackets.SetAckFrequency(packetTolerance, maxAckDelay, ignoreOrder)
+}
+
+func (h *receivedPacketHandler) FlushAcks() {
+	if h.initialPackets != nil {
+		h.initialPackets.FlushAck()
+	}
+	if h.handshakePackets != nil {
+		h.handshakePackets.FlushAck()
+	}
+	h.appDataPackets.FlushAck()
+}
+
 func (h *receivedPacketHandler) GetAlarmTimeout() time.Time {
 	var initialAlarm, handshakeAlarm time.Time
 	if h.initialPackets != nil {
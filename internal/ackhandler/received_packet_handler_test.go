@@ -21,6 +21,9 @@ var _ = Describe("Received Packet Handler", func() {
 		handler = newReceivedPacketHandler(
 			sentPackets,
 			&congestion.RTTStats{},
+			false,
+			protocol.MaxNumAckRanges,
+			utils.DefaultClock{},
 			utils.DefaultLogger,
 			protocol.VersionWhatever,
 		)
@@ -100,6 +103,25 @@ var _ = Describe("Received Packet Handler", func() {
 		Expect(handler.GetAckFrame(protocol.Encryption1RTT)).ToNot(BeNil())
 	})
 
+	It("flushes ACKs", func() {
+		sentPackets.EXPECT().GetLowestPacketNotConfirmedAcked().AnyTimes()
+		handler.SetAckFrequency(10, time.Hour, true)
+		now := time.Now()
+		Expect(handler.ReceivedPacket(0, protocol.Encryption1RTT, now, true)).To(Succeed())
+		Expect(handler.GetAckFrame(protocol.Encryption1RTT)).ToNot(BeNil())
+		Expect(handler.ReceivedPacket(1, protocol.Encryption1RTT, now, true)).To(Succeed())
+		// the ACK is delayed
+		Expect(handler.GetAlarmTimeout()).To(BeTemporally(">", now))
+		Expect(handler.GetAckFrame(protocol.Encryption1RTT)).To(BeNil())
+		handler.DropPackets(protocol.EncryptionInitial)
+		handler.DropPackets(protocol.EncryptionHandshake)
+		handler.FlushAcks()
+		Expect(handler.GetAlarmTimeout()).To(BeZero())
+		ack := handler.GetAckFrame(protocol.Encryption1RTT)
+		Expect(ack).ToNot(BeNil())
+		Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(1)))
+	})
+
 	It("does nothing when dropping 0-RTT packets", func() {
 		handler.DropPackets(protocol.Encryption0RTT)
 	})
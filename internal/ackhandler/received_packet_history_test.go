@@ -14,7 +14,7 @@ var _ = Describe("receivedPacketHistory", func() {
 	)
 
 	BeforeEach(func() {
-		hist = newReceivedPacketHistory()
+		hist = newReceivedPacketHistory(protocol.MaxNumAckRanges)
 	})
 
 	Context("ranges", func() {
@@ -197,6 +197,22 @@ var _ = Describe("receivedPacketHistory", func() {
 			Expect(hist.ranges.Len()).To(Equal(protocol.MaxNumAckRanges))
 			Expect(hist.ranges.Front().Value).To(Equal(utils.PacketInterval{Start: 2, End: 2}))
 		})
+
+		It("doesn't create more than the configured number of ranges", func() {
+			hist = newReceivedPacketHistory(10)
+			for i := protocol.PacketNumber(0); i < 1000; i++ {
+				hist.ReceivedPacket(3 * i)
+				Expect(hist.ranges.Len()).To(BeNumerically("<=", 10))
+			}
+			// the most recent ranges are still acknowledged
+			ackRanges := hist.GetAckRanges()
+			Expect(ackRanges).To(HaveLen(10))
+			Expect(ackRanges[0]).To(Equal(wire.AckRange{Smallest: 3 * 999, Largest: 3 * 999}))
+			Expect(ackRanges[9]).To(Equal(wire.AckRange{Smallest: 3 * 990, Largest: 3 * 990}))
+			// filling a gap still works
+			hist.ReceivedPacket(3*999 - 1)
+			Expect(hist.GetAckRanges()[0]).To(Equal(wire.AckRange{Smallest: 3*999 - 1, Largest: 3 * 999}))
+		})
 	})
 
 	Context("ACK range export", func() {
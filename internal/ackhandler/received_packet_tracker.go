@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"math"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -13,18 +14,26 @@ type receivedPacketTracker struct {
 	largestObserved             protocol.PacketNumber
 	ignoreBelow                 protocol.PacketNumber
 	largestObservedReceivedTime time.Time
+	lastPacketReceivedTime      time.Time
 
 	packetHistory *receivedPacketHistory
 
 	maxAckDelay time.Duration
 	rttStats    *congestion.RTTStats
 
+	ackImmediatelyAfterIdle bool
+
+	// set by the peer using an ACK_FREQUENCY frame
+	packetTolerance int // if 0, the default ack decimation is used
+	ignoreOrder     bool
+
 	packetsReceivedSinceLastAck             int
 	ackElicitingPacketsReceivedSinceLastAck int
 	ackQueued                               bool
 	ackAlarm                                time.Time
 	lastAck                                 *wire.AckFrame
 
+	clock  utils.Clock
 	logger utils.Logger
 
 	version protocol.VersionNumber
@@ -32,15 +41,20 @@ type receivedPacketTracker struct {
 
 func newReceivedPacketTracker(
 	rttStats *congestion.RTTStats,
+	ackImmediatelyAfterIdle bool,
+	maxAckRanges int,
+	clock utils.Clock,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
-		maxAckDelay:   protocol.MaxAckDelay,
-		rttStats:      rttStats,
-		logger:        logger,
-		version:       version,
+		packetHistory:           newReceivedPacketHistory(maxAckRanges),
+		maxAckDelay:             protocol.MaxAckDelay,
+		rttStats:                rttStats,
+		ackImmediatelyAfterIdle: ackImmediatelyAfterIdle,
+		clock:                   clock,
+		logger:                  logger,
+		version:                 version,
 	}
 }
 
@@ -50,13 +64,27 @@ func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumbe
 	}
 
 	isMissing := h.isMissing(packetNumber)
+	afterIdle := h.isIdle(rcvTime)
 	if packetNumber >= h.largestObserved {
 		h.largestObserved = packetNumber
 		h.largestObservedReceivedTime = rcvTime
 	}
+	h.lastPacketReceivedTime = rcvTime
 
 	h.packetHistory.ReceivedPacket(packetNumber)
-	h.maybeQueueAck(packetNumber, rcvTime, shouldInstigateAck, isMissing)
+	h.maybeQueueAck(packetNumber, rcvTime, shouldInstigateAck, isMissing, afterIdle)
+}
+
+// SetAckFrequency sets the number of ack-eliciting packets that may be received
+// before an ACK is sent, and the maximum ack delay.
+// If ignoreOrder is set, reordered packets don't cause ACKs to be sent immediately.
+func (h *receivedPacketTracker) SetAckFrequency(packetTolerance uint64, maxAckDelay time.Duration, ignoreOrder bool) {
+	if packetTolerance > math.MaxInt32 {
+		packetTolerance = math.MaxInt32
+	}
+	h.packetTolerance = int(packetTolerance)
+	h.maxAckDelay = maxAckDelay
+	h.ignoreOrder = ignoreOrder
 }
 
 // IgnoreBelow sets a lower limit for acking packets.
@@ -80,6 +108,15 @@ func (h *receivedPacketTracker) isMissing(p protocol.PacketNumber) bool {
 	return p < h.lastAck.LargestAcked() && !h.lastAck.AcksPacket(p)
 }
 
+// isIdle says if no packet was received for more than both the RTT and the max ack delay.
+// Any ACK that was delayed before this period would already have been sent.
+func (h *receivedPacketTracker) isIdle(now time.Time) bool {
+	if h.lastPacketReceivedTime.IsZero() {
+		return false
+	}
+	return now.Sub(h.lastPacketReceivedTime) > utils.MaxDuration(h.rttStats.SmoothedRTT(), h.maxAckDelay)
+}
+
 func (h *receivedPacketTracker) hasNewMissingPackets() bool {
 	if h.lastAck == nil {
 		return false
@@ -91,7 +128,7 @@ func (h *receivedPacketTracker) hasNewMissingPackets() bool {
 // maybeQueueAck queues an ACK, if necessary.
 // It is implemented analogously to Chrome's QuicConnection::MaybeQueueAck()
 // in ACK_DECIMATION_WITH_REORDERING mode.
-func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber, rcvTime time.Time, shouldInstigateAck, wasMissing bool) {
+func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber, rcvTime time.Time, shouldInstigateAck, wasMissing, afterIdle bool) {
 	h.packetsReceivedSinceLastAck++
 
 	// always ack the first packet
@@ -104,17 +141,34 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 	// Send an ACK if this packet was reported missing in an ACK sent before.
 	// Ack decimation with reordering relies on the timer to send an ACK, but if
 	// missing packets we reported in the previous ack, send an ACK immediately.
-	if wasMissing {
+	if wasMissing && !h.ignoreOrder {
 		if h.logger.Debug() {
 			h.logger.Debugf("\tQueueing ACK because packet %#x was missing before.", packetNumber)
 		}
 		h.ackQueued = true
 	}
 
+	// Send an ACK immediately for the first ack-eliciting packet received after an idle period,
+	// instead of waiting for the ACK timer.
+	if h.ackImmediatelyAfterIdle && afterIdle && shouldInstigateAck {
+		h.logger.Debugf("\tQueueing ACK because the packet was received after an idle period.")
+		h.ackQueued = true
+	}
+
 	if !h.ackQueued && shouldInstigateAck {
 		h.ackElicitingPacketsReceivedSinceLastAck++
 
-		if packetNumber > minReceivedBeforeAckDecimation {
+		if h.packetTolerance > 0 {
+			// the peer told us how often to send ACKs
+			if h.ackElicitingPacketsReceivedSinceLastAck >= h.packetTolerance {
+				h.ackQueued = true
+				if h.logger.Debug() {
+					h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using packet tolerance: %d).", h.ackElicitingPacketsReceivedSinceLastAck, h.packetTolerance)
+				}
+			} else if h.ackAlarm.IsZero() {
+				h.ackAlarm = rcvTime.Add(h.maxAckDelay)
+			}
+		} else if packetNumber > minReceivedBeforeAckDecimation {
 			// ack up to 10 packets at once
 			if h.ackElicitingPacketsReceivedSinceLastAck >= ackElicitingPacketsBeforeAck {
 				h.ackQueued = true
@@ -126,7 +180,7 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 				ackDelay := utils.MinDuration(h.maxAckDelay, time.Duration(float64(h.rttStats.MinRTT())*float64(ackDecimationDelay)))
 				h.ackAlarm = rcvTime.Add(ackDelay)
 				if h.logger.Debug() {
-					h.logger.Debugf("\tSetting ACK timer to min(1/4 min-RTT, max ack delay): %s (%s from now)", ackDelay, time.Until(h.ackAlarm))
+					h.logger.Debugf("\tSetting ACK timer to min(1/4 min-RTT, max ack delay): %s (%s from now)", ackDelay, h.ackAlarm.Sub(h.clock.Now()))
 				}
 			}
 		} else {
@@ -144,14 +198,14 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 			}
 		}
 		// If there are new missing packets to report, set a short timer to send an ACK.
-		if h.hasNewMissingPackets() {
+		if !h.ignoreOrder && h.hasNewMissingPackets() {
 			// wait the minimum of 1/8 min RTT and the existing ack time
 			ackDelay := time.Duration(float64(h.rttStats.MinRTT()) * float64(shortAckDecimationDelay))
 			ackTime := rcvTime.Add(ackDelay)
 			if h.ackAlarm.IsZero() || h.ackAlarm.After(ackTime) {
 				h.ackAlarm = ackTime
 				if h.logger.Debug() {
-					h.logger.Debugf("\tSetting ACK timer to 1/8 min-RTT: %s (%s from now)", ackDelay, time.Until(h.ackAlarm))
+					h.logger.Debugf("\tSetting ACK timer to 1/8 min-RTT: %s (%s from now)", ackDelay, h.ackAlarm.Sub(h.clock.Now()))
 				}
 			}
 		}
@@ -163,8 +217,20 @@ func (h *receivedPacketTracker) maybeQueueAck(packetNumber protocol.PacketNumber
 	}
 }
 
+// FlushAck queues an ACK if there are ack-eliciting packets that are waiting for the ACK timer.
+func (h *receivedPacketTracker) FlushAck() {
+	if h.ackAlarm.IsZero() {
+		return
+	}
+	if h.logger.Debug() {
+		h.logger.Debugf("\tQueueing ACK because it was flushed.")
+	}
+	h.ackQueued = true
+	h.ackAlarm = time.Time{}
+}
+
 func (h *receivedPacketTracker) GetAckFrame() *wire.AckFrame {
-	now := time.Now()
+	now := h.clock.Now()
 	if !h.ackQueued && (h.ackAlarm.IsZero() || h.ackAlarm.After(now)) {
 		return nil
 	}
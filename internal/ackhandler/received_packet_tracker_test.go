@@ -20,7 +20,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &congestion.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger, protocol.VersionWhatever)
+		tracker = newReceivedPacketTracker(rttStats, false, protocol.MaxNumAckRanges, utils.DefaultClock{}, utils.DefaultLogger, protocol.VersionWhatever)
 	})
 
 	Context("accepting packets", func() {
@@ -173,6 +173,86 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(ack.HasMissingRanges()).To(BeTrue())
 				Expect(ack).ToNot(BeNil())
 			})
+
+			Context("using the values from an ACK_FREQUENCY frame", func() {
+				It("queues an ACK after the packet tolerance is reached", func() {
+					receiveAndAck10Packets()
+					tracker.SetAckFrequency(4, 50*time.Millisecond, false)
+					now := time.Now()
+					for p := protocol.PacketNumber(11); p < 14; p++ {
+						tracker.ReceivedPacket(p, now, true)
+						Expect(tracker.ackQueued).To(BeFalse())
+					}
+					Expect(tracker.GetAlarmTimeout()).To(Equal(now.Add(50 * time.Millisecond)))
+					tracker.ReceivedPacket(14, now, true)
+					Expect(tracker.ackQueued).To(BeTrue())
+					Expect(tracker.GetAlarmTimeout()).To(BeZero())
+				})
+
+				It("uses the packet tolerance after ack decimation would have kicked in", func() {
+					receiveAndAckPacketsUntilAckDecimation()
+					tracker.SetAckFrequency(20, 50*time.Millisecond, false)
+					p := protocol.PacketNumber(minReceivedBeforeAckDecimation + 1)
+					for i := 0; i < 19; i++ {
+						tracker.ReceivedPacket(p, time.Now(), true)
+						Expect(tracker.ackQueued).To(BeFalse())
+						p++
+					}
+					tracker.ReceivedPacket(p, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeTrue())
+				})
+
+				It("doesn't queue an ACK for reordered packets, if the peer asked to ignore the order", func() {
+					receiveAndAck10Packets()
+					tracker.SetAckFrequency(100, 50*time.Millisecond, true)
+					tracker.ReceivedPacket(11, time.Now(), true)
+					tracker.ReceivedPacket(13, time.Now(), true)
+					tracker.ackQueued = true // force sending an ACK that reports 12 missing
+					ack := tracker.GetAckFrame()
+					Expect(ack).ToNot(BeNil())
+					Expect(ack.HasMissingRanges()).To(BeTrue())
+					tracker.ReceivedPacket(12, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+				})
+			})
+
+			Context("acknowledging immediately after idle", func() {
+				BeforeEach(func() {
+					tracker = newReceivedPacketTracker(rttStats, true, protocol.MaxNumAckRanges, utils.DefaultClock{}, utils.DefaultLogger, protocol.VersionWhatever)
+					rttStats.UpdateRTT(10*time.Millisecond, 0, time.Now())
+				})
+
+				It("queues an ACK for the first ack-eliciting packet after an idle period", func() {
+					receiveAndAck10Packets()
+					now := time.Now()
+					tracker.ReceivedPacket(11, now, true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					Expect(tracker.GetAckFrame()).To(BeNil())
+					idleTime := utils.MaxDuration(rttStats.SmoothedRTT(), protocol.MaxAckDelay) + time.Millisecond
+					tracker.ReceivedPacket(12, now.Add(idleTime), true)
+					Expect(tracker.ackQueued).To(BeTrue())
+					Expect(tracker.GetAlarmTimeout()).To(BeZero())
+					Expect(tracker.GetAckFrame()).ToNot(BeNil())
+				})
+
+				It("doesn't queue an ACK for packets received within the idle timeout", func() {
+					receiveAndAck10Packets()
+					now := time.Now()
+					tracker.ReceivedPacket(11, now, false)
+					tracker.ReceivedPacket(12, now.Add(protocol.MaxAckDelay/2), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+					Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
+				})
+
+				It("doesn't queue an ACK for non-ack-eliciting packets after an idle period", func() {
+					receiveAndAck10Packets()
+					now := time.Now()
+					tracker.ReceivedPacket(11, now, true)
+					Expect(tracker.GetAckFrame()).To(BeNil())
+					tracker.ReceivedPacket(12, now.Add(time.Second), false)
+					Expect(tracker.ackQueued).To(BeFalse())
+				})
+			})
 		})
 
 		Context("ACK generation", func() {
@@ -315,6 +395,24 @@ var _ = Describe("Received Packet Tracker", func() {
 				tracker.ackAlarm = time.Now().Add(-time.Minute)
 				Expect(tracker.GetAckFrame()).ToNot(BeNil())
 			})
+
+			It("generates an ACK before the timer expires, when it is flushed", func() {
+				tracker.ReceivedPacket(1, time.Time{}, true)
+				tracker.ackQueued = false
+				tracker.ackAlarm = time.Now().Add(time.Minute)
+				tracker.FlushAck()
+				Expect(tracker.GetAlarmTimeout()).To(BeZero())
+				ack := tracker.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(1)))
+			})
+
+			It("doesn't generate an ACK when flushing, if no ACK is pending", func() {
+				tracker.ReceivedPacket(1, time.Time{}, true)
+				Expect(tracker.GetAckFrame()).ToNot(BeNil())
+				tracker.FlushAck()
+				Expect(tracker.GetAckFrame()).To(BeNil())
+			})
 		})
 	})
 })
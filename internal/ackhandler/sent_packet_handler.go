@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"fmt"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -18,8 +19,6 @@ const (
 	// Maximum reordering in time space before time based loss detection considers a packet lost.
 	// Specified as an RTT multiplier.
 	timeThreshold = 9.0 / 8
-	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
-	packetThreshold = 3
 )
 
 type packetNumberSpace struct {
@@ -31,6 +30,9 @@ type packetNumberSpace struct {
 
 	largestAcked protocol.PacketNumber
 	largestSent  protocol.PacketNumber
+
+	// packet numbers of packets that were declared lost, used to detect spurious retransmissions
+	lostPackets []protocol.PacketNumber
 }
 
 func newPacketNumberSpace(initialPN protocol.PacketNumber) *packetNumberSpace {
@@ -48,6 +50,10 @@ type sentPacketHandler struct {
 	initialPackets   *packetNumberSpace
 	handshakePackets *packetNumberSpace
 	appDataPackets   *packetNumberSpace
+	// pnSpaceMutex protects the packet number spaces and their packet numbers,
+	// which are also read by PacketNumberSpaceStats.
+	// It is only needed for writes, since all other methods are called from the same go routine.
+	pnSpaceMutex sync.Mutex
 
 	peerNotAwaitingAddressValidation bool
 	handshakeComplete                bool
@@ -60,15 +66,36 @@ type sentPacketHandler struct {
 
 	bytesInFlight protocol.ByteCount
 
-	congestion congestion.SendAlgorithmWithDebugInfos
-	rttStats   *congestion.RTTStats
+	congestion   congestion.SendAlgorithmWithDebugInfos
+	rttStats     *congestion.RTTStats
+	deliveryRate deliveryRateEstimator
+
+	// congestionSwitchMutex protects the pending switch of the congestion controller,
+	// which is requested by SetCongestionControl.
+	congestionSwitchMutex sync.Mutex
+	// Set when the congestion controller should switch to Reno (or Cubic, if usePendingReno is false).
+	// The switch is only applied when the congestion controller is not in recovery.
+	congestionSwitchPending bool
+	usePendingReno          bool
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
 	ptoMode  SendMode
+	// The maximum PTO, including the exponential backoff.
+	// If zero, the PTO is not capped.
+	maxPTO time.Duration
+	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
+	packetThreshold protocol.PacketNumber
+	// If set, packets are declared lost when all packets sent after them have been acknowledged.
+	earlyRetransmit bool
+	// Set once a packet is acknowledged after a packet sent after it was acknowledged.
+	// Early retransmit is not used once reordering has been observed.
+	reorderingObserved bool
 	// The number of PTO probe packets that should be sent.
 	// Only applies to the application-data packet number space.
 	numProbesToSend int
+	// The number of packets that were declared lost, but were acknowledged later.
+	numSpuriousRetransmissions uint64
 
 	// The alarm timeout
 	alarm time.Time
@@ -77,7 +104,11 @@ type sentPacketHandler struct {
 
 	traceCallback func(quictrace.Event)
 	qlogger       qlog.Tracer
-	logger        utils.Logger
+	// the congestion state that was last logged to the qlog
+	congestionState qlog.CongestionState
+
+	clock  utils.Clock
+	logger utils.Logger
 }
 
 var _ SentPacketHandler = &sentPacketHandler{}
@@ -87,12 +118,16 @@ func newSentPacketHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *congestion.RTTStats,
 	pers protocol.Perspective,
+	maxPTO time.Duration,
+	packetThreshold int,
+	earlyRetransmit bool,
 	traceCallback func(quictrace.Event),
 	qlogger qlog.Tracer,
+	clock utils.Clock,
 	logger utils.Logger,
 ) *sentPacketHandler {
 	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
+		clock,
 		rttStats,
 		true, // use Reno
 	)
@@ -109,8 +144,12 @@ func newSentPacketHandler(
 		rttStats:                         rttStats,
 		congestion:                       congestion,
 		perspective:                      pers,
+		maxPTO:                           maxPTO,
+		packetThreshold:                  protocol.PacketNumber(packetThreshold),
+		earlyRetransmit:                  earlyRetransmit,
 		traceCallback:                    traceCallback,
 		qlogger:                          qlogger,
+		clock:                            clock,
 		logger:                           logger,
 	}
 }
@@ -139,9 +178,13 @@ func (h *sentPacketHandler) dropPackets(encLevel protocol.EncryptionLevel) {
 	// drop the packet history
 	switch encLevel {
 	case protocol.EncryptionInitial:
+		h.pnSpaceMutex.Lock()
 		h.initialPackets = nil
+		h.pnSpaceMutex.Unlock()
 	case protocol.EncryptionHandshake:
+		h.pnSpaceMutex.Lock()
 		h.handshakePackets = nil
+		h.pnSpaceMutex.Unlock()
 	case protocol.Encryption0RTT:
 		// TODO(#2067): invalidate sent data
 		h.appDataPackets.history.Iterate(func(p *Packet) (bool, error) {
@@ -161,7 +204,7 @@ func (h *sentPacketHandler) dropPackets(encLevel protocol.EncryptionLevel) {
 	h.setLossDetectionTimer()
 	h.ptoCount = 0
 	if h.qlogger != nil {
-		h.qlogger.UpdatedPTOCount(time.Now(), 0)
+		h.qlogger.UpdatedPTOCount(h.clock.Now(), 0)
 	}
 	h.ptoMode = SendNone
 }
@@ -202,12 +245,15 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* is ack-elicit
 		}
 	}
 
+	h.pnSpaceMutex.Lock()
 	pnSpace.largestSent = packet.PacketNumber
+	h.pnSpaceMutex.Unlock()
 	isAckEliciting := len(packet.Frames) > 0
 
 	if isAckEliciting {
 		pnSpace.lastSentAckElicitingPacketTime = packet.SendTime
 		packet.includedInBytesInFlight = true
+		h.deliveryRate.OnPacketSent(packet, h.bytesInFlight)
 		h.bytesInFlight += packet.Length
 		if h.numProbesToSend > 0 {
 			h.numProbesToSend--
@@ -227,12 +273,17 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		return qerr.Error(qerr.ProtocolViolation, "Received ACK for an unsent packet")
 	}
 
+	priorLargestAcked := pnSpace.largestAcked
+	h.pnSpaceMutex.Lock()
 	pnSpace.largestAcked = utils.MaxPacketNumber(pnSpace.largestAcked, largestAcked)
+	h.pnSpaceMutex.Unlock()
 
 	if !pnSpace.pns.Validate(ack) {
 		return qerr.Error(qerr.ProtocolViolation, "Received an ACK for a skipped packet number")
 	}
 
+	h.detectSpuriousRetransmissions(ack, pnSpace)
+
 	// Servers complete address validation when a protected packet is received.
 	if h.perspective == protocol.PerspectiveClient && !h.peerNotAwaitingAddressValidation &&
 		(encLevel == protocol.EncryptionHandshake || encLevel == protocol.Encryption1RTT) {
@@ -276,17 +327,24 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		if p.LargestAcked != protocol.InvalidPacketNumber && encLevel == protocol.Encryption1RTT {
 			h.lowestNotConfirmedAcked = utils.MaxPacketNumber(h.lowestNotConfirmedAcked, p.LargestAcked+1)
 		}
+		if p.PacketNumber < priorLargestAcked && !h.reorderingObserved {
+			h.logger.Debugf("\tpacket %#x was reordered, disabling early retransmit", p.PacketNumber)
+			h.reorderingObserved = true
+		}
 		if err := h.onPacketAcked(p); err != nil {
 			return err
 		}
 		if p.includedInBytesInFlight {
 			h.congestion.OnPacketAcked(p.PacketNumber, p.Length, priorInFlight, rcvTime)
+			h.deliveryRate.OnPacketAcked(p, rcvTime)
 		}
 	}
+	h.deliveryRate.OnAckProcessed(h.rttStats.MinRTT())
 
 	if err := h.detectLostPackets(rcvTime, encLevel, priorInFlight); err != nil {
 		return err
 	}
+	h.maybeLogCongestionState(rcvTime)
 
 	h.ptoCount = 0
 	if h.qlogger != nil {
@@ -298,6 +356,25 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	return nil
 }
 
+// detectSpuriousRetransmissions checks if an ACK frame acknowledges packets that were already declared lost.
+func (h *sentPacketHandler) detectSpuriousRetransmissions(ack *wire.AckFrame, pnSpace *packetNumberSpace) {
+	if len(pnSpace.lostPackets) == 0 {
+		return
+	}
+	lostPackets := pnSpace.lostPackets[:0]
+	for _, pn := range pnSpace.lostPackets {
+		if ack.AcksPacket(pn) {
+			h.numSpuriousRetransmissions++
+			if h.logger.Debug() {
+				h.logger.Debugf("\tpacket %#x was declared lost, but was acknowledged", pn)
+			}
+			continue
+		}
+		lostPackets = append(lostPackets, pn)
+	}
+	pnSpace.lostPackets = lostPackets
+}
+
 func (h *sentPacketHandler) GetLowestPacketNotConfirmedAcked() protocol.PacketNumber {
 	return h.lowestNotConfirmedAcked
 }
@@ -425,7 +502,23 @@ func (h *sentPacketHandler) setLossDetectionTimer() {
 
 	// PTO alarm
 	sentTime, encLevel := h.getEarliestSentTimeAndSpace()
-	h.alarm = sentTime.Add(h.rttStats.PTO(encLevel == protocol.Encryption1RTT) << h.ptoCount)
+	h.alarm = sentTime.Add(h.getPTO(encLevel))
+}
+
+// getPTO returns the probe timeout, including the exponential backoff.
+// If a maximum PTO is configured, the exponential backoff is capped at this value.
+// The PTO derived from the RTT is never reduced.
+func (h *sentPacketHandler) getPTO(encLevel protocol.EncryptionLevel) time.Duration {
+	basePTO := h.rttStats.PTO(encLevel == protocol.Encryption1RTT)
+	if h.maxPTO == 0 {
+		return basePTO << h.ptoCount
+	}
+	maxPTO := utils.MaxDuration(h.maxPTO, basePTO)
+	pto := basePTO
+	for i := uint32(0); i < h.ptoCount && pto < maxPTO; i++ {
+		pto <<= 1
+	}
+	return utils.MinDuration(pto, maxPTO)
 }
 
 func (h *sentPacketHandler) detectLostPackets(
@@ -445,6 +538,15 @@ func (h *sentPacketHandler) detectLostPackets(
 	// Packets sent before this time are deemed lost.
 	lostSendTime := now.Add(-lossDelay)
 
+	// If no packet sent after the largest acknowledged packet is outstanding,
+	// no further acknowledgements will be received that could trigger packet threshold loss detection.
+	var earlyRetransmit bool
+	if h.earlyRetransmit && !h.reorderingObserved {
+		if p := pnSpace.history.LastOutstanding(); p != nil && p.PacketNumber < pnSpace.largestAcked {
+			earlyRetransmit = true
+		}
+	}
+
 	var lostPackets []*Packet
 	pnSpace.history.Iterate(func(packet *Packet) (bool, error) {
 		if packet.PacketNumber > pnSpace.largestAcked {
@@ -456,7 +558,7 @@ func (h *sentPacketHandler) detectLostPackets(
 			if h.qlogger != nil {
 				h.qlogger.LostPacket(now, packet.EncryptionLevel, packet.PacketNumber, qlog.PacketLossTimeThreshold)
 			}
-		} else if pnSpace.largestAcked >= packet.PacketNumber+packetThreshold {
+		} else if earlyRetransmit || pnSpace.largestAcked >= packet.PacketNumber+h.packetThreshold {
 			lostPackets = append(lostPackets, packet)
 			if h.qlogger != nil {
 				h.qlogger.LostPacket(now, packet.EncryptionLevel, packet.PacketNumber, qlog.PacketLossReorderingThreshold)
@@ -488,6 +590,10 @@ func (h *sentPacketHandler) detectLostPackets(
 			h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
 		}
 		pnSpace.history.Remove(p.PacketNumber)
+		if len(pnSpace.lostPackets) >= protocol.MaxTrackedLostPackets {
+			pnSpace.lostPackets = pnSpace.lostPackets[1:]
+		}
+		pnSpace.lostPackets = append(pnSpace.lostPackets, p.PacketNumber)
 		if h.traceCallback != nil {
 			frames := make([]wire.Frame, 0, len(p.Frames))
 			for _, f := range p.Frames {
@@ -528,7 +634,12 @@ func (h *sentPacketHandler) onVerifiedLossDetectionTimeout() error {
 			h.logger.Debugf("Loss detection alarm fired in loss timer mode. Loss time: %s", earliestLossTime)
 		}
 		// Early retransmit or time loss detection
-		return h.detectLostPackets(time.Now(), encLevel, h.bytesInFlight)
+		now := h.clock.Now()
+		if err := h.detectLostPackets(now, encLevel, h.bytesInFlight); err != nil {
+			return err
+		}
+		h.maybeLogCongestionState(now)
+		return nil
 	}
 
 	// PTO
@@ -538,7 +649,7 @@ func (h *sentPacketHandler) onVerifiedLossDetectionTimeout() error {
 	}
 	h.ptoCount++
 	if h.qlogger != nil {
-		h.qlogger.UpdatedPTOCount(time.Now(), h.ptoCount)
+		h.qlogger.UpdatedPTOCount(h.clock.Now(), h.ptoCount)
 	}
 	h.numProbesToSend += 2
 	switch encLevel {
@@ -554,6 +665,32 @@ func (h *sentPacketHandler) onVerifiedLossDetectionTimeout() error {
 	return nil
 }
 
+// maybeLogCongestionState logs the state of the congestion controller to the qlog, if it changed.
+func (h *sentPacketHandler) maybeLogCongestionState(now time.Time) {
+	if h.qlogger == nil {
+		return
+	}
+	state := qlog.CongestionStateCongestionAvoidance
+	if h.congestion.InRecovery() {
+		state = qlog.CongestionStateRecovery
+	} else if h.congestion.InSlowStart() {
+		state = qlog.CongestionStateSlowStart
+	}
+	if state == h.congestionState {
+		return
+	}
+	h.congestionState = state
+	h.qlogger.UpdatedCongestionState(now, state)
+}
+
+func (h *sentPacketHandler) NumSpuriousRetransmissions() uint64 {
+	return h.numSpuriousRetransmissions
+}
+
+func (h *sentPacketHandler) DeliveryRate() congestion.Bandwidth {
+	return h.deliveryRate.DeliveryRate()
+}
+
 func (h *sentPacketHandler) GetLossDetectionTimeout() time.Time {
 	return h.alarm
 }
@@ -590,10 +727,60 @@ func (h *sentPacketHandler) PeekPacketNumber(encLevel protocol.EncryptionLevel)
 }
 
 func (h *sentPacketHandler) PopPacketNumber(encLevel protocol.EncryptionLevel) protocol.PacketNumber {
+	h.pnSpaceMutex.Lock()
+	defer h.pnSpaceMutex.Unlock()
+
 	return h.getPacketNumberSpace(encLevel).pns.Pop()
 }
 
+func (h *sentPacketHandler) PacketNumberSpaceStats(encLevel protocol.EncryptionLevel) PacketNumberSpaceStats {
+	h.pnSpaceMutex.Lock()
+	defer h.pnSpaceMutex.Unlock()
+
+	pnSpace := h.getPacketNumberSpace(encLevel)
+	if pnSpace == nil {
+		return PacketNumberSpaceStats{Dropped: true}
+	}
+	return PacketNumberSpaceStats{
+		NextPacketNumber: pnSpace.pns.Peek(),
+		LargestSent:      pnSpace.largestSent,
+		LargestAcked:     pnSpace.largestAcked,
+	}
+}
+
+func (h *sentPacketHandler) SetCongestionControl(reno bool) {
+	h.congestionSwitchMutex.Lock()
+	h.congestionSwitchPending = true
+	h.usePendingReno = reno
+	h.congestionSwitchMutex.Unlock()
+}
+
+func (h *sentPacketHandler) maybeSwitchCongestionControl() {
+	h.congestionSwitchMutex.Lock()
+	defer h.congestionSwitchMutex.Unlock()
+
+	if !h.congestionSwitchPending {
+		return
+	}
+	// Switching the congestion avoidance algorithm while in recovery would mix up the window reduction
+	// of one algorithm with the window growth of the other one.
+	if h.congestion.InRecovery() {
+		return
+	}
+	h.congestionSwitchPending = false
+	h.congestion.SetReno(h.usePendingReno)
+	if h.logger.Debug() {
+		if h.usePendingReno {
+			h.logger.Debugf("Switched congestion control to Reno (congestion window: %d)", h.congestion.GetCongestionWindow())
+		} else {
+			h.logger.Debugf("Switched congestion control to Cubic (congestion window: %d)", h.congestion.GetCongestionWindow())
+		}
+	}
+}
+
 func (h *sentPacketHandler) SendMode() SendMode {
+	h.maybeSwitchCongestionControl()
+
 	numTrackedPackets := h.appDataPackets.history.Len()
 	if h.initialPackets != nil {
 		numTrackedPackets += h.initialPackets.history.Len()
@@ -685,8 +872,10 @@ func (h *sentPacketHandler) ResetForRetry() error {
 		return true, nil
 	})
 
+	h.pnSpaceMutex.Lock()
 	h.initialPackets = newPacketNumberSpace(h.initialPackets.pns.Pop())
 	h.appDataPackets = newPacketNumberSpace(h.appDataPackets.pns.Pop())
+	h.pnSpaceMutex.Unlock()
 	h.alarm = time.Time{}
 	return nil
 }
@@ -10,24 +10,48 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/qlog"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+// congestionStateRecorder is a qlog.Tracer that records congestion state updates.
+type congestionStateRecorder struct {
+	qlog.Tracer
+	states []qlog.CongestionState
+}
+
+func (r *congestionStateRecorder) UpdatedMetrics(time.Time, *congestion.RTTStats, protocol.ByteCount, protocol.ByteCount, int) {
+}
+func (r *congestionStateRecorder) UpdatedPTOCount(time.Time, uint32) {}
+func (r *congestionStateRecorder) LostPacket(time.Time, protocol.EncryptionLevel, protocol.PacketNumber, qlog.PacketLossReason) {
+}
+
+func (r *congestionStateRecorder) UpdatedCongestionState(_ time.Time, state qlog.CongestionState) {
+	r.states = append(r.states, state)
+}
+
 var _ = Describe("SentPacketHandler", func() {
 	var (
 		handler     *sentPacketHandler
 		streamFrame wire.StreamFrame
 		lostPackets []protocol.PacketNumber
 		perspective protocol.Perspective
+		// the packet reordering threshold used for loss detection
+		packetThreshold int
+		earlyRetransmit bool
 	)
 
-	BeforeEach(func() { perspective = protocol.PerspectiveServer })
+	BeforeEach(func() {
+		perspective = protocol.PerspectiveServer
+		packetThreshold = protocol.DefaultPacketReorderingThreshold
+		earlyRetransmit = false
+	})
 
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := &congestion.RTTStats{}
-		handler = newSentPacketHandler(42, rttStats, perspective, nil, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, rttStats, perspective, 0, packetThreshold, earlyRetransmit, nil, nil, utils.DefaultClock{}, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -470,6 +494,37 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
 		})
 
+		It("logs congestion state changes to the qlog", func() {
+			recorder := &congestionStateRecorder{}
+			handler.qlogger = recorder
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			cong.EXPECT().TimeUntilSend(gomock.Any()).Times(2)
+			cong.EXPECT().GetCongestionWindow().AnyTimes()
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+			// lose packet 1
+			gomock.InOrder(
+				cong.EXPECT().MaybeExitSlowStart(),
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(2), protocol.ByteCount(1), protocol.ByteCount(2), gomock.Any()),
+				cong.EXPECT().OnPacketLost(protocol.PacketNumber(1), protocol.ByteCount(1), protocol.ByteCount(2)),
+				cong.EXPECT().InRecovery().Return(true),
+			)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(recorder.states).To(Equal([]qlog.CongestionState{qlog.CongestionStateRecovery}))
+			// only state changes are logged
+			cong.EXPECT().InRecovery().Return(true)
+			handler.maybeLogCongestionState(time.Now())
+			Expect(recorder.states).To(HaveLen(1))
+			cong.EXPECT().InRecovery().Return(false)
+			cong.EXPECT().InSlowStart().Return(false)
+			handler.maybeLogCongestionState(time.Now())
+			Expect(recorder.states).To(Equal([]qlog.CongestionState{
+				qlog.CongestionStateRecovery,
+				qlog.CongestionStateCongestionAvoidance,
+			}))
+		})
+
 		It("calls OnPacketAcked and OnPacketLost with the right bytes_in_flight value", func() {
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
 			cong.EXPECT().TimeUntilSend(gomock.Any()).Times(4)
@@ -508,6 +563,33 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).To(Equal(SendAck))
 		})
 
+		It("switches the congestion controller", func() {
+			handler.SetCongestionControl(false)
+			gomock.InOrder(
+				cong.EXPECT().InRecovery(),
+				cong.EXPECT().SetReno(false),
+				cong.EXPECT().CanSend(gomock.Any()).Return(true),
+			)
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			// the switch is only applied once
+			cong.EXPECT().CanSend(gomock.Any()).Return(true)
+			Expect(handler.SendMode()).To(Equal(SendAny))
+		})
+
+		It("delays switching the congestion controller until recovery ends", func() {
+			handler.SetCongestionControl(false)
+			handler.SetCongestionControl(true)
+			cong.EXPECT().CanSend(gomock.Any()).Return(true).AnyTimes()
+			cong.EXPECT().InRecovery().Return(true).Times(2)
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			gomock.InOrder(
+				cong.EXPECT().InRecovery(),
+				cong.EXPECT().SetReno(true),
+			)
+			Expect(handler.SendMode()).To(Equal(SendAny))
+		})
+
 		It("allows sending of ACKs when we're keeping track of MaxOutstandingSentPackets packets", func() {
 			cong.EXPECT().CanSend(gomock.Any()).Return(true).AnyTimes()
 			cong.EXPECT().TimeUntilSend(gomock.Any()).AnyTimes()
@@ -601,6 +683,55 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(4 * timeout))
 		})
 
+		It("caps the exponential backoff at the maximum PTO", func() {
+			handler.SetHandshakeComplete()
+			sendTime := time.Now().Add(-time.Hour)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: sendTime}))
+			timeout := handler.GetLossDetectionTimeout().Sub(sendTime)
+			handler.maxPTO = 3 * timeout
+			handler.ptoCount = 1
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(2 * timeout))
+			handler.ptoCount = 2
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(3 * timeout))
+			// make sure that the shift doesn't overflow
+			handler.ptoCount = 100
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(3 * timeout))
+		})
+
+		It("doesn't reduce the PTO below the value derived from the RTT", func() {
+			handler.SetHandshakeComplete()
+			sendTime := time.Now().Add(-time.Hour)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: sendTime}))
+			timeout := handler.GetLossDetectionTimeout().Sub(sendTime)
+			handler.maxPTO = timeout / 2
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(timeout))
+			handler.ptoCount = 3
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(timeout))
+		})
+
+		It("keeps probing at a bounded interval on a black-holed path", func() {
+			handler.SetHandshakeComplete()
+			handler.maxPTO = 500 * time.Millisecond
+			now := time.Now()
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now}))
+			var pn protocol.PacketNumber = 2
+			// none of the packets are ever acknowledged
+			for i := 0; i < 20; i++ {
+				timeout := handler.GetLossDetectionTimeout()
+				Expect(timeout.Sub(now)).To(BeNumerically("<=", handler.maxPTO))
+				now = timeout
+				Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: pn, SendTime: now}))
+				pn++
+			}
+			Expect(handler.ptoCount).To(BeEquivalentTo(20))
+		})
+
 		It("resets the PTO mode and PTO count when a packet number space is dropped", func() {
 			now := time.Now()
 			handler.SentPacket(ackElicitingPacket(&Packet{
@@ -795,6 +926,145 @@ var _ = Describe("SentPacketHandler", func() {
 			expectInPacketHistory([]protocol.PacketNumber{4, 5}, protocol.Encryption1RTT)
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
 		})
+
+		Context("with a higher packet reordering threshold", func() {
+			BeforeEach(func() { packetThreshold = 5 })
+
+			It("declares packet below the packet loss threshold as lost", func() {
+				for i := protocol.PacketNumber(1); i <= 6; i++ {
+					handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+				}
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				expectInPacketHistory([]protocol.PacketNumber{2, 3, 4, 5}, protocol.Encryption1RTT)
+				Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			})
+		})
+
+		It("counts spurious retransmissions only once", func() {
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			Expect(handler.NumSpuriousRetransmissions()).To(BeZero())
+			// packet 2 arrives late
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}, {Smallest: 2, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.NumSpuriousRetransmissions()).To(BeEquivalentTo(1))
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.NumSpuriousRetransmissions()).To(BeEquivalentTo(1))
+		})
+
+		Context("on a path that reorders packets", func() {
+			// simulates a path that delays every 5th packet by 4 packets,
+			// i.e. the packets arrive in the order 2, 3, 4, 5, 1, 7, 8, 9, 10, 6, ...
+			// It returns the number of (spuriously) lost packets.
+			runReorderingPath := func() int {
+				const numPackets = 100
+				now := time.Now()
+				for i := protocol.PacketNumber(1); i <= numPackets; i++ {
+					handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, SendTime: now}))
+				}
+				var order []protocol.PacketNumber
+				for i := protocol.PacketNumber(1); i <= numPackets; i += 5 {
+					order = append(order, i+1, i+2, i+3, i+4, i)
+				}
+				received := newReceivedPacketHistory(protocol.MaxNumAckRanges)
+				for _, pn := range order {
+					received.ReceivedPacket(pn)
+					ack := &wire.AckFrame{AckRanges: received.GetAckRanges()}
+					// ACKs arrive quickly, so that the time threshold doesn't trigger
+					Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(time.Millisecond))).To(Succeed())
+				}
+				return len(lostPackets)
+			}
+
+			It("spuriously declares reordered packets lost with the default threshold", func() {
+				Expect(runReorderingPath()).To(Equal(20))
+				Expect(handler.NumSpuriousRetransmissions()).To(BeEquivalentTo(20))
+			})
+
+			Context("with a higher packet reordering threshold", func() {
+				BeforeEach(func() { packetThreshold = 5 })
+
+				It("doesn't declare reordered packets lost", func() {
+					Expect(runReorderingPath()).To(BeZero())
+					Expect(handler.NumSpuriousRetransmissions()).To(BeZero())
+				})
+			})
+		})
+	})
+
+	Context("Early retransmit", func() {
+		// simulates a path that drops the first of every two packets sent.
+		// It returns the average time between receiving the ACK for the second packet and declaring the first one lost.
+		runLossyPath := func() time.Duration {
+			const numRounds = 10
+			const rtt = 100 * time.Millisecond
+			handler.handshakeComplete = true
+			now := time.Now()
+			var recoveryTime time.Duration
+			for i := 0; i < numRounds; i++ {
+				pn := protocol.PacketNumber(2*i + 1)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: pn, SendTime: now}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: pn + 1, SendTime: now}))
+				now = now.Add(rtt)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: pn + 1, Largest: pn + 1}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, now)).To(Succeed())
+				if len(lostPackets) <= i {
+					// the loss detection timer fires just after the loss time
+					lossTime := handler.GetLossDetectionTimeout().Add(time.Nanosecond)
+					Expect(lossTime).To(BeTemporally(">", now))
+					Expect(handler.detectLostPackets(lossTime, protocol.Encryption1RTT, handler.bytesInFlight)).To(Succeed())
+					recoveryTime += lossTime.Sub(now)
+					now = lossTime
+				}
+				Expect(lostPackets).To(HaveLen(i + 1))
+				Expect(lostPackets[i]).To(Equal(pn))
+			}
+			return recoveryTime / numRounds
+		}
+
+		It("waits for the time threshold when early retransmit is disabled", func() {
+			Expect(runLossyPath()).To(BeNumerically("~", 100*time.Millisecond/8, time.Millisecond))
+		})
+
+		Context("with early retransmit", func() {
+			BeforeEach(func() { earlyRetransmit = true })
+
+			It("declares packets lost as soon as all packets sent after them are acknowledged", func() {
+				Expect(runLossyPath()).To(BeZero())
+			})
+
+			It("doesn't declare packets lost while packets sent after the largest acknowledged are outstanding", func() {
+				for i := protocol.PacketNumber(1); i <= 3; i++ {
+					handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+				}
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(lostPackets).To(BeEmpty())
+				expectInPacketHistory([]protocol.PacketNumber{1, 3}, protocol.Encryption1RTT)
+			})
+
+			It("disables early retransmit once reordering is observed", func() {
+				for i := protocol.PacketNumber(1); i <= 3; i++ {
+					handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+				}
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				// packet 1 arrives after packet 2
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(handler.reorderingObserved).To(BeTrue())
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 4}))
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 4}, {Smallest: 1, Largest: 2}}}
+				Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+				Expect(lostPackets).To(BeEmpty())
+				expectInPacketHistory([]protocol.PacketNumber{3}, protocol.Encryption1RTT)
+			})
+		})
 	})
 
 	Context("Delay-based loss detection", func() {
@@ -963,6 +1233,34 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(pn).To(BeZero())
 			Expect(handler.PopPacketNumber(protocol.Encryption1RTT)).To(BeZero())
 		})
+
+		It("reports the packet numbers of a packet number space", func() {
+			Expect(handler.PacketNumberSpaceStats(protocol.Encryption1RTT)).To(Equal(PacketNumberSpaceStats{
+				NextPacketNumber: 0,
+				LargestSent:      protocol.InvalidPacketNumber,
+				LargestAcked:     protocol.InvalidPacketNumber,
+			}))
+			for i := 0; i < 3; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT)}))
+			}
+			stats := handler.PacketNumberSpaceStats(protocol.Encryption1RTT)
+			// packet numbers might have been skipped
+			Expect(stats.LargestSent).To(BeNumerically(">=", 2))
+			Expect(stats.NextPacketNumber).To(BeNumerically(">", stats.LargestSent))
+			Expect(stats.LargestAcked).To(Equal(protocol.InvalidPacketNumber))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 0}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.PacketNumberSpaceStats(protocol.Encryption1RTT).LargestAcked).To(BeZero())
+			// 0-RTT and 1-RTT packets share the packet number space
+			Expect(handler.PacketNumberSpaceStats(protocol.Encryption0RTT)).To(Equal(handler.PacketNumberSpaceStats(protocol.Encryption1RTT)))
+		})
+
+		It("reports dropped packet number spaces", func() {
+			Expect(handler.PacketNumberSpaceStats(protocol.EncryptionInitial).NextPacketNumber).To(Equal(protocol.PacketNumber(42)))
+			handler.DropPackets(protocol.EncryptionInitial)
+			Expect(handler.PacketNumberSpaceStats(protocol.EncryptionInitial)).To(Equal(PacketNumberSpaceStats{Dropped: true}))
+			Expect(handler.PacketNumberSpaceStats(protocol.EncryptionHandshake).Dropped).To(BeFalse())
+		})
 	})
 
 	Context("resetting for retry", func() {
@@ -54,6 +54,15 @@ func (h *sentPacketHistory) FirstOutstanding() *Packet {
 	return &h.packetList.Front().Value
 }
 
+// LastOutstanding returns the last outstanding packet.
+// It must not be modified.
+func (h *sentPacketHistory) LastOutstanding() *Packet {
+	if !h.HasOutstandingPackets() {
+		return nil
+	}
+	return &h.packetList.Back().Value
+}
+
 func (h *sentPacketHistory) Len() int {
 	return len(h.packetMap)
 }
@@ -56,6 +56,20 @@ var _ = Describe("SentPacketHistory", func() {
 		})
 	})
 
+	Context("getting the last outstanding packet", func() {
+		It("gets nil, if there are no packets", func() {
+			Expect(hist.LastOutstanding()).To(BeNil())
+		})
+
+		It("gets the last outstanding packet", func() {
+			hist.SentPacket(&Packet{PacketNumber: 2})
+			hist.SentPacket(&Packet{PacketNumber: 3})
+			back := hist.LastOutstanding()
+			Expect(back).ToNot(BeNil())
+			Expect(back.PacketNumber).To(Equal(protocol.PacketNumber(3)))
+		})
+	})
+
 	It("gets a packet by packet number", func() {
 		p := &Packet{PacketNumber: 2}
 		hist.SentPacket(p)
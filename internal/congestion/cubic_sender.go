@@ -66,17 +66,24 @@ type cubicSender struct {
 	initialMaxCongestionWindow protocol.ByteCount
 
 	minSlowStartExitWindow protocol.ByteCount
+
+	// The time the last retransmittable packet was sent.
+	// Used to detect idle periods.
+	lastSentTime time.Time
+	// Set when the congestion window was validated after an idle period,
+	// until the congestion window is fully utilized again.
+	inPostIdle bool
 }
 
 var _ SendAlgorithm = &cubicSender{}
 var _ SendAlgorithmWithDebugInfos = &cubicSender{}
 
 // NewCubicSender makes a new cubic sender
-func NewCubicSender(clock Clock, rttStats *RTTStats, reno bool) *cubicSender {
+func NewCubicSender(clock utils.Clock, rttStats *RTTStats, reno bool) *cubicSender {
 	return newCubicSender(clock, rttStats, reno, initialCongestionWindow, maxCongestionWindow)
 }
 
-func newCubicSender(clock Clock, rttStats *RTTStats, reno bool, initialCongestionWindow, initialMaxCongestionWindow protocol.ByteCount) *cubicSender {
+func newCubicSender(clock utils.Clock, rttStats *RTTStats, reno bool, initialCongestionWindow, initialMaxCongestionWindow protocol.ByteCount) *cubicSender {
 	return &cubicSender{
 		rttStats:                   rttStats,
 		largestSentPacketNumber:    protocol.InvalidPacketNumber,
@@ -115,6 +122,11 @@ func (c *cubicSender) OnPacketSent(
 	if !isRetransmittable {
 		return
 	}
+	// If nothing else is in flight, the connection might have been idle.
+	if bytesInFlight <= bytes && !c.lastSentTime.IsZero() {
+		c.maybeReduceCwndAfterIdle(sentTime.Sub(c.lastSentTime))
+	}
+	c.lastSentTime = sentTime
 	if c.InRecovery() {
 		// PRR is used when in recovery.
 		c.prr.OnPacketSent(bytes)
@@ -123,6 +135,29 @@ func (c *cubicSender) OnPacketSent(
 	c.hybridSlowStart.OnPacketSent(packetNumber)
 }
 
+// maybeReduceCwndAfterIdle validates the congestion window after an idle period (see RFC 2861).
+// The congestion window is halved for every PTO that the connection was idle,
+// but it is never reduced below the initial congestion window.
+func (c *cubicSender) maybeReduceCwndAfterIdle(idle time.Duration) {
+	pto := c.rttStats.PTO(true)
+	if idle < pto {
+		return
+	}
+	c.inPostIdle = true
+	restartWindow := utils.MinByteCount(c.initialCongestionWindow, c.congestionWindow)
+	if c.congestionWindow <= restartWindow {
+		return
+	}
+	// Remember the current congestion window, so that we can quickly get back to it in slow start.
+	c.slowstartThreshold = utils.MaxByteCount(c.slowstartThreshold, 3*c.congestionWindow/4)
+	for i := idle / pto; i > 0 && c.congestionWindow > restartWindow; i-- {
+		c.congestionWindow /= 2
+	}
+	c.congestionWindow = utils.MaxByteCount(c.congestionWindow, restartWindow)
+	c.hybridSlowStart.Restart()
+	c.cubic.OnApplicationLimited()
+}
+
 func (c *cubicSender) CanSend(bytesInFlight protocol.ByteCount) bool {
 	if !c.noPRR && c.InRecovery() {
 		return c.prr.CanSend(c.GetCongestionWindow(), bytesInFlight, c.GetSlowStartThreshold())
@@ -138,6 +173,12 @@ func (c *cubicSender) InSlowStart() bool {
 	return c.GetCongestionWindow() < c.GetSlowStartThreshold()
 }
 
+// InPostIdle says if the congestion window was reduced after an idle period,
+// and hasn't been fully utilized since then.
+func (c *cubicSender) InPostIdle() bool {
+	return c.inPostIdle
+}
+
 func (c *cubicSender) GetCongestionWindow() protocol.ByteCount {
 	return c.congestionWindow
 }
@@ -229,6 +270,18 @@ func (c *cubicSender) OnPacketLost(
 	c.numAckedPackets = 0
 }
 
+// SetReno switches between Reno and Cubic congestion avoidance.
+// The congestion window and the slow start threshold are kept,
+// and the Cubic epoch starts anew from the current congestion window.
+func (c *cubicSender) SetReno(reno bool) {
+	if c.reno == reno {
+		return
+	}
+	c.reno = reno
+	c.numAckedPackets = 0
+	c.cubic.Reset()
+}
+
 func (c *cubicSender) RenoBeta() float32 {
 	// kNConnectionBeta is the backoff factor after loss for our N-connection
 	// emulation, which emulates the effective backoff of an ensemble of N
@@ -251,6 +304,7 @@ func (c *cubicSender) maybeIncreaseCwnd(
 		c.cubic.OnApplicationLimited()
 		return
 	}
+	c.inPostIdle = false
 	if c.congestionWindow >= c.maxCongestionWindow {
 		return
 	}
@@ -327,6 +381,8 @@ func (c *cubicSender) OnConnectionMigration() {
 	c.lastCutbackExitedSlowstart = false
 	c.cubic.Reset()
 	c.numAckedPackets = 0
+	c.lastSentTime = time.Time{}
+	c.inPostIdle = false
 	c.congestionWindow = c.initialCongestionWindow
 	c.slowstartThreshold = c.initialMaxCongestionWindow
 	c.maxCongestionWindow = c.initialMaxCongestionWindow
@@ -22,6 +22,10 @@ func (c *mockClock) Advance(d time.Duration) {
 	*c = mockClock(time.Time(*c).Add(d))
 }
 
+func (c *mockClock) NewTimer(time.Duration) utils.ClockTimer {
+	panic("not needed by the congestion controller")
+}
+
 const MaxCongestionWindow protocol.ByteCount = 200 * maxDatagramSize
 
 var _ = Describe("Cubic Sender", func() {
@@ -143,6 +147,68 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.BandwidthEstimate()).To(Equal(BandwidthFromDelta(cwnd, rttStats.SmoothedRTT())))
 	})
 
+	Context("congestion window validation after idle", func() {
+		// growCwnd grows the congestion window in slow start, and then acknowledges all outstanding packets
+		growCwnd := func() {
+			for i := 0; i < 20; i++ {
+				SendAvailableSendWindow()
+				AckNPackets(2)
+			}
+			AckNPackets(int(bytesInFlight / maxDatagramSize))
+			Expect(bytesInFlight).To(BeZero())
+			Expect(sender.GetCongestionWindow()).To(BeNumerically(">", 4*defaultWindowTCP))
+		}
+
+		It("doesn't reduce the congestion window if the connection wasn't idle for a PTO", func() {
+			growCwnd()
+			cwnd := sender.GetCongestionWindow()
+			clock.Advance(rttStats.PTO(true) / 2)
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+			Expect(sender.InPostIdle()).To(BeFalse())
+		})
+
+		It("halves the congestion window when returning from idle, and re-enters slow start", func() {
+			growCwnd()
+			sender.ExitSlowstart()
+			Expect(sender.InSlowStart()).To(BeFalse())
+			cwnd := sender.GetCongestionWindow()
+			clock.Advance(rttStats.PTO(true))
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd / 2))
+			Expect(sender.SlowstartThreshold()).To(Equal(cwnd))
+			Expect(sender.InSlowStart()).To(BeTrue())
+			Expect(sender.InPostIdle()).To(BeTrue())
+		})
+
+		It("doesn't reduce the congestion window below the initial window", func() {
+			growCwnd()
+			clock.Advance(10 * rttStats.PTO(true))
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+			Expect(sender.InPostIdle()).To(BeTrue())
+		})
+
+		It("doesn't reduce the congestion window if there are packets in flight", func() {
+			growCwnd()
+			cwnd := sender.GetCongestionWindow()
+			SendAvailableSendWindow()
+			clock.Advance(10 * rttStats.PTO(true))
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+			Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+			Expect(sender.InPostIdle()).To(BeFalse())
+		})
+
+		It("leaves the post-idle state once the congestion window is utilized", func() {
+			growCwnd()
+			clock.Advance(rttStats.PTO(true))
+			SendAvailableSendWindow()
+			Expect(sender.InPostIdle()).To(BeTrue())
+			AckNPackets(2)
+			Expect(sender.InPostIdle()).To(BeFalse())
+		})
+	})
+
 	It("slow start packet loss", func() {
 		sender.SetNumEmulatedConnections(1)
 		const numberOfAcks = 10
@@ -599,6 +665,45 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.HybridSlowStart().Started()).To(BeFalse())
 	})
 
+	It("switches between Reno and Cubic, keeping the congestion window", func() {
+		sender.SetNumEmulatedConnections(1)
+		// Leave slow start.
+		for i := 0; i < 5; i++ {
+			SendAvailableSendWindow()
+			AckNPackets(2)
+		}
+		SendAvailableSendWindow()
+		LoseNPackets(1)
+		for sender.InRecovery() {
+			SendAvailableSendWindow()
+			AckNPackets(2)
+		}
+		Expect(sender.InSlowStart()).To(BeFalse())
+		cwnd := sender.GetCongestionWindow()
+		ssthresh := sender.SlowstartThreshold()
+
+		sender.SetReno(false)
+		Expect(sender.reno).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		Expect(sender.SlowstartThreshold()).To(Equal(ssthresh))
+		// Cubic continues congestion avoidance from the current congestion window.
+		for i := 0; i < 100; i++ {
+			SendAvailableSendWindow()
+			AckNPackets(2)
+			clock.Advance(10 * time.Millisecond)
+			Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", cwnd))
+		}
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">", cwnd))
+		Expect(sender.InSlowStart()).To(BeFalse())
+
+		cwnd = sender.GetCongestionWindow()
+		sender.SetReno(true)
+		Expect(sender.reno).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		LoseNPackets(1)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(float32(cwnd) * renoBeta)))
+	})
+
 	It("default max cwnd", func() {
 		sender = newCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindow)
 
@@ -22,5 +22,9 @@ type SendAlgorithmWithDebugInfos interface {
 	SendAlgorithm
 	InSlowStart() bool
 	InRecovery() bool
+	InPostIdle() bool
 	GetCongestionWindow() protocol.ByteCount
+	// SetReno switches between Reno and Cubic congestion avoidance.
+	// The congestion window and the slow start threshold are kept.
+	SetReno(bool)
 }
@@ -1,6 +1,7 @@
 package flowcontrol
 
 import (
+	"math"
 	"sync"
 	"time"
 
@@ -14,6 +15,9 @@ type baseFlowController struct {
 	bytesSent     protocol.ByteCount
 	sendWindow    protocol.ByteCount
 	lastBlockedAt protocol.ByteCount
+	// wasBlocked is set once we were blocked for the first time.
+	// It is needed to detect when we're blocked at offset 0.
+	wasBlocked bool
 
 	// for receiving data
 	mutex                sync.RWMutex
@@ -22,10 +26,16 @@ type baseFlowController struct {
 	receiveWindow        protocol.ByteCount
 	receiveWindowSize    protocol.ByteCount
 	maxReceiveWindowSize protocol.ByteCount
+	windowGrowthFactor   int
+	// bdpAutoTuning enables sizing the receive window based on the bandwidth-delay product
+	bdpAutoTuning bool
+	// windowUpdateThreshold is the fraction of the receive window that has to be consumed before a window update is sent
+	windowUpdateThreshold float64
 
 	epochStartTime   time.Time
 	epochStartOffset protocol.ByteCount
 	rttStats         *congestion.RTTStats
+	clock            utils.Clock
 
 	logger utils.Logger
 }
@@ -34,9 +44,10 @@ type baseFlowController struct {
 // For every offset, it only returns true once.
 // If it is blocked, the offset is returned.
 func (c *baseFlowController) IsNewlyBlocked() (bool, protocol.ByteCount) {
-	if c.sendWindowSize() != 0 || c.sendWindow == c.lastBlockedAt {
+	if c.sendWindowSize() != 0 || (c.wasBlocked && c.sendWindow == c.lastBlockedAt) {
 		return false, 0
 	}
+	c.wasBlocked = true
 	c.lastBlockedAt = c.sendWindow
 	return true, c.sendWindow
 }
@@ -61,6 +72,20 @@ func (c *baseFlowController) sendWindowSize() protocol.ByteCount {
 	return c.sendWindow - c.bytesSent
 }
 
+// SendOffsets returns the number of bytes sent and the send window.
+// For stream flow controllers, the caller has to synchronize with the send side.
+func (c *baseFlowController) SendOffsets() (protocol.ByteCount, protocol.ByteCount) {
+	return c.bytesSent, c.sendWindow
+}
+
+// ReceiveOffsets returns the number of bytes read, the highest offset received and the receive window.
+func (c *baseFlowController) ReceiveOffsets() (protocol.ByteCount, protocol.ByteCount, protocol.ByteCount) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.bytesRead, c.highestReceived, c.receiveWindow
+}
+
 func (c *baseFlowController) AddBytesRead(n protocol.ByteCount) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -76,7 +101,7 @@ func (c *baseFlowController) AddBytesRead(n protocol.ByteCount) {
 func (c *baseFlowController) hasWindowUpdate() bool {
 	bytesRemaining := c.receiveWindow - c.bytesRead
 	// update the window when more than the threshold was consumed
-	return bytesRemaining <= protocol.ByteCount((float64(c.receiveWindowSize) * float64((1 - protocol.WindowUpdateThreshold))))
+	return bytesRemaining <= protocol.ByteCount((float64(c.receiveWindowSize) * (1 - c.windowUpdateThreshold)))
 }
 
 // getWindowUpdate updates the receive window, if necessary
@@ -85,7 +110,11 @@ func (c *baseFlowController) getWindowUpdate() protocol.ByteCount {
 	if !c.hasWindowUpdate() {
 		return 0
 	}
+	return c.updateWindow()
+}
 
+// updateWindow updates the receive window, and returns the new offset
+func (c *baseFlowController) updateWindow() protocol.ByteCount {
 	c.maybeAdjustWindowSize()
 	c.receiveWindow = c.bytesRead + c.receiveWindowSize
 	return c.receiveWindow
@@ -105,15 +134,33 @@ func (c *baseFlowController) maybeAdjustWindowSize() {
 	}
 
 	fraction := float64(bytesReadInEpoch) / float64(c.receiveWindowSize)
-	if time.Since(c.epochStartTime) < time.Duration(4*fraction*float64(rtt)) {
+	if c.clock.Now().Sub(c.epochStartTime) < time.Duration(4*fraction*float64(rtt)) {
 		// window is consumed too fast, try to increase the window size
-		c.receiveWindowSize = utils.MinByteCount(2*c.receiveWindowSize, c.maxReceiveWindowSize)
+		c.receiveWindowSize = utils.MinByteCount(protocol.ByteCount(c.windowGrowthFactor)*c.receiveWindowSize, c.maxReceiveWindowSize)
+	}
+	if c.bdpAutoTuning {
+		c.maybeAdjustWindowSizeToBDP(bytesReadInEpoch, rtt)
 	}
 	c.startNewAutoTuningEpoch()
 }
 
+// maybeAdjustWindowSizeToBDP increases the receiveWindowSize to a multiple of the bandwidth-delay product.
+// The bandwidth is estimated from the rate at which data was consumed during the current epoch.
+// This allows the window to grow by more than the growth factor at once on long-fat networks.
+func (c *baseFlowController) maybeAdjustWindowSizeToBDP(bytesReadInEpoch protocol.ByteCount, rtt time.Duration) {
+	epochDuration := c.clock.Now().Sub(c.epochStartTime)
+	if epochDuration <= 0 {
+		return
+	}
+	bdp := float64(bytesReadInEpoch) * float64(rtt) / float64(epochDuration)
+	target := math.Min(protocol.BDPWindowMultiplier*bdp, float64(c.maxReceiveWindowSize))
+	if protocol.ByteCount(target) > c.receiveWindowSize {
+		c.receiveWindowSize = protocol.ByteCount(target)
+	}
+}
+
 func (c *baseFlowController) startNewAutoTuningEpoch() {
-	c.epochStartTime = time.Now()
+	c.epochStartTime = c.clock.Now()
 	c.epochStartOffset = c.bytesRead
 }
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -28,6 +29,9 @@ var _ = Describe("Base Flow controller", func() {
 	BeforeEach(func() {
 		controller = &baseFlowController{}
 		controller.rttStats = &congestion.RTTStats{}
+		controller.clock = utils.DefaultClock{}
+		controller.windowGrowthFactor = protocol.DefaultReceiveWindowGrowthFactor
+		controller.windowUpdateThreshold = protocol.WindowUpdateThreshold
 	})
 
 	Context("send flow control", func() {
@@ -72,6 +76,16 @@ var _ = Describe("Base Flow controller", func() {
 			Expect(offset).To(Equal(protocol.ByteCount(100)))
 		})
 
+		It("says when it's blocked at offset 0", func() {
+			newlyBlocked, offset := controller.IsNewlyBlocked()
+			Expect(newlyBlocked).To(BeTrue())
+			Expect(offset).To(BeZero())
+			newlyBlocked, _ = controller.IsNewlyBlocked()
+			Expect(newlyBlocked).To(BeFalse())
+			controller.UpdateSendWindow(100)
+			Expect(controller.IsNewlyBlocked()).To(BeFalse())
+		})
+
 		It("doesn't say that it's newly blocked multiple times for the same offset", func() {
 			controller.UpdateSendWindow(100)
 			controller.AddBytesSent(100)
@@ -85,6 +99,18 @@ var _ = Describe("Base Flow controller", func() {
 			newlyBlocked, _ = controller.IsNewlyBlocked()
 			Expect(newlyBlocked).To(BeTrue())
 		})
+
+		It("reports the send offsets", func() {
+			controller.UpdateSendWindow(100)
+			bytesSent, sendWindow := controller.SendOffsets()
+			Expect(bytesSent).To(BeZero())
+			Expect(sendWindow).To(Equal(protocol.ByteCount(100)))
+			controller.AddBytesSent(42)
+			controller.UpdateSendWindow(200)
+			bytesSent, sendWindow = controller.SendOffsets()
+			Expect(bytesSent).To(Equal(protocol.ByteCount(42)))
+			Expect(sendWindow).To(Equal(protocol.ByteCount(200)))
+		})
 	})
 
 	Context("receive flow control", func() {
@@ -124,6 +150,20 @@ var _ = Describe("Base Flow controller", func() {
 			Expect(offset).To(BeZero())
 		})
 
+		It("reports the receive offsets", func() {
+			controller.highestReceived = receiveWindow - 100
+			bytesRead, highestReceived, window := controller.ReceiveOffsets()
+			Expect(bytesRead).To(Equal(receiveWindow - receiveWindowSize))
+			Expect(highestReceived).To(Equal(receiveWindow - 100))
+			Expect(window).To(Equal(receiveWindow))
+			controller.AddBytesRead(receiveWindowSize - 100)
+			offset := controller.getWindowUpdate()
+			Expect(offset).ToNot(BeZero())
+			bytesRead, _, window = controller.ReceiveOffsets()
+			Expect(bytesRead).To(Equal(receiveWindow - 100))
+			Expect(window).To(Equal(offset))
+		})
+
 		Context("receive window size auto-tuning", func() {
 			var oldWindowSize protocol.ByteCount
 
@@ -231,6 +271,113 @@ var _ = Describe("Base Flow controller", func() {
 				controller.maybeAdjustWindowSize()
 				Expect(controller.receiveWindowSize).To(Equal(controller.maxReceiveWindowSize)) // 5000
 			})
+
+			It("grows the window faster with a larger growth factor", func() {
+				// run the same sequence of fast reads with two different growth factors
+				windowSizes := func(growthFactor int) []protocol.ByteCount {
+					fc := &baseFlowController{
+						bytesRead:            receiveWindow - receiveWindowSize,
+						receiveWindow:        receiveWindow,
+						receiveWindowSize:    receiveWindowSize,
+						maxReceiveWindowSize: 50000,
+						windowGrowthFactor:   growthFactor,
+						rttStats:             &congestion.RTTStats{},
+						clock:                utils.DefaultClock{},
+					}
+					fc.rttStats.UpdateRTT(scaleDuration(20*time.Millisecond), 0, time.Now())
+					var sizes []protocol.ByteCount
+					for i := 0; i < 3; i++ {
+						fc.epochStartTime = time.Now().Add(-time.Millisecond)
+						fc.epochStartOffset = fc.bytesRead
+						fc.AddBytesRead(fc.receiveWindowSize/2 + 1)
+						fc.maybeAdjustWindowSize()
+						sizes = append(sizes, fc.receiveWindowSize)
+					}
+					return sizes
+				}
+				Expect(windowSizes(2)).To(Equal([]protocol.ByteCount{2000, 4000, 8000}))
+				Expect(windowSizes(4)).To(Equal([]protocol.ByteCount{4000, 16000, 50000}))
+			})
+
+			Context("BDP-based auto-tuning", func() {
+				BeforeEach(func() {
+					controller.bdpAutoTuning = true
+				})
+
+				It("increases the window size to twice the BDP", func() {
+					controller.maxReceiveWindowSize = 50000
+					rtt := scaleDuration(100 * time.Millisecond)
+					setRtt(rtt)
+					// read more than half the window in a quarter of the RTT,
+					// i.e. the BDP is more than 4 times the window size
+					dataRead := controller.receiveWindowSize/2 + 1
+					controller.epochStartTime = time.Now().Add(-rtt / 4)
+					controller.epochStartOffset = controller.bytesRead
+					controller.AddBytesRead(dataRead)
+					controller.maybeAdjustWindowSize()
+					// the growth factor alone would only have doubled the window size
+					Expect(controller.receiveWindowSize).To(BeNumerically(">", 3*oldWindowSize))
+					Expect(controller.receiveWindowSize).To(BeNumerically("<=", 2*4*dataRead))
+				})
+
+				It("doesn't increase the window size to a value higher than the maxReceiveWindowSize", func() {
+					setRtt(scaleDuration(20 * time.Millisecond))
+					controller.epochStartTime = time.Now().Add(-time.Millisecond)
+					controller.epochStartOffset = controller.bytesRead
+					controller.AddBytesRead(controller.receiveWindowSize/2 + 1)
+					controller.maybeAdjustWindowSize()
+					Expect(controller.receiveWindowSize).To(Equal(controller.maxReceiveWindowSize))
+				})
+
+				It("grows the window toward the BDP on a high-BDP link", func() {
+					const (
+						bandwidth = 100 << 20 // bytes per second
+						rtt       = 100 * time.Millisecond
+						bdp       = protocol.ByteCount(bandwidth * rtt / time.Second)
+					)
+					// countRounds simulates a peer that sends as much as the window allows in every round trip,
+					// limited by the bandwidth of the link. The data arrives at the link rate, and is read right away.
+					// It returns the number of round trips it takes until the window is at least the BDP.
+					countRounds := func(bdpAutoTuning bool) int {
+						fc := &baseFlowController{
+							receiveWindow:         receiveWindowSize,
+							receiveWindowSize:     receiveWindowSize,
+							maxReceiveWindowSize:  100 << 20,
+							windowGrowthFactor:    protocol.DefaultReceiveWindowGrowthFactor,
+							windowUpdateThreshold: protocol.WindowUpdateThreshold,
+							bdpAutoTuning:         bdpAutoTuning,
+							rttStats:              &congestion.RTTStats{},
+							clock:                 utils.DefaultClock{},
+						}
+						fc.rttStats.UpdateRTT(rtt, 0, time.Now())
+						fc.startNewAutoTuningEpoch()
+						for round := 1; round <= 100; round++ {
+							n := utils.MinByteCount(fc.receiveWindow-fc.bytesRead, bdp)
+							// pretend that receiving the data took as long as transmitting it on the link
+							fc.epochStartTime = time.Now().Add(-time.Duration(n) * time.Second / bandwidth)
+							fc.AddBytesRead(n)
+							Expect(fc.getWindowUpdate()).ToNot(BeZero())
+							if fc.receiveWindowSize >= bdp {
+								return round
+							}
+						}
+						Fail("window never reached the BDP")
+						return 0
+					}
+					Expect(countRounds(true)).To(Equal(1))
+					Expect(countRounds(false)).To(BeNumerically(">", 5))
+				})
+			})
+
+			It("doesn't increase the window size if the growth factor is 1", func() {
+				controller.windowGrowthFactor = 1
+				setRtt(scaleDuration(20 * time.Millisecond))
+				controller.epochStartTime = time.Now().Add(-time.Millisecond)
+				controller.epochStartOffset = controller.bytesRead
+				controller.AddBytesRead(controller.receiveWindowSize/2 + 1)
+				controller.maybeAdjustWindowSize()
+				Expect(controller.receiveWindowSize).To(Equal(oldWindowSize))
+			})
 		})
 	})
 })
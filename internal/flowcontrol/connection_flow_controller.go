@@ -13,6 +13,11 @@ type connectionFlowController struct {
 	baseFlowController
 
 	queueWindowUpdate func()
+
+	// The part of the send window reserved for streams.
+	// Reservations are made from the application's goroutine, so all access to
+	// send-side state is protected by the mutex.
+	reserved protocol.ByteCount
 }
 
 var _ ConnectionFlowController = &connectionFlowController{}
@@ -22,24 +27,85 @@ var _ ConnectionFlowController = &connectionFlowController{}
 func NewConnectionFlowController(
 	receiveWindow protocol.ByteCount,
 	maxReceiveWindow protocol.ByteCount,
+	windowGrowthFactor int,
+	bdpAutoTuning bool,
 	queueWindowUpdate func(),
 	rttStats *congestion.RTTStats,
+	clock utils.Clock,
 	logger utils.Logger,
 ) ConnectionFlowController {
 	return &connectionFlowController{
 		baseFlowController: baseFlowController{
-			rttStats:             rttStats,
-			receiveWindow:        receiveWindow,
-			receiveWindowSize:    receiveWindow,
-			maxReceiveWindowSize: maxReceiveWindow,
-			logger:               logger,
+			rttStats:              rttStats,
+			clock:                 clock,
+			receiveWindow:         receiveWindow,
+			receiveWindowSize:     receiveWindow,
+			maxReceiveWindowSize:  maxReceiveWindow,
+			windowGrowthFactor:    windowGrowthFactor,
+			bdpAutoTuning:         bdpAutoTuning,
+			windowUpdateThreshold: protocol.WindowUpdateThreshold,
+			logger:                logger,
 		},
 		queueWindowUpdate: queueWindowUpdate,
 	}
 }
 
 func (c *connectionFlowController) SendWindowSize() protocol.ByteCount {
-	return c.baseFlowController.sendWindowSize()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.unreservedSendWindowSize()
+}
+
+func (c *connectionFlowController) unreservedSendWindowSize() protocol.ByteCount {
+	size := c.baseFlowController.sendWindowSize()
+	if size < c.reserved {
+		return 0
+	}
+	return size - c.reserved
+}
+
+func (c *connectionFlowController) AddBytesSent(n protocol.ByteCount) {
+	c.mutex.Lock()
+	c.baseFlowController.AddBytesSent(n)
+	c.mutex.Unlock()
+}
+
+func (c *connectionFlowController) UpdateSendWindow(offset protocol.ByteCount) {
+	c.mutex.Lock()
+	c.baseFlowController.UpdateSendWindow(offset)
+	c.mutex.Unlock()
+}
+
+func (c *connectionFlowController) SendOffsets() (protocol.ByteCount, protocol.ByteCount) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.baseFlowController.SendOffsets()
+}
+
+func (c *connectionFlowController) IsNewlyBlocked() (bool, protocol.ByteCount) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.baseFlowController.IsNewlyBlocked()
+}
+
+func (c *connectionFlowController) Reserve(n protocol.ByteCount) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.unreservedSendWindowSize() < n {
+		return false
+	}
+	c.reserved += n
+	return true
+}
+
+func (c *connectionFlowController) Release(n protocol.ByteCount) {
+	c.mutex.Lock()
+	c.reserved -= utils.MinByteCount(n, c.reserved)
+	c.mutex.Unlock()
 }
 
 // IncrementHighestReceived adds an increment to the highestReceived value
@@ -79,6 +145,25 @@ func (c *connectionFlowController) GetWindowUpdate() protocol.ByteCount {
 	return offset
 }
 
+func (c *connectionFlowController) RaiseReceiveWindow(offset protocol.ByteCount) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if offset <= c.receiveWindow {
+		return
+	}
+	c.logger.Debugf("Raising receive flow control window for the connection to %d kB", offset/(1<<10))
+	c.receiveWindow = offset
+	if offset > c.receiveWindowSize {
+		c.receiveWindowSize = utils.MinByteCount(offset, c.maxReceiveWindowSize)
+	}
+}
+
+// MaxReceiveWindowSize returns the maximum size of the connection-level receive window
+func (c *connectionFlowController) MaxReceiveWindowSize() protocol.ByteCount {
+	return c.maxReceiveWindowSize
+}
+
 // EnsureMinimumWindowSize sets a minimum window size
 // it should make sure that the connection-level window is increased when a stream-level window grows
 func (c *connectionFlowController) EnsureMinimumWindowSize(inc protocol.ByteCount) {
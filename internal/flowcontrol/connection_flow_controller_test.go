@@ -26,6 +26,9 @@ var _ = Describe("Connection Flow controller", func() {
 		queuedWindowUpdate = false
 		controller = &connectionFlowController{}
 		controller.rttStats = &congestion.RTTStats{}
+		controller.clock = utils.DefaultClock{}
+		controller.windowGrowthFactor = protocol.DefaultReceiveWindowGrowthFactor
+		controller.windowUpdateThreshold = protocol.WindowUpdateThreshold
 		controller.logger = utils.DefaultLogger
 		controller.queueWindowUpdate = func() { queuedWindowUpdate = true }
 	})
@@ -37,7 +40,7 @@ var _ = Describe("Connection Flow controller", func() {
 			receiveWindow := protocol.ByteCount(2000)
 			maxReceiveWindow := protocol.ByteCount(3000)
 
-			fc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, nil, rttStats, utils.DefaultLogger).(*connectionFlowController)
+			fc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, protocol.DefaultReceiveWindowGrowthFactor, false, nil, rttStats, utils.DefaultClock{}, utils.DefaultLogger).(*connectionFlowController)
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowSize).To(Equal(maxReceiveWindow))
 		})
@@ -130,4 +133,61 @@ var _ = Describe("Connection Flow controller", func() {
 			Expect(controller.epochStartTime).To(BeTemporally("~", time.Now(), 100*time.Millisecond))
 		})
 	})
+
+	Context("raising the receive window", func() {
+		BeforeEach(func() {
+			controller.receiveWindow = 1000
+			controller.receiveWindowSize = 1000
+			controller.maxReceiveWindowSize = 3000
+		})
+
+		It("raises the receive window", func() {
+			controller.RaiseReceiveWindow(2000)
+			_, _, receiveWindow := controller.ReceiveOffsets()
+			Expect(receiveWindow).To(Equal(protocol.ByteCount(2000)))
+			Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(2000)))
+			Expect(controller.IncrementHighestReceived(1500)).To(Succeed())
+		})
+
+		It("doesn't decrease the receive window", func() {
+			controller.RaiseReceiveWindow(500)
+			_, _, receiveWindow := controller.ReceiveOffsets()
+			Expect(receiveWindow).To(Equal(protocol.ByteCount(1000)))
+			Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(1000)))
+		})
+
+		It("doesn't increase the window size beyond the maxReceiveWindowSize", func() {
+			controller.RaiseReceiveWindow(5000)
+			_, _, receiveWindow := controller.ReceiveOffsets()
+			Expect(receiveWindow).To(Equal(protocol.ByteCount(5000)))
+			Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(3000)))
+		})
+	})
+
+	Context("reserving send window", func() {
+		BeforeEach(func() {
+			controller.UpdateSendWindow(100)
+		})
+
+		It("excludes reserved bytes from the send window", func() {
+			Expect(controller.Reserve(60)).To(BeTrue())
+			Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(40)))
+			controller.Release(60)
+			Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(100)))
+		})
+
+		It("doesn't reserve more than the send window", func() {
+			Expect(controller.Reserve(60)).To(BeTrue())
+			Expect(controller.Reserve(41)).To(BeFalse())
+			Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(40)))
+			Expect(controller.Reserve(40)).To(BeTrue())
+			Expect(controller.SendWindowSize()).To(BeZero())
+		})
+
+		It("doesn't release more than was reserved", func() {
+			Expect(controller.Reserve(10)).To(BeTrue())
+			controller.Release(20)
+			Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(100)))
+		})
+	})
 })
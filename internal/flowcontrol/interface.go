@@ -11,6 +11,13 @@ type flowController interface {
 	AddBytesRead(protocol.ByteCount)
 	GetWindowUpdate() protocol.ByteCount // returns 0 if no update is necessary
 	IsNewlyBlocked() (bool, protocol.ByteCount)
+	// for debugging
+	// SendOffsets returns the number of bytes sent, and the send window,
+	// i.e. the highest offset the peer allows us to send.
+	SendOffsets() (bytesSent, sendWindow protocol.ByteCount)
+	// ReceiveOffsets returns the number of bytes read, the highest offset received,
+	// and the receive window, i.e. the highest offset we allow the peer to send.
+	ReceiveOffsets() (bytesRead, highestReceived, receiveWindow protocol.ByteCount)
 }
 
 // A StreamFlowController is a flow controller for a QUIC stream.
@@ -24,11 +31,23 @@ type StreamFlowController interface {
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
+	// SetReceiveWindowSize sets the size of the receive window, disabling auto-tuning for this stream.
+	// The size is limited by the maximum receive window size of the connection.
+	SetReceiveWindowSize(protocol.ByteCount)
+	// for sending
+	// ReserveSendWindow reserves n bytes of the connection-level send window for this stream.
+	// It returns false (and doesn't reserve anything) if the stream- or connection-level send window is smaller than n.
+	ReserveSendWindow(n protocol.ByteCount) bool
+	// ReleaseSendWindow releases the part of the reservation that was not used for sending data.
+	ReleaseSendWindow()
 }
 
 // The ConnectionFlowController is the flow controller for the connection.
 type ConnectionFlowController interface {
 	flowController
+	// RaiseReceiveWindow raises the receive window to the given offset, if it is larger than the current receive window.
+	// It is used when accepting 0-RTT, since the client may use up the window saved in the session ticket.
+	RaiseReceiveWindow(protocol.ByteCount)
 }
 
 type connectionFlowControllerI interface {
@@ -36,6 +55,12 @@ type connectionFlowControllerI interface {
 	// The following two methods are not supposed to be called from outside this packet, but are needed internally
 	// for sending
 	EnsureMinimumWindowSize(protocol.ByteCount)
+	MaxReceiveWindowSize() protocol.ByteCount
+	// Reserve reserves n bytes of the send window.
+	// Reserved bytes are not included in the SendWindowSize.
+	Reserve(n protocol.ByteCount) bool
+	// Release releases n bytes of the reservation, e.g. because they were sent.
+	Release(n protocol.ByteCount)
 	// for receiving
 	IncrementHighestReceived(protocol.ByteCount) error
 }
@@ -19,6 +19,8 @@ type streamFlowController struct {
 	connection connectionFlowControllerI
 
 	receivedFinalOffset bool
+
+	reservedSendWindow protocol.ByteCount // the part of the connection-level send window reserved for this stream
 }
 
 var _ StreamFlowController = &streamFlowController{}
@@ -29,9 +31,13 @@ func NewStreamFlowController(
 	cfc ConnectionFlowController,
 	receiveWindow protocol.ByteCount,
 	maxReceiveWindow protocol.ByteCount,
+	windowGrowthFactor int,
+	bdpAutoTuning bool,
+	windowUpdateThreshold float64,
 	initialSendWindow protocol.ByteCount,
 	queueWindowUpdate func(protocol.StreamID),
 	rttStats *congestion.RTTStats,
+	clock utils.Clock,
 	logger utils.Logger,
 ) StreamFlowController {
 	return &streamFlowController{
@@ -39,12 +45,16 @@ func NewStreamFlowController(
 		connection:        cfc.(connectionFlowControllerI),
 		queueWindowUpdate: func() { queueWindowUpdate(streamID) },
 		baseFlowController: baseFlowController{
-			rttStats:             rttStats,
-			receiveWindow:        receiveWindow,
-			receiveWindowSize:    receiveWindow,
-			maxReceiveWindowSize: maxReceiveWindow,
-			sendWindow:           initialSendWindow,
-			logger:               logger,
+			rttStats:              rttStats,
+			clock:                 clock,
+			receiveWindow:         receiveWindow,
+			receiveWindowSize:     receiveWindow,
+			maxReceiveWindowSize:  maxReceiveWindow,
+			windowGrowthFactor:    windowGrowthFactor,
+			bdpAutoTuning:         bdpAutoTuning,
+			windowUpdateThreshold: windowUpdateThreshold,
+			sendWindow:            initialSendWindow,
+			logger:                logger,
 		},
 	}
 }
@@ -101,35 +111,87 @@ func (c *streamFlowController) Abandon() {
 	}
 }
 
+func (c *streamFlowController) SetReceiveWindowSize(size protocol.ByteCount) {
+	size = utils.MinByteCount(size, c.connection.MaxReceiveWindowSize())
+	c.mutex.Lock()
+	c.receiveWindowSize = size
+	// prevent auto-tuning from changing the window size
+	c.maxReceiveWindowSize = size
+	hasWindowUpdate := c.hasWindowUpdate()
+	c.mutex.Unlock()
+	c.logger.Debugf("Setting receive flow control window for stream %d to %d kB", c.streamID, size/(1<<10))
+	c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(size) * protocol.ConnectionFlowControlMultiplier))
+	if hasWindowUpdate {
+		c.queueWindowUpdate()
+	}
+}
+
 func (c *streamFlowController) AddBytesSent(n protocol.ByteCount) {
 	c.baseFlowController.AddBytesSent(n)
 	c.connection.AddBytesSent(n)
+	// Only release the reservation after adding the bytes sent,
+	// otherwise these bytes could be reserved by another stream in the meantime.
+	if c.reservedSendWindow > 0 {
+		used := utils.MinByteCount(n, c.reservedSendWindow)
+		c.reservedSendWindow -= used
+		c.connection.Release(used)
+	}
 }
 
 func (c *streamFlowController) SendWindowSize() protocol.ByteCount {
-	return utils.MinByteCount(c.baseFlowController.sendWindowSize(), c.connection.SendWindowSize())
+	return utils.MinByteCount(c.baseFlowController.sendWindowSize(), c.connection.SendWindowSize()+c.reservedSendWindow)
+}
+
+func (c *streamFlowController) ReserveSendWindow(n protocol.ByteCount) bool {
+	if c.baseFlowController.sendWindowSize() < c.reservedSendWindow+n || !c.connection.Reserve(n) {
+		return false
+	}
+	c.reservedSendWindow += n
+	return true
+}
+
+func (c *streamFlowController) ReleaseSendWindow() {
+	if c.reservedSendWindow == 0 {
+		return
+	}
+	c.connection.Release(c.reservedSendWindow)
+	c.reservedSendWindow = 0
 }
 
 func (c *streamFlowController) maybeQueueWindowUpdate() {
 	c.mutex.Lock()
-	hasWindowUpdate := !c.receivedFinalOffset && c.hasWindowUpdate()
+	hasWindowUpdate := c.hasWindowUpdate()
 	c.mutex.Unlock()
 	if hasWindowUpdate {
 		c.queueWindowUpdate()
 	}
 }
 
+// hasWindowUpdate says if the receive window should be updated.
+// It must be called with the mutex held.
+func (c *streamFlowController) hasWindowUpdate() bool {
+	// if we already received the final offset for this stream, the peer won't need any additional flow control credit
+	if c.receivedFinalOffset {
+		return false
+	}
+	if c.baseFlowController.hasWindowUpdate() {
+		return true
+	}
+	// If the peer used up the whole window, it is blocked.
+	// Update the window as soon as it can be increased by at least a full packet, so that the peer can continue sending.
+	return c.highestReceived >= c.receiveWindow && c.bytesRead+c.receiveWindowSize >= c.receiveWindow+protocol.MaxReceivePacketSize
+}
+
 func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
 	// don't use defer for unlocking the mutex here, GetWindowUpdate() is called frequently and defer shows up in the profiler
 	c.mutex.Lock()
-	// if we already received the final offset for this stream, the peer won't need any additional flow control credit
-	if c.receivedFinalOffset {
+	if !c.hasWindowUpdate() {
 		c.mutex.Unlock()
 		return 0
 	}
 
 	oldWindowSize := c.receiveWindowSize
-	offset := c.baseFlowController.getWindowUpdate()
+	offset := c.updateWindow()
 	if c.receiveWindowSize > oldWindowSize { // auto-tuning enlarged the window size
 		c.logger.Debugf("Increasing receive flow control window for stream %d to %d kB", c.streamID, c.receiveWindowSize/(1<<10))
 		c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(c.receiveWindowSize) * protocol.ConnectionFlowControlMultiplier))
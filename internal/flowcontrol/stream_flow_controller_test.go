@@ -21,10 +21,13 @@ var _ = Describe("Stream Flow controller", func() {
 		rttStats := &congestion.RTTStats{}
 		controller = &streamFlowController{
 			streamID:   10,
-			connection: NewConnectionFlowController(1000, 1000, func() {}, rttStats, utils.DefaultLogger).(*connectionFlowController),
+			connection: NewConnectionFlowController(1000, 1000, protocol.DefaultReceiveWindowGrowthFactor, false, func() {}, rttStats, utils.DefaultClock{}, utils.DefaultLogger).(*connectionFlowController),
 		}
 		controller.maxReceiveWindowSize = 10000
+		controller.windowGrowthFactor = protocol.DefaultReceiveWindowGrowthFactor
+		controller.windowUpdateThreshold = protocol.WindowUpdateThreshold
 		controller.rttStats = rttStats
+		controller.clock = utils.DefaultClock{}
 		controller.logger = utils.DefaultLogger
 		controller.queueWindowUpdate = func() { queuedWindowUpdate = true }
 	})
@@ -36,8 +39,8 @@ var _ = Describe("Stream Flow controller", func() {
 		sendWindow := protocol.ByteCount(4000)
 
 		It("sets the send and receive windows", func() {
-			cc := NewConnectionFlowController(0, 0, nil, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(0, 0, protocol.DefaultReceiveWindowGrowthFactor, false, nil, nil, utils.DefaultClock{}, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, protocol.DefaultReceiveWindowGrowthFactor, false, protocol.WindowUpdateThreshold, sendWindow, nil, rttStats, utils.DefaultClock{}, utils.DefaultLogger).(*streamFlowController)
 			Expect(fc.streamID).To(Equal(protocol.StreamID(5)))
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowSize).To(Equal(maxReceiveWindow))
@@ -51,8 +54,8 @@ var _ = Describe("Stream Flow controller", func() {
 				queued = true
 			}
 
-			cc := NewConnectionFlowController(0, 0, nil, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, queueWindowUpdate, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(0, 0, protocol.DefaultReceiveWindowGrowthFactor, false, nil, nil, utils.DefaultClock{}, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, protocol.DefaultReceiveWindowGrowthFactor, false, protocol.WindowUpdateThreshold, sendWindow, queueWindowUpdate, rttStats, utils.DefaultClock{}, utils.DefaultLogger).(*streamFlowController)
 			fc.AddBytesRead(receiveWindow)
 			Expect(queued).To(BeTrue())
 		})
@@ -195,6 +198,31 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(controller.connection.GetWindowUpdate()).ToNot(BeZero())
 			})
 
+			It("queues a window update when the window size is increased", func() {
+				controller.SetReceiveWindowSize(200)
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(200)))
+				Expect(controller.GetWindowUpdate()).To(Equal(controller.bytesRead + 200))
+				Expect(controller.connection.(*connectionFlowController).receiveWindowSize).To(Equal(protocol.ByteCount(200 * protocol.ConnectionFlowControlMultiplier)))
+			})
+
+			It("doesn't set a window size larger than the connection's maximum window size", func() {
+				controller.SetReceiveWindowSize(5000)
+				Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(1000)))
+			})
+
+			It("doesn't auto-tune the window size after it was set", func() {
+				controller.SetReceiveWindowSize(oldWindowSize)
+				Expect(queuedWindowUpdate).To(BeFalse())
+				oldOffset := controller.bytesRead
+				setRtt(scaleDuration(20 * time.Millisecond))
+				controller.epochStartOffset = oldOffset
+				controller.epochStartTime = time.Now().Add(-time.Millisecond)
+				controller.AddBytesRead(55)
+				Expect(controller.GetWindowUpdate()).To(Equal(oldOffset + 55 + oldWindowSize))
+				Expect(controller.receiveWindowSize).To(Equal(oldWindowSize))
+			})
+
 			It("doesn't increase the window after a final offset was already received", func() {
 				Expect(controller.UpdateHighestReceived(90, true)).To(Succeed())
 				controller.AddBytesRead(30)
@@ -203,6 +231,53 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(offset).To(BeZero())
 			})
 		})
+
+		Context("window update threshold", func() {
+			const windowSize protocol.ByteCount = 10000
+
+			newController := func(threshold float64) *streamFlowController {
+				rttStats := &congestion.RTTStats{}
+				cc := NewConnectionFlowController(1<<30, 1<<30, protocol.DefaultReceiveWindowGrowthFactor, false, func() {}, rttStats, utils.DefaultClock{}, utils.DefaultLogger)
+				return NewStreamFlowController(5, cc, windowSize, windowSize, protocol.DefaultReceiveWindowGrowthFactor, false, threshold, 0, func(protocol.StreamID) {}, rttStats, utils.DefaultClock{}, utils.DefaultLogger).(*streamFlowController)
+			}
+
+			// countWindowUpdates simulates a peer that sends data just as fast as it is consumed,
+			// and returns the number of window updates generated when reading 100 kB in chunks of 1000 bytes.
+			countWindowUpdates := func(threshold float64) int {
+				fc := newController(threshold)
+				var numUpdates int
+				for offset := protocol.ByteCount(0); offset < 100*windowSize; offset += 1000 {
+					Expect(fc.UpdateHighestReceived(offset+1000, false)).To(Succeed())
+					fc.AddBytesRead(1000)
+					if fc.GetWindowUpdate() != 0 {
+						numUpdates++
+					}
+				}
+				return numUpdates
+			}
+
+			It("sends fewer window updates with higher thresholds", func() {
+				Expect(countWindowUpdates(0.25)).To(Equal(333))
+				Expect(countWindowUpdates(0.5)).To(Equal(200))
+				Expect(countWindowUpdates(0.75)).To(Equal(125))
+			})
+
+			It("updates the window when the peer is blocked, even if the threshold is not reached yet", func() {
+				fc := newController(0.75)
+				Expect(fc.UpdateHighestReceived(windowSize, false)).To(Succeed())
+				fc.AddBytesRead(protocol.MaxReceivePacketSize - 1)
+				Expect(fc.GetWindowUpdate()).To(BeZero())
+				fc.AddBytesRead(1)
+				Expect(fc.GetWindowUpdate()).To(Equal(windowSize + protocol.MaxReceivePacketSize))
+			})
+
+			It("doesn't update the window before the threshold is reached, if the peer is not blocked", func() {
+				fc := newController(0.75)
+				Expect(fc.UpdateHighestReceived(windowSize-1, false)).To(Succeed())
+				fc.AddBytesRead(windowSize / 2)
+				Expect(fc.GetWindowUpdate()).To(BeZero())
+			})
+		})
 	})
 
 	Context("sending data", func() {
@@ -228,5 +303,49 @@ var _ = Describe("Stream Flow controller", func() {
 			Expect(blocked).To(BeTrue())
 			Expect(controller.IsNewlyBlocked()).To(BeFalse())
 		})
+
+		Context("reserving send window", func() {
+			BeforeEach(func() {
+				controller.connection.UpdateSendWindow(100)
+				controller.UpdateSendWindow(200)
+			})
+
+			It("reserves connection-level send window", func() {
+				Expect(controller.ReserveSendWindow(60)).To(BeTrue())
+				Expect(controller.connection.SendWindowSize()).To(Equal(protocol.ByteCount(40)))
+				Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(100)))
+			})
+
+			It("doesn't reserve more than the connection-level send window", func() {
+				Expect(controller.ReserveSendWindow(101)).To(BeFalse())
+				Expect(controller.connection.SendWindowSize()).To(Equal(protocol.ByteCount(100)))
+			})
+
+			It("doesn't reserve more than the stream-level send window", func() {
+				controller.connection.UpdateSendWindow(1000)
+				Expect(controller.ReserveSendWindow(201)).To(BeFalse())
+				Expect(controller.connection.SendWindowSize()).To(Equal(protocol.ByteCount(1000)))
+			})
+
+			It("uses the reservation when sending data", func() {
+				Expect(controller.ReserveSendWindow(60)).To(BeTrue())
+				controller.connection.AddBytesSent(40) // another stream uses up the rest of the window
+				Expect(controller.connection.SendWindowSize()).To(BeZero())
+				Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(60)))
+				controller.AddBytesSent(50)
+				Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(10)))
+				controller.AddBytesSent(10)
+				Expect(controller.SendWindowSize()).To(BeZero())
+				Expect(controller.reservedSendWindow).To(BeZero())
+			})
+
+			It("releases the unused part of the reservation", func() {
+				Expect(controller.ReserveSendWindow(60)).To(BeTrue())
+				controller.AddBytesSent(20)
+				Expect(controller.connection.SendWindowSize()).To(Equal(protocol.ByteCount(40)))
+				controller.ReleaseSendWindow()
+				Expect(controller.connection.SendWindowSize()).To(Equal(protocol.ByteCount(80)))
+			})
+		})
 	})
 })
@@ -0,0 +1,67 @@
+package handshake
+
+import (
+	"crypto"
+	"crypto/cipher"
+
+	"github.com/marten-seemann/qtls"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// cipherSuiteTLS13ByID returns the TLS 1.3 cipher suite with the given ID.
+// It returns nil if the cipher suite is not supported.
+func cipherSuiteTLS13ByID(id uint16) *qtls.CipherSuiteTLS13 {
+	switch id {
+	case qtls.TLS_AES_128_GCM_SHA256:
+		return &qtls.CipherSuiteTLS13{ID: id, KeyLen: 16, AEAD: qtls.AEADAESGCMTLS13, Hash: crypto.SHA256}
+	case qtls.TLS_AES_256_GCM_SHA384:
+		return &qtls.CipherSuiteTLS13{ID: id, KeyLen: 32, AEAD: qtls.AEADAESGCMTLS13, Hash: crypto.SHA384}
+	case qtls.TLS_CHACHA20_POLY1305_SHA256:
+		return &qtls.CipherSuiteTLS13{ID: id, KeyLen: 32, AEAD: aeadChaCha20Poly1305TLS13, Hash: crypto.SHA256}
+	default:
+		return nil
+	}
+}
+
+// aeadChaCha20Poly1305TLS13 creates a ChaCha20-Poly1305 AEAD for TLS 1.3.
+// qtls only exports the constructor for AES-GCM.
+func aeadChaCha20Poly1305TLS13(key, nonceMask []byte) cipher.AEAD {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		panic(err)
+	}
+	ret := &xorNonceAEAD{aead: aead}
+	copy(ret.nonceMask[:], nonceMask)
+	return ret
+}
+
+// xorNonceAEAD wraps an AEAD by XORing a fixed pattern into the nonce before each call.
+type xorNonceAEAD struct {
+	nonceMask [chacha20poly1305.NonceSize]byte
+	aead      cipher.AEAD
+}
+
+var _ cipher.AEAD = &xorNonceAEAD{}
+
+func (f *xorNonceAEAD) NonceSize() int { return 8 } // 64-bit sequence number
+func (f *xorNonceAEAD) Overhead() int  { return f.aead.Overhead() }
+
+func (f *xorNonceAEAD) Seal(out, nonce, plaintext, additionalData []byte) []byte {
+	f.xorNonce(nonce)
+	result := f.aead.Seal(out, f.nonceMask[:], plaintext, additionalData)
+	f.xorNonce(nonce)
+	return result
+}
+
+func (f *xorNonceAEAD) Open(out, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	f.xorNonce(nonce)
+	result, err := f.aead.Open(out, f.nonceMask[:], ciphertext, additionalData)
+	f.xorNonce(nonce)
+	return result, err
+}
+
+func (f *xorNonceAEAD) xorNonce(nonce []byte) {
+	for i, b := range nonce {
+		f.nonceMask[4+i] ^= b
+	}
+}
@@ -0,0 +1,37 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cipher Suites", func() {
+	It("returns the TLS 1.3 cipher suites", func() {
+		for _, id := range []uint16{tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384, tls.TLS_CHACHA20_POLY1305_SHA256} {
+			suite := cipherSuiteTLS13ByID(id)
+			Expect(suite).ToNot(BeNil())
+			Expect(suite.ID).To(Equal(id))
+		}
+	})
+
+	It("doesn't return cipher suites that can't be used with TLS 1.3", func() {
+		Expect(cipherSuiteTLS13ByID(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)).To(BeNil())
+	})
+
+	// uses the ChaCha20-Poly1305 test vector from the QUIC-TLS specification (Appendix A.5)
+	It("opens packets using ChaCha20-Poly1305", func() {
+		suite := cipherSuiteTLS13ByID(tls.TLS_CHACHA20_POLY1305_SHA256)
+		secret := splitHexString("0x9ac312a7f877468ebe69422748ad00a15443f18203a07d6060f688f30f21632b")
+		aead := createAEAD(suite, secret)
+		nonce := make([]byte, 8)
+		binary.BigEndian.PutUint64(nonce, 654360564)
+		header := splitHexString("0x4200bff4")
+		plaintext, err := aead.Open(nil, nonce, splitHexString("0x655e5cd55c41f69080575d7999c25a5bfb"), header)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plaintext).To(Equal([]byte{0x1}))
+		Expect(aead.Seal(nil, nonce, plaintext, header)).To(Equal(splitHexString("0x655e5cd55c41f69080575d7999c25a5bfb")))
+	})
+})
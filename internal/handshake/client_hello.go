@@ -0,0 +1,36 @@
+package handshake
+
+import "golang.org/x/crypto/cryptobyte"
+
+const extensionEarlyData = 42
+
+// clientHelloOffersEarlyData says if a ClientHello message contains the early_data extension,
+// i.e. if the client attempts 0-RTT.
+// It returns false if the message can't be parsed.
+func clientHelloOffersEarlyData(data []byte) bool {
+	s := cryptobyte.String(data)
+	var msgType uint8
+	var body cryptobyte.String
+	if !s.ReadUint8(&msgType) || messageType(msgType) != typeClientHello || !s.ReadUint24LengthPrefixed(&body) {
+		return false
+	}
+	var sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !body.Skip(2+32) || // legacy_version and random
+		!body.ReadUint8LengthPrefixed(&sessionID) ||
+		!body.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!body.ReadUint8LengthPrefixed(&compressionMethods) ||
+		!body.ReadUint16LengthPrefixed(&extensions) {
+		return false
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return false
+		}
+		if extType == extensionEarlyData {
+			return true
+		}
+	}
+	return false
+}
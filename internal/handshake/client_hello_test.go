@@ -0,0 +1,53 @@
+package handshake
+
+import (
+	"golang.org/x/crypto/cryptobyte"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// getClientHello returns a ClientHello message containing extensions of the given types
+func getClientHello(extTypes ...uint16) []byte {
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint8(uint8(typeClientHello))
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(0x303)           // legacy_version
+		b.AddBytes(make([]byte, 32)) // random
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte{1, 2, 3}) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint16(0x1301) })
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, t := range extTypes {
+				b.AddUint16(t)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("foobar")) })
+			}
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+var _ = Describe("ClientHello", func() {
+	It("detects the early_data extension", func() {
+		Expect(clientHelloOffersEarlyData(getClientHello(0x0, quicTLSExtensionType, extensionEarlyData, 0x2b))).To(BeTrue())
+	})
+
+	It("detects that the early_data extension is missing", func() {
+		Expect(clientHelloOffersEarlyData(getClientHello(0x0, quicTLSExtensionType, 0x2b))).To(BeFalse())
+		Expect(clientHelloOffersEarlyData(getClientHello())).To(BeFalse())
+	})
+
+	It("doesn't detect the early_data extension in other messages", func() {
+		data := getClientHello(extensionEarlyData)
+		data[0] = byte(typeServerHello)
+		Expect(clientHelloOffersEarlyData(data)).To(BeFalse())
+	})
+
+	It("handles invalid messages", func() {
+		data := getClientHello(0x0, extensionEarlyData)
+		for i := 0; i < len(data)-1; i++ {
+			Expect(clientHelloOffersEarlyData(data[:i])).To(BeFalse())
+		}
+		Expect(clientHelloOffersEarlyData(nil)).To(BeFalse())
+	})
+})
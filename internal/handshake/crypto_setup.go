@@ -60,7 +60,7 @@ func (m messageType) String() string {
 
 type cryptoSetup struct {
 	tlsConf *qtls.Config
-	conn    *qtls.Conn
+	conn    TLSConn
 
 	messageChan chan []byte
 
@@ -68,6 +68,12 @@ type cryptoSetup struct {
 	peerParams *TransportParameters
 	paramsChan <-chan []byte
 
+	// only set for the server
+	allow0RTT  func(serverName string) bool
+	serverName string
+	// decides if the transport parameters saved in a session ticket are valid for 0-RTT
+	validFor0RTT func(cached, current *TransportParameters) bool
+
 	runner handshakeRunner
 
 	alertChan chan uint8
@@ -90,6 +96,7 @@ type cryptoSetup struct {
 	writeRecord chan struct{}
 
 	rttStats *congestion.RTTStats
+	clock    utils.Clock
 
 	qlogger qlog.Tracer
 	logger  utils.Logger
@@ -100,6 +107,12 @@ type cryptoSetup struct {
 
 	handshakeCompleteTime time.Time
 
+	usedHelloRetryRequest bool
+	zeroRTTRejection      ZeroRTTRejection
+	// only used by the server
+	offered0RTT  bool // the ClientHello contained the early_data extension
+	accepted0RTT bool
+
 	readEncLevel  protocol.EncryptionLevel
 	writeEncLevel protocol.EncryptionLevel
 
@@ -123,6 +136,7 @@ var _ qtls.RecordLayer = &cryptoSetup{}
 var _ CryptoSetup = &cryptoSetup{}
 
 // NewCryptoSetupClient creates a new crypto setup for the client
+// If newTLSConn is nil, qtls is used as the TLS stack.
 func NewCryptoSetupClient(
 	initialStream io.Writer,
 	handshakeStream io.Writer,
@@ -132,8 +146,10 @@ func NewCryptoSetupClient(
 	tp *TransportParameters,
 	runner handshakeRunner,
 	tlsConf *tls.Config,
+	newTLSConn NewTLSConnFunc,
 	enable0RTT bool,
 	rttStats *congestion.RTTStats,
+	clock utils.Clock,
 	qlogger qlog.Tracer,
 	logger utils.Logger,
 ) (CryptoSetup, <-chan *TransportParameters /* ClientHello written. Receive nil for non-0-RTT */) {
@@ -144,17 +160,22 @@ func NewCryptoSetupClient(
 		tp,
 		runner,
 		tlsConf,
+		newTLSConn,
 		enable0RTT,
 		rttStats,
+		clock,
 		qlogger,
 		logger,
 		protocol.PerspectiveClient,
 	)
-	cs.conn = qtls.Client(newConn(localAddr, remoteAddr), cs.tlsConf)
+	if cs.conn == nil {
+		cs.conn = qtls.Client(newConn(localAddr, remoteAddr), cs.tlsConf)
+	}
 	return cs, clientHelloWritten
 }
 
 // NewCryptoSetupServer creates a new crypto setup for the server
+// If newTLSConn is nil, qtls is used as the TLS stack.
 func NewCryptoSetupServer(
 	initialStream io.Writer,
 	handshakeStream io.Writer,
@@ -164,8 +185,12 @@ func NewCryptoSetupServer(
 	tp *TransportParameters,
 	runner handshakeRunner,
 	tlsConf *tls.Config,
+	newTLSConn NewTLSConnFunc,
 	enable0RTT bool,
+	allow0RTT func(serverName string) bool,
+	validFor0RTT func(cached, current *TransportParameters) bool,
 	rttStats *congestion.RTTStats,
+	clock utils.Clock,
 	qlogger qlog.Tracer,
 	logger utils.Logger,
 ) CryptoSetup {
@@ -176,13 +201,31 @@ func NewCryptoSetupServer(
 		tp,
 		runner,
 		tlsConf,
+		newTLSConn,
 		enable0RTT,
 		rttStats,
+		clock,
 		qlogger,
 		logger,
 		protocol.PerspectiveServer,
 	)
-	cs.conn = qtls.Server(newConn(localAddr, remoteAddr), cs.tlsConf)
+	cs.validFor0RTT = validFor0RTT
+	if allow0RTT != nil {
+		cs.allow0RTT = allow0RTT
+		// Record the server name sent by the client.
+		// It is needed to decide if 0-RTT is allowed for this client.
+		getConfigForClient := cs.tlsConf.GetConfigForClient
+		cs.tlsConf.GetConfigForClient = func(ch *qtls.ClientHelloInfo) (*qtls.Config, error) {
+			cs.serverName = ch.ServerName
+			if getConfigForClient == nil {
+				return nil, nil
+			}
+			return getConfigForClient(ch)
+		}
+	}
+	if cs.conn == nil {
+		cs.conn = qtls.Server(newConn(localAddr, remoteAddr), cs.tlsConf)
+	}
 	return cs
 }
 
@@ -193,31 +236,42 @@ func newCryptoSetup(
 	tp *TransportParameters,
 	runner handshakeRunner,
 	tlsConf *tls.Config,
+	newTLSConn NewTLSConnFunc,
 	enable0RTT bool,
 	rttStats *congestion.RTTStats,
+	clock utils.Clock,
 	qlogger qlog.Tracer,
 	logger utils.Logger,
 	perspective protocol.Perspective,
 ) (*cryptoSetup, <-chan *TransportParameters /* ClientHello written. Receive nil for non-0-RTT */) {
 	initialSealer, initialOpener := NewInitialAEAD(connID, perspective)
 	if qlogger != nil {
-		now := time.Now()
+		now := clock.Now()
 		qlogger.UpdatedKeyFromTLS(now, protocol.EncryptionInitial, protocol.PerspectiveClient)
 		qlogger.UpdatedKeyFromTLS(now, protocol.EncryptionInitial, protocol.PerspectiveServer)
 	}
-	extHandler := newExtensionHandler(tp.Marshal(), perspective)
+	data := tp.Marshal()
+	if qlogger != nil {
+		// Parse the marshaled transport parameters, such that the trace also contains the greased transport parameter.
+		var sentParams TransportParameters
+		if err := sentParams.Unmarshal(data, perspective); err == nil {
+			qlogger.SentTransportParameters(clock.Now(), sentParams.toQlog())
+		}
+	}
+	extHandler := newExtensionHandler(data, perspective)
 	cs := &cryptoSetup{
 		initialStream:          initialStream,
 		initialSealer:          initialSealer,
 		initialOpener:          initialOpener,
 		handshakeStream:        handshakeStream,
-		aead:                   newUpdatableAEAD(rttStats, qlogger, logger),
+		aead:                   newUpdatableAEAD(rttStats, clock, qlogger, logger),
 		readEncLevel:           protocol.EncryptionInitial,
 		writeEncLevel:          protocol.EncryptionInitial,
 		runner:                 runner,
 		ourParams:              tp,
 		paramsChan:             extHandler.TransportParameters(),
 		rttStats:               rttStats,
+		clock:                  clock,
 		qlogger:                qlogger,
 		logger:                 logger,
 		perspective:            perspective,
@@ -232,6 +286,9 @@ func newCryptoSetup(
 	}
 	qtlsConf := tlsConfigToQtlsConfig(tlsConf, cs, extHandler, rttStats, cs.marshalPeerParamsForSessionState, cs.handlePeerParamsFromSessionState, cs.accept0RTT, cs.rejected0RTT, enable0RTT)
 	cs.tlsConf = qtlsConf
+	if newTLSConn != nil {
+		cs.conn = cs.createTLSConn(newTLSConn, tlsConf, data)
+	}
 	return cs, cs.clientHelloWrittenChan
 }
 
@@ -240,7 +297,7 @@ func (h *cryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
 	h.initialSealer = initialSealer
 	h.initialOpener = initialOpener
 	if h.qlogger != nil {
-		now := time.Now()
+		now := h.clock.Now()
 		h.qlogger.UpdatedKeyFromTLS(now, protocol.EncryptionInitial, protocol.PerspectiveClient)
 		h.qlogger.UpdatedKeyFromTLS(now, protocol.EncryptionInitial, protocol.PerspectiveServer)
 	}
@@ -266,7 +323,12 @@ func (h *cryptoSetup) RunHandshake() {
 	select {
 	case <-handshakeComplete: // return when the handshake is done
 		h.mutex.Lock()
-		h.handshakeCompleteTime = time.Now()
+		h.handshakeCompleteTime = h.clock.Now()
+		// The TLS stack doesn't ask us if it rejects 0-RTT itself, e.g. if it can't decrypt the session ticket.
+		if h.offered0RTT && !h.accepted0RTT && h.zeroRTTRejection.Reason == ZeroRTTNotRejected {
+			h.logger.Debugf("0-RTT was rejected by TLS.")
+			h.zeroRTTRejection = ZeroRTTRejection{Reason: ZeroRTTRejectedByTLS}
+		}
 		h.mutex.Unlock()
 		h.runner.OnHandshakeComplete()
 	case <-h.closeChan:
@@ -303,6 +365,11 @@ func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLev
 		h.onError(alertUnexpectedMessage, err.Error())
 		return false
 	}
+	if h.perspective == protocol.PerspectiveServer && msgType == typeClientHello && clientHelloOffersEarlyData(data) {
+		h.mutex.Lock()
+		h.offered0RTT = true
+		h.mutex.Unlock()
+	}
 	h.messageChan <- data
 	if encLevel == protocol.Encryption1RTT {
 		h.handlePostHandshakeMessage()
@@ -353,6 +420,7 @@ func (h *cryptoSetup) handleMessageForServer(msgType messageType) bool {
 			// If qtls sends a HelloRetryRequest, it will only write the record.
 			// If it accepts the ClientHello, it will first read the transport parameters.
 			h.logger.Debugf("Sending HelloRetryRequest")
+			h.setUsedHelloRetryRequest()
 			return false
 		case data := <-h.paramsChan:
 			h.handleTransportParameters(data)
@@ -371,6 +439,9 @@ func (h *cryptoSetup) handleMessageForServer(msgType messageType) bool {
 		case <-h.handshakeDone:
 			return false
 		}
+		if h.qlogger != nil {
+			h.qlogger.HandshakeProgressed(h.clock.Now(), qlog.HandshakeEventServerHelloSent)
+		}
 		// get the 1-RTT write key
 		select {
 		case <-h.receivedWriteKey:
@@ -405,6 +476,7 @@ func (h *cryptoSetup) handleMessageForClient(msgType messageType) bool {
 			// is a HelloRetryRequest.
 			// Otherwise, we'd just wait for the Certificate message.
 			h.logger.Debugf("ServerHello is a HelloRetryRequest")
+			h.setUsedHelloRetryRequest()
 			return false
 		case <-h.receivedWriteKey:
 		case <-h.handshakeDone:
@@ -416,6 +488,9 @@ func (h *cryptoSetup) handleMessageForClient(msgType messageType) bool {
 		case <-h.handshakeDone:
 			return false
 		}
+		if h.qlogger != nil {
+			h.qlogger.HandshakeProgressed(h.clock.Now(), qlog.HandshakeEventServerHelloReceived)
+		}
 		return true
 	case typeEncryptedExtensions:
 		select {
@@ -447,10 +522,24 @@ func (h *cryptoSetup) handleMessageForClient(msgType messageType) bool {
 	}
 }
 
+func (h *cryptoSetup) setUsedHelloRetryRequest() {
+	h.mutex.Lock()
+	h.usedHelloRetryRequest = true
+	h.mutex.Unlock()
+}
+
+func (h *cryptoSetup) setZeroRTTRejection(r ZeroRTTRejection) {
+	h.mutex.Lock()
+	h.zeroRTTRejection = r
+	h.mutex.Unlock()
+}
+
 func (h *cryptoSetup) handleTransportParameters(data []byte) {
 	var tp TransportParameters
 	if err := tp.Unmarshal(data, h.perspective.Opposite()); err != nil {
 		h.runner.OnError(qerr.Error(qerr.TransportParameterError, err.Error()))
+	} else if h.qlogger != nil {
+		h.qlogger.ReceivedTransportParameters(h.clock.Now(), tp.toQlog())
 	}
 	h.peerParams = &tp
 	h.runner.OnReceivedParams(h.peerParams)
@@ -492,7 +581,7 @@ func (h *cryptoSetup) handlePeerParamsFromSessionStateImpl(data []byte) (*Transp
 func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
 	var appData []byte
 	// Save transport parameters to the session ticket if we're allowing 0-RTT.
-	if h.tlsConf.MaxEarlyData > 0 {
+	if h.tlsConf.MaxEarlyData > 0 && h.is0RTTAllowed() {
 		appData = (&sessionTicket{
 			Parameters: h.ourParams,
 			RTT:        h.rttStats.SmoothedRTT(),
@@ -504,24 +593,49 @@ func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
 // accept0RTT is called for the server when receiving the client's session ticket.
 // It decides whether to accept 0-RTT.
 func (h *cryptoSetup) accept0RTT(sessionTicketData []byte) bool {
+	if !h.is0RTTAllowed() {
+		h.logger.Debugf("0-RTT is not allowed for this client. Rejecting 0-RTT.")
+		h.setZeroRTTRejection(ZeroRTTRejection{Reason: ZeroRTTRejectedByPolicy})
+		return false
+	}
 	var t sessionTicket
 	if err := t.Unmarshal(sessionTicketData); err != nil {
 		h.logger.Debugf("Unmarshaling transport parameters from session ticket failed: %s", err.Error())
+		h.setZeroRTTRejection(ZeroRTTRejection{Reason: ZeroRTTRejectedInvalidSessionTicket})
 		return false
 	}
-	valid := h.ourParams.ValidFor0RTT(t.Parameters)
+	var valid bool
+	if h.validFor0RTT != nil {
+		valid = h.validFor0RTT(t.Parameters, h.ourParams)
+	} else {
+		valid = h.ourParams.ValidFor0RTT(t.Parameters)
+	}
 	if valid {
 		h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
 		h.rttStats.SetInitialRTT(t.RTT)
+		h.mutex.Lock()
+		h.accepted0RTT = true
+		h.mutex.Unlock()
 	} else {
 		h.logger.Debugf("Transport parameters changed. Rejecting 0-RTT.")
+		h.setZeroRTTRejection(ZeroRTTRejection{
+			Reason:                        ZeroRTTRejectedTransportParameters,
+			MismatchedTransportParameters: h.ourParams.ZeroRTTMismatches(t.Parameters),
+		})
 	}
 	return valid
 }
 
+// is0RTTAllowed is called for the server.
+// It asks the application if 0-RTT is allowed for this client.
+func (h *cryptoSetup) is0RTTAllowed() bool {
+	return h.allow0RTT == nil || h.allow0RTT(h.serverName)
+}
+
 // rejected0RTT is called for the client when the server rejects 0-RTT.
 func (h *cryptoSetup) rejected0RTT() {
 	h.logger.Debugf("0-RTT was rejected. Dropping 0-RTT keys.")
+	h.setZeroRTTRejection(ZeroRTTRejection{Reason: ZeroRTTRejectedByTLS})
 
 	h.mutex.Lock()
 	had0RTTKeys := h.zeroRTTSealer != nil
@@ -582,7 +696,7 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Read keys (using %s)", qtls.CipherSuiteName(suite.ID))
 		if h.qlogger != nil {
-			h.qlogger.UpdatedKeyFromTLS(time.Now(), protocol.Encryption0RTT, h.perspective.Opposite())
+			h.qlogger.UpdatedKeyFromTLS(h.clock.Now(), protocol.Encryption0RTT, h.perspective.Opposite())
 		}
 		return
 	case qtls.EncryptionHandshake:
@@ -604,7 +718,7 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 	}
 	h.mutex.Unlock()
 	if h.qlogger != nil {
-		h.qlogger.UpdatedKeyFromTLS(time.Now(), h.readEncLevel, h.perspective.Opposite())
+		h.qlogger.UpdatedKeyFromTLS(h.clock.Now(), h.readEncLevel, h.perspective.Opposite())
 	}
 	h.receivedReadKey <- struct{}{}
 }
@@ -623,7 +737,7 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 		h.mutex.Unlock()
 		h.logger.Debugf("Installed 0-RTT Write keys (using %s)", qtls.CipherSuiteName(suite.ID))
 		if h.qlogger != nil {
-			h.qlogger.UpdatedKeyFromTLS(time.Now(), protocol.Encryption0RTT, h.perspective)
+			h.qlogger.UpdatedKeyFromTLS(h.clock.Now(), protocol.Encryption0RTT, h.perspective)
 		}
 		return
 	case qtls.EncryptionHandshake:
@@ -649,7 +763,7 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 	}
 	h.mutex.Unlock()
 	if h.qlogger != nil {
-		h.qlogger.UpdatedKeyFromTLS(time.Now(), h.writeEncLevel, h.perspective)
+		h.qlogger.UpdatedKeyFromTLS(h.clock.Now(), h.writeEncLevel, h.perspective)
 	}
 	h.receivedWriteKey <- struct{}{}
 }
@@ -800,7 +914,7 @@ func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	if h.zeroRTTOpener != nil && time.Since(h.handshakeCompleteTime) > 3*h.rttStats.PTO(true) {
+	if h.zeroRTTOpener != nil && h.clock.Now().Sub(h.handshakeCompleteTime) > 3*h.rttStats.PTO(true) {
 		h.zeroRTTOpener = nil
 		h.logger.Debugf("Dropping 0-RTT keys.")
 	}
@@ -814,3 +928,21 @@ func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
 func (h *cryptoSetup) ConnectionState() ConnectionState {
 	return h.conn.ConnectionState()
 }
+
+// UsedHelloRetryRequest says if a HelloRetryRequest was sent (server) or received (client).
+func (h *cryptoSetup) UsedHelloRetryRequest() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.usedHelloRetryRequest
+}
+
+// ZeroRTTRejection says if and why 0-RTT was rejected.
+func (h *cryptoSetup) ZeroRTTRejection() ZeroRTTRejection {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.zeroRTTRejection
+}
+
+func (h *cryptoSetup) NumKeyUpdates() uint64 {
+	return h.aead.NumKeyUpdates()
+}
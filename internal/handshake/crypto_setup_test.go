@@ -17,6 +17,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/testdata"
 	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/qlog"
 	"github.com/marten-seemann/qtls"
 
 	. "github.com/onsi/ginkgo"
@@ -51,6 +52,25 @@ func (s *stream) Write(b []byte) (int, error) {
 	return len(b), nil
 }
 
+// transportParameterRecorder records the transport parameters passed to the qlog.Tracer
+type transportParameterRecorder struct {
+	qlog.Tracer
+	sent, received *qlog.TransportParameters
+}
+
+func (r *transportParameterRecorder) SentTransportParameters(_ time.Time, tp *qlog.TransportParameters) {
+	r.sent = tp
+}
+
+func (r *transportParameterRecorder) ReceivedTransportParameters(_ time.Time, tp *qlog.TransportParameters) {
+	r.received = tp
+}
+
+func (r *transportParameterRecorder) UpdatedKeyFromTLS(time.Time, protocol.EncryptionLevel, protocol.Perspective) {
+}
+
+func (r *transportParameterRecorder) HandshakeProgressed(time.Time, qlog.HandshakeEvent) {}
+
 var _ = Describe("Crypto Setup TLS", func() {
 	var clientConf, serverConf *tls.Config
 
@@ -95,8 +115,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			NewMockHandshakeRunner(mockCtrl),
 			tlsConf,
+			nil,
 			false,
+			nil,
+			nil,
 			&congestion.RTTStats{},
+			utils.DefaultClock{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
@@ -128,8 +152,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			runner,
 			testdata.GetTLSConfig(),
+			nil,
 			false,
+			nil,
+			nil,
 			&congestion.RTTStats{},
+			utils.DefaultClock{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
@@ -167,8 +195,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			runner,
 			testdata.GetTLSConfig(),
+			nil,
 			false,
+			nil,
+			nil,
 			&congestion.RTTStats{},
+			utils.DefaultClock{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
@@ -209,8 +241,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			runner,
 			serverConf,
+			nil,
 			false,
+			nil,
+			nil,
 			&congestion.RTTStats{},
+			utils.DefaultClock{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
@@ -244,8 +280,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			NewMockHandshakeRunner(mockCtrl),
 			serverConf,
+			nil,
 			false,
+			nil,
+			nil,
 			&congestion.RTTStats{},
+			utils.DefaultClock{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
@@ -339,8 +379,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&TransportParameters{},
 				cRunner,
 				clientConf,
+				nil,
 				enable0RTT,
 				&congestion.RTTStats{},
+				utils.DefaultClock{},
 				nil,
 				utils.DefaultLogger.WithPrefix("client"),
 			)
@@ -362,8 +404,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&TransportParameters{StatelessResetToken: &token},
 				sRunner,
 				serverConf,
+				nil,
 				enable0RTT,
+				nil,
+				nil,
 				&congestion.RTTStats{},
+				utils.DefaultClock{},
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
 			)
@@ -384,16 +430,20 @@ var _ = Describe("Crypto Setup TLS", func() {
 		}
 
 		It("handshakes", func() {
-			_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
+			client, clientErr, server, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
 			Expect(clientErr).ToNot(HaveOccurred())
 			Expect(serverErr).ToNot(HaveOccurred())
+			Expect(client.UsedHelloRetryRequest()).To(BeFalse())
+			Expect(server.UsedHelloRetryRequest()).To(BeFalse())
 		})
 
 		It("performs a HelloRetryRequst", func() {
 			serverConf.CurvePreferences = []tls.CurveID{tls.CurveP384}
-			_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
+			client, clientErr, server, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
 			Expect(clientErr).ToNot(HaveOccurred())
 			Expect(serverErr).ToNot(HaveOccurred())
+			Expect(client.UsedHelloRetryRequest()).To(BeTrue())
+			Expect(server.UsedHelloRetryRequest()).To(BeTrue())
 		})
 
 		It("handshakes with client auth", func() {
@@ -416,8 +466,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&TransportParameters{},
 				runner,
 				&tls.Config{InsecureSkipVerify: true},
+				nil,
 				false,
 				&congestion.RTTStats{},
+				utils.DefaultClock{},
 				nil,
 				utils.DefaultLogger.WithPrefix("client"),
 			)
@@ -458,8 +510,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 				cTransportParameters,
 				cRunner,
 				clientConf,
+				nil,
 				false,
 				&congestion.RTTStats{},
+				utils.DefaultClock{},
 				nil,
 				utils.DefaultLogger.WithPrefix("client"),
 			)
@@ -482,8 +536,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sTransportParameters,
 				sRunner,
 				serverConf,
+				nil,
 				false,
+				nil,
+				nil,
 				&congestion.RTTStats{},
+				utils.DefaultClock{},
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
 			)
@@ -515,8 +573,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					cRunner,
 					clientConf,
+					nil,
 					false,
 					&congestion.RTTStats{},
+					utils.DefaultClock{},
 					nil,
 					utils.DefaultLogger.WithPrefix("client"),
 				)
@@ -534,8 +594,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					sRunner,
 					serverConf,
+					nil,
 					false,
+					nil,
+					nil,
 					&congestion.RTTStats{},
+					utils.DefaultClock{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
 				)
@@ -574,8 +638,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					cRunner,
 					clientConf,
+					nil,
 					false,
 					&congestion.RTTStats{},
+					utils.DefaultClock{},
 					nil,
 					utils.DefaultLogger.WithPrefix("client"),
 				)
@@ -593,8 +659,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					sRunner,
 					serverConf,
+					nil,
 					false,
+					nil,
+					nil,
 					&congestion.RTTStats{},
+					utils.DefaultClock{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
 				)
@@ -705,8 +775,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					cRunner,
 					clientConf,
+					nil,
 					true,
 					&congestion.RTTStats{},
+					utils.DefaultClock{},
 					nil,
 					utils.DefaultLogger.WithPrefix("client"),
 				)
@@ -724,8 +796,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					sRunner,
 					serverConf,
+					nil,
 					true,
+					nil,
+					nil,
 					&congestion.RTTStats{},
+					utils.DefaultClock{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
 				)
@@ -746,3 +822,344 @@ var _ = Describe("Crypto Setup TLS", func() {
 		})
 	})
 })
+
+type mockTLSConn struct {
+	handshake func() error
+}
+
+var _ TLSConn = &mockTLSConn{}
+
+func (c *mockTLSConn) Handshake() error                          { return c.handshake() }
+func (c *mockTLSConn) HandlePostHandshakeMessage() error         { return nil }
+func (c *mockTLSConn) GetSessionTicket(b []byte) ([]byte, error) { return b, nil }
+func (c *mockTLSConn) ConnectionState() ConnectionState          { return ConnectionState{} }
+
+var _ = Describe("Crypto Setup, using an external TLS stack", func() {
+	It("exchanges the transport parameters", func() {
+		clientParams := &TransportParameters{InitialMaxData: 0x1337, MaxIdleTimeout: time.Minute}
+		serverParams := &TransportParameters{InitialMaxData: 0x42, MaxIdleTimeout: time.Hour}
+
+		newTLSConn := func(conf *tls.Config, rl TLSRecordLayer, params []byte, isClient bool) TLSConn {
+			Expect(isClient).To(BeTrue())
+			Expect(conf.ServerName).To(Equal("localhost"))
+			return &mockTLSConn{
+				handshake: func() error {
+					defer GinkgoRecover()
+					// send our transport parameters in the ClientHello
+					var tp TransportParameters
+					Expect(tp.Unmarshal(params, protocol.PerspectiveClient)).To(Succeed())
+					Expect(tp.InitialMaxData).To(Equal(clientParams.InitialMaxData))
+					Expect(rl.WriteHandshakeMessage(TLSEncryptionInitial, []byte("ClientHello"))).To(Succeed())
+					// receive the server's transport parameters in the EncryptedExtensions
+					msg, err := rl.ReadHandshakeMessage()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(messageType(msg[0])).To(Equal(typeEncryptedExtensions))
+					rl.ReceivedTransportParameters(serverParams.Marshal())
+					return nil
+				},
+			}
+		}
+
+		runner := NewMockHandshakeRunner(mockCtrl)
+		chunkChan := make(chan chunk, 10)
+		recorder := &transportParameterRecorder{}
+		client, clientHelloWritten := NewCryptoSetupClient(
+			newStream(chunkChan, protocol.EncryptionInitial),
+			newStream(chunkChan, protocol.EncryptionHandshake),
+			protocol.ConnectionID{},
+			nil,
+			nil,
+			clientParams,
+			runner,
+			&tls.Config{ServerName: "localhost"},
+			newTLSConn,
+			false,
+			&congestion.RTTStats{},
+			utils.DefaultClock{},
+			recorder,
+			utils.DefaultLogger.WithPrefix("client"),
+		)
+
+		var receivedParams *TransportParameters
+		runner.EXPECT().OnReceivedParams(gomock.Any()).Do(func(tp *TransportParameters) { receivedParams = tp })
+		runner.EXPECT().OnHandshakeComplete()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			client.RunHandshake()
+			close(done)
+		}()
+
+		var ch chunk
+		Eventually(chunkChan).Should(Receive(&ch))
+		Expect(ch.data).To(Equal([]byte("ClientHello")))
+		Expect(ch.encLevel).To(Equal(protocol.EncryptionInitial))
+		Eventually(clientHelloWritten).Should(Receive(BeNil()))
+		Expect(client.HandleMessage([]byte{byte(typeEncryptedExtensions), 0, 0, 0}, protocol.EncryptionHandshake)).To(BeFalse())
+		Eventually(done).Should(BeClosed())
+		Expect(receivedParams).ToNot(BeNil())
+		Expect(receivedParams.InitialMaxData).To(Equal(serverParams.InitialMaxData))
+		Expect(receivedParams.MaxIdleTimeout).To(Equal(time.Hour))
+		// check that the transport parameters were traced, including the greased parameter
+		Expect(recorder.sent).ToNot(BeNil())
+		Expect(recorder.sent.InitialMaxData).To(Equal(clientParams.InitialMaxData))
+		Expect(recorder.sent.MaxIdleTimeout).To(Equal(time.Minute))
+		Expect(recorder.sent.UnknownParameters).To(HaveLen(1))
+		Expect(recorder.sent.UnknownParameters[0].ID % 31).To(BeEquivalentTo(27))
+		Expect(recorder.received).ToNot(BeNil())
+		Expect(recorder.received.InitialMaxData).To(Equal(serverParams.InitialMaxData))
+		Expect(recorder.received.MaxIdleTimeout).To(Equal(time.Hour))
+		Expect(recorder.received.UnknownParameters).To(HaveLen(1))
+	})
+
+	Context("using the record layer", func() {
+		var rl TLSRecordLayer
+
+		BeforeEach(func() {
+			rl = nil
+			NewCryptoSetupClient(
+				&bytes.Buffer{},
+				&bytes.Buffer{},
+				protocol.ConnectionID{},
+				nil,
+				nil,
+				&TransportParameters{},
+				NewMockHandshakeRunner(mockCtrl),
+				&tls.Config{},
+				func(_ *tls.Config, recordLayer TLSRecordLayer, _ []byte, _ bool) TLSConn {
+					rl = recordLayer
+					return &mockTLSConn{}
+				},
+				false,
+				&congestion.RTTStats{},
+				utils.DefaultClock{},
+				nil,
+				utils.DefaultLogger.WithPrefix("client"),
+			)
+			Expect(rl).ToNot(BeNil())
+		})
+
+		It("refuses to write handshake messages at a different encryption level", func() {
+			Expect(rl.WriteHandshakeMessage(TLSEncryptionHandshake, []byte("foobar"))).To(MatchError("cannot write a handshake message at encryption level 1 (current level: Initial)"))
+			Expect(rl.WriteHandshakeMessage(TLSEncryptionApplication, []byte("foobar"))).To(HaveOccurred())
+		})
+
+		It("refuses to install keys for the Initial encryption level", func() {
+			err := rl.SetReadKey(TLSEncryptionInitial, tls.TLS_AES_128_GCM_SHA256, make([]byte, 32))
+			Expect(err).To(MatchError("keys can only be installed for the Handshake and the 1-RTT encryption level"))
+			Expect(rl.SetWriteKey(TLSEncryptionInitial, tls.TLS_AES_128_GCM_SHA256, make([]byte, 32))).To(HaveOccurred())
+		})
+
+		It("refuses to install keys for unsupported cipher suites", func() {
+			err := rl.SetReadKey(TLSEncryptionHandshake, tls.TLS_RSA_WITH_AES_128_GCM_SHA256, make([]byte, 32))
+			Expect(err).To(MatchError("unsupported cipher suite: 0x9c"))
+			Expect(rl.SetWriteKey(TLSEncryptionHandshake, tls.TLS_RSA_WITH_AES_128_GCM_SHA256, make([]byte, 32))).To(HaveOccurred())
+		})
+	})
+
+	Context("deciding if 0-RTT is allowed", func() {
+		params := &TransportParameters{InitialMaxData: 0x1337, StatelessResetToken: &[16]byte{}}
+		allow0RTT := func(serverName string) bool { return serverName == "allowed.com" }
+
+		// handshake runs a resumed handshake, using a session ticket that would be valid for 0-RTT.
+		// It returns if 0-RTT was accepted, and the data that would be stored in the new session ticket.
+		handshake := func(serverName string) (bool /* accepted 0-RTT */, []byte /* session ticket data */, ZeroRTTRejection) {
+			var accepted bool
+			runner := NewMockHandshakeRunner(mockCtrl)
+			runner.EXPECT().OnHandshakeComplete()
+			server := NewCryptoSetupServer(
+				&bytes.Buffer{},
+				&bytes.Buffer{},
+				protocol.ConnectionID{},
+				nil,
+				nil,
+				params,
+				runner,
+				&tls.Config{},
+				nil,
+				true,
+				allow0RTT,
+				nil,
+				&congestion.RTTStats{},
+				utils.DefaultClock{},
+				nil,
+				utils.DefaultLogger.WithPrefix("server"),
+			)
+			conf := server.(*cryptoSetup).tlsConf
+			server.(*cryptoSetup).conn = &mockTLSConn{
+				handshake: func() error {
+					defer GinkgoRecover()
+					c, err := conf.GetConfigForClient(&qtls.ClientHelloInfo{ServerName: serverName})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(c).To(BeNil())
+					accepted = conf.Accept0RTT((&sessionTicket{Parameters: params, RTT: time.Second}).Marshal())
+					return nil
+				},
+			}
+			server.RunHandshake()
+			ticket, err := server.GetSessionTicket()
+			Expect(err).ToNot(HaveOccurred())
+			return accepted, ticket, server.ZeroRTTRejection()
+		}
+
+		It("accepts 0-RTT and issues session tickets usable for 0-RTT, if allowed for the server name", func() {
+			accepted, ticket, rejection := handshake("allowed.com")
+			Expect(accepted).To(BeTrue())
+			Expect(ticket).ToNot(BeEmpty())
+			Expect(rejection.Reason).To(Equal(ZeroRTTNotRejected))
+		})
+
+		It("rejects 0-RTT and issues session tickets not usable for 0-RTT, if not allowed for the server name", func() {
+			accepted, ticket, rejection := handshake("forbidden.com")
+			Expect(accepted).To(BeFalse())
+			Expect(ticket).To(BeEmpty())
+			Expect(rejection).To(Equal(ZeroRTTRejection{Reason: ZeroRTTRejectedByPolicy}))
+		})
+	})
+
+	Context("deciding if the transport parameters are valid for 0-RTT", func() {
+		cachedParams := &TransportParameters{InitialMaxData: 0x1337}
+		params := &TransportParameters{InitialMaxData: 0x1000, StatelessResetToken: &[16]byte{}}
+		var sessionTicketData []byte
+
+		BeforeEach(func() {
+			sessionTicketData = (&sessionTicket{Parameters: cachedParams, RTT: time.Second}).Marshal()
+		})
+
+		// handshake runs a resumed handshake, using a session ticket containing the cached transport parameters.
+		// It returns if 0-RTT was accepted.
+		handshake := func(validFor0RTT func(cached, current *TransportParameters) bool) (bool, ZeroRTTRejection) {
+			var accepted bool
+			runner := NewMockHandshakeRunner(mockCtrl)
+			runner.EXPECT().OnHandshakeComplete()
+			server := NewCryptoSetupServer(
+				&bytes.Buffer{},
+				&bytes.Buffer{},
+				protocol.ConnectionID{},
+				nil,
+				nil,
+				params,
+				runner,
+				&tls.Config{},
+				nil,
+				true,
+				nil,
+				validFor0RTT,
+				&congestion.RTTStats{},
+				utils.DefaultClock{},
+				nil,
+				utils.DefaultLogger.WithPrefix("server"),
+			)
+			conf := server.(*cryptoSetup).tlsConf
+			server.(*cryptoSetup).conn = &mockTLSConn{
+				handshake: func() error {
+					accepted = conf.Accept0RTT(sessionTicketData)
+					return nil
+				},
+			}
+			server.RunHandshake()
+			return accepted, server.ZeroRTTRejection()
+		}
+
+		It("rejects 0-RTT if the flow control window decreased, by default", func() {
+			accepted, rejection := handshake(nil)
+			Expect(accepted).To(BeFalse())
+			Expect(rejection).To(Equal(ZeroRTTRejection{
+				Reason:                        ZeroRTTRejectedTransportParameters,
+				MismatchedTransportParameters: []string{"initial_max_data"},
+			}))
+		})
+
+		It("rejects 0-RTT if the session ticket can't be parsed", func() {
+			sessionTicketData = []byte("foobar")
+			accepted, rejection := handshake(nil)
+			Expect(accepted).To(BeFalse())
+			Expect(rejection).To(Equal(ZeroRTTRejection{Reason: ZeroRTTRejectedInvalidSessionTicket}))
+		})
+
+		It("uses a custom comparator", func() {
+			var cached, current *TransportParameters
+			accepted, rejection := handshake(func(c, cur *TransportParameters) bool {
+				cached = c
+				current = cur
+				return cur.InitialMaxData <= c.InitialMaxData
+			})
+			Expect(accepted).To(BeTrue())
+			Expect(rejection.Reason).To(Equal(ZeroRTTNotRejected))
+			Expect(cached.InitialMaxData).To(Equal(cachedParams.InitialMaxData))
+			Expect(current).To(Equal(params))
+		})
+	})
+
+	It("tells the client that 0-RTT was rejected by TLS", func() {
+		client, _ := NewCryptoSetupClient(
+			&bytes.Buffer{},
+			&bytes.Buffer{},
+			protocol.ConnectionID{},
+			nil,
+			nil,
+			&TransportParameters{},
+			NewMockHandshakeRunner(mockCtrl),
+			&tls.Config{InsecureSkipVerify: true},
+			nil,
+			true,
+			&congestion.RTTStats{},
+			utils.DefaultClock{},
+			nil,
+			utils.DefaultLogger.WithPrefix("client"),
+		)
+		Expect(client.ZeroRTTRejection().Reason).To(Equal(ZeroRTTNotRejected))
+		client.(*cryptoSetup).rejected0RTT()
+		Expect(client.ZeroRTTRejection()).To(Equal(ZeroRTTRejection{Reason: ZeroRTTRejectedByTLS}))
+	})
+
+	Context("0-RTT rejected by the TLS stack on the server", func() {
+		// handshake runs a handshake with a TLS stack that reads the ClientHello, but never asks if 0-RTT should be accepted.
+		// This is what qtls does if it can't decrypt the session ticket.
+		handshake := func(clientHello []byte) ZeroRTTRejection {
+			runner := NewMockHandshakeRunner(mockCtrl)
+			runner.EXPECT().OnHandshakeComplete()
+			server := NewCryptoSetupServer(
+				&bytes.Buffer{},
+				&bytes.Buffer{},
+				protocol.ConnectionID{},
+				nil,
+				nil,
+				&TransportParameters{StatelessResetToken: &[16]byte{}},
+				runner,
+				&tls.Config{},
+				nil,
+				true,
+				nil,
+				nil,
+				&congestion.RTTStats{},
+				utils.DefaultClock{},
+				nil,
+				utils.DefaultLogger.WithPrefix("server"),
+			)
+			server.(*cryptoSetup).conn = &mockTLSConn{
+				handshake: func() error {
+					_, err := server.(*cryptoSetup).ReadHandshakeMessage()
+					return err
+				},
+			}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				server.HandleMessage(clientHello, protocol.EncryptionInitial)
+				close(done)
+			}()
+			server.RunHandshake()
+			Eventually(done).Should(BeClosed())
+			return server.ZeroRTTRejection()
+		}
+
+		It("records the rejection, if the client offered 0-RTT", func() {
+			Expect(handshake(getClientHello(quicTLSExtensionType, extensionEarlyData))).To(Equal(ZeroRTTRejection{Reason: ZeroRTTRejectedByTLS}))
+		})
+
+		It("doesn't record a rejection, if the client didn't offer 0-RTT", func() {
+			Expect(handshake(getClientHello(quicTLSExtensionType)).Reason).To(Equal(ZeroRTTNotRejected))
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package handshake
 
 import (
+	"crypto/tls"
 	"errors"
 	"io"
 	"time"
@@ -24,6 +25,31 @@ var (
 // ConnectionState contains information about the state of the connection.
 type ConnectionState = qtls.ConnectionState
 
+// ZeroRTTRejectionReason is the reason why 0-RTT was rejected.
+type ZeroRTTRejectionReason uint8
+
+const (
+	// ZeroRTTNotRejected is used if 0-RTT was not rejected, or not attempted at all.
+	ZeroRTTNotRejected ZeroRTTRejectionReason = iota
+	// ZeroRTTRejectedByTLS is used if early data was rejected in the TLS handshake.
+	ZeroRTTRejectedByTLS
+	// ZeroRTTRejectedByPolicy is used if the server didn't allow 0-RTT for this client.
+	ZeroRTTRejectedByPolicy
+	// ZeroRTTRejectedTransportParameters is used if the transport parameters
+	// changed since the session ticket was issued.
+	ZeroRTTRejectedTransportParameters
+	// ZeroRTTRejectedInvalidSessionTicket is used if the data stored in the session ticket couldn't be parsed.
+	ZeroRTTRejectedInvalidSessionTicket
+)
+
+// ZeroRTTRejection describes why 0-RTT was rejected.
+type ZeroRTTRejection struct {
+	Reason ZeroRTTRejectionReason
+	// MismatchedTransportParameters are the names of the transport parameters that don't allow 0-RTT.
+	// It is only set if the Reason is ZeroRTTRejectedTransportParameters.
+	MismatchedTransportParameters []string
+}
+
 type headerDecryptor interface {
 	DecryptHeader(sample []byte, firstByte *byte, pnBytes []byte)
 }
@@ -60,6 +86,56 @@ type tlsExtensionHandler interface {
 	TransportParameters() <-chan []byte
 }
 
+// TLSEncryptionLevel is the encryption level at which a TLSConn exchanges handshake messages and keys.
+type TLSEncryptionLevel uint8
+
+const (
+	// TLSEncryptionInitial is the Initial encryption level.
+	// Its keys are derived by QUIC, they are never installed by the TLS stack.
+	TLSEncryptionInitial TLSEncryptionLevel = iota
+	// TLSEncryptionHandshake is the Handshake encryption level.
+	TLSEncryptionHandshake
+	// TLSEncryptionApplication is the 1-RTT encryption level.
+	TLSEncryptionApplication
+)
+
+// A TLSRecordLayer is implemented by QUIC and passed to the TLS stack when it is created.
+// QUIC doesn't use TLS records, so the TLS stack uses it to exchange handshake messages and keys instead.
+type TLSRecordLayer interface {
+	// ReadHandshakeMessage blocks until the next handshake message from the peer is available.
+	ReadHandshakeMessage() ([]byte, error)
+	// WriteHandshakeMessage sends a handshake message.
+	// The encryption level must be the level of the most recent write key (Initial before any key was installed).
+	WriteHandshakeMessage(TLSEncryptionLevel, []byte) error
+	// SetReadKey installs the traffic secret used to read messages and packets at an encryption level.
+	// The cipher suite is one of the TLS 1.3 cipher suite IDs defined in crypto/tls.
+	SetReadKey(encLevel TLSEncryptionLevel, cipherSuite uint16, trafficSecret []byte) error
+	// SetWriteKey installs the traffic secret used to write messages and packets at an encryption level.
+	SetWriteKey(encLevel TLSEncryptionLevel, cipherSuite uint16, trafficSecret []byte) error
+	// ReceivedTransportParameters passes the content of the peer's quic_transport_parameters extension to QUIC.
+	ReceivedTransportParameters([]byte)
+	// SendAlert reports a fatal handshake error.
+	SendAlert(alert uint8)
+}
+
+// A TLSConn is a TLS 1.3 stack used to perform the handshake.
+// The contract it has to fulfill is documented on quic.TLSConn.
+type TLSConn interface {
+	// Handshake runs the handshake.
+	// It returns when the handshake completes or fails.
+	Handshake() error
+	// HandlePostHandshakeMessage handles a handshake message received after the handshake completed.
+	// The message is read using ReadHandshakeMessage.
+	HandlePostHandshakeMessage() error
+	// GetSessionTicket returns a session ticket containing appData. It is only called on servers.
+	GetSessionTicket(appData []byte) ([]byte, error)
+	ConnectionState() ConnectionState
+}
+
+// NewTLSConnFunc creates a TLSConn.
+// The transport parameters are marshaled, they must be sent to the peer as they are.
+type NewTLSConnFunc func(conf *tls.Config, recordLayer TLSRecordLayer, transportParameters []byte, isClient bool) TLSConn
+
 type handshakeRunner interface {
 	OnReceivedParams(*TransportParameters)
 	OnHandshakeComplete()
@@ -78,6 +154,9 @@ type CryptoSetup interface {
 	SetLargest1RTTAcked(protocol.PacketNumber)
 	DropHandshakeKeys()
 	ConnectionState() ConnectionState
+	NumKeyUpdates() uint64
+	UsedHelloRetryRequest() bool
+	ZeroRTTRejection() ZeroRTTRejection
 
 	GetInitialOpener() (LongHeaderOpener, error)
 	GetHandshakeOpener() (LongHeaderOpener, error)
@@ -0,0 +1,162 @@
+package handshake
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const exportedSessionStateRevision = 1
+
+// MarshalClientSessionState serializes a session state, as it is stored in the tls.ClientSessionCache.
+// Besides the TLS session ticket, it contains the server's transport parameters and the RTT,
+// which are needed to send 0-RTT data.
+func MarshalClientSessionState(cs *tls.ClientSessionState) ([]byte, error) {
+	if cs == nil {
+		return nil, errors.New("no session state")
+	}
+	// qtls.ClientSessionState is identical to the tls.ClientSessionState.
+	// In unsafe.go we check that the two structs are actually identical.
+	session := (*clientSessionState)(unsafe.Pointer(cs))
+	b := &bytes.Buffer{}
+	utils.WriteVarInt(b, exportedSessionStateRevision)
+	utils.WriteVarInt(b, uint64(session.vers))
+	utils.WriteVarInt(b, uint64(session.cipherSuite))
+	writeBytes(b, session.sessionTicket)
+	writeBytes(b, session.masterSecret)
+	writeBytes(b, session.nonce)
+	utils.WriteVarInt(b, uint64(session.receivedAt.UnixNano()))
+	utils.WriteVarInt(b, uint64(session.useBy.UnixNano()))
+	utils.WriteVarInt(b, uint64(session.ageAdd))
+	writeCertificates(b, session.serverCertificates)
+	utils.WriteVarInt(b, uint64(len(session.verifiedChains)))
+	for _, chain := range session.verifiedChains {
+		writeCertificates(b, chain)
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalClientSessionState parses a session state serialized by MarshalClientSessionState.
+func UnmarshalClientSessionState(data []byte) (*tls.ClientSessionState, error) {
+	r := bytes.NewReader(data)
+	rev, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, errors.New("failed to read session state revision")
+	}
+	if rev != exportedSessionStateRevision {
+		return nil, fmt.Errorf("unknown session state revision: %d", rev)
+	}
+	var session clientSessionState
+	vers, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	session.vers = uint16(vers)
+	cipherSuite, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	session.cipherSuite = uint16(cipherSuite)
+	if session.sessionTicket, err = readBytes(r); err != nil {
+		return nil, err
+	}
+	if session.masterSecret, err = readBytes(r); err != nil {
+		return nil, err
+	}
+	if session.nonce, err = readBytes(r); err != nil {
+		return nil, err
+	}
+	receivedAt, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	session.receivedAt = time.Unix(0, int64(receivedAt))
+	useBy, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	session.useBy = time.Unix(0, int64(useBy))
+	ageAdd, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	session.ageAdd = uint32(ageAdd)
+	if session.serverCertificates, err = readCertificates(r); err != nil {
+		return nil, err
+	}
+	numChains, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if numChains > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	for i := uint64(0); i < numChains; i++ {
+		chain, err := readCertificates(r)
+		if err != nil {
+			return nil, err
+		}
+		session.verifiedChains = append(session.verifiedChains, chain)
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("session state has trailing data")
+	}
+	return (*tls.ClientSessionState)(unsafe.Pointer(&session)), nil
+}
+
+func writeBytes(b *bytes.Buffer, data []byte) {
+	utils.WriteVarInt(b, uint64(len(data)))
+	b.Write(data)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	l, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	data := make([]byte, l)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func writeCertificates(b *bytes.Buffer, certs []*x509.Certificate) {
+	utils.WriteVarInt(b, uint64(len(certs)))
+	for _, cert := range certs {
+		writeBytes(b, cert.Raw)
+	}
+}
+
+func readCertificates(r *bytes.Reader) ([]*x509.Certificate, error) {
+	num, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if num > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	certs := make([]*x509.Certificate, 0, num)
+	for i := uint64(0); i < num; i++ {
+		raw, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
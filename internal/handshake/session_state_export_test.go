@@ -0,0 +1,81 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exported session states", func() {
+	var session *clientSessionState
+
+	BeforeEach(func() {
+		cert, err := x509.ParseCertificate(testdata.GetTLSConfig().Certificates[0].Certificate[0])
+		Expect(err).ToNot(HaveOccurred())
+		session = &clientSessionState{
+			sessionTicket:      []byte("ticket"),
+			vers:               tls.VersionTLS13,
+			cipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+			masterSecret:       []byte("secret"),
+			serverCertificates: []*x509.Certificate{cert},
+			verifiedChains:     [][]*x509.Certificate{{cert}, {cert, cert}},
+			receivedAt:         time.Now().Add(-time.Minute),
+			nonce:              []byte("nonce"),
+			useBy:              time.Now().Add(time.Hour),
+			ageAdd:             1337,
+		}
+	})
+
+	It("marshals and unmarshals", func() {
+		data, err := MarshalClientSessionState((*tls.ClientSessionState)(unsafe.Pointer(session)))
+		Expect(err).ToNot(HaveOccurred())
+		cs, err := UnmarshalClientSessionState(data)
+		Expect(err).ToNot(HaveOccurred())
+		s := (*clientSessionState)(unsafe.Pointer(cs))
+		Expect(s.sessionTicket).To(Equal(session.sessionTicket))
+		Expect(s.vers).To(Equal(session.vers))
+		Expect(s.cipherSuite).To(Equal(session.cipherSuite))
+		Expect(s.masterSecret).To(Equal(session.masterSecret))
+		Expect(s.nonce).To(Equal(session.nonce))
+		Expect(s.receivedAt).To(BeTemporally("==", session.receivedAt))
+		Expect(s.useBy).To(BeTemporally("==", session.useBy))
+		Expect(s.ageAdd).To(Equal(session.ageAdd))
+		Expect(s.serverCertificates).To(HaveLen(1))
+		Expect(s.serverCertificates[0].Equal(session.serverCertificates[0])).To(BeTrue())
+		Expect(s.verifiedChains).To(HaveLen(2))
+		Expect(s.verifiedChains[0]).To(HaveLen(1))
+		Expect(s.verifiedChains[1]).To(HaveLen(2))
+	})
+
+	It("refuses to marshal nil session states", func() {
+		_, err := MarshalClientSessionState(nil)
+		Expect(err).To(MatchError("no session state"))
+	})
+
+	It("errors on unknown revisions", func() {
+		_, err := UnmarshalClientSessionState([]byte{0x2a})
+		Expect(err).To(MatchError("unknown session state revision: 42"))
+	})
+
+	It("errors on EOFs", func() {
+		data, err := MarshalClientSessionState((*tls.ClientSessionState)(unsafe.Pointer(session)))
+		Expect(err).ToNot(HaveOccurred())
+		for i := range data {
+			_, err := UnmarshalClientSessionState(data[:i])
+			Expect(err).To(HaveOccurred())
+		}
+	})
+
+	It("errors on trailing data", func() {
+		data, err := MarshalClientSessionState((*tls.ClientSessionState)(unsafe.Pointer(session)))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = UnmarshalClientSessionState(append(data, 0))
+		Expect(err).To(MatchError("session state has trailing data"))
+	})
+})
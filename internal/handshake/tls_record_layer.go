@@ -0,0 +1,95 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/marten-seemann/qtls"
+)
+
+// The tlsRecordLayer is passed to a TLSConn.
+// It translates the calls of the TLS stack to the qtls.RecordLayer implemented by the cryptoSetup.
+type tlsRecordLayer struct {
+	cs         *cryptoSetup
+	paramsChan chan<- []byte
+}
+
+var _ TLSRecordLayer = &tlsRecordLayer{}
+
+// createTLSConn creates an external TLS stack using newTLSConn.
+// The cryptoSetup then receives the peer's transport parameters from this TLS stack.
+func (h *cryptoSetup) createTLSConn(newTLSConn NewTLSConnFunc, conf *tls.Config, params []byte) TLSConn {
+	paramsChan := make(chan []byte)
+	h.paramsChan = paramsChan
+	if conf == nil {
+		conf = &tls.Config{}
+	}
+	rl := &tlsRecordLayer{cs: h, paramsChan: paramsChan}
+	return newTLSConn(conf.Clone(), rl, params, h.perspective == protocol.PerspectiveClient)
+}
+
+func (r *tlsRecordLayer) ReadHandshakeMessage() ([]byte, error) {
+	return r.cs.ReadHandshakeMessage()
+}
+
+func (r *tlsRecordLayer) WriteHandshakeMessage(encLevel TLSEncryptionLevel, msg []byte) error {
+	r.cs.mutex.Lock()
+	writeEncLevel := r.cs.writeEncLevel
+	r.cs.mutex.Unlock()
+
+	var ok bool
+	switch encLevel {
+	case TLSEncryptionInitial:
+		ok = writeEncLevel == protocol.EncryptionInitial
+	case TLSEncryptionHandshake:
+		ok = writeEncLevel == protocol.EncryptionHandshake
+	}
+	if !ok {
+		return fmt.Errorf("cannot write a handshake message at encryption level %d (current level: %s)", encLevel, writeEncLevel)
+	}
+	_, err := r.cs.WriteRecord(msg)
+	return err
+}
+
+func (r *tlsRecordLayer) SetReadKey(encLevel TLSEncryptionLevel, cipherSuite uint16, trafficSecret []byte) error {
+	level, suite, err := getKeyParams(encLevel, cipherSuite)
+	if err != nil {
+		return err
+	}
+	r.cs.SetReadKey(level, suite, trafficSecret)
+	return nil
+}
+
+func (r *tlsRecordLayer) SetWriteKey(encLevel TLSEncryptionLevel, cipherSuite uint16, trafficSecret []byte) error {
+	level, suite, err := getKeyParams(encLevel, cipherSuite)
+	if err != nil {
+		return err
+	}
+	r.cs.SetWriteKey(level, suite, trafficSecret)
+	return nil
+}
+
+func getKeyParams(encLevel TLSEncryptionLevel, cipherSuite uint16) (qtls.EncryptionLevel, *qtls.CipherSuiteTLS13, error) {
+	suite := cipherSuiteTLS13ByID(cipherSuite)
+	if suite == nil {
+		return 0, nil, fmt.Errorf("unsupported cipher suite: %#x", cipherSuite)
+	}
+	switch encLevel {
+	case TLSEncryptionHandshake:
+		return qtls.EncryptionHandshake, suite, nil
+	case TLSEncryptionApplication:
+		return qtls.EncryptionApplication, suite, nil
+	default:
+		return 0, nil, errors.New("keys can only be installed for the Handshake and the 1-RTT encryption level")
+	}
+}
+
+func (r *tlsRecordLayer) ReceivedTransportParameters(data []byte) {
+	r.paramsChan <- data
+}
+
+func (r *tlsRecordLayer) SendAlert(alert uint8) {
+	r.cs.SendAlert(alert)
+}
@@ -3,13 +3,16 @@ package handshake
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"net"
+	"sort"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/qlog"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -71,10 +74,13 @@ var _ = Describe("Transport Parameters", func() {
 			MaxBidiStreamNum:               protocol.StreamNum(getRandomValue()),
 			MaxUniStreamNum:                protocol.StreamNum(getRandomValue()),
 			DisableActiveMigration:         true,
+			EnableResetStreamAt:            true,
+			GreaseQUICBit:                  true,
 			StatelessResetToken:            &token,
 			OriginalConnectionID:           protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
 			AckDelayExponent:               13,
 			MaxAckDelay:                    42 * time.Millisecond,
+			MinAckDelay:                    1337 * time.Microsecond,
 			ActiveConnectionIDLimit:        getRandomValue(),
 		}
 		data := params.Marshal()
@@ -89,13 +95,209 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxBidiStreamNum).To(Equal(params.MaxBidiStreamNum))
 		Expect(p.MaxIdleTimeout).To(Equal(params.MaxIdleTimeout))
 		Expect(p.DisableActiveMigration).To(Equal(params.DisableActiveMigration))
+		Expect(p.EnableResetStreamAt).To(Equal(params.EnableResetStreamAt))
+		Expect(p.GreaseQUICBit).To(Equal(params.GreaseQUICBit))
 		Expect(p.StatelessResetToken).To(Equal(params.StatelessResetToken))
 		Expect(p.OriginalConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}))
 		Expect(p.AckDelayExponent).To(Equal(uint8(13)))
 		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
+		Expect(p.MinAckDelay).To(Equal(1337 * time.Microsecond))
 		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
 	})
 
+	It("saves unknown transport parameters", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, 0x1337)
+		utils.WriteVarInt(b, 6)
+		b.Write([]byte("foobar"))
+		(&TransportParameters{}).marshalVarintParam(b, initialMaxDataParameterID, 0x42)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.InitialMaxData).To(BeEquivalentTo(0x42))
+		Expect(p.UnknownParameters).To(Equal([]UnknownTransportParameter{{ID: 0x1337, Value: []byte("foobar")}}))
+	})
+
+	It("saves the greased transport parameter", func() {
+		p := &TransportParameters{}
+		Expect(p.Unmarshal((&TransportParameters{}).Marshal(), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.UnknownParameters).To(HaveLen(1))
+		Expect(p.UnknownParameters[0].ID % 31).To(BeEquivalentTo(27))
+		Expect(p.UnknownParameters[0].Grease).To(BeTrue())
+	})
+
+	It("identifies greased transport parameter IDs", func() {
+		for _, t := range []struct {
+			id     uint64
+			grease bool
+		}{
+			{id: 27, grease: true},
+			{id: 58, grease: true},
+			{id: 31*1000 + 27, grease: true},
+			{id: greaseID(1 << 40), grease: true},
+			{id: 0, grease: false},
+			{id: 26, grease: false},
+			{id: 28, grease: false},
+			{id: 31, grease: false},
+			{id: uint64(initialMaxDataParameterID), grease: false},
+			{id: uint64(activeConnectionIDLimitParameterID), grease: false},
+			{id: uint64(greaseQUICBitParameterID), grease: false},
+		} {
+			Expect(isGreaseID(t.id)).To(Equal(t.grease), fmt.Sprintf("ID %d", t.id))
+		}
+		for n := uint64(0); n < 100; n++ {
+			Expect(isGreaseID(greaseID(n))).To(BeTrue())
+		}
+	})
+
+	It("classifies unknown transport parameters as greased", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, 0x1337)
+		utils.WriteVarInt(b, 0)
+		utils.WriteVarInt(b, greaseID(42))
+		utils.WriteVarInt(b, 3)
+		b.Write([]byte("foo"))
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.UnknownParameters).To(Equal([]UnknownTransportParameter{
+			{ID: 0x1337, Value: []byte{}},
+			{ID: greaseID(42), Value: []byte("foo"), Grease: true},
+		}))
+	})
+
+	Context("ordering", func() {
+		// parameterIDs returns the IDs of the marshaled transport parameters, in the order they were marshaled
+		parameterIDs := func(data []byte) []uint64 {
+			var ids []uint64
+			r := bytes.NewReader(data)
+			for r.Len() > 0 {
+				id, err := utils.ReadVarInt(r)
+				Expect(err).ToNot(HaveOccurred())
+				l, err := utils.ReadVarInt(r)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = r.Seek(int64(l), io.SeekCurrent)
+				Expect(err).ToNot(HaveOccurred())
+				ids = append(ids, id)
+			}
+			return ids
+		}
+
+		params := &TransportParameters{
+			InitialMaxStreamDataBidiLocal: 0x1234,
+			InitialMaxData:                0x4321,
+			MaxIdleTimeout:                42 * time.Second,
+			StatelessResetToken:           &[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			ActiveConnectionIDLimit:       8,
+		}
+
+		It("uses the default order", func() {
+			ids := parameterIDs(params.Marshal())
+			Expect(ids[0] % 31).To(BeEquivalentTo(27)) // the greased parameter
+			Expect(ids[1:4]).To(Equal([]uint64{
+				uint64(initialMaxStreamDataBidiLocalParameterID),
+				uint64(initialMaxStreamDataBidiRemoteParameterID),
+				uint64(initialMaxStreamDataUniParameterID),
+			}))
+			Expect(ids[len(ids)-1]).To(BeEquivalentTo(activeConnectionIDLimitParameterID))
+		})
+
+		It("uses a custom order", func() {
+			p := *params
+			p.ParameterOrder = []uint64{
+				uint64(activeConnectionIDLimitParameterID),
+				uint64(statelessResetTokenParameterID),
+				0x1337, // not sent
+				uint64(initialMaxDataParameterID),
+			}
+			data := p.Marshal()
+			ids := parameterIDs(data)
+			Expect(ids[0] % 31).To(BeEquivalentTo(27)) // the greased parameter
+			Expect(ids[1:4]).To(Equal([]uint64{
+				uint64(activeConnectionIDLimitParameterID),
+				uint64(statelessResetTokenParameterID),
+				uint64(initialMaxDataParameterID),
+			}))
+			// all other parameters are sent in the default order
+			var otherIDs []uint64
+			for _, id := range parameterIDs(params.Marshal())[1:] {
+				switch transportParameterID(id) {
+				case activeConnectionIDLimitParameterID, statelessResetTokenParameterID, initialMaxDataParameterID:
+				default:
+					otherIDs = append(otherIDs, id)
+				}
+			}
+			Expect(ids[4:]).To(Equal(otherIDs))
+			// check that the parameters can still be parsed
+			tp := &TransportParameters{}
+			Expect(tp.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(tp.InitialMaxStreamDataBidiLocal).To(Equal(params.InitialMaxStreamDataBidiLocal))
+			Expect(tp.InitialMaxData).To(Equal(params.InitialMaxData))
+			Expect(tp.MaxIdleTimeout).To(Equal(params.MaxIdleTimeout))
+			Expect(tp.StatelessResetToken).To(Equal(params.StatelessResetToken))
+			Expect(tp.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+		})
+
+		It("marshals in a canonical form", func() {
+			p := *params
+			p.ParameterOrder = []uint64{uint64(activeConnectionIDLimitParameterID)}
+			data := p.MarshalCanonical()
+			Expect(p.MarshalCanonical()).To(Equal(data))
+			Expect(params.MarshalCanonical()).To(Equal(data))
+			ids := parameterIDs(data)
+			Expect(ids).To(HaveLen(len(parameterIDs(params.Marshal())) - 1)) // no greased parameter
+			Expect(sort.SliceIsSorted(ids, func(i, j int) bool { return ids[i] < ids[j] })).To(BeTrue())
+			// check that the parameters can still be parsed
+			tp := &TransportParameters{}
+			Expect(tp.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(tp.UnknownParameters).To(BeEmpty())
+			Expect(tp.InitialMaxStreamDataBidiLocal).To(Equal(params.InitialMaxStreamDataBidiLocal))
+			Expect(tp.InitialMaxData).To(Equal(params.InitialMaxData))
+			Expect(tp.MaxIdleTimeout).To(Equal(params.MaxIdleTimeout))
+			Expect(tp.StatelessResetToken).To(Equal(params.StatelessResetToken))
+			Expect(tp.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+		})
+	})
+
+	It("converts to the qlog representation", func() {
+		token := [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+		p := &TransportParameters{
+			InitialMaxData:          0x1337,
+			MaxBidiStreamNum:        42,
+			MaxUniStreamNum:         43,
+			MaxIdleTimeout:          time.Minute,
+			StatelessResetToken:     &token,
+			OriginalConnectionID:    protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
+			PreferredAddress:        &PreferredAddress{IPv4Port: 42, ConnectionID: protocol.ConnectionID{1, 2, 3, 4}},
+			ActiveConnectionIDLimit: 7,
+			UnknownParameters:       []UnknownTransportParameter{{ID: 0x1337, Value: []byte("foobar")}},
+		}
+		tp := p.toQlog()
+		Expect(tp.InitialMaxData).To(Equal(protocol.ByteCount(0x1337)))
+		Expect(tp.InitialMaxStreamsBidi).To(BeEquivalentTo(42))
+		Expect(tp.InitialMaxStreamsUni).To(BeEquivalentTo(43))
+		Expect(tp.MaxIdleTimeout).To(Equal(time.Minute))
+		Expect(tp.StatelessResetToken).To(Equal(&token))
+		Expect(tp.OriginalConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}))
+		Expect(tp.PreferredAddress.IPv4Port).To(BeEquivalentTo(42))
+		Expect(tp.PreferredAddress.ConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(tp.ActiveConnectionIDLimit).To(BeEquivalentTo(7))
+		Expect(tp.UnknownParameters).To(Equal([]qlog.UnknownTransportParameter{{ID: 0x1337, Value: []byte("foobar")}}))
+	})
+
+	It("doesn't send min_ack_delay, if the ACK Frequency extension is not supported", func() {
+		p := &TransportParameters{}
+		Expect(p.Unmarshal((&TransportParameters{}).Marshal(), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MinAckDelay).To(BeZero())
+	})
+
+	It("errors when the min_ack_delay is larger than the max_ack_delay", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, uint64(minAckDelayParameterID))
+		utils.WriteVarInt(b, uint64(utils.VarIntLen(30000)))
+		utils.WriteVarInt(b, 30000)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: min_ack_delay (30ms) is larger than max_ack_delay (25ms)"))
+	})
+
 	It("errors when the stateless_reset_token has the wrong length", func() {
 		b := &bytes.Buffer{}
 		utils.WriteVarInt(b, uint64(statelessResetTokenParameterID))
@@ -114,6 +316,30 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for max_packet_size: 1199 (minimum 1200)"))
 	})
 
+	It("sends the max_packet_size", func() {
+		p := &TransportParameters{}
+		Expect(p.Unmarshal((&TransportParameters{}).Marshal(), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxPacketSize).To(Equal(protocol.MaxReceivePacketSize))
+	})
+
+	It("doesn't send the max_packet_size, if it is omitted", func() {
+		p := &TransportParameters{}
+		Expect(p.Unmarshal((&TransportParameters{OmitMaxPacketSize: true}).Marshal(), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxPacketSize).To(Equal(protocol.MaxByteCount))
+	})
+
+	It("doesn't send the stateless_reset_token when using a zero-length connection ID", func() {
+		token := [16]byte{0xde, 0xca, 0xfb, 0xad}
+		p := &TransportParameters{}
+		Expect(p.Unmarshal((&TransportParameters{StatelessResetToken: &token}).Marshal(), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.StatelessResetToken).To(Equal(&token))
+		p = &TransportParameters{}
+		data := (&TransportParameters{StatelessResetToken: &token, ZeroLengthConnectionID: true}).Marshal()
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.StatelessResetToken).To(BeNil())
+		Expect((&TransportParameters{StatelessResetToken: &token, ZeroLengthConnectionID: true}).toQlog().StatelessResetToken).To(BeNil())
+	})
+
 	It("errors when disable_active_migration has content", func() {
 		b := &bytes.Buffer{}
 		utils.WriteVarInt(b, uint64(disableActiveMigrationParameterID))
@@ -123,6 +349,38 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for disable_active_migration: 6 (expected empty)"))
 	})
 
+	It("errors when reset_stream_at has content", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, uint64(resetStreamAtParameterID))
+		utils.WriteVarInt(b, 6)
+		b.Write([]byte("foobar"))
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for reset_stream_at: 6 (expected empty)"))
+	})
+
+	It("doesn't send reset_stream_at, if it's not enabled", func() {
+		data := (&TransportParameters{}).Marshal()
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.EnableResetStreamAt).To(BeFalse())
+	})
+
+	It("errors when grease_quic_bit has content", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, uint64(greaseQUICBitParameterID))
+		utils.WriteVarInt(b, 6)
+		b.Write([]byte("foobar"))
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for grease_quic_bit: 6 (expected empty)"))
+	})
+
+	It("doesn't send grease_quic_bit, if it's not enabled", func() {
+		data := (&TransportParameters{}).Marshal()
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.GreaseQUICBit).To(BeFalse())
+	})
+
 	It("errors when the max_ack_delay is too large", func() {
 		data := (&TransportParameters{MaxAckDelay: 1 << 14 * time.Millisecond}).Marshal()
 		p := &TransportParameters{}
@@ -281,6 +539,20 @@ var _ = Describe("Transport Parameters", func() {
 			Expect(p.PreferredAddress.StatelessResetToken).To(Equal(pa.StatelessResetToken))
 		})
 
+		It("accepts a preferred_address together with disable_active_migration", func() {
+			data := (&TransportParameters{
+				PreferredAddress:       pa,
+				DisableActiveMigration: true,
+			}).Marshal()
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(p.DisableActiveMigration).To(BeTrue())
+			Expect(p.PreferredAddress).ToNot(BeNil())
+			Expect(p.PreferredAddress.IPv4.String()).To(Equal(pa.IPv4.String()))
+			Expect(p.PreferredAddress.IPv4Port).To(Equal(pa.IPv4Port))
+			Expect(p.PreferredAddress.ConnectionID).To(Equal(pa.ConnectionID))
+		})
+
 		It("errors if the client sent a preferred_address", func() {
 			data := (&TransportParameters{PreferredAddress: pa}).Marshal()
 			p := &TransportParameters{}
@@ -317,6 +589,7 @@ var _ = Describe("Transport Parameters", func() {
 				MaxBidiStreamNum:               protocol.StreamNum(getRandomValue()),
 				MaxUniStreamNum:                protocol.StreamNum(getRandomValue()),
 				ActiveConnectionIDLimit:        getRandomValue(),
+				MaxPacketSize:                  1200 + protocol.ByteCount(getRandomValue()),
 			}
 			Expect(params.ValidFor0RTT(params)).To(BeTrue())
 			b := &bytes.Buffer{}
@@ -330,6 +603,7 @@ var _ = Describe("Transport Parameters", func() {
 			Expect(tp.MaxBidiStreamNum).To(Equal(params.MaxBidiStreamNum))
 			Expect(tp.MaxUniStreamNum).To(Equal(params.MaxUniStreamNum))
 			Expect(tp.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+			Expect(tp.MaxPacketSize).To(Equal(params.MaxPacketSize))
 		})
 
 		It("rejects the parameters if it can't parse them", func() {
@@ -400,6 +674,24 @@ var _ = Describe("Transport Parameters", func() {
 				p.MaxUniStreamNum = 0
 				Expect(params.ValidFor0RTT(p)).To(BeFalse())
 			})
+
+			It("rejects the parameters if the MaxPacketSize shrunk", func() {
+				p.MaxPacketSize = protocol.MaxReceivePacketSize + 1
+				Expect(params.ValidFor0RTT(p)).To(BeFalse())
+			})
+
+			It("accepts the parameters if the MaxPacketSize grew", func() {
+				p.MaxPacketSize = protocol.MaxReceivePacketSize - 1
+				Expect(params.ValidFor0RTT(p)).To(BeTrue())
+			})
+
+			It("names the parameters that changed", func() {
+				Expect(params.ZeroRTTMismatches(p)).To(BeEmpty())
+				p.InitialMaxData = 0
+				p.MaxUniStreamNum = 0
+				p.MaxPacketSize = protocol.MaxReceivePacketSize + 1
+				Expect(params.ZeroRTTMismatches(p)).To(Equal([]string{"initial_max_data", "initial_max_streams_uni", "max_packet_size"}))
+			})
 		})
 	})
 })
@@ -14,9 +14,10 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/qlog"
 )
 
-const transportParameterMarshalingVersion = 1
+const transportParameterMarshalingVersion = 2
 
 func init() {
 	rand.Seed(time.Now().UTC().UnixNano())
@@ -40,6 +41,12 @@ const (
 	disableActiveMigrationParameterID         transportParameterID = 0xc
 	preferredAddressParameterID               transportParameterID = 0xd
 	activeConnectionIDLimitParameterID        transportParameterID = 0xe
+	// https://datatracker.ietf.org/doc/draft-ietf-quic-reliable-stream-reset/
+	resetStreamAtParameterID transportParameterID = 0x17f7586d2cb571
+	// https://datatracker.ietf.org/doc/draft-thomson-quic-bit-grease/
+	greaseQUICBitParameterID transportParameterID = 0x2ab2
+	// https://datatracker.ietf.org/doc/draft-iyengar-quic-delayed-ack/
+	minAckDelayParameterID transportParameterID = 0xff02de1a
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -61,10 +68,20 @@ type TransportParameters struct {
 
 	MaxAckDelay      time.Duration
 	AckDelayExponent uint8
+	// MinAckDelay is the min_ack_delay of the ACK Frequency extension.
+	// If zero, the extension is not supported.
+	MinAckDelay time.Duration
 
 	DisableActiveMigration bool
 
+	EnableResetStreamAt bool
+
+	GreaseQUICBit bool
+
 	MaxPacketSize protocol.ByteCount
+	// OmitMaxPacketSize prevents the max_packet_size from being sent.
+	// The peer then uses the default value.
+	OmitMaxPacketSize bool
 
 	MaxUniStreamNum  protocol.StreamNum
 	MaxBidiStreamNum protocol.StreamNum
@@ -76,6 +93,42 @@ type TransportParameters struct {
 	StatelessResetToken     *[16]byte
 	OriginalConnectionID    protocol.ConnectionID
 	ActiveConnectionIDLimit uint64
+
+	// ZeroLengthConnectionID is set if the sender of the transport parameters uses a zero-length connection ID.
+	// Marshal then omits the StatelessResetToken, since the peer can't use it,
+	// and some peers reject a stateless_reset_token in that case.
+	ZeroLengthConnectionID bool
+
+	// UnknownParameters are the transport parameters we don't know, including greased ones.
+	// They are only set when unmarshaling, and never marshaled.
+	UnknownParameters []UnknownTransportParameter
+
+	// ParameterOrder is the order (a list of transport parameter IDs) in which Marshal emits the known parameters.
+	// Parameters that are not contained in the list are emitted afterwards, in the default order.
+	// The greased parameter is always emitted first.
+	// It is only needed to interoperate with peers that (incorrectly) require a specific ordering.
+	ParameterOrder []uint64
+}
+
+// An UnknownTransportParameter is a transport parameter we don't know.
+type UnknownTransportParameter struct {
+	ID    uint64
+	Value []byte
+	// Grease is set if the ID is reserved for greasing.
+	// Greased parameters carry no meaning and are ignored.
+	Grease bool
+}
+
+// isGreaseID says if a transport parameter ID is reserved for greasing.
+// Reserved IDs are of the form 31 * N + 27.
+// Known IDs take precedence: the ID of min_ack_delay happens to be of that form as well.
+func isGreaseID(id uint64) bool {
+	return id%31 == 27
+}
+
+// greaseID returns the n-th transport parameter ID reserved for greasing.
+func greaseID(n uint64) uint64 {
+	return 31*n + 27
 }
 
 // Unmarshal the transport parameters
@@ -124,7 +177,8 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 			initialMaxStreamsUniParameterID,
 			maxIdleTimeoutParameterID,
 			maxPacketSizeParameterID,
-			activeConnectionIDLimitParameterID:
+			activeConnectionIDLimitParameterID,
+			minAckDelayParameterID:
 			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
 				return err
 			}
@@ -145,6 +199,16 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 					return fmt.Errorf("wrong length for disable_active_migration: %d (expected empty)", paramLen)
 				}
 				p.DisableActiveMigration = true
+			case resetStreamAtParameterID:
+				if paramLen != 0 {
+					return fmt.Errorf("wrong length for reset_stream_at: %d (expected empty)", paramLen)
+				}
+				p.EnableResetStreamAt = true
+			case greaseQUICBitParameterID:
+				if paramLen != 0 {
+					return fmt.Errorf("wrong length for grease_quic_bit: %d (expected empty)", paramLen)
+				}
+				p.GreaseQUICBit = true
 			case statelessResetTokenParameterID:
 				if sentBy == protocol.PerspectiveClient {
 					return errors.New("client sent a stateless_reset_token")
@@ -161,7 +225,13 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 				}
 				p.OriginalConnectionID, _ = protocol.ReadConnectionID(r, int(paramLen))
 			default:
-				r.Seek(int64(paramLen), io.SeekCurrent)
+				value := make([]byte, paramLen)
+				r.Read(value)
+				p.UnknownParameters = append(p.UnknownParameters, UnknownTransportParameter{
+					ID:     uint64(paramID),
+					Value:  value,
+					Grease: isGreaseID(uint64(paramID)),
+				})
 			}
 		}
 	}
@@ -175,6 +245,9 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 	if p.MaxPacketSize == 0 {
 		p.MaxPacketSize = protocol.MaxByteCount
 	}
+	if p.MinAckDelay > p.MaxAckDelay {
+		return fmt.Errorf("min_ack_delay (%s) is larger than max_ack_delay (%s)", p.MinAckDelay, p.MaxAckDelay)
+	}
 
 	// check that every transport parameter was sent at most once
 	sort.Slice(parameterIDs, func(i, j int) bool { return parameterIDs[i] < parameterIDs[j] })
@@ -278,6 +351,12 @@ func (p *TransportParameters) readNumericTransportParameter(
 		p.MaxAckDelay = maxAckDelay
 	case activeConnectionIDLimitParameterID:
 		p.ActiveConnectionIDLimit = val
+	case minAckDelayParameterID:
+		minAckDelay := time.Duration(val) * time.Microsecond
+		if minAckDelay < 0 {
+			minAckDelay = utils.InfDuration
+		}
+		p.MinAckDelay = minAckDelay
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -289,13 +368,39 @@ func (p *TransportParameters) Marshal() []byte {
 	b := &bytes.Buffer{}
 
 	//add a greased value
-	utils.WriteVarInt(b, uint64(27+31*rand.Intn(100)))
+	utils.WriteVarInt(b, greaseID(uint64(rand.Intn(100))))
 	length := rand.Intn(16)
 	randomData := make([]byte, length)
 	rand.Read(randomData)
 	utils.WriteVarInt(b, uint64(length))
 	b.Write(randomData)
+	start := b.Len()
 
+	p.marshalParameters(b)
+
+	data := b.Bytes()
+	if len(p.ParameterOrder) > 0 {
+		copy(data[start:], reorderParameters(data[start:], p.ParameterOrder))
+	}
+	return data
+}
+
+// MarshalCanonical marshals the transport parameters in a canonical form.
+// No greased parameter is added, ParameterOrder is ignored, and the parameters are sorted by their ID.
+// Marshaling the same transport parameters therefore always results in the same bytes,
+// which is useful for reproducible handshakes (e.g. in tests and for fuzzing corpora).
+func (p *TransportParameters) MarshalCanonical() []byte {
+	b := &bytes.Buffer{}
+	p.marshalParameters(b)
+	data := b.Bytes()
+	ids, _ := splitParameters(data)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return reorderParameters(data, ids)
+}
+
+// marshalParameters writes all known transport parameters, in the default order.
+// All varints are encoded using the minimal length.
+func (p *TransportParameters) marshalParameters(b *bytes.Buffer) {
 	// initial_max_stream_data_bidi_local
 	p.marshalVarintParam(b, initialMaxStreamDataBidiLocalParameterID, uint64(p.InitialMaxStreamDataBidiLocal))
 	// initial_max_stream_data_bidi_remote
@@ -311,7 +416,9 @@ func (p *TransportParameters) Marshal() []byte {
 	// idle_timeout
 	p.marshalVarintParam(b, maxIdleTimeoutParameterID, uint64(p.MaxIdleTimeout/time.Millisecond))
 	// max_packet_size
-	p.marshalVarintParam(b, maxPacketSizeParameterID, uint64(protocol.MaxReceivePacketSize))
+	if !p.OmitMaxPacketSize {
+		p.marshalVarintParam(b, maxPacketSizeParameterID, uint64(protocol.MaxReceivePacketSize))
+	}
 	// max_ack_delay
 	// Only send it if is different from the default value.
 	if p.MaxAckDelay != protocol.DefaultMaxAckDelay {
@@ -327,10 +434,25 @@ func (p *TransportParameters) Marshal() []byte {
 		utils.WriteVarInt(b, uint64(disableActiveMigrationParameterID))
 		utils.WriteVarInt(b, 0)
 	}
-	if p.StatelessResetToken != nil {
+	// reset_stream_at
+	if p.EnableResetStreamAt {
+		utils.WriteVarInt(b, uint64(resetStreamAtParameterID))
+		utils.WriteVarInt(b, 0)
+	}
+	// grease_quic_bit
+	if p.GreaseQUICBit {
+		utils.WriteVarInt(b, uint64(greaseQUICBitParameterID))
+		utils.WriteVarInt(b, 0)
+	}
+	// min_ack_delay
+	if p.MinAckDelay != 0 {
+		p.marshalVarintParam(b, minAckDelayParameterID, uint64(p.MinAckDelay/time.Microsecond))
+	}
+	// stateless_reset_token
+	if token := p.statelessResetToken(); token != nil {
 		utils.WriteVarInt(b, uint64(statelessResetTokenParameterID))
 		utils.WriteVarInt(b, 16)
-		b.Write(p.StatelessResetToken[:])
+		b.Write(token[:])
 	}
 	if p.PreferredAddress != nil {
 		utils.WriteVarInt(b, uint64(preferredAddressParameterID))
@@ -352,7 +474,44 @@ func (p *TransportParameters) Marshal() []byte {
 
 	// active_connection_id_limit
 	p.marshalVarintParam(b, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit)
-	return b.Bytes()
+}
+
+// splitParameters splits marshaled transport parameters.
+// It returns the parameter IDs in the order they were marshaled, and the encoded parameters.
+func splitParameters(data []byte) ([]uint64, map[uint64][]byte) {
+	var ids []uint64
+	params := make(map[uint64][]byte)
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		start := len(data) - r.Len()
+		// We marshaled the parameters ourselves, so parsing them can't fail.
+		id, _ := utils.ReadVarInt(r)
+		length, _ := utils.ReadVarInt(r)
+		r.Seek(int64(length), io.SeekCurrent)
+		ids = append(ids, id)
+		params[id] = data[start : len(data)-r.Len()]
+	}
+	return ids, params
+}
+
+// reorderParameters reorders marshaled transport parameters.
+// The parameters contained in order are moved to the front, in that order.
+// All other parameters keep their relative order.
+func reorderParameters(data []byte, order []uint64) []byte {
+	ids, params := splitParameters(data)
+	reordered := make([]byte, 0, len(data))
+	for _, id := range order {
+		if param, ok := params[id]; ok {
+			reordered = append(reordered, param...)
+			delete(params, id)
+		}
+	}
+	for _, id := range ids {
+		if param, ok := params[id]; ok {
+			reordered = append(reordered, param...)
+		}
+	}
+	return reordered
 }
 
 func (p *TransportParameters) marshalVarintParam(b *bytes.Buffer, id transportParameterID, val uint64) {
@@ -386,6 +545,8 @@ func (p *TransportParameters) MarshalForSessionTicket(b *bytes.Buffer) {
 	p.marshalVarintParam(b, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum))
 	// active_connection_id_limit
 	p.marshalVarintParam(b, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit)
+	// max_packet_size
+	p.marshalVarintParam(b, maxPacketSizeParameterID, uint64(p.maxPacketSize()))
 }
 
 // UnmarshalFromSessionTicket unmarshals transport parameters from a session ticket.
@@ -402,23 +563,108 @@ func (p *TransportParameters) UnmarshalFromSessionTicket(data []byte) error {
 }
 
 // ValidFor0RTT checks if the transport parameters match those saved in the session ticket.
+// The client sizes its 0-RTT packets according to the max_packet_size saved in the session ticket.
+// The max_packet_size therefore must not be smaller than the saved value.
 func (p *TransportParameters) ValidFor0RTT(tp *TransportParameters) bool {
-	return p.InitialMaxStreamDataBidiLocal == tp.InitialMaxStreamDataBidiLocal &&
-		p.InitialMaxStreamDataBidiRemote == tp.InitialMaxStreamDataBidiRemote &&
-		p.InitialMaxStreamDataUni == tp.InitialMaxStreamDataUni &&
-		p.InitialMaxData == tp.InitialMaxData &&
-		p.MaxBidiStreamNum == tp.MaxBidiStreamNum &&
-		p.MaxUniStreamNum == tp.MaxUniStreamNum
+	return len(p.ZeroRTTMismatches(tp)) == 0
+}
+
+// ZeroRTTMismatches returns the names of the transport parameters that don't match those saved in the session ticket.
+// It returns an empty slice if the transport parameters are valid for 0-RTT.
+func (p *TransportParameters) ZeroRTTMismatches(tp *TransportParameters) []string {
+	var mismatches []string
+	if p.InitialMaxStreamDataBidiLocal != tp.InitialMaxStreamDataBidiLocal {
+		mismatches = append(mismatches, "initial_max_stream_data_bidi_local")
+	}
+	if p.InitialMaxStreamDataBidiRemote != tp.InitialMaxStreamDataBidiRemote {
+		mismatches = append(mismatches, "initial_max_stream_data_bidi_remote")
+	}
+	if p.InitialMaxStreamDataUni != tp.InitialMaxStreamDataUni {
+		mismatches = append(mismatches, "initial_max_stream_data_uni")
+	}
+	if p.InitialMaxData != tp.InitialMaxData {
+		mismatches = append(mismatches, "initial_max_data")
+	}
+	if p.MaxBidiStreamNum != tp.MaxBidiStreamNum {
+		mismatches = append(mismatches, "initial_max_streams_bidi")
+	}
+	if p.MaxUniStreamNum != tp.MaxUniStreamNum {
+		mismatches = append(mismatches, "initial_max_streams_uni")
+	}
+	if p.maxPacketSize() < tp.maxPacketSize() {
+		mismatches = append(mismatches, "max_packet_size")
+	}
+	return mismatches
+}
+
+// maxPacketSize returns the max_packet_size.
+// Our own transport parameters don't set the MaxPacketSize,
+// since we always send protocol.MaxReceivePacketSize.
+func (p *TransportParameters) maxPacketSize() protocol.ByteCount {
+	if p.MaxPacketSize == 0 {
+		return protocol.MaxReceivePacketSize
+	}
+	return p.MaxPacketSize
+}
+
+// statelessResetToken returns the stateless reset token that is actually sent.
+// It is nil when using a zero-length connection ID.
+func (p *TransportParameters) statelessResetToken() *[16]byte {
+	if p.ZeroLengthConnectionID {
+		return nil
+	}
+	return p.StatelessResetToken
 }
 
 // String returns a string representation, intended for logging.
 func (p *TransportParameters) String() string {
 	logString := "&handshake.TransportParameters{OriginalConnectionID: %s, InitialMaxStreamDataBidiLocal: %#x, InitialMaxStreamDataBidiRemote: %#x, InitialMaxStreamDataUni: %#x, InitialMaxData: %#x, MaxBidiStreamNum: %d, MaxUniStreamNum: %d, MaxIdleTimeout: %s, AckDelayExponent: %d, MaxAckDelay: %s, ActiveConnectionIDLimit: %d"
 	logParams := []interface{}{p.OriginalConnectionID, p.InitialMaxStreamDataBidiLocal, p.InitialMaxStreamDataBidiRemote, p.InitialMaxStreamDataUni, p.InitialMaxData, p.MaxBidiStreamNum, p.MaxUniStreamNum, p.MaxIdleTimeout, p.AckDelayExponent, p.MaxAckDelay, p.ActiveConnectionIDLimit}
-	if p.StatelessResetToken != nil { // the client never sends a stateless reset token
+	if token := p.statelessResetToken(); token != nil { // the client never sends a stateless reset token
 		logString += ", StatelessResetToken: %#x"
-		logParams = append(logParams, *p.StatelessResetToken)
+		logParams = append(logParams, *token)
+	}
+	if p.MinAckDelay != 0 {
+		logString += ", MinAckDelay: %s"
+		logParams = append(logParams, p.MinAckDelay)
 	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
+
+// toQlog converts the transport parameters to their qlog representation.
+func (p *TransportParameters) toQlog() *qlog.TransportParameters {
+	tp := &qlog.TransportParameters{
+		OriginalConnectionID:           p.OriginalConnectionID,
+		StatelessResetToken:            p.statelessResetToken(),
+		DisableActiveMigration:         p.DisableActiveMigration,
+		MaxIdleTimeout:                 p.MaxIdleTimeout,
+		MaxPacketSize:                  p.MaxPacketSize,
+		AckDelayExponent:               p.AckDelayExponent,
+		MaxAckDelay:                    p.MaxAckDelay,
+		MinAckDelay:                    p.MinAckDelay,
+		ActiveConnectionIDLimit:        p.ActiveConnectionIDLimit,
+		InitialMaxData:                 p.InitialMaxData,
+		InitialMaxStreamDataBidiLocal:  p.InitialMaxStreamDataBidiLocal,
+		InitialMaxStreamDataBidiRemote: p.InitialMaxStreamDataBidiRemote,
+		InitialMaxStreamDataUni:        p.InitialMaxStreamDataUni,
+		InitialMaxStreamsBidi:          int64(p.MaxBidiStreamNum),
+		InitialMaxStreamsUni:           int64(p.MaxUniStreamNum),
+		EnableResetStreamAt:            p.EnableResetStreamAt,
+		GreaseQUICBit:                  p.GreaseQUICBit,
+	}
+	if p.PreferredAddress != nil {
+		tp.PreferredAddress = &qlog.PreferredAddress{
+			IPv4:                p.PreferredAddress.IPv4,
+			IPv4Port:            p.PreferredAddress.IPv4Port,
+			IPv6:                p.PreferredAddress.IPv6,
+			IPv6Port:            p.PreferredAddress.IPv6Port,
+			ConnectionID:        p.PreferredAddress.ConnectionID,
+			StatelessResetToken: p.PreferredAddress.StatelessResetToken,
+		}
+	}
+	for _, up := range p.UnknownParameters {
+		tp.UnknownParameters = append(tp.UnknownParameters, qlog.UnknownTransportParameter{ID: up.ID, Value: up.Value})
+	}
+	return tp
+}
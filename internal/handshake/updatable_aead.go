@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -47,6 +48,7 @@ type updatableAEAD struct {
 	suite *qtls.CipherSuiteTLS13
 
 	keyPhase          protocol.KeyPhase
+	numKeyUpdates     uint64 // accessed atomically, since it's read from the application's go routine
 	largestAcked      protocol.PacketNumber
 	firstPacketNumber protocol.PacketNumber
 	keyUpdateInterval uint64
@@ -73,6 +75,7 @@ type updatableAEAD struct {
 	headerEncrypter headerProtector
 
 	rttStats *congestion.RTTStats
+	clock    utils.Clock
 
 	qlogger qlog.Tracer
 	logger  utils.Logger
@@ -84,7 +87,7 @@ type updatableAEAD struct {
 var _ ShortHeaderOpener = &updatableAEAD{}
 var _ ShortHeaderSealer = &updatableAEAD{}
 
-func newUpdatableAEAD(rttStats *congestion.RTTStats, qlogger qlog.Tracer, logger utils.Logger) *updatableAEAD {
+func newUpdatableAEAD(rttStats *congestion.RTTStats, clock utils.Clock, qlogger qlog.Tracer, logger utils.Logger) *updatableAEAD {
 	return &updatableAEAD{
 		firstPacketNumber:       protocol.InvalidPacketNumber,
 		largestAcked:            protocol.InvalidPacketNumber,
@@ -92,6 +95,7 @@ func newUpdatableAEAD(rttStats *congestion.RTTStats, qlogger qlog.Tracer, logger
 		firstSentWithCurrentKey: protocol.InvalidPacketNumber,
 		keyUpdateInterval:       keyUpdateInterval,
 		rttStats:                rttStats,
+		clock:                   clock,
 		qlogger:                 qlogger,
 		logger:                  logger,
 	}
@@ -99,6 +103,7 @@ func newUpdatableAEAD(rttStats *congestion.RTTStats, qlogger qlog.Tracer, logger
 
 func (a *updatableAEAD) rollKeys(now time.Time) {
 	a.keyPhase++
+	atomic.AddUint64(&a.numKeyUpdates, 1)
 	a.firstRcvdWithCurrentKey = protocol.InvalidPacketNumber
 	a.firstSentWithCurrentKey = protocol.InvalidPacketNumber
 	a.numRcvdWithCurrentKey = 0
@@ -244,7 +249,7 @@ func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
 
 func (a *updatableAEAD) KeyPhase() protocol.KeyPhaseBit {
 	if a.shouldInitiateKeyUpdate() {
-		now := time.Now()
+		now := a.clock.Now()
 		if a.qlogger != nil {
 			a.qlogger.UpdatedKey(now, a.keyPhase, false)
 		}
@@ -253,6 +258,11 @@ func (a *updatableAEAD) KeyPhase() protocol.KeyPhaseBit {
 	return a.keyPhase.Bit()
 }
 
+// NumKeyUpdates returns the number of key updates, initiated by either endpoint.
+func (a *updatableAEAD) NumKeyUpdates() uint64 {
+	return atomic.LoadUint64(&a.numKeyUpdates)
+}
+
 func (a *updatableAEAD) Overhead() int {
 	return a.aeadOverhead
 }
@@ -15,6 +15,14 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// fixedClock is a clock that always returns the same time
+type fixedClock struct {
+	utils.DefaultClock
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time { return c.now }
+
 var _ = Describe("Updatable AEAD", func() {
 	for i := range cipherSuites {
 		cs := cipherSuites[i]
@@ -27,8 +35,8 @@ var _ = Describe("Updatable AEAD", func() {
 				rand.Read(trafficSecret1)
 				rand.Read(trafficSecret2)
 
-				client = newUpdatableAEAD(rttStats, nil, utils.DefaultLogger)
-				server = newUpdatableAEAD(rttStats, nil, utils.DefaultLogger)
+				client = newUpdatableAEAD(rttStats, utils.DefaultClock{}, nil, utils.DefaultLogger)
+				server = newUpdatableAEAD(rttStats, utils.DefaultClock{}, nil, utils.DefaultLogger)
 				client.SetReadKey(cs, trafficSecret2)
 				client.SetWriteKey(cs, trafficSecret1)
 				server.SetReadKey(cs, trafficSecret1)
@@ -128,10 +136,12 @@ var _ = Describe("Updatable AEAD", func() {
 							// now received a message at key phase one
 							client.rollKeys(now)
 							encrypted1 := client.Seal(nil, msg, 0x43, ad)
+							Expect(server.NumKeyUpdates()).To(BeZero())
 							decrypted, err = server.Open(nil, encrypted1, now, 0x43, protocol.KeyPhaseOne, ad)
 							Expect(err).ToNot(HaveOccurred())
 							Expect(decrypted).To(Equal(msg))
 							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							Expect(server.NumKeyUpdates()).To(Equal(uint64(1)))
 						})
 
 						It("opens a reordered packet with the old keys after an update", func() {
@@ -216,8 +226,10 @@ var _ = Describe("Updatable AEAD", func() {
 							}
 							// no update allowed before receiving an acknowledgement for the current key phase
 							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+							Expect(server.NumKeyUpdates()).To(BeZero())
 							server.SetLargestAcked(0)
 							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							Expect(server.NumKeyUpdates()).To(Equal(uint64(1)))
 						})
 
 						It("initiates a key update after opening the maximum number of packets", func() {
@@ -234,6 +246,27 @@ var _ = Describe("Updatable AEAD", func() {
 							server.SetLargestAcked(1)
 							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
 						})
+
+						It("uses the clock to determine when to drop the keys after a key update", func() {
+							clock := &fixedClock{now: time.Now().Add(time.Hour)}
+							server.clock = clock
+							rttStats.UpdateRTT(10*time.Millisecond, 0, clock.now)
+							pto := rttStats.PTO(true)
+							encrypted0 := client.Seal(nil, msg, 0, ad)
+							encrypted1 := client.Seal(nil, msg, 1, ad)
+							_, err := server.Open(nil, encrypted0, clock.now, 0, protocol.KeyPhaseZero, ad)
+							Expect(err).ToNot(HaveOccurred())
+							for i := 0; i < keyUpdateInterval; i++ {
+								server.Seal(nil, msg, protocol.PacketNumber(i), ad)
+							}
+							server.SetLargestAcked(0)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							// the old keys are dropped 3 PTOs after the key update, as measured by the clock
+							_, err = server.Open(nil, encrypted1, clock.now.Add(pto), 1, protocol.KeyPhaseZero, ad)
+							Expect(err).ToNot(HaveOccurred())
+							_, err = server.Open(nil, encrypted1, clock.now.Add(3*pto).Add(time.Nanosecond), 1, protocol.KeyPhaseZero, ad)
+							Expect(err).To(MatchError(ErrKeysDropped))
+						})
 					})
 
 					Context("reading the key update env", func() {
@@ -48,6 +48,18 @@ func (mr *MockReceivedPacketHandlerMockRecorder) DropPackets(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropPackets", reflect.TypeOf((*MockReceivedPacketHandler)(nil).DropPackets), arg0)
 }
 
+// FlushAcks mocks base method
+func (m *MockReceivedPacketHandler) FlushAcks() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FlushAcks")
+}
+
+// FlushAcks indicates an expected call of FlushAcks
+func (mr *MockReceivedPacketHandlerMockRecorder) FlushAcks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushAcks", reflect.TypeOf((*MockReceivedPacketHandler)(nil).FlushAcks))
+}
+
 // GetAckFrame mocks base method
 func (m *MockReceivedPacketHandler) GetAckFrame(arg0 protocol.EncryptionLevel) *wire.AckFrame {
 	m.ctrl.T.Helper()
@@ -89,3 +101,15 @@ func (mr *MockReceivedPacketHandlerMockRecorder) ReceivedPacket(arg0, arg1, arg2
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedPacket", reflect.TypeOf((*MockReceivedPacketHandler)(nil).ReceivedPacket), arg0, arg1, arg2, arg3)
 }
+
+// SetAckFrequency mocks base method
+func (m *MockReceivedPacketHandler) SetAckFrequency(arg0 uint64, arg1 time.Duration, arg2 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetAckFrequency", arg0, arg1, arg2)
+}
+
+// SetAckFrequency indicates an expected call of SetAckFrequency
+func (mr *MockReceivedPacketHandlerMockRecorder) SetAckFrequency(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAckFrequency", reflect.TypeOf((*MockReceivedPacketHandler)(nil).SetAckFrequency), arg0, arg1, arg2)
+}
@@ -10,6 +10,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	ackhandler "github.com/lucas-clemente/quic-go/internal/ackhandler"
+	congestion "github.com/lucas-clemente/quic-go/internal/congestion"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
 	quictrace "github.com/lucas-clemente/quic-go/quictrace"
@@ -38,6 +39,20 @@ func (m *MockSentPacketHandler) EXPECT() *MockSentPacketHandlerMockRecorder {
 	return m.recorder
 }
 
+// DeliveryRate mocks base method
+func (m *MockSentPacketHandler) DeliveryRate() congestion.Bandwidth {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeliveryRate")
+	ret0, _ := ret[0].(congestion.Bandwidth)
+	return ret0
+}
+
+// DeliveryRate indicates an expected call of DeliveryRate
+func (mr *MockSentPacketHandlerMockRecorder) DeliveryRate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeliveryRate", reflect.TypeOf((*MockSentPacketHandler)(nil).DeliveryRate))
+}
+
 // DropPackets mocks base method
 func (m *MockSentPacketHandler) DropPackets(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -78,6 +93,20 @@ func (mr *MockSentPacketHandlerMockRecorder) GetStats() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockSentPacketHandler)(nil).GetStats))
 }
 
+// NumSpuriousRetransmissions mocks base method
+func (m *MockSentPacketHandler) NumSpuriousRetransmissions() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NumSpuriousRetransmissions")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// NumSpuriousRetransmissions indicates an expected call of NumSpuriousRetransmissions
+func (mr *MockSentPacketHandlerMockRecorder) NumSpuriousRetransmissions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumSpuriousRetransmissions", reflect.TypeOf((*MockSentPacketHandler)(nil).NumSpuriousRetransmissions))
+}
+
 // OnLossDetectionTimeout mocks base method
 func (m *MockSentPacketHandler) OnLossDetectionTimeout() error {
 	m.ctrl.T.Helper()
@@ -92,6 +121,20 @@ func (mr *MockSentPacketHandlerMockRecorder) OnLossDetectionTimeout() *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).OnLossDetectionTimeout))
 }
 
+// PacketNumberSpaceStats mocks base method
+func (m *MockSentPacketHandler) PacketNumberSpaceStats(arg0 protocol.EncryptionLevel) ackhandler.PacketNumberSpaceStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacketNumberSpaceStats", arg0)
+	ret0, _ := ret[0].(ackhandler.PacketNumberSpaceStats)
+	return ret0
+}
+
+// PacketNumberSpaceStats indicates an expected call of PacketNumberSpaceStats
+func (mr *MockSentPacketHandlerMockRecorder) PacketNumberSpaceStats(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacketNumberSpaceStats", reflect.TypeOf((*MockSentPacketHandler)(nil).PacketNumberSpaceStats), arg0)
+}
+
 // PeekPacketNumber mocks base method
 func (m *MockSentPacketHandler) PeekPacketNumber(arg0 protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen) {
 	m.ctrl.T.Helper()
@@ -189,6 +232,18 @@ func (mr *MockSentPacketHandlerMockRecorder) SentPacket(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentPacket", reflect.TypeOf((*MockSentPacketHandler)(nil).SentPacket), arg0)
 }
 
+// SetCongestionControl mocks base method
+func (m *MockSentPacketHandler) SetCongestionControl(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCongestionControl", arg0)
+}
+
+// SetCongestionControl indicates an expected call of SetCongestionControl
+func (mr *MockSentPacketHandlerMockRecorder) SetCongestionControl(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCongestionControl", reflect.TypeOf((*MockSentPacketHandler)(nil).SetCongestionControl), arg0)
+}
+
 // SetHandshakeComplete mocks base method
 func (m *MockSentPacketHandler) SetHandshakeComplete() {
 	m.ctrl.T.Helper()
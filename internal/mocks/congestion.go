@@ -63,6 +63,20 @@ func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) GetCongestionWindow() *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCongestionWindow", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).GetCongestionWindow))
 }
 
+// InPostIdle mocks base method
+func (m *MockSendAlgorithmWithDebugInfos) InPostIdle() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InPostIdle")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// InPostIdle indicates an expected call of InPostIdle
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) InPostIdle() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InPostIdle", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).InPostIdle))
+}
+
 // InRecovery mocks base method
 func (m *MockSendAlgorithmWithDebugInfos) InRecovery() bool {
 	m.ctrl.T.Helper()
@@ -151,6 +165,18 @@ func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnRetransmissionTimeout(a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnRetransmissionTimeout", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnRetransmissionTimeout), arg0)
 }
 
+// SetReno mocks base method
+func (m *MockSendAlgorithmWithDebugInfos) SetReno(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReno", arg0)
+}
+
+// SetReno indicates an expected call of SetReno
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) SetReno(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReno", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).SetReno), arg0)
+}
+
 // TimeUntilSend mocks base method
 func (m *MockSendAlgorithmWithDebugInfos) TimeUntilSend(arg0 protocol.ByteCount) time.Duration {
 	m.ctrl.T.Helper()
@@ -87,6 +87,49 @@ func (mr *MockConnectionFlowControllerMockRecorder) IsNewlyBlocked() *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsNewlyBlocked", reflect.TypeOf((*MockConnectionFlowController)(nil).IsNewlyBlocked))
 }
 
+// RaiseReceiveWindow mocks base method
+func (m *MockConnectionFlowController) RaiseReceiveWindow(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RaiseReceiveWindow", arg0)
+}
+
+// RaiseReceiveWindow indicates an expected call of RaiseReceiveWindow
+func (mr *MockConnectionFlowControllerMockRecorder) RaiseReceiveWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RaiseReceiveWindow", reflect.TypeOf((*MockConnectionFlowController)(nil).RaiseReceiveWindow), arg0)
+}
+
+// ReceiveOffsets mocks base method
+func (m *MockConnectionFlowController) ReceiveOffsets() (protocol.ByteCount, protocol.ByteCount, protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiveOffsets")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	ret1, _ := ret[1].(protocol.ByteCount)
+	ret2, _ := ret[2].(protocol.ByteCount)
+	return ret0, ret1, ret2
+}
+
+// ReceiveOffsets indicates an expected call of ReceiveOffsets
+func (mr *MockConnectionFlowControllerMockRecorder) ReceiveOffsets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveOffsets", reflect.TypeOf((*MockConnectionFlowController)(nil).ReceiveOffsets))
+}
+
+// SendOffsets mocks base method
+func (m *MockConnectionFlowController) SendOffsets() (protocol.ByteCount, protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendOffsets")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	ret1, _ := ret[1].(protocol.ByteCount)
+	return ret0, ret1
+}
+
+// SendOffsets indicates an expected call of SendOffsets
+func (mr *MockConnectionFlowControllerMockRecorder) SendOffsets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendOffsets", reflect.TypeOf((*MockConnectionFlowController)(nil).SendOffsets))
+}
+
 // SendWindowSize mocks base method
 func (m *MockConnectionFlowController) SendWindowSize() protocol.ByteCount {
 	m.ctrl.T.Helper()
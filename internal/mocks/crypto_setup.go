@@ -237,6 +237,20 @@ func (mr *MockCryptoSetupMockRecorder) HandleMessage(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessage", reflect.TypeOf((*MockCryptoSetup)(nil).HandleMessage), arg0, arg1)
 }
 
+// NumKeyUpdates mocks base method
+func (m *MockCryptoSetup) NumKeyUpdates() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NumKeyUpdates")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// NumKeyUpdates indicates an expected call of NumKeyUpdates
+func (mr *MockCryptoSetupMockRecorder) NumKeyUpdates() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumKeyUpdates", reflect.TypeOf((*MockCryptoSetup)(nil).NumKeyUpdates))
+}
+
 // RunHandshake mocks base method
 func (m *MockCryptoSetup) RunHandshake() {
 	m.ctrl.T.Helper()
@@ -260,3 +274,31 @@ func (mr *MockCryptoSetupMockRecorder) SetLargest1RTTAcked(arg0 interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLargest1RTTAcked", reflect.TypeOf((*MockCryptoSetup)(nil).SetLargest1RTTAcked), arg0)
 }
+
+// UsedHelloRetryRequest mocks base method
+func (m *MockCryptoSetup) UsedHelloRetryRequest() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsedHelloRetryRequest")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// UsedHelloRetryRequest indicates an expected call of UsedHelloRetryRequest
+func (mr *MockCryptoSetupMockRecorder) UsedHelloRetryRequest() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsedHelloRetryRequest", reflect.TypeOf((*MockCryptoSetup)(nil).UsedHelloRetryRequest))
+}
+
+// ZeroRTTRejection mocks base method
+func (m *MockCryptoSetup) ZeroRTTRejection() handshake.ZeroRTTRejection {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZeroRTTRejection")
+	ret0, _ := ret[0].(handshake.ZeroRTTRejection)
+	return ret0
+}
+
+// ZeroRTTRejection indicates an expected call of ZeroRTTRejection
+func (mr *MockCryptoSetupMockRecorder) ZeroRTTRejection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZeroRTTRejection", reflect.TypeOf((*MockCryptoSetup)(nil).ZeroRTTRejection))
+}
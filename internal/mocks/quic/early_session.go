@@ -8,9 +8,11 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	quic "github.com/lucas-clemente/quic-go"
+	handshake "github.com/lucas-clemente/quic-go/internal/handshake"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	qtls "github.com/marten-seemann/qtls"
 )
@@ -68,6 +70,34 @@ func (mr *MockEarlySessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockEarlySession)(nil).AcceptUniStream), arg0)
 }
 
+// AckFrequencyMaxAckDelay mocks base method
+func (m *MockEarlySession) AckFrequencyMaxAckDelay() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckFrequencyMaxAckDelay")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// AckFrequencyMaxAckDelay indicates an expected call of AckFrequencyMaxAckDelay
+func (mr *MockEarlySessionMockRecorder) AckFrequencyMaxAckDelay() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckFrequencyMaxAckDelay", reflect.TypeOf((*MockEarlySession)(nil).AckFrequencyMaxAckDelay))
+}
+
+// AckFrequencyPacketTolerance mocks base method
+func (m *MockEarlySession) AckFrequencyPacketTolerance() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckFrequencyPacketTolerance")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// AckFrequencyPacketTolerance indicates an expected call of AckFrequencyPacketTolerance
+func (mr *MockEarlySessionMockRecorder) AckFrequencyPacketTolerance() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckFrequencyPacketTolerance", reflect.TypeOf((*MockEarlySession)(nil).AckFrequencyPacketTolerance))
+}
+
 // CloseWithError mocks base method
 func (m *MockEarlySession) CloseWithError(arg0 protocol.ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -82,6 +112,34 @@ func (mr *MockEarlySessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockEarlySession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CoalescingStats mocks base method
+func (m *MockEarlySession) CoalescingStats() quic.CoalescingStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CoalescingStats")
+	ret0, _ := ret[0].(quic.CoalescingStats)
+	return ret0
+}
+
+// CoalescingStats indicates an expected call of CoalescingStats
+func (mr *MockEarlySessionMockRecorder) CoalescingStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CoalescingStats", reflect.TypeOf((*MockEarlySession)(nil).CoalescingStats))
+}
+
+// ConnectionIDs mocks base method
+func (m *MockEarlySession) ConnectionIDs() quic.ConnectionIDs {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionIDs")
+	ret0, _ := ret[0].(quic.ConnectionIDs)
+	return ret0
+}
+
+// ConnectionIDs indicates an expected call of ConnectionIDs
+func (mr *MockEarlySessionMockRecorder) ConnectionIDs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionIDs", reflect.TypeOf((*MockEarlySession)(nil).ConnectionIDs))
+}
+
 // ConnectionState mocks base method
 func (m *MockEarlySession) ConnectionState() qtls.ConnectionState {
 	m.ctrl.T.Helper()
@@ -110,6 +168,60 @@ func (mr *MockEarlySessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockEarlySession)(nil).Context))
 }
 
+// EstimatedBandwidth mocks base method
+func (m *MockEarlySession) EstimatedBandwidth() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EstimatedBandwidth")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// EstimatedBandwidth indicates an expected call of EstimatedBandwidth
+func (mr *MockEarlySessionMockRecorder) EstimatedBandwidth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EstimatedBandwidth", reflect.TypeOf((*MockEarlySession)(nil).EstimatedBandwidth))
+}
+
+// FlowControlOffsets mocks base method
+func (m *MockEarlySession) FlowControlOffsets() quic.FlowControlOffsets {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlOffsets")
+	ret0, _ := ret[0].(quic.FlowControlOffsets)
+	return ret0
+}
+
+// FlowControlOffsets indicates an expected call of FlowControlOffsets
+func (mr *MockEarlySessionMockRecorder) FlowControlOffsets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlOffsets", reflect.TypeOf((*MockEarlySession)(nil).FlowControlOffsets))
+}
+
+// FlushACKs mocks base method
+func (m *MockEarlySession) FlushACKs() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FlushACKs")
+}
+
+// FlushACKs indicates an expected call of FlushACKs
+func (mr *MockEarlySessionMockRecorder) FlushACKs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushACKs", reflect.TypeOf((*MockEarlySession)(nil).FlushACKs))
+}
+
+// GetVersion mocks base method
+func (m *MockEarlySession) GetVersion() protocol.VersionNumber {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVersion")
+	ret0, _ := ret[0].(protocol.VersionNumber)
+	return ret0
+}
+
+// GetVersion indicates an expected call of GetVersion
+func (mr *MockEarlySessionMockRecorder) GetVersion() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersion", reflect.TypeOf((*MockEarlySession)(nil).GetVersion))
+}
+
 // HandshakeComplete mocks base method
 func (m *MockEarlySession) HandshakeComplete() context.Context {
 	m.ctrl.T.Helper()
@@ -124,6 +236,20 @@ func (mr *MockEarlySessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockEarlySession)(nil).HandshakeComplete))
 }
 
+// LatestAckDelay mocks base method
+func (m *MockEarlySession) LatestAckDelay() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatestAckDelay")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// LatestAckDelay indicates an expected call of LatestAckDelay
+func (mr *MockEarlySessionMockRecorder) LatestAckDelay() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatestAckDelay", reflect.TypeOf((*MockEarlySession)(nil).LatestAckDelay))
+}
+
 // LocalAddr mocks base method
 func (m *MockEarlySession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -138,6 +264,48 @@ func (mr *MockEarlySessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockEarlySession)(nil).LocalAddr))
 }
 
+// NumDroppedUndecryptablePackets mocks base method
+func (m *MockEarlySession) NumDroppedUndecryptablePackets() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NumDroppedUndecryptablePackets")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// NumDroppedUndecryptablePackets indicates an expected call of NumDroppedUndecryptablePackets
+func (mr *MockEarlySessionMockRecorder) NumDroppedUndecryptablePackets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumDroppedUndecryptablePackets", reflect.TypeOf((*MockEarlySession)(nil).NumDroppedUndecryptablePackets))
+}
+
+// NumKeyUpdates mocks base method
+func (m *MockEarlySession) NumKeyUpdates() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NumKeyUpdates")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// NumKeyUpdates indicates an expected call of NumKeyUpdates
+func (mr *MockEarlySessionMockRecorder) NumKeyUpdates() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumKeyUpdates", reflect.TypeOf((*MockEarlySession)(nil).NumKeyUpdates))
+}
+
+// NumSpuriousRetransmissions mocks base method
+func (m *MockEarlySession) NumSpuriousRetransmissions() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NumSpuriousRetransmissions")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// NumSpuriousRetransmissions indicates an expected call of NumSpuriousRetransmissions
+func (mr *MockEarlySessionMockRecorder) NumSpuriousRetransmissions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumSpuriousRetransmissions", reflect.TypeOf((*MockEarlySession)(nil).NumSpuriousRetransmissions))
+}
+
 // OpenStream mocks base method
 func (m *MockEarlySession) OpenStream() (quic.Stream, error) {
 	m.ctrl.T.Helper()
@@ -168,6 +336,21 @@ func (mr *MockEarlySessionMockRecorder) OpenStreamSync(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSync", reflect.TypeOf((*MockEarlySession)(nil).OpenStreamSync), arg0)
 }
 
+// OpenStreamWithWindow mocks base method
+func (m *MockEarlySession) OpenStreamWithWindow(arg0 uint64) (quic.Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamWithWindow", arg0)
+	ret0, _ := ret[0].(quic.Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamWithWindow indicates an expected call of OpenStreamWithWindow
+func (mr *MockEarlySessionMockRecorder) OpenStreamWithWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamWithWindow", reflect.TypeOf((*MockEarlySession)(nil).OpenStreamWithWindow), arg0)
+}
+
 // OpenUniStream mocks base method
 func (m *MockEarlySession) OpenUniStream() (quic.SendStream, error) {
 	m.ctrl.T.Helper()
@@ -198,6 +381,34 @@ func (mr *MockEarlySessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockEarlySession)(nil).OpenUniStreamSync), arg0)
 }
 
+// PacketNumberSpaces mocks base method
+func (m *MockEarlySession) PacketNumberSpaces() quic.PacketNumberSpaces {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacketNumberSpaces")
+	ret0, _ := ret[0].(quic.PacketNumberSpaces)
+	return ret0
+}
+
+// PacketNumberSpaces indicates an expected call of PacketNumberSpaces
+func (mr *MockEarlySessionMockRecorder) PacketNumberSpaces() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacketNumberSpaces", reflect.TypeOf((*MockEarlySession)(nil).PacketNumberSpaces))
+}
+
+// PathUnhealthy mocks base method
+func (m *MockEarlySession) PathUnhealthy() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PathUnhealthy")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// PathUnhealthy indicates an expected call of PathUnhealthy
+func (mr *MockEarlySessionMockRecorder) PathUnhealthy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PathUnhealthy", reflect.TypeOf((*MockEarlySession)(nil).PathUnhealthy))
+}
+
 // RemoteAddr mocks base method
 func (m *MockEarlySession) RemoteAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -211,3 +422,99 @@ func (mr *MockEarlySessionMockRecorder) RemoteAddr() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockEarlySession)(nil).RemoteAddr))
 }
+
+// ResetAllStreams mocks base method
+func (m *MockEarlySession) ResetAllStreams(arg0 protocol.ApplicationErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ResetAllStreams", arg0)
+}
+
+// ResetAllStreams indicates an expected call of ResetAllStreams
+func (mr *MockEarlySessionMockRecorder) ResetAllStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetAllStreams", reflect.TypeOf((*MockEarlySession)(nil).ResetAllStreams), arg0)
+}
+
+// RetirePeerConnectionID mocks base method
+func (m *MockEarlySession) RetirePeerConnectionID(arg0 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetirePeerConnectionID", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RetirePeerConnectionID indicates an expected call of RetirePeerConnectionID
+func (mr *MockEarlySessionMockRecorder) RetirePeerConnectionID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetirePeerConnectionID", reflect.TypeOf((*MockEarlySession)(nil).RetirePeerConnectionID), arg0)
+}
+
+// SendQueueDepth mocks base method
+func (m *MockEarlySession) SendQueueDepth() quic.SendQueueDepth {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendQueueDepth")
+	ret0, _ := ret[0].(quic.SendQueueDepth)
+	return ret0
+}
+
+// SendQueueDepth indicates an expected call of SendQueueDepth
+func (mr *MockEarlySessionMockRecorder) SendQueueDepth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendQueueDepth", reflect.TypeOf((*MockEarlySession)(nil).SendQueueDepth))
+}
+
+// SetCongestionControl mocks base method
+func (m *MockEarlySession) SetCongestionControl(arg0 quic.CongestionControlAlgorithm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCongestionControl", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCongestionControl indicates an expected call of SetCongestionControl
+func (mr *MockEarlySessionMockRecorder) SetCongestionControl(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCongestionControl", reflect.TypeOf((*MockEarlySession)(nil).SetCongestionControl), arg0)
+}
+
+// StreamCounts mocks base method
+func (m *MockEarlySession) StreamCounts() quic.StreamCounts {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamCounts")
+	ret0, _ := ret[0].(quic.StreamCounts)
+	return ret0
+}
+
+// StreamCounts indicates an expected call of StreamCounts
+func (mr *MockEarlySessionMockRecorder) StreamCounts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamCounts", reflect.TypeOf((*MockEarlySession)(nil).StreamCounts))
+}
+
+// UsedHelloRetryRequest mocks base method
+func (m *MockEarlySession) UsedHelloRetryRequest() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsedHelloRetryRequest")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// UsedHelloRetryRequest indicates an expected call of UsedHelloRetryRequest
+func (mr *MockEarlySessionMockRecorder) UsedHelloRetryRequest() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsedHelloRetryRequest", reflect.TypeOf((*MockEarlySession)(nil).UsedHelloRetryRequest))
+}
+
+// ZeroRTTRejection mocks base method
+func (m *MockEarlySession) ZeroRTTRejection() handshake.ZeroRTTRejection {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZeroRTTRejection")
+	ret0, _ := ret[0].(handshake.ZeroRTTRejection)
+	return ret0
+}
+
+// ZeroRTTRejection indicates an expected call of ZeroRTTRejection
+func (mr *MockEarlySessionMockRecorder) ZeroRTTRejection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZeroRTTRejection", reflect.TypeOf((*MockEarlySession)(nil).ZeroRTTRejection))
+}
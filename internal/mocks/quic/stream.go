@@ -10,6 +10,7 @@ import (
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	quic "github.com/lucas-clemente/quic-go"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -60,6 +61,20 @@ func (mr *MockStreamMockRecorder) CancelWrite(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWrite", reflect.TypeOf((*MockStream)(nil).CancelWrite), arg0)
 }
 
+// CancelWriteAt mocks base method
+func (m *MockStream) CancelWriteAt(arg0 protocol.ApplicationErrorCode, arg1 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelWriteAt", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelWriteAt indicates an expected call of CancelWriteAt
+func (mr *MockStreamMockRecorder) CancelWriteAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWriteAt", reflect.TypeOf((*MockStream)(nil).CancelWriteAt), arg0, arg1)
+}
+
 // Close mocks base method
 func (m *MockStream) Close() error {
 	m.ctrl.T.Helper()
@@ -88,6 +103,20 @@ func (mr *MockStreamMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStream)(nil).Context))
 }
 
+// FlowControlOffsets mocks base method
+func (m *MockStream) FlowControlOffsets() quic.FlowControlOffsets {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlOffsets")
+	ret0, _ := ret[0].(quic.FlowControlOffsets)
+	return ret0
+}
+
+// FlowControlOffsets indicates an expected call of FlowControlOffsets
+func (mr *MockStreamMockRecorder) FlowControlOffsets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlOffsets", reflect.TypeOf((*MockStream)(nil).FlowControlOffsets))
+}
+
 // Read mocks base method
 func (m *MockStream) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -103,6 +132,36 @@ func (mr *MockStreamMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStream)(nil).Read), arg0)
 }
 
+// ReadBuffer mocks base method
+func (m *MockStream) ReadBuffer() ([]byte, func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadBuffer")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(func())
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadBuffer indicates an expected call of ReadBuffer
+func (mr *MockStreamMockRecorder) ReadBuffer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadBuffer", reflect.TypeOf((*MockStream)(nil).ReadBuffer))
+}
+
+// SendQueueDepth mocks base method
+func (m *MockStream) SendQueueDepth() quic.SendQueueDepth {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendQueueDepth")
+	ret0, _ := ret[0].(quic.SendQueueDepth)
+	return ret0
+}
+
+// SendQueueDepth indicates an expected call of SendQueueDepth
+func (mr *MockStreamMockRecorder) SendQueueDepth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendQueueDepth", reflect.TypeOf((*MockStream)(nil).SendQueueDepth))
+}
+
 // SetDeadline mocks base method
 func (m *MockStream) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -131,6 +190,18 @@ func (mr *MockStreamMockRecorder) SetReadDeadline(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStream)(nil).SetReadDeadline), arg0)
 }
 
+// SetReceiveWindow mocks base method
+func (m *MockStream) SetReceiveWindow(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReceiveWindow", arg0)
+}
+
+// SetReceiveWindow indicates an expected call of SetReceiveWindow
+func (mr *MockStreamMockRecorder) SetReceiveWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindow", reflect.TypeOf((*MockStream)(nil).SetReceiveWindow), arg0)
+}
+
 // SetWriteDeadline mocks base method
 func (m *MockStream) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
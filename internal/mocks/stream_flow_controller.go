@@ -99,6 +99,63 @@ func (mr *MockStreamFlowControllerMockRecorder) IsNewlyBlocked() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsNewlyBlocked", reflect.TypeOf((*MockStreamFlowController)(nil).IsNewlyBlocked))
 }
 
+// ReceiveOffsets mocks base method
+func (m *MockStreamFlowController) ReceiveOffsets() (protocol.ByteCount, protocol.ByteCount, protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiveOffsets")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	ret1, _ := ret[1].(protocol.ByteCount)
+	ret2, _ := ret[2].(protocol.ByteCount)
+	return ret0, ret1, ret2
+}
+
+// ReceiveOffsets indicates an expected call of ReceiveOffsets
+func (mr *MockStreamFlowControllerMockRecorder) ReceiveOffsets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveOffsets", reflect.TypeOf((*MockStreamFlowController)(nil).ReceiveOffsets))
+}
+
+// ReleaseSendWindow mocks base method
+func (m *MockStreamFlowController) ReleaseSendWindow() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReleaseSendWindow")
+}
+
+// ReleaseSendWindow indicates an expected call of ReleaseSendWindow
+func (mr *MockStreamFlowControllerMockRecorder) ReleaseSendWindow() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseSendWindow", reflect.TypeOf((*MockStreamFlowController)(nil).ReleaseSendWindow))
+}
+
+// ReserveSendWindow mocks base method
+func (m *MockStreamFlowController) ReserveSendWindow(arg0 protocol.ByteCount) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveSendWindow", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ReserveSendWindow indicates an expected call of ReserveSendWindow
+func (mr *MockStreamFlowControllerMockRecorder) ReserveSendWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveSendWindow", reflect.TypeOf((*MockStreamFlowController)(nil).ReserveSendWindow), arg0)
+}
+
+// SendOffsets mocks base method
+func (m *MockStreamFlowController) SendOffsets() (protocol.ByteCount, protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendOffsets")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	ret1, _ := ret[1].(protocol.ByteCount)
+	return ret0, ret1
+}
+
+// SendOffsets indicates an expected call of SendOffsets
+func (mr *MockStreamFlowControllerMockRecorder) SendOffsets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendOffsets", reflect.TypeOf((*MockStreamFlowController)(nil).SendOffsets))
+}
+
 // SendWindowSize mocks base method
 func (m *MockStreamFlowController) SendWindowSize() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -113,6 +170,18 @@ func (mr *MockStreamFlowControllerMockRecorder) SendWindowSize() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SendWindowSize))
 }
 
+// SetReceiveWindowSize mocks base method
+func (m *MockStreamFlowController) SetReceiveWindowSize(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReceiveWindowSize", arg0)
+}
+
+// SetReceiveWindowSize indicates an expected call of SetReceiveWindowSize
+func (mr *MockStreamFlowControllerMockRecorder) SetReceiveWindowSize(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SetReceiveWindowSize), arg0)
+}
+
 // UpdateHighestReceived mocks base method
 func (m *MockStreamFlowController) UpdateHighestReceived(arg0 protocol.ByteCount, arg1 bool) error {
 	m.ctrl.T.Helper()
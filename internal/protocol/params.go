@@ -11,7 +11,8 @@ const MaxPacketSizeIPv6 = 1232
 // MaxCongestionWindowPackets is the maximum congestion window in packet.
 const MaxCongestionWindowPackets = 10000
 
-// MaxUndecryptablePackets limits the number of undecryptable packets that are queued in the session.
+// MaxUndecryptablePackets limits the number of undecryptable packets that are queued in the session,
+// if no other value is configured.
 const MaxUndecryptablePackets = 33
 
 // ConnectionFlowControlMultiplier determines how much larger the connection flow control windows needs to be relative to any stream's flow control window
@@ -30,9 +31,18 @@ const DefaultMaxReceiveStreamFlowControlWindow = 6 * (1 << 20) // 6 MB
 // DefaultMaxReceiveConnectionFlowControlWindow is the default connection-level flow control window for receiving data, for the server
 const DefaultMaxReceiveConnectionFlowControlWindow = 15 * (1 << 20) // 12 MB
 
-// WindowUpdateThreshold is the fraction of the receive window that has to be consumed before an higher offset is advertised to the client
+// WindowUpdateThreshold is the fraction of the receive window that has to be consumed before an higher offset is advertised to the client,
+// if no other value is configured
 const WindowUpdateThreshold = 0.25
 
+// DefaultReceiveWindowGrowthFactor is the default factor by which auto-tuning increases the receive window size
+const DefaultReceiveWindowGrowthFactor = 2
+
+// BDPWindowMultiplier is the multiple of the bandwidth-delay product that the receive window is sized to,
+// if BDP-based auto-tuning is enabled.
+// The window has to be larger than the BDP, since window updates are only sent once a fraction of the window was consumed.
+const BDPWindowMultiplier = 2
+
 // DefaultMaxIncomingStreams is the maximum number of streams that a peer may open
 const DefaultMaxIncomingStreams = 100
 
@@ -51,6 +61,9 @@ const SkipPacketAveragePeriodLength PacketNumber = 500
 // MaxTrackedSkippedPackets is the maximum number of skipped packet numbers the SentPacketHandler keep track of for Optimistic ACK attack mitigation
 const MaxTrackedSkippedPackets = 10
 
+// MaxTrackedLostPackets is the maximum number of lost packet numbers the SentPacketHandler keeps track of for detecting spurious retransmissions
+const MaxTrackedLostPackets = 100
+
 // MaxAcceptQueueSize is the maximum number of sessions that the server queues for accepting.
 // If the queue is full, new connection attempts will be rejected.
 const MaxAcceptQueueSize = 32
@@ -105,6 +118,11 @@ const DefaultHandshakeTimeout = 10 * time.Second
 // It should be shorter than the time that NATs clear their mapping.
 const MaxKeepAliveInterval = 20 * time.Second
 
+// MaxConnectionCloseRetransmissions is the maximum number of times the packet containing the CONNECTION_CLOSE frame
+// is retransmitted in response to packets received after closing the session.
+// After that, all packets for this session are dropped.
+const MaxConnectionCloseRetransmissions = 8
+
 // RetiredConnectionIDDeleteTimeout is the time we keep closed sessions around in order to retransmit the CONNECTION_CLOSE.
 // after this time all information about the old connection will be deleted
 const RetiredConnectionIDDeleteTimeout = 5 * time.Second
@@ -126,10 +144,14 @@ const MaxPostHandshakeCryptoFrameSize = 1000
 const MaxAckFrameSize ByteCount = 1000
 
 // MaxNumAckRanges is the maximum number of ACK ranges that we send in an ACK frame.
-// It also serves as a limit for the packet history.
+// It also serves as the default limit for the packet history.
 // If at any point we keep track of more ranges, old ranges are discarded.
 const MaxNumAckRanges = 500
 
+// DefaultPacketReorderingThreshold is the default maximum reordering in packets
+// before packet threshold loss detection considers a packet lost (kPacketThreshold).
+const DefaultPacketReorderingThreshold = 3
+
 // MinPacingDelay is the minimum duration that is used for packet pacing
 // If the packet packing frequency is higher, multiple packets might be sent at once.
 // Example: For a packet pacing delay of 20 microseconds, we would send 5 packets at once, wait for 100 microseconds, and so forth.
@@ -142,8 +164,9 @@ const DefaultConnectionIDLength = 4
 // MaxActiveConnectionIDs is the number of connection IDs that we're storing.
 const MaxActiveConnectionIDs = 4
 
-// MaxIssuedConnectionIDs is the maximum number of connection IDs that we're issuing at the same time.
-const MaxIssuedConnectionIDs = 6
+// DefaultMaxIssuedConnectionIDs is the maximum number of connection IDs that we're issuing at the same time,
+// if no other value is configured.
+const DefaultMaxIssuedConnectionIDs = 6
 
 // PacketsPerConnectionID is the number of packets we send using one connection ID.
 // If the peer provices us with enough new connection IDs, we switch to a new connection ID.
@@ -159,6 +182,9 @@ const TimerGranularity = time.Millisecond
 // MaxAckDelay is the maximum time by which we delay sending ACKs.
 const MaxAckDelay = 25 * time.Millisecond
 
+// MinAckDelay is the min_ack_delay advertised when the ACK Frequency extension is enabled.
+const MinAckDelay = time.Millisecond
+
 // MaxAckDelayInclGranularity is the max_ack_delay including the timer granularity.
 // This is the value that should be advertised to the peer.
 const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
@@ -0,0 +1,123 @@
+package testutils
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A MockClock is a clock that only advances when Advance is called.
+// Timers created by the clock fire when the clock is advanced past their deadline.
+type MockClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*mockTimer
+}
+
+var _ utils.Clock = &MockClock{}
+
+// NewMockClock creates a new mock clock, starting at the given time.
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+// Now returns the current time of the mock clock.
+func (c *MockClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer that fires once the clock was advanced by d.
+func (c *MockClock) NewTimer(d time.Duration) utils.ClockTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &mockTimer{clock: c, c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	t.set(d)
+	return t
+}
+
+// Advance advances the clock by d, and fires all timers that expire in that interval.
+func (c *MockClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.timers {
+		t.maybeFire()
+	}
+}
+
+// NextDeadline returns the earliest deadline of all timers that are armed.
+// It returns a zero time if no timer is armed.
+func (c *MockClock) NextDeadline() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var deadline time.Time
+	for _, t := range c.timers {
+		if t.active && (deadline.IsZero() || t.deadline.Before(deadline)) {
+			deadline = t.deadline
+		}
+	}
+	return deadline
+}
+
+// AdvanceToNextDeadline advances the clock to the earliest deadline of all timers that are armed.
+// It returns false if no timer is armed.
+func (c *MockClock) AdvanceToNextDeadline() bool {
+	deadline := c.NextDeadline()
+	if deadline.IsZero() {
+		return false
+	}
+	c.Advance(deadline.Sub(c.Now()))
+	return true
+}
+
+type mockTimer struct {
+	clock    *MockClock
+	c        chan time.Time
+	deadline time.Time
+	active   bool
+}
+
+var _ utils.ClockTimer = &mockTimer{}
+
+func (t *mockTimer) Chan() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	wasActive := t.active
+	t.active = false
+	return wasActive
+}
+
+func (t *mockTimer) Reset(d time.Duration) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	wasActive := t.active
+	t.set(d)
+	return wasActive
+}
+
+// set must be called with the clock's mutex held
+func (t *mockTimer) set(d time.Duration) {
+	t.deadline = t.clock.now.Add(d)
+	t.active = true
+	t.maybeFire()
+}
+
+// maybeFire must be called with the clock's mutex held
+func (t *mockTimer) maybeFire() {
+	if !t.active || t.deadline.After(t.clock.now) {
+		return
+	}
+	t.active = false
+	select {
+	case t.c <- t.deadline:
+	default:
+	}
+}
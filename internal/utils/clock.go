@@ -0,0 +1,41 @@
+package utils
+
+import "time"
+
+// A Clock returns the current time and creates timers.
+// It allows replacing the wall clock in tests.
+type Clock interface {
+	Now() time.Time
+	NewTimer(time.Duration) ClockTimer
+}
+
+// A ClockTimer is a timer created by a Clock.
+// It behaves like a time.Timer.
+type ClockTimer interface {
+	Chan() <-chan time.Time
+	Stop() bool
+	Reset(time.Duration) bool
+}
+
+// DefaultClock implements the Clock interface using the Go stdlib clock.
+type DefaultClock struct{}
+
+var _ Clock = DefaultClock{}
+
+// Now gets the current time
+func (DefaultClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer creates a time.Timer that fires after d
+func (DefaultClock) NewTimer(d time.Duration) ClockTimer {
+	return &stdlibTimer{time.NewTimer(d)}
+}
+
+type stdlibTimer struct {
+	*time.Timer
+}
+
+func (t *stdlibTimer) Chan() <-chan time.Time {
+	return t.C
+}
@@ -7,39 +7,65 @@ import (
 
 // A Timer wrapper that behaves correctly when resetting
 type Timer struct {
-	t        *time.Timer
+	clock    Clock
+	t        ClockTimer
 	read     bool
 	deadline time.Time
+	// the deadline that the wrapped timer is currently set to
+	// It might be earlier than the deadline, if the deadline was postponed.
+	armedDeadline time.Time
 }
 
 // NewTimer creates a new timer that is not set
 func NewTimer() *Timer {
-	return &Timer{t: time.NewTimer(time.Duration(math.MaxInt64))}
+	return NewTimerWithClock(DefaultClock{})
+}
+
+// NewTimerWithClock creates a new timer that is not set, using the given clock
+func NewTimerWithClock(clock Clock) *Timer {
+	return &Timer{
+		clock: clock,
+		t:     clock.NewTimer(time.Duration(math.MaxInt64)),
+	}
 }
 
 // Chan returns the channel of the wrapped timer
 func (t *Timer) Chan() <-chan time.Time {
-	return t.t.C
+	return t.t.Chan()
 }
 
-// Reset the timer, no matter whether the value was read or not
+// Reset the timer, no matter whether the value was read or not.
+// Postponing the deadline doesn't reset the wrapped timer, so the timer might fire before the deadline.
+// Callers need to check the Deadline when the timer fires.
 func (t *Timer) Reset(deadline time.Time) {
 	if deadline.Equal(t.deadline) && !t.read {
 		// No need to reset the timer
 		return
 	}
+	// Resetting a timer is expensive.
+	// If the wrapped timer fires before the new deadline, the timer will be reset after it fired.
+	if !t.read && !deadline.IsZero() && !t.armedDeadline.IsZero() && deadline.After(t.armedDeadline) {
+		t.deadline = deadline
+		return
+	}
 
 	// We need to drain the timer if the value from its channel was not read yet.
 	// See https://groups.google.com/forum/#!topic/golang-dev/c9UUfASVPoU
 	if !t.t.Stop() && !t.read {
-		<-t.t.C
+		<-t.t.Chan()
 	}
 	if !deadline.IsZero() {
-		t.t.Reset(time.Until(deadline))
+		t.t.Reset(deadline.Sub(t.clock.Now()))
 	}
 
 	t.read = false
 	t.deadline = deadline
+	t.armedDeadline = deadline
+}
+
+// Deadline returns the deadline the timer was last reset to
+func (t *Timer) Deadline() time.Time {
+	return t.deadline
 }
 
 // SetRead should be called after the value from the chan was read
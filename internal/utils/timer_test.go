@@ -1,12 +1,33 @@
 package utils
 
 import (
+	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+// A resetCountingClock is a Clock that counts how often the timers it created were reset.
+type resetCountingClock struct {
+	DefaultClock
+	numResets int
+}
+
+func (c *resetCountingClock) NewTimer(d time.Duration) ClockTimer {
+	return &resetCountingTimer{ClockTimer: c.DefaultClock.NewTimer(d), clock: c}
+}
+
+type resetCountingTimer struct {
+	ClockTimer
+	clock *resetCountingClock
+}
+
+func (t *resetCountingTimer) Reset(d time.Duration) bool {
+	t.clock.numResets++
+	return t.ClockTimer.Reset(d)
+}
+
 var _ = Describe("Timer", func() {
 	const d = 10 * time.Millisecond
 
@@ -77,4 +98,45 @@ var _ = Describe("Timer", func() {
 		Eventually(t.Chan()).Should(Receive())
 		Consistently(t.Chan()).ShouldNot(Receive())
 	})
+
+	It("doesn't reset the wrapped timer when the deadline is postponed", func() {
+		clock := &resetCountingClock{}
+		t := NewTimerWithClock(clock)
+		deadline := time.Now().Add(d)
+		t.Reset(deadline)
+		for i := 1; i <= 10; i++ {
+			t.Reset(deadline.Add(time.Duration(i) * time.Millisecond))
+		}
+		Expect(clock.numResets).To(Equal(1))
+		Expect(t.Deadline()).To(Equal(deadline.Add(10 * time.Millisecond)))
+		// the wrapped timer fires at the original deadline
+		Eventually(t.Chan()).Should(Receive())
+		t.SetRead()
+		t.Reset(t.Deadline())
+		Expect(clock.numResets).To(Equal(2))
+		Eventually(t.Chan()).Should(Receive())
+	})
+
+	It("resets the wrapped timer when the deadline is moved forward", func() {
+		clock := &resetCountingClock{}
+		t := NewTimerWithClock(clock)
+		t.Reset(time.Now().Add(time.Hour))
+		t.Reset(time.Now().Add(d))
+		Expect(clock.numResets).To(Equal(2))
+		Eventually(t.Chan()).Should(Receive())
+	})
 })
+
+// BenchmarkTimerPostponing simulates the timer usage when sending packets at a high rate:
+// Every packet sent postpones the deadline (e.g. of the PTO) a little bit.
+func BenchmarkTimerPostponing(b *testing.B) {
+	clock := &resetCountingClock{}
+	t := NewTimerWithClock(clock)
+	deadline := time.Now().Add(time.Hour)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		deadline = deadline.Add(time.Microsecond)
+		t.Reset(deadline)
+	}
+	b.ReportMetric(float64(clock.numResets)/float64(b.N), "resets/op")
+}
@@ -0,0 +1,80 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The ACK_FREQUENCY frame type is encoded as a 2-byte varint.
+// See https://datatracker.ietf.org/doc/draft-iyengar-quic-delayed-ack/.
+const ackFrequencyFrameType = 0xaf
+
+// An AckFrequencyFrame is an ACK_FREQUENCY frame
+type AckFrequencyFrame struct {
+	SequenceNumber    uint64
+	PacketTolerance   uint64
+	UpdateMaxAckDelay time.Duration
+	IgnoreOrder       bool
+}
+
+func parseAckFrequencyFrame(r *bytes.Reader, _ protocol.VersionNumber) (*AckFrequencyFrame, error) {
+	frameType, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if frameType != ackFrequencyFrameType {
+		return nil, errors.New("unknown frame type")
+	}
+	f := &AckFrequencyFrame{}
+	if f.SequenceNumber, err = utils.ReadVarInt(r); err != nil {
+		return nil, err
+	}
+	if f.PacketTolerance, err = utils.ReadVarInt(r); err != nil {
+		return nil, err
+	}
+	if f.PacketTolerance == 0 {
+		return nil, errors.New("packet tolerance must not be zero")
+	}
+	updateMaxAckDelay, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	f.UpdateMaxAckDelay = time.Duration(updateMaxAckDelay) * time.Microsecond
+	if f.UpdateMaxAckDelay < 0 {
+		f.UpdateMaxAckDelay = utils.InfDuration
+	}
+	ignoreOrder, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch ignoreOrder {
+	case 0:
+	case 1:
+		f.IgnoreOrder = true
+	default:
+		return nil, errors.New("invalid value for Ignore Order")
+	}
+	return f, nil
+}
+
+func (f *AckFrequencyFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
+	utils.WriteVarInt(b, ackFrequencyFrameType)
+	utils.WriteVarInt(b, f.SequenceNumber)
+	utils.WriteVarInt(b, f.PacketTolerance)
+	utils.WriteVarInt(b, uint64(f.UpdateMaxAckDelay/time.Microsecond))
+	if f.IgnoreOrder {
+		b.WriteByte(1)
+	} else {
+		b.WriteByte(0)
+	}
+	return nil
+}
+
+// Length of a written frame
+func (f *AckFrequencyFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return utils.VarIntLen(ackFrequencyFrameType) + utils.VarIntLen(f.SequenceNumber) + utils.VarIntLen(f.PacketTolerance) + utils.VarIntLen(uint64(f.UpdateMaxAckDelay/time.Microsecond)) + 1
+}
@@ -0,0 +1,97 @@
+package wire
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACK_FREQUENCY frame", func() {
+	Context("parsing", func() {
+		It("accepts a sample frame", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+			data = append(data, encodeVarInt(10)...)         // packet tolerance
+			data = append(data, encodeVarInt(1337)...)       // update max ack delay, in microseconds
+			data = append(data, 1)                           // ignore order
+			b := bytes.NewReader(data)
+			f, err := parseAckFrequencyFrame(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.SequenceNumber).To(Equal(uint64(0xdeadbeef)))
+			Expect(f.PacketTolerance).To(Equal(uint64(10)))
+			Expect(f.UpdateMaxAckDelay).To(Equal(1337 * time.Microsecond))
+			Expect(f.IgnoreOrder).To(BeTrue())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("rejects a packet tolerance of zero", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(1)...)
+			data = append(data, encodeVarInt(0)...)
+			data = append(data, encodeVarInt(1337)...)
+			data = append(data, 0)
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).To(MatchError("packet tolerance must not be zero"))
+		})
+
+		It("rejects invalid values for the Ignore Order field", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(1)...)
+			data = append(data, encodeVarInt(2)...)
+			data = append(data, encodeVarInt(1337)...)
+			data = append(data, 2)
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).To(MatchError("invalid value for Ignore Order"))
+		})
+
+		It("errors on EOFs", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...)
+			data = append(data, encodeVarInt(10)...)
+			data = append(data, encodeVarInt(1337)...)
+			data = append(data, 1)
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseAckFrequencyFrame(bytes.NewReader(data[0:i]), versionIETFFrames)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("writing", func() {
+		It("writes a sample frame", func() {
+			f := &AckFrequencyFrame{
+				SequenceNumber:    0xdecafbad,
+				PacketTolerance:   0xcafe,
+				UpdateMaxAckDelay: 42 * time.Millisecond,
+			}
+			b := &bytes.Buffer{}
+			Expect(f.Write(b, versionIETFFrames)).To(Succeed())
+			expected := encodeVarInt(0xaf)
+			expected = append(expected, encodeVarInt(0xdecafbad)...)
+			expected = append(expected, encodeVarInt(0xcafe)...)
+			expected = append(expected, encodeVarInt(42000)...)
+			expected = append(expected, 0)
+			Expect(b.Bytes()).To(Equal(expected))
+			Expect(f.Length(versionIETFFrames)).To(BeEquivalentTo(b.Len()))
+		})
+
+		It("writes the Ignore Order field", func() {
+			f := &AckFrequencyFrame{
+				SequenceNumber:    1,
+				PacketTolerance:   2,
+				UpdateMaxAckDelay: time.Millisecond,
+				IgnoreOrder:       true,
+			}
+			b := &bytes.Buffer{}
+			Expect(f.Write(b, versionIETFFrames)).To(Succeed())
+			Expect(b.Bytes()[b.Len()-1]).To(Equal(byte(1)))
+			Expect(f.Length(versionIETFFrames)).To(Equal(protocol.ByteCount(b.Len())))
+		})
+	})
+})
@@ -23,6 +23,8 @@ type ExtendedHeader struct {
 	typeByte byte
 
 	KeyPhase protocol.KeyPhaseBit
+	// SpinBit is the latency spin bit. It is only used for short header packets.
+	SpinBit bool
 
 	PacketNumberLen protocol.PacketNumberLen
 	PacketNumber    protocol.PacketNumber
@@ -69,6 +71,7 @@ func (h *ExtendedHeader) parseShortHeader(b *bytes.Reader, _ protocol.VersionNum
 	if h.typeByte&0x4 > 0 {
 		h.KeyPhase = protocol.KeyPhaseOne
 	}
+	h.SpinBit = h.typeByte&0x20 > 0
 
 	if err := h.readPacketNumber(b); err != nil {
 		return false, err
@@ -169,6 +172,9 @@ func (h *ExtendedHeader) writeShortHeader(b *bytes.Buffer, _ protocol.VersionNum
 	if h.KeyPhase == protocol.KeyPhaseOne {
 		typeByte |= byte(1 << 2)
 	}
+	if h.SpinBit {
+		typeByte |= 0x20
+	}
 
 	b.WriteByte(typeByte)
 	b.Write(h.DestConnectionID.Bytes())
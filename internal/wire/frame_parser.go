@@ -12,6 +12,8 @@ import (
 
 type frameParser struct {
 	ackDelayExponent uint8
+	// set when the RESET_STREAM_AT extension was negotiated
+	resetStreamAtEnabled bool
 
 	version protocol.VersionNumber
 }
@@ -57,6 +59,12 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseAckFrame(r, ackDelayExponent, p.version)
 		case 0x4:
 			frame, err = parseResetStreamFrame(r, p.version)
+		case 0x24:
+			if !p.resetStreamAtEnabled {
+				err = errors.New("RESET_STREAM_AT frame received, but the extension was not negotiated")
+				break
+			}
+			frame, err = parseResetStreamFrame(r, p.version)
 		case 0x5:
 			frame, err = parseStopSendingFrame(r, p.version)
 		case 0x6:
@@ -87,6 +95,8 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseConnectionCloseFrame(r, p.version)
 		case 0x1e:
 			frame, err = parseHandshakeDoneFrame(r, p.version)
+		case 0x40: // the first byte of a frame type encoded as a 2-byte varint
+			frame, err = parseAckFrequencyFrame(r, p.version)
 		default:
 			err = errors.New("unknown frame type")
 		}
@@ -126,3 +136,7 @@ func (p *frameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionL
 func (p *frameParser) SetAckDelayExponent(exp uint8) {
 	p.ackDelayExponent = exp
 }
+
+func (p *frameParser) EnableResetStreamAt() {
+	p.resetStreamAtEnabled = true
+}
@@ -93,6 +93,31 @@ var _ = Describe("Frame parsing", func() {
 		Expect(frame).To(Equal(f))
 	})
 
+	It("unpacks RESET_STREAM_AT frames", func() {
+		f := &ResetStreamFrame{
+			StreamID:     0xdeadbeef,
+			ByteOffset:   0xdecafbad1234,
+			ErrorCode:    0x1337,
+			ReliableSize: 0x1234,
+		}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		parser.EnableResetStreamAt()
+		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
+	It("rejects RESET_STREAM_AT frames if the extension was not negotiated", func() {
+		f := &ResetStreamFrame{
+			StreamID:     0xdeadbeef,
+			ByteOffset:   0xdecafbad1234,
+			ReliableSize: 0x1234,
+		}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		_, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x24): RESET_STREAM_AT frame received, but the extension was not negotiated"))
+	})
+
 	It("unpacks STOP_SENDING frames", func() {
 		f := &StopSendingFrame{StreamID: 0x42}
 		buf := &bytes.Buffer{}
@@ -280,6 +305,25 @@ var _ = Describe("Frame parsing", func() {
 		Expect(frame).To(Equal(f))
 	})
 
+	It("unpacks ACK_FREQUENCY frames", func() {
+		f := &AckFrequencyFrame{
+			SequenceNumber:    42,
+			PacketTolerance:   10,
+			UpdateMaxAckDelay: 20 * time.Millisecond,
+			IgnoreOrder:       true,
+		}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
+	It("errors on unknown 2-byte frame types", func() {
+		_, err := parser.ParseNext(bytes.NewReader(encodeVarInt(0xae)), protocol.Encryption1RTT)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x40): unknown frame type"))
+	})
+
 	It("errors on invalid type", func() {
 		_, err := parser.ParseNext(bytes.NewReader([]byte{0x42}), protocol.Encryption1RTT)
 		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x42): unknown frame type"))
@@ -318,6 +362,7 @@ var _ = Describe("Frame parsing", func() {
 			&PathResponseFrame{},
 			&ConnectionCloseFrame{},
 			&HandshakeDoneFrame{},
+			&AckFrequencyFrame{PacketTolerance: 1},
 		}
 
 		var framesSerialized [][]byte
@@ -0,0 +1,22 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// FrameType returns the frame type of a frame, as it is encoded on the wire.
+// For STREAM frames, this includes the OFF, LEN and FIN bits.
+func FrameType(f Frame, version protocol.VersionNumber) uint64 {
+	b := &bytes.Buffer{}
+	if err := f.Write(b, version); err != nil {
+		return 0
+	}
+	typ, err := utils.ReadVarInt(b)
+	if err != nil {
+		return 0
+	}
+	return typ
+}
@@ -0,0 +1,21 @@
+package wire
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Frame types", func() {
+	It("returns the type of STREAM frames", func() {
+		Expect(FrameType(&StreamFrame{StreamID: 1, Data: []byte("foobar")}, versionIETFFrames)).To(BeEquivalentTo(0x8))
+		Expect(FrameType(&StreamFrame{StreamID: 1, Data: []byte("foobar"), FinBit: true}, versionIETFFrames)).To(BeEquivalentTo(0x9))
+		Expect(FrameType(&StreamFrame{StreamID: 1, Data: []byte("foobar"), DataLenPresent: true}, versionIETFFrames)).To(BeEquivalentTo(0xa))
+		Expect(FrameType(&StreamFrame{StreamID: 1, Offset: 42, Data: []byte("foobar")}, versionIETFFrames)).To(BeEquivalentTo(0xc))
+	})
+
+	It("returns the type of other frames", func() {
+		Expect(FrameType(&MaxDataFrame{ByteOffset: 1337}, versionIETFFrames)).To(BeEquivalentTo(0x10))
+		Expect(FrameType(&ResetStreamFrame{StreamID: 3}, versionIETFFrames)).To(BeEquivalentTo(0x4))
+		Expect(FrameType(&AckFrequencyFrame{}, versionIETFFrames)).To(BeEquivalentTo(ackFrequencyFrameType))
+	})
+})
@@ -67,7 +67,18 @@ type Header struct {
 // If we understand the version, the packet is header up unto the packet number.
 // Otherwise, only the invariant part of the header is parsed.
 func ParsePacket(data []byte, shortHeaderConnIDLen int) (*Header, []byte /* packet data */, []byte /* rest */, error) {
-	hdr, err := parseHeader(bytes.NewReader(data), shortHeaderConnIDLen)
+	return parsePacket(data, shortHeaderConnIDLen, false)
+}
+
+// ParsePacketAllowingGreasedQUICBit parses a packet, like ParsePacket.
+// It accepts packets that have the QUIC bit (0x40) cleared.
+// It must only be used once the use of the grease_quic_bit transport parameter was negotiated.
+func ParsePacketAllowingGreasedQUICBit(data []byte, shortHeaderConnIDLen int) (*Header, []byte /* packet data */, []byte /* rest */, error) {
+	return parsePacket(data, shortHeaderConnIDLen, true)
+}
+
+func parsePacket(data []byte, shortHeaderConnIDLen int, allowGreasedQUICBit bool) (*Header, []byte /* packet data */, []byte /* rest */, error) {
+	hdr, err := parseHeader(bytes.NewReader(data), shortHeaderConnIDLen, allowGreasedQUICBit)
 	if err != nil {
 		if err == errUnsupportedVersion {
 			return hdr, nil, nil, nil
@@ -91,9 +102,9 @@ func ParsePacket(data []byte, shortHeaderConnIDLen int) (*Header, []byte /* pack
 // For long header packets:
 // * if we understand the version: up to the packet number
 // * if not, only the invariant part of the header
-func parseHeader(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error) {
+func parseHeader(b *bytes.Reader, shortHeaderConnIDLen int, allowGreasedQUICBit bool) (*Header, error) {
 	startLen := b.Len()
-	h, err := parseHeaderImpl(b, shortHeaderConnIDLen)
+	h, err := parseHeaderImpl(b, shortHeaderConnIDLen, allowGreasedQUICBit)
 	if err != nil {
 		return h, err
 	}
@@ -101,7 +112,7 @@ func parseHeader(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error) {
 	return h, err
 }
 
-func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error) {
+func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int, allowGreasedQUICBit bool) (*Header, error) {
 	typeByte, err := b.ReadByte()
 	if err != nil {
 		return nil, err
@@ -113,7 +124,7 @@ func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error)
 	}
 
 	if !h.IsLongHeader {
-		if h.typeByte&0x40 == 0 {
+		if !allowGreasedQUICBit && h.typeByte&0x40 == 0 {
 			return nil, errors.New("not a QUIC packet")
 		}
 		if err := h.parseShortHeader(b, shortHeaderConnIDLen); err != nil {
@@ -121,7 +132,7 @@ func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error)
 		}
 		return h, nil
 	}
-	return h, h.parseLongHeader(b)
+	return h, h.parseLongHeader(b, allowGreasedQUICBit)
 }
 
 func (h *Header) parseShortHeader(b *bytes.Reader, shortHeaderConnIDLen int) error {
@@ -130,13 +141,13 @@ func (h *Header) parseShortHeader(b *bytes.Reader, shortHeaderConnIDLen int) err
 	return err
 }
 
-func (h *Header) parseLongHeader(b *bytes.Reader) error {
+func (h *Header) parseLongHeader(b *bytes.Reader, allowGreasedQUICBit bool) error {
 	v, err := utils.BigEndian.ReadUint32(b)
 	if err != nil {
 		return err
 	}
 	h.Version = protocol.VersionNumber(v)
-	if h.Version != 0 && h.typeByte&0x40 == 0 {
+	if h.Version != 0 && !allowGreasedQUICBit && h.typeByte&0x40 == 0 {
 		return errors.New("not a QUIC packet")
 	}
 	destConnIDLen, err := b.ReadByte()
@@ -202,6 +202,25 @@ var _ = Describe("Header Parsing", func() {
 			Expect(err).To(MatchError("not a QUIC packet"))
 		})
 
+		It("accepts a cleared 0x40 bit, if greasing the QUIC bit was negotiated", func() {
+			data := []byte{0x80 | 0x2<<4}
+			data = appendVersion(data, versionIETFFrames)
+			data = append(data, 0x4) // dest conn id length
+			data = append(data, []byte{0xde, 0xca, 0xfb, 0xad}...)
+			data = append(data, 0x0)                // src conn id length
+			data = append(data, encodeVarInt(5)...) // length
+			data = append(data, []byte{0x42}...)    // packet number
+			data = append(data, []byte("1234")...)
+			_, _, _, err := ParsePacket(data, 0)
+			Expect(err).To(MatchError("not a QUIC packet"))
+			hdr, pdata, rest, err := ParsePacketAllowingGreasedQUICBit(data, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.Type).To(Equal(protocol.PacketTypeHandshake))
+			Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}))
+			Expect(pdata).To(Equal(data))
+			Expect(rest).To(BeEmpty())
+		})
+
 		It("stops parsing when encountering an unsupported version", func() {
 			data := []byte{
 				0xc0,
@@ -467,6 +486,18 @@ var _ = Describe("Header Parsing", func() {
 			Expect(err).To(MatchError("not a QUIC packet"))
 		})
 
+		It("accepts a cleared 0x40 bit, if greasing the QUIC bit was negotiated", func() {
+			connID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+			data := append([]byte{0x0}, connID...)
+			data = append(data, 0x42) // packet number
+			hdr, pdata, rest, err := ParsePacketAllowingGreasedQUICBit(data, 8)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.IsLongHeader).To(BeFalse())
+			Expect(hdr.DestConnectionID).To(Equal(connID))
+			Expect(pdata).To(Equal(data))
+			Expect(rest).To(BeEmpty())
+		})
+
 		It("errors if the 4th or 5th bit are set", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5}
 			data := append([]byte{0x40 | 0x10 /* set the 4th bit */}, connID...)
@@ -514,6 +545,23 @@ var _ = Describe("Header Parsing", func() {
 			Expect(b.Len()).To(BeZero())
 		})
 
+		It("reads the Spin Bit", func() {
+			data := []byte{
+				0x40 ^ 0x20,
+				0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, // connection ID
+			}
+			data = append(data, 11) // packet number
+			hdr, _, _, err := ParsePacket(data, 6)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.IsLongHeader).To(BeFalse())
+			b := bytes.NewReader(data)
+			extHdr, err := hdr.ParseExtended(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(extHdr.SpinBit).To(BeTrue())
+			Expect(extHdr.KeyPhase).To(Equal(protocol.KeyPhaseZero))
+			Expect(b.Len()).To(BeZero())
+		})
+
 		It("reads a header with a 2 byte packet number", func() {
 			data := []byte{
 				0x40 | 0x1,
@@ -16,4 +16,6 @@ type Frame interface {
 type FrameParser interface {
 	ParseNext(*bytes.Reader, protocol.EncryptionLevel) (Frame, error)
 	SetAckDelayExponent(uint8)
+	// EnableResetStreamAt allows RESET_STREAM_AT frames to be parsed.
+	EnableResetStreamAt()
 }
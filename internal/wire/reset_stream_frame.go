@@ -2,25 +2,30 @@ package wire
 
 import (
 	"bytes"
+	"errors"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 // A ResetStreamFrame is a RESET_STREAM frame in QUIC
+// If the ReliableSize is non-zero, it is sent as a RESET_STREAM_AT frame.
+// The peer then delivers the stream data up to the ReliableSize before resetting the stream.
 type ResetStreamFrame struct {
-	StreamID   protocol.StreamID
-	ErrorCode  protocol.ApplicationErrorCode
-	ByteOffset protocol.ByteCount
+	StreamID     protocol.StreamID
+	ErrorCode    protocol.ApplicationErrorCode
+	ByteOffset   protocol.ByteCount
+	ReliableSize protocol.ByteCount
 }
 
 func parseResetStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*ResetStreamFrame, error) {
-	if _, err := r.ReadByte(); err != nil { // read the TypeByte
+	typeByte, err := r.ReadByte()
+	if err != nil {
 		return nil, err
 	}
 
 	var streamID protocol.StreamID
-	var byteOffset protocol.ByteCount
+	var byteOffset, reliableSize protocol.ByteCount
 	sid, err := utils.ReadVarInt(r)
 	if err != nil {
 		return nil, err
@@ -35,23 +40,45 @@ func parseResetStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*ResetStr
 		return nil, err
 	}
 	byteOffset = protocol.ByteCount(bo)
+	if typeByte == 0x24 {
+		rs, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		reliableSize = protocol.ByteCount(rs)
+		if reliableSize > byteOffset {
+			return nil, errors.New("RESET_STREAM_AT frame: reliable size larger than final size")
+		}
+	}
 
 	return &ResetStreamFrame{
-		StreamID:   streamID,
-		ErrorCode:  protocol.ApplicationErrorCode(errorCode),
-		ByteOffset: byteOffset,
+		StreamID:     streamID,
+		ErrorCode:    protocol.ApplicationErrorCode(errorCode),
+		ByteOffset:   byteOffset,
+		ReliableSize: reliableSize,
 	}, nil
 }
 
 func (f *ResetStreamFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
-	b.WriteByte(0x4)
+	if f.ReliableSize > 0 {
+		b.WriteByte(0x24)
+	} else {
+		b.WriteByte(0x4)
+	}
 	utils.WriteVarInt(b, uint64(f.StreamID))
 	utils.WriteVarInt(b, uint64(f.ErrorCode))
 	utils.WriteVarInt(b, uint64(f.ByteOffset))
+	if f.ReliableSize > 0 {
+		utils.WriteVarInt(b, uint64(f.ReliableSize))
+	}
 	return nil
 }
 
 // Length of a written frame
 func (f *ResetStreamFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
-	return 1 + utils.VarIntLen(uint64(f.StreamID)) + utils.VarIntLen(uint64(f.ErrorCode)) + utils.VarIntLen(uint64(f.ByteOffset))
+	length := 1 + utils.VarIntLen(uint64(f.StreamID)) + utils.VarIntLen(uint64(f.ErrorCode)) + utils.VarIntLen(uint64(f.ByteOffset))
+	if f.ReliableSize > 0 {
+		length += utils.VarIntLen(uint64(f.ReliableSize))
+	}
+	return length
 }
@@ -22,6 +22,33 @@ var _ = Describe("RESET_STREAM frame", func() {
 			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
 			Expect(frame.ByteOffset).To(Equal(protocol.ByteCount(0x987654321)))
 			Expect(frame.ErrorCode).To(Equal(protocol.ApplicationErrorCode(0x1337)))
+			Expect(frame.ReliableSize).To(BeZero())
+		})
+
+		It("accepts a RESET_STREAM_AT frame", func() {
+			data := []byte{0x24}
+			data = append(data, encodeVarInt(0xdeadbeef)...)  // stream ID
+			data = append(data, encodeVarInt(0x1337)...)      // error code
+			data = append(data, encodeVarInt(0x987654321)...) // byte offset
+			data = append(data, encodeVarInt(0x123456)...)    // reliable size
+			b := bytes.NewReader(data)
+			frame, err := parseResetStreamFrame(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
+			Expect(frame.ByteOffset).To(Equal(protocol.ByteCount(0x987654321)))
+			Expect(frame.ErrorCode).To(Equal(protocol.ApplicationErrorCode(0x1337)))
+			Expect(frame.ReliableSize).To(Equal(protocol.ByteCount(0x123456)))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors if the reliable size is larger than the final size", func() {
+			data := []byte{0x24}
+			data = append(data, encodeVarInt(0xdeadbeef)...) // stream ID
+			data = append(data, encodeVarInt(0x1337)...)     // error code
+			data = append(data, encodeVarInt(0x1000)...)     // byte offset
+			data = append(data, encodeVarInt(0x1001)...)     // reliable size
+			_, err := parseResetStreamFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).To(MatchError("RESET_STREAM_AT frame: reliable size larger than final size"))
 		})
 
 		It("errors on EOFs", func() {
@@ -36,6 +63,20 @@ var _ = Describe("RESET_STREAM frame", func() {
 				Expect(err).To(HaveOccurred())
 			}
 		})
+
+		It("errors on EOFs, for RESET_STREAM_AT frames", func() {
+			data := []byte{0x24}
+			data = append(data, encodeVarInt(0xdeadbeef)...)  // stream ID
+			data = append(data, encodeVarInt(0x1337)...)      // error code
+			data = append(data, encodeVarInt(0x987654321)...) // byte offset
+			data = append(data, encodeVarInt(0x123456)...)    // reliable size
+			_, err := parseResetStreamFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseResetStreamFrame(bytes.NewReader(data[0:i]), versionIETFFrames)
+				Expect(err).To(HaveOccurred())
+			}
+		})
 	})
 
 	Context("when writing", func() {
@@ -55,6 +96,24 @@ var _ = Describe("RESET_STREAM frame", func() {
 			Expect(b.Bytes()).To(Equal(expected))
 		})
 
+		It("writes a RESET_STREAM_AT frame", func() {
+			frame := ResetStreamFrame{
+				StreamID:     0x1337,
+				ByteOffset:   0x11223344decafbad,
+				ErrorCode:    0xcafe,
+				ReliableSize: 0x42,
+			}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+			expected := []byte{0x24}
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, encodeVarInt(0xcafe)...)
+			expected = append(expected, encodeVarInt(0x11223344decafbad)...)
+			expected = append(expected, encodeVarInt(0x42)...)
+			Expect(b.Bytes()).To(Equal(expected))
+			Expect(frame.Length(versionIETFFrames)).To(BeEquivalentTo(b.Len()))
+		})
+
 		It("has the correct min length", func() {
 			rst := ResetStreamFrame{
 				StreamID:   0x1337,
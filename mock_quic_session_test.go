@@ -8,8 +8,10 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	handshake "github.com/lucas-clemente/quic-go/internal/handshake"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	qtls "github.com/marten-seemann/qtls"
 )
@@ -67,6 +69,34 @@ func (mr *MockQuicSessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockQuicSession)(nil).AcceptUniStream), arg0)
 }
 
+// AckFrequencyMaxAckDelay mocks base method
+func (m *MockQuicSession) AckFrequencyMaxAckDelay() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckFrequencyMaxAckDelay")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// AckFrequencyMaxAckDelay indicates an expected call of AckFrequencyMaxAckDelay
+func (mr *MockQuicSessionMockRecorder) AckFrequencyMaxAckDelay() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckFrequencyMaxAckDelay", reflect.TypeOf((*MockQuicSession)(nil).AckFrequencyMaxAckDelay))
+}
+
+// AckFrequencyPacketTolerance mocks base method
+func (m *MockQuicSession) AckFrequencyPacketTolerance() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckFrequencyPacketTolerance")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// AckFrequencyPacketTolerance indicates an expected call of AckFrequencyPacketTolerance
+func (mr *MockQuicSessionMockRecorder) AckFrequencyPacketTolerance() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckFrequencyPacketTolerance", reflect.TypeOf((*MockQuicSession)(nil).AckFrequencyPacketTolerance))
+}
+
 // CloseWithError mocks base method
 func (m *MockQuicSession) CloseWithError(arg0 protocol.ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -81,6 +111,34 @@ func (mr *MockQuicSessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockQuicSession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CoalescingStats mocks base method
+func (m *MockQuicSession) CoalescingStats() CoalescingStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CoalescingStats")
+	ret0, _ := ret[0].(CoalescingStats)
+	return ret0
+}
+
+// CoalescingStats indicates an expected call of CoalescingStats
+func (mr *MockQuicSessionMockRecorder) CoalescingStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CoalescingStats", reflect.TypeOf((*MockQuicSession)(nil).CoalescingStats))
+}
+
+// ConnectionIDs mocks base method
+func (m *MockQuicSession) ConnectionIDs() ConnectionIDs {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionIDs")
+	ret0, _ := ret[0].(ConnectionIDs)
+	return ret0
+}
+
+// ConnectionIDs indicates an expected call of ConnectionIDs
+func (mr *MockQuicSessionMockRecorder) ConnectionIDs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionIDs", reflect.TypeOf((*MockQuicSession)(nil).ConnectionIDs))
+}
+
 // ConnectionState mocks base method
 func (m *MockQuicSession) ConnectionState() qtls.ConnectionState {
 	m.ctrl.T.Helper()
@@ -109,6 +167,46 @@ func (mr *MockQuicSessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockQuicSession)(nil).Context))
 }
 
+// EstimatedBandwidth mocks base method
+func (m *MockQuicSession) EstimatedBandwidth() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EstimatedBandwidth")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// EstimatedBandwidth indicates an expected call of EstimatedBandwidth
+func (mr *MockQuicSessionMockRecorder) EstimatedBandwidth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EstimatedBandwidth", reflect.TypeOf((*MockQuicSession)(nil).EstimatedBandwidth))
+}
+
+// FlowControlOffsets mocks base method
+func (m *MockQuicSession) FlowControlOffsets() FlowControlOffsets {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlOffsets")
+	ret0, _ := ret[0].(FlowControlOffsets)
+	return ret0
+}
+
+// FlowControlOffsets indicates an expected call of FlowControlOffsets
+func (mr *MockQuicSessionMockRecorder) FlowControlOffsets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlOffsets", reflect.TypeOf((*MockQuicSession)(nil).FlowControlOffsets))
+}
+
+// FlushACKs mocks base method
+func (m *MockQuicSession) FlushACKs() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FlushACKs")
+}
+
+// FlushACKs indicates an expected call of FlushACKs
+func (mr *MockQuicSessionMockRecorder) FlushACKs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushACKs", reflect.TypeOf((*MockQuicSession)(nil).FlushACKs))
+}
+
 // GetVersion mocks base method
 func (m *MockQuicSession) GetVersion() protocol.VersionNumber {
 	m.ctrl.T.Helper()
@@ -137,6 +235,20 @@ func (mr *MockQuicSessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQuicSession)(nil).HandshakeComplete))
 }
 
+// LatestAckDelay mocks base method
+func (m *MockQuicSession) LatestAckDelay() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatestAckDelay")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// LatestAckDelay indicates an expected call of LatestAckDelay
+func (mr *MockQuicSessionMockRecorder) LatestAckDelay() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatestAckDelay", reflect.TypeOf((*MockQuicSession)(nil).LatestAckDelay))
+}
+
 // LocalAddr mocks base method
 func (m *MockQuicSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -151,6 +263,48 @@ func (mr *MockQuicSessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockQuicSession)(nil).LocalAddr))
 }
 
+// NumDroppedUndecryptablePackets mocks base method
+func (m *MockQuicSession) NumDroppedUndecryptablePackets() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NumDroppedUndecryptablePackets")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// NumDroppedUndecryptablePackets indicates an expected call of NumDroppedUndecryptablePackets
+func (mr *MockQuicSessionMockRecorder) NumDroppedUndecryptablePackets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumDroppedUndecryptablePackets", reflect.TypeOf((*MockQuicSession)(nil).NumDroppedUndecryptablePackets))
+}
+
+// NumKeyUpdates mocks base method
+func (m *MockQuicSession) NumKeyUpdates() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NumKeyUpdates")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// NumKeyUpdates indicates an expected call of NumKeyUpdates
+func (mr *MockQuicSessionMockRecorder) NumKeyUpdates() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumKeyUpdates", reflect.TypeOf((*MockQuicSession)(nil).NumKeyUpdates))
+}
+
+// NumSpuriousRetransmissions mocks base method
+func (m *MockQuicSession) NumSpuriousRetransmissions() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NumSpuriousRetransmissions")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// NumSpuriousRetransmissions indicates an expected call of NumSpuriousRetransmissions
+func (mr *MockQuicSessionMockRecorder) NumSpuriousRetransmissions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumSpuriousRetransmissions", reflect.TypeOf((*MockQuicSession)(nil).NumSpuriousRetransmissions))
+}
+
 // OpenStream mocks base method
 func (m *MockQuicSession) OpenStream() (Stream, error) {
 	m.ctrl.T.Helper()
@@ -181,6 +335,21 @@ func (mr *MockQuicSessionMockRecorder) OpenStreamSync(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamSync), arg0)
 }
 
+// OpenStreamWithWindow mocks base method
+func (m *MockQuicSession) OpenStreamWithWindow(arg0 uint64) (Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamWithWindow", arg0)
+	ret0, _ := ret[0].(Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamWithWindow indicates an expected call of OpenStreamWithWindow
+func (mr *MockQuicSessionMockRecorder) OpenStreamWithWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamWithWindow", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamWithWindow), arg0)
+}
+
 // OpenUniStream mocks base method
 func (m *MockQuicSession) OpenUniStream() (SendStream, error) {
 	m.ctrl.T.Helper()
@@ -211,6 +380,34 @@ func (mr *MockQuicSessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenUniStreamSync), arg0)
 }
 
+// PacketNumberSpaces mocks base method
+func (m *MockQuicSession) PacketNumberSpaces() PacketNumberSpaces {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacketNumberSpaces")
+	ret0, _ := ret[0].(PacketNumberSpaces)
+	return ret0
+}
+
+// PacketNumberSpaces indicates an expected call of PacketNumberSpaces
+func (mr *MockQuicSessionMockRecorder) PacketNumberSpaces() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacketNumberSpaces", reflect.TypeOf((*MockQuicSession)(nil).PacketNumberSpaces))
+}
+
+// PathUnhealthy mocks base method
+func (m *MockQuicSession) PathUnhealthy() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PathUnhealthy")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// PathUnhealthy indicates an expected call of PathUnhealthy
+func (mr *MockQuicSessionMockRecorder) PathUnhealthy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PathUnhealthy", reflect.TypeOf((*MockQuicSession)(nil).PathUnhealthy))
+}
+
 // RemoteAddr mocks base method
 func (m *MockQuicSession) RemoteAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -225,6 +422,102 @@ func (mr *MockQuicSessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQuicSession)(nil).RemoteAddr))
 }
 
+// ResetAllStreams mocks base method
+func (m *MockQuicSession) ResetAllStreams(arg0 protocol.ApplicationErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ResetAllStreams", arg0)
+}
+
+// ResetAllStreams indicates an expected call of ResetAllStreams
+func (mr *MockQuicSessionMockRecorder) ResetAllStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetAllStreams", reflect.TypeOf((*MockQuicSession)(nil).ResetAllStreams), arg0)
+}
+
+// RetirePeerConnectionID mocks base method
+func (m *MockQuicSession) RetirePeerConnectionID(arg0 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetirePeerConnectionID", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RetirePeerConnectionID indicates an expected call of RetirePeerConnectionID
+func (mr *MockQuicSessionMockRecorder) RetirePeerConnectionID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetirePeerConnectionID", reflect.TypeOf((*MockQuicSession)(nil).RetirePeerConnectionID), arg0)
+}
+
+// SendQueueDepth mocks base method
+func (m *MockQuicSession) SendQueueDepth() SendQueueDepth {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendQueueDepth")
+	ret0, _ := ret[0].(SendQueueDepth)
+	return ret0
+}
+
+// SendQueueDepth indicates an expected call of SendQueueDepth
+func (mr *MockQuicSessionMockRecorder) SendQueueDepth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendQueueDepth", reflect.TypeOf((*MockQuicSession)(nil).SendQueueDepth))
+}
+
+// SetCongestionControl mocks base method
+func (m *MockQuicSession) SetCongestionControl(arg0 CongestionControlAlgorithm) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCongestionControl", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCongestionControl indicates an expected call of SetCongestionControl
+func (mr *MockQuicSessionMockRecorder) SetCongestionControl(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCongestionControl", reflect.TypeOf((*MockQuicSession)(nil).SetCongestionControl), arg0)
+}
+
+// StreamCounts mocks base method
+func (m *MockQuicSession) StreamCounts() StreamCounts {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamCounts")
+	ret0, _ := ret[0].(StreamCounts)
+	return ret0
+}
+
+// StreamCounts indicates an expected call of StreamCounts
+func (mr *MockQuicSessionMockRecorder) StreamCounts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamCounts", reflect.TypeOf((*MockQuicSession)(nil).StreamCounts))
+}
+
+// UsedHelloRetryRequest mocks base method
+func (m *MockQuicSession) UsedHelloRetryRequest() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsedHelloRetryRequest")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// UsedHelloRetryRequest indicates an expected call of UsedHelloRetryRequest
+func (mr *MockQuicSessionMockRecorder) UsedHelloRetryRequest() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsedHelloRetryRequest", reflect.TypeOf((*MockQuicSession)(nil).UsedHelloRetryRequest))
+}
+
+// ZeroRTTRejection mocks base method
+func (m *MockQuicSession) ZeroRTTRejection() handshake.ZeroRTTRejection {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZeroRTTRejection")
+	ret0, _ := ret[0].(handshake.ZeroRTTRejection)
+	return ret0
+}
+
+// ZeroRTTRejection indicates an expected call of ZeroRTTRejection
+func (mr *MockQuicSessionMockRecorder) ZeroRTTRejection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZeroRTTRejection", reflect.TypeOf((*MockQuicSession)(nil).ZeroRTTRejection))
+}
+
 // closeForRecreating mocks base method
 func (m *MockQuicSession) closeForRecreating() protocol.PacketNumber {
 	m.ctrl.T.Helper()
@@ -48,6 +48,20 @@ func (mr *MockReceiveStreamIMockRecorder) CancelRead(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRead", reflect.TypeOf((*MockReceiveStreamI)(nil).CancelRead), arg0)
 }
 
+// FlowControlOffsets mocks base method
+func (m *MockReceiveStreamI) FlowControlOffsets() FlowControlOffsets {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlOffsets")
+	ret0, _ := ret[0].(FlowControlOffsets)
+	return ret0
+}
+
+// FlowControlOffsets indicates an expected call of FlowControlOffsets
+func (mr *MockReceiveStreamIMockRecorder) FlowControlOffsets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlOffsets", reflect.TypeOf((*MockReceiveStreamI)(nil).FlowControlOffsets))
+}
+
 // Read mocks base method
 func (m *MockReceiveStreamI) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -63,6 +77,22 @@ func (mr *MockReceiveStreamIMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockReceiveStreamI)(nil).Read), arg0)
 }
 
+// ReadBuffer mocks base method
+func (m *MockReceiveStreamI) ReadBuffer() ([]byte, func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadBuffer")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(func())
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadBuffer indicates an expected call of ReadBuffer
+func (mr *MockReceiveStreamIMockRecorder) ReadBuffer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadBuffer", reflect.TypeOf((*MockReceiveStreamI)(nil).ReadBuffer))
+}
+
 // SetReadDeadline mocks base method
 func (m *MockReceiveStreamI) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -77,6 +107,18 @@ func (mr *MockReceiveStreamIMockRecorder) SetReadDeadline(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReadDeadline), arg0)
 }
 
+// SetReceiveWindow mocks base method
+func (m *MockReceiveStreamI) SetReceiveWindow(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReceiveWindow", arg0)
+}
+
+// SetReceiveWindow indicates an expected call of SetReceiveWindow
+func (mr *MockReceiveStreamIMockRecorder) SetReceiveWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindow", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReceiveWindow), arg0)
+}
+
 // StreamID mocks base method
 func (m *MockReceiveStreamI) StreamID() protocol.StreamID {
 	m.ctrl.T.Helper()
@@ -50,6 +50,20 @@ func (mr *MockSendStreamIMockRecorder) CancelWrite(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWrite", reflect.TypeOf((*MockSendStreamI)(nil).CancelWrite), arg0)
 }
 
+// CancelWriteAt mocks base method
+func (m *MockSendStreamI) CancelWriteAt(arg0 protocol.ApplicationErrorCode, arg1 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelWriteAt", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelWriteAt indicates an expected call of CancelWriteAt
+func (mr *MockSendStreamIMockRecorder) CancelWriteAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWriteAt", reflect.TypeOf((*MockSendStreamI)(nil).CancelWriteAt), arg0, arg1)
+}
+
 // Close mocks base method
 func (m *MockSendStreamI) Close() error {
 	m.ctrl.T.Helper()
@@ -78,6 +92,34 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// FlowControlOffsets mocks base method
+func (m *MockSendStreamI) FlowControlOffsets() FlowControlOffsets {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlOffsets")
+	ret0, _ := ret[0].(FlowControlOffsets)
+	return ret0
+}
+
+// FlowControlOffsets indicates an expected call of FlowControlOffsets
+func (mr *MockSendStreamIMockRecorder) FlowControlOffsets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlOffsets", reflect.TypeOf((*MockSendStreamI)(nil).FlowControlOffsets))
+}
+
+// SendQueueDepth mocks base method
+func (m *MockSendStreamI) SendQueueDepth() SendQueueDepth {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendQueueDepth")
+	ret0, _ := ret[0].(SendQueueDepth)
+	return ret0
+}
+
+// SendQueueDepth indicates an expected call of SendQueueDepth
+func (mr *MockSendStreamIMockRecorder) SendQueueDepth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendQueueDepth", reflect.TypeOf((*MockSendStreamI)(nil).SendQueueDepth))
+}
+
 // SetWriteDeadline mocks base method
 func (m *MockSendStreamI) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -185,3 +227,17 @@ func (mr *MockSendStreamIMockRecorder) popStreamFrame(arg0 interface{}) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockSendStreamI)(nil).popStreamFrame), arg0)
 }
+
+// reserveSendWindow mocks base method
+func (m *MockSendStreamI) reserveSendWindow(arg0 protocol.ByteCount) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "reserveSendWindow", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// reserveSendWindow indicates an expected call of reserveSendWindow
+func (mr *MockSendStreamIMockRecorder) reserveSendWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "reserveSendWindow", reflect.TypeOf((*MockSendStreamI)(nil).reserveSendWindow), arg0)
+}
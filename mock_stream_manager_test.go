@@ -137,6 +137,18 @@ func (mr *MockStreamManagerMockRecorder) HandleMaxStreamsFrame(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMaxStreamsFrame", reflect.TypeOf((*MockStreamManager)(nil).HandleMaxStreamsFrame), arg0)
 }
 
+// HandleStreamsBlockedFrame mocks base method
+func (m *MockStreamManager) HandleStreamsBlockedFrame(arg0 *wire.StreamsBlockedFrame) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "HandleStreamsBlockedFrame", arg0)
+}
+
+// HandleStreamsBlockedFrame indicates an expected call of HandleStreamsBlockedFrame
+func (mr *MockStreamManagerMockRecorder) HandleStreamsBlockedFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleStreamsBlockedFrame", reflect.TypeOf((*MockStreamManager)(nil).HandleStreamsBlockedFrame), arg0)
+}
+
 // OpenStream mocks base method
 func (m *MockStreamManager) OpenStream() (Stream, error) {
 	m.ctrl.T.Helper()
@@ -197,6 +209,58 @@ func (mr *MockStreamManagerMockRecorder) OpenUniStreamSync(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockStreamManager)(nil).OpenUniStreamSync), arg0)
 }
 
+// RaiseIncomingLimits mocks base method
+func (m *MockStreamManager) RaiseIncomingLimits(arg0, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RaiseIncomingLimits", arg0, arg1)
+}
+
+// RaiseIncomingLimits indicates an expected call of RaiseIncomingLimits
+func (mr *MockStreamManagerMockRecorder) RaiseIncomingLimits(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RaiseIncomingLimits", reflect.TypeOf((*MockStreamManager)(nil).RaiseIncomingLimits), arg0, arg1)
+}
+
+// ResetAllStreams mocks base method
+func (m *MockStreamManager) ResetAllStreams(arg0 protocol.ApplicationErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ResetAllStreams", arg0)
+}
+
+// ResetAllStreams indicates an expected call of ResetAllStreams
+func (mr *MockStreamManagerMockRecorder) ResetAllStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetAllStreams", reflect.TypeOf((*MockStreamManager)(nil).ResetAllStreams), arg0)
+}
+
+// SendQueueDepth mocks base method
+func (m *MockStreamManager) SendQueueDepth() SendQueueDepth {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendQueueDepth")
+	ret0, _ := ret[0].(SendQueueDepth)
+	return ret0
+}
+
+// SendQueueDepth indicates an expected call of SendQueueDepth
+func (mr *MockStreamManagerMockRecorder) SendQueueDepth() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendQueueDepth", reflect.TypeOf((*MockStreamManager)(nil).SendQueueDepth))
+}
+
+// StreamCounts mocks base method
+func (m *MockStreamManager) StreamCounts() StreamCounts {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamCounts")
+	ret0, _ := ret[0].(StreamCounts)
+	return ret0
+}
+
+// StreamCounts indicates an expected call of StreamCounts
+func (mr *MockStreamManagerMockRecorder) StreamCounts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamCounts", reflect.TypeOf((*MockStreamManager)(nil).StreamCounts))
+}
+
 // UpdateLimits mocks base method
 func (m *MockStreamManager) UpdateLimits(arg0 *handshake.TransportParameters) error {
 	m.ctrl.T.Helper()
@@ -38,6 +38,7 @@ type packetHandlerMap struct {
 	statelessResetMutex   sync.Mutex
 	statelessResetHasher  hash.Hash
 
+	clock  utils.Clock
 	logger utils.Logger
 }
 
@@ -58,6 +59,7 @@ func newPacketHandlerMap(
 		deleteRetiredSessionsAfter: protocol.RetiredConnectionIDDeleteTimeout,
 		statelessResetEnabled:      len(statelessResetKey) > 0,
 		statelessResetHasher:       hmac.New(sha256.New, statelessResetKey),
+		clock:                      getClock(),
 		logger:                     logger,
 	}
 	go m.listen()
@@ -240,7 +242,7 @@ func (h *packetHandlerMap) handlePacket(
 		h.logger.Debugf("error parsing connection ID on packet from %s: %s", addr, err)
 		return
 	}
-	rcvTime := time.Now()
+	rcvTime := h.clock.Now()
 
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
@@ -91,7 +91,7 @@ func (p *packetContents) ToAckHandlerPacket(now time.Time, q *retransmissionQueu
 			p.frames[i].OnLost = q.AddInitial
 		case protocol.EncryptionHandshake:
 			p.frames[i].OnLost = q.AddHandshake
-		case protocol.Encryption1RTT:
+		case protocol.Encryption0RTT, protocol.Encryption1RTT:
 			p.frames[i].OnLost = q.AddAppData
 		}
 	}
@@ -146,6 +146,7 @@ type ackFrameSource interface {
 type packetPacker struct {
 	srcConnID     protocol.ConnectionID
 	getDestConnID func() protocol.ConnectionID
+	getSpinBit    func() bool
 
 	perspective protocol.Perspective
 	version     protocol.VersionNumber
@@ -170,6 +171,7 @@ var _ packer = &packetPacker{}
 func newPacketPacker(
 	srcConnID protocol.ConnectionID,
 	getDestConnID func() protocol.ConnectionID,
+	getSpinBit func() bool,
 	initialStream cryptoStream,
 	handshakeStream cryptoStream,
 	packetNumberManager packetNumberManager,
@@ -184,6 +186,7 @@ func newPacketPacker(
 	return &packetPacker{
 		cryptoSetup:         cryptoSetup,
 		getDestConnID:       getDestConnID,
+		getSpinBit:          getSpinBit,
 		srcConnID:           srcConnID,
 		initialStream:       initialStream,
 		handshakeStream:     handshakeStream,
@@ -617,8 +620,9 @@ func (p *packetPacker) getShortHeader(kp protocol.KeyPhaseBit) *wire.ExtendedHea
 	hdr := &wire.ExtendedHeader{}
 	hdr.PacketNumber = pn
 	hdr.PacketNumberLen = pnLen
-	hdr.DestConnectionID = p.getDestConnID()
+	hdr.DestConnectionID = rewriteDestConnectionID(p.getDestConnID())
 	hdr.KeyPhase = kp
+	hdr.SpinBit = p.getSpinBit()
 	return hdr
 }
 
@@ -628,7 +632,7 @@ func (p *packetPacker) getLongHeader(encLevel protocol.EncryptionLevel) *wire.Ex
 	hdr.IsLongHeader = true
 	hdr.Version = p.version
 	hdr.SrcConnectionID = p.srcConnID
-	hdr.DestConnectionID = p.getDestConnID()
+	hdr.DestConnectionID = rewriteDestConnectionID(p.getDestConnID())
 
 	// Set the length to the maximum packet size.
 	// Since it is encoded as a varint, this guarantees us that the header will end up at most as big as GetLength() returns.
@@ -79,6 +79,7 @@ var _ = Describe("Packet packer", func() {
 		packer = newPacketPacker(
 			protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
 			func() protocol.ConnectionID { return protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8} },
+			func() bool { return false },
 			initialStream,
 			handshakeStream,
 			pnManager,
@@ -139,6 +140,15 @@ var _ = Describe("Packet packer", func() {
 			Expect(h.PacketNumber).To(Equal(protocol.PacketNumber(0x1337)))
 			Expect(h.PacketNumberLen).To(Equal(protocol.PacketNumberLen4))
 			Expect(h.KeyPhase).To(Equal(protocol.KeyPhaseOne))
+			Expect(h.SpinBit).To(BeFalse())
+		})
+
+		It("sets the spin bit on short headers", func() {
+			pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen4)
+			packer.getSpinBit = func() bool { return true }
+			h := packer.getShortHeader(protocol.KeyPhaseZero)
+			Expect(h.IsLongHeader).To(BeFalse())
+			Expect(h.SpinBit).To(BeTrue())
 		})
 	})
 
@@ -1171,4 +1181,17 @@ var _ = Describe("Converting to AckHandler packets", func() {
 		p.Frames[1].OnLost(nil)
 		Expect(pingLost).To(BeTrue())
 	})
+
+	It("retransmits frames sent in 0-RTT packets as application data", func() {
+		packet := &packetContents{
+			header: &wire.ExtendedHeader{Header: wire.Header{IsLongHeader: true, Type: protocol.PacketType0RTT}},
+			frames: []ackhandler.Frame{{Frame: &wire.MaxDataFrame{ByteOffset: 1337}}},
+		}
+		q := newRetransmissionQueue(protocol.VersionTLS)
+		p := packet.ToAckHandlerPacket(time.Now(), q)
+		Expect(p.EncryptionLevel).To(Equal(protocol.Encryption0RTT))
+		Expect(p.Frames[0].OnLost).ToNot(BeNil())
+		p.Frames[0].OnLost(p.Frames[0].Frame)
+		Expect(q.GetAppDataFrame(protocol.MaxByteCount)).To(Equal(&wire.MaxDataFrame{ByteOffset: 1337}))
+	})
 })
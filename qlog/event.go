@@ -1,6 +1,7 @@
 package qlog
 
 import (
+	"fmt"
 	"net"
 	"sort"
 	"time"
@@ -73,7 +74,7 @@ type eventConnectionStarted struct {
 
 var _ eventDetails = &eventConnectionStarted{}
 
-func (e eventConnectionStarted) Category() category { return categoryTransport }
+func (e eventConnectionStarted) Category() category { return categoryConnectivity }
 func (e eventConnectionStarted) Name() string       { return "connection_started" }
 func (e eventConnectionStarted) IsNil() bool        { return false }
 
@@ -206,6 +207,46 @@ func (e eventUpdatedPTO) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.Uint32Key("pto_count", e.Value)
 }
 
+type eventCongestionStateUpdated struct {
+	State CongestionState
+}
+
+func (e eventCongestionStateUpdated) Category() category { return categoryRecovery }
+func (e eventCongestionStateUpdated) Name() string       { return "congestion_state_updated" }
+func (e eventCongestionStateUpdated) IsNil() bool        { return false }
+
+func (e eventCongestionStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("new", e.State.String())
+}
+
+type eventHandshakeProgressed struct {
+	Event HandshakeEvent
+}
+
+func (e eventHandshakeProgressed) Category() category { return categoryConnectivity }
+func (e eventHandshakeProgressed) Name() string       { return "handshake_progressed" }
+func (e eventHandshakeProgressed) IsNil() bool        { return false }
+
+func (e eventHandshakeProgressed) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("event", e.Event.String())
+}
+
+type eventSocketBufferLimited struct {
+	Buffer    SocketBuffer
+	Requested int
+	Actual    int
+}
+
+func (e eventSocketBufferLimited) Category() category { return categoryConnectivity }
+func (e eventSocketBufferLimited) Name() string       { return "socket_buffer_limited" }
+func (e eventSocketBufferLimited) IsNil() bool        { return false }
+
+func (e eventSocketBufferLimited) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("buffer", e.Buffer.String())
+	enc.IntKey("requested", e.Requested)
+	enc.IntKey("actual", e.Actual)
+}
+
 type eventPacketLost struct {
 	PacketType   PacketType
 	PacketNumber protocol.PacketNumber
@@ -238,3 +279,94 @@ func (e eventKeyUpdated) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("key_type", e.KeyType.String())
 	enc.Uint64KeyOmitEmpty("generation", uint64(e.Generation))
 }
+
+type owner uint8
+
+const (
+	ownerLocal owner = iota
+	ownerRemote
+)
+
+func (o owner) String() string {
+	switch o {
+	case ownerLocal:
+		return "local"
+	case ownerRemote:
+		return "remote"
+	default:
+		panic("unknown owner")
+	}
+}
+
+type eventTransportParameters struct {
+	Owner owner
+	*TransportParameters
+}
+
+func (e eventTransportParameters) Category() category { return categoryTransport }
+func (e eventTransportParameters) Name() string       { return "parameters_set" }
+func (e eventTransportParameters) IsNil() bool        { return false }
+
+func (e eventTransportParameters) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("owner", e.Owner.String())
+	if e.OriginalConnectionID != nil {
+		enc.StringKey("original_connection_id", connectionID(e.OriginalConnectionID).String())
+	}
+	if e.StatelessResetToken != nil {
+		enc.StringKey("stateless_reset_token", fmt.Sprintf("%x", e.StatelessResetToken[:]))
+	}
+	enc.BoolKey("disable_active_migration", e.DisableActiveMigration)
+	enc.FloatKeyOmitEmpty("max_idle_timeout", milliseconds(e.MaxIdleTimeout))
+	enc.Uint64KeyOmitEmpty("max_packet_size", uint64(e.MaxPacketSize))
+	enc.Uint8KeyOmitEmpty("ack_delay_exponent", e.AckDelayExponent)
+	enc.FloatKeyOmitEmpty("max_ack_delay", milliseconds(e.MaxAckDelay))
+	enc.FloatKeyOmitEmpty("min_ack_delay", milliseconds(e.MinAckDelay))
+	enc.Uint64KeyOmitEmpty("active_connection_id_limit", e.ActiveConnectionIDLimit)
+	enc.Int64KeyOmitEmpty("initial_max_data", int64(e.InitialMaxData))
+	enc.Int64KeyOmitEmpty("initial_max_stream_data_bidi_local", int64(e.InitialMaxStreamDataBidiLocal))
+	enc.Int64KeyOmitEmpty("initial_max_stream_data_bidi_remote", int64(e.InitialMaxStreamDataBidiRemote))
+	enc.Int64KeyOmitEmpty("initial_max_stream_data_uni", int64(e.InitialMaxStreamDataUni))
+	enc.Int64KeyOmitEmpty("initial_max_streams_bidi", e.InitialMaxStreamsBidi)
+	enc.Int64KeyOmitEmpty("initial_max_streams_uni", e.InitialMaxStreamsUni)
+	enc.BoolKey("reset_stream_at", e.EnableResetStreamAt)
+	enc.BoolKey("grease_quic_bit", e.GreaseQUICBit)
+	if e.PreferredAddress != nil {
+		enc.ObjectKey("preferred_address", preferredAddress(*e.PreferredAddress))
+	}
+	if len(e.UnknownParameters) > 0 {
+		enc.ArrayKey("unknown_parameters", unknownTransportParameters(e.UnknownParameters))
+	}
+}
+
+type preferredAddress PreferredAddress
+
+var _ gojay.MarshalerJSONObject = preferredAddress{}
+
+func (a preferredAddress) IsNil() bool { return false }
+func (a preferredAddress) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("ip_v4", a.IPv4.String())
+	enc.Uint16Key("port_v4", a.IPv4Port)
+	enc.StringKey("ip_v6", a.IPv6.String())
+	enc.Uint16Key("port_v6", a.IPv6Port)
+	enc.StringKey("connection_id", connectionID(a.ConnectionID).String())
+	enc.StringKey("stateless_reset_token", fmt.Sprintf("%x", a.StatelessResetToken))
+}
+
+type unknownTransportParameters []UnknownTransportParameter
+
+var _ gojay.MarshalerJSONArray = unknownTransportParameters{}
+
+func (ps unknownTransportParameters) IsNil() bool { return ps == nil }
+func (ps unknownTransportParameters) MarshalJSONArray(enc *gojay.Encoder) {
+	for _, p := range ps {
+		enc.Object(unknownTransportParameter(p))
+	}
+}
+
+type unknownTransportParameter UnknownTransportParameter
+
+func (p unknownTransportParameter) IsNil() bool { return false }
+func (p unknownTransportParameter) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint64Key("id", p.ID)
+	enc.StringKey("value", fmt.Sprintf("%x", p.Value))
+}
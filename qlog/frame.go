@@ -100,6 +100,8 @@ func (f frame) MarshalJSONObject(enc *gojay.Encoder) {
 		marshalConnectionCloseFrame(enc, frame)
 	case *wire.HandshakeDoneFrame:
 		marshalHandshakeDoneFrame(enc, frame)
+	case *wire.AckFrequencyFrame:
+		marshalAckFrequencyFrame(enc, frame)
 	default:
 		panic("unknown frame type")
 	}
@@ -145,6 +147,9 @@ func marshalResetStreamFrame(enc *gojay.Encoder, f *wire.ResetStreamFrame) {
 	enc.StringKey("stream_id", toString(int64(f.StreamID)))
 	enc.Int64Key("error_code", int64(f.ErrorCode))
 	enc.StringKey("final_size", toString(int64(f.ByteOffset)))
+	if f.ReliableSize > 0 {
+		enc.StringKey("reliable_size", toString(int64(f.ReliableSize)))
+	}
 }
 
 func marshalStopSendingFrame(enc *gojay.Encoder, f *wire.StopSendingFrame) {
@@ -246,3 +251,11 @@ func marshalConnectionCloseFrame(enc *gojay.Encoder, f *wire.ConnectionCloseFram
 func marshalHandshakeDoneFrame(enc *gojay.Encoder, _ *wire.HandshakeDoneFrame) {
 	enc.StringKey("frame_type", "handshake_done")
 }
+
+func marshalAckFrequencyFrame(enc *gojay.Encoder, f *wire.AckFrequencyFrame) {
+	enc.StringKey("frame_type", "ack_frequency")
+	enc.StringKey("sequence_number", toString(int64(f.SequenceNumber)))
+	enc.StringKey("packet_tolerance", toString(int64(f.PacketTolerance)))
+	enc.StringKey("update_max_ack_delay", toString(f.UpdateMaxAckDelay.Milliseconds()))
+	enc.BoolKey("ignore_order", f.IgnoreOrder)
+}
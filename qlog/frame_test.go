@@ -95,6 +95,24 @@ var _ = Describe("Frames", func() {
 		)
 	})
 
+	It("marshals RESET_STREAM_AT frames", func() {
+		check(
+			&wire.ResetStreamFrame{
+				StreamID:     987,
+				ByteOffset:   1234,
+				ErrorCode:    42,
+				ReliableSize: 123,
+			},
+			map[string]interface{}{
+				"frame_type":    "reset_stream",
+				"stream_id":     "987",
+				"error_code":    42,
+				"final_size":    "1234",
+				"reliable_size": "123",
+			},
+		)
+	})
+
 	It("marshals STOP_SENDING frames", func() {
 		check(
 			&wire.StopSendingFrame{
@@ -346,4 +364,22 @@ var _ = Describe("Frames", func() {
 			},
 		)
 	})
+
+	It("marshals ACK_FREQUENCY frames", func() {
+		check(
+			&wire.AckFrequencyFrame{
+				SequenceNumber:    42,
+				PacketTolerance:   10,
+				UpdateMaxAckDelay: 25 * time.Millisecond,
+				IgnoreOrder:       true,
+			},
+			map[string]interface{}{
+				"frame_type":           "ack_frequency",
+				"sequence_number":      "42",
+				"packet_tolerance":     "10",
+				"update_max_ack_delay": "25",
+				"ignore_order":         true,
+			},
+		)
+	})
 })
@@ -3,6 +3,7 @@ package qlog
 import (
 	"io"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -16,6 +17,10 @@ import (
 type Tracer interface {
 	Export() error
 	StartedConnection(t time.Time, local, remote net.Addr, version protocol.VersionNumber, srcConnID, destConnID protocol.ConnectionID)
+	// SentTransportParameters records the transport parameters sent to the peer.
+	SentTransportParameters(time.Time, *TransportParameters)
+	// ReceivedTransportParameters records the transport parameters received from the peer.
+	ReceivedTransportParameters(time.Time, *TransportParameters)
 	SentPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, ack *wire.AckFrame, frames []wire.Frame)
 	ReceivedRetry(time.Time, *wire.Header)
 	ReceivedPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, frames []wire.Frame)
@@ -23,15 +28,27 @@ type Tracer interface {
 	UpdatedMetrics(t time.Time, rttStats *congestion.RTTStats, cwnd protocol.ByteCount, bytesInFLight protocol.ByteCount, packetsInFlight int)
 	LostPacket(time.Time, protocol.EncryptionLevel, protocol.PacketNumber, PacketLossReason)
 	UpdatedPTOCount(time.Time, uint32)
+	// UpdatedCongestionState records a state change of the congestion controller.
+	UpdatedCongestionState(time.Time, CongestionState)
 	UpdatedKeyFromTLS(time.Time, protocol.EncryptionLevel, protocol.Perspective)
 	UpdatedKey(t time.Time, generation protocol.KeyPhase, remote bool)
+	// HandshakeProgressed records a milestone of the handshake.
+	// Every event is only recorded the first time it occurs.
+	HandshakeProgressed(time.Time, HandshakeEvent)
+	// LimitedSocketBuffer records that the operating system used a smaller UDP socket buffer than requested.
+	LimitedSocketBuffer(t time.Time, buffer SocketBuffer, requested, actual int)
 }
 
 type tracer struct {
+	// Events are recorded from the run loop of the session, as well as from the crypto setup.
+	mutex sync.Mutex
+
 	w           io.WriteCloser
 	odcid       protocol.ConnectionID
 	perspective protocol.Perspective
 
+	handshakeEvents map[HandshakeEvent]struct{}
+
 	events []event
 }
 
@@ -40,9 +57,10 @@ var _ Tracer = &tracer{}
 // NewTracer creates a new tracer to record a qlog.
 func NewTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) Tracer {
 	return &tracer{
-		w:           w,
-		perspective: p,
-		odcid:       odcid,
+		w:               w,
+		perspective:     p,
+		odcid:           odcid,
+		handshakeEvents: make(map[HandshakeEvent]struct{}),
 	}
 }
 
@@ -50,6 +68,9 @@ func (t *tracer) Active() bool { return true }
 
 // Export writes a qlog.
 func (t *tracer) Export() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
 	enc := gojay.NewEncoder(t.w)
 	tl := &topLevel{
 		traces: traces{
@@ -76,7 +97,7 @@ func (t *tracer) StartedConnection(time time.Time, local, remote net.Addr, versi
 	if !ok {
 		return
 	}
-	t.events = append(t.events, event{
+	t.recordEvent(event{
 		Time: time,
 		eventDetails: eventConnectionStarted{
 			SrcAddr:          localAddr,
@@ -88,6 +109,24 @@ func (t *tracer) StartedConnection(time time.Time, local, remote net.Addr, versi
 	})
 }
 
+func (t *tracer) SentTransportParameters(time time.Time, tp *TransportParameters) {
+	t.recordTransportParameters(time, ownerLocal, tp)
+}
+
+func (t *tracer) ReceivedTransportParameters(time time.Time, tp *TransportParameters) {
+	t.recordTransportParameters(time, ownerRemote, tp)
+}
+
+func (t *tracer) recordTransportParameters(time time.Time, owner owner, tp *TransportParameters) {
+	t.recordEvent(event{
+		Time: time,
+		eventDetails: eventTransportParameters{
+			Owner:               owner,
+			TransportParameters: tp,
+		},
+	})
+}
+
 func (t *tracer) SentPacket(time time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, ack *wire.AckFrame, frames []wire.Frame) {
 	numFrames := len(frames)
 	if ack != nil {
@@ -102,7 +141,7 @@ func (t *tracer) SentPacket(time time.Time, hdr *wire.ExtendedHeader, packetSize
 	}
 	header := *transformExtendedHeader(hdr)
 	header.PacketSize = packetSize
-	t.events = append(t.events, event{
+	t.recordEvent(event{
 		Time: time,
 		eventDetails: eventPacketSent{
 			PacketType: PacketTypeFromHeader(&hdr.Header),
@@ -119,7 +158,7 @@ func (t *tracer) ReceivedPacket(time time.Time, hdr *wire.ExtendedHeader, packet
 	}
 	header := *transformExtendedHeader(hdr)
 	header.PacketSize = packetSize
-	t.events = append(t.events, event{
+	t.recordEvent(event{
 		Time: time,
 		eventDetails: eventPacketReceived{
 			PacketType: PacketTypeFromHeader(&hdr.Header),
@@ -130,7 +169,7 @@ func (t *tracer) ReceivedPacket(time time.Time, hdr *wire.ExtendedHeader, packet
 }
 
 func (t *tracer) ReceivedRetry(time time.Time, hdr *wire.Header) {
-	t.events = append(t.events, event{
+	t.recordEvent(event{
 		Time: time,
 		eventDetails: eventRetryReceived{
 			Header: *transformHeader(hdr),
@@ -139,14 +178,14 @@ func (t *tracer) ReceivedRetry(time time.Time, hdr *wire.Header) {
 }
 
 func (t *tracer) BufferedPacket(time time.Time, packetType PacketType) {
-	t.events = append(t.events, event{
+	t.recordEvent(event{
 		Time:         time,
 		eventDetails: eventPacketBuffered{PacketType: packetType},
 	})
 }
 
 func (t *tracer) UpdatedMetrics(time time.Time, rttStats *congestion.RTTStats, cwnd, bytesInFlight protocol.ByteCount, packetsInFlight int) {
-	t.events = append(t.events, event{
+	t.recordEvent(event{
 		Time: time,
 		eventDetails: eventMetricsUpdated{
 			MinRTT:           rttStats.MinRTT(),
@@ -161,7 +200,7 @@ func (t *tracer) UpdatedMetrics(time time.Time, rttStats *congestion.RTTStats, c
 }
 
 func (t *tracer) LostPacket(time time.Time, encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, lossReason PacketLossReason) {
-	t.events = append(t.events, event{
+	t.recordEvent(event{
 		Time: time,
 		eventDetails: eventPacketLost{
 			PacketType:   getPacketTypeFromEncryptionLevel(encLevel),
@@ -172,14 +211,21 @@ func (t *tracer) LostPacket(time time.Time, encLevel protocol.EncryptionLevel, p
 }
 
 func (t *tracer) UpdatedPTOCount(time time.Time, value uint32) {
-	t.events = append(t.events, event{
+	t.recordEvent(event{
 		Time:         time,
 		eventDetails: eventUpdatedPTO{Value: value},
 	})
 }
 
+func (t *tracer) UpdatedCongestionState(time time.Time, state CongestionState) {
+	t.recordEvent(event{
+		Time:         time,
+		eventDetails: eventCongestionStateUpdated{State: state},
+	})
+}
+
 func (t *tracer) UpdatedKeyFromTLS(time time.Time, encLevel protocol.EncryptionLevel, pers protocol.Perspective) {
-	t.events = append(t.events, event{
+	t.recordEvent(event{
 		Time: time,
 		eventDetails: eventKeyUpdated{
 			Trigger: keyUpdateTLS,
@@ -193,20 +239,53 @@ func (t *tracer) UpdatedKey(time time.Time, generation protocol.KeyPhase, remote
 	if remote {
 		trigger = keyUpdateRemote
 	}
-	t.events = append(t.events, event{
+	t.recordEvent(
+		event{
+			Time: time,
+			eventDetails: eventKeyUpdated{
+				Trigger:    trigger,
+				KeyType:    keyTypeClient1RTT,
+				Generation: generation,
+			},
+		},
+		event{
+			Time: time,
+			eventDetails: eventKeyUpdated{
+				Trigger:    trigger,
+				KeyType:    keyTypeServer1RTT,
+				Generation: generation,
+			},
+		},
+	)
+}
+
+func (t *tracer) LimitedSocketBuffer(time time.Time, buffer SocketBuffer, requested, actual int) {
+	t.recordEvent(event{
 		Time: time,
-		eventDetails: eventKeyUpdated{
-			Trigger:    trigger,
-			KeyType:    keyTypeClient1RTT,
-			Generation: generation,
+		eventDetails: eventSocketBufferLimited{
+			Buffer:    buffer,
+			Requested: requested,
+			Actual:    actual,
 		},
 	})
+}
+
+func (t *tracer) HandshakeProgressed(time time.Time, e HandshakeEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, ok := t.handshakeEvents[e]; ok {
+		return
+	}
+	t.handshakeEvents[e] = struct{}{}
 	t.events = append(t.events, event{
-		Time: time,
-		eventDetails: eventKeyUpdated{
-			Trigger:    trigger,
-			KeyType:    keyTypeServer1RTT,
-			Generation: generation,
-		},
+		Time:         time,
+		eventDetails: eventHandshakeProgressed{Event: e},
 	})
 }
+
+func (t *tracer) recordEvent(events ...event) {
+	t.mutex.Lock()
+	t.events = append(t.events, events...)
+	t.mutex.Unlock()
+}
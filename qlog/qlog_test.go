@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -112,7 +113,7 @@ var _ = Describe("Tracer", func() {
 			)
 			entry := exportAndParseSingle()
 			Expect(entry.Time).To(BeTemporally("~", now, time.Millisecond))
-			Expect(entry.Category).To(Equal("transport"))
+			Expect(entry.Category).To(Equal("connectivity"))
 			Expect(entry.Name).To(Equal("connection_started"))
 			ev := entry.Event
 			Expect(ev).To(HaveKeyWithValue("ip_version", "ipv4"))
@@ -125,6 +126,78 @@ var _ = Describe("Tracer", func() {
 			Expect(ev).To(HaveKeyWithValue("dst_cid", "05060708"))
 		})
 
+		It("records sent transport parameters", func() {
+			now := time.Now()
+			tracer.SentTransportParameters(now, &TransportParameters{
+				InitialMaxStreamDataBidiLocal:  1000,
+				InitialMaxStreamDataBidiRemote: 2000,
+				InitialMaxStreamDataUni:        3000,
+				InitialMaxData:                 4000,
+				InitialMaxStreamsBidi:          10,
+				InitialMaxStreamsUni:           20,
+				MaxAckDelay:                    123 * time.Millisecond,
+				AckDelayExponent:               12,
+				DisableActiveMigration:         true,
+				ActiveConnectionIDLimit:        7,
+				MaxIdleTimeout:                 321 * time.Millisecond,
+				StatelessResetToken:            &[16]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00},
+				OriginalConnectionID:           protocol.ConnectionID{0xde, 0xad, 0xc0, 0xde},
+				UnknownParameters:              []UnknownTransportParameter{{ID: 0x1337, Value: []byte{0xca, 0xfe}}},
+			})
+			entry := exportAndParseSingle()
+			Expect(entry.Time).To(BeTemporally("~", now, time.Millisecond))
+			Expect(entry.Category).To(Equal("transport"))
+			Expect(entry.Name).To(Equal("parameters_set"))
+			ev := entry.Event
+			Expect(ev).To(HaveKeyWithValue("owner", "local"))
+			Expect(ev).To(HaveKeyWithValue("original_connection_id", "deadc0de"))
+			Expect(ev).To(HaveKeyWithValue("stateless_reset_token", "112233445566778899aabbccddeeff00"))
+			Expect(ev).To(HaveKeyWithValue("max_idle_timeout", float64(321)))
+			Expect(ev).To(HaveKeyWithValue("max_ack_delay", float64(123)))
+			Expect(ev).To(HaveKeyWithValue("ack_delay_exponent", float64(12)))
+			Expect(ev).To(HaveKeyWithValue("disable_active_migration", true))
+			Expect(ev).To(HaveKeyWithValue("active_connection_id_limit", float64(7)))
+			Expect(ev).To(HaveKeyWithValue("initial_max_data", float64(4000)))
+			Expect(ev).To(HaveKeyWithValue("initial_max_stream_data_bidi_local", float64(1000)))
+			Expect(ev).To(HaveKeyWithValue("initial_max_stream_data_bidi_remote", float64(2000)))
+			Expect(ev).To(HaveKeyWithValue("initial_max_stream_data_uni", float64(3000)))
+			Expect(ev).To(HaveKeyWithValue("initial_max_streams_bidi", float64(10)))
+			Expect(ev).To(HaveKeyWithValue("initial_max_streams_uni", float64(20)))
+			Expect(ev).ToNot(HaveKey("preferred_address"))
+			Expect(ev).To(HaveKey("unknown_parameters"))
+			unknown := ev["unknown_parameters"].([]interface{})
+			Expect(unknown).To(HaveLen(1))
+			Expect(unknown[0]).To(HaveKeyWithValue("id", float64(0x1337)))
+			Expect(unknown[0]).To(HaveKeyWithValue("value", "cafe"))
+		})
+
+		It("records received transport parameters", func() {
+			tracer.ReceivedTransportParameters(time.Now(), &TransportParameters{
+				PreferredAddress: &PreferredAddress{
+					IPv4:                net.IPv4(12, 34, 56, 78),
+					IPv4Port:            123,
+					IPv6:                net.IP{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+					IPv6Port:            456,
+					ConnectionID:        protocol.ConnectionID{8, 7, 6, 5, 4, 3, 2, 1},
+					StatelessResetToken: [16]byte{0xf, 0xe, 0xd, 0xc, 0xb, 0xa, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+				},
+			})
+			entry := exportAndParseSingle()
+			Expect(entry.Name).To(Equal("parameters_set"))
+			ev := entry.Event
+			Expect(ev).To(HaveKeyWithValue("owner", "remote"))
+			Expect(ev).ToNot(HaveKey("original_connection_id"))
+			Expect(ev).ToNot(HaveKey("unknown_parameters"))
+			Expect(ev).To(HaveKey("preferred_address"))
+			pa := ev["preferred_address"].(map[string]interface{})
+			Expect(pa).To(HaveKeyWithValue("ip_v4", "12.34.56.78"))
+			Expect(pa).To(HaveKeyWithValue("port_v4", float64(123)))
+			Expect(pa).To(HaveKeyWithValue("ip_v6", "102:304:506:708:90a:b0c:d0e:f10"))
+			Expect(pa).To(HaveKeyWithValue("port_v6", float64(456)))
+			Expect(pa).To(HaveKeyWithValue("connection_id", "0807060504030201"))
+			Expect(pa).To(HaveKeyWithValue("stateless_reset_token", "0f0e0d0c0b0a09080706050403020100"))
+		})
+
 		It("records a sent packet, without an ACK", func() {
 			now := time.Now()
 			tracer.SentPacket(
@@ -290,6 +363,16 @@ var _ = Describe("Tracer", func() {
 			Expect(ev).To(HaveKeyWithValue("packets_in_flight", float64(42)))
 		})
 
+		It("records congestion state updates", func() {
+			now := time.Now()
+			tracer.UpdatedCongestionState(now, CongestionStateRecovery)
+			entry := exportAndParseSingle()
+			Expect(entry.Time).To(BeTemporally("~", now, time.Millisecond))
+			Expect(entry.Category).To(Equal("recovery"))
+			Expect(entry.Name).To(Equal("congestion_state_updated"))
+			Expect(entry.Event).To(HaveKeyWithValue("new", "recovery"))
+		})
+
 		It("records lost packets", func() {
 			now := time.Now()
 			tracer.LostPacket(now, protocol.EncryptionHandshake, 42, PacketLossReorderingThreshold)
@@ -313,6 +396,63 @@ var _ = Describe("Tracer", func() {
 			Expect(entry.Event).To(HaveKeyWithValue("pto_count", float64(42)))
 		})
 
+		It("records handshake progress", func() {
+			now := time.Now()
+			tracer.HandshakeProgressed(now, HandshakeEventServerHelloSent)
+			entry := exportAndParseSingle()
+			Expect(entry.Time).To(BeTemporally("~", now, time.Millisecond))
+			Expect(entry.Category).To(Equal("connectivity"))
+			Expect(entry.Name).To(Equal("handshake_progressed"))
+			Expect(entry.Event).To(HaveKeyWithValue("event", "server_hello_sent"))
+		})
+
+		It("only records the first occurrence of a handshake event", func() {
+			now := time.Now()
+			tracer.HandshakeProgressed(now, HandshakeEventInitialSent)
+			tracer.HandshakeProgressed(now.Add(time.Second), HandshakeEventInitialSent)
+			tracer.HandshakeProgressed(now.Add(2*time.Second), HandshakeEventComplete)
+			entries := exportAndParse()
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Event).To(HaveKeyWithValue("event", "initial_sent"))
+			Expect(entries[0].Time).To(BeTemporally("~", now, time.Millisecond))
+			Expect(entries[1].Event).To(HaveKeyWithValue("event", "handshake_complete"))
+		})
+
+		It("records events concurrently", func() {
+			now := time.Now()
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for i := 0; i < 10; i++ {
+					tracer.HandshakeProgressed(now, HandshakeEventComplete)
+				}
+			}()
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for i := 0; i < 10; i++ {
+					tracer.UpdatedPTOCount(now, uint32(i))
+				}
+			}()
+			wg.Wait()
+			Expect(exportAndParse()).To(HaveLen(11))
+		})
+
+		It("records limited socket buffers", func() {
+			now := time.Now()
+			tracer.LimitedSocketBuffer(now, SocketBufferSend, 1<<24, 1<<18)
+			entry := exportAndParseSingle()
+			Expect(entry.Time).To(BeTemporally("~", now, time.Millisecond))
+			Expect(entry.Category).To(Equal("connectivity"))
+			Expect(entry.Name).To(Equal("socket_buffer_limited"))
+			ev := entry.Event
+			Expect(ev).To(HaveKeyWithValue("buffer", "send"))
+			Expect(ev).To(HaveKeyWithValue("requested", float64(1<<24)))
+			Expect(ev).To(HaveKeyWithValue("actual", float64(1<<18)))
+		})
+
 		It("records TLS key updates", func() {
 			now := time.Now()
 			tracer.UpdatedKeyFromTLS(now, protocol.EncryptionHandshake, protocol.PerspectiveClient)
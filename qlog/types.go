@@ -2,7 +2,9 @@ package qlog
 
 import (
 	"fmt"
+	"net"
 	"strconv"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
@@ -118,6 +120,93 @@ func (r PacketLossReason) String() string {
 	}
 }
 
+// CongestionState is the state of the congestion controller
+type CongestionState uint8
+
+const (
+	// CongestionStateSlowStart: the congestion controller is in slow start
+	CongestionStateSlowStart CongestionState = iota
+	// CongestionStateCongestionAvoidance: the congestion controller is in congestion avoidance
+	CongestionStateCongestionAvoidance
+	// CongestionStateRecovery: the congestion controller is in recovery
+	CongestionStateRecovery
+)
+
+func (s CongestionState) String() string {
+	switch s {
+	case CongestionStateSlowStart:
+		return "slow_start"
+	case CongestionStateCongestionAvoidance:
+		return "congestion_avoidance"
+	case CongestionStateRecovery:
+		return "recovery"
+	default:
+		panic("unknown congestion state")
+	}
+}
+
+// HandshakeEvent is a milestone of the handshake
+type HandshakeEvent uint8
+
+const (
+	// HandshakeEventInitialSent: the first Initial packet was sent
+	HandshakeEventInitialSent HandshakeEvent = iota
+	// HandshakeEventInitialReceived: the first Initial packet was received
+	HandshakeEventInitialReceived
+	// HandshakeEventServerHelloSent: the server sent the ServerHello
+	HandshakeEventServerHelloSent
+	// HandshakeEventServerHelloReceived: the client received the ServerHello
+	HandshakeEventServerHelloReceived
+	// HandshakeEventComplete: the handshake completed
+	HandshakeEventComplete
+	// HandshakeEventDoneSent: the server sent the HANDSHAKE_DONE frame
+	HandshakeEventDoneSent
+	// HandshakeEventDoneReceived: the client received the HANDSHAKE_DONE frame
+	HandshakeEventDoneReceived
+)
+
+func (e HandshakeEvent) String() string {
+	switch e {
+	case HandshakeEventInitialSent:
+		return "initial_sent"
+	case HandshakeEventInitialReceived:
+		return "initial_received"
+	case HandshakeEventServerHelloSent:
+		return "server_hello_sent"
+	case HandshakeEventServerHelloReceived:
+		return "server_hello_received"
+	case HandshakeEventComplete:
+		return "handshake_complete"
+	case HandshakeEventDoneSent:
+		return "handshake_done_sent"
+	case HandshakeEventDoneReceived:
+		return "handshake_done_received"
+	default:
+		panic("unknown handshake event")
+	}
+}
+
+// SocketBuffer is a buffer of the UDP socket
+type SocketBuffer uint8
+
+const (
+	// SocketBufferReceive is the receive buffer
+	SocketBufferReceive SocketBuffer = iota
+	// SocketBufferSend is the send buffer
+	SocketBufferSend
+)
+
+func (b SocketBuffer) String() string {
+	switch b {
+	case SocketBufferReceive:
+		return "receive"
+	case SocketBufferSend:
+		return "send"
+	default:
+		panic("unknown socket buffer")
+	}
+}
+
 type keyType uint8
 
 const (
@@ -203,3 +292,47 @@ func (t keyUpdateTrigger) String() string {
 		panic("unknown key update trigger")
 	}
 }
+
+// TransportParameters are the transport parameters sent or received during the handshake.
+type TransportParameters struct {
+	OriginalConnectionID    protocol.ConnectionID
+	StatelessResetToken     *[16]byte
+	DisableActiveMigration  bool
+	MaxIdleTimeout          time.Duration
+	MaxPacketSize           protocol.ByteCount
+	AckDelayExponent        uint8
+	MaxAckDelay             time.Duration
+	MinAckDelay             time.Duration
+	ActiveConnectionIDLimit uint64
+
+	InitialMaxData                 protocol.ByteCount
+	InitialMaxStreamDataBidiLocal  protocol.ByteCount
+	InitialMaxStreamDataBidiRemote protocol.ByteCount
+	InitialMaxStreamDataUni        protocol.ByteCount
+	InitialMaxStreamsBidi          int64
+	InitialMaxStreamsUni           int64
+
+	EnableResetStreamAt bool
+	GreaseQUICBit       bool
+
+	PreferredAddress *PreferredAddress
+
+	// UnknownParameters are the transport parameters that quic-go doesn't know, including greased ones.
+	UnknownParameters []UnknownTransportParameter
+}
+
+// PreferredAddress is the preferred address sent by the server.
+type PreferredAddress struct {
+	IPv4                net.IP
+	IPv4Port            uint16
+	IPv6                net.IP
+	IPv6Port            uint16
+	ConnectionID        protocol.ConnectionID
+	StatelessResetToken [16]byte
+}
+
+// An UnknownTransportParameter is a transport parameter that quic-go doesn't know.
+type UnknownTransportParameter struct {
+	ID    uint64
+	Value []byte
+}
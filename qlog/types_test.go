@@ -23,6 +23,27 @@ var _ = Describe("Types", func() {
 		Expect(PacketTypeVersionNegotiation.String()).To(Equal("version_negotiation"))
 	})
 
+	It("has a string representation for the congestion state", func() {
+		Expect(CongestionStateSlowStart.String()).To(Equal("slow_start"))
+		Expect(CongestionStateCongestionAvoidance.String()).To(Equal("congestion_avoidance"))
+		Expect(CongestionStateRecovery.String()).To(Equal("recovery"))
+	})
+
+	It("has a string representation for the handshake event", func() {
+		Expect(HandshakeEventInitialSent.String()).To(Equal("initial_sent"))
+		Expect(HandshakeEventInitialReceived.String()).To(Equal("initial_received"))
+		Expect(HandshakeEventServerHelloSent.String()).To(Equal("server_hello_sent"))
+		Expect(HandshakeEventServerHelloReceived.String()).To(Equal("server_hello_received"))
+		Expect(HandshakeEventComplete.String()).To(Equal("handshake_complete"))
+		Expect(HandshakeEventDoneSent.String()).To(Equal("handshake_done_sent"))
+		Expect(HandshakeEventDoneReceived.String()).To(Equal("handshake_done_received"))
+	})
+
+	It("has a string representation for the socket buffer", func() {
+		Expect(SocketBufferReceive.String()).To(Equal("receive"))
+		Expect(SocketBufferSend.String()).To(Equal("send"))
+	})
+
 	It("has a string representation for the key type", func() {
 		Expect(encLevelToKeyType(protocol.EncryptionInitial, protocol.PerspectiveClient).String()).To(Equal("client_initial_secret"))
 		Expect(encLevelToKeyType(protocol.EncryptionInitial, protocol.PerspectiveServer).String()).To(Equal("server_initial_secret"))
@@ -45,6 +45,9 @@ type receiveStream struct {
 	finRead           bool // set once we read a frame with a FinBit
 	canceledRead      bool // set when CancelRead() is called
 	resetRemotely     bool // set when HandleResetStreamFrame() is called
+	resetPending      bool // set when a RESET_STREAM_AT frame is received, until all data up to the reliable size was read
+
+	reliableSize protocol.ByteCount // only valid if resetPending is set
 
 	readChan chan struct{}
 	deadline time.Time
@@ -81,26 +84,43 @@ func (s *receiveStream) StreamID() protocol.StreamID {
 func (s *receiveStream) Read(p []byte) (int, error) {
 	s.mutex.Lock()
 	completed, n, err := s.readImpl(p)
+	// If the stream was reset with a RESET_STREAM_AT frame, there might be data beyond the reliable size that was never read.
+	abandon := completed && s.resetRemotely
 	s.mutex.Unlock()
 
+	if abandon {
+		s.flowController.Abandon()
+	}
 	if completed {
 		s.sender.onStreamCompleted(s.streamID)
 	}
 	return n, err
 }
 
-func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, error) {
-	if s.finRead {
-		return false, 0, io.EOF
+// ReadBuffer returns the next chunk of data received on the stream, without copying it.
+// It is not thread safe!
+func (s *receiveStream) ReadBuffer() ([]byte, func(), error) {
+	s.mutex.Lock()
+	completed, data, release, err := s.readBufferImpl()
+	// If the stream was reset with a RESET_STREAM_AT frame, there might be data beyond the reliable size that was never read.
+	abandon := completed && s.resetRemotely
+	s.mutex.Unlock()
+
+	if abandon {
+		s.flowController.Abandon()
 	}
-	if s.canceledRead {
-		return false, 0, s.cancelReadErr
+	if completed {
+		s.sender.onStreamCompleted(s.streamID)
 	}
-	if s.resetRemotely {
-		return false, 0, s.resetRemotelyErr
+	if release == nil {
+		release = func() {}
 	}
-	if s.closedForShutdown {
-		return false, 0, s.closeForShutdownErr
+	return data, release, err
+}
+
+func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, error) {
+	if completed, err := s.checkReadable(); err != nil {
+		return completed, 0, err
 	}
 
 	bytesRead := 0
@@ -111,49 +131,8 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 		if s.currentFrame == nil && bytesRead > 0 {
 			return false, bytesRead, s.closeForShutdownErr
 		}
-
-		var deadlineTimer *utils.Timer
-		for {
-			// Stop waiting on errors
-			if s.closedForShutdown {
-				return false, bytesRead, s.closeForShutdownErr
-			}
-			if s.canceledRead {
-				return false, bytesRead, s.cancelReadErr
-			}
-			if s.resetRemotely {
-				return false, bytesRead, s.resetRemotelyErr
-			}
-
-			deadline := s.deadline
-			if !deadline.IsZero() {
-				if !time.Now().Before(deadline) {
-					return false, bytesRead, errDeadline
-				}
-				if deadlineTimer == nil {
-					deadlineTimer = utils.NewTimer()
-				}
-				deadlineTimer.Reset(deadline)
-			}
-
-			if s.currentFrame != nil || s.currentFrameIsLast {
-				break
-			}
-
-			s.mutex.Unlock()
-			if deadline.IsZero() {
-				<-s.readChan
-			} else {
-				select {
-				case <-s.readChan:
-				case <-deadlineTimer.Chan():
-					deadlineTimer.SetRead()
-				}
-			}
-			s.mutex.Lock()
-			if s.currentFrame == nil {
-				s.dequeueNextFrame()
-			}
+		if err := s.waitForData(); err != nil {
+			return false, bytesRead, err
 		}
 
 		if bytesRead > len(p) {
@@ -163,9 +142,15 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			return false, bytesRead, fmt.Errorf("BUG: readPosInFrame (%d) > frame.DataLen (%d) in stream.Read", s.readPosInFrame, len(s.currentFrame))
 		}
 
+		data := s.currentFrame[s.readPosInFrame:]
+		// when a RESET_STREAM_AT frame was received, only data up to the reliable size is delivered
+		if s.resetPending && protocol.ByteCount(len(data)) > s.reliableSize-s.readOffset {
+			data = data[:s.reliableSize-s.readOffset]
+		}
+
 		s.mutex.Unlock()
 
-		m := copy(p[bytesRead:], s.currentFrame[s.readPosInFrame:])
+		m := copy(p[bytesRead:], data)
 		s.readPosInFrame += m
 		bytesRead += m
 		s.readOffset += protocol.ByteCount(m)
@@ -176,6 +161,10 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			s.flowController.AddBytesRead(protocol.ByteCount(m))
 		}
 
+		if s.reachedReliableSize() {
+			return true, bytesRead, s.resetRemotelyErr
+		}
+
 		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
 			s.finRead = true
 			return true, bytesRead, io.EOF
@@ -184,6 +173,122 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 	return false, bytesRead, nil
 }
 
+func (s *receiveStream) readBufferImpl() (bool /*stream completed */, []byte, func(), error) {
+	if completed, err := s.checkReadable(); err != nil {
+		return completed, nil, nil, err
+	}
+	if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
+		s.dequeueNextFrame()
+	}
+	if err := s.waitForData(); err != nil {
+		return false, nil, nil, err
+	}
+
+	data := s.currentFrame[s.readPosInFrame:]
+	// when a RESET_STREAM_AT frame was received, only data up to the reliable size is delivered
+	if s.resetPending && protocol.ByteCount(len(data)) > s.reliableSize-s.readOffset {
+		data = data[:s.reliableSize-s.readOffset]
+	}
+	// Hand the buffer over to the caller.
+	// It is released when the caller is done with it, not when the next frame is dequeued.
+	release := s.currentFrameDone
+	s.currentFrameDone = nil
+	s.readPosInFrame = len(s.currentFrame)
+	s.readOffset += protocol.ByteCount(len(data))
+	// when a RESET_STREAM was received, the flow controller was already informed about the final byteOffset for this stream
+	if !s.resetRemotely {
+		s.flowController.AddBytesRead(protocol.ByteCount(len(data)))
+	}
+
+	if s.reachedReliableSize() {
+		return true, data, release, s.resetRemotelyErr
+	}
+	if s.currentFrameIsLast {
+		s.finRead = true
+		return true, data, release, io.EOF
+	}
+	return false, data, release, nil
+}
+
+// checkReadable returns an error if no more data can be read from the stream.
+func (s *receiveStream) checkReadable() (bool /* stream completed */, error) {
+	if s.finRead {
+		return false, io.EOF
+	}
+	if s.canceledRead {
+		return false, s.cancelReadErr
+	}
+	if s.resetRemotely {
+		return false, s.resetRemotelyErr
+	}
+	if s.closedForShutdown {
+		return false, s.closeForShutdownErr
+	}
+	if s.reachedReliableSize() {
+		return true, s.resetRemotelyErr
+	}
+	return false, nil
+}
+
+// waitForData blocks until the current frame contains data, or until the end of the stream is reached.
+// It must be called with the mutex held.
+func (s *receiveStream) waitForData() error {
+	var deadlineTimer *utils.Timer
+	for {
+		// Stop waiting on errors
+		if s.closedForShutdown {
+			return s.closeForShutdownErr
+		}
+		if s.canceledRead {
+			return s.cancelReadErr
+		}
+		if s.resetRemotely {
+			return s.resetRemotelyErr
+		}
+
+		deadline := s.deadline
+		if !deadline.IsZero() {
+			if !time.Now().Before(deadline) {
+				return errDeadline
+			}
+			if deadlineTimer == nil {
+				deadlineTimer = utils.NewTimer()
+			}
+			deadlineTimer.Reset(deadline)
+		}
+
+		if s.currentFrame != nil || s.currentFrameIsLast {
+			return nil
+		}
+
+		s.mutex.Unlock()
+		if deadline.IsZero() {
+			<-s.readChan
+		} else {
+			select {
+			case <-s.readChan:
+			case <-deadlineTimer.Chan():
+				deadlineTimer.SetRead()
+			}
+		}
+		s.mutex.Lock()
+		if s.currentFrame == nil {
+			s.dequeueNextFrame()
+		}
+	}
+}
+
+// reachedReliableSize checks if all data up to the reliable size of a RESET_STREAM_AT frame was read.
+// If so, the stream is reset.
+func (s *receiveStream) reachedReliableSize() bool {
+	if !s.resetPending || s.readOffset < s.reliableSize {
+		return false
+	}
+	s.resetPending = false
+	s.resetRemotely = true
+	return true
+}
+
 func (s *receiveStream) dequeueNextFrame() {
 	var offset protocol.ByteCount
 	// We're done with the last frame. Release the buffer.
@@ -279,19 +384,47 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 	if s.resetRemotely {
 		return false, nil
 	}
-	s.resetRemotely = true
+	// a RESET_STREAM_AT frame can only reduce the reliable size
+	if s.resetPending && frame.ReliableSize >= s.reliableSize {
+		return false, nil
+	}
+	wasPending := s.resetPending
 	s.resetRemotelyErr = streamCanceledError{
 		errorCode: frame.ErrorCode,
 		error:     fmt.Errorf("stream %d was reset with error code %d", s.streamID, frame.ErrorCode),
 	}
+	// deliver the data up to the reliable size before resetting the stream
+	if frame.ReliableSize > s.readOffset && !s.canceledRead {
+		s.resetPending = true
+		s.reliableSize = frame.ReliableSize
+		s.signalRead()
+		return false, nil
+	}
+	s.resetPending = false
+	s.resetRemotely = true
 	s.signalRead()
-	return newlyRcvdFinalOffset, nil
+	// If a RESET_STREAM_AT frame was received before, the stream wasn't completed when that frame was received.
+	// If the read side was canceled in the meantime, the stream was completed in CancelRead.
+	return newlyRcvdFinalOffset || (wasPending && !s.canceledRead), nil
 }
 
 func (s *receiveStream) CloseRemote(offset protocol.ByteCount) {
 	s.handleStreamFrame(&wire.StreamFrame{FinBit: true, Offset: offset})
 }
 
+func (s *receiveStream) SetReceiveWindow(n uint64) {
+	s.flowController.SetReceiveWindowSize(protocol.ByteCount(n))
+}
+
+func (s *receiveStream) FlowControlOffsets() FlowControlOffsets {
+	bytesRead, highestReceived, receiveWindow := s.flowController.ReceiveOffsets()
+	return FlowControlOffsets{
+		BytesRead:       uint64(bytesRead),
+		HighestReceived: uint64(highestReceived),
+		ReceiveWindow:   uint64(receiveWindow),
+	}
+}
+
 func (s *receiveStream) SetReadDeadline(t time.Time) error {
 	s.mutex.Lock()
 	s.deadline = t
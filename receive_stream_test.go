@@ -4,11 +4,15 @@ import (
 	"errors"
 	"io"
 	"runtime"
+	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -446,6 +450,116 @@ var _ = Describe("Receive Stream", func() {
 		})
 	})
 
+	Context("reading without copying", func() {
+		It("returns the data of a STREAM frame", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad, 0xbe, 0xef}})).To(Succeed())
+			data, release, err := str.ReadBuffer()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+			Expect(release).ToNot(BeNil())
+			release()
+		})
+
+		It("returns the rest of a partially read STREAM frame", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1))
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad, 0xbe, 0xef}})).To(Succeed())
+			b := make([]byte, 1)
+			_, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte{0xde}))
+			data, release, err := str.ReadBuffer()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte{0xad, 0xbe, 0xef}))
+			release()
+		})
+
+		It("hands over ownership of the buffer", func() {
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3)).Times(2)
+			var released1, released2 bool
+			Expect(str.frameQueue.Push([]byte("foo"), 0, func() { released1 = true })).To(Succeed())
+			Expect(str.frameQueue.Push([]byte("bar"), 3, func() { released2 = true })).To(Succeed())
+			data1, release1, err := str.ReadBuffer()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data1).To(Equal([]byte("foo")))
+			data2, release2, err := str.ReadBuffer()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data2).To(Equal([]byte("bar")))
+			// dequeueing the next frame doesn't release the buffer handed out before
+			Expect(released1).To(BeFalse())
+			release1()
+			Expect(released1).To(BeTrue())
+			Expect(released2).To(BeFalse())
+			release2()
+			Expect(released2).To(BeTrue())
+		})
+
+		It("waits until data is available", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(10 * time.Millisecond)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+			}()
+			data, release, err := str.ReadBuffer()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte{0xde, 0xad}))
+			release()
+		})
+
+		It("returns io.EOF together with the last chunk of data", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{
+				Data:   []byte{0xde, 0xad, 0xbe, 0xef},
+				FinBit: true,
+			})).To(Succeed())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			data, release, err := str.ReadBuffer()
+			Expect(err).To(MatchError(io.EOF))
+			Expect(data).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+			release()
+			data, release, err = str.ReadBuffer()
+			Expect(err).To(MatchError(io.EOF))
+			Expect(data).To(BeEmpty())
+			Expect(release).ToNot(BeNil())
+			release()
+		})
+
+		It("only returns data up to the reliable size, when receiving a RESET_STREAM_AT frame", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar1234")})).To(Succeed())
+			Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+				StreamID:     streamID,
+				ByteOffset:   42,
+				ErrorCode:    1234,
+				ReliableSize: 6,
+			})).To(Succeed())
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+			mockFC.EXPECT().Abandon()
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			data, release, err := str.ReadBuffer()
+			Expect(err).To(MatchError("stream 1337 was reset with error code 1234"))
+			Expect(data).To(Equal([]byte("foobar")))
+			release()
+		})
+
+		It("returns errors", func() {
+			testErr := errors.New("test error")
+			str.closeForShutdown(testErr)
+			data, release, err := str.ReadBuffer()
+			Expect(err).To(MatchError(testErr))
+			Expect(data).To(BeEmpty())
+			Expect(release).ToNot(BeNil())
+			release()
+		})
+	})
+
 	Context("stream cancelations", func() {
 		Context("canceling read", func() {
 			It("unblocks Read", func() {
@@ -636,6 +750,113 @@ var _ = Describe("Receive Stream", func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
+
+		Context("receiving RESET_STREAM_AT frames", func() {
+			rst := &wire.ResetStreamFrame{
+				StreamID:     streamID,
+				ByteOffset:   42,
+				ErrorCode:    1234,
+				ReliableSize: 6,
+			}
+
+			It("delivers the data up to the reliable size before resetting the stream", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar1234")})).To(Succeed())
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				b := make([]byte, 4)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foob")))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				n, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError("stream 1337 was reset with error code 1234"))
+				Expect(b[:n]).To(Equal([]byte("ar")))
+				// further calls to Read return the reset error
+				n, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError("stream 1337 was reset with error code 1234"))
+				Expect(n).To(BeZero())
+			})
+
+			It("waits for the data up to the reliable size", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					b := make([]byte, 10)
+					n, err := strWithTimeout.Read(b)
+					Expect(err).To(MatchError("stream 1337 was reset with error code 1234"))
+					Expect(b[:n]).To(Equal([]byte("foobar")))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar1234")})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("resets the stream immediately if the data up to the reliable size was already read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				b := make([]byte, 6)
+				_, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				gomock.InOrder(
+					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true),
+					mockFC.EXPECT().Abandon(),
+				)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError("stream 1337 was reset with error code 1234"))
+			})
+
+			It("resets the stream when a RESET_STREAM frame reduces the reliable size", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true).Times(2)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:   streamID,
+					ByteOffset: 42,
+					ErrorCode:  4321,
+				})).To(Succeed())
+				_, err := strWithTimeout.Read(make([]byte, 6))
+				Expect(err).To(MatchError("stream 1337 was reset with error code 4321"))
+			})
+
+			It("ignores RESET_STREAM_AT frames that increase the reliable size", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true).Times(2)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					ByteOffset:   42,
+					ErrorCode:    1234,
+					ReliableSize: 10,
+				})).To(Succeed())
+				Expect(str.reliableSize).To(Equal(protocol.ByteCount(6)))
+			})
+
+			It("doesn't deliver any data if the read side was canceled", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelRead(1234)
+				gomock.InOrder(
+					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true),
+					mockFC.EXPECT().Abandon(),
+				)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				Expect(str.resetPending).To(BeFalse())
+			})
+		})
 	})
 
 	Context("flow control", func() {
@@ -654,5 +875,66 @@ var _ = Describe("Receive Stream", func() {
 			mockFC.EXPECT().GetWindowUpdate().Return(protocol.ByteCount(0x100))
 			Expect(str.getWindowUpdate()).To(Equal(protocol.ByteCount(0x100)))
 		})
+
+		It("sets the receive window", func() {
+			mockFC.EXPECT().SetReceiveWindowSize(protocol.ByteCount(1 << 20))
+			str.SetReceiveWindow(1 << 20)
+		})
 	})
 })
+
+// receivedDataSink keeps the data read in the benchmarks, simulating an application that holds on to it
+var receivedDataSink []byte
+
+func newBenchmarkReceiveStream(b *testing.B) *receiveStream {
+	const window = protocol.MaxByteCount / 4
+	connFC := flowcontrol.NewConnectionFlowController(window, window, protocol.DefaultReceiveWindowGrowthFactor, false, func() {}, &congestion.RTTStats{}, utils.DefaultClock{}, utils.DefaultLogger)
+	fc := flowcontrol.NewStreamFlowController(1337, connFC, window, window, protocol.DefaultReceiveWindowGrowthFactor, false, protocol.WindowUpdateThreshold, 0, func(protocol.StreamID) {}, &congestion.RTTStats{}, utils.DefaultClock{}, utils.DefaultLogger)
+	return newReceiveStream(1337, NewMockStreamSender(gomock.NewController(b)), fc, protocol.VersionWhatever)
+}
+
+// benchmarkReceiveStream receives b.N STREAM frames, and calls read to consume the data of every frame.
+func benchmarkReceiveStream(b *testing.B, read func(*receiveStream) error) {
+	const frameLen = 1000
+	str := newBenchmarkReceiveStream(b)
+	b.SetBytes(frameLen)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f := wire.GetStreamFrame()
+		f.Offset = protocol.ByteCount(i * frameLen)
+		f.Data = f.Data[:frameLen]
+		if err := str.handleStreamFrame(f); err != nil {
+			b.Fatal(err)
+		}
+		if err := read(str); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkReceiveStreamRead simulates an application that keeps the data read from the stream,
+// e.g. to pass it to another goroutine: Read requires a new buffer for every read.
+func BenchmarkReceiveStreamRead(b *testing.B) {
+	benchmarkReceiveStream(b, func(str *receiveStream) error {
+		buf := make([]byte, 1000)
+		n, err := str.Read(buf)
+		receivedDataSink = buf[:n]
+		return err
+	})
+}
+
+// BenchmarkReceiveStreamReadBuffer simulates the same application using ReadBuffer:
+// The buffers of the received STREAM frames are handed over, and no new buffers need to be allocated.
+func BenchmarkReceiveStreamReadBuffer(b *testing.B) {
+	releasePrevious := func() {}
+	benchmarkReceiveStream(b, func(str *receiveStream) error {
+		data, release, err := str.ReadBuffer()
+		// the application is done with the previous chunk of data
+		releasePrevious()
+		receivedDataSink = data
+		releasePrevious = release
+		return err
+	})
+	releasePrevious()
+}
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -21,12 +22,14 @@ type sendStreamI interface {
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	closeForShutdown(error)
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
+	reserveSendWindow(protocol.ByteCount) bool
 }
 
 type sendStream struct {
 	mutex sync.Mutex
 
 	numOutstandingFrames int64
+	numOutstandingBytes  protocol.ByteCount
 	retransmissionQueue  []*wire.StreamFrame
 
 	ctx       context.Context
@@ -42,6 +45,7 @@ type sendStream struct {
 
 	closedForShutdown bool // set when CloseForShutdown() is called
 	finishedWriting   bool // set once Close() is called
+	reservedWindow    bool // set if a part of the connection-level send window was reserved for this stream
 	canceledWrite     bool // set when CancelWrite() is called, or a STOP_SENDING frame is received
 	finSent           bool // set when a STREAM_FRAME with FIN bit has been sent
 	completed         bool // set when this stream has been reported to the streamSender as completed
@@ -156,6 +160,7 @@ func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Fr
 	f, hasMoreData := s.popNewOrRetransmittedStreamFrame(maxBytes)
 	if f != nil {
 		s.numOutstandingFrames++
+		s.numOutstandingBytes += f.DataLen()
 	}
 	s.mutex.Unlock()
 
@@ -223,8 +228,24 @@ func (s *sendStream) popNewStreamFrame(f *wire.StreamFrame, maxBytes protocol.By
 	}
 	if f.FinBit {
 		s.finSent = true
+		s.releaseSendWindow()
 	}
-	return s.dataForWriting != nil
+	if s.dataForWriting == nil {
+		return false
+	}
+	// If the frame was limited by flow control, tell the peer right away,
+	// instead of waiting until the next time this stream is asked for data.
+	if f.DataLen() == maxDataLen {
+		return true
+	}
+	if isBlocked, offset := s.flowController.IsNewlyBlocked(); isBlocked {
+		s.sender.queueControlFrame(&wire.StreamDataBlockedFrame{
+			StreamID:  s.streamID,
+			DataLimit: offset,
+		})
+		return false
+	}
+	return true
 }
 
 func (s *sendStream) maybeGetRetransmission(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool /* has more retransmissions */) {
@@ -271,9 +292,11 @@ func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.By
 }
 
 func (s *sendStream) frameAcked(f wire.Frame) {
-	f.(*wire.StreamFrame).PutBack()
+	sf := f.(*wire.StreamFrame)
 
 	s.mutex.Lock()
+	s.numOutstandingBytes -= sf.DataLen()
+	sf.PutBack()
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
@@ -300,6 +323,7 @@ func (s *sendStream) queueRetransmission(f wire.Frame) {
 	sf.DataLenPresent = true
 	s.mutex.Lock()
 	s.retransmissionQueue = append(s.retransmissionQueue, sf)
+	s.numOutstandingBytes -= sf.DataLen()
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
@@ -324,12 +348,29 @@ func (s *sendStream) Close() error {
 }
 
 func (s *sendStream) CancelWrite(errorCode protocol.ApplicationErrorCode) {
-	s.cancelWriteImpl(errorCode, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
+	s.cancelWriteImpl(errorCode, 0, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
+}
 
+func (s *sendStream) CancelWriteAt(errorCode protocol.ApplicationErrorCode, reliableSize uint64) error {
+	if !s.sender.supportsResetStreamAt() {
+		return errors.New("peer doesn't support RESET_STREAM_AT")
+	}
+	s.mutex.Lock()
+	// Data passed to a Write call that is still blocked is only counted once it has been packed into STREAM frames.
+	// Canceling drops the rest, so it can't be delivered reliably.
+	// The offset only ever increases, so it's fine to release the mutex before canceling.
+	sentOffset := s.writeOffset
+	s.mutex.Unlock()
+	if protocol.ByteCount(reliableSize) > sentOffset {
+		return fmt.Errorf("reliable size (%d) larger than the amount of data sent (%d)", reliableSize, sentOffset)
+	}
+	// Frames containing data below the reliable size are retransmitted when lost, even after the stream was canceled.
+	s.cancelWriteImpl(errorCode, protocol.ByteCount(reliableSize), fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
+	return nil
 }
 
 // must be called after locking the mutex
-func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, writeErr error) {
+func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, reliableSize protocol.ByteCount, writeErr error) {
 	s.mutex.Lock()
 	if s.canceledWrite {
 		s.mutex.Unlock()
@@ -338,14 +379,16 @@ func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, wr
 	s.ctxCancel()
 	s.canceledWrite = true
 	s.cancelWriteErr = writeErr
+	s.releaseSendWindow()
 	newlyCompleted := s.isNewlyCompleted()
 	s.mutex.Unlock()
 
 	s.signalWrite()
 	s.sender.queueControlFrame(&wire.ResetStreamFrame{
-		StreamID:   s.streamID,
-		ByteOffset: s.writeOffset,
-		ErrorCode:  errorCode,
+		StreamID:     s.streamID,
+		ByteOffset:   s.writeOffset,
+		ErrorCode:    errorCode,
+		ReliableSize: reliableSize,
 	})
 	if newlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
@@ -355,20 +398,70 @@ func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, wr
 func (s *sendStream) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) {
 	s.mutex.Lock()
 	hasStreamData := s.dataForWriting != nil
+	s.flowController.UpdateSendWindow(frame.ByteOffset)
 	s.mutex.Unlock()
 
-	s.flowController.UpdateSendWindow(frame.ByteOffset)
 	if hasStreamData {
 		s.sender.onHasStreamData(s.streamID)
 	}
 }
 
+// reserveSendWindow reserves n bytes of the connection-level send window for this stream.
+func (s *sendStream) reserveSendWindow(n protocol.ByteCount) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.canceledWrite || s.closedForShutdown || s.finishedWriting {
+		return false
+	}
+	if !s.flowController.ReserveSendWindow(n) {
+		return false
+	}
+	s.reservedWindow = true
+	return true
+}
+
+// releaseSendWindow releases the unused part of the reserved send window.
+// It must be called with the mutex held.
+func (s *sendStream) releaseSendWindow() {
+	if !s.reservedWindow {
+		return
+	}
+	s.flowController.ReleaseSendWindow()
+	s.reservedWindow = false
+}
+
 func (s *sendStream) handleStopSendingFrame(frame *wire.StopSendingFrame) {
 	writeErr := streamCanceledError{
 		errorCode: frame.ErrorCode,
 		error:     fmt.Errorf("stream %d was reset with error code %d", s.streamID, frame.ErrorCode),
 	}
-	s.cancelWriteImpl(frame.ErrorCode, writeErr)
+	s.cancelWriteImpl(frame.ErrorCode, 0, writeErr)
+}
+
+func (s *sendStream) SendQueueDepth() SendQueueDepth {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	queued := protocol.ByteCount(len(s.dataForWriting))
+	for _, f := range s.retransmissionQueue {
+		queued += f.DataLen()
+	}
+	return SendQueueDepth{
+		Queued:         uint64(queued),
+		Unacknowledged: uint64(s.numOutstandingBytes),
+	}
+}
+
+func (s *sendStream) FlowControlOffsets() FlowControlOffsets {
+	s.mutex.Lock()
+	bytesSent, sendWindow := s.flowController.SendOffsets()
+	s.mutex.Unlock()
+
+	return FlowControlOffsets{
+		BytesSent:  uint64(bytesSent),
+		SendWindow: uint64(sendWindow),
+	}
 }
 
 func (s *sendStream) Context() context.Context {
@@ -391,6 +484,7 @@ func (s *sendStream) closeForShutdown(err error) {
 	s.ctxCancel()
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
+	s.releaseSendWindow()
 	s.mutex.Unlock()
 	s.signalWrite()
 }
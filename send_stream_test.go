@@ -9,8 +9,11 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -207,6 +210,33 @@ var _ = Describe("Send Stream", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("queues a BLOCKED frame as soon as a STREAM frame uses up the flow control window", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+					close(done)
+				}()
+				waitForWrite()
+
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(3))
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+				mockFC.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(3))
+				mockSender.EXPECT().queueControlFrame(&wire.StreamDataBlockedFrame{
+					StreamID:  streamID,
+					DataLimit: 3,
+				})
+				f, hasMoreData := str.popStreamFrame(1000)
+				Expect(f).ToNot(BeNil())
+				Expect(f.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+				Expect(hasMoreData).To(BeFalse())
+				// make the Write go routine return
+				str.closeForShutdown(nil)
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("says that it doesn't have any more data, when it is flow control blocked", func() {
 				frameHeaderSize := protocol.ByteCount(4)
 				mockSender.EXPECT().onHasStreamData(streamID)
@@ -301,6 +331,50 @@ var _ = Describe("Send Stream", func() {
 				Expect(n).To(BeEquivalentTo(frame.Frame.(*wire.StreamFrame).DataLen()))
 			})
 
+			It("can be used for subsequent writes after the deadline expired", func() {
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(10000)).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).Times(2)
+				data := bytes.Repeat([]byte("foobar"), 20)
+				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+				str.SetWriteDeadline(deadline)
+				var n int
+				writeReturned := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					var err error
+					n, err = strWithTimeout.Write(data)
+					Expect(err).To(MatchError(errDeadline))
+					close(writeReturned)
+				}()
+				waitForWrite()
+				frame, _ := str.popStreamFrame(50)
+				Expect(frame).ToNot(BeNil())
+				f1 := frame.Frame.(*wire.StreamFrame)
+				Eventually(writeReturned, scaleDuration(80*time.Millisecond)).Should(BeClosed())
+				Expect(n).To(BeNumerically(">", 0))
+				Expect(n).To(BeNumerically("<", len(data)))
+				Expect(n).To(BeEquivalentTo(f1.DataLen()))
+
+				// write the rest of the data
+				str.SetWriteDeadline(time.Time{})
+				writeReturned = make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					m, err := strWithTimeout.Write(data[n:])
+					Expect(err).ToNot(HaveOccurred())
+					Expect(m).To(Equal(len(data) - n))
+					close(writeReturned)
+				}()
+				waitForWrite()
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				f2 := frame.Frame.(*wire.StreamFrame)
+				Eventually(writeReturned).Should(BeClosed())
+				Expect(f2.Offset).To(Equal(f1.DataLen()))
+				Expect(append(f1.Data, f2.Data...)).To(Equal(data))
+			})
+
 			It("doesn't pop any data after the deadline expired", func() {
 				mockSender.EXPECT().onHasStreamData(streamID)
 				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(10000)).AnyTimes()
@@ -524,6 +598,76 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("reserving connection-level flow control", func() {
+		var connFC flowcontrol.ConnectionFlowController
+
+		newStreamFlowController := func(id protocol.StreamID) flowcontrol.StreamFlowController {
+			return flowcontrol.NewStreamFlowController(id, connFC, 1000, 1000, protocol.DefaultReceiveWindowGrowthFactor, false, protocol.WindowUpdateThreshold, 1000, nil, &congestion.RTTStats{}, utils.DefaultClock{}, utils.DefaultLogger)
+		}
+
+		BeforeEach(func() {
+			connFC = flowcontrol.NewConnectionFlowController(1000, 1000, protocol.DefaultReceiveWindowGrowthFactor, false, nil, &congestion.RTTStats{}, utils.DefaultClock{}, utils.DefaultLogger)
+			connFC.UpdateSendWindow(100)
+			str = newSendStream(streamID, mockSender, newStreamFlowController(streamID), protocol.VersionWhatever)
+		})
+
+		It("doesn't block the first write, when other streams use up the connection-level window", func() {
+			mockSender.EXPECT().onHasStreamData(gomock.Any()).AnyTimes()
+			Expect(str.reserveSendWindow(60)).To(BeTrue())
+			// another stream tries to send 100 bytes, but only gets the unreserved 40 bytes
+			otherStr := newSendStream(streamID+4, mockSender, newStreamFlowController(streamID+4), protocol.VersionWhatever)
+			go func() {
+				defer GinkgoRecover()
+				otherStr.Write(bytes.Repeat([]byte{'a'}, 100))
+			}()
+			Eventually(func() bool { return otherStr.hasData() }).Should(BeTrue())
+			frame, _ := otherStr.popStreamFrame(1000)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(HaveLen(40))
+			Expect(connFC.SendWindowSize()).To(BeZero())
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				n, err := str.Write(bytes.Repeat([]byte{'b'}, 60))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(60))
+				close(done)
+			}()
+			waitForWrite()
+			frame, _ = str.popStreamFrame(1000)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal(bytes.Repeat([]byte{'b'}, 60)))
+			Eventually(done).Should(BeClosed())
+			otherStr.closeForShutdown(nil)
+		})
+
+		It("doesn't reserve more than the connection-level window", func() {
+			Expect(str.reserveSendWindow(101)).To(BeFalse())
+			Expect(connFC.SendWindowSize()).To(Equal(protocol.ByteCount(100)))
+		})
+
+		It("releases the reservation when the stream is closed for shutdown", func() {
+			Expect(str.reserveSendWindow(60)).To(BeTrue())
+			Expect(connFC.SendWindowSize()).To(Equal(protocol.ByteCount(40)))
+			str.closeForShutdown(errors.New("shutdown"))
+			Expect(connFC.SendWindowSize()).To(Equal(protocol.ByteCount(100)))
+		})
+
+		It("releases the unused part of the reservation when the FIN is sent", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.reserveSendWindow(60)).To(BeTrue())
+			Expect(str.Close()).To(Succeed())
+			frame, _ := str.popStreamFrame(1000)
+			Expect(frame.Frame.(*wire.StreamFrame).FinBit).To(BeTrue())
+			Expect(connFC.SendWindowSize()).To(Equal(protocol.ByteCount(100)))
+		})
+
+		It("doesn't reserve after the stream was closed", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			Expect(str.reserveSendWindow(60)).To(BeFalse())
+		})
+	})
+
 	Context("stream cancellations", func() {
 		Context("canceling writing", func() {
 			It("queues a RESET_STREAM frame", func() {
@@ -619,6 +763,79 @@ var _ = Describe("Send Stream", func() {
 			})
 		})
 
+		Context("canceling writing, with a reliable size", func() {
+			It("queues a RESET_STREAM_AT frame", func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(true)
+				gomock.InOrder(
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+						StreamID:     streamID,
+						ByteOffset:   1234,
+						ErrorCode:    9876,
+						ReliableSize: 1000,
+					}),
+					mockSender.EXPECT().onStreamCompleted(streamID),
+				)
+				str.writeOffset = 1234
+				Expect(str.CancelWriteAt(9876, 1000)).To(Succeed())
+			})
+
+			It("errors if the peer doesn't support RESET_STREAM_AT", func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(false)
+				str.writeOffset = 1234
+				Expect(str.CancelWriteAt(9876, 1000)).To(MatchError("peer doesn't support RESET_STREAM_AT"))
+				Expect(str.Context().Done()).ToNot(BeClosed())
+			})
+
+			It("errors if the reliable size is larger than the amount of data sent", func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(true)
+				str.writeOffset = 1234
+				Expect(str.CancelWriteAt(9876, 1235)).To(MatchError("reliable size (1235) larger than the amount of data sent (1234)"))
+				Expect(str.Context().Done()).ToNot(BeClosed())
+			})
+
+			It("doesn't count data of a blocked Write call that wasn't sent yet", func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(true)
+				str.writeOffset = 1234
+				str.dataForWriting = []byte("foobar")
+				Expect(str.CancelWriteAt(9876, 1235)).To(MatchError("reliable size (1235) larger than the amount of data sent (1234)"))
+				Expect(str.Context().Done()).ToNot(BeClosed())
+			})
+
+			It("retransmits lost data below the reliable size", func() {
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				mockSender.EXPECT().supportsResetStreamAt().Return(true)
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					ByteOffset:   6,
+					ErrorCode:    1234,
+					ReliableSize: 6,
+				})
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := strWithTimeout.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+					close(done)
+				}()
+				waitForWrite()
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Eventually(done).Should(BeClosed())
+				Expect(frame).ToNot(BeNil())
+				Expect(str.CancelWriteAt(1234, 6)).To(Succeed())
+
+				// now lose the frame
+				frame.OnLost(frame.Frame)
+				newFrame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(newFrame).ToNot(BeNil())
+				Expect(newFrame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+				// the stream is completed once the retransmission is acknowledged
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				newFrame.OnAcked(newFrame.Frame)
+			})
+		})
+
 		Context("receiving STOP_SENDING frames", func() {
 			It("queues a RESET_STREAM frames, and copies the error code from the STOP_SENDING frame", func() {
 				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
@@ -752,6 +969,51 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("send queue depth", func() {
+		BeforeEach(func() {
+			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		})
+
+		It("reports queued and unacknowledged data", func() {
+			Expect(str.SendQueueDepth()).To(BeZero())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Write(make([]byte, 1000))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			Expect(str.SendQueueDepth()).To(Equal(SendQueueDepth{Queued: 1000}))
+
+			frame1, _ := str.popStreamFrame(400)
+			Expect(frame1).ToNot(BeNil())
+			len1 := uint64(frame1.Frame.(*wire.StreamFrame).DataLen())
+			Expect(str.SendQueueDepth()).To(Equal(SendQueueDepth{Queued: 1000 - len1, Unacknowledged: len1}))
+			frame2, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame2).ToNot(BeNil())
+			Eventually(done).Should(BeClosed())
+			len2 := uint64(frame2.Frame.(*wire.StreamFrame).DataLen())
+			Expect(len1 + len2).To(BeEquivalentTo(1000))
+			Expect(str.SendQueueDepth()).To(Equal(SendQueueDepth{Unacknowledged: 1000}))
+
+			// lose the first frame
+			frame1.OnLost(frame1.Frame)
+			Expect(str.SendQueueDepth()).To(Equal(SendQueueDepth{Queued: len1, Unacknowledged: len2}))
+			// acknowledge the second frame
+			frame2.OnAcked(frame2.Frame)
+			Expect(str.SendQueueDepth()).To(Equal(SendQueueDepth{Queued: len1}))
+			// retransmit the first frame, and acknowledge it
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			Expect(str.SendQueueDepth()).To(Equal(SendQueueDepth{Unacknowledged: len1}))
+			frame.OnAcked(frame.Frame)
+			Expect(str.SendQueueDepth()).To(BeZero())
+		})
+	})
+
 	Context("determining when a stream is completed", func() {
 		BeforeEach(func() {
 			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
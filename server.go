@@ -44,7 +44,6 @@ type quicSession interface {
 	EarlySession
 	earlySessionReady() <-chan struct{}
 	handlePacket(*receivedPacket)
-	GetVersion() protocol.VersionNumber
 	getPerspective() protocol.Perspective
 	run() error
 	destroy(error)
@@ -65,6 +64,8 @@ type baseServer struct {
 	// If the server is started with ListenAddr, we create a packet conn.
 	// If it is started with Listen, we take a packet conn as a parameter.
 	createdPacketConn bool
+	// set if the operating system used smaller buffer sizes than configured for the packet conn we created
+	bufferSizeLimits []bufferSizeLimit
 
 	tokenGenerator *handshake.TokenGenerator
 
@@ -122,11 +123,17 @@ func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bo
 	if err != nil {
 		return nil, err
 	}
+	bufferSizeLimits, err := setBufferSizes(conn, config, utils.DefaultLogger)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
 	serv, err := listen(conn, tlsConf, config, acceptEarly)
 	if err != nil {
 		return nil, err
 	}
 	serv.createdPacketConn = true
+	serv.bufferSizeLimits = bufferSizeLimits
 	return serv, nil
 }
 
@@ -161,6 +168,15 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 			return nil, fmt.Errorf("%s is not a valid QUIC version", v)
 		}
 	}
+	if config.RetryConnectionIDLength < 1 || config.RetryConnectionIDLength > protocol.MaxConnIDLen {
+		return nil, fmt.Errorf("invalid Retry connection ID length: %d", config.RetryConnectionIDLength)
+	}
+	if config.MinIdleTimeout > config.MaxIdleTimeout {
+		return nil, fmt.Errorf("quic: MinIdleTimeout (%s) is larger than MaxIdleTimeout (%s)", config.MinIdleTimeout, config.MaxIdleTimeout)
+	}
+	if (config.GenerateToken == nil) != (config.ValidateToken == nil) {
+		return nil, errors.New("quic: GenerateToken and ValidateToken must be set together")
+	}
 
 	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, config.StatelessResetKey)
 	if err != nil {
@@ -351,17 +367,15 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) (qui
 	var token *Token
 	var origDestConnectionID protocol.ConnectionID
 	if len(hdr.Token) > 0 {
-		c, err := s.tokenGenerator.DecodeToken(hdr.Token)
-		if err == nil {
-			token = &Token{
-				IsRetryToken: c.IsRetryToken,
-				RemoteAddr:   c.RemoteAddr,
-				SentTime:     c.SentTime,
-			}
-			origDestConnectionID = c.OriginalDestConnectionID
-		}
+		token, origDestConnectionID = s.decodeToken(p.remoteAddr, hdr.Token)
+	}
+	accepted := s.config.AcceptToken(p.remoteAddr, token)
+	// If address validation is required, only a Retry token proves that the client owns its address.
+	if accepted && (token == nil || !token.IsRetryToken) &&
+		s.config.RequireAddressValidation != nil && s.config.RequireAddressValidation(p.remoteAddr) {
+		accepted = false
 	}
-	if !s.config.AcceptToken(p.remoteAddr, token) {
+	if !accepted {
 		go func() {
 			if token != nil && token.IsRetryToken {
 				if err := s.maybeSendInvalidToken(p, hdr); err != nil {
@@ -386,7 +400,14 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) (qui
 		return nil, nil
 	}
 
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	// Retry tokens are only supposed to be used once.
+	// An Initial carrying a replayed token might have been sent by an attacker.
+	if token != nil && token.IsRetryToken && s.config.TokenReplayCache != nil && s.config.TokenReplayCache.Add(hdr.Token) {
+		s.logger.Debugf("Dropping Initial packet with a replayed Retry token from %s.", p.remoteAddr)
+		return nil, nil
+	}
+
+	connID, err := getConnectionIDGenerator(s.config)(s.config.ConnectionIDLength)
 	if err != nil {
 		return nil, err
 	}
@@ -428,7 +449,8 @@ func (s *baseServer) createNewSession(
 		}
 	}
 	if qlogger != nil {
-		qlogger.StartedConnection(time.Now(), s.conn.LocalAddr(), remoteAddr, version, srcConnID, destConnID)
+		qlogger.StartedConnection(getClock().Now(), s.conn.LocalAddr(), remoteAddr, version, srcConnID, destConnID)
+		traceBufferSizeLimits(qlogger, s.bufferSizeLimits)
 	}
 	sess := s.newSession(
 		&conn{pconn: s.conn, currentAddr: remoteAddr},
@@ -485,15 +507,44 @@ func (s *baseServer) handleNewSession(sess quicSession) {
 	}
 }
 
+// decodeToken decodes a token sent by the client.
+// It returns a nil token if the token is invalid.
+func (s *baseServer) decodeToken(remoteAddr net.Addr, data []byte) (*Token, protocol.ConnectionID) {
+	if s.config.ValidateToken != nil {
+		token, origDestConnID, err := s.config.ValidateToken(remoteAddr, data)
+		if err != nil {
+			s.logger.Debugf("Client sent an invalid token: %s", err)
+			return nil, nil
+		}
+		return token, origDestConnID
+	}
+	c, err := s.tokenGenerator.DecodeToken(data)
+	if err != nil {
+		return nil, nil
+	}
+	return &Token{
+		IsRetryToken: c.IsRetryToken,
+		RemoteAddr:   c.RemoteAddr,
+		SentTime:     c.SentTime,
+	}, c.OriginalDestConnectionID
+}
+
+func (s *baseServer) newRetryToken(remoteAddr net.Addr, origDestConnID protocol.ConnectionID) ([]byte, error) {
+	if s.config.GenerateToken != nil {
+		return s.config.GenerateToken(remoteAddr, true, origDestConnID)
+	}
+	return s.tokenGenerator.NewRetryToken(remoteAddr, origDestConnID)
+}
+
 func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 	// Log the Initial packet now.
 	// If no Retry is sent, the packet will be logged by the session.
 	(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
-	token, err := s.tokenGenerator.NewRetryToken(remoteAddr, hdr.DestConnectionID)
+	token, err := s.newRetryToken(remoteAddr, hdr.DestConnectionID)
 	if err != nil {
 		return err
 	}
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	connID, err := getConnectionIDGenerator(s.config)(s.config.RetryConnectionIDLength)
 	if err != nil {
 		return err
 	}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/tls"
+	"encoding/binary"
 	"errors"
 	"net"
 	"reflect"
@@ -118,6 +119,23 @@ var _ = Describe("Server", func() {
 		Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
 	})
 
+	It("errors when the Config contains an invalid Retry connection ID length", func() {
+		_, err := Listen(nil, tlsConf, &Config{RetryConnectionIDLength: protocol.MaxConnIDLen + 1})
+		Expect(err).To(MatchError("invalid Retry connection ID length: 21"))
+	})
+
+	It("errors when the MinIdleTimeout is larger than the MaxIdleTimeout", func() {
+		_, err := Listen(nil, tlsConf, &Config{MinIdleTimeout: time.Minute})
+		Expect(err).To(MatchError("quic: MinIdleTimeout (1m0s) is larger than MaxIdleTimeout (30s)"))
+	})
+
+	It("errors when only one of the token callbacks is set", func() {
+		_, err := Listen(nil, tlsConf, &Config{
+			GenerateToken: func(net.Addr, bool, []byte) ([]byte, error) { return nil, nil },
+		})
+		Expect(err).To(MatchError("quic: GenerateToken and ValidateToken must be set together"))
+	})
+
 	It("fills in default values if options are not set in the Config", func() {
 		ln, err := Listen(conn, tlsConf, &Config{})
 		Expect(err).ToNot(HaveOccurred())
@@ -290,6 +308,88 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			Context("using custom tokens", func() {
+				// The custom token format encodes the expiry time, followed by the original destination connection ID.
+				generateToken := func(expiry time.Time, origDestConnID []byte) []byte {
+					token := make([]byte, 8)
+					binary.BigEndian.PutUint64(token, uint64(expiry.UnixNano()))
+					return append(token, origDestConnID...)
+				}
+
+				var raddr *net.UDPAddr
+
+				BeforeEach(func() {
+					raddr = &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+					serv.config.GenerateToken = func(addr net.Addr, isRetry bool, origDestConnID []byte) ([]byte, error) {
+						Expect(addr).To(Equal(raddr))
+						Expect(isRetry).To(BeTrue())
+						return generateToken(time.Now().Add(time.Second), origDestConnID), nil
+					}
+					serv.config.ValidateToken = func(addr net.Addr, token []byte) (*Token, []byte, error) {
+						Expect(addr).To(Equal(raddr))
+						if len(token) < 8 {
+							return nil, nil, errors.New("token too short")
+						}
+						expiry := time.Unix(0, int64(binary.BigEndian.Uint64(token[:8])))
+						if time.Now().After(expiry) {
+							return nil, nil, errors.New("token expired")
+						}
+						return &Token{IsRetryToken: true, RemoteAddr: raddr.IP.String()}, token[8:], nil
+					}
+				})
+
+				getInitialWithToken := func(token []byte) *receivedPacket {
+					packet := getPacket(&wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeInitial,
+						SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+						DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+						Token:            token,
+						Version:          serv.config.Versions[0],
+					}, make([]byte, protocol.MinInitialPacketSize))
+					packet.remoteAddr = raddr
+					return packet
+				}
+
+				It("sends a Retry packet with a token generated by the callback", func() {
+					serv.config.AcceptToken = func(_ net.Addr, token *Token) bool {
+						Expect(token).To(BeNil())
+						return false
+					}
+					serv.handlePacket(getInitialWithToken(nil))
+					var write mockPacketConnWrite
+					Eventually(conn.dataWritten).Should(Receive(&write))
+					replyHdr := parseHeader(write.data)
+					Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+					Expect(replyHdr.Token).To(HaveLen(8 + 10))
+					Expect(replyHdr.Token[8:]).To(Equal([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}))
+				})
+
+				It("passes the token decoded by the callback to AcceptToken", func() {
+					done := make(chan struct{})
+					serv.config.AcceptToken = func(_ net.Addr, token *Token) bool {
+						defer close(done)
+						Expect(token).To(Equal(&Token{IsRetryToken: true, RemoteAddr: "192.168.13.37"}))
+						return false
+					}
+					token := generateToken(time.Now().Add(time.Second), protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad})
+					serv.handlePacket(getInitialWithToken(token))
+					Eventually(done).Should(BeClosed())
+				})
+
+				It("passes an empty token to AcceptToken, if the callback rejects the token", func() {
+					done := make(chan struct{})
+					serv.config.AcceptToken = func(_ net.Addr, token *Token) bool {
+						defer close(done)
+						Expect(token).To(BeNil())
+						return false
+					}
+					token := generateToken(time.Now().Add(-time.Second), protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad})
+					serv.handlePacket(getInitialWithToken(token))
+					Eventually(done).Should(BeClosed())
+				})
+			})
+
 			It("sends a Version Negotiation Packet for unsupported versions", func() {
 				srcConnID := protocol.ConnectionID{1, 2, 3, 4, 5}
 				destConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6}
@@ -335,6 +435,62 @@ var _ = Describe("Server", func() {
 				Expect(write.data[len(write.data)-16:]).To(Equal(handshake.GetRetryIntegrityTag(write.data[:len(write.data)-16], hdr.DestConnectionID)[:]))
 			})
 
+			It("replies with a Retry packet, if address validation is required", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				var clientAddr net.Addr
+				serv.config.RequireAddressValidation = func(addr net.Addr) bool {
+					clientAddr = addr
+					return true
+				}
+				// a token received in a NEW_TOKEN frame is not sufficient
+				token, err := serv.tokenGenerator.NewToken(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337})
+				Expect(err).ToNot(HaveOccurred())
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Token:            token,
+					Version:          protocol.VersionTLS,
+				}
+				packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				serv.handlePacket(packet)
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				Expect(write.to.String()).To(Equal("127.0.0.1:1337"))
+				replyHdr := parseHeader(write.data)
+				Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+				Expect(replyHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+				Expect(clientAddr).To(Equal(packet.remoteAddr))
+			})
+
+			It("uses the configured connection ID length for Retry packets", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
+				serv.config.RetryConnectionIDLength = 17
+				var requestedLen int
+				serv.config.GenerateConnectionID = func(l int) ([]byte, error) {
+					requestedLen = l
+					return bytes.Repeat([]byte{0x42}, l), nil
+				}
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Version:          protocol.VersionTLS,
+				}
+				packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				packet.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				serv.handlePacket(packet)
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				replyHdr := parseHeader(write.data)
+				Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+				Expect(replyHdr.SrcConnectionID).To(Equal(protocol.ConnectionID(bytes.Repeat([]byte{0x42}, 17))))
+				Expect(requestedLen).To(Equal(17))
+			})
+
 			It("sends an INVALID_TOKEN error, if an invalid retry token is received", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
 				token, err := serv.tokenGenerator.NewRetryToken(&net.UDPAddr{}, nil)
@@ -371,6 +527,54 @@ var _ = Describe("Server", func() {
 				Expect(ccf.ReasonPhrase).To(BeEmpty())
 			})
 
+			It("only accepts a Retry token once, if a TokenReplayCache is used", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				serv.config.TokenReplayCache = NewTokenReplayCache(10)
+				token, err := serv.tokenGenerator.NewRetryToken(&net.UDPAddr{}, nil)
+				Expect(err).ToNot(HaveOccurred())
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Token:            token,
+					Version:          protocol.VersionTLS,
+				}
+				var createdSession bool
+				sess := NewMockQuicSession(mockCtrl)
+				serv.newSession = func(
+					_ connection,
+					_ sessionRunner,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ [16]byte,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ qlog.Tracer,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					createdSession = true
+					return sess
+				}
+				phm.EXPECT().GetStatelessResetToken(gomock.Any())
+				// Pretend that we're already keeping track of this connection ID,
+				// so that the server doesn't start the session.
+				phm.EXPECT().Add(gomock.Any(), sess).Return(false)
+				Expect(serv.handlePacketImpl(getPacket(hdr, make([]byte, protocol.MinInitialPacketSize)))).To(BeFalse())
+				Expect(createdSession).To(BeTrue())
+
+				// replay the token
+				createdSession = false
+				Expect(serv.handlePacketImpl(getPacket(hdr, make([]byte, protocol.MinInitialPacketSize)))).To(BeFalse())
+				Expect(createdSession).To(BeFalse())
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+			})
+
 			It("doesn't send an INVALID_TOKEN error, if the packet is corrupted", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
 				token, err := serv.tokenGenerator.NewRetryToken(&net.UDPAddr{}, nil)
@@ -780,6 +984,94 @@ var _ = Describe("Server", func() {
 				cancel() // complete the handshake
 				Eventually(done).Should(BeClosed())
 			})
+
+			It("returns every session exactly once, if Accept is called concurrently", func() {
+				const numAcceptors = 20
+				const numSessions = 200
+
+				// Set up all mocks before starting, since the sessions are handled concurrently.
+				sessions := make(chan quicSession, numSessions)
+				for i := 0; i < numSessions; i++ {
+					sess := NewMockQuicSession(mockCtrl)
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel() // the handshake is already complete
+					sess.EXPECT().HandshakeComplete().Return(ctx)
+					sess.EXPECT().run()
+					sess.EXPECT().Context().Return(context.Background())
+					sessions <- sess
+				}
+				serv.newSession = func(
+					_ connection,
+					runner sessionRunner,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ [16]byte,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ qlog.Tracer,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					return <-sessions
+				}
+				phm.EXPECT().GetStatelessResetToken(gomock.Any()).Times(numSessions)
+				phm.EXPECT().Add(gomock.Any(), gomock.Any()).Return(true).Times(2 * numSessions)
+
+				ctx, cancel := context.WithCancel(context.Background())
+				acceptedChan := make(chan Session, numSessions)
+				var wg sync.WaitGroup
+				wg.Add(numAcceptors)
+				for i := 0; i < numAcceptors; i++ {
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+						for {
+							sess, err := serv.Accept(ctx)
+							if err != nil {
+								Expect(err).To(MatchError(context.Canceled))
+								return
+							}
+							acceptedChan <- sess
+						}
+					}()
+				}
+
+				created := make(map[quicSession]struct{}, numSessions)
+				var mutex sync.Mutex
+				var createWg sync.WaitGroup
+				createWg.Add(numSessions)
+				for i := 0; i < numSessions; i++ {
+					go func() {
+						defer GinkgoRecover()
+						defer createWg.Done()
+						sess := serv.createNewSession(&net.UDPAddr{}, nil, nil, nil, nil, protocol.VersionWhatever)
+						mutex.Lock()
+						created[sess] = struct{}{}
+						mutex.Unlock()
+					}()
+				}
+				createWg.Wait()
+				Expect(created).To(HaveLen(numSessions))
+
+				accepted := make(map[Session]struct{}, numSessions)
+				for i := 0; i < numSessions; i++ {
+					var sess Session
+					Eventually(acceptedChan).Should(Receive(&sess))
+					// use map lookups, since HaveKey compares the (deeply equal) mocks by value
+					_, ok := accepted[sess]
+					Expect(ok).To(BeFalse())
+					accepted[sess] = struct{}{}
+					_, ok = created[sess.(quicSession)]
+					Expect(ok).To(BeTrue())
+				}
+				Consistently(acceptedChan).ShouldNot(Receive())
+				cancel()
+				wg.Wait()
+			})
 		})
 	})
 
@@ -10,6 +10,7 @@ import (
 	"net"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
@@ -44,7 +45,12 @@ type streamManager interface {
 	AcceptUniStream(context.Context) (ReceiveStream, error)
 	DeleteStream(protocol.StreamID) error
 	UpdateLimits(*handshake.TransportParameters) error
+	RaiseIncomingLimits(maxBidiStreams, maxUniStreams uint64)
 	HandleMaxStreamsFrame(*wire.MaxStreamsFrame) error
+	HandleStreamsBlockedFrame(*wire.StreamsBlockedFrame)
+	SendQueueDepth() SendQueueDepth
+	StreamCounts() StreamCounts
+	ResetAllStreams(protocol.ApplicationErrorCode)
 	CloseWithError(error)
 }
 
@@ -56,6 +62,9 @@ type cryptoStreamHandler interface {
 	GetSessionTicket() ([]byte, error)
 	io.Closer
 	ConnectionState() handshake.ConnectionState
+	NumKeyUpdates() uint64
+	UsedHelloRetryRequest() bool
+	ZeroRTTRejection() handshake.ZeroRTTRejection
 }
 
 type receivedPacket struct {
@@ -106,8 +115,34 @@ type closeError struct {
 
 var errCloseForRecreating = errors.New("closing session in order to recreate it")
 
+var errMaxConnectionLifetimeExceeded = qerr.Error(qerr.NoError, "maximum connection lifetime exceeded")
+
 // A Session is a QUIC session
 type session struct {
+	// latestAckDelay is the ACK delay (in nanoseconds) of the most recent ACK frame received for 1-RTT packets.
+	// It is accessed atomically, and is the first field to guarantee 64-bit alignment.
+	latestAckDelay int64
+	// estimatedBandwidth is the delivery rate estimate of the sent packet handler (in bytes per second).
+	// It is accessed atomically, and is placed next to latestAckDelay to guarantee 64-bit alignment.
+	estimatedBandwidth uint64
+	// numSpuriousRetransmissions is the number of packets that were declared lost, but were acknowledged later.
+	// It is accessed atomically.
+	numSpuriousRetransmissions uint64
+	// numDroppedUndecryptablePackets is the number of packets dropped because the undecryptable packet queue was full.
+	// It is accessed atomically.
+	numDroppedUndecryptablePackets uint64
+	// The number of datagrams and QUIC packets sent, as well as the number and the total size
+	// of the datagrams that contained more than one (coalesced) QUIC packet.
+	// They are accessed atomically.
+	numDatagramsSent          uint64
+	numPacketsSent            uint64
+	numCoalescedDatagramsSent uint64
+	numCoalescedBytesSent     uint64
+	// The packet tolerance and the max ACK delay (in nanoseconds) requested by the peer in the most recent ACK_FREQUENCY frame.
+	// They are accessed atomically.
+	ackFrequencyPacketTolerance uint64
+	ackFrequencyMaxAckDelay     int64
+
 	// Destination connection ID used during the handshake.
 	// Used to check source connection ID on incoming packets.
 	handshakeDestConnID protocol.ConnectionID
@@ -119,6 +154,7 @@ type session struct {
 	initialVersion protocol.VersionNumber // if version negotiation is performed, this is the version we initially tried
 	version        protocol.VersionNumber
 	config         *Config
+	clock          utils.Clock
 
 	conn      connection
 	sendQueue *sendQueue
@@ -126,6 +162,7 @@ type session struct {
 	streamsMap      streamManager
 	connIDManager   *connIDManager
 	connIDGenerator *connIDGenerator
+	spinBitManager  *spinBitManager
 
 	rttStats *congestion.RTTStats
 
@@ -139,6 +176,12 @@ type session struct {
 	tokenStoreKey         string                    // only set for the client
 	tokenGenerator        *handshake.TokenGenerator // only set for the server
 
+	// The transport parameters saved in the session ticket, if 0-RTT was accepted with a custom Accept0RTTTransportParameters.
+	// The client may use up the stream receive windows saved in the session ticket on the streams it opens in 0-RTT.
+	// They are set on the handshake goroutine, and used when creating stream flow controllers.
+	zeroRTTParamsMutex sync.Mutex
+	zeroRTTParams      *handshake.TransportParameters // only set for the server
+
 	unpacker    unpacker
 	frameParser wire.FrameParser
 	packer      packer
@@ -185,6 +228,23 @@ type session struct {
 	// It is reset as soon as we receive a packet from the peer.
 	keepAlivePingSent bool
 	keepAliveInterval time.Duration
+	// lastPacketSentTime is the time when the last packet was sent.
+	// It is used to keep the NAT binding alive.
+	lastPacketSentTime time.Time
+	// natKeepAlivePingQueued stores whether a PING to keep the NAT binding alive was queued, but not sent yet.
+	natKeepAlivePingQueued bool
+	// healthCheckPingSentTime is the time when a health check PING was queued.
+	// It is reset as soon as we receive a packet from the peer.
+	healthCheckPingSentTime time.Time
+	// pathUnhealthy is set when a health check PING was not acknowledged in time.
+	pathUnhealthy utils.AtomicBool
+	// ackFlushRequested is set by FlushACKs, and handled by the run loop.
+	ackFlushRequested utils.AtomicBool
+	// resetStreamAtNegotiated is set when the peer supports the RESET_STREAM_AT extension.
+	resetStreamAtNegotiated utils.AtomicBool
+
+	// ACK_FREQUENCY frames with a sequence number smaller than this value are ignored
+	nextAckFrequencySeqNum uint64
 
 	traceCallback func(quictrace.Event)
 
@@ -223,6 +283,7 @@ var newSession = func(
 		perspective:           protocol.PerspectiveServer,
 		handshakeCompleteChan: make(chan struct{}),
 		qlogger:               qlogger,
+		clock:                 getClock(),
 		logger:                logger,
 		version:               v,
 	}
@@ -241,6 +302,8 @@ var newSession = func(
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		clientDestConnID,
+		getConnectionIDGenerator(s.config),
+		s.config.MaxIssuedConnectionIDs,
 		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
 		runner.GetStatelessResetToken,
 		runner.Remove,
@@ -253,8 +316,14 @@ var newSession = func(
 		0,
 		s.rttStats,
 		s.perspective,
+		s.config.MaxProbeTimeout,
+		s.config.PacketReorderingThreshold,
+		s.config.EarlyRetransmit,
+		s.config.ImmediateAckAfterIdle,
+		s.config.MaxAckRanges,
 		s.traceCallback,
 		s.qlogger,
+		s.clock,
 		s.logger,
 		s.version,
 	)
@@ -271,9 +340,15 @@ var newSession = func(
 		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
 		AckDelayExponent:               protocol.AckDelayExponent,
 		DisableActiveMigration:         true,
+		EnableResetStreamAt:            true,
+		GreaseQUICBit:                  true,
+		OmitMaxPacketSize:              s.config.DisableMaxPacketSizeParameter,
+		MinAckDelay:                    s.minAckDelay(),
 		StatelessResetToken:            &statelessResetToken,
 		OriginalConnectionID:           origDestConnID,
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
+		ZeroLengthConnectionID:         srcConnID.Len() == 0,
+		ParameterOrder:                 s.config.TransportParameterOrder,
 	}
 	cs := handshake.NewCryptoSetupServer(
 		initialStream,
@@ -292,8 +367,12 @@ var newSession = func(
 			},
 		},
 		tlsConf,
+		s.config.NewTLSConn,
 		enable0RTT,
+		s.allow0RTT,
+		s.validFor0RTT,
 		s.rttStats,
+		s.clock,
 		qlogger,
 		logger,
 	)
@@ -301,6 +380,7 @@ var newSession = func(
 	s.packer = newPacketPacker(
 		srcConnID,
 		s.connIDManager.Get,
+		s.spinBitManager.SpinBit,
 		initialStream,
 		handshakeStream,
 		s.sentPacketHandler,
@@ -342,6 +422,7 @@ var newClientSession = func(
 		logID:                 destConnID.String(),
 		logger:                logger,
 		qlogger:               qlogger,
+		clock:                 getClock(),
 		initialVersion:        initialVersion,
 		version:               v,
 	}
@@ -355,6 +436,8 @@ var newClientSession = func(
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		nil,
+		getConnectionIDGenerator(s.config),
+		s.config.MaxIssuedConnectionIDs,
 		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
 		runner.GetStatelessResetToken,
 		runner.Remove,
@@ -367,8 +450,14 @@ var newClientSession = func(
 		initialPacketNumber,
 		s.rttStats,
 		s.perspective,
+		s.config.MaxProbeTimeout,
+		s.config.PacketReorderingThreshold,
+		s.config.EarlyRetransmit,
+		s.config.ImmediateAckAfterIdle,
+		s.config.MaxAckRanges,
 		s.traceCallback,
 		s.qlogger,
+		s.clock,
 		s.logger,
 		s.version,
 	)
@@ -385,7 +474,12 @@ var newClientSession = func(
 		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
 		AckDelayExponent:               protocol.AckDelayExponent,
 		DisableActiveMigration:         true,
+		EnableResetStreamAt:            true,
+		GreaseQUICBit:                  true,
+		OmitMaxPacketSize:              s.config.DisableMaxPacketSizeParameter,
+		MinAckDelay:                    s.minAckDelay(),
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
+		ParameterOrder:                 s.config.TransportParameterOrder,
 	}
 	cs, clientHelloWritten := handshake.NewCryptoSetupClient(
 		initialStream,
@@ -401,8 +495,10 @@ var newClientSession = func(
 			onHandshakeComplete: func() { close(s.handshakeCompleteChan) },
 		},
 		tlsConf,
+		s.config.NewTLSConn,
 		enable0RTT,
 		s.rttStats,
+		s.clock,
 		qlogger,
 		logger,
 	)
@@ -413,6 +509,7 @@ var newClientSession = func(
 	s.packer = newPacketPacker(
 		srcConnID,
 		s.connIDManager.Get,
+		s.spinBitManager.SpinBit,
 		initialStream,
 		handshakeStream,
 		s.sentPacketHandler,
@@ -442,11 +539,15 @@ func (s *session) preSetup() {
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
 	s.frameParser = wire.NewFrameParser(s.version)
 	s.rttStats = &congestion.RTTStats{}
+	s.spinBitManager = newSpinBitManager(s.config.DisableSpinBit, s.perspective)
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.InitialMaxData,
 		protocol.ByteCount(s.config.MaxReceiveConnectionFlowControlWindow),
+		s.config.ReceiveWindowGrowthFactor,
+		s.config.EnableBDPWindowAutoTuning,
 		s.onHasConnectionWindowUpdate,
 		s.rttStats,
+		s.clock,
 		s.logger,
 	)
 	s.earlySessionReadyChan = make(chan struct{})
@@ -455,6 +556,9 @@ func (s *session) preSetup() {
 		s.newFlowController,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
+		uint64(s.config.MaxAutoIncomingStreams),
+		uint64(s.config.MaxAutoIncomingUniStreams),
+		s.allowStreamLimitIncrease,
 		s.perspective,
 		s.version,
 	)
@@ -462,12 +566,12 @@ func (s *session) preSetup() {
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
-	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
+	s.undecryptablePackets = make([]*receivedPacket, 0, s.config.MaxUndecryptablePackets)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
 
-	s.timer = utils.NewTimer()
-	now := time.Now()
+	s.timer = utils.NewTimerWithClock(s.clock)
+	now := s.clock.Now()
 	s.lastPacketReceivedTime = now
 	s.sessionCreationTime = now
 
@@ -525,6 +629,11 @@ runLoop:
 			break runLoop
 		case <-s.timer.Chan():
 			s.timer.SetRead()
+			// The timer fires early if its deadline was postponed.
+			// In that case, there's nothing to do yet.
+			if s.clock.Now().Before(s.timer.Deadline()) {
+				continue
+			}
 			// We do all the interesting stuff after the switch statement, so
 			// nothing to see here.
 		case <-s.sendingScheduled:
@@ -547,8 +656,8 @@ runLoop:
 			s.handleHandshakeComplete()
 		}
 
-		now := time.Now()
-		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
+		now := s.clock.Now()
+		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && !timeout.After(now) {
 			// This could cause packets to be retransmitted.
 			// Check it before trying to send packets.
 			if err := s.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
@@ -560,6 +669,26 @@ runLoop:
 		if s.pacingDeadline.IsZero() { // the timer didn't have a pacing deadline set
 			pacingDeadline = s.sentPacketHandler.TimeUntilSend()
 		}
+		if s.ackFlushRequested.Get() {
+			s.ackFlushRequested.Set(false)
+			s.receivedPacketHandler.FlushAcks()
+		}
+		if natKeepAliveTime := s.nextNATKeepAliveTime(); !natKeepAliveTime.IsZero() && !now.Before(natKeepAliveTime) {
+			// send a PING frame since we haven't sent a packet in a while
+			s.logger.Debugf("Sending a PING to keep the NAT binding alive.")
+			s.framer.QueueControlFrame(&wire.PingFrame{})
+			s.natKeepAlivePingQueued = true
+		}
+		if healthCheckTime := s.nextHealthCheckTime(); !healthCheckTime.IsZero() && !now.Before(healthCheckTime) {
+			// send a PING frame to check if the path still works
+			s.logger.Debugf("Sending a PING to check the health of the path.")
+			s.framer.QueueControlFrame(&wire.PingFrame{})
+			s.healthCheckPingSentTime = now
+		}
+		if deadline := s.healthCheckDeadline(); !deadline.IsZero() && !now.Before(deadline) {
+			s.logger.Debugf("Health check PING was not acknowledged. Marking the path as unhealthy.")
+			s.pathUnhealthy.Set(true)
+		}
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
 			// send a PING frame since there is no activity in the session
 			s.logger.Debugf("Sending a keep-alive PING to keep the connection alive.")
@@ -568,8 +697,11 @@ runLoop:
 		} else if !s.handshakeComplete && now.Sub(s.sessionCreationTime) >= s.config.HandshakeTimeout {
 			s.destroyImpl(qerr.TimeoutError("Handshake did not complete in time"))
 			continue
+		} else if idleDeadline := s.handshakeIdleDeadline(); !idleDeadline.IsZero() && !now.Before(idleDeadline) {
+			s.closeIdle(qerr.TimeoutError("No recent network activity during the handshake"))
+			continue
 		} else if s.handshakeComplete && now.Sub(s.idleTimeoutStartTime()) >= s.idleTimeout {
-			s.destroyImpl(qerr.TimeoutError("No recent network activity"))
+			s.closeIdle(qerr.TimeoutError("No recent network activity"))
 			continue
 		} else if !pacingDeadline.IsZero() && now.Before(pacingDeadline) {
 			// If we get to this point before the pacing deadline, we should wait until that deadline.
@@ -583,8 +715,16 @@ runLoop:
 			s.destroyImpl(qerr.TimeoutError("Handshake did not complete in time"))
 			continue
 		}
+		if idleDeadline := s.handshakeIdleDeadline(); !idleDeadline.IsZero() && !now.Before(idleDeadline) {
+			s.closeIdle(qerr.TimeoutError("No recent network activity during the handshake"))
+			continue
+		}
 		if s.handshakeComplete && now.Sub(s.idleTimeoutStartTime()) >= s.idleTimeout {
-			s.destroyImpl(qerr.TimeoutError("No recent network activity"))
+			s.closeIdle(qerr.TimeoutError("No recent network activity"))
+			continue
+		}
+		if s.maxConnectionLifetimeExceeded(now) {
+			s.closeLocal(errMaxConnectionLifetimeExceeded)
 			continue
 		}
 
@@ -622,6 +762,72 @@ func (s *session) ConnectionState() ConnectionState {
 	return s.cryptoStreamHandler.ConnectionState()
 }
 
+func (s *session) NumKeyUpdates() uint64 {
+	return s.cryptoStreamHandler.NumKeyUpdates()
+}
+
+func (s *session) UsedHelloRetryRequest() bool {
+	return s.cryptoStreamHandler.UsedHelloRetryRequest()
+}
+
+func (s *session) ZeroRTTRejection() ZeroRTTRejection {
+	return s.cryptoStreamHandler.ZeroRTTRejection()
+}
+
+func (s *session) LatestAckDelay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.latestAckDelay))
+}
+
+func (s *session) AckFrequencyPacketTolerance() uint64 {
+	return atomic.LoadUint64(&s.ackFrequencyPacketTolerance)
+}
+
+func (s *session) AckFrequencyMaxAckDelay() time.Duration {
+	if d := atomic.LoadInt64(&s.ackFrequencyMaxAckDelay); d != 0 {
+		return time.Duration(d)
+	}
+	return protocol.MaxAckDelay
+}
+
+func (s *session) EstimatedBandwidth() uint64 {
+	return atomic.LoadUint64(&s.estimatedBandwidth)
+}
+
+func (s *session) NumSpuriousRetransmissions() uint64 {
+	return atomic.LoadUint64(&s.numSpuriousRetransmissions)
+}
+
+func (s *session) NumDroppedUndecryptablePackets() uint64 {
+	return atomic.LoadUint64(&s.numDroppedUndecryptablePackets)
+}
+
+func (s *session) CoalescingStats() CoalescingStats {
+	return CoalescingStats{
+		DatagramsSent:          atomic.LoadUint64(&s.numDatagramsSent),
+		PacketsSent:            atomic.LoadUint64(&s.numPacketsSent),
+		CoalescedDatagramsSent: atomic.LoadUint64(&s.numCoalescedDatagramsSent),
+		CoalescedBytesSent:     atomic.LoadUint64(&s.numCoalescedBytesSent),
+	}
+}
+
+func (s *session) FlushACKs() {
+	s.ackFlushRequested.Set(true)
+	s.scheduleSending()
+}
+
+func (s *session) PathUnhealthy() bool {
+	return s.pathUnhealthy.Get()
+}
+
+// minAckDelay is the min_ack_delay sent in the transport parameters.
+// It is zero if the ACK Frequency extension is disabled.
+func (s *session) minAckDelay() time.Duration {
+	if !s.config.EnableAckFrequency {
+		return 0
+	}
+	return protocol.MinAckDelay
+}
+
 // Time when the next keep-alive packet should be sent.
 // It returns a zero time if no keep-alive should be sent.
 func (s *session) nextKeepAliveTime() time.Time {
@@ -631,18 +837,99 @@ func (s *session) nextKeepAliveTime() time.Time {
 	return s.lastPacketReceivedTime.Add(s.keepAliveInterval / 2)
 }
 
+// Time when the next PING should be sent to keep the NAT binding alive.
+// It returns a zero time if no PING should be sent.
+func (s *session) nextNATKeepAliveTime() time.Time {
+	if s.config.NATKeepAlivePeriod == 0 || !s.handshakeComplete || s.natKeepAlivePingQueued {
+		return time.Time{}
+	}
+	return s.lastPacketSentTime.Add(s.config.NATKeepAlivePeriod)
+}
+
+// Time when the next PING should be sent to check the health of the path.
+// It returns a zero time if health checks are disabled, or a health check PING is in flight.
+func (s *session) nextHealthCheckTime() time.Time {
+	if s.config.HealthCheckInterval == 0 || !s.handshakeComplete || !s.healthCheckPingSentTime.IsZero() {
+		return time.Time{}
+	}
+	return s.lastPacketReceivedTime.Add(s.config.HealthCheckInterval)
+}
+
+// Time when the path is considered unhealthy if the health check PING is not acknowledged.
+// It returns a zero time if no health check PING is in flight, or the path was already marked as unhealthy.
+func (s *session) healthCheckDeadline() time.Time {
+	if s.healthCheckPingSentTime.IsZero() || s.pathUnhealthy.Get() {
+		return time.Time{}
+	}
+	return s.healthCheckPingSentTime.Add(3 * s.rttStats.PTO(true))
+}
+
+// Time when the session is closed if no packet is received before the handshake completes.
+// It returns a zero time if the handshake has completed, or no handshake idle timeout is configured.
+func (s *session) handshakeIdleDeadline() time.Time {
+	if s.handshakeComplete || s.config.HandshakeIdleTimeout == 0 {
+		return time.Time{}
+	}
+	return s.idleTimeoutStartTime().Add(s.config.HandshakeIdleTimeout)
+}
+
+// Time when the MaxConnectionLifetime is exceeded.
+// After that, streams have a brief window to get their data acknowledged before the session is closed.
+// It returns a zero time if the lifetime of the session is not limited.
+func (s *session) lifetimeDeadline() time.Time {
+	if s.config.MaxConnectionLifetime == 0 {
+		return time.Time{}
+	}
+	deadline := s.sessionCreationTime.Add(s.config.MaxConnectionLifetime)
+	if s.clock.Now().Before(deadline) {
+		return deadline
+	}
+	return deadline.Add(3 * s.rttStats.PTO(true))
+}
+
+// maxConnectionLifetimeExceeded says if the session should be closed because the MaxConnectionLifetime was exceeded.
+// This is the case once all stream data was acknowledged, or the window for streams to complete has expired.
+func (s *session) maxConnectionLifetimeExceeded(now time.Time) bool {
+	if s.config.MaxConnectionLifetime == 0 {
+		return false
+	}
+	deadline := s.sessionCreationTime.Add(s.config.MaxConnectionLifetime)
+	if now.Before(deadline) {
+		return false
+	}
+	if depth := s.streamsMap.SendQueueDepth(); depth.Queued == 0 && depth.Unacknowledged == 0 {
+		return true
+	}
+	return !now.Before(deadline.Add(3 * s.rttStats.PTO(true)))
+}
+
 func (s *session) maybeResetTimer() {
 	var deadline time.Time
 	if !s.handshakeComplete {
 		deadline = s.sessionCreationTime.Add(s.config.HandshakeTimeout)
+		if idleDeadline := s.handshakeIdleDeadline(); !idleDeadline.IsZero() {
+			deadline = utils.MinTime(deadline, idleDeadline)
+		}
 	} else {
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() {
 			deadline = keepAliveTime
 		} else {
 			deadline = s.idleTimeoutStartTime().Add(s.idleTimeout)
 		}
+		if natKeepAliveTime := s.nextNATKeepAliveTime(); !natKeepAliveTime.IsZero() {
+			deadline = utils.MinTime(deadline, natKeepAliveTime)
+		}
+		if healthCheckTime := s.nextHealthCheckTime(); !healthCheckTime.IsZero() {
+			deadline = utils.MinTime(deadline, healthCheckTime)
+		}
+		if healthCheckDeadline := s.healthCheckDeadline(); !healthCheckDeadline.IsZero() {
+			deadline = utils.MinTime(deadline, healthCheckDeadline)
+		}
 	}
 
+	if lifetimeDeadline := s.lifetimeDeadline(); !lifetimeDeadline.IsZero() {
+		deadline = utils.MinTime(deadline, lifetimeDeadline)
+	}
 	if ackAlarm := s.receivedPacketHandler.GetAlarmTimeout(); !ackAlarm.IsZero() {
 		deadline = utils.MinTime(deadline, ackAlarm)
 	}
@@ -662,6 +949,9 @@ func (s *session) idleTimeoutStartTime() time.Time {
 
 func (s *session) handleHandshakeComplete() {
 	s.handshakeComplete = true
+	if s.qlogger != nil {
+		s.qlogger.HandshakeProgressed(s.clock.Now(), qlog.HandshakeEventComplete)
+	}
 	s.handshakeCompleteChan = nil // prevent this case from ever being selected again
 	s.handshakeCtxCancel()
 
@@ -679,7 +969,7 @@ func (s *session) handleHandshakeComplete() {
 				s.queueControlFrame(s.oneRTTStream.PopCryptoFrame(protocol.MaxPostHandshakeCryptoFrameSize))
 			}
 		}
-		token, err := s.tokenGenerator.NewToken(s.conn.RemoteAddr())
+		token, err := s.newToken()
 		if err != nil {
 			s.closeLocal(err)
 		}
@@ -687,6 +977,14 @@ func (s *session) handleHandshakeComplete() {
 		s.cryptoStreamHandler.DropHandshakeKeys()
 		s.queueControlFrame(&wire.HandshakeDoneFrame{})
 	}
+
+	// If the peer supports the ACK Frequency extension, tell it how often to send ACKs.
+	if s.config.AckFrequencyPacketTolerance > 0 && s.peerParams != nil && s.peerParams.MinAckDelay != 0 {
+		s.queueControlFrame(&wire.AckFrequencyFrame{
+			PacketTolerance:   uint64(s.config.AckFrequencyPacketTolerance),
+			UpdateMaxAckDelay: s.peerParams.MaxAckDelay,
+		})
+	}
 }
 
 func (s *session) handlePacketImpl(rp *receivedPacket) bool {
@@ -701,7 +999,7 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 			p.data = data
 		}
 
-		hdr, packetData, rest, err := wire.ParsePacket(p.data, s.srcConnIDLen)
+		hdr, packetData, rest, err := s.parsePacket(p.data)
 		if err != nil {
 			s.logger.Debugf("error parsing packet: %s", err)
 			break
@@ -732,6 +1030,69 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 	return processed
 }
 
+// allow0RTT is only used by the server.
+// It asks the application if 0-RTT is allowed for a client that sent the server name.
+func (s *session) allow0RTT(serverName string) bool {
+	if s.config.Allow0RTT == nil {
+		return true
+	}
+	return s.config.Allow0RTT(&ClientInfo{
+		RemoteAddr: s.conn.RemoteAddr(),
+		ServerName: serverName,
+	})
+}
+
+// validFor0RTT is only used by the server.
+// It decides if the transport parameters saved in a session ticket are valid for 0-RTT.
+func (s *session) validFor0RTT(cached, current *handshake.TransportParameters) bool {
+	if s.config.Accept0RTTTransportParameters == nil {
+		return current.ValidFor0RTT(cached)
+	}
+	if !s.config.Accept0RTTTransportParameters(toZeroRTTTransportParameters(cached), toZeroRTTTransportParameters(current)) {
+		return false
+	}
+	// The client may use up the limits saved in the session ticket in 0-RTT packets.
+	// Raise our receive windows and stream limits, in case they were decreased.
+	s.zeroRTTParamsMutex.Lock()
+	s.zeroRTTParams = cached
+	s.zeroRTTParamsMutex.Unlock()
+	s.connFlowController.RaiseReceiveWindow(cached.InitialMaxData)
+	s.streamsMap.RaiseIncomingLimits(uint64(cached.MaxBidiStreamNum), uint64(cached.MaxUniStreamNum))
+	return true
+}
+
+func toZeroRTTTransportParameters(p *handshake.TransportParameters) *ZeroRTTTransportParameters {
+	maxPacketSize := p.MaxPacketSize
+	if maxPacketSize == 0 {
+		maxPacketSize = protocol.MaxReceivePacketSize
+	}
+	return &ZeroRTTTransportParameters{
+		InitialMaxStreamDataBidiLocal:  uint64(p.InitialMaxStreamDataBidiLocal),
+		InitialMaxStreamDataBidiRemote: uint64(p.InitialMaxStreamDataBidiRemote),
+		InitialMaxStreamDataUni:        uint64(p.InitialMaxStreamDataUni),
+		InitialMaxData:                 uint64(p.InitialMaxData),
+		MaxBidiStreams:                 int64(p.MaxBidiStreamNum),
+		MaxUniStreams:                  int64(p.MaxUniStreamNum),
+		MaxPacketSize:                  uint64(maxPacketSize),
+	}
+}
+
+func (s *session) allowStreamLimitIncrease(t protocol.StreamType, newLimit uint64) bool {
+	if s.config.AllowStreamLimitIncrease == nil {
+		return true
+	}
+	return s.config.AllowStreamLimitIncrease(t == protocol.StreamTypeUni, int(newLimit))
+}
+
+func (s *session) parsePacket(data []byte) (*wire.Header, []byte /* packet data */, []byte /* rest */, error) {
+	// We always offer the grease_quic_bit transport parameter.
+	// Once the peer offered it as well, it might clear the QUIC bit.
+	if s.peerParams != nil && s.peerParams.GreaseQUICBit {
+		return wire.ParsePacketAllowingGreasedQUICBit(data, s.srcConnIDLen)
+	}
+	return wire.ParsePacket(data, s.srcConnIDLen)
+}
+
 func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /* was the packet successfully processed */ {
 	var wasQueued bool
 
@@ -818,7 +1179,7 @@ func (s *session) handleRetryPacket(hdr *wire.Header, data []byte) bool /* was t
 	s.logger.Debugf("<- Received Retry")
 	s.logger.Debugf("Switching destination connection ID to: %s", hdr.SrcConnectionID)
 	if s.qlogger != nil {
-		s.qlogger.ReceivedRetry(time.Now(), hdr)
+		s.qlogger.ReceivedRetry(s.clock.Now(), hdr)
 	}
 	s.origDestConnID = s.handshakeDestConnID
 	newDestConnID := hdr.SrcConnectionID
@@ -852,6 +1213,8 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 	s.lastPacketReceivedTime = rcvTime
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
+	s.healthCheckPingSentTime = time.Time{}
+	s.pathUnhealthy.Set(false)
 
 	// Only used for tracing.
 	// If we're not tracing, this slice will always remain empty.
@@ -874,7 +1237,7 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 		if s.traceCallback != nil || s.qlogger != nil {
 			frames = append(frames, frame)
 		}
-		if err := s.handleFrame(frame, packet.encryptionLevel); err != nil {
+		if err := s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
 			return err
 		}
 	}
@@ -893,12 +1256,19 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 	}
 	if s.qlogger != nil {
 		s.qlogger.ReceivedPacket(rcvTime, packet.hdr, protocol.ByteCount(len(packet.data)), frames)
+		if packet.encryptionLevel == protocol.EncryptionInitial {
+			s.qlogger.HandshakeProgressed(rcvTime, qlog.HandshakeEventInitialReceived)
+		}
+	}
+
+	if packet.encryptionLevel == protocol.Encryption1RTT {
+		s.spinBitManager.ReceivedPacket(packet.packetNumber, packet.hdr.SpinBit)
 	}
 
 	return s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, packet.encryptionLevel, rcvTime, isAckEliciting)
 }
 
-func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel) error {
+func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, destConnID protocol.ConnectionID) error {
 	var err error
 	wire.LogFrame(s.logger, f, false)
 	switch frame := f.(type) {
@@ -921,6 +1291,7 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel) e
 	case *wire.DataBlockedFrame:
 	case *wire.StreamDataBlockedFrame:
 	case *wire.StreamsBlockedFrame:
+		s.streamsMap.HandleStreamsBlockedFrame(frame)
 	case *wire.StopSendingFrame:
 		err = s.handleStopSendingFrame(frame)
 	case *wire.PingFrame:
@@ -934,12 +1305,21 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel) e
 	case *wire.NewConnectionIDFrame:
 		err = s.handleNewConnectionIDFrame(frame)
 	case *wire.RetireConnectionIDFrame:
-		err = s.handleRetireConnectionIDFrame(frame)
+		err = s.handleRetireConnectionIDFrame(frame, destConnID)
 	case *wire.HandshakeDoneFrame:
 		err = s.handleHandshakeDoneFrame()
+	case *wire.AckFrequencyFrame:
+		err = s.handleAckFrequencyFrame(frame)
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
+	// Transport errors should carry the type of the frame that triggered the error.
+	// Copy the error, since errors might be shared between sessions.
+	if qErr, ok := err.(*qerr.QuicError); ok && !qErr.IsApplicationError() && qErr.FrameType == 0 {
+		e := *qErr
+		e.FrameType = wire.FrameType(f, s.version)
+		err = &e
+	}
 	return err
 }
 
@@ -954,6 +1334,16 @@ func (s *session) handlePacket(p *receivedPacket) {
 }
 
 func (s *session) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame) {
+	if s.config.OnConnectionClose != nil {
+		s.config.OnConnectionClose(ConnectionCloseInfo{
+			Session:            s,
+			RemoteAddr:         s.RemoteAddr(),
+			IsApplicationError: frame.IsApplicationError,
+			ErrorCode:          uint64(frame.ErrorCode),
+			FrameType:          frame.FrameType,
+			ReasonPhrase:       frame.ReasonPhrase,
+		})
+	}
 	var e error
 	if frame.IsApplicationError {
 		e = qerr.ApplicationError(frame.ErrorCode, frame.ReasonPhrase)
@@ -1037,6 +1427,14 @@ func (s *session) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+// newToken generates the token sent in a NEW_TOKEN frame
+func (s *session) newToken() ([]byte, error) {
+	if s.config.GenerateToken != nil {
+		return s.config.GenerateToken(s.conn.RemoteAddr(), false, nil)
+	}
+	return s.tokenGenerator.NewToken(s.conn.RemoteAddr())
+}
+
 func (s *session) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return qerr.Error(qerr.ProtocolViolation, "Received NEW_TOKEN frame from the client.")
@@ -1051,24 +1449,51 @@ func (s *session) handleNewConnectionIDFrame(f *wire.NewConnectionIDFrame) error
 	return s.connIDManager.Add(f)
 }
 
-func (s *session) handleRetireConnectionIDFrame(f *wire.RetireConnectionIDFrame) error {
-	return s.connIDGenerator.Retire(f.SequenceNumber)
+func (s *session) handleRetireConnectionIDFrame(f *wire.RetireConnectionIDFrame, destConnID protocol.ConnectionID) error {
+	return s.connIDGenerator.Retire(f.SequenceNumber, destConnID)
 }
 
 func (s *session) handleHandshakeDoneFrame() error {
 	if s.perspective == protocol.PerspectiveServer {
 		return qerr.Error(qerr.ProtocolViolation, "received a HANDSHAKE_DONE frame")
 	}
+	if s.qlogger != nil {
+		s.qlogger.HandshakeProgressed(s.lastPacketReceivedTime, qlog.HandshakeEventDoneReceived)
+	}
 	s.cryptoStreamHandler.DropHandshakeKeys()
 	return nil
 }
 
+func (s *session) handleAckFrequencyFrame(frame *wire.AckFrequencyFrame) error {
+	if !s.config.EnableAckFrequency {
+		return qerr.Error(qerr.ProtocolViolation, "received an ACK_FREQUENCY frame, but the ACK Frequency extension was not negotiated")
+	}
+	if frame.UpdateMaxAckDelay < protocol.MinAckDelay {
+		return qerr.Error(qerr.ProtocolViolation, "received an ACK_FREQUENCY frame with an Update Max Ack Delay smaller than the min_ack_delay")
+	}
+	// ACK_FREQUENCY frames might be reordered
+	if frame.SequenceNumber < s.nextAckFrequencySeqNum {
+		return nil
+	}
+	s.nextAckFrequencySeqNum = frame.SequenceNumber + 1
+	s.receivedPacketHandler.SetAckFrequency(frame.PacketTolerance, frame.UpdateMaxAckDelay, frame.IgnoreOrder)
+	atomic.StoreUint64(&s.ackFrequencyPacketTolerance, frame.PacketTolerance)
+	atomic.StoreInt64(&s.ackFrequencyMaxAckDelay, int64(frame.UpdateMaxAckDelay))
+	return nil
+}
+
 func (s *session) handleAckFrame(frame *wire.AckFrame, encLevel protocol.EncryptionLevel) error {
 	if err := s.sentPacketHandler.ReceivedAck(frame, encLevel, s.lastPacketReceivedTime); err != nil {
 		return err
 	}
+	atomic.StoreUint64(&s.estimatedBandwidth, uint64(s.sentPacketHandler.DeliveryRate()/congestion.BytesPerSecond))
+	atomic.StoreUint64(&s.numSpuriousRetransmissions, s.sentPacketHandler.NumSpuriousRetransmissions())
 	if encLevel == protocol.Encryption1RTT {
 		s.cryptoStreamHandler.SetLargest1RTTAcked(frame.LargestAcked())
+		atomic.StoreInt64(&s.latestAckDelay, int64(frame.DelayTime))
+		if s.config.PacketTimestampTracer != nil {
+			s.config.PacketTimestampTracer.ReceivedAck(frame.LargestAcked(), frame.DelayTime, s.lastPacketReceivedTime)
+		}
 	}
 	return nil
 }
@@ -1085,6 +1510,16 @@ func (s *session) closeLocal(e error) {
 	})
 }
 
+// closeIdle closes the session when the idle timeout expires.
+// Unless configured otherwise, the session is closed silently.
+func (s *session) closeIdle(e error) {
+	if s.config.SendCloseOnIdleTimeout {
+		s.closeLocal(e)
+		return
+	}
+	s.destroyImpl(e)
+}
+
 // destroy closes the session without sending the error on the wire
 func (s *session) destroy(e error) {
 	s.destroyImpl(e)
@@ -1124,6 +1559,10 @@ func (s *session) shutdown() {
 	<-s.ctx.Done()
 }
 
+func (s *session) ResetAllStreams(code protocol.ApplicationErrorCode) {
+	s.streamsMap.ResetAllStreams(code)
+}
+
 func (s *session) CloseWithError(code protocol.ApplicationErrorCode, desc string) error {
 	s.closeLocal(qerr.ApplicationError(qerr.ErrorCode(code), desc))
 	<-s.ctx.Done()
@@ -1180,6 +1619,12 @@ func (s *session) processTransportParameters(params *handshake.TransportParamete
 	s.peerParams = params
 	// Our local idle timeout will always be > 0.
 	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
+	if s.idleTimeout < s.config.MinIdleTimeout {
+		// The peer's value is valid. Rejecting it is a local policy decision, not a protocol violation.
+		// SERVER_BUSY was renamed to CONNECTION_REFUSED in later drafts.
+		s.closeLocal(qerr.Error(qerr.ServerBusy, fmt.Sprintf("negotiated idle timeout (%s) is smaller than the minimum idle timeout (%s)", s.idleTimeout, s.config.MinIdleTimeout)))
+		return
+	}
 	s.keepAliveInterval = utils.MinDuration(s.idleTimeout/2, protocol.MaxKeepAliveInterval)
 	if err := s.streamsMap.UpdateLimits(params); err != nil {
 		s.closeLocal(err)
@@ -1187,17 +1632,27 @@ func (s *session) processTransportParameters(params *handshake.TransportParamete
 	}
 	s.packer.HandleTransportParameters(params)
 	s.frameParser.SetAckDelayExponent(params.AckDelayExponent)
+	// We always offer the RESET_STREAM_AT extension.
+	if params.EnableResetStreamAt {
+		s.frameParser.EnableResetStreamAt()
+		s.resetStreamAtNegotiated.Set(true)
+	}
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.rttStats.SetMaxAckDelay(params.MaxAckDelay)
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
 	if params.StatelessResetToken != nil {
 		s.connIDManager.SetStatelessResetToken(*params.StatelessResetToken)
 	}
-	// We don't support connection migration yet, so we don't have any use for the preferred_address.
+	// We don't support connection migration yet, so we don't migrate to the preferred_address.
+	// We still use its connection ID, like any other connection ID issued by the server.
+	// The preferred_address stateless reset token is registered when switching to this connection ID,
+	// such that stateless resets sent by the server are recognized from then on.
 	if params.PreferredAddress != nil {
-		s.logger.Debugf("Server sent preferred_address. Retiring the preferred_address connection ID.")
-		// Retire the connection ID.
-		s.connIDManager.AddFromPreferredAddress(params.PreferredAddress.ConnectionID, &params.PreferredAddress.StatelessResetToken)
+		s.logger.Debugf("Server sent preferred_address. Adding the preferred_address connection ID.")
+		if err := s.connIDManager.AddFromPreferredAddress(params.PreferredAddress.ConnectionID, &params.PreferredAddress.StatelessResetToken); err != nil {
+			s.closeLocal(err)
+			return
+		}
 	}
 	// On the server side, the early session is ready as soon as we processed
 	// the client's transport parameters.
@@ -1326,13 +1781,18 @@ func (s *session) sendProbePacket(encLevel protocol.EncryptionLevel) error {
 }
 
 func (s *session) sendPacket() (bool, error) {
-	if isBlocked, offset := s.connFlowController.IsNewlyBlocked(); isBlocked {
-		s.framer.QueueControlFrame(&wire.DataBlockedFrame{DataLimit: offset})
+	// We're only blocked if there's stream data waiting to be sent.
+	// Before receiving the peer's transport parameters, the send window is 0, but that doesn't mean we're blocked.
+	// If the peer's initial_max_data is 0, we're blocked at offset 0.
+	if s.peerParams != nil && s.framer.HasActiveStreams() {
+		if isBlocked, offset := s.connFlowController.IsNewlyBlocked(); isBlocked {
+			s.framer.QueueControlFrame(&wire.DataBlockedFrame{DataLimit: offset})
+		}
 	}
 	s.windowUpdateQueue.QueueAll()
 
 	if !s.handshakeConfirmed {
-		now := time.Now()
+		now := s.clock.Now()
 		packet, err := s.packer.PackCoalescedPacket()
 		if err != nil || packet == nil {
 			return false, err
@@ -1344,7 +1804,10 @@ func (s *session) sendPacket() (bool, error) {
 			s.sentPacketHandler.SentPacket(p.ToAckHandlerPacket(now, s.retransmissionQueue))
 		}
 		s.connIDManager.SentPacket()
+		s.lastPacketSentTime = now
+		s.natKeepAlivePingQueued = false
 		s.logCoalescedPacket(now, packet)
+		s.countSentDatagram(len(packet.packets), packet.buffer.Len())
 		s.sendQueue.Send(packet.buffer)
 		return true, nil
 	}
@@ -1357,33 +1820,57 @@ func (s *session) sendPacket() (bool, error) {
 }
 
 func (s *session) sendPackedPacket(packet *packedPacket) {
-	now := time.Now()
+	now := s.clock.Now()
 	if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && packet.IsAckEliciting() {
 		s.firstAckElicitingPacketAfterIdleSentTime = now
 	}
-	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(time.Now(), s.retransmissionQueue))
+	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
 	s.connIDManager.SentPacket()
+	s.lastPacketSentTime = now
+	s.natKeepAlivePingQueued = false
 	s.logPacket(now, packet)
+	s.countSentDatagram(1, packet.buffer.Len())
 	s.sendQueue.Send(packet.buffer)
 }
 
+// countSentDatagram updates the coalescing statistics for a datagram containing numPackets QUIC packets.
+func (s *session) countSentDatagram(numPackets int, size protocol.ByteCount) {
+	atomic.AddUint64(&s.numDatagramsSent, 1)
+	atomic.AddUint64(&s.numPacketsSent, uint64(numPackets))
+	if numPackets > 1 {
+		atomic.AddUint64(&s.numCoalescedDatagramsSent, 1)
+		atomic.AddUint64(&s.numCoalescedBytesSent, uint64(size))
+	}
+}
+
 func (s *session) sendConnectionClose(quicErr *qerr.QuicError) ([]byte, error) {
 	packet, err := s.packer.PackConnectionClose(quicErr)
 	if err != nil {
 		return nil, err
 	}
-	s.logCoalescedPacket(time.Now(), packet)
+	s.logCoalescedPacket(s.clock.Now(), packet)
+	s.countSentDatagram(len(packet.packets), packet.buffer.Len())
 	return packet.buffer.Data, s.conn.Write(packet.buffer.Data)
 }
 
 func (s *session) logPacketContents(now time.Time, p *packetContents) {
 	// qlog
 	if s.qlogger != nil {
+		var hasHandshakeDone bool
 		frames := make([]wire.Frame, 0, len(p.frames))
 		for _, f := range p.frames {
 			frames = append(frames, f.Frame)
+			if _, ok := f.Frame.(*wire.HandshakeDoneFrame); ok {
+				hasHandshakeDone = true
+			}
 		}
 		s.qlogger.SentPacket(now, p.header, p.length, p.ack, frames)
+		if p.header.IsLongHeader && p.header.Type == protocol.PacketTypeInitial {
+			s.qlogger.HandshakeProgressed(now, qlog.HandshakeEventInitialSent)
+		}
+		if hasHandshakeDone {
+			s.qlogger.HandshakeProgressed(now, qlog.HandshakeEventDoneSent)
+		}
 	}
 
 	// quic-trace
@@ -1403,6 +1890,12 @@ func (s *session) logPacketContents(now time.Time, p *packetContents) {
 		})
 	}
 
+	if s.config.PacketTimestampTracer != nil {
+		if encLevel := p.EncryptionLevel(); encLevel == protocol.Encryption0RTT || encLevel == protocol.Encryption1RTT {
+			s.config.PacketTimestampTracer.SentPacket(p.header.PacketNumber, now)
+		}
+	}
+
 	// quic-go logging
 	if !s.logger.Debug() {
 		return
@@ -1454,6 +1947,17 @@ func (s *session) OpenStreamSync(ctx context.Context) (Stream, error) {
 	return s.streamsMap.OpenStreamSync(ctx)
 }
 
+func (s *session) OpenStreamWithWindow(n uint64) (Stream, error) {
+	str, err := s.streamsMap.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	// If the window can't be reserved, writing to the stream will block
+	// until the peer grants more flow control credit.
+	str.(sendStreamI).reserveSendWindow(protocol.ByteCount(n))
+	return str, nil
+}
+
 func (s *session) OpenUniStream() (SendStream, error) {
 	return s.streamsMap.OpenUniStream()
 }
@@ -1475,14 +1979,30 @@ func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlow
 			}
 		}
 	}
+	var receiveWindow protocol.ByteCount = protocol.InitialMaxStreamData
+	s.zeroRTTParamsMutex.Lock()
+	if p := s.zeroRTTParams; p != nil {
+		if id.Type() == protocol.StreamTypeUni {
+			receiveWindow = utils.MaxByteCount(receiveWindow, p.InitialMaxStreamDataUni)
+		} else if id.InitiatedBy() == s.perspective {
+			receiveWindow = utils.MaxByteCount(receiveWindow, p.InitialMaxStreamDataBidiLocal)
+		} else {
+			receiveWindow = utils.MaxByteCount(receiveWindow, p.InitialMaxStreamDataBidiRemote)
+		}
+	}
+	s.zeroRTTParamsMutex.Unlock()
 	return flowcontrol.NewStreamFlowController(
 		id,
 		s.connFlowController,
-		protocol.InitialMaxStreamData,
+		receiveWindow,
 		protocol.ByteCount(s.config.MaxReceiveStreamFlowControlWindow),
+		s.config.ReceiveWindowGrowthFactor,
+		s.config.EnableBDPWindowAutoTuning,
+		s.config.StreamWindowUpdateThreshold,
 		initialSendWindow,
 		s.onHasStreamWindowUpdate,
 		s.rttStats,
+		s.clock,
 		s.logger,
 	)
 }
@@ -1496,9 +2016,14 @@ func (s *session) scheduleSending() {
 }
 
 func (s *session) tryQueueingUndecryptablePacket(p *receivedPacket, hdr *wire.Header) {
-	if len(s.undecryptablePackets)+1 > protocol.MaxUndecryptablePackets {
-		s.logger.Infof("Dropping undecryptable packet (%d bytes). Undecryptable packet queue full.", len(p.data))
-		return
+	if len(s.undecryptablePackets)+1 > s.config.MaxUndecryptablePackets {
+		// drop the oldest packet to make room for the new one
+		dropped := s.undecryptablePackets[0]
+		s.logger.Infof("Dropping undecryptable packet (%d bytes). Undecryptable packet queue full.", len(dropped.data))
+		dropped.buffer.Decrement()
+		dropped.buffer.MaybeRelease()
+		s.undecryptablePackets = append(s.undecryptablePackets[:0], s.undecryptablePackets[1:]...)
+		atomic.AddUint64(&s.numDroppedUndecryptablePackets, 1)
 	}
 	s.logger.Infof("Queueing packet (%d bytes) for later decryption", len(p.data))
 	if s.qlogger != nil {
@@ -1534,6 +2059,10 @@ func (s *session) onHasStreamData(id protocol.StreamID) {
 	s.scheduleSending()
 }
 
+func (s *session) supportsResetStreamAt() bool {
+	return s.resetStreamAtNegotiated.Get()
+}
+
 func (s *session) onStreamCompleted(id protocol.StreamID) {
 	if err := s.streamsMap.DeleteStream(id); err != nil {
 		s.closeLocal(err)
@@ -1548,6 +2077,78 @@ func (s *session) RemoteAddr() net.Addr {
 	return s.conn.RemoteAddr()
 }
 
+func (s *session) SendQueueDepth() SendQueueDepth {
+	return s.streamsMap.SendQueueDepth()
+}
+
+func (s *session) StreamCounts() StreamCounts {
+	return s.streamsMap.StreamCounts()
+}
+
+func (s *session) FlowControlOffsets() FlowControlOffsets {
+	bytesSent, sendWindow := s.connFlowController.SendOffsets()
+	bytesRead, highestReceived, receiveWindow := s.connFlowController.ReceiveOffsets()
+	return FlowControlOffsets{
+		BytesSent:       uint64(bytesSent),
+		SendWindow:      uint64(sendWindow),
+		BytesRead:       uint64(bytesRead),
+		HighestReceived: uint64(highestReceived),
+		ReceiveWindow:   uint64(receiveWindow),
+	}
+}
+
+func (s *session) PacketNumberSpaces() PacketNumberSpaces {
+	return PacketNumberSpaces{
+		Initial:   s.packetNumberSpaceStats(protocol.EncryptionInitial),
+		Handshake: s.packetNumberSpaceStats(protocol.EncryptionHandshake),
+		AppData:   s.packetNumberSpaceStats(protocol.Encryption1RTT),
+	}
+}
+
+func (s *session) packetNumberSpaceStats(encLevel protocol.EncryptionLevel) PacketNumberSpaceStats {
+	stats := s.sentPacketHandler.PacketNumberSpaceStats(encLevel)
+	if stats.Dropped {
+		return PacketNumberSpaceStats{Dropped: true}
+	}
+	return PacketNumberSpaceStats{
+		NextPacketNumber: int64(stats.NextPacketNumber),
+		LargestSent:      int64(stats.LargestSent),
+		LargestAcked:     int64(stats.LargestAcked),
+	}
+}
+
+func (s *session) ConnectionIDs() ConnectionIDs {
+	local, highestSeq := s.connIDGenerator.ConnectionIDs()
+	peer, retiredBelow := s.connIDManager.ConnectionIDs()
+	return ConnectionIDs{
+		Local:                      local,
+		HighestLocalSequenceNumber: highestSeq,
+		Peer:                       peer,
+		PeerRetiredBelow:           retiredBelow,
+	}
+}
+
+func (s *session) RetirePeerConnectionID(seq uint64) error {
+	if err := s.connIDManager.Retire(seq); err != nil {
+		return err
+	}
+	s.scheduleSending()
+	return nil
+}
+
+func (s *session) SetCongestionControl(cc CongestionControlAlgorithm) error {
+	switch cc {
+	case CongestionControlReno:
+		s.sentPacketHandler.SetCongestionControl(true)
+	case CongestionControlCubic:
+		s.sentPacketHandler.SetCongestionControl(false)
+	default:
+		return fmt.Errorf("unknown congestion control algorithm: %d", cc)
+	}
+	s.scheduleSending()
+	return nil
+}
+
 func (s *session) getPerspective() protocol.Perspective {
 	return s.perspective
 }
@@ -0,0 +1,80 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+)
+
+// ExportSessionState serializes a session state, as it is stored in the tls.ClientSessionCache.
+// Besides the TLS session ticket, the serialized state contains the server's transport parameters,
+// and can therefore be used to send 0-RTT data using DialEarlyWithSessionState.
+// Note that the serialized state contains the TLS resumption secret, and needs to be kept confidential.
+func ExportSessionState(state *tls.ClientSessionState) ([]byte, error) {
+	return handshake.MarshalClientSessionState(state)
+}
+
+// DialEarlyWithSessionState establishes a new 0-RTT QUIC connection to a server using a net.PacketConn,
+// resuming a session from a session state that was serialized using ExportSessionState.
+// The tls.ClientSessionCache is not consulted when resuming the session.
+// If it is set, session tickets received on the new session are added to the cache.
+// See DialEarly for details.
+func DialEarlyWithSessionState(
+	pconn net.PacketConn,
+	remoteAddr net.Addr,
+	host string,
+	sessionState []byte,
+	tlsConf *tls.Config,
+	config *Config,
+) (EarlySession, error) {
+	if tlsConf == nil {
+		return nil, errors.New("quic: tls.Config not set")
+	}
+	state, err := handshake.UnmarshalClientSessionState(sessionState)
+	if err != nil {
+		return nil, fmt.Errorf("quic: invalid session state: %s", err)
+	}
+	tlsConf = tlsConf.Clone()
+	tlsConf.ClientSessionCache = &importedSessionCache{
+		state: state,
+		cache: tlsConf.ClientSessionCache,
+	}
+	return dialContext(context.Background(), pconn, remoteAddr, host, tlsConf, config, true, false)
+}
+
+// The importedSessionCache is a tls.ClientSessionCache that returns an imported session state once.
+// Afterwards, and for new session tickets, it uses the application's tls.ClientSessionCache, if set.
+type importedSessionCache struct {
+	mutex sync.Mutex
+	state *tls.ClientSessionState
+
+	cache tls.ClientSessionCache
+}
+
+var _ tls.ClientSessionCache = &importedSessionCache{}
+
+func (c *importedSessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	c.mutex.Lock()
+	state := c.state
+	c.state = nil
+	c.mutex.Unlock()
+
+	if state != nil {
+		return state, true
+	}
+	if c.cache != nil {
+		return c.cache.Get(sessionKey)
+	}
+	return nil, false
+}
+
+func (c *importedSessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	if c.cache != nil {
+		c.cache.Put(sessionKey, cs)
+	}
+}
@@ -0,0 +1,67 @@
+package quic
+
+import (
+	"crypto/tls"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Session state export", func() {
+	It("refuses to export nil session states", func() {
+		_, err := ExportSessionState(nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("refuses to dial without a tls.Config", func() {
+		_, err := DialEarlyWithSessionState(nil, &net.UDPAddr{}, "localhost", []byte{0x1}, nil, nil)
+		Expect(err).To(MatchError("quic: tls.Config not set"))
+	})
+
+	It("refuses to dial with an invalid session state", func() {
+		_, err := DialEarlyWithSessionState(nil, &net.UDPAddr{}, "localhost", []byte{0x2a}, &tls.Config{}, nil)
+		Expect(err).To(MatchError("quic: invalid session state: unknown session state revision: 42"))
+	})
+
+	Context("the imported session cache", func() {
+		It("returns the imported session state only once", func() {
+			state := &tls.ClientSessionState{}
+			cache := &importedSessionCache{state: state}
+			s, ok := cache.Get("localhost")
+			Expect(ok).To(BeTrue())
+			Expect(s).To(BeIdenticalTo(state))
+			_, ok = cache.Get("localhost")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("uses the application's cache after returning the imported session state", func() {
+			appCache := tls.NewLRUClientSessionCache(1)
+			appState := &tls.ClientSessionState{}
+			appCache.Put("localhost", appState)
+			state := &tls.ClientSessionState{}
+			cache := &importedSessionCache{state: state, cache: appCache}
+			s, ok := cache.Get("localhost")
+			Expect(ok).To(BeTrue())
+			Expect(s).To(BeIdenticalTo(state))
+			s, ok = cache.Get("localhost")
+			Expect(ok).To(BeTrue())
+			Expect(s).To(BeIdenticalTo(appState))
+		})
+
+		It("passes new session tickets to the application's cache", func() {
+			appCache := tls.NewLRUClientSessionCache(1)
+			cache := &importedSessionCache{state: &tls.ClientSessionState{}, cache: appCache}
+			state := &tls.ClientSessionState{}
+			cache.Put("localhost", state)
+			s, ok := appCache.Get("localhost")
+			Expect(ok).To(BeTrue())
+			Expect(s).To(BeIdenticalTo(state))
+		})
+
+		It("drops new session tickets if the application didn't set a cache", func() {
+			cache := &importedSessionCache{}
+			Expect(func() { cache.Put("localhost", &tls.ClientSessionState{}) }).ToNot(Panic())
+		})
+	})
+})
@@ -17,6 +17,8 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	mockackhandler "github.com/lucas-clemente/quic-go/internal/mocks/ackhandler"
@@ -25,6 +27,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/testutils"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/qlog"
 )
 
 func areSessionsRunning() bool {
@@ -39,6 +42,57 @@ func areClosedSessionsRunning() bool {
 	return strings.Contains(b.String(), "quic-go.(*closedLocalSession).run")
 }
 
+// packetTimestampRecorder is a PacketTimestampTracer that records the send times of packets and the receive times of ACKs.
+type packetTimestampRecorder struct {
+	sentPNs   []protocol.PacketNumber
+	sentTimes []time.Time
+	acks      []protocol.PacketNumber
+	ackDelays []time.Duration
+	ackTimes  []time.Time
+}
+
+func (r *packetTimestampRecorder) SentPacket(pn protocol.PacketNumber, t time.Time) {
+	r.sentPNs = append(r.sentPNs, pn)
+	r.sentTimes = append(r.sentTimes, t)
+}
+
+func (r *packetTimestampRecorder) ReceivedAck(largestAcked protocol.PacketNumber, ackDelay time.Duration, t time.Time) {
+	r.acks = append(r.acks, largestAcked)
+	r.ackDelays = append(r.ackDelays, ackDelay)
+	r.ackTimes = append(r.ackTimes, t)
+}
+
+// handshakeEventRecorder is a qlog.Tracer that records handshake events.
+// Calling any method other than SentPacket, ReceivedPacket and HandshakeProgressed panics.
+type handshakeEventRecorder struct {
+	qlog.Tracer
+	events []qlog.HandshakeEvent
+}
+
+func (r *handshakeEventRecorder) SentPacket(time.Time, *wire.ExtendedHeader, protocol.ByteCount, *wire.AckFrame, []wire.Frame) {
+}
+
+func (r *handshakeEventRecorder) ReceivedPacket(time.Time, *wire.ExtendedHeader, protocol.ByteCount, []wire.Frame) {
+}
+
+func (r *handshakeEventRecorder) HandshakeProgressed(_ time.Time, e qlog.HandshakeEvent) {
+	r.events = append(r.events, e)
+}
+
+// transportParameterRecorder is a qlog.Tracer that records the transport parameters we sent.
+// Calling any method other than SentTransportParameters and UpdatedKeyFromTLS panics.
+type transportParameterRecorder struct {
+	qlog.Tracer
+	sent *qlog.TransportParameters
+}
+
+func (r *transportParameterRecorder) SentTransportParameters(_ time.Time, tp *qlog.TransportParameters) {
+	r.sent = tp
+}
+
+func (r *transportParameterRecorder) UpdatedKeyFromTLS(time.Time, protocol.EncryptionLevel, protocol.Perspective) {
+}
+
 var _ = Describe("Session", func() {
 	var (
 		sess          *session
@@ -143,6 +197,37 @@ var _ = Describe("Session", func() {
 					Data:     []byte("foobar"),
 				})).To(Succeed())
 			})
+
+			It("adds the frame type to transport errors", func() {
+				testErr := qerr.Error(qerr.FlowControlError, "flow control violation")
+				f := &wire.StreamFrame{
+					StreamID:       5,
+					Offset:         100,
+					Data:           []byte("foobar"),
+					DataLenPresent: true,
+				}
+				str := NewMockReceiveStreamI(mockCtrl)
+				str.EXPECT().handleStreamFrame(f).Return(testErr)
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
+				err := sess.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(BeAssignableToTypeOf(&qerr.QuicError{}))
+				qErr := err.(*qerr.QuicError)
+				Expect(qErr.ErrorCode).To(Equal(qerr.FlowControlError))
+				Expect(qErr.FrameType).To(BeEquivalentTo(0x8 | 0x4 | 0x2)) // STREAM frame with the OFF and LEN bit set
+				// the original error must not be modified
+				Expect(testErr.FrameType).To(BeZero())
+			})
+
+			It("doesn't overwrite the frame type of transport errors", func() {
+				testErr := qerr.ErrorWithFrameType(qerr.FlowControlError, 0x42, "flow control violation")
+				f := &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}
+				str := NewMockReceiveStreamI(mockCtrl)
+				str.EXPECT().handleStreamFrame(f).Return(testErr)
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
+				err := sess.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(BeAssignableToTypeOf(&qerr.QuicError{}))
+				Expect(err.(*qerr.QuicError).FrameType).To(BeEquivalentTo(0x42))
+			})
 		})
 
 		Context("handling ACK frames", func() {
@@ -150,10 +235,113 @@ var _ = Describe("Session", func() {
 				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
 				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 				sph.EXPECT().ReceivedAck(f, protocol.EncryptionHandshake, gomock.Any())
+				sph.EXPECT().DeliveryRate()
+				sph.EXPECT().NumSpuriousRetransmissions()
 				sess.sentPacketHandler = sph
 				err := sess.handleAckFrame(f, protocol.EncryptionHandshake)
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("records the receive time of ACKs for 1-RTT packets", func() {
+				recorder := &packetTimestampRecorder{}
+				sess.config.PacketTimestampTracer = recorder
+				rcvTime := time.Now().Add(-time.Second)
+				sess.lastPacketReceivedTime = rcvTime
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}, DelayTime: 5 * time.Millisecond}
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, rcvTime)
+				sph.EXPECT().ReceivedAck(f, protocol.EncryptionHandshake, rcvTime)
+				sph.EXPECT().DeliveryRate().Times(2)
+				sph.EXPECT().NumSpuriousRetransmissions().Times(2)
+				sess.sentPacketHandler = sph
+				cryptoSetup.EXPECT().SetLargest1RTTAcked(protocol.PacketNumber(3))
+				Expect(sess.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				Expect(sess.handleAckFrame(f, protocol.EncryptionHandshake)).To(Succeed())
+				Expect(recorder.acks).To(Equal([]protocol.PacketNumber{3}))
+				Expect(recorder.ackDelays).To(Equal([]time.Duration{5 * time.Millisecond}))
+				Expect(recorder.ackTimes).To(Equal([]time.Time{rcvTime}))
+			})
+
+			It("tells the ACK delay of the most recent ACK for 1-RTT packets", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+				sph.EXPECT().DeliveryRate().Times(3)
+				sph.EXPECT().NumSpuriousRetransmissions().Times(3)
+				sess.sentPacketHandler = sph
+				cryptoSetup.EXPECT().SetLargest1RTTAcked(gomock.Any()).Times(2)
+				Expect(sess.LatestAckDelay()).To(BeZero())
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}, DelayTime: 5 * time.Millisecond}
+				Expect(sess.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				Expect(sess.LatestAckDelay()).To(Equal(5 * time.Millisecond))
+				// ACK delays for Handshake packets are ignored
+				f = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}, DelayTime: time.Second}
+				Expect(sess.handleAckFrame(f, protocol.EncryptionHandshake)).To(Succeed())
+				Expect(sess.LatestAckDelay()).To(Equal(5 * time.Millisecond))
+				f = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 4}}, DelayTime: 3 * time.Millisecond}
+				Expect(sess.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				Expect(sess.LatestAckDelay()).To(Equal(3 * time.Millisecond))
+			})
+
+			It("tells the estimated bandwidth", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				sph.EXPECT().NumSpuriousRetransmissions().Times(2)
+				sess.sentPacketHandler = sph
+				Expect(sess.EstimatedBandwidth()).To(BeZero())
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				sph.EXPECT().DeliveryRate().Return(congestion.Bandwidth(1e6) * congestion.BytesPerSecond)
+				Expect(sess.handleAckFrame(f, protocol.EncryptionHandshake)).To(Succeed())
+				Expect(sess.EstimatedBandwidth()).To(BeEquivalentTo(1e6))
+				sph.EXPECT().DeliveryRate().Return(congestion.Bandwidth(2e6) * congestion.BytesPerSecond)
+				Expect(sess.handleAckFrame(f, protocol.EncryptionHandshake)).To(Succeed())
+				Expect(sess.EstimatedBandwidth()).To(BeEquivalentTo(2e6))
+			})
+
+			It("tells the number of spurious retransmissions", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				sph.EXPECT().DeliveryRate().Times(2)
+				sess.sentPacketHandler = sph
+				Expect(sess.NumSpuriousRetransmissions()).To(BeZero())
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				sph.EXPECT().NumSpuriousRetransmissions().Return(uint64(3))
+				Expect(sess.handleAckFrame(f, protocol.EncryptionHandshake)).To(Succeed())
+				Expect(sess.NumSpuriousRetransmissions()).To(BeEquivalentTo(3))
+				sph.EXPECT().NumSpuriousRetransmissions().Return(uint64(5))
+				Expect(sess.handleAckFrame(f, protocol.EncryptionHandshake)).To(Succeed())
+				Expect(sess.NumSpuriousRetransmissions()).To(BeEquivalentTo(5))
+			})
+		})
+
+		It("returns the packet number spaces", func() {
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			sph.EXPECT().PacketNumberSpaceStats(protocol.EncryptionInitial).Return(ackhandler.PacketNumberSpaceStats{Dropped: true})
+			sph.EXPECT().PacketNumberSpaceStats(protocol.EncryptionHandshake).Return(ackhandler.PacketNumberSpaceStats{
+				NextPacketNumber: 3,
+				LargestSent:      2,
+				LargestAcked:     protocol.InvalidPacketNumber,
+			})
+			sph.EXPECT().PacketNumberSpaceStats(protocol.Encryption1RTT).Return(ackhandler.PacketNumberSpaceStats{
+				NextPacketNumber: 10,
+				LargestSent:      8,
+				LargestAcked:     7,
+			})
+			Expect(sess.PacketNumberSpaces()).To(Equal(PacketNumberSpaces{
+				Initial:   PacketNumberSpaceStats{Dropped: true},
+				Handshake: PacketNumberSpaceStats{NextPacketNumber: 3, LargestSent: 2, LargestAcked: -1},
+				AppData:   PacketNumberSpaceStats{NextPacketNumber: 10, LargestSent: 8, LargestAcked: 7},
+			}))
+		})
+
+		It("switches the congestion control algorithm", func() {
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			sph.EXPECT().SetCongestionControl(false)
+			Expect(sess.SetCongestionControl(CongestionControlCubic)).To(Succeed())
+			sph.EXPECT().SetCongestionControl(true)
+			Expect(sess.SetCongestionControl(CongestionControlReno)).To(Succeed())
+			Expect(sess.SetCongestionControl(42)).To(MatchError("unknown congestion control algorithm: 42"))
 		})
 
 		Context("handling RESET_STREAM frames", func() {
@@ -188,7 +376,7 @@ var _ = Describe("Session", func() {
 				Expect(sess.handleFrame(&wire.ResetStreamFrame{
 					StreamID:  3,
 					ErrorCode: 42,
-				}, protocol.EncryptionUnspecified)).To(Succeed())
+				}, protocol.EncryptionUnspecified, protocol.ConnectionID{})).To(Succeed())
 			})
 		})
 
@@ -223,7 +411,7 @@ var _ = Describe("Session", func() {
 				Expect(sess.handleFrame(&wire.MaxStreamDataFrame{
 					StreamID:   10,
 					ByteOffset: 1337,
-				}, protocol.EncryptionUnspecified)).To(Succeed())
+				}, protocol.EncryptionUnspecified, protocol.ConnectionID{})).To(Succeed())
 			})
 		})
 
@@ -265,7 +453,7 @@ var _ = Describe("Session", func() {
 				Expect(sess.handleFrame(&wire.StopSendingFrame{
 					StreamID:  3,
 					ErrorCode: 1337,
-				}, protocol.EncryptionUnspecified)).To(Succeed())
+				}, protocol.EncryptionUnspecified, protocol.ConnectionID{})).To(Succeed())
 			})
 		})
 
@@ -273,23 +461,53 @@ var _ = Describe("Session", func() {
 			Expect(sess.handleFrame(&wire.NewConnectionIDFrame{
 				SequenceNumber: 10,
 				ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
-			}, protocol.Encryption1RTT)).To(Succeed())
+			}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
 			Expect(sess.connIDManager.queue.Back().Value.ConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
 		})
 
+		It("retires the peer's connection ID on request", func() {
+			Expect(sess.handleFrame(&wire.NewConnectionIDFrame{
+				SequenceNumber:      1,
+				ConnectionID:        protocol.ConnectionID{1, 2, 3, 4},
+				StatelessResetToken: [16]byte{1},
+			}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(sess.handleFrame(&wire.NewConnectionIDFrame{
+				SequenceNumber:      2,
+				ConnectionID:        protocol.ConnectionID{5, 6, 7, 8},
+				StatelessResetToken: [16]byte{2},
+			}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			sessionRunner.EXPECT().AddResetToken([16]byte{1}, sess)
+			Expect(sess.connIDManager.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+			sess.framer.AppendControlFrames(nil, protocol.MaxByteCount) // the RETIRE_CONNECTION_ID frame for the initial connection ID
+			sessionRunner.EXPECT().RetireResetToken([16]byte{1})
+			sessionRunner.EXPECT().AddResetToken([16]byte{2}, sess)
+			Expect(sess.RetirePeerConnectionID(1)).To(Succeed())
+			frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.RetireConnectionIDFrame{SequenceNumber: 1}}}))
+			Expect(sess.connIDManager.Get()).To(Equal(protocol.ConnectionID{5, 6, 7, 8}))
+			Expect(sess.ConnectionIDs().Peer).To(Equal([]ConnectionIDInfo{{SequenceNumber: 2, ConnectionID: protocol.ConnectionID{5, 6, 7, 8}, InUse: true}}))
+		})
+
+		It("rejects RETIRE_CONNECTION_ID frames that retire the connection ID the packet was sent to", func() {
+			err := sess.handleFrame(&wire.RetireConnectionIDFrame{SequenceNumber: 0}, protocol.Encryption1RTT, srcConnID)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ProtocolViolation))
+			Expect(sess.ConnectionIDs().Local).To(Equal([]ConnectionIDInfo{{SequenceNumber: 0, ConnectionID: srcConnID}}))
+		})
+
 		It("handles PING frames", func() {
-			err := sess.handleFrame(&wire.PingFrame{}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.PingFrame{}, protocol.EncryptionUnspecified, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("rejects PATH_RESPONSE frames", func() {
-			err := sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.EncryptionUnspecified, protocol.ConnectionID{})
 			Expect(err).To(MatchError("unexpected PATH_RESPONSE frame"))
 		})
 
 		It("handles PATH_CHALLENGE frames", func() {
 			data := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
-			err := sess.handleFrame(&wire.PathChallengeFrame{Data: data}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.PathChallengeFrame{Data: data}, protocol.EncryptionUnspecified, protocol.ConnectionID{})
 			Expect(err).ToNot(HaveOccurred())
 			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
 			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.PathResponseFrame{Data: data}}}))
@@ -303,17 +521,19 @@ var _ = Describe("Session", func() {
 		})
 
 		It("handles BLOCKED frames", func() {
-			err := sess.handleFrame(&wire.DataBlockedFrame{}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.DataBlockedFrame{}, protocol.EncryptionUnspecified, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("handles STREAM_BLOCKED frames", func() {
-			err := sess.handleFrame(&wire.StreamDataBlockedFrame{}, protocol.EncryptionUnspecified)
+			err := sess.handleFrame(&wire.StreamDataBlockedFrame{}, protocol.EncryptionUnspecified, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("handles STREAM_ID_BLOCKED frames", func() {
-			err := sess.handleFrame(&wire.StreamsBlockedFrame{}, protocol.EncryptionUnspecified)
+		It("handles STREAMS_BLOCKED frames", func() {
+			f := &wire.StreamsBlockedFrame{Type: protocol.StreamTypeBidi, StreamLimit: 10}
+			streamManager.EXPECT().HandleStreamsBlockedFrame(f)
+			err := sess.handleFrame(f, protocol.EncryptionUnspecified, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -337,7 +557,7 @@ var _ = Describe("Session", func() {
 				ErrorCode:    qerr.StreamLimitError,
 				ReasonPhrase: "foobar",
 			}
-			Expect(sess.handleFrame(ccf, protocol.EncryptionUnspecified)).To(Succeed())
+			Expect(sess.handleFrame(ccf, protocol.EncryptionUnspecified, protocol.ConnectionID{})).To(Succeed())
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
@@ -362,13 +582,111 @@ var _ = Describe("Session", func() {
 				ReasonPhrase:       "foobar",
 				IsApplicationError: true,
 			}
-			Expect(sess.handleFrame(ccf, protocol.EncryptionUnspecified)).To(Succeed())
+			Expect(sess.handleFrame(ccf, protocol.EncryptionUnspecified, protocol.ConnectionID{})).To(Succeed())
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+
+		It("calls the OnConnectionClose callback before closing the session", func() {
+			testErr := qerr.Error(qerr.FrameEncodingError, "foobar")
+			var info *ConnectionCloseInfo
+			sess.config.OnConnectionClose = func(i ConnectionCloseInfo) {
+				Expect(sess.Context().Done()).ToNot(BeClosed())
+				info = &i
+			}
+			remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 4433}
+			mconn.EXPECT().RemoteAddr().Return(remoteAddr)
+			streamManager.EXPECT().CloseWithError(testErr)
+			sessionRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				Expect(sess.run()).To(MatchError(testErr))
+			}()
+			ccf := &wire.ConnectionCloseFrame{
+				ErrorCode:    qerr.FrameEncodingError,
+				FrameType:    0x8,
+				ReasonPhrase: "foobar",
+			}
+			Expect(sess.handleFrame(ccf, protocol.EncryptionUnspecified, protocol.ConnectionID{})).To(Succeed())
+			Expect(info).ToNot(BeNil())
+			Expect(*info).To(Equal(ConnectionCloseInfo{
+				Session:      sess,
+				RemoteAddr:   remoteAddr,
+				ErrorCode:    uint64(qerr.FrameEncodingError),
+				FrameType:    0x8,
+				ReasonPhrase: "foobar",
+			}))
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
 		It("errors on HANDSHAKE_DONE frames", func() {
 			Expect(sess.handleHandshakeDoneFrame()).To(MatchError("PROTOCOL_VIOLATION: received a HANDSHAKE_DONE frame"))
 		})
+
+		Context("handling ACK_FREQUENCY frames", func() {
+			var rph *mockackhandler.MockReceivedPacketHandler
+
+			BeforeEach(func() {
+				rph = mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				sess.receivedPacketHandler = rph
+				sess.config.EnableAckFrequency = true
+			})
+
+			It("advertises support in the transport parameters", func() {
+				Expect(sess.minAckDelay()).To(Equal(protocol.MinAckDelay))
+				sess.config.EnableAckFrequency = false
+				Expect(sess.minAckDelay()).To(BeZero())
+			})
+
+			It("adjusts the ACK behavior", func() {
+				Expect(sess.AckFrequencyPacketTolerance()).To(BeZero())
+				Expect(sess.AckFrequencyMaxAckDelay()).To(Equal(protocol.MaxAckDelay))
+				rph.EXPECT().SetAckFrequency(uint64(10), 40*time.Millisecond, true)
+				Expect(sess.handleFrame(&wire.AckFrequencyFrame{
+					SequenceNumber:    0,
+					PacketTolerance:   10,
+					UpdateMaxAckDelay: 40 * time.Millisecond,
+					IgnoreOrder:       true,
+				}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(sess.AckFrequencyPacketTolerance()).To(Equal(uint64(10)))
+				Expect(sess.AckFrequencyMaxAckDelay()).To(Equal(40 * time.Millisecond))
+			})
+
+			It("ignores reordered frames", func() {
+				rph.EXPECT().SetAckFrequency(uint64(20), 40*time.Millisecond, false)
+				Expect(sess.handleFrame(&wire.AckFrequencyFrame{
+					SequenceNumber:    2,
+					PacketTolerance:   20,
+					UpdateMaxAckDelay: 40 * time.Millisecond,
+				}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(sess.handleFrame(&wire.AckFrequencyFrame{
+					SequenceNumber:    1,
+					PacketTolerance:   10,
+					UpdateMaxAckDelay: 50 * time.Millisecond,
+				}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(sess.AckFrequencyPacketTolerance()).To(Equal(uint64(20)))
+				Expect(sess.AckFrequencyMaxAckDelay()).To(Equal(40 * time.Millisecond))
+			})
+
+			It("rejects an Update Max Ack Delay smaller than the min_ack_delay", func() {
+				err := sess.handleFrame(&wire.AckFrequencyFrame{
+					PacketTolerance:   10,
+					UpdateMaxAckDelay: protocol.MinAckDelay - 1,
+				}, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(MatchError("PROTOCOL_VIOLATION (frame type: 0xaf): received an ACK_FREQUENCY frame with an Update Max Ack Delay smaller than the min_ack_delay"))
+			})
+
+			It("rejects ACK_FREQUENCY frames if the extension is disabled", func() {
+				sess.config.EnableAckFrequency = false
+				err := sess.handleFrame(&wire.AckFrequencyFrame{
+					PacketTolerance:   10,
+					UpdateMaxAckDelay: 40 * time.Millisecond,
+				}, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(MatchError("PROTOCOL_VIOLATION (frame type: 0xaf): received an ACK_FREQUENCY frame, but the ACK Frequency extension was not negotiated"))
+			})
+		})
 	})
 
 	It("tells its versions", func() {
@@ -376,6 +694,40 @@ var _ = Describe("Session", func() {
 		Expect(sess.GetVersion()).To(Equal(protocol.VersionNumber(4242)))
 	})
 
+	It("tells its send queue depth", func() {
+		streamManager.EXPECT().SendQueueDepth().Return(SendQueueDepth{Queued: 1337, Unacknowledged: 42})
+		Expect(sess.SendQueueDepth()).To(Equal(SendQueueDepth{Queued: 1337, Unacknowledged: 42}))
+	})
+
+	It("tells the number of open streams", func() {
+		streamManager.EXPECT().StreamCounts().Return(StreamCounts{SendStreams: 3, ReceiveStreams: 4})
+		Expect(sess.StreamCounts()).To(Equal(StreamCounts{SendStreams: 3, ReceiveStreams: 4}))
+	})
+
+	It("resets all streams", func() {
+		streamManager.EXPECT().ResetAllStreams(protocol.ApplicationErrorCode(1337))
+		sess.ResetAllStreams(1337)
+	})
+
+	It("tells the number of key updates", func() {
+		cryptoSetup.EXPECT().NumKeyUpdates().Return(uint64(3))
+		Expect(sess.NumKeyUpdates()).To(Equal(uint64(3)))
+	})
+
+	It("tells if a HelloRetryRequest was used", func() {
+		cryptoSetup.EXPECT().UsedHelloRetryRequest().Return(true)
+		Expect(sess.UsedHelloRetryRequest()).To(BeTrue())
+	})
+
+	It("tells why 0-RTT was rejected", func() {
+		rejection := handshake.ZeroRTTRejection{
+			Reason:                        handshake.ZeroRTTRejectedTransportParameters,
+			MismatchedTransportParameters: []string{"initial_max_data"},
+		}
+		cryptoSetup.EXPECT().ZeroRTTRejection().Return(rejection)
+		Expect(sess.ZeroRTTRejection()).To(Equal(rejection))
+	})
+
 	Context("closing", func() {
 		var (
 			runErr         error
@@ -463,6 +815,30 @@ var _ = Describe("Session", func() {
 			Expect(sess.Context().Done()).To(BeClosed())
 		})
 
+		It("includes the type of the frame that caused the error in the close frame", func() {
+			testErr := qerr.Error(qerr.FlowControlError, "flow control violation")
+			f := &wire.StreamFrame{StreamID: 3, Data: []byte("foobar"), FinBit: true}
+			str := NewMockReceiveStreamI(mockCtrl)
+			str.EXPECT().handleStreamFrame(f).Return(testErr)
+			streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(3)).Return(str, nil)
+			err := sess.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).To(HaveOccurred())
+
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(quicErr *qerr.QuicError) (*coalescedPacket, error) {
+				Expect(quicErr.IsApplicationError()).To(BeFalse())
+				Expect(quicErr.ErrorCode).To(Equal(qerr.FlowControlError))
+				Expect(quicErr.FrameType).To(BeEquivalentTo(0x8 | 0x1)) // STREAM frame with the FIN bit set
+				return &coalescedPacket{buffer: getPacketBuffer()}, nil
+			})
+			mconn.EXPECT().Write(gomock.Any())
+			sess.closeLocal(err)
+			Eventually(areSessionsRunning).Should(BeFalse())
+			expectedRunErr = err
+		})
+
 		It("closes the session in order to recreate it", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			sessionRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
@@ -518,7 +894,7 @@ var _ = Describe("Session", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(*wire.Header, time.Time, []byte) (*unpackedPacket, error) {
 				buf := &bytes.Buffer{}
 				Expect((&wire.ConnectionCloseFrame{ErrorCode: qerr.StreamLimitError}).Write(buf, sess.version)).To(Succeed())
-				return &unpackedPacket{data: buf.Bytes(), encryptionLevel: protocol.Encryption1RTT}, nil
+				return &unpackedPacket{hdr: &wire.ExtendedHeader{}, data: buf.Bytes(), encryptionLevel: protocol.Encryption1RTT}, nil
 			})
 			// don't EXPECT any calls to packer.PackPacket()
 			sess.handlePacket(&receivedPacket{
@@ -601,6 +977,38 @@ var _ = Describe("Session", func() {
 			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
 		})
 
+		It("drops packets with a cleared QUIC bit, if greasing the QUIC bit wasn't negotiated", func() {
+			sess.peerParams = &handshake.TransportParameters{}
+			packet := getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, nil)
+			packet.data[0] &^= 0x40
+			Expect(sess.handlePacketImpl(packet)).To(BeFalse())
+		})
+
+		It("accepts packets with a cleared QUIC bit, if greasing the QUIC bit was negotiated", func() {
+			sess.peerParams = &handshake.TransportParameters{GreaseQUICBit: true}
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x1337,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.Encryption1RTT, gomock.Any(), false)
+			sess.receivedPacketHandler = rph
+			packet := getPacket(hdr, nil)
+			packet.data[0] &^= 0x40
+			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+		})
+
 		It("drops a packet when unpacking fails", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -753,6 +1161,38 @@ var _ = Describe("Session", func() {
 			Expect(sess.undecryptablePackets).To(Equal([]*receivedPacket{packet}))
 		})
 
+		It("drops the oldest undecryptable packet when the queue is full", func() {
+			sess.handshakeComplete = false
+			sess.config.MaxUndecryptablePackets = 3
+			var packets []*receivedPacket
+			for i := protocol.PacketNumber(1); i <= 5; i++ {
+				hdr := &wire.ExtendedHeader{
+					Header: wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeHandshake,
+						DestConnectionID: destConnID,
+						SrcConnectionID:  srcConnID,
+						Length:           1,
+						Version:          sess.version,
+					},
+					PacketNumberLen: protocol.PacketNumberLen1,
+					PacketNumber:    i,
+				}
+				packets = append(packets, getPacket(hdr, nil))
+			}
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrKeysNotYetAvailable).Times(5)
+			for _, p := range packets[:3] {
+				Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			}
+			Expect(sess.undecryptablePackets).To(Equal(packets[:3]))
+			Expect(sess.NumDroppedUndecryptablePackets()).To(BeZero())
+			for _, p := range packets[3:] {
+				Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			}
+			Expect(sess.undecryptablePackets).To(Equal(packets[2:]))
+			Expect(sess.NumDroppedUndecryptablePackets()).To(BeEquivalentTo(2))
+		})
+
 		Context("updating the remote address", func() {
 			It("doesn't support connection migration", func() {
 				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
@@ -843,6 +1283,40 @@ var _ = Describe("Session", func() {
 				Expect(sess.undecryptablePackets[0].data).To(HaveLen(hdrLen1 + 456 - 3))
 			})
 
+			It("selects the keys for every coalesced packet, and processes the remaining packets if decryption fails", func() {
+				getPacketOfType := func(typ protocol.PacketType, length protocol.ByteCount) *receivedPacket {
+					return getPacket(&wire.ExtendedHeader{
+						Header: wire.Header{
+							IsLongHeader:     true,
+							Type:             typ,
+							DestConnectionID: srcConnID,
+							SrcConnectionID:  destConnID,
+							Version:          protocol.VersionTLS,
+							Length:           length,
+						},
+						PacketNumberLen: protocol.PacketNumberLen3,
+					}, make([]byte, int(length)-3))
+				}
+				packet := getPacketOfType(protocol.PacketTypeInitial, 456)
+				zeroRTTPacket := getPacketOfType(protocol.PacketType0RTT, 123)
+				gomock.InOrder(
+					unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, _ time.Time, data []byte) (*unpackedPacket, error) {
+						Expect(hdr.Type).To(Equal(protocol.PacketTypeInitial))
+						return nil, handshake.ErrDecryptionFailed
+					}),
+					unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, _ time.Time, data []byte) (*unpackedPacket, error) {
+						Expect(hdr.Type).To(Equal(protocol.PacketType0RTT))
+						Expect(data).To(Equal(zeroRTTPacket.data))
+						return &unpackedPacket{
+							encryptionLevel: protocol.Encryption0RTT,
+							data:            []byte{0},
+						}, nil
+					}),
+				)
+				packet.data = append(packet.data, zeroRTTPacket.data...)
+				Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+			})
+
 			It("ignores coalesced packet parts if the destination connection IDs don't match", func() {
 				wrongConnID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
 				Expect(srcConnID).ToNot(Equal(wrongConnID))
@@ -889,6 +1363,33 @@ var _ = Describe("Session", func() {
 			sent, err := sess.sendPacket()
 			Expect(err).NotTo(HaveOccurred())
 			Expect(sent).To(BeTrue())
+			stats := sess.CoalescingStats()
+			Expect(stats.DatagramsSent).To(BeEquivalentTo(1))
+			Expect(stats.PacketsSent).To(BeEquivalentTo(1))
+			Expect(stats.CoalescedDatagramsSent).To(BeZero())
+			Expect(stats.PacketsPerDatagram()).To(Equal(1.0))
+			Expect(stats.AverageCoalescedDatagramSize()).To(BeZero())
+		})
+
+		It("records the send time of every packet", func() {
+			recorder := &packetTimestampRecorder{}
+			sess.config.PacketTimestampTracer = recorder
+			sess.handshakeConfirmed = true
+			for pn := protocol.PacketNumber(1); pn <= 5; pn++ {
+				packer.EXPECT().PackPacket().Return(getPacket(pn), nil)
+				mconn.EXPECT().Write(gomock.Any())
+				sent, err := sess.sendPacket()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sent).To(BeTrue())
+			}
+			Expect(recorder.sentPNs).To(Equal([]protocol.PacketNumber{1, 2, 3, 4, 5}))
+			Expect(recorder.sentTimes).To(HaveLen(5))
+			for i, t := range recorder.sentTimes {
+				Expect(t).ToNot(BeZero())
+				if i > 0 {
+					Expect(t).ToNot(BeTemporally("<", recorder.sentTimes[i-1]))
+				}
+			}
 		})
 
 		It("doesn't send packets if there's nothing to send", func() {
@@ -912,6 +1413,8 @@ var _ = Describe("Session", func() {
 
 		It("adds a BLOCKED frame when it is connection-level flow control blocked", func() {
 			sess.handshakeConfirmed = true
+			sess.peerParams = &handshake.TransportParameters{}
+			sess.framer.AddActiveStream(3)
 			fc := mocks.NewMockConnectionFlowController(mockCtrl)
 			fc.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(1337))
 			packer.EXPECT().PackPacket().Return(getPacket(1), nil)
@@ -924,6 +1427,76 @@ var _ = Describe("Session", func() {
 			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.DataBlockedFrame{DataLimit: 1337}}}))
 		})
 
+		It("adds a BLOCKED frame at the blocking offset, once", func() {
+			sess.handshakeConfirmed = true
+			sess.peerParams = &handshake.TransportParameters{}
+			sess.framer.AddActiveStream(3)
+			fc := flowcontrol.NewConnectionFlowController(100, 100, 0, false, nil, nil, utils.DefaultClock{}, utils.DefaultLogger)
+			fc.UpdateSendWindow(1000)
+			fc.AddBytesSent(1000)
+			sess.connFlowController = fc
+			packer.EXPECT().PackPacket().Return(getPacket(1), nil)
+			mconn.EXPECT().Write(gomock.Any())
+			_, err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.DataBlockedFrame{DataLimit: 1000}}}))
+			// don't queue another BLOCKED frame for the same offset
+			packer.EXPECT().PackPacket().Return(getPacket(2), nil)
+			mconn.EXPECT().Write(gomock.Any())
+			_, err = sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			frames, _ = sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(BeEmpty())
+		})
+
+		It("adds a BLOCKED frame if the peer's initial_max_data is 0, until it receives a MAX_DATA frame", func() {
+			sess.handshakeConfirmed = true
+			sess.peerParams = &handshake.TransportParameters{InitialMaxData: 0}
+			sess.framer.AddActiveStream(3)
+			sess.connFlowController = flowcontrol.NewConnectionFlowController(100, 100, 0, false, nil, nil, utils.DefaultClock{}, utils.DefaultLogger)
+			Expect(sess.connFlowController.SendWindowSize()).To(BeZero())
+			packer.EXPECT().PackPacket().Return(getPacket(1), nil)
+			mconn.EXPECT().Write(gomock.Any())
+			_, err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.DataBlockedFrame{DataLimit: 0}}}))
+			// the peer grants flow control credit
+			Expect(sess.handleFrame(&wire.MaxDataFrame{ByteOffset: 1000}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(sess.connFlowController.SendWindowSize()).To(Equal(protocol.ByteCount(1000)))
+			packer.EXPECT().PackPacket().Return(getPacket(2), nil)
+			mconn.EXPECT().Write(gomock.Any())
+			_, err = sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			frames, _ = sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(BeEmpty())
+		})
+
+		It("doesn't add a BLOCKED frame before receiving the peer's transport parameters", func() {
+			sess.handshakeConfirmed = true
+			sess.framer.AddActiveStream(3)
+			sess.connFlowController = flowcontrol.NewConnectionFlowController(100, 100, 0, false, nil, nil, utils.DefaultClock{}, utils.DefaultLogger)
+			packer.EXPECT().PackPacket().Return(getPacket(1), nil)
+			mconn.EXPECT().Write(gomock.Any())
+			_, err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(BeEmpty())
+		})
+
+		It("doesn't add a BLOCKED frame if there's no stream data waiting to be sent", func() {
+			sess.handshakeConfirmed = true
+			sess.peerParams = &handshake.TransportParameters{InitialMaxData: 0}
+			sess.connFlowController = flowcontrol.NewConnectionFlowController(100, 100, 0, false, nil, nil, utils.DefaultClock{}, utils.DefaultLogger)
+			packer.EXPECT().PackPacket().Return(getPacket(1), nil)
+			mconn.EXPECT().Write(gomock.Any())
+			_, err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(BeEmpty())
+		})
+
 		It("doesn't send when the SentPacketHandler doesn't allow it", func() {
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
@@ -1114,6 +1687,89 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	Context("using a mock clock", func() {
+		var clock *testutils.MockClock
+
+		getAckElicitingPacket := func(pn protocol.PacketNumber) *packedPacket {
+			p := getPacket(pn)
+			p.frames = []ackhandler.Frame{{Frame: &wire.PingFrame{}}}
+			p.length = 1000
+			return p
+		}
+
+		BeforeEach(func() {
+			clock = testutils.NewMockClock(time.Now())
+			sess.clock = clock
+			sess.timer = utils.NewTimerWithClock(clock)
+			sess.sentPacketHandler, sess.receivedPacketHandler = ackhandler.NewAckHandler(
+				0,
+				sess.rttStats,
+				protocol.PerspectiveServer,
+				sess.config.MaxProbeTimeout,
+				sess.config.PacketReorderingThreshold,
+				false,
+				false,
+				sess.config.MaxAckRanges,
+				nil,
+				nil,
+				clock,
+				utils.DefaultLogger,
+				protocol.VersionTLS,
+			)
+			sess.sentPacketHandler.SetHandshakeComplete()
+			sess.handshakeConfirmed = true
+		})
+
+		AfterEach(func() {
+			// make the go routine return
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			sess.shutdown()
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+
+		It("sends probe packets when the PTO expires, with exponential backoff", func() {
+			sent := make(chan time.Time, 100)
+			mconn.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				sent <- clock.Now()
+				return len(p), nil
+			}).AnyTimes()
+			packer.EXPECT().PackPacket().Return(getAckElicitingPacket(1), nil)
+			packer.EXPECT().PackPacket().AnyTimes()
+			pn := protocol.PacketNumber(1)
+			packer.EXPECT().MaybePackProbePacket(protocol.Encryption1RTT).DoAndReturn(func(protocol.EncryptionLevel) (*packedPacket, error) {
+				pn++
+				return getAckElicitingPacket(pn), nil
+			}).AnyTimes()
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			sess.scheduleSending()
+			var lastSent time.Time
+			Eventually(sent).Should(Receive(&lastSent))
+			Expect(lastSent).To(Equal(clock.Now()))
+
+			pto := sess.rttStats.PTO(true)
+			for i := 0; i < 4; i++ {
+				deadline := lastSent.Add(pto << i)
+				clock.Advance(deadline.Sub(clock.Now()) - time.Nanosecond)
+				Consistently(sent, 50*time.Millisecond).ShouldNot(Receive())
+				clock.Advance(time.Nanosecond)
+				// two probe packets are sent when the PTO expires
+				for j := 0; j < 2; j++ {
+					Eventually(sent).Should(Receive(&lastSent))
+					Expect(lastSent).To(Equal(deadline))
+				}
+				Consistently(sent, 50*time.Millisecond).ShouldNot(Receive())
+			}
+			Expect(pn).To(Equal(protocol.PacketNumber(9)))
+		})
+	})
+
 	Context("scheduling sending", func() {
 		BeforeEach(func() {
 			sess.handshakeConfirmed = true
@@ -1181,6 +1837,36 @@ var _ = Describe("Session", func() {
 			}()
 			Eventually(written).Should(BeClosed())
 		})
+
+		It("sends an ACK right away when ACKs are flushed", func() {
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().ShouldSendNumPackets().AnyTimes().Return(1)
+			sph.EXPECT().SentPacket(gomock.Any())
+			sess.sentPacketHandler = sph
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			// the ACK timer only fires in an hour
+			rph.EXPECT().GetAlarmTimeout().Return(time.Now().Add(time.Hour)).AnyTimes()
+			sess.receivedPacketHandler = rph
+
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			// don't EXPECT any calls to mconn.Write()
+			time.Sleep(50 * time.Millisecond)
+			written := make(chan struct{})
+			gomock.InOrder(
+				rph.EXPECT().FlushAcks(),
+				packer.EXPECT().PackPacket().Return(getPacket(1), nil),
+				mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(written) }),
+			)
+			sess.FlushACKs()
+			Eventually(written).Should(BeClosed())
+		})
 	})
 
 	It("sends coalesced packets before the handshake is confirmed", func() {
@@ -1241,6 +1927,15 @@ var _ = Describe("Session", func() {
 
 		sess.scheduleSending()
 		Eventually(sent).Should(BeClosed())
+		stats := sess.CoalescingStats()
+		Expect(stats).To(Equal(CoalescingStats{
+			DatagramsSent:          1,
+			PacketsSent:            2,
+			CoalescedDatagramsSent: 1,
+			CoalescedBytesSent:     6,
+		}))
+		Expect(stats.PacketsPerDatagram()).To(Equal(2.0))
+		Expect(stats.AverageCoalescedDatagramSize()).To(Equal(6.0))
 
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -1332,6 +2027,23 @@ var _ = Describe("Session", func() {
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	It("uses the GenerateToken callback to generate the token sent in the NEW_TOKEN frame", func() {
+		remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+		sess.config.GenerateToken = func(addr net.Addr, isRetry bool, origDestConnID []byte) ([]byte, error) {
+			Expect(addr).To(Equal(remoteAddr))
+			Expect(isRetry).To(BeFalse())
+			Expect(origDestConnID).To(BeNil())
+			return []byte("custom token"), nil
+		}
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().GetSessionTicket()
+		cryptoSetup.EXPECT().DropHandshakeKeys()
+		mconn.EXPECT().RemoteAddr().Return(remoteAddr)
+		sess.handleHandshakeComplete()
+		frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+		Expect(frames).To(ContainElement(ackhandler.Frame{Frame: &wire.NewTokenFrame{Token: []byte("custom token")}}))
+	})
+
 	It("doesn't cancel the HandshakeComplete context when the handshake fails", func() {
 		packer.EXPECT().PackCoalescedPacket().AnyTimes()
 		streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -1535,6 +2247,86 @@ var _ = Describe("Session", func() {
 			// don't EXPECT() any calls to mconn.Write()
 			time.Sleep(50 * time.Millisecond)
 		})
+
+		Context("keeping the NAT binding alive", func() {
+			var natKeepAlivePeriod time.Duration
+
+			BeforeEach(func() {
+				natKeepAlivePeriod = scaleDuration(50 * time.Millisecond)
+				sess.config.KeepAlive = false
+				sess.config.NATKeepAlivePeriod = natKeepAlivePeriod
+			})
+
+			It("sends a PING at the NAT keep-alive period on an otherwise idle connection", func() {
+				setRemoteIdleTimeout(30 * time.Second)
+				start := time.Now()
+				sess.lastPacketReceivedTime = start
+				sess.lastPacketSentTime = start
+				sent := make(chan struct{})
+				packer.EXPECT().PackCoalescedPacket().Do(func() (*packedPacket, error) {
+					frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+					Expect(frames).To(HaveLen(1))
+					Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
+					close(sent)
+					return nil, nil
+				})
+				runSession()
+				Eventually(sent).Should(BeClosed())
+				Expect(time.Since(start)).To(BeNumerically(">=", natKeepAlivePeriod))
+			})
+
+			It("doesn't send a PING if a packet was sent recently", func() {
+				setRemoteIdleTimeout(30 * time.Second)
+				sess.lastPacketReceivedTime = time.Now()
+				sess.lastPacketSentTime = time.Now().Add(time.Hour)
+				runSession()
+				// don't EXPECT() any calls to packer.PackCoalescedPacket()
+				time.Sleep(2 * natKeepAlivePeriod)
+			})
+		})
+
+		Context("health checks", func() {
+			var healthCheckInterval time.Duration
+
+			BeforeEach(func() {
+				healthCheckInterval = scaleDuration(50 * time.Millisecond)
+				sess.config.KeepAlive = false
+				sess.config.HealthCheckInterval = healthCheckInterval
+				sess.rttStats.UpdateRTT(5*time.Millisecond, 0, time.Now())
+			})
+
+			It("sends a PING and marks the path as unhealthy if it is not acknowledged", func() {
+				setRemoteIdleTimeout(30 * time.Second)
+				start := time.Now()
+				sess.lastPacketReceivedTime = start
+				sent := make(chan struct{})
+				packer.EXPECT().PackCoalescedPacket().Do(func() (*packedPacket, error) {
+					frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+					Expect(frames).To(HaveLen(1))
+					Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
+					close(sent)
+					return nil, nil
+				})
+				packer.EXPECT().PackCoalescedPacket().AnyTimes()
+				runSession()
+				Eventually(sent).Should(BeClosed())
+				Expect(time.Since(start)).To(BeNumerically(">=", healthCheckInterval))
+				Expect(sess.PathUnhealthy()).To(BeFalse())
+				// the PING is never acknowledged, as if the path was black-holed
+				Eventually(sess.PathUnhealthy).Should(BeTrue())
+				Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			})
+
+			It("doesn't send a PING if health checks are disabled", func() {
+				setRemoteIdleTimeout(30 * time.Second)
+				sess.config.HealthCheckInterval = 0
+				sess.lastPacketReceivedTime = time.Now().Add(-10 * time.Second)
+				runSession()
+				// don't EXPECT() any calls to packer.PackCoalescedPacket()
+				time.Sleep(2 * healthCheckInterval)
+				Expect(sess.PathUnhealthy()).To(BeFalse())
+			})
+		})
 	})
 
 	Context("timeouts", func() {
@@ -1579,6 +2371,104 @@ var _ = Describe("Session", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("times out due to no network activity during the handshake", func() {
+			sess.handshakeComplete = false
+			sess.config.HandshakeIdleTimeout = time.Second
+			sess.lastPacketReceivedTime = time.Now().Add(-2 * time.Second)
+			sessionRunner.EXPECT().Remove(gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				nerr, ok := err.(net.Error)
+				Expect(ok).To(BeTrue())
+				Expect(nerr.Timeout()).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("No recent network activity during the handshake"))
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("does not use the handshake idle timeout after the handshake completes", func() {
+			sess.handshakeComplete = true
+			sess.config.HandshakeIdleTimeout = time.Millisecond
+			sess.idleTimeout = time.Hour
+			sess.lastPacketReceivedTime = time.Now().Add(-time.Minute)
+			packer.EXPECT().PackCoalescedPacket().AnyTimes()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(quicErr *qerr.QuicError) (*coalescedPacket, error) {
+				Expect(quicErr.ErrorCode).To(Equal(qerr.NoError))
+				return &coalescedPacket{buffer: getPacketBuffer()}, nil
+			})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			Consistently(sess.Context().Done()).ShouldNot(BeClosed())
+			// make the go routine return
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any())
+			sess.shutdown()
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+
+		It("closes the session when the maximum connection lifetime is exceeded", func() {
+			sess.handshakeComplete = true
+			sess.idleTimeout = time.Hour
+			sess.config.MaxConnectionLifetime = time.Minute
+			sess.sessionCreationTime = time.Now().Add(-time.Minute)
+			streamManager.EXPECT().SendQueueDepth().Return(SendQueueDepth{}).AnyTimes()
+			packer.EXPECT().PackCoalescedPacket().AnyTimes()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(quicErr *qerr.QuicError) (*coalescedPacket, error) {
+				Expect(quicErr.ErrorCode).To(Equal(qerr.NoError))
+				Expect(quicErr.ErrorMessage).To(Equal("maximum connection lifetime exceeded"))
+				return &coalescedPacket{buffer: getPacketBuffer()}, nil
+			})
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				Expect(err).To(MatchError("NO_ERROR: maximum connection lifetime exceeded"))
+				nerr, ok := err.(net.Error)
+				Expect(ok).To(BeTrue())
+				Expect(nerr.Timeout()).To(BeFalse())
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("gives streams a brief window to complete when the maximum connection lifetime is exceeded", func() {
+			sess.handshakeComplete = true
+			sess.idleTimeout = time.Hour
+			sess.config.MaxConnectionLifetime = time.Minute
+			sess.sessionCreationTime = time.Now().Add(-time.Minute)
+			sess.rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+			gracePeriod := 3 * sess.rttStats.PTO(true)
+			streamManager.EXPECT().SendQueueDepth().Return(SendQueueDepth{Unacknowledged: 100}).AnyTimes()
+			packer.EXPECT().PackCoalescedPacket().AnyTimes()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				Expect(err).To(MatchError("NO_ERROR: maximum connection lifetime exceeded"))
+				close(done)
+			}()
+			Consistently(done, gracePeriod/2).ShouldNot(BeClosed())
+			Eventually(done, 2*gracePeriod).Should(BeClosed())
+		})
+
 		It("does not use the idle timeout before the handshake complete", func() {
 			sess.handshakeComplete = false
 			sess.config.MaxIdleTimeout = 9999 * time.Second
@@ -1630,6 +2520,38 @@ var _ = Describe("Session", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("sends a CONNECTION_CLOSE when the idle timeout expires, if configured", func() {
+			sess.config.SendCloseOnIdleTimeout = true
+			packer.EXPECT().PackCoalescedPacket().AnyTimes()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(quicErr *qerr.QuicError) (*coalescedPacket, error) {
+				Expect(quicErr.IsApplicationError()).To(BeFalse())
+				Expect(quicErr.ErrorCode).To(Equal(qerr.NoError))
+				Expect(quicErr.ErrorMessage).To(Equal("No recent network activity"))
+				return &coalescedPacket{buffer: getPacketBuffer()}, nil
+			})
+			mconn.EXPECT().Write(gomock.Any())
+			sessionRunner.EXPECT().Retire(clientDestConnID)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			sess.idleTimeout = 0
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().GetSessionTicket().MaxTimes(1)
+				cryptoSetup.EXPECT().DropHandshakeKeys().MaxTimes(1)
+				mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{})
+				close(sess.handshakeCompleteChan)
+				err := sess.run()
+				nerr, ok := err.(net.Error)
+				Expect(ok).To(BeTrue())
+				Expect(nerr.Timeout()).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("No recent network activity"))
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("doesn't time out when it just sent a packet", func() {
 			sess.lastPacketReceivedTime = time.Now().Add(-time.Hour)
 			sess.firstAckElicitingPacketAfterIdleSentTime = time.Now().Add(-time.Second)
@@ -1675,6 +2597,24 @@ var _ = Describe("Session", func() {
 			Expect(str).To(Equal(mstr))
 		})
 
+		It("opens streams with a reserved flow control window", func() {
+			mstr := NewMockStreamI(mockCtrl)
+			streamManager.EXPECT().OpenStream().Return(mstr, nil)
+			mstr.EXPECT().reserveSendWindow(protocol.ByteCount(1337)).Return(true)
+			str, err := sess.OpenStreamWithWindow(1337)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(mstr))
+		})
+
+		It("returns the stream if the flow control window can't be reserved", func() {
+			mstr := NewMockStreamI(mockCtrl)
+			streamManager.EXPECT().OpenStream().Return(mstr, nil)
+			mstr.EXPECT().reserveSendWindow(protocol.ByteCount(1337)).Return(false)
+			str, err := sess.OpenStreamWithWindow(1337)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(mstr))
+		})
+
 		It("opens unidirectional streams", func() {
 			mstr := NewMockSendStreamI(mockCtrl)
 			streamManager.EXPECT().OpenUniStream().Return(mstr, nil)
@@ -1723,6 +2663,88 @@ var _ = Describe("Session", func() {
 		mconn.EXPECT().RemoteAddr().Return(addr)
 		Expect(sess.RemoteAddr()).To(Equal(addr))
 	})
+
+	It("sends the stateless_reset_token", func() {
+		conn := NewMockConnection(mockCtrl)
+		conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}).AnyTimes()
+		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{}).AnyTimes()
+		tokenGenerator, err := handshake.NewTokenGenerator()
+		Expect(err).ToNot(HaveOccurred())
+		recorder := &transportParameterRecorder{}
+		newSession(
+			conn,
+			sessionRunner,
+			nil,
+			clientDestConnID,
+			destConnID,
+			srcConnID,
+			[16]byte{0xde, 0xca, 0xfb, 0xad},
+			populateServerConfig(&Config{}),
+			nil, // tls.Config
+			tokenGenerator,
+			false,
+			recorder,
+			utils.DefaultLogger,
+			protocol.VersionTLS,
+		)
+		Expect(recorder.sent).ToNot(BeNil())
+		Expect(recorder.sent.StatelessResetToken).To(Equal(&[16]byte{0xde, 0xca, 0xfb, 0xad}))
+	})
+
+	It("raises the limits when accepting 0-RTT with decreased transport parameters", func() {
+		conn := NewMockConnection(mockCtrl)
+		conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}).AnyTimes()
+		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{}).AnyTimes()
+		tokenGenerator, err := handshake.NewTokenGenerator()
+		Expect(err).ToNot(HaveOccurred())
+		var cachedInitialMaxData, currentInitialMaxData uint64
+		s := newSession(
+			conn,
+			sessionRunner,
+			nil,
+			clientDestConnID,
+			destConnID,
+			srcConnID,
+			[16]byte{},
+			populateServerConfig(&Config{
+				MaxIncomingUniStreams: 1,
+				Accept0RTTTransportParameters: func(cached, current *ZeroRTTTransportParameters) bool {
+					cachedInitialMaxData = cached.InitialMaxData
+					currentInitialMaxData = current.InitialMaxData
+					return true
+				},
+			}),
+			nil, // tls.Config
+			tokenGenerator,
+			false,
+			nil,
+			utils.DefaultLogger,
+			protocol.VersionTLS,
+		).(*session)
+		current := &handshake.TransportParameters{
+			InitialMaxStreamDataUni: protocol.InitialMaxStreamData,
+			InitialMaxData:          protocol.InitialMaxData,
+			MaxUniStreamNum:         1,
+		}
+		cached := &handshake.TransportParameters{
+			InitialMaxStreamDataUni: 2 * protocol.InitialMaxStreamData,
+			InitialMaxData:          4 * protocol.InitialMaxData,
+			MaxUniStreamNum:         3,
+		}
+		Expect(s.validFor0RTT(cached, current)).To(BeTrue())
+		Expect(cachedInitialMaxData).To(BeEquivalentTo(4 * protocol.InitialMaxData))
+		Expect(currentInitialMaxData).To(BeEquivalentTo(protocol.InitialMaxData))
+		// The client sends more data, on more streams, than allowed by the current parameters.
+		for _, id := range []protocol.StreamID{2, 6, 10} {
+			Expect(s.handleFrame(&wire.StreamFrame{
+				StreamID: id,
+				Offset:   protocol.InitialMaxStreamData,
+				Data:     []byte("foobar"),
+			}, protocol.Encryption0RTT, protocol.ConnectionID{})).To(Succeed())
+		}
+		_, highestReceived, _ := s.connFlowController.ReceiveOffsets()
+		Expect(highestReceived).To(BeNumerically(">", protocol.InitialMaxData))
+	})
 })
 
 var _ = Describe("Client Session", func() {
@@ -1856,6 +2878,59 @@ var _ = Describe("Client Session", func() {
 		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
 	})
 
+	Context("sending ACK_FREQUENCY frames", func() {
+		BeforeEach(func() {
+			quicConf.AckFrequencyPacketTolerance = 10
+		})
+
+		It("sends an ACK_FREQUENCY frame when the handshake completes, if the server supports it", func() {
+			sess.peerParams = &handshake.TransportParameters{
+				MaxAckDelay: 30 * time.Millisecond,
+				MinAckDelay: time.Millisecond,
+			}
+			sess.handleHandshakeComplete()
+			frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(&wire.AckFrequencyFrame{
+				PacketTolerance:   10,
+				UpdateMaxAckDelay: 30 * time.Millisecond,
+			}))
+		})
+
+		It("doesn't send an ACK_FREQUENCY frame, if the server doesn't support it", func() {
+			sess.peerParams = &handshake.TransportParameters{MaxAckDelay: 30 * time.Millisecond}
+			sess.handleHandshakeComplete()
+			frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			Expect(frames).To(BeEmpty())
+		})
+	})
+
+	It("records the progress of the handshake", func() {
+		recorder := &handshakeEventRecorder{}
+		sess.qlogger = recorder
+		initialHdr := wire.Header{
+			IsLongHeader:     true,
+			Type:             protocol.PacketTypeInitial,
+			DestConnectionID: srcConnID,
+			SrcConnectionID:  destConnID,
+		}
+		sess.logPacketContents(time.Now(), &packetContents{header: &wire.ExtendedHeader{Header: initialHdr}})
+		Expect(sess.handleUnpackedPacket(&unpackedPacket{
+			hdr:             &wire.ExtendedHeader{Header: initialHdr},
+			encryptionLevel: protocol.EncryptionInitial,
+			data:            []byte{0x1}, // one PING frame
+		}, time.Now())).To(Succeed())
+		sess.handleHandshakeComplete()
+		cryptoSetup.EXPECT().DropHandshakeKeys()
+		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
+		Expect(recorder.events).To(Equal([]qlog.HandshakeEvent{
+			qlog.HandshakeEventInitialSent,
+			qlog.HandshakeEventInitialReceived,
+			qlog.HandshakeEventComplete,
+			qlog.HandshakeEventDoneReceived,
+		}))
+	})
+
 	Context("handling tokens", func() {
 		var mockTokenStore *MockTokenStore
 
@@ -1977,6 +3052,45 @@ var _ = Describe("Client Session", func() {
 			expectClose()
 		})
 
+		It("recognizes stateless resets using the preferred_address stateless reset token", func() {
+			token := [16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+			params := &handshake.TransportParameters{
+				PreferredAddress: &handshake.PreferredAddress{
+					IPv4:                net.IPv4(127, 0, 0, 1),
+					IPv6:                net.IP{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+					ConnectionID:        protocol.ConnectionID{1, 2, 3, 4},
+					StatelessResetToken: token,
+				},
+			}
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			packer.EXPECT().PackCoalescedPacket().MaxTimes(1)
+			sess.processTransportParameters(params)
+
+			phm := newPacketHandlerMap(newMockPacketConn(), 4, nil, utils.DefaultLogger).(*packetHandlerMap)
+			defer func() {
+				phm.Destroy()
+				Eventually(phm.listening).Should(BeClosed())
+			}()
+			statelessReset := append([]byte{0x40} /* short header packet */, make([]byte, 50)...)
+			statelessReset = append(statelessReset, token[:]...)
+
+			// The token is not used before switching to the preferred_address connection ID.
+			phm.handlePacket(&net.UDPAddr{}, getPacketBuffer(), statelessReset)
+			Consistently(errChan, 50*time.Millisecond).ShouldNot(Receive())
+
+			sessionRunner.EXPECT().AddResetToken(token, sess).Do(func(t [16]byte, h packetHandler) {
+				phm.AddResetToken(t, h)
+			})
+			Expect(sess.connIDManager.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+
+			sessionRunner.EXPECT().RemoveResetToken(token)
+			sessionRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+			cryptoSetup.EXPECT().Close()
+			phm.handlePacket(&net.UDPAddr{}, getPacketBuffer(), statelessReset)
+			Eventually(errChan).Should(Receive(MatchError("received a stateless reset")))
+			closed = true
+		})
+
 		It("uses the minimum of the peers' idle timeouts", func() {
 			sess.config.MaxIdleTimeout = 19 * time.Second
 			params := &handshake.TransportParameters{
@@ -1987,6 +3101,45 @@ var _ = Describe("Client Session", func() {
 			Expect(sess.idleTimeout).To(Equal(18 * time.Second))
 		})
 
+		It("enables RESET_STREAM_AT, if the peer supports it", func() {
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			Expect(sess.supportsResetStreamAt()).To(BeFalse())
+			sess.processTransportParameters(&handshake.TransportParameters{EnableResetStreamAt: true})
+			Expect(sess.supportsResetStreamAt()).To(BeTrue())
+			b := &bytes.Buffer{}
+			f := &wire.ResetStreamFrame{StreamID: 4, ByteOffset: 100, ReliableSize: 10}
+			Expect(f.Write(b, sess.version)).To(Succeed())
+			frame, err := sess.frameParser.ParseNext(bytes.NewReader(b.Bytes()), protocol.Encryption1RTT)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(f))
+		})
+
+		It("doesn't enable RESET_STREAM_AT, if the peer doesn't support it", func() {
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sess.processTransportParameters(&handshake.TransportParameters{})
+			Expect(sess.supportsResetStreamAt()).To(BeFalse())
+			b := &bytes.Buffer{}
+			Expect((&wire.ResetStreamFrame{StreamID: 4, ByteOffset: 100, ReliableSize: 10}).Write(b, sess.version)).To(Succeed())
+			_, err := sess.frameParser.ParseNext(bytes.NewReader(b.Bytes()), protocol.Encryption1RTT)
+			Expect(err).To(MatchError(ContainSubstring("RESET_STREAM_AT frame received, but the extension was not negotiated")))
+		})
+
+		It("accepts an idle timeout that is not smaller than the minimum idle timeout", func() {
+			sess.config.MaxIdleTimeout = 30 * time.Second
+			sess.config.MinIdleTimeout = 10 * time.Second
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sess.processTransportParameters(&handshake.TransportParameters{MaxIdleTimeout: 10 * time.Second})
+			Expect(sess.idleTimeout).To(Equal(10 * time.Second))
+		})
+
+		It("closes the connection if the negotiated idle timeout is smaller than the minimum idle timeout", func() {
+			sess.config.MaxIdleTimeout = 30 * time.Second
+			sess.config.MinIdleTimeout = 10 * time.Second
+			expectClose()
+			sess.processTransportParameters(&handshake.TransportParameters{MaxIdleTimeout: 5 * time.Second})
+			Eventually(errChan).Should(Receive(MatchError("SERVER_BUSY: negotiated idle timeout (5s) is smaller than the minimum idle timeout (10s)")))
+		})
+
 		It("errors if the TransportParameters contain an original_connection_id, although no Retry was performed", func() {
 			expectClose()
 			sess.processTransportParameters(&handshake.TransportParameters{
@@ -0,0 +1,68 @@
+package quic
+
+import (
+	"crypto/rand"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// randomlyDisableSpinBit decides if the spin bit is disabled for a connection,
+// even though it wasn't disabled in the config.
+// The spin bit is disabled for 1 in 16 connections, see section 17.3.1 of draft-ietf-quic-transport.
+// This makes sure that connections that disable the spin bit don't stand out.
+// It is declared as a variable, such that it can be mocked in the tests.
+var randomlyDisableSpinBit = func() bool {
+	b := make([]byte, 1)
+	rand.Read(b) // ignore the error here
+	return b[0]&0xf == 0
+}
+
+// The spinBitManager determines the value of the latency spin bit.
+// The server reflects the spin bit of the packet with the highest packet number received from the client,
+// the client inverts the spin bit of the packet with the highest packet number received from the server.
+// This causes the spin bit to flip once per round trip, allowing on-path observers to measure the RTT.
+// If the spin bit is disabled, it is set to a random value, which is then used for the lifetime of the connection.
+type spinBitManager struct {
+	perspective protocol.Perspective
+
+	disabled bool
+	value    bool
+
+	largestRcvdPacketNumber protocol.PacketNumber
+}
+
+func newSpinBitManager(disable bool, pers protocol.Perspective) *spinBitManager {
+	m := &spinBitManager{
+		perspective:             pers,
+		disabled:                disable || randomlyDisableSpinBit(),
+		largestRcvdPacketNumber: protocol.InvalidPacketNumber,
+	}
+	if m.disabled {
+		b := make([]byte, 1)
+		rand.Read(b) // ignore the error here
+		m.value = b[0]&0x1 > 0
+	}
+	return m
+}
+
+// ReceivedPacket must be called for every 1-RTT packet received.
+func (m *spinBitManager) ReceivedPacket(pn protocol.PacketNumber, spinBit bool) {
+	if m.disabled {
+		return
+	}
+	// only packets with a higher packet number than all previously received packets change the spin bit
+	if m.largestRcvdPacketNumber != protocol.InvalidPacketNumber && pn <= m.largestRcvdPacketNumber {
+		return
+	}
+	m.largestRcvdPacketNumber = pn
+	if m.perspective == protocol.PerspectiveClient {
+		m.value = !spinBit
+	} else {
+		m.value = spinBit
+	}
+}
+
+// SpinBit returns the value of the spin bit that is used for the next 1-RTT packet.
+func (m *spinBitManager) SpinBit() bool {
+	return m.value
+}
@@ -0,0 +1,110 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spin Bit Manager", func() {
+	var origRandomlyDisableSpinBit func() bool
+
+	BeforeEach(func() {
+		origRandomlyDisableSpinBit = randomlyDisableSpinBit
+		randomlyDisableSpinBit = func() bool { return false }
+	})
+
+	AfterEach(func() {
+		randomlyDisableSpinBit = origRandomlyDisableSpinBit
+	})
+
+	It("flips the spin bit once per RTT", func() {
+		client := newSpinBitManager(false, protocol.PerspectiveClient)
+		server := newSpinBitManager(false, protocol.PerspectiveServer)
+		Expect(client.SpinBit()).To(BeFalse())
+		Expect(server.SpinBit()).To(BeFalse())
+
+		var clientPN, serverPN protocol.PacketNumber
+		var values []bool
+		for i := 0; i < 6; i++ {
+			// the client sends a packet, and the server receives it...
+			values = append(values, client.SpinBit())
+			server.ReceivedPacket(clientPN, client.SpinBit())
+			clientPN++
+			// ... and the server responds
+			client.ReceivedPacket(serverPN, server.SpinBit())
+			serverPN++
+		}
+		Expect(values).To(Equal([]bool{false, true, false, true, false, true}))
+	})
+
+	It("doesn't flip the spin bit when packets are sent within the same RTT", func() {
+		client := newSpinBitManager(false, protocol.PerspectiveClient)
+		server := newSpinBitManager(false, protocol.PerspectiveServer)
+		for pn := protocol.PacketNumber(0); pn < 3; pn++ {
+			server.ReceivedPacket(pn, client.SpinBit())
+		}
+		Expect(server.SpinBit()).To(BeFalse())
+		client.ReceivedPacket(0, server.SpinBit())
+		Expect(client.SpinBit()).To(BeTrue())
+		for pn := protocol.PacketNumber(3); pn < 6; pn++ {
+			server.ReceivedPacket(pn, client.SpinBit())
+		}
+		Expect(server.SpinBit()).To(BeTrue())
+	})
+
+	It("only uses the spin bit of the packet with the highest packet number", func() {
+		server := newSpinBitManager(false, protocol.PerspectiveServer)
+		server.ReceivedPacket(10, true)
+		Expect(server.SpinBit()).To(BeTrue())
+		// reordered packet
+		server.ReceivedPacket(9, false)
+		Expect(server.SpinBit()).To(BeTrue())
+		// duplicate packet
+		server.ReceivedPacket(10, false)
+		Expect(server.SpinBit()).To(BeTrue())
+		server.ReceivedPacket(11, false)
+		Expect(server.SpinBit()).To(BeFalse())
+	})
+
+	It("uses a fixed value when the spin bit is disabled", func() {
+		for i := 0; i < 10; i++ {
+			client := newSpinBitManager(true, protocol.PerspectiveClient)
+			value := client.SpinBit()
+			for pn := protocol.PacketNumber(0); pn < 10; pn++ {
+				client.ReceivedPacket(pn, pn%2 == 0)
+				Expect(client.SpinBit()).To(Equal(value))
+			}
+		}
+	})
+
+	It("chooses a random value when the spin bit is disabled", func() {
+		var numTrue int
+		for i := 0; i < 200; i++ {
+			if newSpinBitManager(true, protocol.PerspectiveServer).SpinBit() {
+				numTrue++
+			}
+		}
+		Expect(numTrue).To(And(BeNumerically(">", 50), BeNumerically("<", 150)))
+	})
+
+	It("randomly disables the spin bit", func() {
+		randomlyDisableSpinBit = func() bool { return true }
+		server := newSpinBitManager(false, protocol.PerspectiveServer)
+		Expect(server.disabled).To(BeTrue())
+		value := server.SpinBit()
+		server.ReceivedPacket(0, !value)
+		Expect(server.SpinBit()).To(Equal(value))
+	})
+
+	It("disables the spin bit for 1 in 16 connections", func() {
+		var numDisabled int
+		for i := 0; i < 16000; i++ {
+			if origRandomlyDisableSpinBit() {
+				numDisabled++
+			}
+		}
+		Expect(numDisabled).To(BeNumerically("~", 1000, 200))
+	})
+})
@@ -15,6 +15,8 @@ import (
 type streamSender interface {
 	queueControlFrame(wire.Frame)
 	onHasStreamData(protocol.StreamID)
+	// supportsResetStreamAt says if RESET_STREAM_AT frames can be sent
+	supportsResetStreamAt() bool
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
 }
@@ -52,6 +54,7 @@ type streamI interface {
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
+	reserveSendWindow(protocol.ByteCount) bool
 }
 
 var _ receiveStreamI = (streamI)(nil)
@@ -141,6 +144,15 @@ func (s *stream) SetDeadline(t time.Time) error {
 	return nil
 }
 
+func (s *stream) FlowControlOffsets() FlowControlOffsets {
+	offsets := s.sendStream.FlowControlOffsets()
+	receiveOffsets := s.receiveStream.FlowControlOffsets()
+	offsets.BytesRead = receiveOffsets.BytesRead
+	offsets.HighestReceived = receiveOffsets.HighestReceived
+	offsets.ReceiveWindow = receiveOffsets.ReceiveWindow
+	return offsets
+}
+
 // CloseForShutdown closes a stream abruptly.
 // It makes Read and Write unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RST.
@@ -55,6 +55,18 @@ var _ = Describe("Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	It("reports the flow control offsets of both directions", func() {
+		mockFC.EXPECT().SendOffsets().Return(protocol.ByteCount(10), protocol.ByteCount(100))
+		mockFC.EXPECT().ReceiveOffsets().Return(protocol.ByteCount(20), protocol.ByteCount(30), protocol.ByteCount(200))
+		Expect(str.FlowControlOffsets()).To(Equal(FlowControlOffsets{
+			BytesSent:       10,
+			SendWindow:      100,
+			BytesRead:       20,
+			HighestReceived: 30,
+			ReceiveWindow:   200,
+		}))
+	})
+
 	Context("deadlines", func() {
 		It("sets a write deadline, when SetDeadline is called", func() {
 			str.SetDeadline(time.Now().Add(-time.Second))
@@ -44,6 +44,19 @@ func (streamOpenErr) Timeout() bool     { return false }
 // errTooManyOpenStreams is used internally by the outgoing streams maps.
 var errTooManyOpenStreams = errors.New("too many open streams")
 
+// streamLimitReachedErr is returned by OpenStreamSync when the context is canceled
+// while waiting for the peer to raise the stream limit.
+type streamLimitReachedErr struct{ ctxErr error }
+
+var _ net.Error = &streamLimitReachedErr{}
+
+func (e streamLimitReachedErr) Error() string {
+	return fmt.Sprintf("%s: %s", errTooManyOpenStreams, e.ctxErr)
+}
+func (e streamLimitReachedErr) Unwrap() error { return e.ctxErr }
+func (streamLimitReachedErr) Temporary() bool { return true }
+func (e streamLimitReachedErr) Timeout() bool { return e.ctxErr == context.DeadlineExceeded }
+
 type streamsMap struct {
 	perspective protocol.Perspective
 
@@ -63,9 +76,15 @@ func newStreamsMap(
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
+	maxIncomingBidiStreamsLimit uint64,
+	maxIncomingUniStreamsLimit uint64,
+	allowLimitIncrease func(protocol.StreamType, uint64) bool,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) streamManager {
+	if allowLimitIncrease == nil {
+		allowLimitIncrease = func(protocol.StreamType, uint64) bool { return true }
+	}
 	m := &streamsMap{
 		perspective:       perspective,
 		newFlowController: newFlowController,
@@ -84,6 +103,8 @@ func newStreamsMap(
 			return newStream(id, m.sender, m.newFlowController(id), version)
 		},
 		maxIncomingBidiStreams,
+		maxIncomingBidiStreamsLimit,
+		func(n uint64) bool { return allowLimitIncrease(protocol.StreamTypeBidi, n) },
 		sender.queueControlFrame,
 	)
 	m.outgoingUniStreams = newOutgoingUniStreamsMap(
@@ -99,6 +120,8 @@ func newStreamsMap(
 			return newReceiveStream(id, m.sender, m.newFlowController(id), version)
 		},
 		maxIncomingUniStreams,
+		maxIncomingUniStreamsLimit,
+		func(n uint64) bool { return allowLimitIncrease(protocol.StreamTypeUni, n) },
 		sender.queueControlFrame,
 	)
 	return m
@@ -223,6 +246,15 @@ func (m *streamsMap) HandleMaxStreamsFrame(f *wire.MaxStreamsFrame) error {
 	return nil
 }
 
+func (m *streamsMap) HandleStreamsBlockedFrame(f *wire.StreamsBlockedFrame) {
+	switch f.Type {
+	case protocol.StreamTypeUni:
+		m.incomingUniStreams.HandleStreamsBlocked(f.StreamLimit)
+	case protocol.StreamTypeBidi:
+		m.incomingBidiStreams.HandleStreamsBlocked(f.StreamLimit)
+	}
+}
+
 func (m *streamsMap) UpdateLimits(p *handshake.TransportParameters) error {
 	if p.MaxBidiStreamNum > protocol.MaxStreamCount ||
 		p.MaxUniStreamNum > protocol.MaxStreamCount {
@@ -234,6 +266,60 @@ func (m *streamsMap) UpdateLimits(p *handshake.TransportParameters) error {
 	return nil
 }
 
+// RaiseIncomingLimits raises the number of streams the peer is allowed to open.
+// It is used when accepting 0-RTT, since the client may open as many streams as allowed by the limits saved in the session ticket.
+func (m *streamsMap) RaiseIncomingLimits(maxBidiStreams, maxUniStreams uint64) {
+	m.incomingBidiStreams.RaiseLimit(maxBidiStreams)
+	m.incomingUniStreams.RaiseLimit(maxUniStreams)
+}
+
+func (m *streamsMap) StreamCounts() StreamCounts {
+	var numOutgoingBidi, numOutgoingUni int
+	m.outgoingBidiStreams.ForEach(func(streamI) { numOutgoingBidi++ })
+	m.outgoingUniStreams.ForEach(func(sendStreamI) { numOutgoingUni++ })
+	numIncomingBidi := m.incomingBidiStreams.NumStreams()
+	return StreamCounts{
+		SendStreams:    numOutgoingBidi + numIncomingBidi + numOutgoingUni,
+		ReceiveStreams: numOutgoingBidi + numIncomingBidi + m.incomingUniStreams.NumStreams(),
+	}
+}
+
+func (m *streamsMap) SendQueueDepth() SendQueueDepth {
+	var depth SendQueueDepth
+	add := func(str sendStreamI) {
+		d := str.SendQueueDepth()
+		depth.Queued += d.Queued
+		depth.Unacknowledged += d.Unacknowledged
+	}
+	m.outgoingBidiStreams.ForEach(func(str streamI) { add(str) })
+	m.outgoingUniStreams.ForEach(func(str sendStreamI) { add(str) })
+	m.incomingBidiStreams.ForEach(func(str streamI) { add(str) })
+	return depth
+}
+
+// ResetAllStreams cancels reading and writing on all open streams, using the same error code.
+func (m *streamsMap) ResetAllStreams(code protocol.ApplicationErrorCode) {
+	// Collect the streams first.
+	// Canceling a stream might complete it, which then deletes it from the streams map.
+	var sendStreams []sendStreamI
+	var receiveStreams []receiveStreamI
+	addBidi := func(str streamI) {
+		sendStreams = append(sendStreams, str)
+		receiveStreams = append(receiveStreams, str)
+	}
+	m.outgoingBidiStreams.ForEach(addBidi)
+	m.incomingBidiStreams.ForEach(addBidi)
+	m.outgoingUniStreams.ForEach(func(str sendStreamI) { sendStreams = append(sendStreams, str) })
+	m.incomingUniStreams.ForEach(func(str receiveStreamI) { receiveStreams = append(receiveStreams, str) })
+
+	for _, str := range sendStreams {
+		str.CancelWrite(code)
+	}
+	for _, str := range receiveStreams {
+		str.CancelRead(code)
+	}
+}
+
 func (m *streamsMap) CloseWithError(err error) {
 	m.outgoingBidiStreams.CloseWithError(err)
 	m.outgoingUniStreams.CloseWithError(err)
@@ -26,17 +26,20 @@ func (s *mockGenericStream) closeForShutdown(err error) {
 
 var _ = Describe("Streams Map (incoming)", func() {
 	const (
-		maxNumStreams uint64 = 5
+		maxNumStreams      uint64 = 5
+		maxNumStreamsLimit uint64 = 12
 	)
 
 	var (
 		m              *incomingItemsMap
 		newItemCounter int
 		mockSender     *MockStreamSender
+		allowIncrease  func(uint64) bool
 	)
 
 	BeforeEach(func() {
 		newItemCounter = 0
+		allowIncrease = nil
 		mockSender = NewMockStreamSender(mockCtrl)
 		m = newIncomingItemsMap(
 			func(num protocol.StreamNum) item {
@@ -44,6 +47,8 @@ var _ = Describe("Streams Map (incoming)", func() {
 				return &mockGenericStream{num: num}
 			},
 			maxNumStreams,
+			maxNumStreamsLimit,
+			func(n uint64) bool { return allowIncrease == nil || allowIncrease(n) },
 			mockSender.queueControlFrame,
 		)
 	})
@@ -152,6 +157,35 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(str2.(*mockGenericStream).closeErr).To(MatchError(testErr))
 	})
 
+	It("iterates over all streams", func() {
+		_, err := m.GetOrOpenStream(1)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = m.GetOrOpenStream(3)
+		Expect(err).ToNot(HaveOccurred())
+		var nums []protocol.StreamNum
+		m.ForEach(func(str item) { nums = append(nums, str.(*mockGenericStream).num) })
+		Expect(nums).To(ConsistOf(protocol.StreamNum(1), protocol.StreamNum(2), protocol.StreamNum(3)))
+	})
+
+	It("counts the open streams", func() {
+		Expect(m.NumStreams()).To(BeZero())
+		_, err := m.GetOrOpenStream(2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.NumStreams()).To(Equal(2))
+		// streams deleted before they are accepted are not counted
+		Expect(m.DeleteStream(2)).To(Succeed())
+		Expect(m.NumStreams()).To(Equal(1))
+		_, err = m.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		mockSender.EXPECT().queueControlFrame(gomock.Any())
+		_, err = m.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.NumStreams()).To(Equal(1))
+		mockSender.EXPECT().queueControlFrame(gomock.Any())
+		Expect(m.DeleteStream(1)).To(Succeed())
+		Expect(m.NumStreams()).To(BeZero())
+	})
+
 	It("deletes streams", func() {
 		mockSender.EXPECT().queueControlFrame(gomock.Any())
 		_, err := m.GetOrOpenStream(1)
@@ -218,4 +252,83 @@ var _ = Describe("Streams Map (incoming)", func() {
 		})
 		Expect(m.DeleteStream(4)).To(Succeed())
 	})
+
+	It("raises the limit without sending a MAX_STREAMS frame", func() {
+		m.RaiseLimit(maxNumStreams + 2)
+		_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 2))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 3))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("doesn't lower the limit", func() {
+		m.RaiseLimit(maxNumStreams - 1)
+		_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("raising the limit when the peer is blocked", func() {
+		It("raises the limit and sends a MAX_STREAMS frame", func() {
+			_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{
+				Type:         streamTypeGeneric,
+				MaxStreamNum: protocol.StreamNum(2 * maxNumStreams),
+			})
+			m.HandleStreamsBlocked(protocol.StreamNum(maxNumStreams))
+			_, err = m.GetOrOpenStream(protocol.StreamNum(2 * maxNumStreams))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("ignores STREAMS_BLOCKED frames for an old limit", func() {
+			m.HandleStreamsBlocked(protocol.StreamNum(maxNumStreams - 1))
+			_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 1))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("doesn't raise the limit beyond the configured maximum", func() {
+			mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{
+				Type:         streamTypeGeneric,
+				MaxStreamNum: protocol.StreamNum(2 * maxNumStreams),
+			})
+			m.HandleStreamsBlocked(protocol.StreamNum(maxNumStreams))
+			mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{
+				Type:         streamTypeGeneric,
+				MaxStreamNum: protocol.StreamNum(maxNumStreamsLimit),
+			})
+			m.HandleStreamsBlocked(protocol.StreamNum(2 * maxNumStreams))
+			m.HandleStreamsBlocked(protocol.StreamNum(maxNumStreamsLimit))
+		})
+
+		It("doesn't raise the limit if the increase is not allowed", func() {
+			var requested []uint64
+			allowIncrease = func(n uint64) bool {
+				requested = append(requested, n)
+				return false
+			}
+			m.HandleStreamsBlocked(protocol.StreamNum(maxNumStreams))
+			Expect(requested).To(Equal([]uint64{2 * maxNumStreams}))
+			_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 1))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("doesn't hold the lock while asking if the limit may be raised", func() {
+			_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams))
+			Expect(err).ToNot(HaveOccurred())
+			var numStreams int
+			allowIncrease = func(uint64) bool {
+				numStreams = m.NumStreams()
+				return true
+			}
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				m.HandleStreamsBlocked(protocol.StreamNum(maxNumStreams))
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+			Expect(numStreams).To(Equal(int(maxNumStreams)))
+		})
+	})
 })
@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -25,8 +26,10 @@ type incomingUniStreamsMap struct {
 	nextStreamToOpen   protocol.StreamNum // the highest stream that the peer openend
 	maxStream          protocol.StreamNum // the highest stream that the peer is allowed to open
 	maxNumStreams      uint64             // maximum number of streams
+	maxNumStreamsLimit uint64             // the limit up to which maxNumStreams is raised when the peer is blocked
 
 	newStream        func(protocol.StreamNum) receiveStreamI
+	allowIncrease    func(newMaxNumStreams uint64) bool
 	queueMaxStreamID func(*wire.MaxStreamsFrame)
 	// streamNumToID    func(protocol.StreamNum) protocol.StreamID // only used for generating errors
 
@@ -36,6 +39,8 @@ type incomingUniStreamsMap struct {
 func newIncomingUniStreamsMap(
 	newStream func(protocol.StreamNum) receiveStreamI,
 	maxStreams uint64,
+	maxStreamsLimit uint64,
+	allowIncrease func(newMaxNumStreams uint64) bool,
 	queueControlFrame func(wire.Frame),
 ) *incomingUniStreamsMap {
 	return &incomingUniStreamsMap{
@@ -44,7 +49,9 @@ func newIncomingUniStreamsMap(
 		streamsToDelete:    make(map[protocol.StreamNum]struct{}),
 		maxStream:          protocol.StreamNum(maxStreams),
 		maxNumStreams:      maxStreams,
+		maxNumStreamsLimit: maxStreamsLimit,
 		newStream:          newStream,
+		allowIncrease:      allowIncrease,
 		nextStreamToOpen:   1,
 		nextStreamToAccept: 1,
 		queueMaxStreamID:   func(f *wire.MaxStreamsFrame) { queueControlFrame(f) },
@@ -158,17 +165,86 @@ func (m *incomingUniStreamsMap) deleteStream(num protocol.StreamNum) error {
 
 	delete(m.streams, num)
 	// queue a MAX_STREAM_ID frame, giving the peer the option to open a new stream
-	if m.maxNumStreams > uint64(len(m.streams)) {
-		numNewStreams := m.maxNumStreams - uint64(len(m.streams))
-		m.maxStream = m.nextStreamToOpen + protocol.StreamNum(numNewStreams) - 1
-		m.queueMaxStreamID(&wire.MaxStreamsFrame{
-			Type:         protocol.StreamTypeUni,
-			MaxStreamNum: m.maxStream,
-		})
-	}
+	m.maybeQueueMaxStreams()
 	return nil
 }
 
+// HandleStreamsBlocked raises the stream limit (up to maxNumStreamsLimit),
+// if the peer is blocked on the limit that is currently in effect.
+func (m *incomingUniStreamsMap) HandleStreamsBlocked(limit protocol.StreamNum) {
+	m.mutex.Lock()
+	// The peer might have sent the STREAMS_BLOCKED frame before it received our last MAX_STREAMS frame.
+	if limit < m.maxStream || m.maxNumStreams >= m.maxNumStreamsLimit {
+		m.mutex.Unlock()
+		return
+	}
+	newMaxNumStreams := utils.MaxUint64(2*m.maxNumStreams, 1)
+	newMaxNumStreams = utils.MinUint64(newMaxNumStreams, m.maxNumStreamsLimit)
+	m.mutex.Unlock()
+
+	// Don't hold the mutex while calling allowIncrease.
+	// The callback might use the streams map, e.g. to get the number of open streams.
+	if m.allowIncrease != nil && !m.allowIncrease(newMaxNumStreams) {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	// The limit might have been raised in the meantime.
+	if newMaxNumStreams <= m.maxNumStreams {
+		return
+	}
+	m.maxNumStreams = newMaxNumStreams
+	m.maybeQueueMaxStreams()
+}
+
+// RaiseLimit raises the number of streams the peer is allowed to open, if maxNumStreams is larger than the current limit.
+// It doesn't queue a MAX_STREAMS frame, since the peer already knows about this limit (e.g. from a session ticket).
+func (m *incomingUniStreamsMap) RaiseLimit(maxNumStreams uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if maxNumStreams <= m.maxNumStreams {
+		return
+	}
+	m.maxNumStreams = maxNumStreams
+	if maxStream := protocol.StreamNum(maxNumStreams); maxStream > m.maxStream {
+		m.maxStream = maxStream
+	}
+}
+
+func (m *incomingUniStreamsMap) maybeQueueMaxStreams() {
+	if m.maxNumStreams <= uint64(len(m.streams)) {
+		return
+	}
+	numNewStreams := m.maxNumStreams - uint64(len(m.streams))
+	maxStream := m.nextStreamToOpen + protocol.StreamNum(numNewStreams) - 1
+	if maxStream <= m.maxStream {
+		return
+	}
+	m.maxStream = maxStream
+	m.queueMaxStreamID(&wire.MaxStreamsFrame{
+		Type:         protocol.StreamTypeUni,
+		MaxStreamNum: m.maxStream,
+	})
+}
+
+func (m *incomingUniStreamsMap) ForEach(f func(receiveStreamI)) {
+	m.mutex.Lock()
+	for _, str := range m.streams {
+		f(str)
+	}
+	m.mutex.Unlock()
+}
+
+// NumStreams returns the number of open streams.
+// Streams that were deleted, but not yet accepted, are not counted.
+func (m *incomingUniStreamsMap) NumStreams() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.streams) - len(m.streamsToDelete)
+}
+
 func (m *incomingUniStreamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
@@ -92,7 +92,7 @@ func (m *outgoingBidiStreamsMap) OpenStreamSync(ctx context.Context) (streamI, e
 		case <-ctx.Done():
 			m.mutex.Lock()
 			delete(m.openQueue, queuePos)
-			return nil, ctx.Err()
+			return nil, streamLimitReachedErr{ctx.Err()}
 		case <-waitChan:
 		}
 		m.mutex.Lock()
@@ -190,6 +190,14 @@ func (m *outgoingBidiStreamsMap) unblockOpenSync() {
 	}
 }
 
+func (m *outgoingBidiStreamsMap) ForEach(f func(streamI)) {
+	m.mutex.RLock()
+	for _, str := range m.streams {
+		f(str)
+	}
+	m.mutex.RUnlock()
+}
+
 func (m *outgoingBidiStreamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
@@ -90,7 +90,7 @@ func (m *outgoingItemsMap) OpenStreamSync(ctx context.Context) (item, error) {
 		case <-ctx.Done():
 			m.mutex.Lock()
 			delete(m.openQueue, queuePos)
-			return nil, ctx.Err()
+			return nil, streamLimitReachedErr{ctx.Err()}
 		case <-waitChan:
 		}
 		m.mutex.Lock()
@@ -188,6 +188,14 @@ func (m *outgoingItemsMap) unblockOpenSync() {
 	}
 }
 
+func (m *outgoingItemsMap) ForEach(f func(item)) {
+	m.mutex.RLock()
+	for _, str := range m.streams {
+		f(str)
+	}
+	m.mutex.RUnlock()
+}
+
 func (m *outgoingItemsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
@@ -3,6 +3,8 @@ package quic
 import (
 	"context"
 	"errors"
+	"net"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -86,6 +88,16 @@ var _ = Describe("Streams Map (outgoing)", func() {
 			Expect(err.(streamError).TestError()).To(MatchError("Tried to delete unknown outgoing stream 1"))
 		})
 
+		It("iterates over all streams", func() {
+			_, err := m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			var nums []protocol.StreamNum
+			m.ForEach(func(str item) { nums = append(nums, str.(*mockGenericStream).num) })
+			Expect(nums).To(ConsistOf(protocol.StreamNum(1), protocol.StreamNum(2)))
+		})
+
 		It("closes all streams when CloseWithError is called", func() {
 			str1, err := m.OpenStream()
 			Expect(err).ToNot(HaveOccurred())
@@ -130,6 +142,27 @@ var _ = Describe("Streams Map (outgoing)", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("reports the stream limit as the reason for blocking when the deadline expires", func() {
+			m.SetMaxStream(2)
+			for i := 0; i < 2; i++ {
+				_, err := m.OpenStreamSync(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+			}
+			mockSender.EXPECT().queueControlFrame(&wire.StreamsBlockedFrame{
+				Type:        streamTypeGeneric,
+				StreamLimit: 2,
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+			defer cancel()
+			_, err := m.OpenStreamSync(ctx)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(err).To(BeAssignableToTypeOf(streamLimitReachedErr{}))
+			nerr, ok := err.(net.Error)
+			Expect(ok).To(BeTrue())
+			Expect(nerr.Temporary()).To(BeTrue())
+			Expect(nerr.Timeout()).To(BeTrue())
+		})
+
 		It("unblocks when the context is canceled", func() {
 			mockSender.EXPECT().queueControlFrame(gomock.Any())
 			ctx, cancel := context.WithCancel(context.Background())
@@ -137,7 +170,10 @@ var _ = Describe("Streams Map (outgoing)", func() {
 			go func() {
 				defer GinkgoRecover()
 				_, err := m.OpenStreamSync(ctx)
-				Expect(err).To(MatchError("context canceled"))
+				Expect(err).To(MatchError("too many open streams: context canceled"))
+				Expect(err).To(MatchError(context.Canceled))
+				Expect(err.(net.Error).Temporary()).To(BeTrue())
+				Expect(err.(net.Error).Timeout()).To(BeFalse())
 				close(done)
 			}()
 
@@ -92,7 +92,7 @@ func (m *outgoingUniStreamsMap) OpenStreamSync(ctx context.Context) (sendStreamI
 		case <-ctx.Done():
 			m.mutex.Lock()
 			delete(m.openQueue, queuePos)
-			return nil, ctx.Err()
+			return nil, streamLimitReachedErr{ctx.Err()}
 		case <-waitChan:
 		}
 		m.mutex.Lock()
@@ -190,6 +190,14 @@ func (m *outgoingUniStreamsMap) unblockOpenSync() {
 	}
 }
 
+func (m *outgoingUniStreamsMap) ForEach(f func(sendStreamI)) {
+	m.mutex.RLock()
+	for _, str := range m.streams {
+		f(str)
+	}
+	m.mutex.RUnlock()
+}
+
 func (m *outgoingUniStreamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
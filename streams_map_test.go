@@ -71,13 +71,16 @@ var _ = Describe("Streams Map", func() {
 
 		Context(perspective.String(), func() {
 			var (
-				m          *streamsMap
-				mockSender *MockStreamSender
+				m                  *streamsMap
+				mockSender         *MockStreamSender
+				allowLimitIncrease func(protocol.StreamType, uint64) bool
 			)
 
 			const (
-				MaxBidiStreamNum = 111
-				MaxUniStreamNum  = 222
+				MaxBidiStreamNum     = 111
+				MaxUniStreamNum      = 222
+				MaxAutoBidiStreamNum = 333
+				MaxAutoUniStreamNum  = 444
 			)
 
 			allowUnlimitedStreams := func() {
@@ -89,7 +92,20 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, protocol.VersionWhatever).(*streamsMap)
+				allowLimitIncrease = nil
+				m = newStreamsMap(
+					mockSender,
+					newFlowController,
+					MaxBidiStreamNum,
+					MaxUniStreamNum,
+					MaxAutoBidiStreamNum,
+					MaxAutoUniStreamNum,
+					func(t protocol.StreamType, n uint64) bool {
+						return allowLimitIncrease == nil || allowLimitIncrease(t, n)
+					},
+					perspective,
+					protocol.VersionWhatever,
+				).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -136,6 +152,35 @@ var _ = Describe("Streams Map", func() {
 					Expect(str).To(BeAssignableToTypeOf(&receiveStream{}))
 					Expect(str.StreamID()).To(Equal(ids.firstIncomingUniStream))
 				})
+
+				It("accepts unidirectional streams independently of bidirectional streams", func() {
+					acceptedBidi := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						_, err := m.AcceptStream(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						close(acceptedBidi)
+					}()
+					// open more unidirectional streams than the peer is allowed to open bidirectional streams
+					Expect(MaxUniStreamNum).To(BeNumerically(">", MaxBidiStreamNum))
+					for i := 0; i < MaxUniStreamNum; i++ {
+						_, err := m.GetOrOpenReceiveStream(ids.firstIncomingUniStream + protocol.StreamID(4*i))
+						Expect(err).ToNot(HaveOccurred())
+					}
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingUniStream + protocol.StreamID(4*MaxUniStreamNum))
+					Expect(err).To(HaveOccurred())
+					Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.StreamStateError))
+					for i := 0; i < MaxUniStreamNum; i++ {
+						str, err := m.AcceptUniStream(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						Expect(str.StreamID()).To(Equal(ids.firstIncomingUniStream + protocol.StreamID(4*i)))
+					}
+					Consistently(acceptedBidi).ShouldNot(BeClosed())
+					// the peer can still open bidirectional streams
+					_, err = m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
+					Expect(err).ToNot(HaveOccurred())
+					Eventually(acceptedBidi).Should(BeClosed())
+				})
 			})
 
 			Context("deleting", func() {
@@ -429,6 +474,141 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 
+			Context("handling STREAMS_BLOCKED frames", func() {
+				It("raises the limit for bidirectional streams", func() {
+					id := protocol.StreamNum(MaxBidiStreamNum).StreamID(protocol.StreamTypeBidi, perspective.Opposite())
+					_, err := m.GetOrOpenReceiveStream(id)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = m.GetOrOpenReceiveStream(id + 4)
+					Expect(err).To(HaveOccurred())
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{
+						Type:         protocol.StreamTypeBidi,
+						MaxStreamNum: 2 * MaxBidiStreamNum,
+					})
+					m.HandleStreamsBlockedFrame(&wire.StreamsBlockedFrame{
+						Type:        protocol.StreamTypeBidi,
+						StreamLimit: MaxBidiStreamNum,
+					})
+					_, err = m.GetOrOpenReceiveStream(id + 4)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("raises the limit for unidirectional streams, up to the configured maximum", func() {
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{
+						Type:         protocol.StreamTypeUni,
+						MaxStreamNum: MaxAutoUniStreamNum,
+					})
+					m.HandleStreamsBlockedFrame(&wire.StreamsBlockedFrame{
+						Type:        protocol.StreamTypeUni,
+						StreamLimit: MaxUniStreamNum,
+					})
+				})
+
+				It("allows getting the stream counts when asked if the limit may be raised", func() {
+					var counts StreamCounts
+					allowLimitIncrease = func(protocol.StreamType, uint64) bool {
+						counts = m.StreamCounts()
+						return true
+					}
+					mockSender.EXPECT().queueControlFrame(gomock.Any())
+					done := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						m.HandleStreamsBlockedFrame(&wire.StreamsBlockedFrame{
+							Type:        protocol.StreamTypeUni,
+							StreamLimit: MaxUniStreamNum,
+						})
+						close(done)
+					}()
+					Eventually(done).Should(BeClosed())
+					Expect(counts).To(Equal(StreamCounts{}))
+				})
+
+				It("asks if the limit may be raised", func() {
+					allowLimitIncrease = func(t protocol.StreamType, n uint64) bool {
+						Expect(t).To(Equal(protocol.StreamTypeBidi))
+						Expect(n).To(BeEquivalentTo(2 * MaxBidiStreamNum))
+						return false
+					}
+					m.HandleStreamsBlockedFrame(&wire.StreamsBlockedFrame{
+						Type:        protocol.StreamTypeBidi,
+						StreamLimit: MaxBidiStreamNum,
+					})
+				})
+			})
+
+			It("sums up the send queue depth of all streams", func() {
+				allowUnlimitedStreams()
+				setDepth := func(str *sendStream, queued int, unacked protocol.ByteCount) {
+					str.dataForWriting = make([]byte, queued)
+					str.numOutstandingBytes = unacked
+				}
+				str1, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				setDepth(&str1.(*stream).sendStream, 1, 10)
+				str2, err := m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				setDepth(str2.(*sendStream), 2, 20)
+				str3, err := m.GetOrOpenSendStream(ids.firstIncomingBidiStream)
+				Expect(err).ToNot(HaveOccurred())
+				setDepth(&str3.(*stream).sendStream, 3, 30)
+				// receive streams don't count
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(m.SendQueueDepth()).To(Equal(SendQueueDepth{Queued: 6, Unacknowledged: 60}))
+			})
+
+			It("counts the open streams", func() {
+				allowUnlimitedStreams()
+				Expect(m.StreamCounts()).To(Equal(StreamCounts{}))
+				bidi, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				uni, err := m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(m.StreamCounts()).To(Equal(StreamCounts{SendStreams: 2, ReceiveStreams: 1}))
+				// opening an incoming stream implicitly opens all lower streams
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream + 4)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(m.StreamCounts()).To(Equal(StreamCounts{SendStreams: 4, ReceiveStreams: 4}))
+				Expect(m.DeleteStream(uni.StreamID())).To(Succeed())
+				Expect(m.StreamCounts()).To(Equal(StreamCounts{SendStreams: 3, ReceiveStreams: 4}))
+				Expect(m.DeleteStream(bidi.StreamID())).To(Succeed())
+				Expect(m.DeleteStream(ids.firstIncomingUniStream)).To(Succeed())
+				Expect(m.StreamCounts()).To(Equal(StreamCounts{SendStreams: 2, ReceiveStreams: 2}))
+			})
+
+			It("resets all streams", func() {
+				allowUnlimitedStreams()
+				var frames []wire.Frame
+				mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+					frames = append(frames, f)
+				}).AnyTimes()
+				mockSender.EXPECT().onStreamCompleted(gomock.Any()).AnyTimes()
+				_, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenSendStream(ids.firstIncomingBidiStream)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				m.ResetAllStreams(1337)
+				Expect(frames).To(ConsistOf(
+					&wire.ResetStreamFrame{StreamID: ids.firstOutgoingBidiStream, ErrorCode: 1337},
+					&wire.StopSendingFrame{StreamID: ids.firstOutgoingBidiStream, ErrorCode: 1337},
+					&wire.ResetStreamFrame{StreamID: ids.firstOutgoingUniStream, ErrorCode: 1337},
+					&wire.ResetStreamFrame{StreamID: ids.firstIncomingBidiStream, ErrorCode: 1337},
+					&wire.StopSendingFrame{StreamID: ids.firstIncomingBidiStream, ErrorCode: 1337},
+					&wire.StopSendingFrame{StreamID: ids.firstIncomingUniStream, ErrorCode: 1337},
+				))
+				// streams that were already reset are not reset again
+				frames = nil
+				m.ResetAllStreams(42)
+				Expect(frames).To(BeEmpty())
+			})
+
 			It("closes", func() {
 				testErr := errors.New("test error")
 				m.CloseWithError(testErr)
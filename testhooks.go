@@ -0,0 +1,36 @@
+// +build quictesthooks
+
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// DestConnectionIDRewriter is called with the destination connection ID of every packet sent.
+// The packet is sent with the connection ID it returns.
+// It can be used to test how load balancers route packets.
+// It must be set before establishing any connections.
+// It is only available when building with the quictesthooks build tag, and must not be used in production.
+var DestConnectionIDRewriter func(destConnID []byte) []byte
+
+func rewriteDestConnectionID(c protocol.ConnectionID) protocol.ConnectionID {
+	if DestConnectionIDRewriter == nil {
+		return c
+	}
+	return protocol.ConnectionID(DestConnectionIDRewriter(c.Bytes()))
+}
+
+// Clock is used by sessions to read the current time and to schedule timers.
+// It allows tests to control the time, e.g. to trigger loss recovery or idle timeouts deterministically.
+// If nil, the wall clock is used.
+// It must be set before establishing any connections.
+// It is only available when building with the quictesthooks build tag, and must not be used in production.
+var Clock utils.Clock
+
+func getClock() utils.Clock {
+	if Clock == nil {
+		return utils.DefaultClock{}
+	}
+	return Clock
+}
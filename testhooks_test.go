@@ -0,0 +1,82 @@
+// +build quictesthooks
+
+package quic
+
+import (
+	"bytes"
+	"net"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
+	mockackhandler "github.com/lucas-clemente/quic-go/internal/mocks/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testutils"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test Hooks", func() {
+	AfterEach(func() {
+		DestConnectionIDRewriter = nil
+		Clock = nil
+	})
+
+	It("rewrites the destination connection ID of outgoing packets", func() {
+		origConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
+		DestConnectionIDRewriter = func(c []byte) []byte {
+			Expect(c).To(Equal(origConnID.Bytes()))
+			return []byte{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+		}
+
+		pnManager := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sealingManager := NewMockSealingManager(mockCtrl)
+		ackFramer := NewMockAckFrameSource(mockCtrl)
+		packer := newPacketPacker(
+			protocol.ConnectionID{8, 7, 6, 5, 4, 3, 2, 1},
+			func() protocol.ConnectionID { return origConnID },
+			func() bool { return false },
+			NewMockCryptoStream(mockCtrl),
+			NewMockCryptoStream(mockCtrl),
+			pnManager,
+			newRetransmissionQueue(protocol.VersionTLS),
+			&net.TCPAddr{},
+			sealingManager,
+			NewMockFrameSource(mockCtrl),
+			ackFramer,
+			protocol.PerspectiveServer,
+			protocol.VersionTLS,
+		)
+		sealer := mocks.NewMockShortHeaderSealer(mockCtrl)
+		sealer.EXPECT().KeyPhase().Return(protocol.KeyPhaseOne).AnyTimes()
+		sealer.EXPECT().Overhead().Return(7).AnyTimes()
+		sealer.EXPECT().EncryptHeader(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		sealer.EXPECT().Seal(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(dst, src []byte, pn protocol.PacketNumber, associatedData []byte) []byte {
+			return append(src, bytes.Repeat([]byte{'s'}, 7)...)
+		})
+		pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+		pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+		sealingManager.EXPECT().Get1RTTSealer().Return(sealer, nil)
+		ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT).Return(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}})
+		p, err := packer.MaybePackAckPacket(true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p).ToNot(BeNil())
+
+		hdr, _, _, err := wire.ParsePacket(p.buffer.Data, 8)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}))
+	})
+
+	It("uses the wall clock by default", func() {
+		Expect(getClock()).To(Equal(utils.DefaultClock{}))
+	})
+
+	It("uses the clock that was set", func() {
+		clock := testutils.NewMockClock(time.Now())
+		Clock = clock
+		Expect(getClock()).To(Equal(clock))
+	})
+})
@@ -0,0 +1,45 @@
+package quic
+
+import "sync"
+
+type tokenReplayCache struct {
+	mutex sync.Mutex
+
+	m      map[string]struct{}
+	tokens []string // ring buffer, containing the tokens in the order they were added
+	len    int
+	p      int
+}
+
+var _ TokenReplayCache = &tokenReplayCache{}
+
+// NewTokenReplayCache creates a new cache used by the server to detect replayed Retry tokens.
+// It holds up to size tokens. Once it is full, the oldest token is evicted when a new token is added.
+func NewTokenReplayCache(size int) TokenReplayCache {
+	return &tokenReplayCache{
+		m:      make(map[string]struct{}, size),
+		tokens: make([]string, size),
+	}
+}
+
+func (c *tokenReplayCache) Add(token []byte) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := string(token)
+	if _, ok := c.m[key]; ok {
+		return true
+	}
+	if len(c.tokens) == 0 {
+		return false
+	}
+	if c.len == len(c.tokens) {
+		delete(c.m, c.tokens[c.p])
+	} else {
+		c.len++
+	}
+	c.tokens[c.p] = key
+	c.m[key] = struct{}{}
+	c.p = (c.p + 1) % len(c.tokens)
+	return false
+}
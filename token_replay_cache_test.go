@@ -0,0 +1,42 @@
+package quic
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token Replay Cache", func() {
+	token := func(num int) []byte {
+		return []byte(fmt.Sprintf("token %d", num))
+	}
+
+	It("detects replayed tokens", func() {
+		c := NewTokenReplayCache(3)
+		Expect(c.Add(token(1))).To(BeFalse())
+		Expect(c.Add(token(2))).To(BeFalse())
+		Expect(c.Add(token(1))).To(BeTrue())
+		Expect(c.Add(token(2))).To(BeTrue())
+		Expect(c.Add(token(3))).To(BeFalse())
+	})
+
+	It("evicts the oldest tokens when full", func() {
+		c := NewTokenReplayCache(3)
+		for i := 1; i <= 5; i++ {
+			Expect(c.Add(token(i))).To(BeFalse())
+		}
+		Expect(c.(*tokenReplayCache).m).To(HaveLen(3))
+		// tokens 1 and 2 were evicted
+		Expect(c.Add(token(5))).To(BeTrue())
+		Expect(c.Add(token(4))).To(BeTrue())
+		Expect(c.Add(token(3))).To(BeTrue())
+		Expect(c.Add(token(1))).To(BeFalse())
+	})
+
+	It("doesn't store any tokens if the size is 0", func() {
+		c := NewTokenReplayCache(0)
+		Expect(c.Add(token(1))).To(BeFalse())
+		Expect(c.Add(token(1))).To(BeFalse())
+	})
+})
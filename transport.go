@@ -0,0 +1,77 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A Transport uses a single net.PacketConn to dial QUIC connections and to listen for incoming connections.
+// The packets received on the PacketConn are demultiplexed using the QUIC connection IDs.
+// Since all connections share the connection ID routing, they all use the ConnectionIDLength
+// and the StatelessResetKey of the Transport.
+// The ConnectionIDLength and StatelessResetKey set in the Config passed to Listen and Dial are ignored.
+type Transport struct {
+	// The Conn is used to send and receive the packets of all connections.
+	// It must not be used with another Transport, or with the package-level Listen and Dial functions.
+	Conn net.PacketConn
+
+	// The length of the connection IDs used by all connections.
+	// If zero, the default of 4 bytes is used.
+	ConnectionIDLength int
+
+	// The StatelessResetKey is used to generate stateless reset tokens.
+	// If no key is configured, sending of stateless resets is disabled.
+	StatelessResetKey []byte
+}
+
+// Listen starts listening for incoming QUIC connections.
+// There can only be a single listener on any Transport.
+// See Listen for details.
+func (t *Transport) Listen(tlsConf *tls.Config, config *Config) (Listener, error) {
+	return listen(t.Conn, tlsConf, t.populateConfig(config), false)
+}
+
+// ListenEarly works like Listen, but it returns sessions before the handshake completes.
+func (t *Transport) ListenEarly(tlsConf *tls.Config, config *Config) (EarlyListener, error) {
+	s, err := listen(t.Conn, tlsConf, t.populateConfig(config), true)
+	if err != nil {
+		return nil, err
+	}
+	return &earlyServer{s}, nil
+}
+
+// Dial establishes a new QUIC connection to a server.
+// The host parameter is used for SNI.
+// See Dial for details.
+func (t *Transport) Dial(ctx context.Context, remoteAddr net.Addr, host string, tlsConf *tls.Config, config *Config) (Session, error) {
+	return dialContext(ctx, t.Conn, remoteAddr, host, tlsConf, t.populateConfig(config), false, false)
+}
+
+// DialEarly establishes a new 0-RTT QUIC connection to a server.
+// See Dial for details.
+func (t *Transport) DialEarly(ctx context.Context, remoteAddr net.Addr, host string, tlsConf *tls.Config, config *Config) (EarlySession, error) {
+	return dialContext(ctx, t.Conn, remoteAddr, host, tlsConf, t.populateConfig(config), true, false)
+}
+
+// Close closes the underlying PacketConn.
+// All connections and the listener using this Transport are closed.
+func (t *Transport) Close() error {
+	return t.Conn.Close()
+}
+
+func (t *Transport) populateConfig(config *Config) *Config {
+	if config == nil {
+		config = &Config{}
+	} else {
+		config = config.Clone()
+	}
+	config.ConnectionIDLength = t.ConnectionIDLength
+	if config.ConnectionIDLength == 0 {
+		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
+	}
+	config.StatelessResetKey = t.StatelessResetKey
+	return config
+}
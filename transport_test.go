@@ -0,0 +1,95 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transport", func() {
+	var (
+		tr         *Transport
+		serverConf *tls.Config
+		clientConf *tls.Config
+	)
+
+	BeforeEach(func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		tr = &Transport{Conn: conn, ConnectionIDLength: 6}
+		serverConf = testdata.GetTLSConfig()
+		serverConf.NextProtos = []string{"transport-test"}
+		clientConf = &tls.Config{
+			InsecureSkipVerify: true,
+			NextProtos:         []string{"transport-test"},
+		}
+	})
+
+	AfterEach(func() {
+		tr.Close()
+	})
+
+	It("uses its connection ID settings for all connections", func() {
+		c := tr.populateConfig(&Config{ConnectionIDLength: 10, StatelessResetKey: []byte("foobar"), HandshakeTimeout: time.Minute})
+		Expect(c.ConnectionIDLength).To(Equal(6))
+		Expect(c.StatelessResetKey).To(BeNil())
+		Expect(c.HandshakeTimeout).To(Equal(time.Minute))
+		Expect((&Transport{}).populateConfig(nil).ConnectionIDLength).To(Equal(4))
+	})
+
+	It("dials and listens on the same socket", func() {
+		// Connection IDs in the config are ignored. Using different lengths on the same
+		// net.PacketConn would be an error otherwise.
+		ln, err := tr.Listen(serverConf, &Config{ConnectionIDLength: 8})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		serverErrChan := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			if err != nil {
+				serverErrChan <- err
+				return
+			}
+			str, err := sess.AcceptStream(context.Background())
+			if err != nil {
+				serverErrChan <- err
+				return
+			}
+			data, err := ioutil.ReadAll(str)
+			if err != nil {
+				serverErrChan <- err
+				return
+			}
+			_, err = str.Write(append([]byte("echo: "), data...))
+			if err == nil {
+				err = str.Close()
+			}
+			serverErrChan <- err
+		}()
+
+		// dial the listener running on the same Transport
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		sess, err := tr.Dial(ctx, tr.Conn.LocalAddr(), "localhost", clientConf, &Config{ConnectionIDLength: 12})
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("echo: foobar"))
+		Eventually(serverErrChan).Should(Receive(BeNil()))
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+	})
+})